@@ -1,26 +1,35 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"github.com/kabanero-io/kabanero-operator/pkg/apis"
+	kabanerov1beta1 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1beta1"
 	kabanerowebhookv1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/webhook/kabanero/v1alpha2"
 	stackwebhook "github.com/kabanero-io/kabanero-operator/pkg/webhook/stack"
 
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 var log = logf.Log.WithName("cmd")
 
+// The address on which the liveness and readiness checks are served.
+const healthProbeBindAddress = ":8081"
+
 // These variables are injected during the build using ldflags
 var GitTag string
 var GitCommit string
@@ -78,7 +87,8 @@ func main() {
 
 	// Create a new Cmd to provide shared dependencies and start components
 	mgr, err := manager.New(cfg, manager.Options{
-		Namespace: namespace,
+		Namespace:              namespace,
+		HealthProbeBindAddress: healthProbeBindAddress,
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -100,6 +110,24 @@ func main() {
 	hookServer.Register("/validate-stacks", stackwebhook.BuildValidatingWebhook(&mgr))
 	hookServer.Register("/mutate-stacks", stackwebhook.BuildMutatingWebhook(&mgr))
 
+	// v1beta1 requests are converted to v1alpha2 for the webhooks above.  The v1beta1
+	// defaults that the schema cannot express are set first.
+	hookServer.Register("/mutate-stacks/v1beta1", admission.DefaultingWebhookFor(&kabanerov1beta1.Stack{}))
+	hookServer.Register("/mutate-kabaneros/v1beta1", admission.DefaultingWebhookFor(&kabanerov1beta1.Kabanero{}))
+
+	// The webhooks are ready when the serving certificate can be loaded.
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddReadyzCheck("certificate", func(req *http.Request) error {
+		return checkServingCertificate(hookServer.CertDir)
+	}); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	log.Info("Starting the Cmd.")
 
 	// Start the Cmd
@@ -108,3 +136,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// Returns an error if the webhook serving certificate and key in the input directory
+// cannot be loaded.
+func checkServingCertificate(certDir string) error {
+	certFile := filepath.Join(certDir, "tls.crt")
+	keyFile := filepath.Join(certDir, "tls.key")
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		return fmt.Errorf("Unable to load the webhook serving certificate: %v", err)
+	}
+	return nil
+}
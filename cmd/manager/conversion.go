@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+)
+
+// The conversion webhook of the stacks and kabaneros CRDs is served by the operator, rather
+// than by the admission webhook, which only exists once a Kabanero instance is reconciled.
+// The Service of the webhook, and its serving certificate, are declared with the operator:
+// OLM creates them from the webhook definition of the CSV, and injects its CA bundle into
+// the CRDs.  When the operator is deployed from deploy/, the Service is
+// deploy/webhook_service.yaml, and the OpenShift service CA creates the certificate secret.
+const conversionWebhookPort = 9443
+
+// The directory in which the serving certificate is mounted.
+const conversionCertDir = "/tmp/k8s-webhook-server/serving-certs"
+
+// How often to check whether the serving certificate has been mounted.
+const conversionCertPollInterval = 10 * time.Second
+
+// Serves the conversion webhook once its serving certificate is mounted.  The service CA
+// only creates the secret after the Service, so the volume may be populated after the
+// operator starts.
+type conversionWebhookServer struct {
+	*webhook.Server
+}
+
+func (s conversionWebhookServer) Start(stop <-chan struct{}) error {
+	waiting := false
+	for !fileExists(filepath.Join(s.CertDir, "tls.crt")) || !fileExists(filepath.Join(s.CertDir, "tls.key")) {
+		if !waiting {
+			log.Info(fmt.Sprintf("Waiting for the serving certificate of the conversion webhook in %v", s.CertDir))
+			waiting = true
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(conversionCertPollInterval):
+		}
+	}
+
+	log.Info("Serving the conversion webhook")
+	return s.Server.Start(stop)
+}
+
+// Returns true if the file exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Serves the conversion webhook from the manager.  The webhook is not served when the
+// operator runs outside of the cluster.
+func addConversionWebhook(mgr manager.Manager) error {
+	if _, err := k8sutil.GetOperatorNamespace(); err != nil {
+		if errors.Is(err, k8sutil.ErrRunLocal) {
+			log.Info("Skipping the conversion webhook; not running in a cluster.")
+			return nil
+		}
+		return err
+	}
+
+	server := &webhook.Server{Port: conversionWebhookPort, CertDir: conversionCertDir}
+	server.Register("/convert", &conversion.Webhook{})
+	return mgr.Add(conversionWebhookServer{server})
+}
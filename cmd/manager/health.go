@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// The name of the leader election lock ConfigMap.
+const leaderLockName = "kabanero-operator-lock"
+
+// The amount of time that a health check waits for the API server.
+const healthCheckTimeout = 5 * time.Second
+
+// Adds the liveness and readiness checks to the manager.  The operator is live while
+// its reconcile workers make progress, and ready while it can reach the API server and
+// still holds the leader lock.
+func addHealthChecks(mgr manager.Manager, cfg *rest.Config, reconcileStallTimeout time.Duration) error {
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return err
+	}
+
+	if err := mgr.AddHealthzCheck("reconcile", func(req *http.Request) error {
+		return cutils.CheckReconcileProgress(reconcileStallTimeout, time.Now())
+	}); err != nil {
+		return err
+	}
+
+	apiServerCheck, err := newAPIServerCheck(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := mgr.AddReadyzCheck("apiserver", apiServerCheck); err != nil {
+		return err
+	}
+
+	return mgr.AddReadyzCheck("leader", newLeaderCheck(mgr.GetAPIReader()))
+}
+
+// Returns a check that fails when the API server cannot be reached.
+func newAPIServerCheck(cfg *rest.Config) (healthz.Checker, error) {
+	checkCfg := rest.CopyConfig(cfg)
+	checkCfg.Timeout = healthCheckTimeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(checkCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req *http.Request) error {
+		if _, err := discoveryClient.ServerVersion(); err != nil {
+			return fmt.Errorf("Unable to reach the API server: %v", err)
+		}
+		return nil
+	}, nil
+}
+
+// Returns a check that fails when the leader lock is no longer owned by this pod.  The
+// check always passes when the operator is not running in a cluster, since there is no
+// lock to hold.
+func newLeaderCheck(reader client.Reader) healthz.Checker {
+	return func(req *http.Request) error {
+		operatorNs, err := k8sutil.GetOperatorNamespace()
+		if err != nil {
+			if errors.Is(err, k8sutil.ErrRunLocal) {
+				return nil
+			}
+			return err
+		}
+
+		podName := os.Getenv(k8sutil.PodNameEnvVar)
+		if len(podName) == 0 {
+			return fmt.Errorf("The %v environment variable is not set, or is empty", k8sutil.PodNameEnvVar)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		defer cancel()
+
+		lock := &corev1.ConfigMap{}
+		if err := reader.Get(ctx, types.NamespacedName{Name: leaderLockName, Namespace: operatorNs}, lock); err != nil {
+			return fmt.Errorf("Unable to read the leader lock %v: %v", leaderLockName, err)
+		}
+
+		for _, owner := range lock.OwnerReferences {
+			if owner.Kind == "Pod" && owner.Name == podName {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("The leader lock %v is not owned by pod %v", leaderLockName, podName)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -15,6 +16,9 @@ import (
 
 	"github.com/kabanero-io/kabanero-operator/pkg/apis"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/selftest"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	artifactcache "github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
 
 	knsapis "knative.dev/serving/pkg/apis/serving/v1alpha1"
 	appsv1 "github.com/openshift/api/apps/v1"
@@ -28,7 +32,6 @@ import (
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	kubemetrics "github.com/operator-framework/operator-sdk/pkg/kube-metrics"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
-	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 	"github.com/spf13/pflag"
@@ -36,6 +39,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 )
 
@@ -44,7 +48,12 @@ var (
 	metricsHost       = "0.0.0.0"
 	metricsPort int32 = 8383
 	operatorMetricsPort int32 = 8686
+	healthProbePort     int32 = 8081
 )
+
+// The amount of time to wait for in-flight pipeline activations when stopping.
+// This should be less than the pod's termination grace period.
+const activationDrainTimeout = 20 * time.Second
 var log = logf.Log.WithName("cmd")
 
 // These variables are injected during the build using ldflags
@@ -77,28 +86,47 @@ func printVersion() {
 }
 
 func main() {
-	// Add the zap logger flag set to the CLI. The flag set must
-	// be added before calling pflag.Parse().
-	pflag.CommandLine.AddFlagSet(zap.FlagSet())
+	// The zap logger flags.  The level can be changed while the operator runs, so the
+	// logger is built by the operator rather than from the operator-sdk flag set.
+	zapLevel := pflag.String("zap-level", "info", "Level of the components that the trace specification does not set: error, info, debug, trace or a verbosity number")
+	zapDevel := pflag.Bool("zap-devel", false, "Enable the development logger, which writes console encoded messages")
 
 	// Add flags registered by imported packages (e.g. glog and
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	// The self-test validates configuration files without connecting to a cluster.
+	selfTest := pflag.Bool("self-test", false, "Validate the Kabanero and Stack files, then exit")
+	kabaneroFile := pflag.String("kabanero-file", "", "Kabanero instance file validated by the self-test")
+	stackFiles := pflag.StringSlice("stack-file", []string{}, "Stack instance files validated by the self-test")
+
+	// The liveness check fails when a reconcile runs for longer than this.
+	reconcileStallTimeout := pflag.Duration("reconcile-stall-timeout", 15*time.Minute, "Time after which a reconcile that has not completed fails the liveness check")
+
 	pflag.Parse()
 
-	// Use a zap logr.Logger implementation. If none of the zap
-	// flags are configured (or if the zap flag set is not being
-	// used), this defaults to a production zap logger.
-	//
+	// Use a zap logr.Logger implementation, whose level follows the trace specification
+	// of the Kabanero instance and the kabanero-operator-trace ConfigMap.
+	if err := cutils.SetTraceDefaultLevel(*zapLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --zap-level: %v\n", err)
+		os.Exit(1)
+	}
+
 	// The logger instantiated here can be changed to any logger
 	// implementing the logr.Logger interface. This logger will
 	// be propagated through the whole operator, generating
 	// uniform and structured logs.
-	logf.SetLogger(zap.Logger())
+	logf.SetLogger(cutils.NewTraceLogger(zap.New(zap.Level(&cutils.TraceLevel), zap.UseDevMode(*zapDevel))))
 
 	printVersion()
 
+	if *selfTest {
+		if !selftest.Run(selftest.Options{KabaneroFile: *kabaneroFile, StackFiles: *stackFiles}, os.Stdout, log) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	namespace, err := k8sutil.GetWatchNamespace()
 	if err != nil {
 		log.Error(err, "Failed to get watch namespace")
@@ -122,8 +150,9 @@ func main() {
 
 	// Set default manager options
 	options := manager.Options{
-		Namespace:          namespace,
-		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		Namespace:              namespace,
+		MetricsBindAddress:     fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		HealthProbeBindAddress: fmt.Sprintf("%s:%d", metricsHost, healthProbePort),
 	}
 
 	// Add support for MultiNamespace set in WATCH_NAMESPACE (e.g ns1,ns2)
@@ -185,19 +214,50 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Warm start the download cache from its volume, if one is configured.
+	if dir := os.Getenv(artifactcache.DiskCacheDirEnv); len(dir) != 0 {
+		if err := artifactcache.SetDiskCacheDir(dir); err != nil {
+			log.Error(err, "Downloads are cached in memory only")
+		}
+	}
+
+	// Purge unused download cache entries while the manager runs.
+	if err := artifactcache.AddToManager(mgr); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	// Serve the liveness and readiness checks
+	if err := addHealthChecks(mgr, cfg, *reconcileStallTimeout); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Add the Metrics Service
 	addMetrics(ctx, cfg)
 
+	// Serve the conversion webhook of the Kabanero and Stack CRDs
+	if err := addConversionWebhook(mgr); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	log.Info("Starting the Cmd.")
 
 	// Start the Cmd
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
+	err = mgr.Start(signals.SetupSignalHandler())
+
+	// The manager has stopped.  Give the pipeline activations that are in progress
+	// a chance to finish, and to save their progress in the status.
+	cutils.DrainActivations(activationDrainTimeout, log)
+
+	if err != nil {
 		log.Error(err, "Manager exited non-zero")
 		os.Exit(1)
 	}
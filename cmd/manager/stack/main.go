@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"github.com/kabanero-io/kabanero-operator/pkg/apis"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	artifactcache "github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -25,6 +28,10 @@ var (
 	metricsHost       = "0.0.0.0"
 	metricsPort int32 = 8383
 )
+
+// The amount of time to wait for in-flight pipeline activations when stopping.
+// This should be less than the pod's termination grace period.
+const activationDrainTimeout = 20 * time.Second
 var log = logf.Log.WithName("cmd")
 
 // These variables are injected during the build using ldflags
@@ -110,6 +117,19 @@ func main() {
 		os.Exit(1)
 	}
 */
+	// Warm start the download cache from its volume, if one is configured.
+	if dir := os.Getenv(artifactcache.DiskCacheDirEnv); len(dir) != 0 {
+		if err := artifactcache.SetDiskCacheDir(dir); err != nil {
+			log.Error(err, "Downloads are cached in memory only")
+		}
+	}
+
+	// Purge unused download cache entries while the manager runs.
+	if err := artifactcache.AddToManager(mgr); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
 	if err := stack.AddToManager(mgr); err != nil {
 		log.Error(err, "")
@@ -119,7 +139,13 @@ func main() {
 	log.Info("Starting the Cmd.")
 
 	// Start the Cmd
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
+	err = mgr.Start(signals.SetupSignalHandler())
+
+	// The manager has stopped.  Give the pipeline activations that are in progress
+	// a chance to finish, and to save their progress in the status.
+	cutils.DrainActivations(activationDrainTimeout, log)
+
+	if err != nil {
 		log.Error(err, "Manager exited non-zero")
 		os.Exit(1)
 	}
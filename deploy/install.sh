@@ -46,9 +46,14 @@ if [ "$OCMIN" != "$OCHEAD" ]; then
   exit 1
 fi
 
-# Check to see if we're upgrading, and if so, that we're at N-1 or N.
+# Check to see if we're upgrading, and if so, that we're at N-1 or N.  Releases before 0.10
+# subscribed to the kabanero operator in the kabanero namespace.
+OPERATOR_NS=openshift-operators
 if [ `oc get subscription kabanero-operator -n kabanero --no-headers --ignore-not-found | wc -l` -gt 0 ] ; then
-		CSV=$(oc get subscription kabanero-operator -n kabanero --output=jsonpath={.status.installedCSV})
+	OPERATOR_NS=kabanero
+fi
+if [ `oc get subscription kabanero-operator -n $OPERATOR_NS --no-headers --ignore-not-found | wc -l` -gt 0 ] ; then
+		CSV=$(oc get subscription kabanero-operator -n $OPERATOR_NS --output=jsonpath={.status.installedCSV})
     if ! [[ "$CSV" =~ ^kabanero-operator\.v0\.[89]\..* ]]; then
         printf "Cannot upgrade kabanero-operator CSV version $CSV to $RELEASE.  Upgrade is supported from the previous minor release."
         exit 1
@@ -272,12 +277,17 @@ unsubscribe eclipse-che kabanero
 oc adm policy add-scc-to-user anyuid system:serviceaccount:kabanero:che-workspace
 oc adm policy add-scc-to-user privileged system:serviceaccount:kabanero:che-workspace
 
+# OLM only installs an operator that serves a conversion webhook in all namespaces, so the
+# kabanero operator is subscribed in openshift-operators.  Remove the subscription of
+# releases before 0.10, which was in the kabanero namespace.
+unsubscribe kabanero-operator kabanero
+
 # Apply codeready-workspaces and kabanero subscriptions.
 oc apply -f $KABANERO_SUBSCRIPTIONS_YAML --selector kabanero.io/install=14-subscription
 
 # Verify Subscriptions
 checksub codeready-workspaces kabanero 
-checksub kabanero-operator kabanero
+checksub kabanero-operator openshift-operators
 
 ### CustomResources
 
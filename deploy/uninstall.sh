@@ -187,7 +187,7 @@ unsubscribe () {
 	fi
 }
 
-unsubscribe kabanero-operator kabanero
+unsubscribe kabanero-operator openshift-operators
 
 unsubscribe serverless-operator openshift-operators
 
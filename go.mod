@@ -3,6 +3,9 @@ module github.com/kabanero-io/kabanero-operator
 go 1.13
 
 require (
+	github.com/Masterminds/sprig/v3 v3.0.2
+	github.com/aws/aws-sdk-go v1.29.34
+	github.com/awslabs/amazon-ecr-credential-helper/ecr-login v0.0.0-20211215200129-69c85dc22db6
 	github.com/blang/semver v3.5.1+incompatible
 	github.com/coreos/go-semver v0.3.0
 	github.com/docker/cli v0.0.0-20200210162036-a4bedce16568
@@ -11,7 +14,7 @@ require (
 	github.com/elsony/devfile2-registry/tools v0.0.0-20200603181527-db339ef8dd30
 	github.com/go-logr/logr v0.1.0
 	github.com/go-openapi/spec v0.19.6
-	github.com/google/go-cmp v0.4.0
+	github.com/google/go-cmp v0.5.6
 	github.com/google/go-containerregistry v0.0.0-20200331213917-3d03ed9b1ca2
 	github.com/google/go-github/v29 v29.0.3
 	github.com/manifestival/controller-runtime-client v0.1.1-0.20200218204725-1af9550ddf8f
@@ -19,10 +22,19 @@ require (
 	github.com/openshift/api v3.9.1-0.20190924102528-32369d4db2ad+incompatible
 	github.com/operator-framework/operator-lifecycle-manager v3.11.0+incompatible
 	github.com/operator-framework/operator-sdk v0.17.1
+	github.com/prometheus/client_golang v1.5.1
 	github.com/spf13/pflag v1.0.5
 	github.com/tektoncd/operator v0.0.0-20191017104520-be5a46fc149a
 	github.com/tektoncd/pipeline v0.10.1
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+	go.uber.org/zap v1.14.1
+	golang.org/x/crypto v0.0.0-20200302210943-78000ba7a073
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	golang.org/x/sync v0.0.0-20200317015054-43a5402ce75a
+	google.golang.org/grpc v1.41.0
 	gopkg.in/yaml.v2 v2.2.8
 	k8s.io/api v0.17.6
 	k8s.io/apiextensions-apiserver v0.17.6
@@ -0,0 +1,43 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// Before the kabaneros CRD had a conversion webhook, the API server converted v1alpha1
+// instances by changing their apiVersion.  Instances still stored as v1alpha1 are converted
+// the same way: the fields with the same name in both versions are kept, unless their
+// types differ.
+
+var _ conversion.Convertible = &Kabanero{}
+
+// ConvertTo converts this Kabanero instance to the hub version, v1alpha2.
+func (src *Kabanero) ConvertTo(dstRaw conversion.Hub) error {
+	return convertByName(src, dstRaw.(*kabanerov1alpha2.Kabanero))
+}
+
+// ConvertFrom converts a Kabanero instance of the hub version, v1alpha2, to this version.
+func (dst *Kabanero) ConvertFrom(srcRaw conversion.Hub) error {
+	return convertByName(srcRaw.(*kabanerov1alpha2.Kabanero), dst)
+}
+
+// Copies the fields of the input object to the fields with the same JSON name in the
+// output object.  The kind and version of the output object are kept.
+func convertByName(src runtime.Object, dst runtime.Object) error {
+	gvk := dst.GetObjectKind().GroupVersionKind()
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, dst); err != nil {
+		if _, typeMismatch := err.(*json.UnmarshalTypeError); !typeMismatch {
+			return err
+		}
+	}
+	dst.GetObjectKind().SetGroupVersionKind(gvk)
+	return nil
+}
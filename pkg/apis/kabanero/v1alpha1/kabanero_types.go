@@ -298,7 +298,7 @@ type AdmissionControllerWebhookStatus struct {
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="CreationTimestamp is a timestamp representing the server time when this object was created. It is not guaranteed to be set in happens-before order across separate operations."
 // +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.kabaneroInstance.version",description="Kabanero operator instance version."
 // +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.kabaneroInstance.ready",description="Kabanero operator instance readiness status. The status is directly correlated to the availability of the operator's resources dependencies."
-// +kubebuilder:resource:path=kabaneros,scope=Namespaced
+// +kubebuilder:resource:path=kabaneros,scope=Namespaced,shortName=kab
 // +kubebuilder:unservedversion
 type Kabanero struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -0,0 +1,10 @@
+package v1alpha2
+
+// v1alpha2 is the storage version of the Stack and Kabanero resources.  The other versions
+// are converted to and from it by the conversion webhook of the operator.
+
+// Hub marks Stack as the type that the other versions of Stack are converted through.
+func (*Stack) Hub() {}
+
+// Hub marks Kabanero as the type that the other versions of Kabanero are converted through.
+func (*Kabanero) Hub() {}
@@ -1,7 +1,15 @@
 package v1alpha2
 
 import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -19,13 +27,59 @@ type KabaneroSpec struct {
 
 	Version string `json:"version,omitempty"`
 
+	// The channel that the software revisions of the components are chosen from.  The
+	// latest revision of each component in the channel is used.  The default is stable.
+	Channel string `json:"channel,omitempty"`
+
 	// +listType=set
 	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
 
+	// The namespaces, other than the namespace of the owning resource, into which
+	// pipeline assets may be created.  If empty, assets may be created in any
+	// namespace preset in their manifest.
+	// +listType=set
+	AllowedAssetNamespaces []string `json:"allowedAssetNamespaces,omitempty"`
+
+	// The kinds of resources, in addition to those in the tekton.dev and
+	// triggers.tekton.dev groups, that pipeline archives may contain.
+	AllowedAssetKinds []AssetGroupKind `json:"allowedAssetKinds,omitempty"`
+
+	// Create the namespaces preset in pipeline asset manifests when they do not
+	// exist.  The namespaces are labelled with the owning Kabanero instance.
+	CreateAssetNamespaces bool `json:"createAssetNamespaces,omitempty"`
+
+	// The namespace that the Tekton triggers run in.  Trigger assets that preset the
+	// tekton-pipelines namespace are created in this namespace instead.  Defaults to
+	// tekton-pipelines.
+	TriggerNamespace string `json:"triggerNamespace,omitempty"`
+
+	// What happens to a pipeline asset when it is no longer used by any stack or
+	// gitops pipeline.  One of Delete (the default), Orphan, or Retain.  A Stack
+	// may override this value.
+	AssetDeletionPolicy string `json:"assetDeletionPolicy,omitempty"`
+
+	// What happens when a pipeline asset was modified after it was applied.  One of
+	// Repair (the default), which applies the asset again, or Report, which marks the
+	// asset as drifted and leaves it alone.
+	AssetDriftPolicy string `json:"assetDriftPolicy,omitempty"`
+
 	Github GithubConfig `json:"github,omitempty"`
 
 	GovernancePolicy GovernancePolicyConfig `json:"governancePolicy,omitempty"`
 
+	// The image scanner that is asked about the images of a stack version before it is
+	// activated.
+	VulnerabilityScan VulnerabilityScanSpec `json:"vulnerabilityScan,omitempty"`
+
+	// How many completed PipelineRuns and TaskRuns of the Kabanero pipelines are kept.
+	PipelineRunRetention PipelineRunRetentionSpec `json:"pipelineRunRetention,omitempty"`
+
+	// The storage bound to the workspaces of the PipelineRuns created by the TriggerTemplates
+	// in pipeline archives, so that archives do not depend on the storage of a cluster.
+	// +listType=map
+	// +listMapKey=name
+	Workspaces []WorkspaceBindingSpec `json:"workspaces,omitempty"`
+
 	Stacks InstanceStackConfig `json:"stacks,omitempty"`
 
 	// +listType=map
@@ -52,15 +106,224 @@ type KabaneroSpec struct {
 	Sso SsoCustomizationSpec `json:"sso,omitempty"`
 
 	Gitops GitopsSpec `json:"gitops,omitempty"`
+
+	ArtifactProxy ArtifactProxySpec `json:"artifactProxy,omitempty"`
+
+	EgressProxy EgressProxySpec `json:"egressProxy,omitempty"`
+
+	Downloads DownloadLimitsSpec `json:"downloads,omitempty"`
+
+	Logging LoggingSpec `json:"logging,omitempty"`
+
+	Tracing TracingSpec `json:"tracing,omitempty"`
+
+	// Maps an image registry to the registry that mirrors it, for example
+	// docker.io: registry.internal:5000.  Stack images are resolved using the mirror.
+	RegistryMirrors map[string]string `json:"registryMirrors,omitempty"`
+
+	// The name of a ConfigMap in the Kabanero namespace, whose ca-bundle.crt entry holds
+	// additional CA certificates that are trusted when connecting to image registries.
+	RegistryCABundleConfigMap string `json:"registryCABundleConfigMap,omitempty"`
+
+	// The name of a ConfigMap in the Kabanero namespace, whose entries are made available
+	// to the templates in pipeline archives.  Values set by the operator take precedence.
+	RenderingContextConfigMap string `json:"renderingContextConfigMap,omitempty"`
+
+	// Where the pods of the deployments created by the operator are scheduled.
+	Scheduling SchedulingSpec `json:"scheduling,omitempty"`
+
+	// Labels added to the resources the operator creates for the instance, and to the
+	// pipeline assets.  For example, cost allocation or backup selectors.
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+
+	// Annotations added to the resources the operator creates for the instance, and to
+	// the pipeline assets.
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+}
+
+// Returns true if each entry of the input labels has a valid label key and value.
+func IsValidCommonLabels(labels map[string]string) bool {
+	for key, value := range labels {
+		if len(validation.IsQualifiedName(key)) != 0 || len(validation.IsValidLabelValue(value)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if each entry of the input annotations has a valid annotation key.
+func IsValidCommonAnnotations(annotations map[string]string) bool {
+	for key := range annotations {
+		if len(validation.IsQualifiedName(strings.ToLower(key))) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SchedulingSpec defines the scheduling constraints added to the pod template of each
+// Deployment the operator creates, for example to run the components on infra nodes.
+type SchedulingSpec struct {
+	// Added to the node selector of the pods.  An entry replaces an entry with the same
+	// key in the manifest.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Added to the tolerations of the pods.
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Replaces the affinity of the pods.
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+}
+
+// The ConfigMap key that holds the registry CA certificates.
+const RegistryCABundleKey = "ca-bundle.crt"
+
+// ArtifactProxySpec defines an in-cluster caching proxy that stack index and pipeline
+// archive downloads are routed through.  A request for https://host/path is sent to
+// <url>/https/host/path.
+type ArtifactProxySpec struct {
+	Url string `json:"url,omitempty"`
+
+	// The name of a secret in the Kabanero namespace, whose ca.crt entry is trusted
+	// when connecting to the proxy.
+	CaSecretName string `json:"caSecretName,omitempty"`
+
+	SkipCertVerification bool `json:"skipCertVerification,omitempty"`
+
+	// Whether the credentials of a download are sent to the proxy.  By default the
+	// Authorization header is removed from proxied requests.
+	ForwardCredentials bool `json:"forwardCredentials,omitempty"`
+}
+
+// EgressProxySpec defines the forward proxy that stack index, pipeline archive and image
+// registry traffic leaves the cluster through.  When no field is set, the proxy of the
+// OpenShift cluster Proxy resource is used.
+type EgressProxySpec struct {
+	// The proxy used for http requests, for example http://proxy.example.com:3128.
+	HttpProxy string `json:"httpProxy,omitempty"`
+
+	// The proxy used for https requests.
+	HttpsProxy string `json:"httpsProxy,omitempty"`
+
+	// A comma separated list of hosts, domains, IP addresses and CIDRs that are
+	// connected to directly.  A domain also matches its subdomains, and the value *
+	// disables the proxy.
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
+// LoggingSpec defines which operator components log at which level.
+type LoggingSpec struct {
+	// The trace specification, a colon separated list of component=level entries, for
+	// example *=info:stack=debug:controller_kabaneroplatform=trace.  The component is
+	// the name of a logger, or * for every logger, and the level is one of error, info,
+	// debug and trace.  Entries of the kabanero-operator-trace ConfigMap take precedence.
+	TraceSpec string `json:"traceSpec,omitempty"`
+}
+
+// TracingSpec defines the OpenTelemetry collector that the operator exports the spans of
+// its stack reconciles to, over OTLP gRPC.
+type TracingSpec struct {
+	// The host and port of the collector, for example otel-collector.observability:4317.
+	// Spans are not recorded when no endpoint is set.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// When true, the connection to the collector does not use TLS.
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// DownloadLimitsSpec limits the number of stack index and pipeline archive downloads
+// that run at the same time, across all stacks, and their size.  Zero means the default
+// is used.
+type DownloadLimitsSpec struct {
+	// The maximum number of simultaneous downloads.  The default is 10.
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+
+	// The maximum number of simultaneous downloads from a single host.  The default is 4.
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentPerHost int `json:"maxConcurrentPerHost,omitempty"`
+
+	// The maximum size, in bytes, of a downloaded stack index or pipeline archive.  The
+	// default is 50 MiB.
+	// +kubebuilder:validation:Minimum=0
+	MaxDownloadSize int64 `json:"maxDownloadSize,omitempty"`
+
+	// The maximum size, in bytes, of a single file in a pipeline archive.  The default
+	// is 5 MiB.
+	// +kubebuilder:validation:Minimum=0
+	MaxArchiveFileSize int64 `json:"maxArchiveFileSize,omitempty"`
+
+	// The maximum size, in bytes, of the files in a pipeline archive once extracted.  The
+	// default is 100 MiB.
+	// +kubebuilder:validation:Minimum=0
+	MaxArchiveExtractedSize int64 `json:"maxArchiveExtractedSize,omitempty"`
+
+	// The maximum total size, in bytes, of the downloads kept in the download cache.  The
+	// least recently used downloads are evicted first.  The default is 256 MiB.
+	// +kubebuilder:validation:Minimum=0
+	MaxCacheSize int64 `json:"maxCacheSize,omitempty"`
+
+	// The maximum number of downloads kept in the download cache.  The default is 500.
+	// +kubebuilder:validation:Minimum=0
+	MaxCacheEntries int `json:"maxCacheEntries,omitempty"`
+
+	// How long to wait for a connection to be made, and for the TLS handshake, when
+	// downloading.  Defaults to 30 seconds.
+	ConnectTimeout *metav1.Duration `json:"connectTimeout,omitempty"`
+
+	// How long to wait for a download to complete once connected.  Defaults to 5 minutes.
+	ReadTimeout *metav1.Duration `json:"readTimeout,omitempty"`
+
+	Retry DownloadRetrySpec `json:"retry,omitempty"`
+}
+
+// DownloadRetrySpec defines how a download that fails with a transient error is retried.
+// Zero means the default is used.
+type DownloadRetrySpec struct {
+	// The maximum number of attempts at each download.  The default is 3.  A value of 1
+	// disables retries.
+	// +kubebuilder:validation:Minimum=0
+	Attempts int `json:"attempts,omitempty"`
+
+	// The percentage by which the wait between two attempts is randomly lengthened or
+	// shortened.  The default is 20.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	JitterPercent int `json:"jitterPercent,omitempty"`
+
+	// The response status codes that are retried.  Each entry is a code, such as 503, or
+	// a class, such as 5xx.  The default is 408, 429 and 5xx.
+	// +listType=set
+	RetryOnStatus []string `json:"retryOnStatus,omitempty"`
 }
 
+// Returns true if each entry of the input retry status list is a status code or class.
+func IsValidDownloadRetry(retry DownloadRetrySpec) bool {
+	for _, status := range retry.RetryOnStatus {
+		if !retryStatusRegexp.MatchString(status) {
+			return false
+		}
+	}
+	return true
+}
+
+var retryStatusRegexp = regexp.MustCompile(`^[1-5]([0-9][0-9]|[xX][xX])$`)
+
 type GitopsSpec struct {
+	// Set to false to remove the gitops pipelines and their assets.  Defaults to true.
+	Enable *bool `json:"enable,omitempty"`
+
 	// +listType=map
 	// +listMapKey=id
 	// +listMapKey=sha256
 	Pipelines []PipelineSpec `json:"pipelines,omitempty"`
 }
 
+// Returns true unless the gitops pipelines were disabled.
+func (gs GitopsSpec) IsEnabled() bool {
+	return gs.Enable == nil || *gs.Enable
+}
+
 func (gs GitopsSpec) GetVersions() []ComponentSpecVersion {
 	return []ComponentSpecVersion{gs}
 }
@@ -73,14 +336,147 @@ func (gs GitopsSpec) GetPipelines() []PipelineSpec {
 	return gs.Pipelines
 }
 
+const (
+	// Stack conflict policy: the first repository listing a stack version wins.
+	StackConflictPolicyFirstWins = "first-wins"
+
+	// Stack conflict policy: a stack version listed by more than one repository is an error.
+	StackConflictPolicyError = "error"
+
+	// Stack conflict policy prefix: the named repository wins.  For example, prefer-repository:incubator.
+	StackConflictPolicyPreferRepositoryPrefix = "prefer-repository:"
+)
+
+// A kind of resource that pipeline archives may contain.
+type AssetGroupKind struct {
+	// The API group of the resource.  Empty for the core group.
+	Group string `json:"group,omitempty"`
+	// The kind of the resource.  If empty, all kinds in the group are allowed.
+	Kind string `json:"kind,omitempty"`
+}
+
+// The namespace that the Tekton triggers run in, unless the Kabanero instance says otherwise.
+const DefaultTriggerNamespace = "tekton-pipelines"
+
+// The API groups whose resources pipeline archives may always contain.
+var DefaultAllowedAssetGroups = []string{"tekton.dev", "triggers.tekton.dev"}
+
+// Returns true if pipeline archives may contain resources of the input group and
+// kind.  Resources in the default groups are always allowed.
+func IsAssetGroupKindAllowed(group string, kind string, allowedKinds []AssetGroupKind) bool {
+	for _, allowedGroup := range DefaultAllowedAssetGroups {
+		if group == allowedGroup {
+			return true
+		}
+	}
+
+	for _, allowed := range allowedKinds {
+		if allowed.Group == group && (len(allowed.Kind) == 0 || allowed.Kind == kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The kinds, outside of the default groups, that the stack controller is granted access
+// to.  An entry without a kind covers all kinds in its group.  Assets of other kinds could
+// not be applied, so they cannot be allowed.
+var ManageableAssetKinds = []AssetGroupKind{
+	{Group: "", Kind: "ConfigMap"},
+	{Group: "", Kind: "PersistentVolumeClaim"},
+	{Group: "", Kind: "Secret"},
+	{Group: "", Kind: "Service"},
+	{Group: "", Kind: "ServiceAccount"},
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "appsody.dev"},
+}
+
+// Returns true if the stack controller is granted access to the kinds covered by the
+// input allowed asset kind.
+func IsAssetGroupKindManageable(allowed AssetGroupKind) bool {
+	for _, manageable := range ManageableAssetKinds {
+		if manageable.Group == allowed.Group && (len(manageable.Kind) == 0 || manageable.Kind == allowed.Kind) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// Asset deletion policy: the asset is deleted.
+	AssetDeletionPolicyDelete = "Delete"
+
+	// Asset deletion policy: the asset is left in place, and is no longer tracked.
+	AssetDeletionPolicyOrphan = "Orphan"
+
+	// Asset deletion policy: the asset is left in place, and is labelled as inactive.
+	AssetDeletionPolicyRetain = "Retain"
+)
+
+// Returns true if the input asset deletion policy is valid.  An empty policy is valid.
+func IsValidAssetDeletionPolicy(policy string) bool {
+	switch policy {
+	case "", AssetDeletionPolicyDelete, AssetDeletionPolicyOrphan, AssetDeletionPolicyRetain:
+		return true
+	}
+	return false
+}
+
+const (
+	// Asset drift policy: a modified asset is applied again from its manifest.
+	AssetDriftPolicyRepair = "Repair"
+
+	// Asset drift policy: a modified asset is left alone, and its status is drifted.
+	AssetDriftPolicyReport = "Report"
+)
+
+// Returns true if the input asset drift policy is valid.  An empty policy is valid.
+func IsValidAssetDriftPolicy(policy string) bool {
+	switch policy {
+	case "", AssetDriftPolicyRepair, AssetDriftPolicyReport:
+		return true
+	}
+	return false
+}
+
 // InstanceStackConfig defines the customization entries for a set of stacks.
 type InstanceStackConfig struct {
 	SkipRegistryCertVerification bool `json:"skipRegistryCertVerification,omitempty"`
 
+	// How to resolve a stack version that is listed by more than one repository.  One of
+	// first-wins (the default), error, or prefer-repository:<repository name>.
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+
 	// +listType=map
 	// +listMapKey=name
 	Repositories []RepositoryConfig `json:"repositories,omitempty"`
 
+	// The names of secrets in the Kabanero namespace that hold image registry
+	// credentials.  They are tried in order, after any listed by a stack version.
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+
+	// How long a resolved repository index is cached.  Defaults to 5 minutes.  A value of
+	// zero disables the cache.
+	IndexCacheTTL *metav1.Duration `json:"indexCacheTTL,omitempty"`
+
+	// How often the image tags of active stack versions are checked, to see whether they
+	// still refer to the digests they had when the versions were activated.  Defaults to
+	// 1 hour.  A value of zero disables the check.
+	DigestDriftCheckInterval *metav1.Duration `json:"digestDriftCheckInterval,omitempty"`
+
+	// How long the digest that an image tag refers to is cached, so that stack versions
+	// sharing images do not repeat the same registry requests.  Defaults to 5 minutes.  A
+	// value of zero disables the cache.
+	DigestCacheTTL *metav1.Duration `json:"digestCacheTTL,omitempty"`
+
+	// A cron expression, such as "0 2 * * *" for 02:00 UTC every day, for when the
+	// repository indexes are re-read and the image tags of active stack versions are
+	// checked for drift.  This is in addition to the index cache TTL and the drift
+	// check interval, so setting them to long durations confines refreshes to the
+	// schedule.
+	RefreshSchedule string `json:"refreshSchedule,omitempty"`
+
 	// +listType=map
 	// +listMapKey=id
 	// +listMapKey=sha256
@@ -89,10 +485,148 @@ type InstanceStackConfig struct {
 
 // PipelineSpec defines a set of pipelines and associated resources for a component.
 type PipelineSpec struct {
-	Id         string            `json:"id,omitempty"`
+	Id string `json:"id,omitempty"`
+	// The digest of the pipeline archive.  A sha256 or sha512 hex digest, optionally
+	// prefixed with its algorithm, as in sha512:<digest>.
 	Sha256     string            `json:"sha256,omitempty"`
 	Https      HttpsProtocolFile `json:"https,omitempty"`
 	GitRelease GitReleaseSpec    `json:"gitRelease,omitempty"`
+	// A Tekton bundle holding the pipeline assets.  When set, the sha256 is the digest of
+	// the bundle's image manifest.
+	Oci OciBundleSpec `json:"oci,omitempty"`
+	// How the manifests in the pipeline archive are rendered.  One of directive (the
+	// default), which only processes Kabanero directives, or gotemplate, which processes
+	// the manifests as Go templates with the sprig functions before processing the directives.
+	Renderer string `json:"renderer,omitempty"`
+	// When set, a ServiceAccount, Role and RoleBinding are created for the runs of the
+	// pipeline in the namespace of its assets.
+	ServiceAccount *PipelineServiceAccountSpec `json:"serviceAccount,omitempty"`
+	// When set, the pipeline archive is verified against this detached signature before
+	// its manifests are read.
+	Signature *PipelineSignatureSpec `json:"signature,omitempty"`
+}
+
+// PipelineSignatureSpec defines where to retrieve the detached signature of a pipeline
+// archive, and the public key that verifies it.
+type PipelineSignatureSpec struct {
+	// The format of the signature.  One of gpg (the default), an armored or binary OpenPGP
+	// signature, or cosign, a base64 encoded ECDSA signature over the sha256 of the archive.
+	Format     string            `json:"format,omitempty"`
+	Https      HttpsProtocolFile `json:"https,omitempty"`
+	GitRelease GitReleaseSpec    `json:"gitRelease,omitempty"`
+	// The secret holding the public key, in the namespace the pipeline is activated in.
+	KeySecretRef SignatureKeySecretRef `json:"keySecretRef,omitempty"`
+}
+
+// SignatureKeySecretRef identifies the secret holding a public key.
+type SignatureKeySecretRef struct {
+	Name string `json:"name,omitempty"`
+	// The key of the public key in the secret data.  The default is publicKey.
+	Key string `json:"key,omitempty"`
+}
+
+// OciBundleSpec defines how to pull a Tekton bundle from an OCI registry.
+type OciBundleSpec struct {
+	// The reference of the bundle image, such as quay.io/kabanero/java-pipelines:0.9.0.
+	Bundle               string `json:"bundle,omitempty"`
+	SkipCertVerification bool   `json:"skipCertVerification,omitempty"`
+}
+
+// The prefix of the URL that a pipeline pulled from an OCI registry is tracked by in
+// the pipeline status.
+const OciBundleUrlPrefix = "oci://"
+
+// Returns the URL that the archive of the pipeline is tracked by.  A Tekton bundle is
+// tracked by its reference, prefixed with oci://.
+func (pipeline PipelineSpec) ArchiveUrl() string {
+	if len(pipeline.Oci.Bundle) != 0 {
+		return OciBundleUrlPrefix + pipeline.Oci.Bundle
+	}
+	return pipeline.Https.Url
+}
+
+// Returns true if certificate verification is skipped when the archive of the pipeline
+// is retrieved.
+func (pipeline PipelineSpec) SkipCertVerification() bool {
+	switch {
+	case pipeline.GitRelease.IsUsable():
+		return pipeline.GitRelease.SkipCertVerification
+	case len(pipeline.Oci.Bundle) != 0:
+		return pipeline.Oci.SkipCertVerification
+	}
+	return pipeline.Https.SkipCertVerification
+}
+
+// PipelineServiceAccountSpec defines the identity of the runs of a pipeline.
+type PipelineServiceAccountSpec struct {
+	// The name of the ServiceAccount, Role and RoleBinding.  Defaults to the name of the
+	// stack, or Kabanero instance, followed by the pipeline id.
+	Name string `json:"name,omitempty"`
+	// The names of the secrets used to pull the images of the pipeline runs.
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+}
+
+const (
+	// Pipeline renderer: the manifests are processed by the Kabanero directive processor.
+	PipelineRendererDirective = "directive"
+
+	// Pipeline renderer: the manifests are processed as Go templates, then by the
+	// Kabanero directive processor.
+	PipelineRendererGoTemplate = "gotemplate"
+)
+
+// Returns true if the input pipeline renderer is valid.  An empty renderer is valid.
+func IsValidPipelineRenderer(renderer string) bool {
+	switch renderer {
+	case "", PipelineRendererDirective, PipelineRendererGoTemplate:
+		return true
+	}
+	return false
+}
+
+const (
+	// Pipeline signature format: an armored or binary OpenPGP detached signature.
+	PipelineSignatureFormatGpg = "gpg"
+
+	// Pipeline signature format: a base64 encoded ECDSA signature, as written by cosign sign-blob.
+	PipelineSignatureFormatCosign = "cosign"
+
+	// The key of the public key in the signature key secret, when none is specified.
+	DefaultSignatureKeySecretKey = "publicKey"
+)
+
+// Returns true if the input pipeline signature is valid.  The signature, when set, must
+// have a known format, a location and a public key secret.
+func IsValidPipelineSignature(sig *PipelineSignatureSpec) bool {
+	if sig == nil {
+		return true
+	}
+	switch sig.Format {
+	case "", PipelineSignatureFormatGpg, PipelineSignatureFormatCosign:
+	default:
+		return false
+	}
+	if len(sig.Https.Url) == 0 && !sig.GitRelease.IsUsable() {
+		return false
+	}
+	return len(sig.KeySecretRef.Name) != 0
+}
+
+// Returns true if the input pipeline service account is valid.  The name, when set, must
+// be usable as the name of a ServiceAccount, Role and RoleBinding.
+func IsValidPipelineServiceAccount(sa *PipelineServiceAccountSpec) bool {
+	if sa == nil || len(sa.Name) == 0 {
+		return true
+	}
+	return len(validation.IsDNS1123Subdomain(sa.Name)) == 0
+}
+
+var pipelineDigestRegexp = regexp.MustCompile(`^(?i)((sha256:)?[0-9a-f]{64}|(sha512:)?[0-9a-f]{128})$`)
+
+// Returns true if the input pipeline digest is a sha256 or sha512 hex digest, optionally
+// prefixed with its algorithm.  An empty digest is valid.
+func IsValidPipelineSha256(digest string) bool {
+	return len(digest) == 0 || pipelineDigestRegexp.MatchString(digest)
 }
 
 // HttpsProtocolFile defines how to retrieve a file over https
@@ -123,6 +657,260 @@ type GithubConfig struct {
 // GovernancePolicyConfig defines customization entries for governance policies.
 type GovernancePolicyConfig struct {
 	StackPolicy string `json:"stackPolicy,omitempty"`
+	// The registries that the images of active stack versions may come from, as in
+	// docker.io or quay.io.  Any registry is allowed when the list is empty.
+	// +listType=set
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+	// When true, every pipeline of an active stack version must set a sha256, so that
+	// the pipeline archive is pinned to a digest.
+	RequireDigests bool `json:"requireDigests,omitempty"`
+	// The ids of the stacks whose versions may not be activated.
+	// +listType=set
+	BannedStackIds []string `json:"bannedStackIds,omitempty"`
+	// The maximum number of active versions of each stack.  The most recent versions
+	// are kept active.  Zero means no limit.
+	MaxActiveVersions int `json:"maxActiveVersions,omitempty"`
+	// What happens to the versions beyond maxActiveVersions.  One of Refuse (the default),
+	// which does not activate them and reports them as errors, or DeactivateOldest, which
+	// sets their desired state to inactive.
+	MaxActiveVersionsPolicy string `json:"maxActiveVersionsPolicy,omitempty"`
+}
+
+const (
+	// Maximum active versions policy: the versions beyond the maximum are not activated,
+	// and their status is error.
+	MaxActiveVersionsPolicyRefuse = "Refuse"
+
+	// Maximum active versions policy: the desired state of the versions beyond the
+	// maximum is set to inactive.
+	MaxActiveVersionsPolicyDeactivateOldest = "DeactivateOldest"
+)
+
+// Returns true if the input maximum active versions policy is valid.  An empty policy is valid.
+func IsValidMaxActiveVersionsPolicy(policy string) bool {
+	switch policy {
+	case "", MaxActiveVersionsPolicyRefuse, MaxActiveVersionsPolicyDeactivateOldest:
+		return true
+	}
+	return false
+}
+
+// Returns true if the policy constrains the versions of a stack beyond the digest policy.
+func (p GovernancePolicyConfig) HasStackRules() bool {
+	return len(p.AllowedRegistries) != 0 || p.RequireDigests || len(p.BannedStackIds) != 0 || p.MaxActiveVersions > 0
+}
+
+// VulnerabilityScanSpec defines the scanner that reports the vulnerabilities of the images
+// of active stack versions.  Images are referenced by digest.  The scanner is either the
+// security scan of a Quay registry, which is done by Clair, or the Trivy scanner adapter,
+// which serves the Harbor pluggable scanner API.
+type VulnerabilityScanSpec struct {
+	// The URL of the scanner: the base URL of the Quay registry that holds the images, such
+	// as https://quay.io, or of the Trivy scanner adapter.  Images are not scanned when empty.
+	Url string `json:"url,omitempty"`
+
+	// The kind of scanner at the URL.  One of Quay (the default) or Trivy.
+	Scanner string `json:"scanner,omitempty"`
+
+	// The name of a secret in the Kabanero namespace, whose token entry is sent to the
+	// scanner as a bearer token.
+	TokenSecretName string `json:"tokenSecretName,omitempty"`
+
+	SkipCertVerification bool `json:"skipCertVerification,omitempty"`
+
+	// The maximum number of critical vulnerabilities that an image of an active stack
+	// version may have.  The default is zero.
+	MaxCriticalVulnerabilities int `json:"maxCriticalVulnerabilities,omitempty"`
+
+	// What happens to a version whose images have more critical vulnerabilities than
+	// allowed.  One of Block (the default), which does not activate the version, or Warn,
+	// which activates it and reports it in the Vulnerable condition of the stack.
+	Action string `json:"action,omitempty"`
+
+	// What happens to a version whose images could not be scanned.  One of Fail (the
+	// default), which does not activate the version, or Ignore, which activates it and
+	// reports it in the Vulnerable condition of the stack.
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+const (
+	// Vulnerability scanner: the security scan of a Quay registry, which is done by Clair.
+	VulnerabilityScannerQuay = "Quay"
+
+	// Vulnerability scanner: the Trivy scanner adapter (harbor-scanner-trivy).
+	VulnerabilityScannerTrivy = "Trivy"
+)
+
+const (
+	// Vulnerability scan action: versions with too many critical vulnerabilities are not activated.
+	VulnerabilityScanActionBlock = "Block"
+
+	// Vulnerability scan action: versions with too many critical vulnerabilities are reported.
+	VulnerabilityScanActionWarn = "Warn"
+)
+
+const (
+	// Vulnerability scan failure policy: versions whose images could not be scanned are not activated.
+	VulnerabilityScanFailurePolicyFail = "Fail"
+
+	// Vulnerability scan failure policy: versions whose images could not be scanned are activated.
+	VulnerabilityScanFailurePolicyIgnore = "Ignore"
+)
+
+// Returns true if a scanner is configured.
+func (s VulnerabilityScanSpec) IsEnabled() bool {
+	return len(s.Url) != 0
+}
+
+// Returns true if the scanner URL is an http or https URL, the scanner, action and failure
+// policy are among the allowed values, and the maximum is not negative.  An empty
+// configuration is valid.
+func IsValidVulnerabilityScan(s VulnerabilityScanSpec) bool {
+	if len(s.Url) != 0 {
+		scanUrl, err := url.Parse(s.Url)
+		if err != nil || (scanUrl.Scheme != "http" && scanUrl.Scheme != "https") || len(scanUrl.Host) == 0 {
+			return false
+		}
+	}
+	switch s.Scanner {
+	case "", VulnerabilityScannerQuay, VulnerabilityScannerTrivy:
+	default:
+		return false
+	}
+	switch s.Action {
+	case "", VulnerabilityScanActionBlock, VulnerabilityScanActionWarn:
+	default:
+		return false
+	}
+	switch s.FailurePolicy {
+	case "", VulnerabilityScanFailurePolicyFail, VulnerabilityScanFailurePolicyIgnore:
+	default:
+		return false
+	}
+	return s.MaxCriticalVulnerabilities >= 0
+}
+
+// PipelineRunRetentionSpec defines which completed runs of the pipelines and tasks created
+// by Kabanero are deleted.  A run is deleted when either limit is exceeded.  Runs that
+// have not completed are never deleted.
+type PipelineRunRetentionSpec struct {
+	// The number of completed runs kept for each pipeline or task.  Zero keeps all of them.
+	MaxCount int `json:"maxCount,omitempty"`
+
+	// How long completed runs are kept, as a duration such as 72h.  Empty keeps them
+	// regardless of their age.
+	MaxAge string `json:"maxAge,omitempty"`
+}
+
+// Returns true if completed runs are deleted.
+func (r PipelineRunRetentionSpec) IsEnabled() bool {
+	return r.MaxCount > 0 || len(r.MaxAge) != 0
+}
+
+// Returns the maximum age of completed runs, or zero if they are kept regardless of age.
+func (r PipelineRunRetentionSpec) GetMaxAge() (time.Duration, error) {
+	if len(r.MaxAge) == 0 {
+		return 0, nil
+	}
+	return time.ParseDuration(r.MaxAge)
+}
+
+// Returns true if the maximum count is not negative, and the maximum age is a positive
+// duration.  An empty configuration is valid.
+func IsValidPipelineRunRetention(r PipelineRunRetentionSpec) bool {
+	if r.MaxCount < 0 {
+		return false
+	}
+	if len(r.MaxAge) != 0 {
+		maxAge, err := r.GetMaxAge()
+		if err != nil || maxAge <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WorkspaceBindingSpec defines the PersistentVolumeClaim template bound to a workspace of
+// the PipelineRuns created by TriggerTemplates.  A workspace is only bound when the
+// PipelineRun binds it to an emptyDir, or to no volume.
+type WorkspaceBindingSpec struct {
+	// The name of the workspace, as bound by the PipelineRun.
+	Name string `json:"name"`
+
+	// The storage class of the claim.  When empty, the default storage class of the
+	// cluster is used.
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// The size of the claim, such as 5Gi.  The default is 1Gi.
+	Size string `json:"size,omitempty"`
+
+	// The access mode of the claim.  The default is ReadWriteOnce.
+	AccessMode string `json:"accessMode,omitempty"`
+}
+
+// The defaults of a workspace binding.
+const (
+	DefaultWorkspaceSize       = "1Gi"
+	DefaultWorkspaceAccessMode = string(corev1.ReadWriteOnce)
+)
+
+// Returns the size of the claim, or the default size.
+func (w WorkspaceBindingSpec) GetSize() string {
+	if len(w.Size) == 0 {
+		return DefaultWorkspaceSize
+	}
+	return w.Size
+}
+
+// Returns the access mode of the claim, or the default access mode.
+func (w WorkspaceBindingSpec) GetAccessMode() string {
+	if len(w.AccessMode) == 0 {
+		return DefaultWorkspaceAccessMode
+	}
+	return w.AccessMode
+}
+
+// Returns true if every input workspace binding has a unique name, a valid size and a
+// valid access mode.
+func IsValidWorkspaceBindings(bindings []WorkspaceBindingSpec) bool {
+	names := make(map[string]bool)
+	for _, binding := range bindings {
+		if len(binding.Name) == 0 || names[binding.Name] {
+			return false
+		}
+		names[binding.Name] = true
+
+		size, err := resource.ParseQuantity(binding.GetSize())
+		if err != nil || size.Sign() <= 0 {
+			return false
+		}
+
+		switch corev1.PersistentVolumeAccessMode(binding.GetAccessMode()) {
+		case corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the input workspace bindings, with the overrides applied on top by name.
+func MergeWorkspaceBindings(bindings []WorkspaceBindingSpec, overrides []WorkspaceBindingSpec) []WorkspaceBindingSpec {
+	if len(overrides) == 0 {
+		return bindings
+	}
+
+	merged := []WorkspaceBindingSpec{}
+	overridden := make(map[string]bool)
+	for _, override := range overrides {
+		overridden[override.Name] = true
+	}
+	for _, binding := range bindings {
+		if !overridden[binding.Name] {
+			merged = append(merged, binding)
+		}
+	}
+	return append(merged, overrides...)
 }
 
 // RepositoryConfig defines customization entries for a stack.
@@ -134,6 +922,90 @@ type RepositoryConfig struct {
 	Pipelines  []PipelineSpec    `json:"pipelines,omitempty"`
 	Https      HttpsProtocolFile `json:"https,omitempty"`
 	GitRelease GitReleaseSpec    `json:"gitRelease,omitempty"`
+	// A ConfigMap in the Kabanero namespace that contains the stack index.  Used
+	// when neither gitRelease nor https are specified.
+	ConfigMapRef ConfigMapReference `json:"configMapRef,omitempty"`
+	// An OCI image that contains the stack index.  Used when neither gitRelease,
+	// configMapRef nor https are specified.
+	Oci OciIndexSpec `json:"oci,omitempty"`
+	// The name of the resolver that reads the stack index: https, git, oci, configmap,
+	// or a resolver compiled into the operator by a distribution.  When empty, the
+	// resolver is chosen from the fields that are set.
+	Resolver string `json:"resolver,omitempty"`
+	// Settings read by the resolver.  The built-in resolvers do not use them.
+	ResolverParameters map[string]string `json:"resolverParameters,omitempty"`
+}
+
+// The names of the built-in stack index resolvers.
+const (
+	IndexResolverHttps     = "https"
+	IndexResolverGit       = "git"
+	IndexResolverOci       = "oci"
+	IndexResolverConfigMap = "configmap"
+)
+
+// Returns true if the repository has the settings of the built-in resolver it names.
+// Other resolver names are not checked, since distributions may compile in resolvers.
+func (r RepositoryConfig) HasResolverSettings() bool {
+	switch r.Resolver {
+	case IndexResolverHttps:
+		return len(r.Https.Url) != 0
+	case IndexResolverGit:
+		return r.GitRelease.IsUsable()
+	case IndexResolverOci:
+		return r.Oci.IsUsable()
+	case IndexResolverConfigMap:
+		return r.ConfigMapRef.IsUsable()
+	}
+	return true
+}
+
+// OciIndexSpec defines how to pull a stack index from an OCI registry.
+type OciIndexSpec struct {
+	// The reference of the image, such as quay.io/kabanero/stacks-index:0.9.0.
+	Image string `json:"image,omitempty"`
+	// The path of the index in the image.  Defaults to index.yaml.
+	Path                 string `json:"path,omitempty"`
+	SkipCertVerification bool   `json:"skipCertVerification,omitempty"`
+}
+
+// The default path of the stack index in an OCI image.
+const DefaultOciIndexPath = "index.yaml"
+
+// Returns true if the user specified an image.
+func (oci OciIndexSpec) IsUsable() bool {
+	return len(oci.Image) != 0
+}
+
+// Returns the path of the index in the image, or the default path.
+func (oci OciIndexSpec) GetPath() string {
+	if len(oci.Path) == 0 {
+		return DefaultOciIndexPath
+	}
+	return oci.Path
+}
+
+// The default ConfigMap key that holds a stack index.
+const DefaultConfigMapIndexKey = "index.yaml"
+
+// ConfigMapReference identifies a ConfigMap entry.
+type ConfigMapReference struct {
+	Name string `json:"name,omitempty"`
+	// The key of the entry.  Defaults to index.yaml.
+	Key string `json:"key,omitempty"`
+}
+
+// Returns true if the user specified a ConfigMap name.
+func (ref ConfigMapReference) IsUsable() bool {
+	return len(ref.Name) != 0
+}
+
+// Returns the key of the ConfigMap entry, or the default key.
+func (ref ConfigMapReference) GetKey() string {
+	if len(ref.Key) == 0 {
+		return DefaultConfigMapIndexKey
+	}
+	return ref.Key
 }
 
 // GitReleaseSpec defines customization entries for a Git release.
@@ -154,21 +1026,126 @@ func (gitRelease GitReleaseSpec) IsUsable() bool {
 
 // KabaneroCliServicesCustomizationSpec defines customization entries for the Kabanero CLI.
 type KabaneroCliServicesCustomizationSpec struct {
-	//Future: Enable     bool   `json:"enable,omitempty"`
-	Version                  string `json:"version,omitempty"`
-	Image                    string `json:"image,omitempty"`
-	Repository               string `json:"repository,omitempty"`
-	Tag                      string `json:"tag,omitempty"`
-	SessionExpirationSeconds string `json:"sessionExpirationSeconds,omitempty"`
+	Enable                   *bool                       `json:"enable,omitempty"`
+	Version                  string                      `json:"version,omitempty"`
+	Image                    string                      `json:"image,omitempty"`
+	Repository               string                      `json:"repository,omitempty"`
+	Tag                      string                      `json:"tag,omitempty"`
+	SessionExpirationSeconds string                      `json:"sessionExpirationSeconds,omitempty"`
+	Resources                corev1.ResourceRequirements `json:"resources,omitempty"`
+	// An OpenID Connect provider that authenticates CLI users, instead of GitHub.
+	Oidc *CliOidcSpec `json:"oidc,omitempty"`
+	// The name of a secret in the Kabanero namespace, whose tls.crt and tls.key entries
+	// are presented by the CLI route instead of the default certificate of the cluster.
+	// An optional ca.crt entry holds the CA certificate chain.
+	TlsSecretName string `json:"tlsSecretName,omitempty"`
+	// The number of CLI service pods.  The default is 1.  When there is more than one,
+	// a PodDisruptionBudget keeps one of them available while nodes are drained.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// The hostname and TLS policy of the CLI route.
+	Route RouteCustomizationSpec `json:"route,omitempty"`
+}
+
+// Returns true unless the CLI services were disabled.
+func (cs KabaneroCliServicesCustomizationSpec) IsEnabled() bool {
+	return cs.Enable == nil || *cs.Enable
+}
+
+// CliOidcSpec defines the OpenID Connect provider that authenticates CLI users.
+type CliOidcSpec struct {
+	// The issuer URL of the provider, such as https://sso.example.com/auth/realms/kabanero.
+	IssuerUrl string `json:"issuerUrl,omitempty"`
+	ClientId  string `json:"clientId,omitempty"`
+	// The secret holding the client secret, in the Kabanero instance namespace.
+	ClientSecretRef CliOidcClientSecretRef `json:"clientSecretRef,omitempty"`
+	// The claim of the ID token that lists the groups of the user.  The default is groups.
+	GroupsClaim string `json:"groupsClaim,omitempty"`
+	// The groups whose members are bound to the CLI admin role.
+	// +listType=set
+	AdminGroups []string `json:"adminGroups,omitempty"`
+}
+
+// CliOidcClientSecretRef identifies the secret holding an OpenID Connect client secret.
+type CliOidcClientSecretRef struct {
+	Name string `json:"name,omitempty"`
+	// The key of the client secret in the secret data.  The default is clientSecret.
+	Key string `json:"key,omitempty"`
+}
+
+const (
+	// The claim that lists the groups of a CLI user, when none is specified.
+	DefaultCliOidcGroupsClaim = "groups"
+
+	// The key of the client secret in the OpenID Connect client secret, when none is specified.
+	DefaultCliOidcClientSecretKey = "clientSecret"
+)
+
+// Returns true if the input CLI OpenID Connect provider is valid.  The provider, when set,
+// must have an https issuer URL, a client ID and a client secret.
+func IsValidCliOidc(oidc *CliOidcSpec) bool {
+	if oidc == nil {
+		return true
+	}
+	issuerUrl, err := url.Parse(oidc.IssuerUrl)
+	if err != nil || issuerUrl.Scheme != "https" || len(issuerUrl.Host) == 0 {
+		return false
+	}
+	return len(oidc.ClientId) != 0 && len(oidc.ClientSecretRef.Name) != 0
 }
 
 // KabaneroLandingCustomizationSpec defines customization entries for Kabanero landing page.
 type KabaneroLandingCustomizationSpec struct {
-	Enable     *bool  `json:"enable,omitempty"`
-	Version    string `json:"version,omitempty"`
-	Image      string `json:"image,omitempty"`
-	Repository string `json:"repository,omitempty"`
-	Tag        string `json:"tag,omitempty"`
+	Enable     *bool                       `json:"enable,omitempty"`
+	Version    string                      `json:"version,omitempty"`
+	Image      string                      `json:"image,omitempty"`
+	Repository string                      `json:"repository,omitempty"`
+	Tag        string                      `json:"tag,omitempty"`
+	Resources  corev1.ResourceRequirements `json:"resources,omitempty"`
+	// The hostname and TLS policy of the landing page route.
+	Route RouteCustomizationSpec `json:"route,omitempty"`
+}
+
+// RouteCustomizationSpec defines customization entries for the route of a component.  Empty
+// entries keep the value of the component manifest.
+type RouteCustomizationSpec struct {
+	// The hostname of the route.  When not set, the cluster generates one from the ingress
+	// domain.
+	Hostname string `json:"hostname,omitempty"`
+	// The TLS termination of the route: reencrypt or passthrough.  The components serve
+	// TLS, so edge termination is not supported.
+	Termination string `json:"termination,omitempty"`
+	// What the route does with insecure connections: Redirect, Allow or None.
+	InsecureEdgeTerminationPolicy string `json:"insecureEdgeTerminationPolicy,omitempty"`
+}
+
+const (
+	// Route termination: the router decrypts the traffic, and encrypts it again for the component.
+	RouteTerminationReencrypt = "reencrypt"
+
+	// Route termination: the component decrypts the traffic.
+	RouteTerminationPassthrough = "passthrough"
+)
+
+// Returns true if the input route customization is valid.  The hostname, when set, must be
+// a DNS subdomain, and the termination and insecure connection policy must be supported.
+// A passthrough route can not allow insecure connections.
+func IsValidRouteCustomization(route RouteCustomizationSpec) bool {
+	if len(route.Hostname) != 0 && len(validation.IsDNS1123Subdomain(route.Hostname)) != 0 {
+		return false
+	}
+	switch route.Termination {
+	case "", RouteTerminationReencrypt, RouteTerminationPassthrough:
+	default:
+		return false
+	}
+	switch route.InsecureEdgeTerminationPolicy {
+	case "", "Redirect", "None":
+	case "Allow":
+		return route.Termination != RouteTerminationPassthrough
+	default:
+		return false
+	}
+	return true
 }
 
 // CRWCustomizationSpec defines customization entries for codeready-workspaces.
@@ -200,11 +1177,12 @@ type CWRCustomResourceDevFileRegImage struct {
 }
 
 type EventsCustomizationSpec struct {
-	Enable     *bool  `json:"enable,omitempty"`
-	Version    string `json:"version,omitempty"`
-	Image      string `json:"image,omitempty"`
-	Repository string `json:"repository,omitempty"`
-	Tag        string `json:"tag,omitempty"`
+	Enable     *bool                       `json:"enable,omitempty"`
+	Version    string                      `json:"version,omitempty"`
+	Image      string                      `json:"image,omitempty"`
+	Repository string                      `json:"repository,omitempty"`
+	Tag        string                      `json:"tag,omitempty"`
+	Resources  corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // Determines if the Events component should be enabled.  Starting with
@@ -230,30 +1208,118 @@ type CollectionControllerSpec struct {
 
 // StackControllerSpec defines customization entried for the Kabanero stack controller.
 type StackControllerSpec struct {
-	Version    string `json:"version,omitempty"`
-	Image      string `json:"image,omitempty"`
-	Repository string `json:"repository,omitempty"`
-	Tag        string `json:"tag,omitempty"`
+	Enable     *bool                       `json:"enable,omitempty"`
+	Version    string                      `json:"version,omitempty"`
+	Image      string                      `json:"image,omitempty"`
+	Repository string                      `json:"repository,omitempty"`
+	Tag        string                      `json:"tag,omitempty"`
+	Resources  corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// Returns true unless the stack controller was disabled.  Without the stack controller,
+// the instance has no stacks.
+func (scs StackControllerSpec) IsEnabled() bool {
+	return scs.Enable == nil || *scs.Enable
 }
 
 type AdmissionControllerWebhookCustomizationSpec struct {
-	Version    string `json:"version,omitempty"`
-	Image      string `json:"image,omitempty"`
-	Repository string `json:"repository,omitempty"`
-	Tag        string `json:"tag,omitempty"`
+	Version    string                      `json:"version,omitempty"`
+	Image      string                      `json:"image,omitempty"`
+	Repository string                      `json:"repository,omitempty"`
+	Tag        string                      `json:"tag,omitempty"`
+	Resources  corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Whether the admission webhook rejects settings that skip certificate verification.
+	CertVerificationPolicy CertVerificationPolicySpec `json:"certVerificationPolicy,omitempty"`
+}
+
+// CertVerificationPolicySpec defines which hosts, if any, certificate verification may be
+// skipped for.  The policy applies to the Kabanero instance that sets it, and to the Stacks
+// in its namespace.
+type CertVerificationPolicySpec struct {
+	// When true, the admission webhook rejects a Kabanero instance or Stack that skips
+	// certificate verification for a host that is not allowed.
+	Enforce bool `json:"enforce,omitempty"`
+
+	// The hosts that certificate verification may still be skipped for, as in
+	// registry.internal:5000, github.example.com or *.example.com.  A host without a port
+	// matches any port.
+	// +listType=set
+	AllowedHosts []string `json:"allowedHosts,omitempty"`
+}
+
+// Returns true if the policy allows certificate verification to be skipped for the input
+// host, which may include a port.  An empty host is only allowed when the policy is not
+// enforced.
+func (p CertVerificationPolicySpec) IsSkipAllowed(host string) bool {
+	if !p.Enforce {
+		return true
+	}
+	if len(host) == 0 {
+		return false
+	}
+
+	host = strings.ToLower(host)
+	hostname := host
+	if i := strings.LastIndex(host, ":"); i != -1 && !strings.HasSuffix(host, "]") {
+		hostname = host[:i]
+	}
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		switch {
+		case allowed == host || allowed == hostname:
+			return true
+		case strings.HasPrefix(allowed, "*.") && strings.HasSuffix(hostname, allowed[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the host of the input URL, or an empty string if it cannot be parsed.
+func GetUrlHost(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// Returns the host that the archive of the pipeline is retrieved from, or an empty string
+// if it is not known.  The registry of a Tekton bundle is the part of the reference before
+// the first slash, if it looks like a host.
+func (pipeline PipelineSpec) ArchiveHost() string {
+	switch {
+	case pipeline.GitRelease.IsUsable():
+		return pipeline.GitRelease.Hostname
+	case len(pipeline.Oci.Bundle) != 0:
+		return GetImageHost(pipeline.Oci.Bundle)
+	}
+	return GetUrlHost(pipeline.Https.Url)
+}
+
+// Returns the registry host of the input image reference.  An image without a registry
+// is pulled from docker.io.
+func GetImageHost(image string) string {
+	if i := strings.Index(image, "/"); i != -1 && strings.ContainsAny(image[:i], ".:") {
+		return image[:i]
+	}
+	return "docker.io"
 }
 
 type DevfileRegistrySpec struct {
-	Version    string `json:"version,omitempty"`
-	Image      string `json:"image,omitempty"`
-	Repository string `json:"repository,omitempty"`
-	Tag        string `json:"tag,omitempty"`
+	Version    string                      `json:"version,omitempty"`
+	Image      string                      `json:"image,omitempty"`
+	Repository string                      `json:"repository,omitempty"`
+	Tag        string                      `json:"tag,omitempty"`
+	Resources  corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 type SsoCustomizationSpec struct {
-	Enable          bool   `json:"enable,omitempty"`
-	Provider        string `json:"provider,omitempty"`
-	AdminSecretName string `json:"adminSecretName,omitempty"`
+	Enable          bool                        `json:"enable,omitempty"`
+	Provider        string                      `json:"provider,omitempty"`
+	AdminSecretName string                      `json:"adminSecretName,omitempty"`
+	Resources       corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // KabaneroStatus defines the observed state of the Kabanero instance.
@@ -302,6 +1368,145 @@ type KabaneroStatus struct {
 
 	// Target namespace status
 	TargetNamespaces TargetNamespaceStatus `json:"targetNamespaces,omitempty"`
+
+	// Version skew between the operator and the components it deployed.
+	VersionSkew VersionSkewStatus `json:"versionSkew,omitempty"`
+
+	// The number of stacks in each state.
+	StackSummary StackSummaryStatus `json:"stackSummary,omitempty"`
+
+	// The progress of the migration of the v1alpha1 Collection resources to Stack resources.
+	// Not set when there are no collections.
+	CollectionMigration *CollectionMigrationStatus `json:"collectionMigration,omitempty"`
+
+	// The progress of the deletion of the instance.  Only set while the instance is deleted.
+	Deletion *KabaneroDeletionStatus `json:"deletion,omitempty"`
+
+	// The generation of the instance that the status was computed for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	Conditions []KabaneroCondition `json:"conditions,omitempty"`
+}
+
+// The types of KabaneroCondition.
+const (
+	// All the components of the instance are ready.
+	KabaneroConditionReady = "Ready"
+)
+
+// The reasons of the KabaneroConditionReady condition.
+const (
+	// All the components of the instance are ready.
+	KabaneroReasonComponentsReady = "ComponentsReady"
+
+	// One or more components of the instance are not ready.
+	KabaneroReasonComponentsNotReady = "ComponentsNotReady"
+
+	// The instance could not be reconciled.
+	KabaneroReasonReconcileFailed = "ReconcileFailed"
+
+	// Another, older, instance manages the namespace of the instance.
+	KabaneroReasonStandby = "Standby"
+)
+
+// KabaneroCondition describes an aspect of the state of a Kabanero instance.
+type KabaneroCondition struct {
+	Type string `json:"type"`
+	// True, False, or Unknown.
+	Status             string       `json:"status"`
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	Reason             string       `json:"reason,omitempty"`
+	Message            string       `json:"message,omitempty"`
+	// The generation of the resource that the condition was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// Returns the condition of the input type, or nil if it is not set.
+func (s KabaneroStatus) GetCondition(conditionType string) *KabaneroCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// Sets a condition.  The transition time is only updated if the status changes.
+func (s *KabaneroStatus) SetCondition(condition KabaneroCondition) {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == condition.Type {
+			if s.Conditions[i].Status == condition.Status {
+				condition.LastTransitionTime = s.Conditions[i].LastTransitionTime
+			}
+			s.Conditions[i] = condition
+			return
+		}
+	}
+	s.Conditions = append(s.Conditions, condition)
+}
+
+// StackSummaryStatus counts the stacks in the Kabanero namespace by state.
+type StackSummaryStatus struct {
+	// Stacks with at least one active version, and no failures.
+	Active int `json:"active"`
+
+	// Stacks with no active versions, and no failures.
+	Inactive int `json:"inactive"`
+
+	// Stacks with a failed version or asset.
+	Failed int `json:"failed"`
+
+	// The names of the failed stacks.
+	FailingStacks []string `json:"failingStacks,omitempty"`
+}
+
+// KabaneroDeletionStatus reports the progress of the deletion of a Kabanero instance.
+type KabaneroDeletionStatus struct {
+	// The phase of the deletion: Stacks, PipelineAssets, Bindings or Components.
+	Phase string `json:"phase,omitempty"`
+
+	// What the phase is waiting on, or why it failed.
+	Message string `json:"message,omitempty"`
+}
+
+// The phases of the deletion of a Kabanero instance, in order.
+const (
+	// The stacks owned by the instance are deleted, and the stack controller removes their assets.
+	KabaneroDeletionPhaseStacks = "Stacks"
+
+	// The assets of the gitops pipelines are deleted.
+	KabaneroDeletionPhasePipelineAssets = "PipelineAssets"
+
+	// The role bindings and other objects in other namespaces are deleted.
+	KabaneroDeletionPhaseBindings = "Bindings"
+
+	// The web console customizations, the admission webhook and other cluster level components are deleted.
+	KabaneroDeletionPhaseComponents = "Components"
+)
+
+// CollectionMigrationStatus reports the progress of the migration of the v1alpha1 Collection
+// resources of a Kabanero instance to Stack resources.
+type CollectionMigrationStatus struct {
+	// The number of collections that were migrated.
+	Migrated int `json:"migrated"`
+
+	// The names of the collections that are not migrated yet.
+	// +listType=set
+	Pending []string `json:"pending,omitempty"`
+
+	// Why the pending collections are not migrated yet.
+	Message string `json:"message,omitempty"`
+}
+
+// VersionSkewStatus reports whether the running components are at the versions the operator expects.
+type VersionSkewStatus struct {
+	// True if one or more components are not at the expected version.
+	Skewed  string `json:"skewed,omitempty"`
+	Message string `json:"message,omitempty"`
+	// The version of Kabanero that the operator deploys by default.
+	OperatorVersion string `json:"operatorVersion,omitempty"`
 }
 
 type TargetNamespaceStatus struct {
@@ -320,6 +1525,12 @@ type PipelineStatus struct {
 	Url        string         `json:"url,omitempty"`
 	GitRelease GitReleaseInfo `json:"gitRelease,omitempty"`
 	Digest     string         `json:"digest,omitempty"`
+	// How the manifests in the pipeline archive were rendered.
+	Renderer string `json:"renderer,omitempty"`
+	// The detached signature the pipeline archive was verified against.
+	Signature *PipelineSignatureSpec `json:"signature,omitempty"`
+	// The assets are listed in the order they are applied: Tasks, ClusterTasks,
+	// Conditions, Pipelines, trigger resources, and then any other kinds.
 	// +listType=map
 	// +listMapKey=assetName
 	// +listMapKey=namespace
@@ -327,6 +1538,21 @@ type PipelineStatus struct {
 	// +listMapKey=version
 	// +listMapKey=kind
 	ActiveAssets []RepositoryAssetStatus `json:"activeAssets,omitempty"`
+	// The URLs of the EventListeners of the pipeline, when the stack exposes them.
+	// +listType=map
+	// +listMapKey=name
+	// +listMapKey=namespace
+	EventListeners []EventListenerStatus `json:"eventListeners,omitempty"`
+}
+
+// EventListenerStatus reports where an EventListener is exposed.
+type EventListenerStatus struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// The URL of the Route or Ingress.  Empty until a Route is admitted by a router.
+	Url string `json:"url,omitempty"`
+	// Why the URL is not known.
+	Message string `json:"message,omitempty"`
 }
 
 // The status of the gitops pipelines
@@ -356,6 +1582,20 @@ type KabaneroInstanceStatus struct {
 	Ready   string `json:"ready,omitempty"`
 	Message string `json:"message,omitempty"`
 	Version string `json:"version,omitempty"`
+
+	// The channel that the software revisions of the components were chosen from.
+	Channel string `json:"channel,omitempty"`
+
+	// The software revision chosen for each component.
+	// +listType=map
+	// +listMapKey=component
+	Revisions []SoftwareRevisionStatus `json:"revisions,omitempty"`
+}
+
+// SoftwareRevisionStatus reports the software revision chosen for a component.
+type SoftwareRevisionStatus struct {
+	Component string `json:"component"`
+	Version   string `json:"version"`
 }
 
 // TektonStatus defines the observed status details of Tekton.
@@ -393,6 +1633,8 @@ type KabaneroLandingPageStatus struct {
 	Ready   string `json:"ready,omitempty"`
 	Message string `json:"message,omitempty"`
 	Version string `json:"version,omitempty"`
+	// +listType=set
+	Hostnames []string `json:"hostnames,omitempty"`
 }
 
 // AppsodyStatus defines the observed status details of Appsody.
@@ -480,7 +1722,8 @@ type SsoStatus struct {
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="CreationTimestamp is a timestamp representing the server time when this object was created. It is not guaranteed to be set in happens-before order across separate operations."
 // +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.kabaneroInstance.version",description="Kabanero operator instance version."
 // +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.kabaneroInstance.ready",description="Kabanero operator instance readiness status. The status is directly correlated to the availability of the operator's resources dependencies."
-// +kubebuilder:resource:path=kabaneros,scope=Namespaced
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.kabaneroInstance.message",description="Kabanero operator instance status message."
+// +kubebuilder:resource:path=kabaneros,scope=Namespaced,shortName=kab
 // +kubebuilder:storageversion
 type Kabanero struct {
 	metav1.TypeMeta   `json:",inline"`
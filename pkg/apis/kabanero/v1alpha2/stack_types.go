@@ -1,7 +1,9 @@
 package v1alpha2
 
 import (
+	"regexp"
 	"strings"
+	"time"
 	
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -35,15 +37,74 @@ const (
 	StackPolicyNone = "none"
 )
 
+// The stack id, Spec.Name, is the name of the Appsody stack directory.  Appsody stack
+// creation constrains it: "The name must start with a lowercase letter, contain only
+// lowercase letters, numbers, or dashes, and cannot end in a dash."
+var stackIdRegexp = regexp.MustCompile("^[a-z]([a-z0-9-]*[a-z0-9])?$")
+
+// The maximum length of a stack id.
+const MaxStackIdLength = 68
+
+// Returns true if the input stack id follows the Appsody stack naming rules.
+func IsValidStackId(id string) bool {
+	return len(id) <= MaxStackIdLength && stackIdRegexp.MatchString(id)
+}
+
 // StackSpec defines the desired composition of a Stack
 // +k8s:openapi-gen=true
 type StackSpec struct {
 	Name string `json:"name,omitempty"`
+	// What happens to the pipeline assets of this stack when they are no longer used.
+	// Overrides the assetDeletionPolicy of the Kabanero instance.
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+	// The name of a ConfigMap in the stack's namespace, whose entries are made available
+	// to the templates in pipeline archives.  Its entries override those of the
+	// renderingContextConfigMap of the Kabanero instance.
+	RenderingContextConfigMap string `json:"renderingContextConfigMap,omitempty"`
+	// When set, the EventListeners in the pipeline archives of this stack are exposed by a
+	// Route or an Ingress, and their URLs are reported in the pipeline status.
+	EventListenerRoute *EventListenerRouteSpec `json:"eventListenerRoute,omitempty"`
+	// The storage bound to the workspaces of the PipelineRuns created by the TriggerTemplates
+	// of this stack.  Overrides the workspaces of the Kabanero instance with the same name.
+	// +listType=map
+	// +listMapKey=name
+	Workspaces []WorkspaceBindingSpec `json:"workspaces,omitempty"`
 	// +listType=map
 	// +listMapKey=version
 	Versions []StackVersion `json:"versions,omitempty"`
 }
 
+// EventListenerRouteSpec defines how the EventListeners of a stack are exposed.
+type EventListenerRouteSpec struct {
+	// Route (the default), for an OpenShift Route with edge TLS termination, or Ingress.
+	Kind string `json:"kind,omitempty"`
+	// The host name.  When empty, the OpenShift router generates one.  Required for an Ingress.
+	Host string `json:"host,omitempty"`
+	// Ingress only: the name of the secret holding the TLS certificate for the host.  When
+	// empty, the Ingress serves plain HTTP.
+	TlsSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+const (
+	// Event listener route kinds.
+	EventListenerRouteKindRoute   = "Route"
+	EventListenerRouteKindIngress = "Ingress"
+)
+
+// Returns true if the input event listener route is valid.  A nil route is valid.
+func IsValidEventListenerRoute(route *EventListenerRouteSpec) bool {
+	if route == nil {
+		return true
+	}
+	switch route.Kind {
+	case "", EventListenerRouteKindRoute:
+		return len(route.TlsSecretName) == 0
+	case EventListenerRouteKindIngress:
+		return len(route.Host) != 0
+	}
+	return false
+}
+
 func (s StackSpec) GetVersions() []ComponentSpecVersion {
 	ret := make([]ComponentSpecVersion, len(s.Versions))
 	for i, _ := range s.Versions {
@@ -69,6 +130,26 @@ type StackVersion struct {
 	Images               []Image        `json:"images,omitempty"`
 	Devfile              string         `json:"devfile,omitempty"`
 	Metafile             string         `json:"metafile,omitempty"`
+	// The location of the repository that this version was read from.
+	RepositoryUrl        string         `json:"repositoryUrl,omitempty"`
+	// The names of secrets in the stack's namespace that hold credentials for the
+	// image registry.  They are tried in order, before the Kabanero instance's
+	// defaults, when the image digests are retrieved.
+	ImagePullSecrets     []string       `json:"imagePullSecrets,omitempty"`
+	// True if the stack index marks this version as deprecated.
+	Deprecated           bool           `json:"deprecated,omitempty"`
+	// The date this version stops being supported, as in 2021-06-30, or an RFC 3339 time.
+	EndOfSupport         string         `json:"endOfSupport,omitempty"`
+}
+
+// Parses the end of support date of a stack version.  A date without a time is the start
+// of that day, in UTC.
+func ParseEndOfSupport(endOfSupport string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", endOfSupport)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, endOfSupport)
 }
 
 func (sv StackVersion) GetVersion() string {
@@ -111,8 +192,93 @@ type RepositoryAssetStatus struct {
 	Digest        string `json:"assetDigest,omitempty"`
 	Status        string `json:"status,omitempty"`
 	StatusMessage string `json:"statusMessage,omitempty"`
+	// A machine-readable reason for the status message.  One of the StackReason values.
+	Reason string `json:"reason,omitempty"`
+	// When the status last changed.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// When the asset was last applied to the cluster.
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+	// The digest of the pipeline archive that the asset was last applied from.
+	ArchiveDigest string `json:"archiveDigest,omitempty"`
+	// A hash of the content of the asset in the cluster, taken when it was last applied.
+	// Used to detect changes made to the asset outside of the operator.
+	AppliedHash string `json:"appliedHash,omitempty"`
 }
 
+// The reasons reported alongside status messages, so that automation does not need
+// to parse the messages.
+const (
+	// The digest of a downloaded pipeline archive, or of a file in it, does not match
+	// the digest it was published with.
+	StackReasonArchiveDigestMismatch = "ArchiveDigestMismatch"
+
+	// A pipeline archive could not be downloaded.
+	StackReasonArchiveUnavailable = "ArchiveUnavailable"
+
+	// A pipeline archive, or a file in it, is larger than the configured limits.
+	StackReasonArchiveTooLarge = "ArchiveTooLarge"
+
+	// The detached signature of a pipeline archive could not be retrieved, or does not
+	// verify the archive.
+	StackReasonArchiveSignatureInvalid = "ArchiveSignatureInvalid"
+
+	// A manifest could not be read, or is not allowed.
+	StackReasonManifestRejected = "ManifestRejected"
+
+	// A manifest is missing required fields, or has fields that are not known.
+	StackReasonManifestInvalid = "ManifestInvalid"
+
+	// A manifest could not be applied to the cluster.
+	StackReasonManifestApplyFailed = "ManifestApplyFailed"
+
+	// The state of an asset in the cluster could not be checked.
+	StackReasonAssetCheckFailed = "AssetCheckFailed"
+
+	// An asset was modified in the cluster after it was applied.
+	StackReasonAssetDrifted = "AssetDrifted"
+
+	// Asset activation was interrupted by an operator shutdown.
+	StackReasonActivationInterrupted = "ActivationInterrupted"
+
+	// The image registry rejected the credentials used to read an image.
+	StackReasonRegistryUnauthorized = "RegistryUnauthorized"
+
+	// The image registry is rate limiting requests, or is unavailable.
+	StackReasonRegistryUnavailable = "RegistryUnavailable"
+
+	// The digest of an image could not be retrieved for another reason.
+	StackReasonDigestLookupFailed = "DigestLookupFailed"
+
+	// The desired state of a stack version is not valid.
+	StackReasonInvalidDesiredState = "InvalidDesiredState"
+
+	// An image of the stack version is not from a registry allowed by the governance policy.
+	StackReasonRegistryNotAllowed = "RegistryNotAllowed"
+
+	// A pipeline of the stack version does not set a digest, which the governance policy requires.
+	StackReasonDigestRequired = "DigestRequired"
+
+	// The stack id is banned by the governance policy.
+	StackReasonStackBanned = "StackBanned"
+
+	// The stack has more active versions than the governance policy allows.
+	StackReasonTooManyActiveVersions = "TooManyActiveVersions"
+
+	// An image of the stack version has more critical vulnerabilities than the
+	// vulnerability scan of the Kabanero instance allows.
+	StackReasonVulnerabilitiesFound = "VulnerabilitiesFound"
+
+	// An image of the stack version could not be scanned by the vulnerability scanner.
+	StackReasonScanFailed = "ScanFailed"
+
+	// The vulnerability scanner has not completed the scan of an image of the stack version.
+	StackReasonScanPending = "ScanPending"
+
+	// The images of the active stack versions have no more critical vulnerabilities than
+	// the vulnerability scan of the Kabanero instance allows.
+	StackReasonNoVulnerabilities = "NoVulnerabilities"
+)
+
 // StackStatus defines the observed state of a stack
 // +k8s:openapi-gen=true
 type StackStatus struct {
@@ -121,6 +287,65 @@ type StackStatus struct {
 	// +listMapKey=version
 	Versions []StackVersionStatus `json:"versions,omitempty"`
 	Summary  string               `json:"summary,omitempty"`
+	// The ConfigMap holding the full asset status messages, if the status was
+	// compacted because it was too large.
+	DetailsConfigMap string `json:"detailsConfigMap,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []StackCondition `json:"conditions,omitempty"`
+}
+
+// The types of StackCondition.
+const (
+	// The image tag of an active stack version refers to a different digest than
+	// it did when the version was activated.
+	StackConditionDigestDrifted = "DigestDrifted"
+
+	// All versions of the stack are in the desired state.
+	StackConditionReady = "Ready"
+
+	// An active version of the stack is deprecated, or past its end of support date.
+	StackConditionDeprecated = "Deprecated"
+
+	// An image of an active stack version, or of a version that was blocked, has more
+	// critical vulnerabilities than allowed.
+	StackConditionVulnerable = "Vulnerable"
+)
+
+// StackCondition describes an aspect of the state of a stack.
+type StackCondition struct {
+	Type string `json:"type"`
+	// True, False, or Unknown.
+	Status             string       `json:"status"`
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	Reason             string       `json:"reason,omitempty"`
+	Message            string       `json:"message,omitempty"`
+	// The generation of the resource that the condition was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// Returns the condition of the input type, or nil if it is not set.
+func (s StackStatus) GetCondition(conditionType string) *StackCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// Sets a condition.  The transition time is only updated if the status changes.
+func (s *StackStatus) SetCondition(condition StackCondition) {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == condition.Type {
+			if s.Conditions[i].Status == condition.Status {
+				condition.LastTransitionTime = s.Conditions[i].LastTransitionTime
+			}
+			s.Conditions[i] = condition
+			return
+		}
+	}
+	s.Conditions = append(s.Conditions, condition)
 }
 
 func (s StackStatus) GetVersions() []ComponentStatusVersion {
@@ -134,6 +359,7 @@ func (s StackStatus) GetVersions() []ComponentStatusVersion {
 // StackVersionStatus defines the observed state of a specific stack version.
 type StackVersionStatus struct {
 	Version  string `json:"version,omitempty"`
+	// The location of the repository that this version was read from.
 	Location string `json:"location,omitempty"`
 	// +listType=map
 	// +listMapKey=name
@@ -141,10 +367,16 @@ type StackVersionStatus struct {
 	Pipelines     []PipelineStatus `json:"pipelines,omitempty"`
 	Status        string           `json:"status,omitempty"`
 	StatusMessage string           `json:"statusMessage,omitempty"`
+	// A machine-readable reason for the status message.  One of the StackReason values.
+	Reason string `json:"reason,omitempty"`
 	// +listType=map
 	// +listMapKey=id
 	// +listMapKey=image
 	Images []ImageStatus `json:"images,omitempty"`
+	// True if the stack index marks this version as deprecated.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// The date this version stops being supported.
+	EndOfSupport string `json:"endOfSupport,omitempty"`
 }
 
 func (sv StackVersionStatus) GetVersion() string {
@@ -172,6 +404,10 @@ type ImageStatus struct {
 type ImageDigest struct {
 	Activation string `json:"activation,omitempty"`
 	Message    string `json:"message,omitempty"`
+	// The digest that the image tag referred to when it was last checked.
+	Current string `json:"current,omitempty"`
+	// When the image tag was last checked.
+	LastChecked *metav1.Time `json:"lastChecked,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -180,8 +416,11 @@ type ImageDigest struct {
 // +k8s:openapi-gen=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="CreationTimestamp is a timestamp representing the server time when this object was created. It is not guaranteed to be set in happens-before order across separate operations."
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether all versions of the stack are in the desired state."
+// +kubebuilder:printcolumn:name="Active",type="string",JSONPath=".status.versions[?(@.status==\"active\")].version",description="The active stack versions."
 // +kubebuilder:printcolumn:name="Summary",type="string",JSONPath=".status.summary",description="Stack summary."
-// +kubebuilder:resource:path=stacks,scope=Namespaced
+// +kubebuilder:resource:path=stacks,scope=Namespaced,shortName=stk
+// +kubebuilder:storageversion
 type Stack struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -0,0 +1,93 @@
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Stack sync status: the Stack instance matches the repository index.
+	StackHubSyncStatusSynced = "synced"
+
+	// Stack sync status: the Stack instance is owned by something else, and was not changed.
+	StackHubSyncStatusConflict = "conflict"
+
+	// Stack sync status: the Stack instance could not be created or updated.
+	StackHubSyncStatusFailed = "failed"
+)
+
+// StackHubSpec defines the repository whose stacks are materialized as Stack instances.
+// +k8s:openapi-gen=true
+type StackHubSpec struct {
+	// The repository that the stack index is read from.
+	Repository RepositoryConfig `json:"repository,omitempty"`
+
+	// The pipelines of the stacks, if the repository does not list its own.
+	// +listType=map
+	// +listMapKey=id
+	// +listMapKey=sha256
+	Pipelines []PipelineSpec `json:"pipelines,omitempty"`
+
+	// How to resolve a stack version that the repository of another StackHub already
+	// provides.  One of first-wins (the default), error, or prefer-repository:<repository name>.
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+
+	// Skip the certificate verification of the container registries that hold the stack
+	// images.
+	SkipRegistryCertVerification bool `json:"skipRegistryCertVerification,omitempty"`
+
+	// How often the repository index is re-read.  Defaults to 5 minutes.
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+}
+
+// StackHubStatus defines the observed state of a stack hub.
+// +k8s:openapi-gen=true
+type StackHubStatus struct {
+	Ready   string `json:"ready,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// The last time the repository index was read successfully.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// +listType=map
+	// +listMapKey=name
+	Stacks []StackHubStackStatus `json:"stacks,omitempty"`
+}
+
+// StackHubStackStatus defines the sync status of a single stack listed by the repository index.
+type StackHubStackStatus struct {
+	Name string `json:"name,omitempty"`
+	// +listType=set
+	Versions      []string `json:"versions,omitempty"`
+	Status        string   `json:"status,omitempty"`
+	StatusMessage string   `json:"statusMessage,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StackHub is the Schema for the stackhubs API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="CreationTimestamp is a timestamp representing the server time when this object was created. It is not guaranteed to be set in happens-before order across separate operations."
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready",description="Stack hub readiness status."
+// +kubebuilder:resource:path=stackhubs,scope=Namespaced
+type StackHub struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StackHubSpec   `json:"spec,omitempty"`
+	Status StackHubStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StackHubList contains a list of StackHubs
+type StackHubList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// +listType=set
+	Items []StackHub `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StackHub{}, &StackHubList{})
+}
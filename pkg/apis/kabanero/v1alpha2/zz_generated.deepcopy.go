@@ -5,12 +5,16 @@
 package v1alpha2
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AdmissionControllerWebhookCustomizationSpec) DeepCopyInto(out *AdmissionControllerWebhookCustomizationSpec) {
 	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.CertVerificationPolicy.DeepCopyInto(&out.CertVerificationPolicy)
 	return
 }
 
@@ -56,6 +60,38 @@ func (in *AppsodyStatus) DeepCopy() *AppsodyStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactProxySpec) DeepCopyInto(out *ArtifactProxySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactProxySpec.
+func (in *ArtifactProxySpec) DeepCopy() *ArtifactProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AssetGroupKind) DeepCopyInto(out *AssetGroupKind) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AssetGroupKind.
+func (in *AssetGroupKind) DeepCopy() *AssetGroupKind {
+	if in == nil {
+		return nil
+	}
+	out := new(AssetGroupKind)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CRWCustomizationSpec) DeepCopyInto(out *CRWCustomizationSpec) {
 	*out = *in
@@ -193,6 +229,65 @@ func (in *CWRCustomResourceDevFileRegImage) DeepCopy() *CWRCustomResourceDevFile
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertVerificationPolicySpec) DeepCopyInto(out *CertVerificationPolicySpec) {
+	*out = *in
+	if in.AllowedHosts != nil {
+		in, out := &in.AllowedHosts, &out.AllowedHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertVerificationPolicySpec.
+func (in *CertVerificationPolicySpec) DeepCopy() *CertVerificationPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertVerificationPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CliOidcClientSecretRef) DeepCopyInto(out *CliOidcClientSecretRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CliOidcClientSecretRef.
+func (in *CliOidcClientSecretRef) DeepCopy() *CliOidcClientSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CliOidcClientSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CliOidcSpec) DeepCopyInto(out *CliOidcSpec) {
+	*out = *in
+	out.ClientSecretRef = in.ClientSecretRef
+	if in.AdminGroups != nil {
+		in, out := &in.AdminGroups, &out.AdminGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CliOidcSpec.
+func (in *CliOidcSpec) DeepCopy() *CliOidcSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CliOidcSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CliStatus) DeepCopyInto(out *CliStatus) {
 	*out = *in
@@ -246,9 +341,47 @@ func (in *CollectionControllerStatus) DeepCopy() *CollectionControllerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CollectionMigrationStatus) DeepCopyInto(out *CollectionMigrationStatus) {
+	*out = *in
+	if in.Pending != nil {
+		in, out := &in.Pending, &out.Pending
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CollectionMigrationStatus.
+func (in *CollectionMigrationStatus) DeepCopy() *CollectionMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CollectionMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevfileRegistrySpec) DeepCopyInto(out *DevfileRegistrySpec) {
 	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
 	return
 }
 
@@ -262,6 +395,102 @@ func (in *DevfileRegistrySpec) DeepCopy() *DevfileRegistrySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownloadLimitsSpec) DeepCopyInto(out *DownloadLimitsSpec) {
+	*out = *in
+	if in.ConnectTimeout != nil {
+		in, out := &in.ConnectTimeout, &out.ConnectTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ReadTimeout != nil {
+		in, out := &in.ReadTimeout, &out.ReadTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	in.Retry.DeepCopyInto(&out.Retry)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownloadLimitsSpec.
+func (in *DownloadLimitsSpec) DeepCopy() *DownloadLimitsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DownloadLimitsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownloadRetrySpec) DeepCopyInto(out *DownloadRetrySpec) {
+	*out = *in
+	if in.RetryOnStatus != nil {
+		in, out := &in.RetryOnStatus, &out.RetryOnStatus
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownloadRetrySpec.
+func (in *DownloadRetrySpec) DeepCopy() *DownloadRetrySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DownloadRetrySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressProxySpec) DeepCopyInto(out *EgressProxySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressProxySpec.
+func (in *EgressProxySpec) DeepCopy() *EgressProxySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressProxySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventListenerRouteSpec) DeepCopyInto(out *EventListenerRouteSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventListenerRouteSpec.
+func (in *EventListenerRouteSpec) DeepCopy() *EventListenerRouteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EventListenerRouteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventListenerStatus) DeepCopyInto(out *EventListenerStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventListenerStatus.
+func (in *EventListenerStatus) DeepCopy() *EventListenerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EventListenerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventsCustomizationSpec) DeepCopyInto(out *EventsCustomizationSpec) {
 	*out = *in
@@ -270,6 +499,7 @@ func (in *EventsCustomizationSpec) DeepCopyInto(out *EventsCustomizationSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	in.Resources.DeepCopyInto(&out.Resources)
 	return
 }
 
@@ -360,10 +590,17 @@ func (in *GithubConfig) DeepCopy() *GithubConfig {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitopsSpec) DeepCopyInto(out *GitopsSpec) {
 	*out = *in
+	if in.Enable != nil {
+		in, out := &in.Enable, &out.Enable
+		*out = new(bool)
+		**out = **in
+	}
 	if in.Pipelines != nil {
 		in, out := &in.Pipelines, &out.Pipelines
 		*out = make([]PipelineSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -404,6 +641,16 @@ func (in *GitopsStatus) DeepCopy() *GitopsStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GovernancePolicyConfig) DeepCopyInto(out *GovernancePolicyConfig) {
 	*out = *in
+	if in.AllowedRegistries != nil {
+		in, out := &in.AllowedRegistries, &out.AllowedRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BannedStackIds != nil {
+		in, out := &in.BannedStackIds, &out.BannedStackIds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -452,6 +699,10 @@ func (in *Image) DeepCopy() *Image {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageDigest) DeepCopyInto(out *ImageDigest) {
 	*out = *in
+	if in.LastChecked != nil {
+		in, out := &in.LastChecked, &out.LastChecked
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -468,7 +719,7 @@ func (in *ImageDigest) DeepCopy() *ImageDigest {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageStatus) DeepCopyInto(out *ImageStatus) {
 	*out = *in
-	out.Digest = in.Digest
+	in.Digest.DeepCopyInto(&out.Digest)
 	return
 }
 
@@ -492,10 +743,32 @@ func (in *InstanceStackConfig) DeepCopyInto(out *InstanceStackConfig) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IndexCacheTTL != nil {
+		in, out := &in.IndexCacheTTL, &out.IndexCacheTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DigestDriftCheckInterval != nil {
+		in, out := &in.DigestDriftCheckInterval, &out.DigestDriftCheckInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DigestCacheTTL != nil {
+		in, out := &in.DigestCacheTTL, &out.DigestCacheTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	if in.Pipelines != nil {
 		in, out := &in.Pipelines, &out.Pipelines
 		*out = make([]PipelineSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -541,6 +814,23 @@ func (in *Kabanero) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KabaneroCliServicesCustomizationSpec) DeepCopyInto(out *KabaneroCliServicesCustomizationSpec) {
 	*out = *in
+	if in.Enable != nil {
+		in, out := &in.Enable, &out.Enable
+		*out = new(bool)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Oidc != nil {
+		in, out := &in.Oidc, &out.Oidc
+		*out = new(CliOidcSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	out.Route = in.Route
 	return
 }
 
@@ -554,9 +844,50 @@ func (in *KabaneroCliServicesCustomizationSpec) DeepCopy() *KabaneroCliServicesC
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KabaneroCondition) DeepCopyInto(out *KabaneroCondition) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KabaneroCondition.
+func (in *KabaneroCondition) DeepCopy() *KabaneroCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(KabaneroCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KabaneroDeletionStatus) DeepCopyInto(out *KabaneroDeletionStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KabaneroDeletionStatus.
+func (in *KabaneroDeletionStatus) DeepCopy() *KabaneroDeletionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KabaneroDeletionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KabaneroInstanceStatus) DeepCopyInto(out *KabaneroInstanceStatus) {
 	*out = *in
+	if in.Revisions != nil {
+		in, out := &in.Revisions, &out.Revisions
+		*out = make([]SoftwareRevisionStatus, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -578,6 +909,8 @@ func (in *KabaneroLandingCustomizationSpec) DeepCopyInto(out *KabaneroLandingCus
 		*out = new(bool)
 		**out = **in
 	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	out.Route = in.Route
 	return
 }
 
@@ -594,6 +927,11 @@ func (in *KabaneroLandingCustomizationSpec) DeepCopy() *KabaneroLandingCustomiza
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KabaneroLandingPageStatus) DeepCopyInto(out *KabaneroLandingPageStatus) {
 	*out = *in
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -648,24 +986,68 @@ func (in *KabaneroSpec) DeepCopyInto(out *KabaneroSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.AllowedAssetNamespaces != nil {
+		in, out := &in.AllowedAssetNamespaces, &out.AllowedAssetNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedAssetKinds != nil {
+		in, out := &in.AllowedAssetKinds, &out.AllowedAssetKinds
+		*out = make([]AssetGroupKind, len(*in))
+		copy(*out, *in)
+	}
 	in.Github.DeepCopyInto(&out.Github)
-	out.GovernancePolicy = in.GovernancePolicy
+	in.GovernancePolicy.DeepCopyInto(&out.GovernancePolicy)
+	out.VulnerabilityScan = in.VulnerabilityScan
+	out.PipelineRunRetention = in.PipelineRunRetention
+	if in.Workspaces != nil {
+		in, out := &in.Workspaces, &out.Workspaces
+		*out = make([]WorkspaceBindingSpec, len(*in))
+		copy(*out, *in)
+	}
 	in.Stacks.DeepCopyInto(&out.Stacks)
 	if in.Triggers != nil {
 		in, out := &in.Triggers, &out.Triggers
 		*out = make([]TriggerSpec, len(*in))
 		copy(*out, *in)
 	}
-	out.CliServices = in.CliServices
+	in.CliServices.DeepCopyInto(&out.CliServices)
 	in.Landing.DeepCopyInto(&out.Landing)
 	in.CodereadyWorkspaces.DeepCopyInto(&out.CodereadyWorkspaces)
 	in.Events.DeepCopyInto(&out.Events)
 	out.CollectionController = in.CollectionController
-	out.StackController = in.StackController
-	out.AdmissionControllerWebhook = in.AdmissionControllerWebhook
-	out.DevfileRegistry = in.DevfileRegistry
-	out.Sso = in.Sso
+	in.StackController.DeepCopyInto(&out.StackController)
+	in.AdmissionControllerWebhook.DeepCopyInto(&out.AdmissionControllerWebhook)
+	in.DevfileRegistry.DeepCopyInto(&out.DevfileRegistry)
+	in.Sso.DeepCopyInto(&out.Sso)
 	in.Gitops.DeepCopyInto(&out.Gitops)
+	out.ArtifactProxy = in.ArtifactProxy
+	out.EgressProxy = in.EgressProxy
+	in.Downloads.DeepCopyInto(&out.Downloads)
+	out.Logging = in.Logging
+	out.Tracing = in.Tracing
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Scheduling.DeepCopyInto(&out.Scheduling)
+	if in.CommonLabels != nil {
+		in, out := &in.CommonLabels, &out.CommonLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CommonAnnotations != nil {
+		in, out := &in.CommonAnnotations, &out.CommonAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -682,14 +1064,14 @@ func (in *KabaneroSpec) DeepCopy() *KabaneroSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KabaneroStatus) DeepCopyInto(out *KabaneroStatus) {
 	*out = *in
-	out.KabaneroInstance = in.KabaneroInstance
+	in.KabaneroInstance.DeepCopyInto(&out.KabaneroInstance)
 	out.Serverless = in.Serverless
 	out.Tekton = in.Tekton
 	in.Cli.DeepCopyInto(&out.Cli)
 	if in.Landing != nil {
 		in, out := &in.Landing, &out.Landing
 		*out = new(KabaneroLandingPageStatus)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	out.Appsody = in.Appsody
 	if in.Kappnav != nil {
@@ -713,6 +1095,25 @@ func (in *KabaneroStatus) DeepCopyInto(out *KabaneroStatus) {
 	out.Sso = in.Sso
 	in.Gitops.DeepCopyInto(&out.Gitops)
 	in.TargetNamespaces.DeepCopyInto(&out.TargetNamespaces)
+	out.VersionSkew = in.VersionSkew
+	in.StackSummary.DeepCopyInto(&out.StackSummary)
+	if in.CollectionMigration != nil {
+		in, out := &in.CollectionMigration, &out.CollectionMigration
+		*out = new(CollectionMigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deletion != nil {
+		in, out := &in.Deletion, &out.Deletion
+		*out = new(KabaneroDeletionStatus)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]KabaneroCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -768,11 +1169,126 @@ func (in *KnativeServingStatus) DeepCopy() *KnativeServingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggingSpec) DeepCopyInto(out *LoggingSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggingSpec.
+func (in *LoggingSpec) DeepCopy() *LoggingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OciBundleSpec) DeepCopyInto(out *OciBundleSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OciBundleSpec.
+func (in *OciBundleSpec) DeepCopy() *OciBundleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OciBundleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OciIndexSpec) DeepCopyInto(out *OciIndexSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OciIndexSpec.
+func (in *OciIndexSpec) DeepCopy() *OciIndexSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OciIndexSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineRunRetentionSpec) DeepCopyInto(out *PipelineRunRetentionSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineRunRetentionSpec.
+func (in *PipelineRunRetentionSpec) DeepCopy() *PipelineRunRetentionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineRunRetentionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineServiceAccountSpec) DeepCopyInto(out *PipelineServiceAccountSpec) {
+	*out = *in
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineServiceAccountSpec.
+func (in *PipelineServiceAccountSpec) DeepCopy() *PipelineServiceAccountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineServiceAccountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineSignatureSpec) DeepCopyInto(out *PipelineSignatureSpec) {
+	*out = *in
+	out.Https = in.Https
+	out.GitRelease = in.GitRelease
+	out.KeySecretRef = in.KeySecretRef
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineSignatureSpec.
+func (in *PipelineSignatureSpec) DeepCopy() *PipelineSignatureSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineSignatureSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipelineSpec) DeepCopyInto(out *PipelineSpec) {
 	*out = *in
 	out.Https = in.Https
 	out.GitRelease = in.GitRelease
+	out.Oci = in.Oci
+	if in.ServiceAccount != nil {
+		in, out := &in.ServiceAccount, &out.ServiceAccount
+		*out = new(PipelineServiceAccountSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = new(PipelineSignatureSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -790,9 +1306,21 @@ func (in *PipelineSpec) DeepCopy() *PipelineSpec {
 func (in *PipelineStatus) DeepCopyInto(out *PipelineStatus) {
 	*out = *in
 	out.GitRelease = in.GitRelease
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = new(PipelineSignatureSpec)
+		**out = **in
+	}
 	if in.ActiveAssets != nil {
 		in, out := &in.ActiveAssets, &out.ActiveAssets
 		*out = make([]RepositoryAssetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EventListeners != nil {
+		in, out := &in.EventListeners, &out.EventListeners
+		*out = make([]EventListenerStatus, len(*in))
 		copy(*out, *in)
 	}
 	return
@@ -811,6 +1339,14 @@ func (in *PipelineStatus) DeepCopy() *PipelineStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RepositoryAssetStatus) DeepCopyInto(out *RepositoryAssetStatus) {
 	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -830,10 +1366,21 @@ func (in *RepositoryConfig) DeepCopyInto(out *RepositoryConfig) {
 	if in.Pipelines != nil {
 		in, out := &in.Pipelines, &out.Pipelines
 		*out = make([]PipelineSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	out.Https = in.Https
 	out.GitRelease = in.GitRelease
+	out.ConfigMapRef = in.ConfigMapRef
+	out.Oci = in.Oci
+	if in.ResolverParameters != nil {
+		in, out := &in.ResolverParameters, &out.ResolverParameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -847,6 +1394,57 @@ func (in *RepositoryConfig) DeepCopy() *RepositoryConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteCustomizationSpec) DeepCopyInto(out *RouteCustomizationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteCustomizationSpec.
+func (in *RouteCustomizationSpec) DeepCopy() *RouteCustomizationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteCustomizationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulingSpec) DeepCopyInto(out *SchedulingSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingSpec.
+func (in *SchedulingSpec) DeepCopy() *SchedulingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServerlessStatus) DeepCopyInto(out *ServerlessStatus) {
 	*out = *in
@@ -864,9 +1462,26 @@ func (in *ServerlessStatus) DeepCopy() *ServerlessStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SoftwareRevisionStatus) DeepCopyInto(out *SoftwareRevisionStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SoftwareRevisionStatus.
+func (in *SoftwareRevisionStatus) DeepCopy() *SoftwareRevisionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SoftwareRevisionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SsoCustomizationSpec) DeepCopyInto(out *SsoCustomizationSpec) {
 	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
 	return
 }
 
@@ -896,6 +1511,22 @@ func (in *SsoStatus) DeepCopy() *SsoStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SignatureKeySecretRef) DeepCopyInto(out *SignatureKeySecretRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SignatureKeySecretRef.
+func (in *SignatureKeySecretRef) DeepCopy() *SignatureKeySecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SignatureKeySecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Stack) DeepCopyInto(out *Stack) {
 	*out = *in
@@ -924,9 +1555,35 @@ func (in *Stack) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackCondition) DeepCopyInto(out *StackCondition) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackCondition.
+func (in *StackCondition) DeepCopy() *StackCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(StackCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StackControllerSpec) DeepCopyInto(out *StackControllerSpec) {
 	*out = *in
+	if in.Enable != nil {
+		in, out := &in.Enable, &out.Enable
+		*out = new(bool)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
 	return
 }
 
@@ -956,6 +1613,144 @@ func (in *StackControllerStatus) DeepCopy() *StackControllerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackHub) DeepCopyInto(out *StackHub) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackHub.
+func (in *StackHub) DeepCopy() *StackHub {
+	if in == nil {
+		return nil
+	}
+	out := new(StackHub)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StackHub) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackHubList) DeepCopyInto(out *StackHubList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]StackHub, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackHubList.
+func (in *StackHubList) DeepCopy() *StackHubList {
+	if in == nil {
+		return nil
+	}
+	out := new(StackHubList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StackHubList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackHubSpec) DeepCopyInto(out *StackHubSpec) {
+	*out = *in
+	in.Repository.DeepCopyInto(&out.Repository)
+	if in.Pipelines != nil {
+		in, out := &in.Pipelines, &out.Pipelines
+		*out = make([]PipelineSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackHubSpec.
+func (in *StackHubSpec) DeepCopy() *StackHubSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StackHubSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackHubStackStatus) DeepCopyInto(out *StackHubStackStatus) {
+	*out = *in
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackHubStackStatus.
+func (in *StackHubStackStatus) DeepCopy() *StackHubStackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StackHubStackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackHubStatus) DeepCopyInto(out *StackHubStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Stacks != nil {
+		in, out := &in.Stacks, &out.Stacks
+		*out = make([]StackHubStackStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackHubStatus.
+func (in *StackHubStatus) DeepCopy() *StackHubStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StackHubStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StackList) DeepCopyInto(out *StackList) {
 	*out = *in
@@ -992,6 +1787,16 @@ func (in *StackList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StackSpec) DeepCopyInto(out *StackSpec) {
 	*out = *in
+	if in.EventListenerRoute != nil {
+		in, out := &in.EventListenerRoute, &out.EventListenerRoute
+		*out = new(EventListenerRouteSpec)
+		**out = **in
+	}
+	if in.Workspaces != nil {
+		in, out := &in.Workspaces, &out.Workspaces
+		*out = make([]WorkspaceBindingSpec, len(*in))
+		copy(*out, *in)
+	}
 	if in.Versions != nil {
 		in, out := &in.Versions, &out.Versions
 		*out = make([]StackVersion, len(*in))
@@ -1022,6 +1827,13 @@ func (in *StackStatus) DeepCopyInto(out *StackStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]StackCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -1035,19 +1847,47 @@ func (in *StackStatus) DeepCopy() *StackStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackSummaryStatus) DeepCopyInto(out *StackSummaryStatus) {
+	*out = *in
+	if in.FailingStacks != nil {
+		in, out := &in.FailingStacks, &out.FailingStacks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackSummaryStatus.
+func (in *StackSummaryStatus) DeepCopy() *StackSummaryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StackSummaryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StackVersion) DeepCopyInto(out *StackVersion) {
 	*out = *in
 	if in.Pipelines != nil {
 		in, out := &in.Pipelines, &out.Pipelines
 		*out = make([]PipelineSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Images != nil {
 		in, out := &in.Images, &out.Images
 		*out = make([]Image, len(*in))
 		copy(*out, *in)
 	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -1074,7 +1914,9 @@ func (in *StackVersionStatus) DeepCopyInto(out *StackVersionStatus) {
 	if in.Images != nil {
 		in, out := &in.Images, &out.Images
 		*out = make([]ImageStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -1126,6 +1968,22 @@ func (in *TektonStatus) DeepCopy() *TektonStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracingSpec) DeepCopyInto(out *TracingSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingSpec.
+func (in *TracingSpec) DeepCopy() *TracingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TracingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TriggerSpec) DeepCopyInto(out *TriggerSpec) {
 	*out = *in
@@ -1143,3 +2001,51 @@ func (in *TriggerSpec) DeepCopy() *TriggerSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionSkewStatus) DeepCopyInto(out *VersionSkewStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionSkewStatus.
+func (in *VersionSkewStatus) DeepCopy() *VersionSkewStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionSkewStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityScanSpec) DeepCopyInto(out *VulnerabilityScanSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityScanSpec.
+func (in *VulnerabilityScanSpec) DeepCopy() *VulnerabilityScanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityScanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkspaceBindingSpec) DeepCopyInto(out *WorkspaceBindingSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkspaceBindingSpec.
+func (in *WorkspaceBindingSpec) DeepCopy() *WorkspaceBindingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkspaceBindingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
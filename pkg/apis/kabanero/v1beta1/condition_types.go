@@ -0,0 +1,180 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionType is the aspect of the state of a resource that a condition describes.
+type ConditionType string
+
+// ConditionReason is a machine-readable reason for the status of a condition.  Automation
+// should switch on the reason, and not parse the message.
+type ConditionReason string
+
+// Condition describes an aspect of the state of a Stack or Kabanero instance.  Stacks and
+// Kabanero instances share the same condition type in v1beta1.
+type Condition struct {
+	Type ConditionType `json:"type"`
+
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The generation of the resource that the condition was set for.  The condition is
+	// out of date if it is lower than metadata.generation.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// When the status last changed.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// One of the ConditionReason values.
+	Reason ConditionReason `json:"reason,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+// Returns true if the status of the condition is True.
+func (c Condition) IsTrue() bool {
+	return c.Status == corev1.ConditionTrue
+}
+
+// Returns the condition of the input type, or nil if it is not set.
+func FindCondition(conditions []Condition, conditionType ConditionType) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// Sets a condition.  The transition time is only updated if the status changes.
+func SetCondition(conditions *[]Condition, condition Condition) {
+	for i := range *conditions {
+		if (*conditions)[i].Type == condition.Type {
+			if (*conditions)[i].Status == condition.Status {
+				condition.LastTransitionTime = (*conditions)[i].LastTransitionTime
+			}
+			(*conditions)[i] = condition
+			return
+		}
+	}
+	*conditions = append(*conditions, condition)
+}
+
+// The types of the conditions of a Stack.
+const (
+	// All versions of the stack are in the desired state.
+	StackConditionReady ConditionType = "Ready"
+
+	// The image tag of an active stack version refers to a different digest than it did
+	// when the version was activated.
+	StackConditionDigestDrifted ConditionType = "DigestDrifted"
+
+	// An active version of the stack is deprecated, or past its end of support date.
+	StackConditionDeprecated ConditionType = "Deprecated"
+
+	// An image of an active stack version, or of a version that was blocked, has more
+	// critical vulnerabilities than allowed.
+	StackConditionVulnerable ConditionType = "Vulnerable"
+)
+
+// The reasons of the conditions of a Stack.  The reasons of failed versions and assets are
+// also reported in the status of the versions and assets.
+const (
+	// Ready: all versions are in the desired state.
+	StackReasonVersionsReady ConditionReason = "VersionsReady"
+
+	// Ready: one or more versions failed, for a reason that is not more specific.
+	StackReasonVersionsFailed ConditionReason = "VersionsFailed"
+
+	// Ready: one or more pipeline assets could not be applied, or were modified.
+	StackReasonAssetsFailed ConditionReason = "AssetsFailed"
+
+	// DigestDrifted: the tag of an image refers to a different digest.
+	StackReasonImageTagMoved ConditionReason = "ImageTagMoved"
+
+	// DigestDrifted: the tags of the images refer to the digests they were activated with.
+	StackReasonImageTagsUnchanged ConditionReason = "ImageTagsUnchanged"
+
+	// Deprecated: an active version is marked as deprecated in the stack index.
+	StackReasonVersionsDeprecated ConditionReason = "VersionsDeprecated"
+
+	// Deprecated: an active version is past its end of support date.
+	StackReasonEndOfSupport ConditionReason = "EndOfSupport"
+
+	// Deprecated: the active versions are supported.
+	StackReasonVersionsSupported ConditionReason = "VersionsSupported"
+
+	// Vulnerable: the images could not be scanned.
+	StackReasonScanFailed ConditionReason = "ScanFailed"
+
+	// Vulnerable: the scanner has not completed the scans of the images.
+	StackReasonScanPending ConditionReason = "ScanPending"
+
+	// Vulnerable: the images have no more critical vulnerabilities than allowed.
+	StackReasonNoVulnerabilities ConditionReason = "NoVulnerabilities"
+
+	// The reasons of failed versions and assets.
+	StackReasonArchiveDigestMismatch   ConditionReason = "ArchiveDigestMismatch"
+	StackReasonArchiveUnavailable      ConditionReason = "ArchiveUnavailable"
+	StackReasonArchiveTooLarge         ConditionReason = "ArchiveTooLarge"
+	StackReasonArchiveSignatureInvalid ConditionReason = "ArchiveSignatureInvalid"
+	StackReasonManifestRejected        ConditionReason = "ManifestRejected"
+	StackReasonManifestInvalid         ConditionReason = "ManifestInvalid"
+	StackReasonManifestApplyFailed     ConditionReason = "ManifestApplyFailed"
+	StackReasonAssetCheckFailed        ConditionReason = "AssetCheckFailed"
+	StackReasonAssetDrifted            ConditionReason = "AssetDrifted"
+	StackReasonActivationInterrupted   ConditionReason = "ActivationInterrupted"
+	StackReasonRegistryUnauthorized    ConditionReason = "RegistryUnauthorized"
+	StackReasonRegistryUnavailable     ConditionReason = "RegistryUnavailable"
+	StackReasonDigestLookupFailed      ConditionReason = "DigestLookupFailed"
+	StackReasonInvalidDesiredState     ConditionReason = "InvalidDesiredState"
+	StackReasonRegistryNotAllowed      ConditionReason = "RegistryNotAllowed"
+	StackReasonDigestRequired          ConditionReason = "DigestRequired"
+	StackReasonStackBanned             ConditionReason = "StackBanned"
+	StackReasonTooManyActiveVersions   ConditionReason = "TooManyActiveVersions"
+	StackReasonVulnerabilitiesFound    ConditionReason = "VulnerabilitiesFound"
+)
+
+// The types of the conditions of a Kabanero instance.  Besides Ready, each component that
+// reports its readiness has a condition, named after the component.
+const (
+	// All the components of the instance are ready.
+	KabaneroConditionReady ConditionType = "Ready"
+
+	KabaneroConditionServerlessReady                 ConditionType = "ServerlessReady"
+	KabaneroConditionTektonReady                     ConditionType = "TektonReady"
+	KabaneroConditionCliReady                        ConditionType = "CliReady"
+	KabaneroConditionLandingReady                    ConditionType = "LandingReady"
+	KabaneroConditionAppsodyReady                    ConditionType = "AppsodyReady"
+	KabaneroConditionKappnavReady                    ConditionType = "KappnavReady"
+	KabaneroConditionCodeReadyWorkspacesReady        ConditionType = "CodeReadyWorkspacesReady"
+	KabaneroConditionEventsReady                     ConditionType = "EventsReady"
+	KabaneroConditionStackControllerReady            ConditionType = "StackControllerReady"
+	KabaneroConditionAdmissionControllerWebhookReady ConditionType = "AdmissionControllerWebhookReady"
+	KabaneroConditionSsoReady                        ConditionType = "SsoReady"
+	KabaneroConditionGitopsReady                     ConditionType = "GitopsReady"
+	KabaneroConditionTargetNamespacesReady           ConditionType = "TargetNamespacesReady"
+)
+
+// The reasons of the conditions of a Kabanero instance.
+const (
+	// Ready: all the components of the instance are ready.
+	KabaneroReasonComponentsReady ConditionReason = "ComponentsReady"
+
+	// Ready: one or more components of the instance are not ready.
+	KabaneroReasonComponentsNotReady ConditionReason = "ComponentsNotReady"
+
+	// Ready: the instance could not be reconciled.
+	KabaneroReasonReconcileFailed ConditionReason = "ReconcileFailed"
+
+	// Ready: another, older, instance manages the namespace of the instance.
+	KabaneroReasonStandby ConditionReason = "Standby"
+
+	// <Component>Ready: the component is ready.
+	KabaneroReasonComponentReady ConditionReason = "ComponentReady"
+
+	// <Component>Ready: the component is not ready.  The message says why.
+	KabaneroReasonComponentNotReady ConditionReason = "ComponentNotReady"
+)
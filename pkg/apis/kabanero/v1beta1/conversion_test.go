@@ -0,0 +1,294 @@
+package v1beta1
+
+import (
+	"reflect"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var conversionStack = kabanerov1alpha2.Stack{
+	ObjectMeta: metav1.ObjectMeta{Name: "java-microprofile", Namespace: "kabanero", Generation: 3},
+	Spec: kabanerov1alpha2.StackSpec{
+		Name:           "java-microprofile",
+		DeletionPolicy: kabanerov1alpha2.AssetDeletionPolicyRetain,
+		Workspaces:     []kabanerov1alpha2.WorkspaceBindingSpec{{Name: "source", Size: "5Gi"}},
+		Versions: []kabanerov1alpha2.StackVersion{{
+			Version:      "0.2.26",
+			DesiredState: kabanerov1alpha2.StackDesiredStateActive,
+			Pipelines:    []kabanerov1alpha2.PipelineSpec{{Id: "default", Sha256: "abc", Https: kabanerov1alpha2.HttpsProtocolFile{Url: "https://github.com/kabanero-io/pipelines.tar.gz"}}},
+			Images:       []kabanerov1alpha2.Image{{Id: "java-microprofile", Image: "docker.io/kabanero/java-microprofile"}},
+		}},
+	},
+	Status: kabanerov1alpha2.StackStatus{
+		StatusMessage: "An error occurred",
+		Summary:       "[ 0.2.26: active ]",
+		Versions:      []kabanerov1alpha2.StackVersionStatus{{Version: "0.2.26", Status: kabanerov1alpha2.StackDesiredStateActive}},
+		Conditions: []kabanerov1alpha2.StackCondition{{
+			Type:               kabanerov1alpha2.StackConditionReady,
+			Status:             string(corev1.ConditionTrue),
+			Reason:             string(StackReasonVersionsReady),
+			ObservedGeneration: 3,
+		}},
+	},
+}
+
+// Test that a v1alpha2 Stack converts to v1beta1, and back, without loss.
+func TestStackConversionRoundTrip(t *testing.T) {
+	stack := &Stack{}
+	if err := stack.ConvertFrom(conversionStack.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+
+	if stack.Spec.Id != "java-microprofile" || stack.Status.Message != "An error occurred" {
+		t.Fatalf("Expected the id and message to be converted, but found %v", stack)
+	}
+	ready := FindCondition(stack.Status.Conditions, StackConditionReady)
+	if ready == nil || !ready.IsTrue() || ready.Reason != StackReasonVersionsReady || ready.ObservedGeneration != 3 {
+		t.Fatalf("Expected a Ready condition for generation 3, but found %v", stack.Status.Conditions)
+	}
+
+	hub := &kabanerov1alpha2.Stack{}
+	if err := stack.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*hub, conversionStack) {
+		t.Fatalf("Expected the stack to convert back to %v, but found %v", conversionStack, *hub)
+	}
+}
+
+// Test that the desired state of a version is lower cased, and restored when the stack
+// converts back, unless it was changed.
+func TestStackConversionDesiredState(t *testing.T) {
+	hub := conversionStack.DeepCopy()
+	hub.Annotations = map[string]string{"team": "a"}
+	hub.Spec.Versions[0].DesiredState = "Inactive"
+	original := hub.DeepCopy()
+
+	stack := &Stack{}
+	if err := stack.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if stack.Spec.Versions[0].DesiredState != kabanerov1alpha2.StackDesiredStateInactive {
+		t.Fatalf("Expected desired state inactive, but found %v", stack.Spec.Versions[0].DesiredState)
+	}
+	if stack.Annotations[DesiredStatesAnnotation] != `{"0.2.26":"Inactive"}` || stack.Annotations["team"] != "a" {
+		t.Fatalf("Expected the original desired state to be kept in an annotation, but found %v", stack.Annotations)
+	}
+	if !reflect.DeepEqual(hub, original) {
+		t.Fatalf("Expected the converted stack to be unchanged, but found %v", hub)
+	}
+
+	converted := &kabanerov1alpha2.Stack{}
+	if err := stack.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(converted, original) {
+		t.Fatalf("Expected the stack to convert back to %v, but found %v", original, converted)
+	}
+
+	// A desired state changed in v1beta1 is not restored.
+	stack.Spec.Versions[0].DesiredState = kabanerov1alpha2.StackDesiredStateActive
+	converted = &kabanerov1alpha2.Stack{}
+	if err := stack.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if converted.Spec.Versions[0].DesiredState != kabanerov1alpha2.StackDesiredStateActive {
+		t.Fatalf("Expected desired state active, but found %v", converted.Spec.Versions[0].DesiredState)
+	}
+	if _, ok := converted.Annotations[DesiredStatesAnnotation]; ok {
+		t.Fatalf("Expected the annotation to be removed, but found %v", converted.Annotations)
+	}
+}
+
+// Test that the readiness of the components of a Kabanero instance is reported as
+// conditions in v1beta1, and that the conditions are dropped in v1alpha2.
+func TestKabaneroConversionRoundTrip(t *testing.T) {
+	enable := true
+	hub := kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", Generation: 2},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			AssetDeletionPolicy: kabanerov1alpha2.AssetDeletionPolicyOrphan,
+			StackController:     kabanerov1alpha2.StackControllerSpec{Enable: &enable},
+			CommonLabels:        map[string]string{"cost-center": "1234"},
+		},
+		Status: kabanerov1alpha2.KabaneroStatus{
+			Tekton:             kabanerov1alpha2.TektonStatus{Ready: "True", Version: "0.10.1"},
+			Cli:                kabanerov1alpha2.CliStatus{Ready: "False", Message: "The CLI deployment is not available"},
+			Events:             &kabanerov1alpha2.EventsStatus{Ready: "true"},
+			ObservedGeneration: 2,
+			Conditions: []kabanerov1alpha2.KabaneroCondition{{
+				Type:               kabanerov1alpha2.KabaneroConditionReady,
+				Status:             string(corev1.ConditionFalse),
+				Reason:             kabanerov1alpha2.KabaneroReasonComponentsNotReady,
+				ObservedGeneration: 2,
+			}},
+		},
+	}
+
+	k := &Kabanero{}
+	if err := k.ConvertFrom(hub.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+
+	if k.Spec.PipelineAssets.DeletionPolicy != kabanerov1alpha2.AssetDeletionPolicyOrphan || k.Spec.CommonMetadata.Labels["cost-center"] != "1234" {
+		t.Fatalf("Expected the spec to be converted, but found %v", k.Spec)
+	}
+	expected := map[ConditionType]corev1.ConditionStatus{
+		KabaneroConditionReady:       corev1.ConditionFalse,
+		KabaneroConditionTektonReady: corev1.ConditionTrue,
+		KabaneroConditionCliReady:    corev1.ConditionFalse,
+		KabaneroConditionEventsReady: corev1.ConditionTrue,
+	}
+	if len(k.Status.Conditions) != len(expected) {
+		t.Fatalf("Expected conditions %v, but found %v", expected, k.Status.Conditions)
+	}
+	for conditionType, status := range expected {
+		condition := FindCondition(k.Status.Conditions, conditionType)
+		if condition == nil || condition.Status != status || condition.ObservedGeneration != 2 {
+			t.Fatalf("Expected condition %v to be %v, but found %v", conditionType, status, condition)
+		}
+	}
+	cli := FindCondition(k.Status.Conditions, KabaneroConditionCliReady)
+	if cli.Reason != KabaneroReasonComponentNotReady || cli.Message != "The CLI deployment is not available" {
+		t.Fatalf("Expected the CLI condition to say why it is not ready, but found %v", cli)
+	}
+
+	converted := &kabanerov1alpha2.Kabanero{}
+	if err := k.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*converted, hub) {
+		t.Fatalf("Expected the instance to convert back to %v, but found %v", hub, *converted)
+	}
+}
+
+// Test that the collection controller, which v1beta1 does not have, is kept in an
+// annotation, and restored when the instance converts back.
+func TestKabaneroConversionCollectionController(t *testing.T) {
+	hub := kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", Annotations: map[string]string{"team": "a"}},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			CollectionController: kabanerov1alpha2.CollectionControllerSpec{Version: "0.9.0"},
+		},
+	}
+
+	k := &Kabanero{}
+	if err := k.ConvertFrom(hub.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+	if k.Annotations[CollectionControllerAnnotation] != `{"version":"0.9.0"}` || k.Annotations["team"] != "a" {
+		t.Fatalf("Expected the collection controller to be kept in an annotation, but found %v", k.Annotations)
+	}
+
+	converted := &kabanerov1alpha2.Kabanero{}
+	if err := k.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*converted, hub) {
+		t.Fatalf("Expected the instance to convert back to %v, but found %v", hub, *converted)
+	}
+}
+
+// Test that every field of the v1alpha2 resources converts to v1beta1 and back.  A field
+// added to v1alpha2 fails the test until the conversion functions convert it.
+func TestConversionAllFields(t *testing.T) {
+	hub := kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero"}}
+	fillFields(reflect.ValueOf(&hub.Spec).Elem(), 0)
+	fillFields(reflect.ValueOf(&hub.Status).Elem(), 0)
+	hub.Status.Conditions[0].Status = string(corev1.ConditionTrue)
+
+	// v1beta1 does not report the collection controller, which is no longer deployed.
+	hub.Status.CollectionController = kabanerov1alpha2.CollectionControllerStatus{}
+
+	k := &Kabanero{}
+	if err := k.ConvertFrom(hub.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+	converted := &kabanerov1alpha2.Kabanero{}
+	if err := k.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*converted, hub) {
+		t.Fatalf("Expected the instance to convert back to %v, but found %v", hub, *converted)
+	}
+
+	hubStack := kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{Name: "nodejs"}}
+	fillFields(reflect.ValueOf(&hubStack.Spec).Elem(), 0)
+	fillFields(reflect.ValueOf(&hubStack.Status).Elem(), 0)
+	hubStack.Status.Conditions[0].Status = string(corev1.ConditionTrue)
+
+	stack := &Stack{}
+	if err := stack.ConvertFrom(hubStack.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+	convertedStack := &kabanerov1alpha2.Stack{}
+	if err := stack.ConvertTo(convertedStack); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*convertedStack, hubStack) {
+		t.Fatalf("Expected the stack to convert back to %v, but found %v", hubStack, *convertedStack)
+	}
+}
+
+// Sets every exported field of the input value to a value other than its zero value.
+// Slices and maps get one element.  Recursive types stop at a fixed depth.
+func fillFields(v reflect.Value, depth int) {
+	if depth > 10 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	case reflect.Ptr:
+		value := reflect.New(v.Type().Elem())
+		fillFields(value.Elem(), depth+1)
+		v.Set(value)
+	case reflect.Slice:
+		value := reflect.MakeSlice(v.Type(), 1, 1)
+		fillFields(value.Index(0), depth+1)
+		v.Set(value)
+	case reflect.Map:
+		key := reflect.New(v.Type().Key()).Elem()
+		fillFields(key, depth+1)
+		value := reflect.New(v.Type().Elem()).Elem()
+		fillFields(value, depth+1)
+		m := reflect.MakeMap(v.Type())
+		m.SetMapIndex(key, value)
+		v.Set(m)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				fillFields(v.Field(i), depth+1)
+			}
+		}
+	}
+}
+
+// Test that the defaults are set, and that set values are kept.
+func TestDefault(t *testing.T) {
+	stack := &Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs"},
+		Spec:       StackSpec{Versions: []StackVersion{{Version: "0.3.1"}, {Version: "0.3.2", DesiredState: kabanerov1alpha2.StackDesiredStateInactive}}},
+	}
+	stack.Default()
+	if stack.Spec.Id != "nodejs" || stack.Spec.Versions[0].DesiredState != kabanerov1alpha2.StackDesiredStateActive || stack.Spec.Versions[1].DesiredState != kabanerov1alpha2.StackDesiredStateInactive {
+		t.Fatalf("Unexpected stack defaults: %v", stack.Spec)
+	}
+
+	k := &Kabanero{Spec: KabaneroSpec{PipelineAssets: PipelineAssetsSpec{DriftPolicy: kabanerov1alpha2.AssetDriftPolicyReport}}}
+	k.Default()
+	if k.Spec.PipelineAssets.DeletionPolicy != kabanerov1alpha2.AssetDeletionPolicyDelete || k.Spec.PipelineAssets.DriftPolicy != kabanerov1alpha2.AssetDriftPolicyReport || k.Spec.Stacks.ConflictPolicy != kabanerov1alpha2.StackConflictPolicyFirstWins {
+		t.Fatalf("Unexpected Kabanero defaults: %v", k.Spec)
+	}
+}
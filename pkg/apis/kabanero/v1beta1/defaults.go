@@ -0,0 +1,39 @@
+package v1beta1
+
+import (
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// The defaults that the schema can express are set by the +kubebuilder:default markers.
+// The Default methods set the same values, and the ones that depend on other fields or on
+// types shared with v1alpha2, which do not carry v1beta1 markers.  They are called by the
+// defaulting webhooks of the admission webhook server.
+
+var _ admission.Defaulter = &Stack{}
+var _ admission.Defaulter = &Kabanero{}
+
+// Default sets the default values of a Stack.
+func (s *Stack) Default() {
+	if len(s.Spec.Id) == 0 {
+		s.Spec.Id = s.GetName()
+	}
+	for i := range s.Spec.Versions {
+		if len(s.Spec.Versions[i].DesiredState) == 0 {
+			s.Spec.Versions[i].DesiredState = kabanerov1alpha2.StackDesiredStateActive
+		}
+	}
+}
+
+// Default sets the default values of a Kabanero instance.
+func (k *Kabanero) Default() {
+	if len(k.Spec.PipelineAssets.DeletionPolicy) == 0 {
+		k.Spec.PipelineAssets.DeletionPolicy = kabanerov1alpha2.AssetDeletionPolicyDelete
+	}
+	if len(k.Spec.PipelineAssets.DriftPolicy) == 0 {
+		k.Spec.PipelineAssets.DriftPolicy = kabanerov1alpha2.AssetDriftPolicyRepair
+	}
+	if len(k.Spec.Stacks.ConflictPolicy) == 0 {
+		k.Spec.Stacks.ConflictPolicy = kabanerov1alpha2.StackConflictPolicyFirstWins
+	}
+}
@@ -0,0 +1,317 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var _ conversion.Convertible = &Kabanero{}
+
+// The annotation that keeps the v1alpha2 spec.collectionController, which v1beta1 does not
+// have, so that it is restored when the instance is converted back to v1alpha2.
+const CollectionControllerAnnotation = "kabanero.io/v1alpha2-collection-controller"
+
+// ConvertTo converts this Kabanero instance to the hub version, v1alpha2.  The
+// <Component>Ready conditions are not converted, as v1alpha2 reports the readiness of the
+// components in their status.
+func (src *Kabanero) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*kabanerov1alpha2.Kabanero)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = convertKabaneroSpecTo(src.Spec)
+
+	// An annotation that cannot be parsed is dropped, rather than failing the conversion,
+	// which would make the instance unreadable.
+	if annotation, ok := src.Annotations[CollectionControllerAnnotation]; ok {
+		if err := json.Unmarshal([]byte(annotation), &dst.Spec.CollectionController); err != nil {
+			dst.Spec.CollectionController = kabanerov1alpha2.CollectionControllerSpec{}
+		}
+		dst.Annotations = withoutAnnotation(src.Annotations, CollectionControllerAnnotation)
+	}
+
+	dst.Status = convertKabaneroStatusTo(src.Status)
+
+	return nil
+}
+
+// ConvertFrom converts a Kabanero instance of the hub version, v1alpha2, to this version.
+// A <Component>Ready condition is added for each component that reports its readiness.
+// The spec of the collection controller is kept in the CollectionControllerAnnotation.
+func (dst *Kabanero) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*kabanerov1alpha2.Kabanero)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Annotations = withoutAnnotation(src.Annotations, CollectionControllerAnnotation)
+
+	dst.Spec = convertKabaneroSpecFrom(src.Spec)
+	if !reflect.DeepEqual(src.Spec.CollectionController, kabanerov1alpha2.CollectionControllerSpec{}) {
+		annotation, err := json.Marshal(src.Spec.CollectionController)
+		if err != nil {
+			return err
+		}
+		annotations := map[string]string{CollectionControllerAnnotation: string(annotation)}
+		for key, value := range dst.Annotations {
+			annotations[key] = value
+		}
+		dst.Annotations = annotations
+	}
+
+	dst.Status = convertKabaneroStatusFrom(src.Status)
+
+	return nil
+}
+
+// Returns the v1alpha2 spec of the input v1beta1 spec, without the collection controller.
+func convertKabaneroSpecTo(src KabaneroSpec) kabanerov1alpha2.KabaneroSpec {
+	return kabanerov1alpha2.KabaneroSpec{
+		Version:                    src.Version,
+		Channel:                    src.Channel,
+		TargetNamespaces:           src.TargetNamespaces,
+		AllowedAssetNamespaces:     src.PipelineAssets.AllowedNamespaces,
+		AllowedAssetKinds:          src.PipelineAssets.AllowedKinds,
+		CreateAssetNamespaces:      src.PipelineAssets.CreateNamespaces,
+		TriggerNamespace:           src.PipelineAssets.TriggerNamespace,
+		AssetDeletionPolicy:        src.PipelineAssets.DeletionPolicy,
+		AssetDriftPolicy:           src.PipelineAssets.DriftPolicy,
+		Github:                     src.Github,
+		GovernancePolicy:           src.GovernancePolicy,
+		VulnerabilityScan:          src.VulnerabilityScan,
+		PipelineRunRetention:       src.PipelineRunRetention,
+		Workspaces:                 src.Workspaces,
+		Stacks:                     src.Stacks,
+		Triggers:                   src.Triggers,
+		CliServices:                src.CliServices,
+		Landing:                    src.Landing,
+		CodereadyWorkspaces:        src.CodeReadyWorkspaces,
+		Events:                     src.Events,
+		StackController:            src.StackController,
+		AdmissionControllerWebhook: src.AdmissionControllerWebhook,
+		DevfileRegistry:            src.DevfileRegistry,
+		Sso:                        src.Sso,
+		Gitops:                     src.Gitops,
+		ArtifactProxy:              src.ArtifactProxy,
+		EgressProxy:                src.EgressProxy,
+		Downloads:                  src.Downloads,
+		Logging:                    src.Logging,
+		Tracing:                    src.Tracing,
+		RegistryMirrors:            src.Registries.Mirrors,
+		RegistryCABundleConfigMap:  src.Registries.CABundleConfigMap,
+		RenderingContextConfigMap:  src.RenderingContextConfigMap,
+		Scheduling:                 src.Scheduling,
+		CommonLabels:               src.CommonMetadata.Labels,
+		CommonAnnotations:          src.CommonMetadata.Annotations,
+	}
+}
+
+// Returns the v1beta1 spec of the input v1alpha2 spec.  The collection controller is not
+// converted.
+func convertKabaneroSpecFrom(src kabanerov1alpha2.KabaneroSpec) KabaneroSpec {
+	return KabaneroSpec{
+		Version:          src.Version,
+		Channel:          src.Channel,
+		TargetNamespaces: src.TargetNamespaces,
+		PipelineAssets: PipelineAssetsSpec{
+			AllowedNamespaces: src.AllowedAssetNamespaces,
+			AllowedKinds:      src.AllowedAssetKinds,
+			CreateNamespaces:  src.CreateAssetNamespaces,
+			TriggerNamespace:  src.TriggerNamespace,
+			DeletionPolicy:    src.AssetDeletionPolicy,
+			DriftPolicy:       src.AssetDriftPolicy,
+		},
+		Github:                     src.Github,
+		GovernancePolicy:           src.GovernancePolicy,
+		VulnerabilityScan:          src.VulnerabilityScan,
+		PipelineRunRetention:       src.PipelineRunRetention,
+		Workspaces:                 src.Workspaces,
+		Stacks:                     src.Stacks,
+		Triggers:                   src.Triggers,
+		CliServices:                src.CliServices,
+		Landing:                    src.Landing,
+		CodeReadyWorkspaces:        src.CodereadyWorkspaces,
+		Events:                     src.Events,
+		StackController:            src.StackController,
+		AdmissionControllerWebhook: src.AdmissionControllerWebhook,
+		DevfileRegistry:            src.DevfileRegistry,
+		Sso:                        src.Sso,
+		Gitops:                     src.Gitops,
+		ArtifactProxy:              src.ArtifactProxy,
+		EgressProxy:                src.EgressProxy,
+		Downloads:                  src.Downloads,
+		Logging:                    src.Logging,
+		Tracing:                    src.Tracing,
+		Registries: RegistriesSpec{
+			Mirrors:           src.RegistryMirrors,
+			CABundleConfigMap: src.RegistryCABundleConfigMap,
+		},
+		RenderingContextConfigMap: src.RenderingContextConfigMap,
+		Scheduling:                src.Scheduling,
+		CommonMetadata: CommonMetadataSpec{
+			Labels:      src.CommonLabels,
+			Annotations: src.CommonAnnotations,
+		},
+	}
+}
+
+// Returns the v1alpha2 status of the input v1beta1 status, without the <Component>Ready
+// conditions.
+func convertKabaneroStatusTo(src KabaneroStatus) kabanerov1alpha2.KabaneroStatus {
+	dst := kabanerov1alpha2.KabaneroStatus{
+		KabaneroInstance:           src.KabaneroInstance,
+		Serverless:                 src.Serverless,
+		Tekton:                     src.Tekton,
+		Cli:                        src.Cli,
+		Landing:                    src.Landing,
+		Appsody:                    src.Appsody,
+		Kappnav:                    src.Kappnav,
+		CodereadyWorkspaces:        src.CodeReadyWorkspaces,
+		Events:                     src.Events,
+		StackController:            src.StackController,
+		AdmissionControllerWebhook: src.AdmissionControllerWebhook,
+		Sso:                        src.Sso,
+		Gitops:                     src.Gitops,
+		TargetNamespaces:           src.TargetNamespaces,
+		VersionSkew:                src.VersionSkew,
+		StackSummary:               src.StackSummary,
+		CollectionMigration:        src.CollectionMigration,
+		Deletion:                   src.Deletion,
+		ObservedGeneration:         src.ObservedGeneration,
+	}
+
+	components := componentConditionTypes()
+	for _, condition := range src.Conditions {
+		if components[condition.Type] {
+			continue
+		}
+		dst.Conditions = append(dst.Conditions, kabanerov1alpha2.KabaneroCondition{
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             string(condition.Reason),
+			Message:            condition.Message,
+			ObservedGeneration: condition.ObservedGeneration,
+		})
+	}
+	return dst
+}
+
+// Returns the v1beta1 status of the input v1alpha2 status, with a <Component>Ready
+// condition for each component that reports its readiness.  The status of the collection
+// controller is not converted.
+func convertKabaneroStatusFrom(src kabanerov1alpha2.KabaneroStatus) KabaneroStatus {
+	dst := KabaneroStatus{
+		KabaneroInstance:           src.KabaneroInstance,
+		Serverless:                 src.Serverless,
+		Tekton:                     src.Tekton,
+		Cli:                        src.Cli,
+		Landing:                    src.Landing,
+		Appsody:                    src.Appsody,
+		Kappnav:                    src.Kappnav,
+		CodeReadyWorkspaces:        src.CodereadyWorkspaces,
+		Events:                     src.Events,
+		StackController:            src.StackController,
+		AdmissionControllerWebhook: src.AdmissionControllerWebhook,
+		Sso:                        src.Sso,
+		Gitops:                     src.Gitops,
+		TargetNamespaces:           src.TargetNamespaces,
+		VersionSkew:                src.VersionSkew,
+		StackSummary:               src.StackSummary,
+		CollectionMigration:        src.CollectionMigration,
+		Deletion:                   src.Deletion,
+		ObservedGeneration:         src.ObservedGeneration,
+	}
+
+	for _, condition := range src.Conditions {
+		dst.Conditions = append(dst.Conditions, Condition{
+			Type:               ConditionType(condition.Type),
+			Status:             conditionStatus(condition.Status),
+			ObservedGeneration: condition.ObservedGeneration,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             ConditionReason(condition.Reason),
+			Message:            condition.Message,
+		})
+	}
+	for _, component := range componentReadiness(src) {
+		if len(component.ready) == 0 {
+			continue
+		}
+		condition := Condition{
+			Type:               component.conditionType,
+			Status:             conditionStatus(component.ready),
+			ObservedGeneration: src.ObservedGeneration,
+			Reason:             KabaneroReasonComponentReady,
+		}
+		if !condition.IsTrue() {
+			condition.Reason = KabaneroReasonComponentNotReady
+			condition.Message = component.message
+		}
+		dst.Conditions = append(dst.Conditions, condition)
+	}
+	return dst
+}
+
+// The readiness that a component reports in the v1alpha2 status.
+type componentStatus struct {
+	conditionType ConditionType
+	ready         string
+	message       string
+}
+
+// Returns the readiness of the components of the instance.  A component that is not
+// deployed reports an empty readiness.
+func componentReadiness(status kabanerov1alpha2.KabaneroStatus) []componentStatus {
+	components := []componentStatus{
+		{KabaneroConditionServerlessReady, status.Serverless.Ready, status.Serverless.Message},
+		{KabaneroConditionTektonReady, status.Tekton.Ready, status.Tekton.Message},
+		{KabaneroConditionCliReady, status.Cli.Ready, status.Cli.Message},
+		{KabaneroConditionAppsodyReady, status.Appsody.Ready, status.Appsody.Message},
+		{KabaneroConditionStackControllerReady, status.StackController.Ready, status.StackController.Message},
+		{KabaneroConditionAdmissionControllerWebhookReady, status.AdmissionControllerWebhook.Ready, status.AdmissionControllerWebhook.Message},
+		{KabaneroConditionSsoReady, status.Sso.Ready, status.Sso.Message},
+		{KabaneroConditionGitopsReady, status.Gitops.Ready, status.Gitops.Message},
+		{KabaneroConditionTargetNamespacesReady, status.TargetNamespaces.Ready, status.TargetNamespaces.Message},
+	}
+	if status.Landing != nil {
+		components = append(components, componentStatus{KabaneroConditionLandingReady, status.Landing.Ready, status.Landing.Message})
+	}
+	if status.Kappnav != nil {
+		components = append(components, componentStatus{KabaneroConditionKappnavReady, status.Kappnav.Ready, status.Kappnav.Message})
+	}
+	if status.CodereadyWorkspaces != nil {
+		components = append(components, componentStatus{KabaneroConditionCodeReadyWorkspacesReady, status.CodereadyWorkspaces.Ready, status.CodereadyWorkspaces.Message})
+	}
+	if status.Events != nil {
+		components = append(components, componentStatus{KabaneroConditionEventsReady, status.Events.Ready, status.Events.Message})
+	}
+	return components
+}
+
+// Returns the types of the <Component>Ready conditions.
+func componentConditionTypes() map[ConditionType]bool {
+	types := make(map[ConditionType]bool)
+	for _, component := range componentReadiness(kabanerov1alpha2.KabaneroStatus{
+		Landing:             &kabanerov1alpha2.KabaneroLandingPageStatus{},
+		Kappnav:             &kabanerov1alpha2.KappnavStatus{},
+		CodereadyWorkspaces: &kabanerov1alpha2.CRWStatus{},
+		Events:              &kabanerov1alpha2.EventsStatus{},
+	}) {
+		types[component.conditionType] = true
+	}
+	return types
+}
+
+// Returns the condition status of the input v1alpha2 status, which the components report
+// in any case.
+func conditionStatus(status string) corev1.ConditionStatus {
+	switch {
+	case strings.EqualFold(status, string(corev1.ConditionTrue)):
+		return corev1.ConditionTrue
+	case strings.EqualFold(status, string(corev1.ConditionFalse)):
+		return corev1.ConditionFalse
+	}
+	return corev1.ConditionUnknown
+}
@@ -0,0 +1,270 @@
+package v1beta1
+
+import (
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// NOTE: The +listType=set marker is required by OpenAPI generation for list types.
+
+// The v1beta1 Kabanero differs from the v1alpha2 Kabanero in that:
+//  - the settings of the pipeline assets are grouped in spec.pipelineAssets.
+//  - the settings of the image registries are grouped in spec.registries.
+//  - the labels and annotations added to the resources are grouped in spec.commonMetadata.
+//  - the collection controller, which is no longer deployed, is not configured or reported.
+//  - the status of CodeReady Workspaces is status.codeReadyWorkspaces, as in the spec.
+//  - conditions are Conditions, with an observed generation and a typed reason.
+//  - the readiness of each component is also reported as a <Component>Ready condition.
+//  - the schema validates and defaults the fields that the admission webhook checked.
+// The types that did not change are shared with v1alpha2.
+
+// KabaneroSpec defines the desired state of Kabanero
+// +k8s:openapi-gen=true
+// +kubebuilder:validation:XValidation:rule="!has(self.stacks) || !has(self.stacks.conflictPolicy) || self.stacks.conflictPolicy in ['first-wins', 'error'] || self.stacks.conflictPolicy.startsWith('prefer-repository:')",message="stacks.conflictPolicy must be first-wins, error, or prefer-repository:<repository name>"
+// +kubebuilder:validation:XValidation:rule="!has(self.workspaces) || self.workspaces.all(w, !has(w.accessMode) || w.accessMode in ['ReadWriteOnce', 'ReadOnlyMany', 'ReadWriteMany'])",message="the accessMode of a workspace must be ReadWriteOnce, ReadOnlyMany or ReadWriteMany"
+// +kubebuilder:validation:XValidation:rule="!has(self.pipelineRunRetention) || !has(self.pipelineRunRetention.maxCount) || self.pipelineRunRetention.maxCount >= 0",message="pipelineRunRetention.maxCount must not be negative"
+type KabaneroSpec struct {
+	Version string `json:"version,omitempty"`
+
+	// The channel that the software revisions of the components are chosen from.  The
+	// latest revision of each component in the channel is used.  The default is stable.
+	Channel string `json:"channel,omitempty"`
+
+	// +listType=set
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+
+	// Where the pipeline assets of the stacks and gitops pipelines are created, and what
+	// happens to them when they are modified or no longer used.
+	PipelineAssets PipelineAssetsSpec `json:"pipelineAssets,omitempty"`
+
+	Github kabanerov1alpha2.GithubConfig `json:"github,omitempty"`
+
+	GovernancePolicy kabanerov1alpha2.GovernancePolicyConfig `json:"governancePolicy,omitempty"`
+
+	// The image scanner that is asked about the images of a stack version before it is
+	// activated.
+	VulnerabilityScan kabanerov1alpha2.VulnerabilityScanSpec `json:"vulnerabilityScan,omitempty"`
+
+	// How many completed PipelineRuns and TaskRuns of the Kabanero pipelines are kept.
+	PipelineRunRetention kabanerov1alpha2.PipelineRunRetentionSpec `json:"pipelineRunRetention,omitempty"`
+
+	// The storage bound to the workspaces of the PipelineRuns created by the TriggerTemplates
+	// in pipeline archives, so that archives do not depend on the storage of a cluster.
+	// +listType=map
+	// +listMapKey=name
+	Workspaces []kabanerov1alpha2.WorkspaceBindingSpec `json:"workspaces,omitempty"`
+
+	Stacks kabanerov1alpha2.InstanceStackConfig `json:"stacks,omitempty"`
+
+	// +listType=map
+	// +listMapKey=id
+	// +listMapKey=sha256
+	Triggers []kabanerov1alpha2.TriggerSpec `json:"triggers,omitempty"`
+
+	CliServices kabanerov1alpha2.KabaneroCliServicesCustomizationSpec `json:"cliServices,omitempty"`
+
+	Landing kabanerov1alpha2.KabaneroLandingCustomizationSpec `json:"landing,omitempty"`
+
+	CodeReadyWorkspaces kabanerov1alpha2.CRWCustomizationSpec `json:"codeReadyWorkspaces,omitempty"`
+
+	Events kabanerov1alpha2.EventsCustomizationSpec `json:"events,omitempty"`
+
+	StackController kabanerov1alpha2.StackControllerSpec `json:"stackController,omitempty"`
+
+	AdmissionControllerWebhook kabanerov1alpha2.AdmissionControllerWebhookCustomizationSpec `json:"admissionControllerWebhook,omitempty"`
+
+	DevfileRegistry kabanerov1alpha2.DevfileRegistrySpec `json:"devfileRegistry,omitempty"`
+
+	Sso kabanerov1alpha2.SsoCustomizationSpec `json:"sso,omitempty"`
+
+	Gitops kabanerov1alpha2.GitopsSpec `json:"gitops,omitempty"`
+
+	ArtifactProxy kabanerov1alpha2.ArtifactProxySpec `json:"artifactProxy,omitempty"`
+
+	EgressProxy kabanerov1alpha2.EgressProxySpec `json:"egressProxy,omitempty"`
+
+	Downloads kabanerov1alpha2.DownloadLimitsSpec `json:"downloads,omitempty"`
+
+	Logging kabanerov1alpha2.LoggingSpec `json:"logging,omitempty"`
+
+	Tracing kabanerov1alpha2.TracingSpec `json:"tracing,omitempty"`
+
+	// The mirrors of the image registries, and the certificates trusted when connecting
+	// to them.
+	Registries RegistriesSpec `json:"registries,omitempty"`
+
+	// The name of a ConfigMap in the Kabanero namespace, whose entries are made available
+	// to the templates in pipeline archives.  Values set by the operator take precedence.
+	RenderingContextConfigMap string `json:"renderingContextConfigMap,omitempty"`
+
+	// Where the pods of the deployments created by the operator are scheduled.
+	Scheduling kabanerov1alpha2.SchedulingSpec `json:"scheduling,omitempty"`
+
+	// The labels and annotations added to the resources the operator creates for the
+	// instance, and to the pipeline assets.
+	CommonMetadata CommonMetadataSpec `json:"commonMetadata,omitempty"`
+}
+
+// PipelineAssetsSpec defines where pipeline assets are created, and how they are managed.
+// +k8s:openapi-gen=true
+type PipelineAssetsSpec struct {
+	// The namespaces, other than the namespace of the owning resource, into which
+	// pipeline assets may be created.  If empty, assets may be created in any
+	// namespace preset in their manifest.
+	// +listType=set
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// The kinds of resources, in addition to those in the tekton.dev and
+	// triggers.tekton.dev groups, that pipeline archives may contain.
+	AllowedKinds []kabanerov1alpha2.AssetGroupKind `json:"allowedKinds,omitempty"`
+
+	// Create the namespaces preset in pipeline asset manifests when they do not
+	// exist.  The namespaces are labelled with the owning Kabanero instance.
+	CreateNamespaces bool `json:"createNamespaces,omitempty"`
+
+	// The namespace that the Tekton triggers run in.  Trigger assets that preset the
+	// tekton-pipelines namespace are created in this namespace instead.  Defaults to
+	// tekton-pipelines.
+	TriggerNamespace string `json:"triggerNamespace,omitempty"`
+
+	// What happens to a pipeline asset when it is no longer used by any stack or
+	// gitops pipeline.  One of Delete (the default), Orphan, or Retain.  A Stack
+	// may override this value.
+	// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+	// +kubebuilder:default=Delete
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// What happens when a pipeline asset was modified after it was applied.  One of
+	// Repair (the default), which applies the asset again, or Report, which marks the
+	// asset as drifted and leaves it alone.
+	// +kubebuilder:validation:Enum=Repair;Report
+	// +kubebuilder:default=Repair
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+}
+
+// RegistriesSpec defines how the images of the stacks are resolved.
+// +k8s:openapi-gen=true
+type RegistriesSpec struct {
+	// Maps an image registry to the registry that mirrors it, for example
+	// docker.io: registry.internal:5000.  Stack images are resolved using the mirror.
+	Mirrors map[string]string `json:"mirrors,omitempty"`
+
+	// The name of a ConfigMap in the Kabanero namespace, whose ca-bundle.crt entry holds
+	// additional CA certificates that are trusted when connecting to image registries.
+	CABundleConfigMap string `json:"caBundleConfigMap,omitempty"`
+}
+
+// CommonMetadataSpec defines the metadata added to the resources of the instance.
+// +k8s:openapi-gen=true
+type CommonMetadataSpec struct {
+	// Labels added to the resources the operator creates for the instance, and to the
+	// pipeline assets.  For example, cost allocation or backup selectors.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations added to the resources the operator creates for the instance, and to
+	// the pipeline assets.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// KabaneroStatus defines the observed state of the Kabanero instance.
+// +k8s:openapi-gen=true
+type KabaneroStatus struct {
+	// Kabanero operator instance readiness status. The status is directly correlated to the availability of resources dependencies.
+	KabaneroInstance kabanerov1alpha2.KabaneroInstanceStatus `json:"kabaneroInstance,omitempty"`
+
+	// OpenShift serverless operator status.
+	Serverless kabanerov1alpha2.ServerlessStatus `json:"serverless,omitempty"`
+
+	// Tekton instance readiness status.
+	Tekton kabanerov1alpha2.TektonStatus `json:"tekton,omitempty"`
+
+	// CLI readiness status.
+	Cli kabanerov1alpha2.CliStatus `json:"cli,omitempty"`
+
+	// Kabanero Landing page readiness status.
+	Landing *kabanerov1alpha2.KabaneroLandingPageStatus `json:"landing,omitempty"`
+
+	// Appsody instance readiness status.
+	Appsody kabanerov1alpha2.AppsodyStatus `json:"appsody,omitempty"`
+
+	// Kabanero Application Navigator instance readiness status.
+	Kappnav *kabanerov1alpha2.KappnavStatus `json:"kappnav,omitempty"`
+
+	// Codeready-workspaces instance readiness status.
+	CodeReadyWorkspaces *kabanerov1alpha2.CRWStatus `json:"codeReadyWorkspaces,omitempty"`
+
+	// Events instance status
+	Events *kabanerov1alpha2.EventsStatus `json:"events,omitempty"`
+
+	// Kabanero stack controller readiness status.
+	StackController kabanerov1alpha2.StackControllerStatus `json:"stackController,omitempty"`
+
+	// Admission webhook instance status
+	AdmissionControllerWebhook kabanerov1alpha2.AdmissionControllerWebhookStatus `json:"admissionControllerWebhook,omitempty"`
+
+	// SSO server status
+	Sso kabanerov1alpha2.SsoStatus `json:"sso,omitempty"`
+
+	Gitops kabanerov1alpha2.GitopsStatus `json:"gitops,omitempty"`
+
+	// Target namespace status
+	TargetNamespaces kabanerov1alpha2.TargetNamespaceStatus `json:"targetNamespaces,omitempty"`
+
+	// Version skew between the operator and the components it deployed.
+	VersionSkew kabanerov1alpha2.VersionSkewStatus `json:"versionSkew,omitempty"`
+
+	// The number of stacks in each state.
+	StackSummary kabanerov1alpha2.StackSummaryStatus `json:"stackSummary,omitempty"`
+
+	// The progress of the migration of the v1alpha1 Collection resources to Stack resources.
+	// Not set when there are no collections.
+	CollectionMigration *kabanerov1alpha2.CollectionMigrationStatus `json:"collectionMigration,omitempty"`
+
+	// The progress of the deletion of the instance.  Only set while the instance is deleted.
+	Deletion *kabanerov1alpha2.KabaneroDeletionStatus `json:"deletion,omitempty"`
+
+	// The generation of the instance that the status was computed for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The Ready condition, and a <Component>Ready condition for each component that
+	// reports its readiness.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// Kabanero is the Schema for the kabaneros API
+// Note that kubebuilder and operator-sdk currently disagree about what the
+// plural of this type should be.  The +kubebuilder:resource marker sets the
+// plural to what operator-sdk expects.
+
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="CreationTimestamp is a timestamp representing the server time when this object was created. It is not guaranteed to be set in happens-before order across separate operations."
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.kabaneroInstance.version",description="Kabanero operator instance version."
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether all the components of the instance are ready."
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason",description="Why the instance is or is not ready."
+// +kubebuilder:resource:path=kabaneros,scope=Namespaced,shortName=kab
+type Kabanero struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KabaneroSpec   `json:"spec,omitempty"`
+	Status KabaneroStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KabaneroList contains a list of Kabanero
+type KabaneroList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// +listType=set
+	Items []Kabanero `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Kabanero{}, &KabaneroList{})
+}
@@ -0,0 +1,193 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"strings"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var _ conversion.Convertible = &Stack{}
+
+// The annotation that keeps the desired states of the v1alpha2 versions that are not lower
+// case, as in {"0.2.26": "Active"}.  v1beta1 only accepts lower case desired states, so they
+// are restored from the annotation when the Stack is converted back to v1alpha2.
+const DesiredStatesAnnotation = "kabanero.io/v1alpha2-desired-states"
+
+// ConvertTo converts this Stack to the hub version, v1alpha2.
+func (src *Stack) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*kabanerov1alpha2.Stack)
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	// An annotation that cannot be parsed is dropped, rather than failing the conversion,
+	// which would make the Stack unreadable.
+	desiredStates := map[string]string{}
+	if annotation, ok := src.Annotations[DesiredStatesAnnotation]; ok {
+		if err := json.Unmarshal([]byte(annotation), &desiredStates); err != nil {
+			desiredStates = map[string]string{}
+		}
+		dst.Annotations = withoutAnnotation(src.Annotations, DesiredStatesAnnotation)
+	}
+
+	dst.Spec.Name = src.Spec.Id
+	dst.Spec.DeletionPolicy = src.Spec.DeletionPolicy
+	dst.Spec.RenderingContextConfigMap = src.Spec.RenderingContextConfigMap
+	dst.Spec.EventListenerRoute = src.Spec.EventListenerRoute
+	dst.Spec.Workspaces = src.Spec.Workspaces
+	dst.Spec.Versions = nil
+	if src.Spec.Versions != nil {
+		dst.Spec.Versions = make([]kabanerov1alpha2.StackVersion, len(src.Spec.Versions))
+		for i, version := range src.Spec.Versions {
+			dst.Spec.Versions[i] = convertStackVersionTo(version)
+
+			// The desired state is only restored if it was not changed in v1beta1.
+			if desiredState, ok := desiredStates[version.Version]; ok && strings.ToLower(desiredState) == version.DesiredState {
+				dst.Spec.Versions[i].DesiredState = desiredState
+			}
+		}
+	}
+
+	dst.Status.StatusMessage = src.Status.Message
+	dst.Status.Versions = src.Status.Versions
+	dst.Status.Summary = src.Status.Summary
+	dst.Status.DetailsConfigMap = src.Status.DetailsConfigMap
+	dst.Status.Conditions = nil
+	if src.Status.Conditions != nil {
+		dst.Status.Conditions = make([]kabanerov1alpha2.StackCondition, len(src.Status.Conditions))
+		for i, condition := range src.Status.Conditions {
+			dst.Status.Conditions[i] = kabanerov1alpha2.StackCondition{
+				Type:               string(condition.Type),
+				Status:             string(condition.Status),
+				LastTransitionTime: condition.LastTransitionTime,
+				Reason:             string(condition.Reason),
+				Message:            condition.Message,
+				ObservedGeneration: condition.ObservedGeneration,
+			}
+		}
+	}
+
+	return nil
+}
+
+// ConvertFrom converts a Stack of the hub version, v1alpha2, to this version.  The desired
+// state of the versions is lower cased, as v1alpha2 accepts it in any case.  The desired
+// states that change are kept in the DesiredStatesAnnotation.
+func (dst *Stack) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*kabanerov1alpha2.Stack)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Annotations = withoutAnnotation(src.Annotations, DesiredStatesAnnotation)
+
+	dst.Spec.Id = src.Spec.Name
+	dst.Spec.DeletionPolicy = src.Spec.DeletionPolicy
+	dst.Spec.RenderingContextConfigMap = src.Spec.RenderingContextConfigMap
+	dst.Spec.EventListenerRoute = src.Spec.EventListenerRoute
+	dst.Spec.Workspaces = src.Spec.Workspaces
+	dst.Spec.Versions = nil
+	if src.Spec.Versions != nil {
+		dst.Spec.Versions = make([]StackVersion, len(src.Spec.Versions))
+		desiredStates := map[string]string{}
+		for i, version := range src.Spec.Versions {
+			dst.Spec.Versions[i] = convertStackVersionFrom(version)
+			if dst.Spec.Versions[i].DesiredState != version.DesiredState {
+				desiredStates[version.Version] = version.DesiredState
+			}
+		}
+
+		if len(desiredStates) != 0 {
+			annotation, err := json.Marshal(desiredStates)
+			if err != nil {
+				return err
+			}
+			annotations := map[string]string{DesiredStatesAnnotation: string(annotation)}
+			for key, value := range dst.Annotations {
+				annotations[key] = value
+			}
+			dst.Annotations = annotations
+		}
+	}
+
+	dst.Status.Message = src.Status.StatusMessage
+	dst.Status.Versions = src.Status.Versions
+	dst.Status.Summary = src.Status.Summary
+	dst.Status.DetailsConfigMap = src.Status.DetailsConfigMap
+	dst.Status.Conditions = convertConditionsFrom(src.Status.Conditions)
+
+	return nil
+}
+
+// Returns the v1alpha2 stack version of the input v1beta1 stack version.
+func convertStackVersionTo(src StackVersion) kabanerov1alpha2.StackVersion {
+	return kabanerov1alpha2.StackVersion{
+		SkipRegistryCertVerification: src.SkipRegistryCertVerification,
+		Pipelines:                    src.Pipelines,
+		Version:                      src.Version,
+		DesiredState:                 src.DesiredState,
+		SkipCertVerification:         src.SkipCertVerification,
+		Images:                       src.Images,
+		Devfile:                      src.Devfile,
+		Metafile:                     src.Metafile,
+		RepositoryUrl:                src.RepositoryUrl,
+		ImagePullSecrets:             src.ImagePullSecrets,
+		Deprecated:                   src.Deprecated,
+		EndOfSupport:                 src.EndOfSupport,
+	}
+}
+
+// Returns the v1beta1 stack version of the input v1alpha2 stack version.  The desired
+// state is lower cased.
+func convertStackVersionFrom(src kabanerov1alpha2.StackVersion) StackVersion {
+	return StackVersion{
+		SkipRegistryCertVerification: src.SkipRegistryCertVerification,
+		Pipelines:                    src.Pipelines,
+		Version:                      src.Version,
+		DesiredState:                 strings.ToLower(src.DesiredState),
+		SkipCertVerification:         src.SkipCertVerification,
+		Images:                       src.Images,
+		Devfile:                      src.Devfile,
+		Metafile:                     src.Metafile,
+		RepositoryUrl:                src.RepositoryUrl,
+		ImagePullSecrets:             src.ImagePullSecrets,
+		Deprecated:                   src.Deprecated,
+		EndOfSupport:                 src.EndOfSupport,
+	}
+}
+
+// Returns the v1beta1 conditions of the input v1alpha2 stack conditions.
+func convertConditionsFrom(conditions []kabanerov1alpha2.StackCondition) []Condition {
+	if conditions == nil {
+		return nil
+	}
+	converted := make([]Condition, len(conditions))
+	for i, condition := range conditions {
+		converted[i] = Condition{
+			Type:               ConditionType(condition.Type),
+			Status:             conditionStatus(condition.Status),
+			ObservedGeneration: condition.ObservedGeneration,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             ConditionReason(condition.Reason),
+			Message:            condition.Message,
+		}
+	}
+	return converted
+}
+
+// Returns a copy of the input annotations without the input annotation.  The annotations
+// are shared with the object being converted, so they are not changed.
+func withoutAnnotation(annotations map[string]string, name string) map[string]string {
+	if _, ok := annotations[name]; !ok {
+		return annotations
+	}
+	copied := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		if key != name {
+			copied[key] = value
+		}
+	}
+	if len(copied) == 0 {
+		return nil
+	}
+	return copied
+}
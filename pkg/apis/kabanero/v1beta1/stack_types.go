@@ -0,0 +1,130 @@
+package v1beta1
+
+import (
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// NOTE: The +listType=set marker are required by OpenAPI generation for list types.
+
+// The v1beta1 Stack differs from the v1alpha2 Stack in that:
+//  - the stack id is spec.id, and not spec.name.
+//  - the status message is status.message, and not status.statusMessage.
+//  - conditions are Conditions, with an observed generation and a typed reason.
+//  - the schema validates and defaults the fields that the admission webhook checked.
+// The types that did not change are shared with v1alpha2.
+
+// StackSpec defines the desired composition of a Stack
+// +k8s:openapi-gen=true
+// +kubebuilder:validation:XValidation:rule="!has(self.eventListenerRoute) || !has(self.eventListenerRoute.kind) || self.eventListenerRoute.kind != 'Ingress' || has(self.eventListenerRoute.host)",message="an Ingress eventListenerRoute requires a host"
+// +kubebuilder:validation:XValidation:rule="!has(self.eventListenerRoute) || !has(self.eventListenerRoute.tlsSecretName) || (has(self.eventListenerRoute.kind) && self.eventListenerRoute.kind == 'Ingress')",message="only an Ingress eventListenerRoute can set tlsSecretName"
+type StackSpec struct {
+	// The id of the stack, which is the name of its Appsody stack directory.  Defaults to
+	// the name of the Stack resource.
+	// +kubebuilder:validation:MaxLength=68
+	// +kubebuilder:validation:Pattern=`^[a-z]([a-z0-9-]*[a-z0-9])?$`
+	Id string `json:"id,omitempty"`
+	// What happens to the pipeline assets of this stack when they are no longer used.
+	// Overrides the assetDeletionPolicy of the Kabanero instance.
+	// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+	// The name of a ConfigMap in the stack's namespace, whose entries are made available
+	// to the templates in pipeline archives.  Its entries override those of the
+	// renderingContextConfigMap of the Kabanero instance.
+	RenderingContextConfigMap string `json:"renderingContextConfigMap,omitempty"`
+	// When set, the EventListeners in the pipeline archives of this stack are exposed by a
+	// Route or an Ingress, and their URLs are reported in the pipeline status.
+	EventListenerRoute *kabanerov1alpha2.EventListenerRouteSpec `json:"eventListenerRoute,omitempty"`
+	// The storage bound to the workspaces of the PipelineRuns created by the TriggerTemplates
+	// of this stack.  Overrides the workspaces of the Kabanero instance with the same name.
+	// +listType=map
+	// +listMapKey=name
+	Workspaces []kabanerov1alpha2.WorkspaceBindingSpec `json:"workspaces,omitempty"`
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=version
+	Versions []StackVersion `json:"versions,omitempty"`
+}
+
+// StackVersion defines the desired composition of a specific stack version.
+// +kubebuilder:validation:XValidation:rule="!has(self.pipelines) || self.pipelines.all(p, (has(p.https) && has(p.https.url)) || (has(p.gitRelease) && has(p.gitRelease.assetName)) || (has(p.oci) && has(p.oci.bundle)))",message="each pipeline must set https.url, gitRelease or oci.bundle"
+type StackVersion struct {
+	SkipRegistryCertVerification bool `json:"skipRegistryCertVerification,omitempty"`
+
+	// +listType=map
+	// +listMapKey=id
+	// +listMapKey=sha256
+	Pipelines []kabanerov1alpha2.PipelineSpec `json:"pipelines,omitempty"`
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+	// +kubebuilder:validation:Enum=active;inactive
+	// +kubebuilder:default=active
+	DesiredState         string `json:"desiredState,omitempty"`
+	SkipCertVerification bool   `json:"skipCertVerification,omitempty"`
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=id
+	// +listMapKey=image
+	Images   []kabanerov1alpha2.Image `json:"images,omitempty"`
+	Devfile  string                   `json:"devfile,omitempty"`
+	Metafile string                   `json:"metafile,omitempty"`
+	// The location of the repository that this version was read from.
+	RepositoryUrl string `json:"repositoryUrl,omitempty"`
+	// The names of secrets in the stack's namespace that hold credentials for the
+	// image registry.  They are tried in order, before the Kabanero instance's
+	// defaults, when the image digests are retrieved.
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// True if the stack index marks this version as deprecated.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// The date this version stops being supported, as in 2021-06-30, or an RFC 3339 time.
+	EndOfSupport string `json:"endOfSupport,omitempty"`
+}
+
+// StackStatus defines the observed state of a stack
+// +k8s:openapi-gen=true
+type StackStatus struct {
+	Message string `json:"message,omitempty"`
+	// +listType=map
+	// +listMapKey=version
+	Versions []kabanerov1alpha2.StackVersionStatus `json:"versions,omitempty"`
+	Summary  string                                `json:"summary,omitempty"`
+	// The ConfigMap holding the full asset status messages, if the status was
+	// compacted because it was too large.
+	DetailsConfigMap string `json:"detailsConfigMap,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Stack is the Schema for the stack API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="CreationTimestamp is a timestamp representing the server time when this object was created. It is not guaranteed to be set in happens-before order across separate operations."
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether all versions of the stack are in the desired state."
+// +kubebuilder:printcolumn:name="Active",type="string",JSONPath=".status.versions[?(@.status==\"active\")].version",description="The active stack versions."
+// +kubebuilder:printcolumn:name="Summary",type="string",JSONPath=".status.summary",description="Stack summary."
+// +kubebuilder:resource:path=stacks,scope=Namespaced,shortName=stk
+type Stack struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StackSpec   `json:"spec,omitempty"`
+	Status StackStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StackList contains a list of Stacks
+type StackList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	// +listType=set
+	Items []Stack `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Stack{}, &StackList{})
+}
@@ -2,10 +2,12 @@ package kabaneroplatform
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
 
 	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
 	corev1 "k8s.io/api/core/v1"
@@ -19,6 +21,9 @@ import (
 	"strings"
 )
 
+// The ConfigMap that the OpenShift service CA injects its CA bundle into.
+const webhookCACertConfigMapName = "kabanero-operator-admission-webhook-ca-cert"
+
 func reconcileAdmissionControllerWebhook(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
 
 	// Figure out what version of the orchestration we are going to use.
@@ -95,6 +100,9 @@ func reconcileAdmissionControllerWebhook(ctx context.Context, k *kabanerov1alpha
 	transforms := []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
+		injectCommonMetadata(k),
+		injectScheduling(k),
+		kabTransforms.SetContainerResources(k.Spec.AdmissionControllerWebhook.Resources),
 	}
 
 	m, err := mOrig.Transform(transforms...)
@@ -114,10 +122,21 @@ func reconcileAdmissionControllerWebhook(ctx context.Context, k *kabanerov1alpha
 	// into a secret, which the webhook pod will use.  The CA certificate needs
 	// to be injected into the mutating webhook configuration and validating
 	// webhook configuration, so that the Kube API server trusts the pod(s).
-	if rev.Version != "0.4.0" {
+	//
+	// The OpenShift service CA rotates both.  The webhook server reloads the certificate
+	// when the secret changes, and a change to the CA ConfigMap triggers a reconcile,
+	// which copies the new CA bundle into the webhook configurations.
+	//
+	// The webhook configurations are cluster level, so only the shared owner points them
+	// at its webhook.
+	sharedOwner, err := isSharedOwner(ctx, k, c)
+	if err != nil {
+		return err
+	}
+	if rev.Version != "0.4.0" && sharedOwner {
 		cmInstance := &corev1.ConfigMap{}
 		err = c.Get(context.Background(), types.NamespacedName{
-			Name:      "kabanero-operator-admission-webhook-ca-cert",
+			Name:      webhookCACertConfigMapName,
 			Namespace: k.GetNamespace()}, cmInstance)
 		if err != nil {
 			message := "The webhook configuration could not be created"
@@ -133,6 +152,13 @@ func reconcileAdmissionControllerWebhook(ctx context.Context, k *kabanerov1alpha
 			return err
 		}
 
+		// A bundle the API server cannot use would reject every stack and Kabanero change.
+		if !x509.NewCertPool().AppendCertsFromPEM([]byte(caCert)) {
+			err = fmt.Errorf("The service CA injected into the %v configmap could not be parsed", webhookCACertConfigMapName)
+			reqLogger.Error(err, "Error creating webhook")
+			return err
+		}
+
 		// Create the mutating webhook and validating webhook configuration
 		encoded := base64.StdEncoding.EncodeToString([]byte(caCert))
 		templateContext["caBundle"] = encoded
@@ -162,8 +188,9 @@ func reconcileAdmissionControllerWebhook(ctx context.Context, k *kabanerov1alpha
 }
 
 // Removes the admission webhook server, as well as the resources
-// created by controller-runtime that support the webhook.
-func cleanupAdmissionControllerWebhook(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+// created by controller-runtime that support the webhook.  The cluster level
+// webhook configurations are only removed when removeConfigurations is true.
+func cleanupAdmissionControllerWebhook(k *kabanerov1alpha2.Kabanero, c client.Client, removeConfigurations bool, reqLogger logr.Logger) error {
 
 	rev, err := resolveSoftwareRevision(k, "admission-webhook", k.Spec.AdmissionControllerWebhook.Version)
 	if err != nil {
@@ -210,7 +237,7 @@ func cleanupAdmissionControllerWebhook(k *kabanerov1alpha2.Kabanero, c client.Cl
 	}
 
 	// The webhook configs are only created by manifestival later than Kabanero 0.4.0.
-	if rev.Version != "0.4.0" {
+	if rev.Version != "0.4.0" && removeConfigurations {
 		f, err := rev.OpenOrchestration("kabanero-operator-admission-webhook-config.yaml")
 		if err != nil {
 			return err
@@ -255,20 +282,22 @@ func cleanupAdmissionControllerWebhook(k *kabanerov1alpha2.Kabanero, c client.Cl
 			return err
 		}
 
-		mutatingWebhookConfigInstance := &admissionregistrationv1beta1.MutatingWebhookConfiguration{}
-		mutatingWebhookConfigInstance.Name = "webhook.operator.kabanero.io"
-		err = c.Delete(context.TODO(), mutatingWebhookConfigInstance)
+		if removeConfigurations {
+			mutatingWebhookConfigInstance := &admissionregistrationv1beta1.MutatingWebhookConfiguration{}
+			mutatingWebhookConfigInstance.Name = "webhook.operator.kabanero.io"
+			err = c.Delete(context.TODO(), mutatingWebhookConfigInstance)
 
-		if (err != nil) && (errors.IsNotFound(err) == false) {
-			return err
-		}
+			if (err != nil) && (errors.IsNotFound(err) == false) {
+				return err
+			}
 
-		validatingWebhookConfigInstance := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{}
-		validatingWebhookConfigInstance.Name = "webhook.operator.kabanero.io"
-		err = c.Delete(context.TODO(), validatingWebhookConfigInstance)
+			validatingWebhookConfigInstance := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{}
+			validatingWebhookConfigInstance.Name = "webhook.operator.kabanero.io"
+			err = c.Delete(context.TODO(), validatingWebhookConfigInstance)
 
-		if (err != nil) && (errors.IsNotFound(err) == false) {
-			return err
+			if (err != nil) && (errors.IsNotFound(err) == false) {
+				return err
+			}
 		}
 	}
 
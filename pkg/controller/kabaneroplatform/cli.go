@@ -9,10 +9,12 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
 	mfc "github.com/manifestival/controller-runtime-client"
 	mf "github.com/manifestival/manifestival"
 	routev1 "github.com/openshift/api/route/v1"
@@ -23,10 +25,41 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// The name of the secret holding the key the CLI encrypts its tokens with.
+const cliEncryptionKeySecretName = "kabanero-cli-aes-encryption-key-secret"
+
+// The key of the encryption key in the secret.
+const cliEncryptionKeyKey = "AESEncryptionKey"
+
+// Annotation on a Kabanero instance that rotates the CLI encryption key.  Each new
+// annotation value rotates the key again, so a timestamp is a good choice of value.
+// The CLI only decrypts its tokens with the current key, so a rotation ends the existing
+// CLI sessions.
+const cliEncryptionKeyRotateAnnotation = "kabanero.io/rotate-cli-encryption-key"
+
+// Annotations on the encryption key secret, holding the last rotation annotation value
+// that was processed, and when the key was last rotated.  The rotation time is also set
+// on the CLI pod template, so that the CLI pods are rolled when the key changes.
+const (
+	cliEncryptionKeyRotationRequestAnnotation = "kabanero.io/rotation-request"
+	cliEncryptionKeyRotatedAtAnnotation       = "kabanero.io/encryption-key-rotated-at"
+)
+
+// Reconciles the Kabanero CLI service.
+func reconcileKabaneroCliServices(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) componentResult {
+	return resultFromError(reconcileKabaneroCli(ctx, k, cl, reqLogger))
+}
+
 // Reconciles the Kabanero CLI service.
 func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) error {
-	// Create the AES encryption key secret, if we don't already have one
-	err := createEncryptionKeySecret(k, cl, reqLogger)
+	// If the CLI services are disabled, remove them.
+	if !k.Spec.CliServices.IsEnabled() {
+		return cleanupKabaneroCli(ctx, k, cl, reqLogger)
+	}
+
+	// Create the AES encryption key secret, if we don't already have one, and rotate the key
+	// if it was requested.
+	keyRotatedAt, err := reconcileEncryptionKeySecret(ctx, k, cl, reqLogger)
 	if err != nil {
 		return err
 	}
@@ -63,11 +96,33 @@ func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl
 	}
 
 	usingPassthroughTLS := strings.HasSuffix(rev.OrchestrationPath, "0.1")
-	transformedManifest, err := processTransformation(k, m, usingPassthroughTLS, reqLogger)
+	if usingPassthroughTLS && k.Spec.CliServices.Route.Termination == kabanerov1alpha2.RouteTerminationReencrypt {
+		return fmt.Errorf("Kabanero %v Spec.CliServices.Route.Termination reencrypt requires a CLI services version whose route uses reencrypt TLS termination", k.Name)
+	}
+
+	transformedManifest, err := processTransformation(k, m, usingPassthroughTLS, keyRotatedAt, reqLogger)
 	if err != nil {
 		return err
 	}
 
+	// Present the certificate of the CLI TLS secret on the route, if one was provided.
+	if len(k.Spec.CliServices.TlsSecretName) != 0 {
+		if usingPassthroughTLS || k.Spec.CliServices.Route.Termination == kabanerov1alpha2.RouteTerminationPassthrough {
+			return fmt.Errorf("Kabanero %v Spec.CliServices.TlsSecretName requires a CLI route that uses reencrypt TLS termination", k.Name)
+		}
+
+		setRouteCertificate, err := getCliRouteCertificate(ctx, k, cl)
+		if err != nil {
+			return err
+		}
+
+		certifiedManifest, err := transformedManifest.Transform(setRouteCertificate)
+		if err != nil {
+			return err
+		}
+		transformedManifest = &certifiedManifest
+	}
+
 	err = transformedManifest.Apply()
 	if err != nil {
 		return err
@@ -90,7 +145,7 @@ func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl
 			return err
 		}
 
-		transformedManifest, err := processTransformation(k, manifest, true, reqLogger)
+		transformedManifest, err := processTransformation(k, manifest, true, keyRotatedAt, reqLogger)
 		if err != nil {
 			return err
 		}
@@ -101,6 +156,12 @@ func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl
 		}
 	}
 
+	// Keep one of the CLI service pods available while nodes are drained
+	err = reconcileCliDisruptionBudget(k, rev, templateContext, cl, reqLogger)
+	if err != nil {
+		return err
+	}
+
 	// If there is a role binding config map, delete it (previous version)
 	err = destroyRoleBindingConfigMap(k, cl, reqLogger)
 	if err != nil {
@@ -110,10 +171,52 @@ func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl
 	return nil
 }
 
-func processTransformation(k *kabanerov1alpha2.Kabanero, manifest mf.Manifest, processEnv bool, reqLogger logr.Logger) (*mf.Manifest, error) {
+// Creates the PodDisruptionBudget of the CLI services when they run more than one pod, and
+// deletes it otherwise.  A budget for a single pod would block node drains.
+func reconcileCliDisruptionBudget(k *kabanerov1alpha2.Kabanero, rev versioning.SoftwareRevision, templateContext map[string]interface{}, cl client.Client, reqLogger logr.Logger) error {
+	f, err := rev.OpenOrchestration("kabanero-cli-pdb.yaml")
+	if err != nil {
+		return err
+	}
+
+	s, err := renderOrchestration(f, templateContext)
+	if err != nil {
+		return err
+	}
+
+	m, err := mf.ManifestFrom(mf.Reader(strings.NewReader(s)), mf.UseClient(mfc.NewClient(cl)), mf.UseLogger(reqLogger.WithName("manifestival")))
+	if err != nil {
+		return err
+	}
+
+	if k.Spec.CliServices.Replicas == nil || *k.Spec.CliServices.Replicas <= 1 {
+		m, err = m.Transform(mf.InjectNamespace(k.GetNamespace()))
+		if err != nil {
+			return err
+		}
+		return m.Delete()
+	}
+
+	m, err = m.Transform(mf.InjectOwner(k), mf.InjectNamespace(k.GetNamespace()), injectCommonMetadata(k))
+	if err != nil {
+		return err
+	}
+	return m.Apply()
+}
+
+func processTransformation(k *kabanerov1alpha2.Kabanero, manifest mf.Manifest, processEnv bool, keyRotatedAt string, reqLogger logr.Logger) (*mf.Manifest, error) {
 	transforms := []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
+		injectCommonMetadata(k),
+		injectScheduling(k),
+		kabTransforms.SetContainerResources(k.Spec.CliServices.Resources),
+		kabTransforms.SetRoute(k.Spec.CliServices.Route.Hostname, k.Spec.CliServices.Route.Termination, k.Spec.CliServices.Route.InsecureEdgeTerminationPolicy),
+	}
+
+	// Scale the CLI services, if a replica count was provided
+	if k.Spec.CliServices.Replicas != nil {
+		transforms = append(transforms, kabTransforms.SetReplicas(*k.Spec.CliServices.Replicas))
 	}
 
 	if processEnv {
@@ -149,6 +252,35 @@ func processTransformation(k *kabanerov1alpha2.Kabanero, manifest mf.Manifest, p
 			transforms = append(transforms, kabTransforms.AddEnvVariable("github.api.url", apiUrl.String()))
 		}
 
+		// The CLI authenticates users with an OpenID Connect provider instead of Github, if one was provided
+		if oidc := k.Spec.CliServices.Oidc; oidc != nil {
+			groupsClaim := oidc.GroupsClaim
+			if len(groupsClaim) == 0 {
+				groupsClaim = kabanerov1alpha2.DefaultCliOidcGroupsClaim
+			}
+			clientSecretKey := oidc.ClientSecretRef.Key
+			if len(clientSecretKey) == 0 {
+				clientSecretKey = kabanerov1alpha2.DefaultCliOidcClientSecretKey
+			}
+			transforms = append(transforms,
+				kabTransforms.AddEnvVariable("KABANERO_CLI_AUTH_PROVIDER", "oidc"),
+				kabTransforms.AddEnvVariable("KABANERO_CLI_OIDC_ISSUER_URL", oidc.IssuerUrl),
+				kabTransforms.AddEnvVariable("KABANERO_CLI_OIDC_CLIENT_ID", oidc.ClientId),
+				kabTransforms.AddEnvVariableFromSecret("KABANERO_CLI_OIDC_CLIENT_SECRET", oidc.ClientSecretRef.Name, clientSecretKey),
+				kabTransforms.AddEnvVariable("KABANERO_CLI_OIDC_GROUPS_CLAIM", groupsClaim))
+
+			// The members of these groups are bound to the admin role
+			if len(oidc.AdminGroups) > 0 {
+				transforms = append(transforms, kabTransforms.AddEnvVariable("KABANERO_CLI_OIDC_ADMIN_GROUPS", strings.Join(oidc.AdminGroups, ",")))
+			}
+		}
+
+		// Roll the CLI pods each time the encryption key is rotated, so that they pick up
+		// the new key.
+		if len(keyRotatedAt) != 0 {
+			transforms = append(transforms, kabTransforms.SetPodTemplateAnnotation(cliEncryptionKeyRotatedAtAnnotation, keyRotatedAt))
+		}
+
 		// Set JwtExpiration for login duration/timeout
 		// Specify a positive integer followed by a unit of time, which can be hours (h), minutes (m), or seconds (s).
 		if len(k.Spec.CliServices.SessionExpirationSeconds) > 0 {
@@ -176,27 +308,48 @@ func processTransformation(k *kabanerov1alpha2.Kabanero, manifest mf.Manifest, p
 	return &manifestTrasformed, nil
 }
 
+// Returns a transformation that sets the certificate and key of the CLI TLS secret on the
+// CLI route.
+func getCliRouteCertificate(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client) (mf.Transformer, error) {
+	secretName := k.Spec.CliServices.TlsSecretName
+	secret := &corev1.Secret{}
+	err := cl.Get(ctx, types.NamespacedName{Name: secretName, Namespace: k.GetNamespace()}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the CLI TLS secret. Secret name: %v. Namespace: %v. Error: %v", secretName, k.GetNamespace(), err)
+	}
+
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if len(secret.Data[key]) == 0 {
+			return nil, fmt.Errorf("The data.%v entry was not found in secret %v. Namespace: %v", key, secretName, k.GetNamespace())
+		}
+	}
+
+	return kabTransforms.SetRouteCertificate(string(secret.Data[corev1.TLSCertKey]), string(secret.Data[corev1.TLSPrivateKeyKey]), string(secret.Data["ca.crt"])), nil
+}
+
 // Tries to see if the CLI route has been assigned a hostname.
 func getCliRouteStatus(k *kabanerov1alpha2.Kabanero, reqLogger logr.Logger, c client.Client) (bool, error) {
+	// If disabled, there is no route to report on.
+	if !k.Spec.CliServices.IsEnabled() {
+		k.Status.Cli = kabanerov1alpha2.CliStatus{}
+		return true, nil
+	}
 
 	// Check that the route is accepted
 	cliRoute := &routev1.Route{}
 	cliRouteName := types.NamespacedName{Namespace: k.ObjectMeta.Namespace, Name: "kabanero-cli"}
 	err := c.Get(context.TODO(), cliRouteName, cliRoute)
 	if err == nil {
-		k.Status.Cli.Hostnames = nil
-		// Looking for an ingress that has an admitted status and a hostname
-		for _, ingress := range cliRoute.Status.Ingress {
-			routeAdmitted := false
-			for _, condition := range ingress.Conditions {
-				if condition.Type == routev1.RouteAdmitted && condition.Status == corev1.ConditionTrue {
-					routeAdmitted = true
-				}
-			}
-			if routeAdmitted == true && len(ingress.Host) > 0 {
-				k.Status.Cli.Hostnames = append(k.Status.Cli.Hostnames, ingress.Host)
-			}
+		// Looking for an ingress that has an admitted status and a hostname, which must
+		// be the requested hostname if there is one.
+		hostnames, message := getAdmittedRouteHostnames(cliRoute, k.Spec.CliServices.Route.Hostname)
+		k.Status.Cli.Hostnames = hostnames
+		if len(message) > 0 {
+			k.Status.Cli.Ready = "False"
+			k.Status.Cli.Message = message
+			return false, nil
 		}
+
 		// If we found a hostname from an admitted route, we're done.
 		if len(k.Status.Cli.Hostnames) > 0 {
 			k.Status.Cli.Ready = "True"
@@ -223,6 +376,64 @@ func getCliRouteStatus(k *kabanerov1alpha2.Kabanero, reqLogger logr.Logger, c cl
 	return true, nil
 }
 
+// Removes the CLI service resources, and the CLI encryption key secret, when the CLI
+// services are disabled.
+func cleanupKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) error {
+	rev, err := resolveSoftwareRevision(k, "cli-services", k.Spec.CliServices.Version)
+	if err != nil {
+		return err
+	}
+
+	templateContext := rev.Identifiers
+	image, err := imageUriWithOverrides(k.Spec.CliServices.Repository, k.Spec.CliServices.Tag, k.Spec.CliServices.Image, rev)
+	if err != nil {
+		return err
+	}
+	templateContext["image"] = image
+	templateContext["instance"] = k.ObjectMeta.UID
+	templateContext["version"] = rev.Version
+
+	orchestrations := []string{"kabanero-cli.yaml", "kabanero-cli-pdb.yaml"}
+	if !strings.HasSuffix(rev.OrchestrationPath, "0.1") {
+		orchestrations = append(orchestrations, "kabanero-cli-deployment.yaml")
+	}
+
+	for _, orchestration := range orchestrations {
+		f, err := rev.OpenOrchestration(orchestration)
+		if err != nil {
+			return err
+		}
+
+		s, err := renderOrchestration(f, templateContext)
+		if err != nil {
+			return err
+		}
+
+		m, err := mf.ManifestFrom(mf.Reader(strings.NewReader(s)), mf.UseClient(mfc.NewClient(cl)), mf.UseLogger(reqLogger.WithName("manifestival")))
+		if err != nil {
+			return err
+		}
+
+		m, err = m.Transform(mf.InjectNamespace(k.GetNamespace()))
+		if err != nil {
+			return err
+		}
+
+		err = m.Delete()
+		if err != nil {
+			return err
+		}
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: cliEncryptionKeySecretName, Namespace: k.GetNamespace()}}
+	err = cl.Delete(ctx, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
 // Deletes the role binding config map which may have existed in a prior version
 func destroyRoleBindingConfigMap(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
 
@@ -250,7 +461,7 @@ func destroyRoleBindingConfigMap(k *kabanerov1alpha2.Kabanero, c client.Client,
 
 // Creates the secret containing the AES encryption key used by the CLI.
 func createEncryptionKeySecret(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
-	secretName := "kabanero-cli-aes-encryption-key-secret"
+	secretName := cliEncryptionKeySecretName
 
 	// Check if the Secret already exists.
 	secretInstance := &corev1.Secret{}
@@ -275,21 +486,19 @@ func createEncryptionKeySecret(k *kabanerov1alpha2.Kabanero, c client.Client, re
 		secretInstance.ObjectMeta.Namespace = k.ObjectMeta.Namespace
 		secretInstance.ObjectMeta.OwnerReferences = append(secretInstance.ObjectMeta.OwnerReferences, ownerRef)
 
-		// Generate a 64 character random value
-		possibleChars := []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()-=_+")
-		maxVal := big.NewInt(int64(len(possibleChars)))
-		var buf bytes.Buffer
-		for i := 0; i < 64; i++ {
-			curInt, randErr := rand.Int(rand.Reader, maxVal)
-			if randErr != nil {
-				return randErr
-			}
-			// Convert int to char
-			buf.WriteByte(possibleChars[curInt.Int64()])
+		// A rotation requested before the secret existed is satisfied by the new key.
+		if request, found := k.GetAnnotations()[cliEncryptionKeyRotateAnnotation]; found {
+			secretInstance.ObjectMeta.Annotations = map[string]string{cliEncryptionKeyRotationRequestAnnotation: request}
+		}
+
+		var key string
+		key, err = generateEncryptionKey()
+		if err != nil {
+			return err
 		}
 
 		secretMap := make(map[string]string)
-		secretMap["AESEncryptionKey"] = buf.String()
+		secretMap[cliEncryptionKeyKey] = key
 		secretInstance.StringData = secretMap
 
 		reqLogger.Info(fmt.Sprintf("Attempting to create the CLI AES Encryption key secret"))
@@ -298,3 +507,70 @@ func createEncryptionKeySecret(k *kabanerov1alpha2.Kabanero, c client.Client, re
 
 	return err
 }
+
+// Generates a 64 character random encryption key.
+func generateEncryptionKey() (string, error) {
+	possibleChars := []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()-=_+")
+	maxVal := big.NewInt(int64(len(possibleChars)))
+	var buf bytes.Buffer
+	for i := 0; i < 64; i++ {
+		curInt, err := rand.Int(rand.Reader, maxVal)
+		if err != nil {
+			return "", err
+		}
+		// Convert int to char
+		buf.WriteByte(possibleChars[curInt.Int64()])
+	}
+	return buf.String(), nil
+}
+
+// Creates the CLI encryption key secret if it does not exist, and otherwise rotates its key
+// if a rotation was requested.  Returns when the key was last rotated, or an empty string
+// if it was never rotated.
+func reconcileEncryptionKeySecret(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (string, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: cliEncryptionKeySecretName, Namespace: k.GetNamespace()}, secret)
+	if errors.IsNotFound(err) {
+		return "", createEncryptionKeySecret(k, c, reqLogger)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if isEncryptionKeyRotationRequested(k, secret) {
+		key, err := generateEncryptionKey()
+		if err != nil {
+			return "", err
+		}
+
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		secret.Data[cliEncryptionKeyKey] = []byte(key)
+
+		annotations := secret.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[cliEncryptionKeyRotatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		if request, found := k.GetAnnotations()[cliEncryptionKeyRotateAnnotation]; found {
+			annotations[cliEncryptionKeyRotationRequestAnnotation] = request
+		}
+		secret.SetAnnotations(annotations)
+
+		reqLogger.Info("Attempting to rotate the CLI AES Encryption key")
+		err = c.Update(ctx, secret)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return secret.GetAnnotations()[cliEncryptionKeyRotatedAtAnnotation], nil
+}
+
+// Returns true if a rotation annotation value that was not processed yet is set on the
+// Kabanero instance.
+func isEncryptionKeyRotationRequested(k *kabanerov1alpha2.Kabanero, secret *corev1.Secret) bool {
+	request, found := k.GetAnnotations()[cliEncryptionKeyRotateAnnotation]
+	return found && request != secret.GetAnnotations()[cliEncryptionKeyRotationRequestAnnotation]
+}
@@ -0,0 +1,35 @@
+package kabaneroplatform
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Test when a rotation of the CLI encryption key is requested.
+func TestIsEncryptionKeyRotationRequested(t *testing.T) {
+	secret := &corev1.Secret{}
+	k := &kabanerov1alpha2.Kabanero{}
+
+	// No rotation was requested.
+	if isEncryptionKeyRotationRequested(k, secret) {
+		t.Fatal("Expected no rotation without the annotation")
+	}
+
+	// A new rotation annotation value rotates the key once.
+	k.SetAnnotations(map[string]string{cliEncryptionKeyRotateAnnotation: "1"})
+	if !isEncryptionKeyRotationRequested(k, secret) {
+		t.Fatal("Expected a rotation for the new annotation value")
+	}
+	secret.SetAnnotations(map[string]string{cliEncryptionKeyRotationRequestAnnotation: "1"})
+	if isEncryptionKeyRotationRequested(k, secret) {
+		t.Fatal("Expected the processed annotation value not to rotate the key again")
+	}
+
+	// Each new value rotates the key again.
+	k.Annotations[cliEncryptionKeyRotateAnnotation] = "2"
+	if !isEncryptionKeyRotationRequested(k, secret) {
+		t.Fatal("Expected a rotation for the changed annotation value")
+	}
+}
@@ -316,6 +316,7 @@ func processCRWYaml(ctx context.Context, k *kabanerov1alpha2.Kabanero, rev versi
 	transforms := []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(namespace),
+		injectCommonMetadata(k),
 	}
 
 	m, err := mOrig.Transform(transforms...)
@@ -467,19 +468,19 @@ func cleanupCRW(ctx context.Context, k *kabanerov1alpha2.Kabanero, rev versionin
 	return nil
 }
 
-// Deletes the resources associated with the codeready-workspaces deployment.
+// Deletes the resources associated with the codeready-workspaces deployment.  They are
+// shared by the Kabanero instances, so they are left to the remaining instances, if any.
 func deleteCRWOperatorResources(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) error {
 	rev, err := resolveSoftwareRevision(k, "codeready-workspaces", k.Spec.CodereadyWorkspaces.Operator.CustomResourceInstance.DevFileRegistryImage.Version)
 	if err != nil {
 		return err
 	}
-	kiList := &kabanerov1alpha2.KabaneroList{}
-	err = c.List(context.TODO(), kiList)
+	otherInstances, err := hasOtherKabaneroInstances(ctx, k, c)
 	if err != nil {
 		return err
 	}
 
-	if len(kiList.Items) == 1 {
+	if !otherInstances {
 		err = processCRWYaml(ctx, k, rev, unstructured.Unstructured{}.Object, c, crwYamlNameCodewindClusterRole, false, k.GetNamespace())
 		if err != nil {
 			return err
@@ -0,0 +1,316 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Collections were replaced by stacks in v1alpha2.  The Collection resources of an upgraded
+// installation are left behind.  Their Stack resources are created from the repository
+// indexes, like any other featured stack, and what the administrator chose is the desired
+// state of each collection version, so it is carried over to the stack versions.  A
+// collection whose stack is in no repository index is migrated to a Stack resource of its
+// own, built from the pipelines and images the collection last activated.
+
+// The annotation set on a Collection resource once it was migrated.
+const collectionMigratedAnnotation = "kabanero.io/migrated-to-stack"
+
+var collectionListGVK = schema.GroupVersionKind{
+	Group:   "kabanero.io",
+	Version: "v1alpha1",
+	Kind:    "CollectionList",
+}
+
+// Migrates each v1alpha1 Collection resource in the namespace of the Kabanero instance to
+// the Stack resource of the same name, and reports the progress in the instance status.
+func migrateCollections(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) error {
+	collections := &unstructured.UnstructuredList{}
+	collections.SetGroupVersionKind(collectionListGVK)
+	err := cl.List(ctx, collections, client.InNamespace(k.GetNamespace()))
+	if err != nil {
+		// The Collection CRD is gone, or was never installed.
+		if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+			k.Status.CollectionMigration = nil
+			return nil
+		}
+		return fmt.Errorf("Unable to list the collections in namespace %v: %v", k.GetNamespace(), err)
+	}
+
+	if len(collections.Items) == 0 {
+		k.Status.CollectionMigration = nil
+		return nil
+	}
+
+	status := &kabanerov1alpha2.CollectionMigrationStatus{}
+	messages := []string{}
+	defer func() {
+		status.Message = strings.Join(messages, " ")
+		k.Status.CollectionMigration = status
+	}()
+
+	for i := range collections.Items {
+		collection := &collections.Items[i]
+		if _, migrated := collection.GetAnnotations()[collectionMigratedAnnotation]; migrated {
+			status.Migrated++
+			continue
+		}
+
+		message, err := migrateCollection(ctx, k, cl, collection)
+		if err != nil {
+			status.Pending = append(status.Pending, collection.GetName())
+			messages = append(messages, fmt.Sprintf("Collection %v could not be migrated: %v.", collection.GetName(), err))
+			return err
+		}
+
+		if len(message) != 0 {
+			reqLogger.Info(message)
+			status.Pending = append(status.Pending, collection.GetName())
+			messages = append(messages, message)
+			continue
+		}
+
+		status.Migrated++
+		reqLogger.Info(fmt.Sprintf("Migrated collection %v to stack %v.", collection.GetName(), collection.GetName()))
+	}
+
+	return nil
+}
+
+// Migrates a collection to the Stack resource of the same name, and marks it migrated.  When
+// the stack exists, the desired state of the collection versions is copied to the stack
+// versions that do not have one.  Otherwise, the stack is created from the collection.
+// Returns why the collection cannot be migrated yet, if it cannot.
+func migrateCollection(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, collection *unstructured.Unstructured) (string, error) {
+	stackResource := &kabanerov1alpha2.Stack{}
+	err := cl.Get(ctx, types.NamespacedName{Name: collection.GetName(), Namespace: collection.GetNamespace()}, stackResource)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return "", err
+		}
+
+		versions, err := getCollectionStackVersions(collection)
+		if err != nil {
+			return "", err
+		}
+		if len(versions) == 0 {
+			return fmt.Sprintf("Collection %v cannot be migrated yet, because it has no activated versions, and no repository index contains stack %v.", collection.GetName(), collection.GetName()), nil
+		}
+
+		err = cl.Create(ctx, newMigratedStack(k, collection, versions))
+		if err != nil {
+			return "", err
+		}
+	} else {
+		desiredStates := getCollectionDesiredStates(collection)
+		changed := false
+		for j, version := range stackResource.Spec.Versions {
+			state, found := desiredStates[version.Version]
+			if found && len(version.DesiredState) == 0 {
+				stackResource.Spec.Versions[j].DesiredState = state
+				changed = true
+			}
+		}
+		if changed {
+			err = cl.Update(ctx, stackResource)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	annotations := collection.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[collectionMigratedAnnotation] = collection.GetName()
+	collection.SetAnnotations(annotations)
+	return "", cl.Update(ctx, collection)
+}
+
+// Returns a Stack resource, owned by the Kabanero instance, for the input collection.
+func newMigratedStack(k *kabanerov1alpha2.Kabanero, collection *unstructured.Unstructured, versions []kabanerov1alpha2.StackVersion) *kabanerov1alpha2.Stack {
+	name, _, _ := unstructured.NestedString(collection.Object, "spec", "name")
+	if len(name) == 0 {
+		name = collection.GetName()
+	}
+
+	ownerIsController := true
+	return &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      collection.GetName(),
+			Namespace: k.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{
+				metav1.OwnerReference{
+					APIVersion: k.TypeMeta.APIVersion,
+					Kind:       k.TypeMeta.Kind,
+					Name:       k.ObjectMeta.Name,
+					UID:        k.ObjectMeta.UID,
+					Controller: &ownerIsController,
+				},
+			},
+		},
+		Spec: kabanerov1alpha2.StackSpec{
+			Name:     name,
+			Versions: versions,
+		},
+	}
+}
+
+// Returns the stack versions of the input collection.  Only the versions that the collection
+// activated are known, since their pipelines and images are in the collection status.  Each
+// version has a desired state, so that the stack is kept while no repository index contains
+// it.  A version that the administrator did not choose a desired state for keeps the state
+// that the collection reported, or is inactive.
+func getCollectionStackVersions(collection *unstructured.Unstructured) ([]kabanerov1alpha2.StackVersion, error) {
+	desiredStates := getCollectionDesiredStates(collection)
+
+	// The repository and certificate verification of each version are in the spec.  Early
+	// collections had a single version, in the spec itself.
+	repositoryUrls := make(map[string]string)
+	skipCertVerification := make(map[string]bool)
+	addSpecVersion := func(fields map[string]interface{}) {
+		version, _, _ := unstructured.NestedString(fields, "version")
+		if len(version) == 0 {
+			return
+		}
+		if url, _, _ := unstructured.NestedString(fields, "repositoryUrl"); len(url) != 0 {
+			repositoryUrls[version] = url
+		}
+		if skip, _, _ := unstructured.NestedBool(fields, "skipCertVerification"); skip {
+			skipCertVerification[version] = true
+		}
+	}
+
+	spec, _, _ := unstructured.NestedMap(collection.Object, "spec")
+	addSpecVersion(spec)
+	specVersions, _, _ := unstructured.NestedSlice(spec, "versions")
+	for _, version := range specVersions {
+		if fields, ok := version.(map[string]interface{}); ok {
+			addSpecVersion(fields)
+		}
+	}
+
+	stackVersions := []kabanerov1alpha2.StackVersion{}
+	addStatusVersion := func(version string, state string, pipelines []interface{}, images []interface{}) error {
+		if len(version) == 0 || len(pipelines) == 0 {
+			return nil
+		}
+		for _, stackVersion := range stackVersions {
+			if stackVersion.Version == version {
+				return nil
+			}
+		}
+
+		stackVersion := kabanerov1alpha2.StackVersion{
+			Version:              version,
+			RepositoryUrl:        repositoryUrls[version],
+			SkipCertVerification: skipCertVerification[version],
+		}
+
+		desiredState, found := desiredStates[version]
+		if !found {
+			desiredState = strings.ToLower(state)
+			if desiredState != kabanerov1alpha2.StackDesiredStateActive {
+				desiredState = kabanerov1alpha2.StackDesiredStateInactive
+			}
+		}
+		stackVersion.DesiredState = desiredState
+
+		for _, pipeline := range pipelines {
+			if fields, ok := pipeline.(map[string]interface{}); ok {
+				id, _, _ := unstructured.NestedString(fields, "name")
+				url, _, _ := unstructured.NestedString(fields, "url")
+				digest, _, _ := unstructured.NestedString(fields, "digest")
+				stackVersion.Pipelines = append(stackVersion.Pipelines, kabanerov1alpha2.PipelineSpec{
+					Id:     id,
+					Sha256: digest,
+					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: url, SkipCertVerification: stackVersion.SkipCertVerification},
+				})
+			}
+		}
+
+		for _, image := range images {
+			if fields, ok := image.(map[string]interface{}); ok {
+				id, _, _ := unstructured.NestedString(fields, "id")
+				name, _, _ := unstructured.NestedString(fields, "image")
+				stackVersion.Images = append(stackVersion.Images, kabanerov1alpha2.Image{Id: id, Image: name})
+			}
+		}
+
+		// Stack images do not have a tag.
+		err := sutils.RemoveTagFromStackImages(&stackVersion, collection.GetName())
+		if err != nil {
+			return err
+		}
+
+		stackVersions = append(stackVersions, stackVersion)
+		return nil
+	}
+
+	status, _, _ := unstructured.NestedMap(collection.Object, "status")
+	statusVersions, _, _ := unstructured.NestedSlice(status, "versions")
+	for _, version := range statusVersions {
+		if fields, ok := version.(map[string]interface{}); ok {
+			name, _, _ := unstructured.NestedString(fields, "version")
+			state, _, _ := unstructured.NestedString(fields, "status")
+			pipelines, _, _ := unstructured.NestedSlice(fields, "pipelines")
+			images, _, _ := unstructured.NestedSlice(fields, "images")
+			err := addStatusVersion(name, state, pipelines, images)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Early collections only reported their active version.
+	activeVersion, _, _ := unstructured.NestedString(status, "activeVersion")
+	state, _, _ := unstructured.NestedString(status, "status")
+	pipelines, _, _ := unstructured.NestedSlice(status, "activePipelines")
+	images, _, _ := unstructured.NestedSlice(status, "images")
+	err := addStatusVersion(activeVersion, state, pipelines, images)
+	if err != nil {
+		return nil, err
+	}
+
+	return stackVersions, nil
+}
+
+// Returns the desired state of each version of the input collection, keyed by version.
+// Early collections had a single version, in the spec itself.
+func getCollectionDesiredStates(collection *unstructured.Unstructured) map[string]string {
+	desiredStates := make(map[string]string)
+	addDesiredState := func(fields map[string]interface{}) {
+		version, _, _ := unstructured.NestedString(fields, "version")
+		state, _, _ := unstructured.NestedString(fields, "desiredState")
+		state = strings.ToLower(state)
+		if len(version) != 0 && (state == kabanerov1alpha2.StackDesiredStateActive || state == kabanerov1alpha2.StackDesiredStateInactive) {
+			desiredStates[version] = state
+		}
+	}
+
+	spec, found, _ := unstructured.NestedMap(collection.Object, "spec")
+	if !found {
+		return desiredStates
+	}
+	addDesiredState(spec)
+
+	versions, _, _ := unstructured.NestedSlice(spec, "versions")
+	for _, version := range versions {
+		if fields, ok := version.(map[string]interface{}); ok {
+			addDesiredState(fields)
+		}
+	}
+	return desiredStates
+}
@@ -0,0 +1,114 @@
+package kabaneroplatform
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Test that the desired states are read from the versions of a collection, and from the
+// spec of an early, single version collection.
+func TestGetCollectionDesiredStates(t *testing.T) {
+	collection := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"name":         "java-microprofile",
+			"version":      "0.2.19",
+			"desiredState": "Active",
+			"versions": []interface{}{
+				map[string]interface{}{"version": "0.2.19", "desiredState": "active"},
+				map[string]interface{}{"version": "0.2.20", "desiredState": "inactive"},
+				map[string]interface{}{"version": "0.2.21"},
+				map[string]interface{}{"version": "0.2.22", "desiredState": "unknown"},
+			},
+		},
+	}}
+
+	states := getCollectionDesiredStates(collection)
+	if len(states) != 2 || states["0.2.19"] != "active" || states["0.2.20"] != "inactive" {
+		t.Fatalf("Expected 0.2.19 to be active and 0.2.20 inactive, but found %v", states)
+	}
+
+	states = getCollectionDesiredStates(&unstructured.Unstructured{Object: map[string]interface{}{}})
+	if len(states) != 0 {
+		t.Fatalf("Expected no desired states, but found %v", states)
+	}
+}
+
+// Test that the stack versions are built from the activated versions of a collection, and
+// from the active version of an early collection.
+func TestGetCollectionStackVersions(t *testing.T) {
+	pipelines := []interface{}{
+		map[string]interface{}{"name": "default", "url": "https://example.com/default-pipeline.tar.gz", "digest": "0123abcd"},
+	}
+	images := []interface{}{
+		map[string]interface{}{"id": "java-microprofile", "image": "docker.io/kabanero/java-microprofile:0.2"},
+	}
+
+	collection := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "java-microprofile"},
+		"spec": map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{"version": "0.2.19", "desiredState": "inactive", "repositoryUrl": "https://example.com/index.yaml", "skipCertVerification": true},
+			},
+		},
+		"status": map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{"version": "0.2.19", "status": "active", "pipelines": pipelines, "images": images},
+				map[string]interface{}{"version": "0.2.20", "status": "active", "pipelines": pipelines},
+				map[string]interface{}{"version": "0.2.21", "status": "inactive"},
+			},
+		},
+	}}
+
+	versions, err := getCollectionStackVersions(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 stack versions, but found %v", versions)
+	}
+
+	// The desired state of the spec wins over the status.
+	v := versions[0]
+	if v.Version != "0.2.19" || v.DesiredState != "inactive" || v.RepositoryUrl != "https://example.com/index.yaml" || !v.SkipCertVerification {
+		t.Fatalf("Unexpected stack version 0.2.19: %v", v)
+	}
+	if len(v.Pipelines) != 1 || v.Pipelines[0].Id != "default" || v.Pipelines[0].Sha256 != "0123abcd" || v.Pipelines[0].Https.Url != "https://example.com/default-pipeline.tar.gz" || !v.Pipelines[0].Https.SkipCertVerification {
+		t.Fatalf("Unexpected pipelines for stack version 0.2.19: %v", v.Pipelines)
+	}
+	if len(v.Images) != 1 || v.Images[0].Id != "java-microprofile" || v.Images[0].Image != "docker.io/kabanero/java-microprofile" {
+		t.Fatalf("Unexpected images for stack version 0.2.19: %v", v.Images)
+	}
+
+	if versions[1].Version != "0.2.20" || versions[1].DesiredState != "active" {
+		t.Fatalf("Expected stack version 0.2.20 to be active, but found %v", versions[1])
+	}
+
+	// An early collection, with a single version.
+	collection = &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "nodejs"},
+		"spec":     map[string]interface{}{"version": "0.2.6"},
+		"status": map[string]interface{}{
+			"activeVersion":   "0.2.6",
+			"status":          "Active",
+			"activePipelines": pipelines,
+		},
+	}}
+
+	versions, err = getCollectionStackVersions(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].Version != "0.2.6" || versions[0].DesiredState != "active" || len(versions[0].Pipelines) != 1 {
+		t.Fatalf("Expected active stack version 0.2.6, but found %v", versions)
+	}
+
+	// A collection that never activated a version.
+	versions, err = getCollectionStackVersions(&unstructured.Unstructured{Object: map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 0 {
+		t.Fatalf("Expected no stack versions, but found %v", versions)
+	}
+}
@@ -8,7 +8,11 @@ import (
 	"text/template"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
 	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
+	mf "github.com/manifestival/manifestival"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // Evaluates the image uri using any provided overrides. Here repository, tag and image are from
@@ -105,7 +109,9 @@ func renderOrchestration(r io.Reader, context map[string]interface{}) (string, e
 	return rendered, nil
 }
 
-// Resolve the SoftwareRevision object for a named software component.
+// Resolve the SoftwareRevision object for a named software component.  Without a version
+// override, the latest revision of the component in the channel of the Kabanero instance is
+// used.  The chosen revision is recorded in the instance status.
 func resolveSoftwareRevision(k *kabanerov1alpha2.Kabanero, softwareComponent string, softwareVersionOverride string) (versioning.SoftwareRevision, error) {
 	v, kabaneroVersion := resolveKabaneroVersion(k)
 
@@ -114,17 +120,24 @@ func resolveSoftwareRevision(k *kabanerov1alpha2.Kabanero, softwareComponent str
 		return versioning.SoftwareRevision{}, fmt.Errorf("Data related to the Kabanero release identifier `%v` cannot be found", kabaneroVersion)
 	}
 
+	channel := resolveChannel(k)
+	if !kabaneroRevision.HasChannel(channel) {
+		return versioning.SoftwareRevision{}, fmt.Errorf("The channel `%v` is not defined for the Kabanero release identifier `%v`", channel, kabaneroVersion)
+	}
+
 	if softwareVersionOverride == "" {
-		rev := kabaneroRevision.SoftwareComponent(softwareComponent)
+		rev := kabaneroRevision.SoftwareComponentInChannel(softwareComponent, channel)
 		if rev == nil {
-			return versioning.SoftwareRevision{}, fmt.Errorf("Data related to the software component `%v` within Kabanero release identifier `%v` cannot be found", softwareComponent, kabaneroVersion)
+			return versioning.SoftwareRevision{}, fmt.Errorf("Data related to the software component `%v` within Kabanero release identifier `%v` and channel `%v` cannot be found", softwareComponent, kabaneroVersion, channel)
 		}
 
+		recordSoftwareRevision(k, channel, softwareComponent, rev.Version)
 		return *rev, nil
 	} else {
 		allRevs := v.RelatedSoftwareRevisions[softwareComponent]
 		for _, rev := range allRevs {
 			if rev.Version == softwareVersionOverride {
+				recordSoftwareRevision(k, channel, softwareComponent, rev.Version)
 				return rev, nil
 			}
 		}
@@ -133,6 +146,27 @@ func resolveSoftwareRevision(k *kabanerov1alpha2.Kabanero, softwareComponent str
 	}
 }
 
+// Resolves the channel of the Kabanero instance.
+func resolveChannel(k *kabanerov1alpha2.Kabanero) string {
+	if len(k.Spec.Channel) == 0 {
+		return versioning.DefaultChannel
+	}
+	return k.Spec.Channel
+}
+
+// Records the software revision chosen for a component in the status of the Kabanero instance.
+func recordSoftwareRevision(k *kabanerov1alpha2.Kabanero, channel string, softwareComponent string, version string) {
+	k.Status.KabaneroInstance.Channel = channel
+	for i, revision := range k.Status.KabaneroInstance.Revisions {
+		if revision.Component == softwareComponent {
+			k.Status.KabaneroInstance.Revisions[i].Version = version
+			return
+		}
+	}
+
+	k.Status.KabaneroInstance.Revisions = append(k.Status.KabaneroInstance.Revisions, kabanerov1alpha2.SoftwareRevisionStatus{Component: softwareComponent, Version: version})
+}
+
 // Resolves the version of the Kabanero instance.
 func resolveKabaneroVersion(k *kabanerov1alpha2.Kabanero) (versioning.VersionDocument, string) {
 	v := versioning.Data
@@ -150,3 +184,49 @@ func bothZero(string1 string, string2 string) bool {
 func neitherZero(string1 string, string2 string) bool {
 	return (len(string1) > 0) && (len(string2) > 0)
 }
+
+// Returns the transform that applies the scheduling constraints of the Kabanero instance to
+// the deployments of a component.
+func injectScheduling(k *kabanerov1alpha2.Kabanero) mf.Transformer {
+	return kabTransforms.InjectScheduling(k.Spec.Scheduling.NodeSelector, k.Spec.Scheduling.Tolerations, k.Spec.Scheduling.Affinity)
+}
+
+// Returns the transform that adds the common labels and annotations of the Kabanero
+// instance to a resource.
+func injectCommonMetadata(k *kabanerov1alpha2.Kabanero) mf.Transformer {
+	return kabTransforms.InjectCommonMetadata(k.Spec.CommonLabels, k.Spec.CommonAnnotations)
+}
+
+// Returns the hostnames that the routers admitted for a route.  When a hostname was requested
+// and is not admitted, the message explains why.
+func getAdmittedRouteHostnames(route *routev1.Route, requestedHostname string) ([]string, string) {
+	var hostnames []string
+	message := ""
+	for _, ingress := range route.Status.Ingress {
+		for _, condition := range ingress.Conditions {
+			if condition.Type != routev1.RouteAdmitted {
+				continue
+			}
+			if condition.Status == corev1.ConditionTrue && len(ingress.Host) > 0 {
+				hostnames = append(hostnames, ingress.Host)
+			} else if len(condition.Message) > 0 {
+				message = fmt.Sprintf("The hostname %v was not admitted by router %v: %v", ingress.Host, ingress.RouterName, condition.Message)
+			}
+		}
+	}
+
+	if len(requestedHostname) == 0 {
+		return hostnames, ""
+	}
+
+	for _, hostname := range hostnames {
+		if hostname == requestedHostname {
+			return hostnames, ""
+		}
+	}
+
+	if len(message) == 0 {
+		message = fmt.Sprintf("The hostname %v has not been admitted", requestedHostname)
+	}
+	return hostnames, message
+}
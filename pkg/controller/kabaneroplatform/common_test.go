@@ -141,3 +141,31 @@ func TestResolveSoftwareRevision(t *testing.T) {
 		})
 	}
 }
+
+// Test that the chosen revisions are recorded in the status, and that an unknown channel
+// is reported.
+func TestResolveSoftwareRevisionChannel(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{}
+	rev, err := resolveSoftwareRevision(k, "cli-services", "")
+	if err != nil {
+		t.Fatal("Unexpected error: ", err)
+	}
+	status := k.Status.KabaneroInstance
+	if status.Channel != "stable" || len(status.Revisions) != 1 || status.Revisions[0].Component != "cli-services" || status.Revisions[0].Version != rev.Version {
+		t.Fatalf("Expected cli-services %v to be recorded in the stable channel, but found %+v", rev.Version, status)
+	}
+
+	_, err = resolveSoftwareRevision(k, "cli-services", "0.9.0")
+	if err != nil {
+		t.Fatal("Unexpected error: ", err)
+	}
+	if len(k.Status.KabaneroInstance.Revisions) != 1 || k.Status.KabaneroInstance.Revisions[0].Version != "0.9.0" {
+		t.Fatalf("Expected the override to replace the recorded revision, but found %+v", k.Status.KabaneroInstance.Revisions)
+	}
+
+	k.Spec.Channel = "unknown"
+	_, err = resolveSoftwareRevision(k, "cli-services", "")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown channel")
+	}
+}
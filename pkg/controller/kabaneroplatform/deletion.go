@@ -0,0 +1,142 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The deletion of a Kabanero instance runs in phases.  The stacks are deleted first, while
+// the stack controller can still run their finalizers, which remove the stack assets.  The
+// assets of the gitops pipelines follow, then the objects in other namespaces, which cannot
+// be garbage collected, and finally the components that are shared by the cluster.  The
+// phase is recorded in the instance status, along with what it is waiting on.
+
+// The amount of time to wait before checking on a waiting deletion phase again.
+const deletionPhaseRequeueDelay = 10 * time.Second
+
+// A phase of the deletion of a Kabanero instance.  The phase returns what it is waiting on
+// while it is not done.
+type deletionPhase struct {
+	name string
+	run  func(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) (string, error)
+}
+
+// The phases of the deletion, in order.
+var deletionPhases = []deletionPhase{
+	{kabanerov1alpha2.KabaneroDeletionPhaseStacks, deleteStacksPhase},
+	{kabanerov1alpha2.KabaneroDeletionPhasePipelineAssets, deletePipelineAssetsPhase},
+	{kabanerov1alpha2.KabaneroDeletionPhaseBindings, deleteBindingsPhase},
+	{kabanerov1alpha2.KabaneroDeletionPhaseComponents, deleteComponentsPhase},
+}
+
+// Runs the deletion phases of the Kabanero instance, starting from the phase recorded in its
+// status.  Returns true when all of the phases are done.  When a phase is waiting or fails,
+// the status reports it, and the deletion must be retried.
+func runDeletionPhases(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) (bool, error) {
+	start := 0
+	if k.Status.Deletion != nil {
+		for i, phase := range deletionPhases {
+			if phase.name == k.Status.Deletion.Phase {
+				start = i
+			}
+		}
+	}
+
+	for _, phase := range deletionPhases[start:] {
+		waiting, err := phase.run(ctx, k, cl, reqLogger)
+		if err == nil && len(waiting) == 0 {
+			reqLogger.Info(fmt.Sprintf("Deletion phase %v is complete.", phase.name))
+			continue
+		}
+
+		message := waiting
+		if err != nil {
+			message = err.Error()
+		} else {
+			reqLogger.Info(fmt.Sprintf("Deletion phase %v is waiting: %v", phase.name, waiting))
+		}
+
+		k.Status.Deletion = &kabanerov1alpha2.KabaneroDeletionStatus{Phase: phase.name, Message: message}
+		statusErr := patchKabaneroStatus(ctx, cl, k)
+		if statusErr != nil {
+			reqLogger.Error(statusErr, "Unable to report the deletion progress.")
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Deletes the stacks owned by the instance, and waits until the stack controller ran their
+// finalizers.
+func deleteStacksPhase(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) (string, error) {
+	return deleteOwnedStacks(ctx, k, cl)
+}
+
+// Deletes the assets of the gitops pipelines.
+func deletePipelineAssetsPhase(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) (string, error) {
+	return "", cleanupGitopsPipelines(ctx, k, cl, reqLogger)
+}
+
+// Deletes the objects that the stack controller and the target namespaces use in other
+// namespaces.
+func deleteBindingsPhase(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) (string, error) {
+	err := cleanupStackControllerBindings(ctx, k, cl)
+	if err != nil {
+		return "", err
+	}
+
+	return "", cleanupTargetNamespaces(ctx, k, cl)
+}
+
+// Deletes the web console customizations, the admission webhook, and the other components
+// that are not garbage collected.  The cluster level resources that the instances share are
+// left to the remaining instances, if any.
+func deleteComponentsPhase(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) (string, error) {
+	otherInstances, err := hasOtherKabaneroInstances(ctx, k, cl)
+	if err != nil {
+		return "", err
+	}
+
+	// if landing enabled
+	if !otherInstances && (k.Spec.Landing.Enable == nil || (k.Spec.Landing.Enable != nil && *(k.Spec.Landing.Enable) == true)) {
+		// Remove landing page customizations for the current namespace.
+		err := removeWebConsoleCustomization(k, cl)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Remove the webhook configurations and friends.
+	err = cleanupAdmissionControllerWebhook(k, cl, !otherInstances, reqLogger)
+	if err != nil {
+		return "", err
+	}
+
+	// Remove resources deployed in support of codeready-workspaces.
+	err = deleteCRWOperatorResources(ctx, k, cl)
+	if err != nil {
+		return "", err
+	}
+
+	// Cleanup the Devfile registry controller and its cross-namespace objects
+	err = cleanupDevfileRegistry(k, cl, reqLogger)
+	if err != nil {
+		return "", err
+	}
+
+	// Forget the index refreshes of the instance and of its stacks.
+	stack.PruneIndexRefreshes("Kabanero", k.GetNamespace(), nil)
+	stack.PruneIndexRefreshes("Stack", k.GetNamespace(), nil)
+
+	// Forget the component metrics of the instance.
+	pruneComponentMetrics(k.GetNamespace())
+
+	return "", nil
+}
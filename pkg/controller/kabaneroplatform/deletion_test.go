@@ -0,0 +1,246 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var deletionTestLogger logr.Logger = log.WithValues("Request.Namespace", "test", "Request.Name", "deletion_test")
+
+// The names of the deletion phases, in order.
+var deletionPhaseNames = []string{
+	kabanerov1alpha2.KabaneroDeletionPhaseStacks,
+	kabanerov1alpha2.KabaneroDeletionPhasePipelineAssets,
+	kabanerov1alpha2.KabaneroDeletionPhaseBindings,
+	kabanerov1alpha2.KabaneroDeletionPhaseComponents,
+}
+
+// Deletion phases that record that they ran, and wait or fail when the test asks them to.
+type testDeletionPhases struct {
+	ran     []string
+	waiting map[string]string
+	errs    map[string]error
+}
+
+// Replaces the deletion phases with the test phases, until the returned function is called.
+func (p *testDeletionPhases) install() func() {
+	saved := deletionPhases
+	deletionPhases = nil
+	for _, name := range deletionPhaseNames {
+		name := name
+		deletionPhases = append(deletionPhases, deletionPhase{name, func(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) (string, error) {
+			p.ran = append(p.ran, name)
+			return p.waiting[name], p.errs[name]
+		}})
+	}
+	return func() { deletionPhases = saved }
+}
+
+// Returns a Kabanero instance that is being deleted, stored in the input client.
+func createDeletedKabanero(cl unitTestClient) *kabanerov1alpha2.Kabanero {
+	now := metav1.Now()
+	k := createKabanero("https://example.com/kabanero-index.yaml")
+	k.DeletionTimestamp = &now
+	k.Finalizers = []string{"kabanero.io.kabanero-operator"}
+	cl.kabaneros[k.Name] = k.DeepCopy()
+	return k
+}
+
+// Test that the deletion resumes from the phase recorded in the status, and stops at a
+// phase that waits or fails, recording it in the status.
+func TestRunDeletionPhases(t *testing.T) {
+	phaseErr := errors.New("Unable to delete the role bindings")
+
+	tests := []struct {
+		name    string
+		start   string
+		waiting map[string]string
+		errs    map[string]error
+		ran     []string
+		done    bool
+		status  *kabanerov1alpha2.KabaneroDeletionStatus
+	}{
+		{
+			name: "all phases",
+			ran:  deletionPhaseNames,
+			done: true,
+		},
+		{
+			name:  "resume from Stacks",
+			start: kabanerov1alpha2.KabaneroDeletionPhaseStacks,
+			ran:   deletionPhaseNames,
+			done:  true,
+		},
+		{
+			name:  "resume from PipelineAssets",
+			start: kabanerov1alpha2.KabaneroDeletionPhasePipelineAssets,
+			ran:   deletionPhaseNames[1:],
+			done:  true,
+		},
+		{
+			name:  "resume from Bindings",
+			start: kabanerov1alpha2.KabaneroDeletionPhaseBindings,
+			ran:   deletionPhaseNames[2:],
+			done:  true,
+		},
+		{
+			name:  "resume from Components",
+			start: kabanerov1alpha2.KabaneroDeletionPhaseComponents,
+			ran:   deletionPhaseNames[3:],
+			done:  true,
+		},
+		{
+			name:    "waiting phase",
+			waiting: map[string]string{kabanerov1alpha2.KabaneroDeletionPhaseStacks: "Deletion blocked waiting for 1 owned Stacks to be deleted: java"},
+			ran:     deletionPhaseNames[:1],
+			status:  &kabanerov1alpha2.KabaneroDeletionStatus{Phase: kabanerov1alpha2.KabaneroDeletionPhaseStacks, Message: "Deletion blocked waiting for 1 owned Stacks to be deleted: java"},
+		},
+		{
+			name:   "failed phase",
+			start:  kabanerov1alpha2.KabaneroDeletionPhasePipelineAssets,
+			errs:   map[string]error{kabanerov1alpha2.KabaneroDeletionPhaseBindings: phaseErr},
+			ran:    deletionPhaseNames[1:3],
+			status: &kabanerov1alpha2.KabaneroDeletionStatus{Phase: kabanerov1alpha2.KabaneroDeletionPhaseBindings, Message: phaseErr.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		phases := &testDeletionPhases{waiting: test.waiting, errs: test.errs}
+		restore := phases.install()
+
+		ctx := context.Background()
+		cl := newUnitTestClient()
+		k := createDeletedKabanero(cl)
+		if len(test.start) != 0 {
+			k.Status.Deletion = &kabanerov1alpha2.KabaneroDeletionStatus{Phase: test.start}
+		}
+
+		done, err := runDeletionPhases(ctx, k, cl, deletionTestLogger)
+		restore()
+
+		if done != test.done {
+			t.Errorf("%v: expected done to be %v, but was %v", test.name, test.done, done)
+		}
+		if test.errs == nil && err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		}
+		if test.errs != nil && err != phaseErr {
+			t.Errorf("%v: expected the phase error, but found %v", test.name, err)
+		}
+		if !reflect.DeepEqual(phases.ran, test.ran) {
+			t.Errorf("%v: expected phases %v to run, but %v ran", test.name, test.ran, phases.ran)
+		}
+
+		// A phase that did not complete is reported in the status of the instance.
+		if test.status != nil {
+			stored := &kabanerov1alpha2.Kabanero{}
+			err = cl.Get(ctx, types.NamespacedName{Name: k.Name, Namespace: k.Namespace}, stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(stored.Status.Deletion, test.status) {
+				t.Errorf("%v: expected deletion status %+v, but found %+v", test.name, test.status, stored.Status.Deletion)
+			}
+		}
+	}
+}
+
+// Test that the finalizer is only removed once the last deletion phase is done.
+func TestProcessDeletionFinalizer(t *testing.T) {
+	ctx := context.Background()
+	cl := newUnitTestClient()
+	k := createDeletedKabanero(cl)
+
+	phases := &testDeletionPhases{waiting: map[string]string{kabanerov1alpha2.KabaneroDeletionPhaseComponents: "Waiting for the admission webhook"}}
+	defer phases.install()()
+
+	beingDeleted, result, err := processDeletion(ctx, k, cl, deletionTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !beingDeleted || result.RequeueAfter != deletionPhaseRequeueDelay {
+		t.Fatalf("Expected the deletion to be retried after %v, but the result was %+v", deletionPhaseRequeueDelay, result)
+	}
+	if !isFinalizerInList(cl.kabaneros[k.Name], "kabanero.io.kabanero-operator") {
+		t.Fatal("Expected the finalizer to be kept while the last phase is waiting")
+	}
+
+	// The deletion resumes from the last phase, and the finalizer is removed once it is done.
+	phases.waiting = nil
+	phases.ran = nil
+	k = cl.kabaneros[k.Name].DeepCopy()
+	_, _, err = processDeletion(ctx, k, cl, deletionTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(phases.ran, deletionPhaseNames[3:]) {
+		t.Fatalf("Expected only the last phase to run again, but %v ran", phases.ran)
+	}
+	if isFinalizerInList(cl.kabaneros[k.Name], "kabanero.io.kabanero-operator") {
+		t.Fatal("Expected the finalizer to be removed after the last phase")
+	}
+}
+
+// Test that a failed phase keeps the finalizer, and is reported as an error.
+func TestProcessDeletionPhaseError(t *testing.T) {
+	ctx := context.Background()
+	cl := newUnitTestClient()
+	k := createDeletedKabanero(cl)
+
+	phases := &testDeletionPhases{errs: map[string]error{kabanerov1alpha2.KabaneroDeletionPhasePipelineAssets: errors.New("Unable to delete the assets")}}
+	defer phases.install()()
+
+	_, _, err := processDeletion(ctx, k, cl, deletionTestLogger)
+	if err == nil {
+		t.Fatal("Expected the phase error to be returned")
+	}
+	if !isFinalizerInList(cl.kabaneros[k.Name], "kabanero.io.kabanero-operator") {
+		t.Fatal("Expected the finalizer to be kept after a phase failed")
+	}
+	if deletion := cl.kabaneros[k.Name].Status.Deletion; deletion == nil || deletion.Phase != kabanerov1alpha2.KabaneroDeletionPhasePipelineAssets {
+		t.Fatalf("Expected the failed phase to be reported, but found %+v", deletion)
+	}
+}
+
+// Test that the stacks phase deletes the stacks owned by the instance and its StackHubs,
+// and waits until they are gone.
+func TestDeleteStacksPhase(t *testing.T) {
+	ctx := context.Background()
+	cl := newUnitTestClient()
+	k := createDeletedKabanero(cl)
+
+	isController := true
+	hub := &kabanerov1alpha2.StackHub{ObjectMeta: metav1.ObjectMeta{Name: k.Name + "-default", Namespace: k.Namespace, UID: "hub-uid",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: k.APIVersion, Kind: k.Kind, Name: k.Name, UID: k.UID, Controller: &isController}}}}
+	cl.hubs[hub.Name] = hub
+	cl.objs["java"] = &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{Name: "java", Namespace: k.Namespace,
+		OwnerReferences: []metav1.OwnerReference{{Kind: "StackHub", Name: hub.Name, UID: hub.UID}}}}
+	cl.objs["other"] = &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: k.Namespace}}
+
+	waiting, err := deleteStacksPhase(ctx, k, cl, deletionTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(waiting) == 0 {
+		t.Fatal("Expected the phase to wait for the owned stack to be deleted")
+	}
+	if cl.objs["java"] != nil || cl.objs["other"] == nil {
+		t.Fatal("Expected only the owned stack to be deleted")
+	}
+
+	waiting, err = deleteStacksPhase(ctx, k, cl, deletionTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(waiting) != 0 {
+		t.Fatalf("Expected the phase to be done, but it is waiting: %v", waiting)
+	}
+}
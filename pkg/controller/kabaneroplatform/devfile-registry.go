@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
 	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
 
 	corev1 "k8s.io/api/core/v1"
@@ -65,6 +66,9 @@ func reconcileDevfileRegistry(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 	transforms := []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
+		injectCommonMetadata(k),
+		injectScheduling(k),
+		kabTransforms.SetContainerResources(k.Spec.DevfileRegistry.Resources),
 	}
 
 	m, err := mOrig.Transform(transforms...)
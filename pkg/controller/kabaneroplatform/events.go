@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
 	mf "github.com/manifestival/manifestival"
 	mfc "github.com/manifestival/controller-runtime-client"
 	routev1 "github.com/openshift/api/route/v1"
@@ -62,6 +63,9 @@ func reconcileEvents(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl clien
 	transforms := []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
+		injectCommonMetadata(k),
+		injectScheduling(k),
+		kabTransforms.SetContainerResources(k.Spec.Events.Resources),
 	}
 
 	m, err := mOrig.Transform(transforms...)
@@ -119,6 +123,7 @@ func cleanupEvents(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.
 	transforms := []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
+		injectScheduling(k),
 	}
 
 	m, err := mOrig.Transform(transforms...)
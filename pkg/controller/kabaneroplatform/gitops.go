@@ -13,8 +13,26 @@ import (
 
 // Activates the Gitops pipelines
 func reconcileGitopsPipelines(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	// If the gitops pipelines are disabled, remove their assets.
+	if !k.Spec.Gitops.IsEnabled() {
+		err := cleanupGitopsPipelines(ctx, k, c, reqLogger)
+		if err != nil {
+			return err
+		}
+		k.Status.Gitops = kabanerov1alpha2.GitopsStatus{}
+		return nil
+	}
+
 	reqLogger.Info("Reconciling Gitops pipelines.")
 
+	// Don't start a new activation if the operator is stopping.  The current
+	// status is kept, and the pipelines are reconciled when the operator runs again.
+	if !cutils.BeginActivation() {
+		reqLogger.Info("The operator is stopping. Deferring Gitops pipeline reconciliation.")
+		return nil
+	}
+	defer cutils.EndActivation()
+
 	// Gather the known asset (*-tasks, *-pipeline) substitution data.  (none presently)
 	renderingContext := make(map[string]interface{})
 
@@ -28,8 +46,13 @@ func reconcileGitopsPipelines(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 		Controller: &ownerIsController,
 	}
 
+	activationOptions, err := cutils.GetActivationOptions(c, k)
+	if err != nil {
+		return err
+	}
+
 	// Activate the pipelines used by the gitops repository
-	assetUseMap, err := cutils.ActivatePipelines(k.Spec.Gitops, k.Status.Gitops, k.GetNamespace(), renderingContext, assetOwner, c, reqLogger)
+	assetUseMap, err := cutils.ActivatePipelines(ctx, k.Spec.Gitops, k.Status.Gitops, k.GetNamespace(), activationOptions, renderingContext, assetOwner, c, reqLogger)
 
 	if err != nil {
 		return err
@@ -42,7 +65,7 @@ func reconcileGitopsPipelines(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 		if pipeline.GitRelease.IsUsable() {
 			key.GitRelease = gitReleaseSpecToGitReleaseInfo(pipeline.GitRelease)
 		} else {
-			key.Url = pipeline.Https.Url
+			key.Url = pipeline.ArchiveUrl()
 		}
 		value := assetUseMap[key]
 		if value == nil {
@@ -103,7 +126,7 @@ func cleanupGitopsPipelines(ctx context.Context, k *kabanerov1alpha2.Kabanero, c
 				asset.Namespace = k.GetNamespace()
 			}
 			
-			cutils.DeleteAsset(c, asset, assetOwner, reqLogger)
+			cutils.DeleteAsset(c, asset, assetOwner, k.Spec.AssetDeletionPolicy, reqLogger)
 		}
 	}
 
@@ -113,5 +136,8 @@ func cleanupGitopsPipelines(ctx context.Context, k *kabanerov1alpha2.Kabanero, c
 // Returns the readiness status of the Gitops pipelines.  Presently the status is determined
 // when the pipelines are activated.  We are just reporting that status here.
 func getGitopsStatus(k *kabanerov1alpha2.Kabanero) (bool, error) {
+	if !k.Spec.Gitops.IsEnabled() {
+		return true, nil
+	}
 	return k.Status.Gitops.Ready == "True", nil
 }
@@ -273,3 +273,54 @@ func TestCleanupGitopsPipelines(t *testing.T) {
 	}
 }
 
+// Disabling the gitops pipelines removes their assets, and clears their status.
+func TestReconcileGitopsPipelinesDisabled(t *testing.T) {
+	enable := false
+	kabaneroResource := kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Gitops: kabanerov1alpha2.GitopsSpec{
+				Enable: &enable,
+				Pipelines: []kabanerov1alpha2.PipelineSpec{{
+					Id:     "default",
+					Sha256: digest1Pipeline.sha256,
+					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: "bogus"},
+				}},
+			},
+		},
+		Status: kabanerov1alpha2.KabaneroStatus{
+			Gitops: kabanerov1alpha2.GitopsStatus{
+				Ready: "False",
+				Pipelines: []kabanerov1alpha2.PipelineStatus{{
+					Name:   "default",
+					Digest: digest1Pipeline.sha256,
+					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
+						Name:      "my-pipeline",
+						Namespace: "kabanero",
+					}},
+				}},
+			},
+		},
+	}
+
+	clientMap := make(map[client.ObjectKey]bool)
+	clientMap[client.ObjectKey{Name: "my-pipeline", Namespace: "kabanero"}] = true
+	client := gitopsTestClient{clientMap}
+
+	err := reconcileGitopsPipelines(context.TODO(), &kabaneroResource, client, klog)
+	if err != nil {
+		t.Fatal("Returned error: " + err.Error())
+	}
+
+	if len(client.objs) != 0 {
+		t.Fatal(fmt.Sprintf("Client map should have 0 entries, but has %v: %v", len(client.objs), client.objs))
+	}
+
+	if len(kabaneroResource.Status.Gitops.Pipelines) != 0 {
+		t.Fatal(fmt.Sprintf("Kabanero status should have no pipelines, but has %v", kabaneroResource.Status.Gitops.Pipelines))
+	}
+
+	if ready, _ := getGitopsStatus(&kabaneroResource); !ready {
+		t.Fatal("Disabled gitops pipelines should be reported as ready")
+	}
+}
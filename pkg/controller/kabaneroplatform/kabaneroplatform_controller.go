@@ -10,19 +10,25 @@ import (
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
-  "github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
 	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
 	mfc "github.com/manifestival/controller-runtime-client"
 	mf "github.com/manifestival/manifestival"
+	routev1 "github.com/openshift/api/route/v1"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -39,8 +45,9 @@ var ctrlr controller.Controller
 var operatorContainerImage string
 var operatorContainerImageOp sync.Once
 
-// A list of functions driven by the reconciler
-type reconcileFunc func(context.Context, *kabanerov1alpha2.Kabanero, client.Client, logr.Logger) error
+// A list of functions driven by the reconciler.  Each reports its own result, so that
+// one failing component does not prevent the others from being reconciled.
+type reconcileFunc func(context.Context, *kabanerov1alpha2.Kabanero, client.Client, logr.Logger) componentResult
 
 type reconcileFuncType struct {
 	name     string
@@ -48,15 +55,16 @@ type reconcileFuncType struct {
 }
 
 var reconcileFuncs = []reconcileFuncType{
-	{name: "stack controller", function: reconcileStackController},
-	{name: "landing page", function: deployLandingPage},
-	{name: "cli service", function: reconcileKabaneroCli},
-	{name: "CodeReady Workspaces", function: reconcileCRW},
-	{name: "events", function: reconcileEvents},
-	{name: "sso", function: reconcileSso},
-	{name: "gitops", function: reconcileGitopsPipelines},
-	{name: "target namespaces", function: reconcileTargetNamespaces},
-	{name: "devfile registry controller", function: reconcileDevfileRegistry},
+	{name: "stack controller", function: withErrorResult(reconcileStackController)},
+	{name: "landing page", function: withErrorResult(deployLandingPage)},
+	{name: "cli service", function: reconcileKabaneroCliServices},
+	{name: "CodeReady Workspaces", function: withErrorResult(reconcileCRW)},
+	{name: "events", function: withErrorResult(reconcileEvents)},
+	{name: "sso", function: withErrorResult(reconcileSso)},
+	{name: "gitops", function: withErrorResult(reconcileGitopsPipelines)},
+	{name: "target namespaces", function: withErrorResult(reconcileTargetNamespaces)},
+	{name: "devfile registry controller", function: withErrorResult(reconcileDevfileRegistry)},
+	{name: "operator configuration", function: withErrorResult(reconcileOperatorConfig)},
 }
 
 // Add creates a new Kabanero Controller and adds it to the Manager. The Manager will set fields on the Controller
@@ -68,17 +76,29 @@ func Add(mgr manager.Manager) error {
 		return err
 }
 
-	// Lets be sure a single namespace is specified.
-	numberOfWatchNamespaces := len(strings.Split(watchNamespace, ","))
-	if numberOfWatchNamespaces != 1 {
-		return fmt.Errorf("%v watch namespaces were specified, but only a single watch namespace is supported: %v", numberOfWatchNamespaces, watchNamespace)
+	// Each watch namespace may contain one Kabanero instance.  The operator pod is read
+	// through the cache, so its namespace must be watched too.
+	watchNamespaces := getWatchNamespaces(watchNamespace)
+	operatorNamespace, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		// Running locally.
+		operatorNamespace = watchNamespaces[0]
+	}
+	if !isWatchedNamespace(watchNamespaces, operatorNamespace) {
+		return fmt.Errorf("The operator namespace %v must be one of the watch namespaces: %v", operatorNamespace, watchNamespace)
 	}
 	
+	// Asset namespaces of the gitops pipelines are cluster scoped, and must not be cached.
+	cutils.SetNamespaceReader(mgr.GetAPIReader())
+	cutils.SetRegistryAuthenticator(stack.GetBundleRegistryAuthenticators)
+
 	r := &ReconcileKabanero{
-		client:          mgr.GetClient(),
-		scheme:          mgr.GetScheme(),
-		requeueDelayMap: make(map[string]RequeueData),
-	  watchNamespace:  watchNamespace}
+		client:            mgr.GetClient(),
+		scheme:            mgr.GetScheme(),
+		requeueDelayMap:   make(map[string]RequeueData),
+		watchNamespaces:   watchNamespaces,
+		operatorNamespace: operatorNamespace,
+		recorder:          mgr.GetEventRecorderFor("kabaneroplatform-controller")}
 
 	// Create a new controller
 	c, err := controller.New("kabaneroplatform-controller", mgr, controller.Options{Reconciler: r})
@@ -93,18 +113,52 @@ func Add(mgr manager.Manager) error {
 		return err
 	}
 
-	// Watch Stacks
-	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.Stack{}}, getWatchHandlerForKabaneroOwner(), getWatchPredicateFunc())
+	// When a Kabanero instance is deleted, the remaining instances are reconciled, so that
+	// the next oldest one takes over the namespace or the shared resources it managed.
+	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.Kabanero{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.kabaneroMapFunc)}, predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false }})
+	if err != nil {
+		return err
+	}
+
+	// Watch Stacks.  The stacks of the Kabanero instance repositories are controlled by its
+	// StackHubs, and all of the stacks in the namespace are counted in the stack summary.
+	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.Stack{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.stackMapFunc)}, getStackWatchPredicateFunc())
 	if err != nil {
 		return err
 	}
 
-	// Watch Kabanero owned deployments.
+	// Watch the StackHubs that read the stack repositories, so that a deleted or edited
+	// StackHub is repaired, and a failure to read a repository is reported.
+	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.StackHub{}}, getWatchHandlerForKabaneroOwner(), getStackHubWatchPredicateFunc())
+	if err != nil {
+		return err
+	}
+
+	// Watch Kabanero owned deployments, so that a deleted or edited component deployment
+	// is repaired right away.
 	err = c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, getWatchHandlerForKabaneroOwner(), getWatchPredicateFunc())
 	if err != nil {
 		return err
 	}
 
+	// Watch Kabanero owned services and routes.  They do not track their generation, so
+	// their spec is compared instead.  The admitted hostnames of a route are reported in
+	// the instance status, so a change to the route status is processed too.
+	err = c.Watch(&source.Kind{Type: &corev1.Service{}}, getWatchHandlerForKabaneroOwner(), getSpecWatchPredicateFunc())
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &routev1.Route{}}, getWatchHandlerForKabaneroOwner(), getSpecWatchPredicateFunc())
+	if err != nil {
+		return err
+	}
+
 	// Watch CheCluster instances.  We watch these so that we can enforce
 	// some fields that should not be changed by the user.
 	err = watchCRWInstance(c)
@@ -122,6 +176,22 @@ func Add(mgr manager.Manager) error {
 		return err
 	}
 
+	// Watch ConfigMaps, so that a changed operator configuration is put back, a rotated
+	// webhook CA is copied into the webhook configurations, and a changed trace
+	// specification is applied.
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.configMapMapFunc)})
+	if err != nil {
+		return err
+	}
+
+	// Watch Secrets, so that a rotated CLI TLS certificate is copied into the CLI route.
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.cliTLSSecretMapFunc)})
+	if err != nil {
+		return err
+	}
+
 /* Useful if RoleBindingList is changed to use Structured instead of Unstructured
 	// Index Rolebindings by name
 	if err := mgr.GetFieldIndexer().IndexField(&rbacv1.RoleBinding{}, "metadata.name", func(rawObj runtime.Object) []string {
@@ -144,7 +214,7 @@ func (r *ReconcileKabanero) getOperatorImage() (string, error) {
 
 	// Second, get the Pod instance with that name
 	pod := &corev1.Pod{}
-	kubePodName := types.NamespacedName{Name: podName, Namespace: r.watchNamespace}
+	kubePodName := types.NamespacedName{Name: podName, Namespace: r.operatorNamespace}
 	err := r.client.Get(context.TODO(), kubePodName, pod)
 	if err != nil {
 		return "", fmt.Errorf("Pod %v could not be retrieved: %v", podName, err.Error())
@@ -175,22 +245,100 @@ func getWatchPredicateFunc() predicate.Funcs {
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			// Returning true only when the metadata generation has changed,
 			// allows us to ignore events where only the object status has changed,
-			// since the generation is not incremented when only the status changes
+			// since the generation is not incremented when only the status changes.
+			// A new index refresh request is processed too.
+			return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+				e.MetaOld.GetAnnotations()[stack.RefreshIndexAnnotation] != e.MetaNew.GetAnnotations()[stack.RefreshIndexAnnotation]
+		},
+	}
+}
+
+// Returns a watch predicate for objects that do not track their generation.  An update
+// is processed when the spec of the object changed, or the status of a route.
+func getSpecWatchPredicateFunc() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			switch oldObject := e.ObjectOld.(type) {
+			case *corev1.Service:
+				newObject, ok := e.ObjectNew.(*corev1.Service)
+				return !ok || !equality.Semantic.DeepEqual(oldObject.Spec, newObject.Spec)
+			case *routev1.Route:
+				newObject, ok := e.ObjectNew.(*routev1.Route)
+				return !ok || !equality.Semantic.DeepEqual(oldObject.Spec, newObject.Spec) || !equality.Semantic.DeepEqual(oldObject.Status, newObject.Status)
+			}
 			return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration()
 		},
 	}
 }
 
+// Returns the stack watch predicate.  A change to the state a stack is counted under in
+// the stack summary is processed too.
+func getStackWatchPredicateFunc() predicate.Funcs {
+	p := getWatchPredicateFunc()
+	updateFunc := p.UpdateFunc
+	p.UpdateFunc = func(e event.UpdateEvent) bool {
+		return updateFunc(e) || stackSummaryStateChanged(e)
+	}
+	return p
+}
+
+// Returns the StackHub watch predicate.  A change to the readiness of the StackHub is
+// processed too.
+func getStackHubWatchPredicateFunc() predicate.Funcs {
+	p := getWatchPredicateFunc()
+	updateFunc := p.UpdateFunc
+	p.UpdateFunc = func(e event.UpdateEvent) bool {
+		oldHub, ok := e.ObjectOld.(*kabanerov1alpha2.StackHub)
+		if !ok {
+			return updateFunc(e)
+		}
+		newHub, ok := e.ObjectNew.(*kabanerov1alpha2.StackHub)
+		if !ok {
+			return updateFunc(e)
+		}
+		return updateFunc(e) || oldHub.Status.Ready != newHub.Status.Ready || oldHub.Status.Message != newHub.Status.Message
+	}
+	return p
+}
+
 var _ reconcile.Reconciler = &ReconcileKabanero{}
 
 // ReconcileKabanero reconciles a KabaneroPlatform object
 type ReconcileKabanero struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client          client.Client
-	scheme          *runtime.Scheme
-	requeueDelayMap map[string]RequeueData
-	watchNamespace  string
+	client            client.Client
+	scheme            *runtime.Scheme
+	requeueDelayMap   map[string]RequeueData
+	watchNamespaces   []string
+	operatorNamespace string
+	recorder          record.EventRecorder
+}
+
+// Returns the namespaces in the input comma separated list.  An empty list means all
+// namespaces, and is returned as a single empty namespace.
+func getWatchNamespaces(watchNamespace string) []string {
+	namespaces := []string{}
+	for _, namespace := range strings.Split(watchNamespace, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if len(namespace) != 0 {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	if len(namespaces) == 0 {
+		return []string{""}
+	}
+	return namespaces
+}
+
+// Returns true if the input namespace is in the input watch namespaces.
+func isWatchedNamespace(watchNamespaces []string, namespace string) bool {
+	for _, watchNamespace := range watchNamespaces {
+		if len(watchNamespace) == 0 || watchNamespace == namespace {
+			return true
+		}
+	}
+	return false
 }
 
 // RequeueData stores information that enables reconcile operations to be retried.
@@ -204,26 +352,123 @@ type RequeueData struct {
 func (r *ReconcileKabanero) targetNamespaceMapFunc(a handler.MapObject) []reconcile.Request {
 	log.Info(fmt.Sprintf("Processing for change in namespace %v", a.Meta.GetName()))
 	
-	// List Kabanero instances
+	// For each Kabanero instance, if spec.targetNamespaces includes a.meta.name then add a reconcile request.
+	requests := []reconcile.Request{}
+	for _, watchNamespace := range r.watchNamespaces {
+		kabaneros := &kabanerov1alpha2.KabaneroList{}
+		err := r.client.List(context.TODO(), kabaneros, client.InNamespace(watchNamespace))
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Could not process namespace event for \"%v\"", a.Meta.GetName()))
+			return nil
+		}
+
+		for _, kabanero := range kabaneros.Items {
+			for _, namespace := range kabanero.Spec.TargetNamespaces {
+				if namespace == a.Meta.GetName() {
+					requests = append(requests, reconcile.Request{types.NamespacedName{Name: kabanero.Name, Namespace: kabanero.Namespace}})
+					break
+				}
+			}
+		}
+	}
+	
+  return requests
+}
+
+// When we see that a Kabanero instance was deleted, we want to reconcile the other instances,
+// one of which may take over what the deleted instance managed.
+func (r *ReconcileKabanero) kabaneroMapFunc(a handler.MapObject) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, watchNamespace := range r.watchNamespaces {
+		kabaneros := &kabanerov1alpha2.KabaneroList{}
+		err := r.client.List(context.TODO(), kabaneros, client.InNamespace(watchNamespace))
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Could not process Kabanero event for \"%v\"", a.Meta.GetName()))
+			return nil
+		}
+
+		for _, kabanero := range kabaneros.Items {
+			if kabanero.GetUID() != a.Meta.GetUID() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: kabanero.Name, Namespace: kabanero.Namespace}})
+			}
+		}
+	}
+	return requests
+}
+
+// When we see that a stack has changed, we want to reconcile the Kabanero instances in its
+// namespace, which count it in their stack summary.
+func (r *ReconcileKabanero) stackMapFunc(a handler.MapObject) []reconcile.Request {
+	if !isWatchedNamespace(r.watchNamespaces, a.Meta.GetNamespace()) {
+		return nil
+	}
+
 	kabaneros := &kabanerov1alpha2.KabaneroList{}
-	err := r.client.List(context.TODO(), kabaneros, client.InNamespace(r.watchNamespace))
+	err := r.client.List(context.TODO(), kabaneros, client.InNamespace(a.Meta.GetNamespace()))
 	if err != nil {
-		log.Error(err, fmt.Sprintf("Could not process namespace event for \"%v\"", a.Meta.GetName()))
+		log.Error(err, fmt.Sprintf("Could not process Stack event for \"%v\"", a.Meta.GetName()))
 		return nil
 	}
 
-	// For each Kabanero instance, if spec.targetNamespaces includes a.meta.name then add a reconcile request.
 	requests := []reconcile.Request{}
 	for _, kabanero := range kabaneros.Items {
-		for _, namespace := range kabanero.Spec.TargetNamespaces {
-			if namespace == a.Meta.GetName() {
-				requests = append(requests, reconcile.Request{types.NamespacedName{Name: kabanero.Name, Namespace: kabanero.Namespace}})
-				break
-			}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: kabanero.Name, Namespace: kabanero.Namespace}})
+	}
+	return requests
+}
+
+// When we see that a ConfigMap has changed, we want to reconcile any Kabanero instances that
+// publish their configuration into it.  A change to the webhook CA ConfigMap means the service
+// CA was rotated.  The stack indexes held in ConfigMaps are read by the StackHubs of the
+// Kabanero instance, which watch them themselves.
+func (r *ReconcileKabanero) configMapMapFunc(a handler.MapObject) []reconcile.Request {
+	if !isWatchedNamespace(r.watchNamespaces, a.Meta.GetNamespace()) {
+		return nil
+	}
+
+	// The operator configuration ConfigMap is read-only, so any change to it is reverted.
+	// Creating, changing or removing the trace ConfigMap changes the log level.
+	if a.Meta.GetName() != operatorConfigMapName && a.Meta.GetName() != webhookCACertConfigMapName && a.Meta.GetName() != cutils.TraceConfigMapName {
+		return nil
+	}
+
+	kabaneros := &kabanerov1alpha2.KabaneroList{}
+	err := r.client.List(context.TODO(), kabaneros, client.InNamespace(a.Meta.GetNamespace()))
+	if err != nil {
+		log.Error(err, fmt.Sprintf("Could not process ConfigMap event for \"%v\"", a.Meta.GetName()))
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, kabanero := range kabaneros.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: kabanero.Name, Namespace: kabanero.Namespace}})
+	}
+
+	return requests
+}
+
+// Returns a reconcile request for each Kabanero instance whose CLI route presents the
+// certificate of the input Secret.
+func (r *ReconcileKabanero) cliTLSSecretMapFunc(a handler.MapObject) []reconcile.Request {
+	if !isWatchedNamespace(r.watchNamespaces, a.Meta.GetNamespace()) {
+		return nil
+	}
+
+	kabaneros := &kabanerov1alpha2.KabaneroList{}
+	err := r.client.List(context.TODO(), kabaneros, client.InNamespace(a.Meta.GetNamespace()))
+	if err != nil {
+		log.Error(err, fmt.Sprintf("Could not process Secret event for \"%v\"", a.Meta.GetName()))
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, kabanero := range kabaneros.Items {
+		if kabanero.Spec.CliServices.TlsSecretName == a.Meta.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: kabanero.Name, Namespace: kabanero.Namespace}})
 		}
 	}
-	
-  return requests
+
+	return requests
 }
 
 // Determine if requeue is needed or not.
@@ -249,8 +494,10 @@ func (r *ReconcileKabanero) determineHowToRequeue(ctx context.Context, request r
 		if strings.Compare(errorMessage, instance.Status.KabaneroInstance.Message) != 0 {
 			instance.Status.KabaneroInstance.Message = errorMessage
 			instance.Status.KabaneroInstance.Ready = "False"
+			instance.Status.ObservedGeneration = instance.GetGeneration()
+			setReadyCondition(instance, corev1.ConditionFalse, kabanerov1alpha2.KabaneroReasonReconcileFailed, errorMessage)
 			// Update the kabanero instance status.
-			err := r.client.Status().Update(ctx, instance)
+			err := patchKabaneroStatus(ctx, r.client, instance)
 			if err != nil {
 				reqLogger.Error(err, "Error updating Kabanero status.")
 			}
@@ -281,10 +528,13 @@ func (r *ReconcileKabanero) determineHowToRequeue(ctx context.Context, request r
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Result, error) {
-	ctx := context.Background()
+	// The liveness check reports a reconcile that does not complete.
+	defer cutils.BeginReconcile()()
 
-	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	// Every message of this reconcile, including those of the downloads, carries its ID.
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name, cutils.ReconcileIDKey, cutils.NewReconcileID())
 	reqLogger.Info("Reconciling Kabanero")
+	ctx := cache.WithLogger(context.Background(), reqLogger)
 
 	// Retrieve the Kabanero operator image name, for use later.  Only do this once.  Can't do it
 	// in the add() method because the client is not started yet (that would have been ideal).
@@ -334,20 +584,41 @@ func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Resu
 	// Initializes dependency data
 	initializeDependencies(instance)
 
+	// Only the oldest instance of a namespace manages it.  The others wait until it is
+	// deleted, and are not given a finalizer until then.
+	namespaceOwner, err := getNamespaceOwner(ctx, instance, r.client)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if namespaceOwner.GetUID() != instance.GetUID() {
+		return reconcile.Result{}, reportStandbyInstance(ctx, instance, namespaceOwner, r.client, reqLogger)
+	}
+
+	// The settings of the operator process are shared by every stack and component, so
+	// they are taken from the shared owner.
+	sharedOwner, err := isSharedOwner(ctx, instance, r.client)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if sharedOwner {
+		applySharedSettings(ctx, instance, r.client, reqLogger)
+	}
+
 	// Process kabanero instance deletion logic.
-	beingDeleted, err := processDeletion(ctx, instance, r.client, reqLogger)
+	beingDeleted, result, err := processDeletion(ctx, instance, r.client, reqLogger)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
 	if beingDeleted {
-		return reconcile.Result{}, nil
+		return result, nil
 	}
 
 	// Reconcile the admission controller webhook
 	err = reconcileAdmissionControllerWebhook(ctx, instance, r.client, reqLogger)
 	if err != nil {
 		reqLogger.Error(err, "Error reconciling kabanero-admission-controller-webhook")
+		reportComponentResult(r.recorder, instance, "admission controller webhook", resultFromError(err))
 		return reconcile.Result{}, err
 	}
 
@@ -363,25 +634,61 @@ func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Resu
 		return reconcile.Result{Requeue: true, RequeueAfter: 10 * time.Second}, nil
 	}
 
-	// Iterate the components and try to reconcile.  If something goes wrong,
-	// update the status and try again later.
+	// Iterate the components and try to reconcile each of them.  If something goes
+	// wrong, update the status and try again when the components asked to be retried.
+	components := aggregateResult{}
 	for _, component := range reconcileFuncs {
-		err = component.function(ctx, instance, r.client, reqLogger)
-		if err != nil {
-			reqLogger.Error(err, fmt.Sprintf("Error deploying %v.", component.name))
-			processStatus(ctx, request, instance, r.client, reqLogger)
-			return reconcile.Result{}, err
+		start := time.Now()
+		result := component.function(ctx, instance, r.client, reqLogger)
+		recordComponentMetrics(instance, component.name, result, start, time.Now())
+		if result.err != nil {
+			reqLogger.Error(result.err, fmt.Sprintf("Error deploying %v (%v).", component.name, result.class))
+			reportComponentResult(r.recorder, instance, component.name, result)
+		}
+		components.add(component.name, result)
+	}
+	recordComponentVersionMetrics(instance)
+
+	if components.failed() {
+		reqLogger.Info(fmt.Sprintf("Components not reconciled: %v", components.err().Error()))
+		processStatus(ctx, request, instance, r.client, reqLogger)
+		if components.backoff {
+			return r.determineHowToRequeue(ctx, request, instance, components.err().Error(), r.requeueDelayMap, reqLogger)
+		}
+		if components.requeueAfter == 0 {
+			return reconcile.Result{}, nil
 		}
+		return reconcile.Result{Requeue: true, RequeueAfter: components.requeueAfter}, nil
 	}
 
-	// Deploy featured stack resources.
-	err = reconcileFeaturedStacks(ctx, instance, r.client, reqLogger)
+	// Don't activate stacks while the running components are too far away
+	// from the expected versions.
+	err = checkVersionSkew(ctx, instance, r.client, reqLogger)
 	if err != nil {
-		reqLogger.Error(err, "Error reconciling featured stacks.")
+		reqLogger.Error(err, "Error checking component versions.")
+		reportComponentResult(r.recorder, instance, "component versions", resultFromError(err))
 		processStatus(ctx, request, instance, r.client, reqLogger)
 		return r.determineHowToRequeue(ctx, request, instance, err.Error(), r.requeueDelayMap, reqLogger)
 	}
 
+	// Deploy featured stack resources.  Without the stack controller there are no stacks.
+	if instance.Spec.StackController.IsEnabled() {
+		err = reconcileFeaturedStacks(ctx, instance, r.client, reqLogger)
+		if err != nil {
+			reqLogger.Error(err, "Error reconciling featured stacks.")
+			reportComponentResult(r.recorder, instance, "featured stacks", resultFromError(err))
+			processStatus(ctx, request, instance, r.client, reqLogger)
+			return r.determineHowToRequeue(ctx, request, instance, err.Error(), r.requeueDelayMap, reqLogger)
+		}
+
+		// Migrate the collections of past releases to stacks.
+		err = migrateCollections(ctx, instance, r.client, reqLogger)
+		if err != nil {
+			reqLogger.Error(err, "Error migrating collections to stacks.")
+			reportComponentResult(r.recorder, instance, "collection migration", resultFromError(err))
+		}
+	}
+
 	// things worked reset requeue data
 	r.requeueDelayMap[request.Namespace] = RequeueData{0, time.Now()}
 
@@ -397,12 +704,58 @@ func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Resu
 		return reconcile.Result{Requeue: true, RequeueAfter: 60 * time.Second}, err
 	}
 
+	// Some components may want to check on things periodically, and the repository
+	// indexes are re-read on the refresh schedule.
+	requeueAfter := components.requeueAfter
+	if untilRefresh := stack.TimeUntilScheduledRefresh(instance, time.Now()); untilRefresh > 0 && (requeueAfter == 0 || untilRefresh < requeueAfter) {
+		requeueAfter = untilRefresh
+	}
+	if requeueAfter != 0 {
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueAfter}, nil
+	}
+
 	return reconcile.Result{}, nil
 }
 
+// Applies the settings of the operator process that the Kabanero instances share.
+func applySharedSettings(ctx context.Context, instance *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) {
+	cache.SetDownloadLimits(instance.Spec.Downloads.MaxConcurrent, instance.Spec.Downloads.MaxConcurrentPerHost)
+	cache.SetCacheLimits(instance.Spec.Downloads.MaxCacheSize, instance.Spec.Downloads.MaxCacheEntries)
+	cutils.SetArchiveLimits(instance.Spec.Downloads)
+
+	// A proxy that cannot be determined is reported, and the previous one is kept.
+	if err := cache.SetEgressProxy(ctx, c, instance.Spec.EgressProxy); err != nil {
+		reqLogger.Error(err, "Unable to set the egress proxy")
+	}
+
+	if err := reconcileTraceSpec(ctx, instance, c); err != nil {
+		reqLogger.Error(err, "Unable to set the trace specification")
+	}
+
+	if err := cutils.SetTracingEndpoint(ctx, instance.Spec.Tracing); err != nil {
+		reqLogger.Error(err, "Unable to set the tracing endpoint")
+	}
+}
+
+// Reports that the input instance is not reconciled, since another instance manages its
+// namespace.
+func reportStandbyInstance(ctx context.Context, k *kabanerov1alpha2.Kabanero, owner *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	if !k.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	message := fmt.Sprintf("Kabanero instance %v manages namespace %v. This instance is not reconciled until %v is deleted.", owner.GetName(), k.GetNamespace(), owner.GetName())
+	reqLogger.Info(message)
+	k.Status.KabaneroInstance.Ready = "False"
+	k.Status.KabaneroInstance.Message = message
+	setReadyCondition(k, corev1.ConditionFalse, kabanerov1alpha2.KabaneroReasonStandby, message)
+	return patchKabaneroStatus(ctx, c, k)
+}
+
 // Drives kabanero instance deletion processing. This includes creating a finalizer, handling
-// kabanero instance cleanup logic, and finalizer removal.
-func processDeletion(ctx context.Context, k *kabanerov1alpha2.Kabanero, client client.Client, reqLogger logr.Logger) (bool, error) {
+// kabanero instance cleanup logic, and finalizer removal.  The result requeues the instance
+// while a deletion phase is waiting.
+func processDeletion(ctx context.Context, k *kabanerov1alpha2.Kabanero, client client.Client, reqLogger logr.Logger) (bool, reconcile.Result, error) {
 	// The kabanero instance is not deleted. Create a finalizer if it was not created already.
 	kabaneroFinalizer := "kabanero.io.kabanero-operator"
 	foundFinalizer := isFinalizerInList(k, kabaneroFinalizer)
@@ -417,21 +770,25 @@ func processDeletion(ctx context.Context, k *kabanerov1alpha2.Kabanero, client c
 			err := client.Update(ctx, k)
 			if err != nil {
 				reqLogger.Error(err, "Unable to set the kabanero operator finalizer.")
-				return beingDeleted, err
+				return beingDeleted, reconcile.Result{}, err
 			}
 			k.SetGroupVersionKind(gvk)
 		}
 
-		return beingDeleted, nil
+		return beingDeleted, reconcile.Result{}, nil
 	}
 
 	// The instance is being deleted.
 	if foundFinalizer {
-		// Drive kabanero cleanup processing.
-		err := cleanup(ctx, k, client, reqLogger)
+		// Drive kabanero cleanup processing, one phase at a time.
+		done, err := runDeletionPhases(ctx, k, client, reqLogger)
 		if err != nil {
 			reqLogger.Error(err, "Error during cleanup processing.")
-			return beingDeleted, err
+			return beingDeleted, reconcile.Result{}, err
+		}
+
+		if !done {
+			return beingDeleted, reconcile.Result{RequeueAfter: deletionPhaseRequeueDelay}, nil
 		}
 
 		// Remove the finalizer entry from the instance.
@@ -453,63 +810,13 @@ func processDeletion(ctx context.Context, k *kabanerov1alpha2.Kabanero, client c
 
 		if err != nil {
 			reqLogger.Error(err, "Error while attempting to remove the finalizer.")
-			return beingDeleted, err
+			return beingDeleted, reconcile.Result{}, err
 		}
 
 		k.SetGroupVersionKind(gvk)
 	}
 
-	return beingDeleted, nil
-}
-
-// Handles all cleanup logic for the Kabanero instance.
-func cleanup(ctx context.Context, k *kabanerov1alpha2.Kabanero, client client.Client, reqLogger logr.Logger) error {
-	// if landing enabled
-	if k.Spec.Landing.Enable == nil || (k.Spec.Landing.Enable != nil && *(k.Spec.Landing.Enable) == true) {
-		// Remove landing page customizations for the current namespace.
-		err := removeWebConsoleCustomization(k, client)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Remove the webhook configurations and friends.
-	err := cleanupAdmissionControllerWebhook(k, client, reqLogger)
-	if err != nil {
-		return err
-	}
-
-	// Remove the cross-namespace objects that the stack controller uses.
-	err = cleanupStackController(ctx, k, client)
-	if err != nil {
-		return err
-	}
-
-	// Remove resources deployed in support of codeready-workspaces.
-	err = deleteCRWOperatorResources(ctx, k, client)
-	if err != nil {
-		return err
-	}
-
-	// Cleanup the Gitops pipelines and their cross-namespace objects
-	err = cleanupGitopsPipelines(ctx, k, client, reqLogger)
-	if err != nil {
-		return err
-	}
-	
-	// Remove the cross-namespace objects that target namespaces use.
-	err = cleanupTargetNamespaces(ctx, k, client)
-	if err != nil {
-		return err
-	}
-	
-	// Cleanup the Devfile registry controller and its cross-namespace objects
-	err = cleanupDevfileRegistry(k, client, reqLogger)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return beingDeleted, reconcile.Result{}, nil
 }
 
 // Returns true if the kabanero operator instance has the given finalizer defined. False otherwise.
@@ -547,48 +854,86 @@ func processStatus(ctx context.Context, request reconcile.Request, k *kabanerov1
 	isGitopsReady, _ := getGitopsStatus(k)
 	isTargetNamespacesReady, _ := getTargetNamespacesStatus(k)
 
-	// Set the overall status.
-	isKabaneroReady := isStackControllerReady &&
-		isTektonReady &&
-		isServerlessReady &&
-		isCliRouteReady &&
-		isKabaneroLandingReady &&
-		isAppsodyReady &&
-		isKubernetesAppNavigatorReady &&
-		isCRWReady &&
-		isEventsReady &&
-		isAdmissionControllerWebhookReady &&
-		isSsoReady &&
-		isGitopsReady &&
-		isTargetNamespacesReady
+	// The stack summary is informational, and does not affect readiness.
+	err := getStackSummaryStatus(ctx, k, c)
+	if err != nil {
+		reqLogger.Error(err, "Unable to summarize the stack status")
+	}
+
+	// Set the overall status.  The components are named after their status fields.
+	components := []struct {
+		name  string
+		ready bool
+	}{
+		{"stackController", isStackControllerReady},
+		{"tekton", isTektonReady},
+		{"serverless", isServerlessReady},
+		{"cli", isCliRouteReady},
+		{"landing", isKabaneroLandingReady},
+		{"appsody", isAppsodyReady},
+		{"kappnav", isKubernetesAppNavigatorReady},
+		{"codereadyWorkspaces", isCRWReady},
+		{"events", isEventsReady},
+		{"admissionControllerWebhook", isAdmissionControllerWebhookReady},
+		{"sso", isSsoReady},
+		{"gitops", isGitopsReady},
+		{"targetNamespaces", isTargetNamespacesReady},
+	}
+
+	notReady := []string{}
+	for _, component := range components {
+		if !component.ready {
+			notReady = append(notReady, component.name)
+		}
+	}
+	isKabaneroReady := len(notReady) == 0
 
+	k.Status.ObservedGeneration = k.GetGeneration()
 	if isKabaneroReady {
 		k.Status.KabaneroInstance.Message = ""
 		k.Status.KabaneroInstance.Ready = "True"
+		setReadyCondition(k, corev1.ConditionTrue, kabanerov1alpha2.KabaneroReasonComponentsReady, "")
 	} else {
 		k.Status.KabaneroInstance.Message = errorMessage
+		setReadyCondition(k, corev1.ConditionFalse, kabanerov1alpha2.KabaneroReasonComponentsNotReady, fmt.Sprintf("The following components are not ready: %v", strings.Join(notReady, ", ")))
 	}
 
-	// Update the kabanero instance status in a retriable manner. The instance may have changed.
-	err := timer.Retry(10, 100*time.Millisecond, func() (bool, error) {
-		err := c.Status().Update(ctx, k)
-		if err != nil {
-			if errors.IsConflict(err) {
-				k = &kabanerov1alpha2.Kabanero{}
-				err = c.Get(context.TODO(), request.NamespacedName, k)
-
-				if err != nil {
-					return false, err
-				}
+	// Update the kabanero instance status.  The instance may have changed, so the status is patched.
+	err = patchKabaneroStatus(ctx, c, k)
 
-				return false, nil
-			}
-		}
+	return isKabaneroReady, err
+}
 
-		return true, nil
+// Sets the Ready condition of the Kabanero instance.
+func setReadyCondition(k *kabanerov1alpha2.Kabanero, status corev1.ConditionStatus, reason string, message string) {
+	now := metav1.Now()
+	k.Status.SetCondition(kabanerov1alpha2.KabaneroCondition{
+		Type:               kabanerov1alpha2.KabaneroConditionReady,
+		Status:             string(status),
+		LastTransitionTime: &now,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: k.GetGeneration(),
 	})
+}
 
-	return isKabaneroReady, err
+// Writes the status of the Kabanero instance if it differs from the status of the instance
+// in the cluster.  A merge patch of the status subresource is used, so that concurrent
+// changes to the instance do not cause conflicts.
+func patchKabaneroStatus(ctx context.Context, c client.Client, k *kabanerov1alpha2.Kabanero) error {
+	current := &kabanerov1alpha2.Kabanero{}
+	err := c.Get(ctx, client.ObjectKey{Name: k.GetName(), Namespace: k.GetNamespace()}, current)
+	if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(current.Status, k.Status) {
+		return nil
+	}
+
+	patched := current.DeepCopy()
+	patched.Status = k.Status
+	return c.Status().Patch(ctx, patched, client.MergeFrom(current))
 }
 
 // Initializes dependencies.
@@ -0,0 +1,65 @@
+package kabaneroplatform
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// Test that the Ready condition is replaced, and that its transition time only changes
+// with its status.
+func TestSetReadyCondition(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{}
+	setReadyCondition(k, corev1.ConditionFalse, kabanerov1alpha2.KabaneroReasonComponentsNotReady, "The following components are not ready: tekton")
+	ready := k.Status.GetCondition(kabanerov1alpha2.KabaneroConditionReady)
+	if ready == nil || ready.Status != "False" || ready.Reason != kabanerov1alpha2.KabaneroReasonComponentsNotReady {
+		t.Fatalf("Expected the instance not to be ready: %+v", ready)
+	}
+	transitionTime := ready.LastTransitionTime
+
+	setReadyCondition(k, corev1.ConditionFalse, kabanerov1alpha2.KabaneroReasonReconcileFailed, "Unable to reconcile")
+	ready = k.Status.GetCondition(kabanerov1alpha2.KabaneroConditionReady)
+	if ready.Reason != kabanerov1alpha2.KabaneroReasonReconcileFailed || ready.LastTransitionTime != transitionTime {
+		t.Fatalf("Expected the reason to change, and the transition time to be kept: %+v", ready)
+	}
+
+	setReadyCondition(k, corev1.ConditionTrue, kabanerov1alpha2.KabaneroReasonComponentsReady, "")
+	ready = k.Status.GetCondition(kabanerov1alpha2.KabaneroConditionReady)
+	if ready.Status != "True" || ready.LastTransitionTime == transitionTime {
+		t.Fatalf("Expected the instance to be ready with a new transition time: %+v", ready)
+	}
+	if len(k.Status.Conditions) != 1 {
+		t.Fatalf("Expected a single condition: %+v", k.Status.Conditions)
+	}
+}
+
+// Test that a service or route update is processed when its spec changes, and only a
+// route when its status changes.
+func TestSpecWatchPredicate(t *testing.T) {
+	p := getSpecWatchPredicateFunc()
+
+	oldService := &corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 443}}}}
+	newService := oldService.DeepCopy()
+	newService.Labels = map[string]string{"app": "kabanero-cli"}
+	newService.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "example.com"}}
+	if p.Update(event.UpdateEvent{MetaOld: oldService, ObjectOld: oldService, MetaNew: newService, ObjectNew: newService}) {
+		t.Fatal("Expected a service update without a spec change to be ignored")
+	}
+	newService.Spec.Ports[0].Port = 8443
+	if !p.Update(event.UpdateEvent{MetaOld: oldService, ObjectOld: oldService, MetaNew: newService, ObjectNew: newService}) {
+		t.Fatal("Expected a service spec change to be processed")
+	}
+
+	oldRoute := &routev1.Route{Spec: routev1.RouteSpec{Host: "kabanero-cli.apps.example.com"}}
+	newRoute := oldRoute.DeepCopy()
+	if p.Update(event.UpdateEvent{MetaOld: oldRoute, ObjectOld: oldRoute, MetaNew: newRoute, ObjectNew: newRoute}) {
+		t.Fatal("Expected an unchanged route to be ignored")
+	}
+	newRoute.Status.Ingress = []routev1.RouteIngress{{Host: "kabanero-cli.apps.example.com"}}
+	if !p.Update(event.UpdateEvent{MetaOld: oldRoute, ObjectOld: oldRoute, MetaNew: newRoute, ObjectNew: newRoute}) {
+		t.Fatal("Expected a route status change to be processed")
+	}
+}
@@ -28,10 +28,17 @@ import (
 var kllog = rlog.Log.WithName("kabanero-landing")
 
 // Deploys resources and customizes to the Openshift web console.
-func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, logger logr.Logger) error {
+func deployLandingPage(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, logger logr.Logger) error {
+	// The web console links are cluster level, so only the shared owner points them at
+	// its landing page.
+	sharedOwner, err := isSharedOwner(ctx, k, c)
+	if err != nil {
+		return err
+	}
+
 	// If enable is false do not deploy the landing page.
 	if k.Spec.Landing.Enable != nil && *(k.Spec.Landing.Enable) == false {
-		err := cleanupLandingPage(k, c)
+		err := cleanupLandingPage(k, c, sharedOwner)
 		return err
 	}
 
@@ -67,7 +74,12 @@ func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client
 		return err
 	}
 
-	transforms := []mf.Transformer{mf.InjectOwner(k), mf.InjectNamespace(k.GetNamespace())}
+	transforms := []mf.Transformer{
+		mf.InjectOwner(k),
+		mf.InjectNamespace(k.GetNamespace()),
+		injectCommonMetadata(k),
+		kabTransforms.SetRoute(k.Spec.Landing.Route.Hostname, k.Spec.Landing.Route.Termination, k.Spec.Landing.Route.InsecureEdgeTerminationPolicy),
+	}
 	m, err := mOrig.Transform(transforms...)
 	if err != nil {
 		return err
@@ -104,6 +116,9 @@ func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client
 	transforms = []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
+		injectCommonMetadata(k),
+		injectScheduling(k),
+		kabTransforms.SetContainerResources(k.Spec.Landing.Resources),
 		kabTransforms.AddEnvVariable("LANDING_URL", landingURL),
 	}
 
@@ -157,16 +172,20 @@ func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client
 	}
 
 	// Update the web console's ConfigMap with custom data.
-	err = customizeWebConsole(k, c, landingURL)
+	if sharedOwner {
+		err = customizeWebConsole(k, c, landingURL)
+	}
 
 	return err
 }
 
-func cleanupLandingPage(k *kabanerov1alpha2.Kabanero, c client.Client) error {
-	err := removeWebConsoleCustomization(k, c)
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			return err
+func cleanupLandingPage(k *kabanerov1alpha2.Kabanero, c client.Client, removeConsoleLinks bool) error {
+	if removeConsoleLinks {
+		err := removeWebConsoleCustomization(k, c)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
 		}
 	}
 
@@ -242,15 +261,16 @@ func getLandingURL(k *kabanerov1alpha2.Kabanero, c client.Client) (string, error
 		return landingURL, err
 	}
 
-	// Look for the ingress entry with the status of admitted.
-	// There should only be one URL and it should be the one that is auto generated.
-	for _, ingress := range landingRoute.Status.Ingress {
-		for _, condition := range ingress.Conditions {
-			if condition.Type == routev1.RouteAdmitted && condition.Status == corev1.ConditionTrue {
-				landingURL = ingress.Host
-				break
-			}
-		}
+	// Look for the ingress entry with the status of admitted.  Use the requested hostname
+	// if there is one, or else the one that is auto generated.
+	hostnames, message := getAdmittedRouteHostnames(landingRoute, k.Spec.Landing.Route.Hostname)
+	if len(message) > 0 {
+		return landingURL, errors.New(message)
+	}
+	if len(k.Spec.Landing.Route.Hostname) > 0 {
+		landingURL = k.Spec.Landing.Route.Hostname
+	} else if len(hostnames) > 0 {
+		landingURL = hostnames[0]
 	}
 
 	// If the URL is invalid, return an error.
@@ -431,6 +451,22 @@ func getKabaneroLandingPageStatus(k *kabanerov1alpha2.Kabanero, c client.Client)
 		return false, err
 	}
 
+	// Report the admitted hostnames of the landing route.
+	landingRoute := &routev1.Route{}
+	err = c.Get(context.TODO(), types.NamespacedName{Namespace: k.ObjectMeta.Namespace, Name: "kabanero-landing"}, landingRoute)
+	if err != nil {
+		k.Status.Landing.Ready = "False"
+		k.Status.Landing.Message = "The Route object for the landing page could not be retrieved: " + err.Error()
+		return false, err
+	}
+
+	hostnames, message := getAdmittedRouteHostnames(landingRoute, k.Spec.Landing.Route.Hostname)
+	k.Status.Landing.Hostnames = hostnames
+	if len(message) > 0 {
+		ready = false
+		finalErrorMessage += message + ". "
+	}
+
 	for _, pod := range pods.Items {
 		for _, condition := range pod.Status.Conditions {
 			if strings.ToLower(string(condition.Type)) == "ready" {
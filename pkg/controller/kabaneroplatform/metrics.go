@@ -0,0 +1,100 @@
+package kabaneroplatform
+
+import (
+	"sync"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Metrics describing the reconciliation of the components of each Kabanero instance.  They
+// are served by the manager's metrics endpoint.
+var (
+	componentReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kabanero_component_reconcile_duration_seconds",
+		Help:    "Time taken to reconcile a component of a Kabanero instance.",
+		Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120},
+	}, []string{"namespace", "component"})
+
+	componentLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kabanero_component_last_success_timestamp_seconds",
+		Help: "Time a component of a Kabanero instance was last reconciled successfully, in seconds since the epoch.",
+	}, []string{"namespace", "component"})
+
+	componentReconcileFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kabanero_component_reconcile_failures_total",
+		Help: "Number of times a component of a Kabanero instance was not reconciled, by reason: transient, waiting, permanent or forbidden.",
+	}, []string{"namespace", "component", "reason"})
+
+	componentVersion = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kabanero_component_version",
+		Help: "The software revision chosen for a component of a Kabanero instance.  The value is always 1.",
+	}, []string{"namespace", "component", "version"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(componentReconcileDuration, componentLastSuccess, componentReconcileFailures, componentVersion)
+}
+
+// The version reported for each software component, by namespace, so that the series of
+// a previous version can be removed when it changes.
+var componentVersions = struct {
+	sync.Mutex
+	versions map[string]map[string]string
+}{versions: make(map[string]map[string]string)}
+
+// Records the outcome of reconciling a component of the Kabanero instance.
+func recordComponentMetrics(k *kabanerov1alpha2.Kabanero, name string, result componentResult, start time.Time, end time.Time) {
+	namespace := k.GetNamespace()
+	componentReconcileDuration.WithLabelValues(namespace, name).Observe(end.Sub(start).Seconds())
+	if result.err != nil {
+		componentReconcileFailures.WithLabelValues(namespace, name, result.class.String()).Inc()
+		return
+	}
+
+	componentLastSuccess.WithLabelValues(namespace, name).Set(float64(end.Unix()))
+}
+
+// Records the software revisions chosen for the components of the Kabanero instance.
+func recordComponentVersionMetrics(k *kabanerov1alpha2.Kabanero) {
+	namespace := k.GetNamespace()
+
+	componentVersions.Lock()
+	defer componentVersions.Unlock()
+
+	versions := componentVersions.versions[namespace]
+	if versions == nil {
+		versions = make(map[string]string)
+		componentVersions.versions[namespace] = versions
+	}
+
+	for _, revision := range k.Status.KabaneroInstance.Revisions {
+		previous, ok := versions[revision.Component]
+		if ok && previous != revision.Version {
+			componentVersion.DeleteLabelValues(namespace, revision.Component, previous)
+		}
+		versions[revision.Component] = revision.Version
+		componentVersion.WithLabelValues(namespace, revision.Component, revision.Version).Set(1)
+	}
+}
+
+// Removes the metrics of a Kabanero instance that was deleted.
+func pruneComponentMetrics(namespace string) {
+	componentVersions.Lock()
+	defer componentVersions.Unlock()
+
+	for component, version := range componentVersions.versions[namespace] {
+		componentVersion.DeleteLabelValues(namespace, component, version)
+	}
+	delete(componentVersions.versions, namespace)
+
+	for _, component := range reconcileFuncs {
+		componentReconcileDuration.DeleteLabelValues(namespace, component.name)
+		componentLastSuccess.DeleteLabelValues(namespace, component.name)
+		for _, class := range []errorClass{errorClassTransient, errorClassWaiting, errorClassPermanent, errorClassForbidden} {
+			componentReconcileFailures.DeleteLabelValues(namespace, component.name, class.String())
+		}
+	}
+}
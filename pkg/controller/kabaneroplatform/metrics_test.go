@@ -0,0 +1,57 @@
+package kabaneroplatform
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that the outcome of reconciling a component is recorded.
+func TestRecordComponentMetrics(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "metrics-test"}}
+	defer pruneComponentMetrics(k.GetNamespace())
+
+	start := time.Unix(1000, 0)
+	recordComponentMetrics(k, "sso", componentSuccess(), start, start.Add(time.Second))
+	if value := testutil.ToFloat64(componentLastSuccess.WithLabelValues(k.GetNamespace(), "sso")); value != 1001 {
+		t.Errorf("Expected the last success at 1001, but found %v", value)
+	}
+
+	recordComponentMetrics(k, "sso", componentPermanentError(errors.New("bad config")), start, start.Add(2*time.Second))
+	if value := testutil.ToFloat64(componentReconcileFailures.WithLabelValues(k.GetNamespace(), "sso", "permanent")); value != 1 {
+		t.Errorf("Expected 1 permanent failure, but found %v", value)
+	}
+	if value := testutil.ToFloat64(componentLastSuccess.WithLabelValues(k.GetNamespace(), "sso")); value != 1001 {
+		t.Errorf("Expected the last success to remain at 1001, but found %v", value)
+	}
+}
+
+// Test that only the current version of a component is reported.
+func TestRecordComponentVersionMetrics(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "metrics-test"}}
+	defer pruneComponentMetrics(k.GetNamespace())
+
+	k.Status.KabaneroInstance.Revisions = []kabanerov1alpha2.SoftwareRevisionStatus{{Component: "landing", Version: "0.1.0"}}
+	recordComponentVersionMetrics(k)
+	if count := testutil.CollectAndCount(componentVersion); count != 1 {
+		t.Fatalf("Expected 1 version series, but found %v", count)
+	}
+
+	k.Status.KabaneroInstance.Revisions[0].Version = "0.2.0"
+	recordComponentVersionMetrics(k)
+	if count := testutil.CollectAndCount(componentVersion); count != 1 {
+		t.Fatalf("Expected 1 version series after the upgrade, but found %v", count)
+	}
+	if value := testutil.ToFloat64(componentVersion.WithLabelValues(k.GetNamespace(), "landing", "0.2.0")); value != 1 {
+		t.Errorf("Expected version 0.2.0 to be reported, but found %v", value)
+	}
+
+	pruneComponentMetrics(k.GetNamespace())
+	if count := testutil.CollectAndCount(componentVersion); count != 0 {
+		t.Errorf("Expected no version series after pruning, but found %v", count)
+	}
+}
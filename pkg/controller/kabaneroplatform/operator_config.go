@@ -0,0 +1,136 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The name of the ConfigMap that publishes the operator's effective configuration.
+const operatorConfigMapName = "kabanero-operator-config-status"
+
+// Marks the ConfigMap as written by the operator.  Changes made by anyone else are reverted.
+const operatorConfigReadOnlyAnnotation = "kabanero.io/read-only"
+
+// Publishes the configuration that the operator is using into a ConfigMap in the Kabanero
+// namespace, so that it can be checked without looking inside the operator pod.  The
+// ConfigMap is rewritten whenever the configuration, or the ConfigMap itself, changes.
+func reconcileOperatorConfig(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	data := getOperatorConfig(k)
+
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, client.ObjectKey{Name: operatorConfigMapName, Namespace: k.GetNamespace()}, cm)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		ownerIsController := true
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        operatorConfigMapName,
+				Namespace:   k.GetNamespace(),
+				Annotations: map[string]string{operatorConfigReadOnlyAnnotation: "true"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: k.TypeMeta.APIVersion,
+					Kind:       k.TypeMeta.Kind,
+					Name:       k.ObjectMeta.Name,
+					UID:        k.ObjectMeta.UID,
+					Controller: &ownerIsController,
+				}},
+			},
+			Data: data,
+		}
+
+		reqLogger.Info(fmt.Sprintf("Creating the operator configuration ConfigMap %v", operatorConfigMapName))
+		return c.Create(ctx, cm)
+	}
+
+	if reflect.DeepEqual(cm.Data, data) && cm.Annotations[operatorConfigReadOnlyAnnotation] == "true" {
+		return nil
+	}
+
+	if cm.Annotations == nil {
+		cm.Annotations = make(map[string]string)
+	}
+	cm.Annotations[operatorConfigReadOnlyAnnotation] = "true"
+	cm.Data = data
+
+	reqLogger.Info(fmt.Sprintf("Updating the operator configuration ConfigMap %v", operatorConfigMapName))
+	return c.Update(ctx, cm)
+}
+
+// Returns the configuration that the operator is using for the input Kabanero instance.
+// The logging flags are those of the operator process.  The stack controller runs in its
+// own pod, and is not reported on separately: the settings it uses, such as the download
+// limits, come from the same Kabanero instance, but its logging flags are fixed by its
+// deployment.
+func getOperatorConfig(k *kabanerov1alpha2.Kabanero) map[string]string {
+	watchNamespace, _ := k8sutil.GetWatchNamespace()
+	maxDownloads, maxDownloadsPerHost := cache.GetDownloadLimits()
+
+	conflictPolicy := k.Spec.Stacks.ConflictPolicy
+	if len(conflictPolicy) == 0 {
+		conflictPolicy = kabanerov1alpha2.StackConflictPolicyFirstWins
+	}
+
+	assetDeletionPolicy := k.Spec.AssetDeletionPolicy
+	if len(assetDeletionPolicy) == 0 {
+		assetDeletionPolicy = kabanerov1alpha2.AssetDeletionPolicyDelete
+	}
+
+	assetDriftPolicy := k.Spec.AssetDriftPolicy
+	if len(assetDriftPolicy) == 0 {
+		assetDriftPolicy = kabanerov1alpha2.AssetDriftPolicyRepair
+	}
+
+	return map[string]string{
+		"watchNamespace":                      watchNamespace,
+		"operatorImage":                       operatorContainerImage,
+		"kabaneroVersion":                     k.Status.KabaneroInstance.Version,
+		"downloads.maxConcurrent":             strconv.Itoa(maxDownloads),
+		"downloads.maxConcurrentPerHost":      strconv.Itoa(maxDownloadsPerHost),
+		"stacks.indexCacheTTL":                stack.GetIndexCacheTTL(k).String(),
+		"stacks.digestDriftCheckInterval":     stack.GetDigestDriftCheckInterval(k).String(),
+		"stacks.digestCacheTTL":               stack.GetDigestCacheTTL(k).String(),
+		"stacks.refreshSchedule":              k.Spec.Stacks.RefreshSchedule,
+		"stacks.conflictPolicy":               conflictPolicy,
+		"stacks.skipRegistryCertVerification": strconv.FormatBool(k.Spec.Stacks.SkipRegistryCertVerification),
+		"artifactProxy.enabled":               strconv.FormatBool(len(k.Spec.ArtifactProxy.Url) != 0),
+		"triggerNamespace":                    cutils.GetTriggerNamespace(k),
+		"logging.flags":                       getLoggingFlags(pflag.CommandLine),
+		"features.createAssetNamespaces":      strconv.FormatBool(k.Spec.CreateAssetNamespaces),
+		"features.assetDeletionPolicy":        assetDeletionPolicy,
+		"features.assetDriftPolicy":           assetDriftPolicy,
+		"features.registryMirrors":            strconv.FormatBool(len(k.Spec.RegistryMirrors) != 0),
+	}
+}
+
+// Returns the logging flags set on the operator's command line, such as --zap-level,
+// or "default" if none were set.
+func getLoggingFlags(flags *pflag.FlagSet) string {
+	set := []string{}
+	flags.Visit(func(f *pflag.Flag) {
+		if strings.HasPrefix(f.Name, "zap-") {
+			set = append(set, fmt.Sprintf("--%v=%v", f.Name, f.Value.String()))
+		}
+	})
+	if len(set) == 0 {
+		return "default"
+	}
+	return strings.Join(set, " ")
+}
@@ -0,0 +1,107 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	utesting "github.com/kabanero-io/kabanero-operator/pkg/controller/utils/testing"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var oclog = logf.Log.WithName("operator_config_test")
+
+// Returns the operator ConfigMap, and the number of times it was written.
+func getOperatorConfig(t *testing.T, c *utesting.Client) (*corev1.ConfigMap, int) {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(context.TODO(), client.ObjectKey{Name: operatorConfigMapName, Namespace: "kabanero"}, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writes := 0
+	for _, action := range c.Actions() {
+		if action.Kind.Kind == "ConfigMap" && (action.Verb == utesting.VerbCreate || action.Verb == utesting.VerbUpdate) {
+			writes++
+		}
+	}
+	return cm, writes
+}
+
+// Test that the configuration is published, only rewritten when it changes, and
+// that changes made by others are reverted.
+func TestReconcileOperatorConfig(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "1234"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Stacks: kabanerov1alpha2.InstanceStackConfig{
+				IndexCacheTTL: &metav1.Duration{Duration: 10 * time.Minute},
+			},
+		},
+	}
+
+	c := utesting.NewClient()
+	err := reconcileOperatorConfig(context.TODO(), k, c, oclog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, writes := getOperatorConfig(t, c)
+	if writes != 1 {
+		t.Fatalf("Expected the ConfigMap to be created, but found %v writes: %v", writes, cm)
+	}
+	if cm.Data["stacks.indexCacheTTL"] != "10m0s" || cm.Data["stacks.conflictPolicy"] != kabanerov1alpha2.StackConflictPolicyFirstWins {
+		t.Errorf("Unexpected configuration: %v", cm.Data)
+	}
+	if len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0].UID != k.UID {
+		t.Errorf("Expected the ConfigMap to be owned by the Kabanero instance: %v", cm.OwnerReferences)
+	}
+
+	// Nothing changed.
+	err = reconcileOperatorConfig(context.TODO(), k, c, oclog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, writes = getOperatorConfig(t, c); writes != 1 {
+		t.Fatalf("Expected the ConfigMap not to be rewritten, but found %v writes", writes)
+	}
+
+	// Someone edited the ConfigMap.
+	cm.Data["stacks.indexCacheTTL"] = "1h"
+	if err = c.Update(context.TODO(), cm); err != nil {
+		t.Fatal(err)
+	}
+	err = reconcileOperatorConfig(context.TODO(), k, c, oclog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The edit is the second write.
+	if cm, writes = getOperatorConfig(t, c); writes != 3 || cm.Data["stacks.indexCacheTTL"] != "10m0s" {
+		t.Fatalf("Expected the edit to be reverted, but found %v writes: %v", writes, cm.Data)
+	}
+}
+
+// Test that only the logging flags set on the command line are reported.
+func TestGetLoggingFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("zap-level", "info", "")
+	flags.Bool("zap-devel", false, "")
+	flags.String("namespace", "", "")
+
+	if logging := getLoggingFlags(flags); logging != "default" {
+		t.Fatalf("Expected the default logging flags, but found %v", logging)
+	}
+
+	err := flags.Parse([]string{"--zap-level=debug", "--namespace=kabanero"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logging := getLoggingFlags(flags); logging != "--zap-level=debug" {
+		t.Fatalf("Expected --zap-level=debug, but found %v", logging)
+	}
+}
@@ -0,0 +1,224 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// How a component failure should be retried.
+type errorClass int
+
+const (
+	// The component reconciled successfully.
+	errorClassNone errorClass = iota
+
+	// Something went wrong that is likely to go away by itself (a conflict, a
+	// timeout, an unavailable API server).  Retry soon.
+	errorClassTransient
+
+	// The component is waiting for something outside of its control, such as
+	// a deployment becoming available.  Retry after the requeue hint.
+	errorClassWaiting
+
+	// The Kabanero instance is not valid, for example it references a secret
+	// that does not exist.  Retrying will not help until the instance (or
+	// something it watches) changes, so there is no requeue.
+	errorClassPermanent
+
+	// The operator is not allowed to do something.  The permission may be granted
+	// without the Kabanero instance changing, so retry with an increasing delay.
+	errorClassForbidden
+)
+
+// The reasons of the events recorded on the Kabanero instance when a component is not
+// reconciled.
+const (
+	componentFailedEventReason  = "ComponentFailed"
+	componentWaitingEventReason = "ComponentWaiting"
+)
+
+// Default requeue hints, for results that do not specify one.
+const (
+	transientRequeueDelay = 10 * time.Second
+	waitingRequeueDelay   = 30 * time.Second
+)
+
+func (c errorClass) String() string {
+	switch c {
+	case errorClassNone:
+		return "none"
+	case errorClassTransient:
+		return "transient"
+	case errorClassWaiting:
+		return "waiting"
+	case errorClassPermanent:
+		return "permanent"
+	case errorClassForbidden:
+		return "forbidden"
+	}
+	return "unknown"
+}
+
+// The outcome of a single component reconciler.
+type componentResult struct {
+	// The classification of err.  errorClassNone if err is nil.
+	class errorClass
+
+	// The error, if any.
+	err error
+
+	// When to reconcile again.  Zero means the component does not need to be
+	// reconciled again until something changes.
+	requeueAfter time.Duration
+}
+
+// A successful result, with no requeue.
+func componentSuccess() componentResult {
+	return componentResult{}
+}
+
+// A successful result, which should be reconciled again after the input delay.
+func componentRequeueAfter(delay time.Duration) componentResult {
+	return componentResult{requeueAfter: delay}
+}
+
+// A component that is waiting for something, and should be checked again after the input delay.
+func componentWaiting(err error, delay time.Duration) componentResult {
+	return componentResult{class: errorClassWaiting, err: err, requeueAfter: delay}
+}
+
+// A component that can not be reconciled until the Kabanero instance changes.
+func componentPermanentError(err error) componentResult {
+	return componentResult{class: errorClassPermanent, err: err}
+}
+
+// A component that was not allowed to do something.  It is retried with backoff.
+func componentForbiddenError(err error) componentResult {
+	return componentResult{class: errorClassForbidden, err: err}
+}
+
+// A component that failed, but is expected to succeed if retried.
+func componentTransientError(err error) componentResult {
+	return componentResult{class: errorClassTransient, err: err, requeueAfter: transientRequeueDelay}
+}
+
+// An error that carries its own classification.  Component reconcilers that
+// return a plain error can wrap it in a classifiedError to control how it is retried.
+type classifiedError struct {
+	result componentResult
+}
+
+func (e classifiedError) Error() string {
+	return e.result.err.Error()
+}
+
+// Wraps an error, marking it as waiting for the input delay.
+func waitingError(err error, delay time.Duration) error {
+	return classifiedError{result: componentWaiting(err, delay)}
+}
+
+// Wraps an error, marking it as permanent.
+func permanentError(err error) error {
+	return classifiedError{result: componentPermanentError(err)}
+}
+
+// Converts the error returned by a component reconciler into a result.  Errors
+// are transient unless they say otherwise.
+func resultFromError(err error) componentResult {
+	if err == nil {
+		return componentSuccess()
+	}
+
+	if ce, ok := err.(classifiedError); ok {
+		return ce.result
+	}
+
+	if errors.IsForbidden(err) {
+		return componentForbiddenError(err)
+	}
+
+	if errors.IsInvalid(err) || errors.IsBadRequest(err) {
+		return componentPermanentError(err)
+	}
+
+	return componentTransientError(err)
+}
+
+// Adapts a component reconciler that returns an error into one that returns a result.
+func withErrorResult(f func(context.Context, *kabanerov1alpha2.Kabanero, client.Client, logr.Logger) error) reconcileFunc {
+	return func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) componentResult {
+		return resultFromError(f(ctx, k, c, reqLogger))
+	}
+}
+
+// The combined outcome of all of the component reconcilers.
+type aggregateResult struct {
+	// The failed components, and their results, in the order they were reconciled.
+	failures []string
+	results  []componentResult
+
+	// The shortest requeue hint of any component, or zero if none asked to be requeued.
+	requeueAfter time.Duration
+
+	// True if any component should be retried with backoff.
+	backoff bool
+}
+
+// Adds the result of a component.
+func (a *aggregateResult) add(name string, result componentResult) {
+	if result.requeueAfter > 0 && (a.requeueAfter == 0 || result.requeueAfter < a.requeueAfter) {
+		a.requeueAfter = result.requeueAfter
+	}
+
+	if result.class == errorClassForbidden {
+		a.backoff = true
+	}
+
+	if result.err != nil {
+		a.failures = append(a.failures, name)
+		a.results = append(a.results, result)
+	}
+}
+
+// Returns true if any component failed.
+func (a aggregateResult) failed() bool {
+	return len(a.failures) != 0
+}
+
+// Returns a single error describing every failed component, or nil if none failed.
+func (a aggregateResult) err() error {
+	if !a.failed() {
+		return nil
+	}
+
+	messages := []string{}
+	for i, name := range a.failures {
+		messages = append(messages, fmt.Sprintf("Error deploying %v: %v", name, a.results[i].err.Error()))
+	}
+	return fmt.Errorf("%v", strings.Join(messages, "; "))
+}
+
+// Records an event on the Kabanero instance for a component that was not reconciled, so
+// that describing the instance explains why it is not ready.  A component that is waiting
+// is reported as a normal event, since it is expected to resolve by itself.
+func reportComponentResult(recorder record.EventRecorder, k *kabanerov1alpha2.Kabanero, name string, result componentResult) {
+	if recorder == nil || result.err == nil {
+		return
+	}
+
+	eventType, reason := corev1.EventTypeWarning, componentFailedEventReason
+	if result.class == errorClassWaiting {
+		eventType, reason = corev1.EventTypeNormal, componentWaitingEventReason
+	}
+
+	recorder.Event(k, eventType, reason, fmt.Sprintf("Error deploying %v (%v): %v", name, result.class, result.err.Error()))
+}
@@ -0,0 +1,109 @@
+package kabaneroplatform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+)
+
+// Test that errors returned by component reconcilers are classified.
+func TestResultFromError(t *testing.T) {
+	plain := errors.New("something went wrong")
+	tests := []struct {
+		err          error
+		class        errorClass
+		requeueAfter time.Duration
+	}{
+		{nil, errorClassNone, 0},
+		{plain, errorClassTransient, transientRequeueDelay},
+		{kerrors.NewConflict(schema.GroupResource{}, "test", plain), errorClassTransient, transientRequeueDelay},
+		{kerrors.NewBadRequest("bad"), errorClassPermanent, 0},
+		{kerrors.NewForbidden(schema.GroupResource{Resource: "serviceaccounts"}, "test", plain), errorClassForbidden, 0},
+		{permanentError(plain), errorClassPermanent, 0},
+		{waitingError(plain, 5*time.Second), errorClassWaiting, 5 * time.Second},
+	}
+
+	for _, test := range tests {
+		result := resultFromError(test.err)
+		if result.class != test.class {
+			t.Errorf("Expected error %v to be %v, but it was %v", test.err, test.class, result.class)
+		}
+		if result.requeueAfter != test.requeueAfter {
+			t.Errorf("Expected error %v to requeue after %v, but found %v", test.err, test.requeueAfter, result.requeueAfter)
+		}
+	}
+}
+
+// Test that the shortest requeue hint wins, and that every failure is reported.
+func TestAggregateResult(t *testing.T) {
+	a := aggregateResult{}
+	a.add("stack controller", componentSuccess())
+	if a.failed() || a.err() != nil || a.requeueAfter != 0 {
+		t.Fatalf("Expected an empty result, but found %v", a)
+	}
+
+	a.add("landing page", componentRequeueAfter(2*time.Minute))
+	a.add("sso", componentWaiting(errors.New("secret missing"), waitingRequeueDelay))
+	a.add("gitops", componentPermanentError(errors.New("bad config")))
+
+	if !a.failed() {
+		t.Fatal("Expected the result to be failed")
+	}
+	if a.requeueAfter != waitingRequeueDelay {
+		t.Errorf("Expected to requeue after %v, but found %v", waitingRequeueDelay, a.requeueAfter)
+	}
+
+	if a.backoff {
+		t.Error("Expected no backoff without a forbidden error")
+	}
+	a.add("events", componentForbiddenError(errors.New("forbidden")))
+	if !a.backoff {
+		t.Error("Expected backoff after a forbidden error")
+	}
+
+	expected := "Error deploying sso: secret missing; Error deploying gitops: bad config; Error deploying events: forbidden"
+	if a.err().Error() != expected {
+		t.Errorf("Expected error \"%v\", but found \"%v\"", expected, a.err().Error())
+	}
+}
+
+// Test that components that are not reconciled are reported as events on the instance.
+func TestReportComponentResult(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+	recorder := record.NewFakeRecorder(10)
+
+	reportComponentResult(recorder, k, "landing page", componentSuccess())
+	if len(recorder.Events) != 0 {
+		t.Fatalf("Expected no event for a successful component, but found %v", len(recorder.Events))
+	}
+
+	tests := []struct {
+		result componentResult
+		prefix string
+	}{
+		{componentPermanentError(errors.New("bad config")), "Warning ComponentFailed Error deploying sso (permanent): bad config"},
+		{componentWaiting(errors.New("not available"), waitingRequeueDelay), "Normal ComponentWaiting Error deploying sso (waiting): not available"},
+	}
+
+	for _, test := range tests {
+		reportComponentResult(recorder, k, "sso", test.result)
+		select {
+		case event := <-recorder.Events:
+			if !strings.HasPrefix(event, test.prefix) {
+				t.Errorf("Expected event \"%v\", but found \"%v\"", test.prefix, event)
+			}
+		default:
+			t.Errorf("Expected an event for %v", test.result.err)
+		}
+	}
+
+	// A missing recorder is ignored.
+	reportComponentResult(nil, k, "sso", componentPermanentError(errors.New("bad config")))
+}
@@ -0,0 +1,61 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Several Kabanero instances share the settings of the operator process and the cluster level
+// resources.  Rather than each instance overwriting them, the oldest instance of the watched
+// namespaces manages them.  When it is deleted, the shared resources are left in place, and the
+// next oldest instance takes them over.  They are only removed with the last instance.
+
+// Returns the Kabanero instance that manages the namespace of the input instance.
+func getNamespaceOwner(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Reader) (*kabanerov1alpha2.Kabanero, error) {
+	kabaneros := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(ctx, kabaneros, client.InNamespace(k.GetNamespace()))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the Kabanero instances in namespace %v: %v", k.GetNamespace(), err)
+	}
+
+	owner := cutils.OldestKabaneroInstance(kabaneros.Items)
+	if owner == nil {
+		// The cache has not seen the instance yet.
+		return k, nil
+	}
+	return owner, nil
+}
+
+// Returns true if the input instance manages the settings and the cluster level resources
+// that the Kabanero instances share.
+func isSharedOwner(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Reader) (bool, error) {
+	kabaneros := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(ctx, kabaneros)
+	if err != nil {
+		return false, fmt.Errorf("Unable to list the Kabanero instances: %v", err)
+	}
+
+	owner := cutils.OldestKabaneroInstance(kabaneros.Items)
+	return owner == nil || owner.GetUID() == k.GetUID(), nil
+}
+
+// Returns true if a Kabanero instance other than the input one remains, which then takes over
+// the shared resources.
+func hasOtherKabaneroInstances(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Reader) (bool, error) {
+	kabaneros := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(ctx, kabaneros)
+	if err != nil {
+		return false, fmt.Errorf("Unable to list the Kabanero instances: %v", err)
+	}
+
+	for _, kabanero := range kabaneros.Items {
+		if kabanero.GetUID() != k.GetUID() && kabanero.DeletionTimestamp.IsZero() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
 
 	mf "github.com/manifestival/manifestival"
 	mfc "github.com/manifestival/controller-runtime-client"
@@ -39,10 +40,14 @@ func reconcileSso(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Cl
 		return err
 	}
 	
-	// Go make sure that the necessary secret has been created.
+	// Go make sure that the necessary secret has been created.  Secrets are not
+	// watched, so if it is missing, check again later.
 	err = checkSecret(ctx, k, c, reqLogger)
 	if err != nil {
-		return err
+		if _, ok := err.(classifiedError); ok {
+			return err
+		}
+		return waitingError(err, waitingRequeueDelay)
 	}
 	
 	//The context which will be used to render any templates
@@ -101,6 +106,9 @@ func reconcileSso(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Cl
 	transforms := []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
+		injectCommonMetadata(k),
+		injectScheduling(k),
+		kabTransforms.SetContainerResources(k.Spec.Sso.Resources),
 	}
 
 	m, err := mOrig.Transform(transforms...)
@@ -121,7 +129,7 @@ func reconcileSso(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Cl
 func checkSecret(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
 
 	if len(k.Spec.Sso.AdminSecretName) == 0 {
-		return errors.New("The SSO admin secret name must be specified in the Kabanero CR instance")
+		return permanentError(errors.New("The SSO admin secret name must be specified in the Kabanero CR instance"))
 	}
 	
 	secretInstance := &corev1.Secret{}
@@ -7,9 +7,15 @@ import (
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	mfc "github.com/manifestival/controller-runtime-client"
 	mf "github.com/manifestival/manifestival"
 	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	rlog "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -20,11 +26,22 @@ const (
 	scVersionSoftCompName   = "stack-controller"
 	scOrchestrationFileName = "stack-controller.yaml"
 
+	scClusterOrchestrationFileName = "stack-controller-cluster.yaml"
+
 	scDeploymentResourceName = "kabanero-operator-stack-controller"
+
+	// Label set on the trigger Role and RoleBinding.  The value is the UID of the
+	// Kabanero instance.
+	stackTriggerOwnerLabel = "kabanero.io/stack-trigger-owner"
 )
 
 // Installs the Kabanero stack controller.
 func reconcileStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, _ logr.Logger) error {
+	// If the stack controller is disabled, remove it.
+	if !k.Spec.StackController.IsEnabled() {
+		return disableStackController(ctx, k, c)
+	}
+
 	logger := sclog.WithValues("Kabanero instance namespace", k.Namespace, "Kabanero instance Name", k.Name)
 	logger.Info("Reconciling Kabanero stack controller installation.")
 
@@ -63,6 +80,9 @@ func reconcileStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 	transforms := []mf.Transformer{
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
+		injectCommonMetadata(k),
+		injectScheduling(k),
+		kabTransforms.SetContainerResources(k.Spec.StackController.Resources),
 	}
 
 	m, err := mOrig.Transform(transforms...)
@@ -75,11 +95,14 @@ func reconcileStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 		return err
 	}
 
-	// Create a RoleBinding in the tekton-pipelines namespace that will allow
+	// Create a Role and RoleBinding in the trigger namespace that will allow
 	// the stack controller to create triggerbinding and triggertemplate
-	// objects in the tekton-pipelines namespace.
+	// objects in the trigger namespace.
+	triggerNamespace := cutils.GetTriggerNamespace(k)
 	templateCtx["name"] = "kabanero-" + k.GetNamespace() + "-stack-trigger-rolebinding"
+	templateCtx["roleName"] = "kabanero-" + k.GetNamespace() + "-stack-trigger-role"
 	templateCtx["kabaneroNamespace"] = k.GetNamespace()
+	templateCtx["triggerNamespace"] = triggerNamespace
 
 	f, err = rev.OpenOrchestration("stack-controller-tekton.yaml")
 	if err != nil {
@@ -96,7 +119,48 @@ func reconcileStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 		return err
 	}
 
-	err = mOrig.Apply()
+	m, err = mOrig.Transform(injectCommonMetadata(k))
+	if err != nil {
+		return err
+	}
+
+	err = m.Apply()
+	if err != nil {
+		return err
+	}
+
+	// The trigger namespace may have changed.  Remove the access granted in the
+	// previous one.
+	err = deleteStaleStackTriggerRoles(ctx, k, c, triggerNamespace, logger)
+	if err != nil {
+		return err
+	}
+
+	// Create a ClusterRole that lets the stack controller create missing
+	// asset namespaces.
+	templateCtx["clusterRoleName"] = "kabanero-" + k.GetNamespace() + "-stack-controller-cluster"
+
+	f, err = rev.OpenOrchestration(scClusterOrchestrationFileName)
+	if err != nil {
+		return err
+	}
+
+	s, err = renderOrchestration(f, templateCtx)
+	if err != nil {
+		return err
+	}
+
+	mOrig, err = mf.ManifestFrom(mf.Reader(strings.NewReader(s)), mf.UseClient(mfc.NewClient(c)), mf.UseLogger(logger.WithName("manifestival")))
+	if err != nil {
+		return err
+	}
+
+	m, err = mOrig.Transform(injectCommonMetadata(k))
+	if err != nil {
+		return err
+	}
+
+	err = m.Apply()
 	if err != nil {
 		return err
 	}
@@ -107,23 +171,51 @@ func reconcileStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 // Removes the cross-namespace objects created during the stack controller
 // deployment.
 func cleanupStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) error {
-	logger := sclog.WithValues("Kabanero instance namespace", k.Namespace, "Kabanero instance Name", k.Name)
-	logger.Info("Removing Kabanero stack controller installation.")
-
 	// First, we need to delete all of the stacks that we own.  We must do this first, to let the
 	// stack controller run its finalizer for all of the stacks, before deleting the
 	// stack controller pods etc.
+	waiting, err := deleteOwnedStacks(ctx, k, c)
+	if err != nil {
+		return err
+	}
+
+	// If there are still some stacks left, need to come back and try again later...
+	if len(waiting) != 0 {
+		return fmt.Errorf(waiting)
+	}
+
+	return cleanupStackControllerBindings(ctx, k, c)
+}
+
+// Deletes the stacks owned by the Kabanero instance, or by its StackHubs.  Returns what the deletion is waiting
+// on while some of the stacks still exist, since the stack controller runs their finalizers.
+func deleteOwnedStacks(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (string, error) {
+	logger := sclog.WithValues("Kabanero instance namespace", k.Namespace, "Kabanero instance Name", k.Name)
+
 	stackList := &kabanerov1alpha2.StackList{}
 	err := c.List(ctx, stackList, client.InNamespace(k.GetNamespace()))
 	if err != nil {
-		return fmt.Errorf("Unable to list stacks in finalizer: %v", err.Error())
+		return "", fmt.Errorf("Unable to list stacks in finalizer: %v", err.Error())
+	}
+
+	// The stacks of the Kabanero instance repositories are owned by its StackHubs.
+	owners := map[types.UID]bool{k.UID: true}
+	hubList := &kabanerov1alpha2.StackHubList{}
+	err = c.List(ctx, hubList, client.InNamespace(k.GetNamespace()))
+	if err != nil {
+		return "", fmt.Errorf("Unable to list stack hubs in finalizer: %v", err.Error())
+	}
+	for i := range hubList.Items {
+		if metav1.IsControlledBy(&hubList.Items[i], k) {
+			owners[hubList.Items[i].UID] = true
+		}
 	}
 
-	stackCount := 0
+	stackNames := []string{}
 	for _, stack := range stackList.Items {
 		for _, ownerRef := range stack.OwnerReferences {
-			if ownerRef.UID == k.UID {
-				stackCount = stackCount + 1
+			if owners[ownerRef.UID] {
+				stackNames = append(stackNames, stack.Name)
 				if stack.DeletionTimestamp.IsZero() {
 					err = c.Delete(ctx, &stack)
 					if err != nil {
@@ -131,18 +223,25 @@ func cleanupStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero, c
 						logger.Error(err, "Unable to delete stack %v", stack.Name)
 					}
 				}
+				break
 			}
 		}
 	}
 
-	// If there are still some stacks left, need to come back and try again later...
-	if stackCount > 0 {
-		return fmt.Errorf("Deletion blocked waiting for %v owned Stacks to be deleted", stackCount)
+	if len(stackNames) > 0 {
+		return fmt.Sprintf("Deletion blocked waiting for %v owned Stacks to be deleted: %v", len(stackNames), strings.Join(stackNames, ", ")), nil
 	}
 
-	// Now that the stacks have all been deleted, proceed with the cross-namespace objects.
-	// Objects in this namespace will be deleted implicitly when the Kabanero CR instance is
-	// deleted, because of the OwnerReference in those objects.
+	return "", nil
+}
+
+// Removes the cross-namespace objects that the stack controller uses.  Objects in this
+// namespace will be deleted implicitly when the Kabanero CR instance is deleted, because
+// of the OwnerReference in those objects.
+func cleanupStackControllerBindings(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) error {
+	logger := sclog.WithValues("Kabanero instance namespace", k.Namespace, "Kabanero instance Name", k.Name)
+	logger.Info("Removing Kabanero stack controller installation.")
+
 	rev, err := resolveSoftwareRevision(k, scVersionSoftCompName, k.Spec.StackController.Version)
 	if err != nil {
 		logger.Error(err, "Unable to resolve software revision.")
@@ -150,8 +249,11 @@ func cleanupStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero, c
 	}
 
 	templateCtx := rev.Identifiers
+	templateCtx["instance"] = k.ObjectMeta.UID
 	templateCtx["name"] = "kabanero-" + k.GetNamespace() + "-stack-trigger-rolebinding"
+	templateCtx["roleName"] = "kabanero-" + k.GetNamespace() + "-stack-trigger-role"
 	templateCtx["kabaneroNamespace"] = k.GetNamespace()
+	templateCtx["triggerNamespace"] = cutils.GetTriggerNamespace(k)
 
 	f, err := rev.OpenOrchestration("stack-controller-tekton.yaml")
 	if err != nil {
@@ -173,11 +275,128 @@ func cleanupStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero, c
 		return err
 	}
 
+	templateCtx["clusterRoleName"] = "kabanero-" + k.GetNamespace() + "-stack-controller-cluster"
+
+	f, err = rev.OpenOrchestration(scClusterOrchestrationFileName)
+	if err != nil {
+		return err
+	}
+
+	s, err = renderOrchestration(f, templateCtx)
+	if err != nil {
+		return err
+	}
+
+	m, err = mf.ManifestFrom(mf.Reader(strings.NewReader(s)), mf.UseClient(mfc.NewClient(c)), mf.UseLogger(logger.WithName("manifestival")))
+	if err != nil {
+		return err
+	}
+
+	err = m.Delete()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Removes the stack controller of a Kabanero instance that disabled it.  The stacks
+// owned by the instance are deleted first, while the stack controller can still run
+// their finalizers, then the cross-namespace objects, and finally the stack controller
+// deployment itself.
+func disableStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) error {
+	err := cleanupStackController(ctx, k, c)
+	if err != nil {
+		return err
+	}
+
+	rev, err := resolveSoftwareRevision(k, scVersionSoftCompName, k.Spec.StackController.Version)
+	if err != nil {
+		return err
+	}
+
+	templateCtx := rev.Identifiers
+	image, err := imageUriWithOverrides(k.Spec.StackController.Repository, k.Spec.StackController.Tag, k.Spec.StackController.Image, rev)
+	if err != nil {
+		return err
+	}
+	templateCtx["image"] = image
+	templateCtx["instance"] = k.ObjectMeta.UID
+	templateCtx["version"] = rev.Version
+
+	f, err := rev.OpenOrchestration(scOrchestrationFileName)
+	if err != nil {
+		return err
+	}
+
+	s, err := renderOrchestration(f, templateCtx)
+	if err != nil {
+		return err
+	}
+
+	m, err := mf.ManifestFrom(mf.Reader(strings.NewReader(s)), mf.UseClient(mfc.NewClient(c)), mf.UseLogger(sclog.WithName("manifestival")))
+	if err != nil {
+		return err
+	}
+
+	m, err = m.Transform(mf.InjectNamespace(k.GetNamespace()))
+	if err != nil {
+		return err
+	}
+
+	return m.Delete()
+}
+
+// Deletes the trigger Roles and RoleBindings created for the input Kabanero instance
+// in namespaces other than the current trigger namespace.
+func deleteStaleStackTriggerRoles(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, triggerNamespace string, logger logr.Logger) error {
+	ownerLabel := client.MatchingLabels{stackTriggerOwnerLabel: string(k.GetUID())}
+
+	bindingList := &rbacv1.RoleBindingList{}
+	err := c.List(ctx, bindingList, ownerLabel)
+	if err != nil {
+		return fmt.Errorf("Unable to list the stack controller trigger RoleBindings: %v", err.Error())
+	}
+	for i := range bindingList.Items {
+		binding := &bindingList.Items[i]
+		if binding.GetNamespace() == triggerNamespace {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Deleting RoleBinding %v in previous trigger namespace %v", binding.GetName(), binding.GetNamespace()))
+		err = c.Delete(ctx, binding)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	roleList := &rbacv1.RoleList{}
+	err = c.List(ctx, roleList, ownerLabel)
+	if err != nil {
+		return fmt.Errorf("Unable to list the stack controller trigger Roles: %v", err.Error())
+	}
+	for i := range roleList.Items {
+		role := &roleList.Items[i]
+		if role.GetNamespace() == triggerNamespace {
+			continue
+		}
+		logger.Info(fmt.Sprintf("Deleting Role %v in previous trigger namespace %v", role.GetName(), role.GetNamespace()))
+		err = c.Delete(ctx, role)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Returns the readiness status of the Kabanero stack controller installation.
 func getStackControllerStatus(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (bool, error) {
+	// If disabled, there is no deployment to report on.
+	if !k.Spec.StackController.IsEnabled() {
+		k.Status.StackController = kabanerov1alpha2.StackControllerStatus{}
+		return true, nil
+	}
+
 	k.Status.StackController.Message = ""
 	k.Status.StackController.Ready = "False"
 
@@ -0,0 +1,81 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"sort"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// The states that stacks are counted under in the Kabanero status.
+const (
+	stackSummaryActive   = "active"
+	stackSummaryInactive = "inactive"
+	stackSummaryFailed   = "failed"
+)
+
+// Returns the state a stack is counted under.  A stack with a failed version, or a failed
+// or drifted asset, is failed.  Otherwise it is active if any of its versions are active.
+func getStackSummaryState(s *kabanerov1alpha2.Stack) string {
+	state := stackSummaryInactive
+	for _, version := range s.Status.Versions {
+		if version.Status == kabanerov1alpha2.StackStateError {
+			return stackSummaryFailed
+		}
+		for _, pipeline := range version.Pipelines {
+			for _, asset := range pipeline.ActiveAssets {
+				if cutils.IsFailedAssetStatus(asset.Status) {
+					return stackSummaryFailed
+				}
+			}
+		}
+		if version.Status == kabanerov1alpha2.StackDesiredStateActive {
+			state = stackSummaryActive
+		}
+	}
+	return state
+}
+
+// Counts the stacks in the Kabanero instance's namespace by state, so that the health of
+// all stacks can be seen from the Kabanero instance.
+func getStackSummaryStatus(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) error {
+	stacks := &kabanerov1alpha2.StackList{}
+	err := c.List(ctx, stacks, client.InNamespace(k.GetNamespace()))
+	if err != nil {
+		return err
+	}
+
+	summary := kabanerov1alpha2.StackSummaryStatus{}
+	for i := range stacks.Items {
+		switch getStackSummaryState(&stacks.Items[i]) {
+		case stackSummaryActive:
+			summary.Active++
+		case stackSummaryFailed:
+			summary.Failed++
+			summary.FailingStacks = append(summary.FailingStacks, stacks.Items[i].GetName())
+		default:
+			summary.Inactive++
+		}
+	}
+	sort.Strings(summary.FailingStacks)
+
+	k.Status.StackSummary = summary
+	return nil
+}
+
+// Returns true if a stack update changes the state it is counted under.  Stack status
+// updates are otherwise ignored by the watch.
+func stackSummaryStateChanged(e event.UpdateEvent) bool {
+	oldStack, ok := e.ObjectOld.(*kabanerov1alpha2.Stack)
+	if !ok {
+		return false
+	}
+	newStack, ok := e.ObjectNew.(*kabanerov1alpha2.Stack)
+	if !ok {
+		return false
+	}
+	return getStackSummaryState(oldStack) != getStackSummaryState(newStack)
+}
@@ -0,0 +1,64 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	utesting "github.com/kabanero-io/kabanero-operator/pkg/controller/utils/testing"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func summaryTestStack(name string, versions ...kabanerov1alpha2.StackVersionStatus) *kabanerov1alpha2.Stack {
+	return &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kabanero"},
+		Status:     kabanerov1alpha2.StackStatus{Versions: versions},
+	}
+}
+
+// Test that stacks are counted by state, and the failing stacks are listed.
+func TestGetStackSummaryStatus(t *testing.T) {
+	failedAsset := []kabanerov1alpha2.PipelineStatus{{ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{Name: "build-task", Status: cutils.AssetStatusFailed}}}}
+	driftedAsset := []kabanerov1alpha2.PipelineStatus{{ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{Name: "build-task", Status: cutils.AssetStatusDrifted}}}}
+	// Stacks in other namespaces are not counted.
+	otherNamespace := summaryTestStack("nodejs", kabanerov1alpha2.StackVersionStatus{Version: "0.3.1", Status: kabanerov1alpha2.StackDesiredStateActive})
+	otherNamespace.Namespace = "other"
+
+	c := utesting.NewClient(
+		summaryTestStack("java-microprofile", kabanerov1alpha2.StackVersionStatus{Version: "0.2.1", Status: kabanerov1alpha2.StackDesiredStateActive}),
+		summaryTestStack("nodejs", kabanerov1alpha2.StackVersionStatus{Version: "0.3.1", Status: kabanerov1alpha2.StackDesiredStateInactive}),
+		summaryTestStack("java-spring-boot2", kabanerov1alpha2.StackVersionStatus{Version: "0.3.2", Status: kabanerov1alpha2.StackStateError}),
+		summaryTestStack("nodejs-express", kabanerov1alpha2.StackVersionStatus{Version: "0.4.0", Status: kabanerov1alpha2.StackDesiredStateActive, Pipelines: failedAsset}),
+		summaryTestStack("python-flask", kabanerov1alpha2.StackVersionStatus{Version: "0.2.0", Status: kabanerov1alpha2.StackDesiredStateActive, Pipelines: driftedAsset}),
+		otherNamespace,
+	)
+
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+	err := getStackSummaryStatus(context.TODO(), k, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := k.Status.StackSummary
+	if summary.Active != 1 || summary.Inactive != 1 || summary.Failed != 3 {
+		t.Fatalf("Unexpected stack counts: %+v", summary)
+	}
+	if len(summary.FailingStacks) != 3 || summary.FailingStacks[0] != "java-spring-boot2" || summary.FailingStacks[1] != "nodejs-express" || summary.FailingStacks[2] != "python-flask" {
+		t.Fatalf("Unexpected failing stacks: %v", summary.FailingStacks)
+	}
+}
+
+// Test that stack status updates are only processed when the summary state changes.
+func TestStackSummaryStateChanged(t *testing.T) {
+	active := summaryTestStack("nodejs", kabanerov1alpha2.StackVersionStatus{Version: "0.3.1", Status: kabanerov1alpha2.StackDesiredStateActive})
+	failed := summaryTestStack("nodejs", kabanerov1alpha2.StackVersionStatus{Version: "0.3.1", Status: kabanerov1alpha2.StackStateError})
+
+	if stackSummaryStateChanged(event.UpdateEvent{ObjectOld: active, ObjectNew: active.DeepCopy()}) {
+		t.Error("Expected an unchanged state to be ignored")
+	}
+	if !stackSummaryStateChanged(event.UpdateEvent{ObjectOld: active, ObjectNew: failed}) {
+		t.Error("Expected a changed state to be processed")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 
 	"github.com/go-logr/logr"
 
@@ -24,13 +25,22 @@ type targetNamespaceRoleBindingTemplate struct {
 	saName          string
 	saNamespace     string
 	clusterRoleName string
+
+	// The labels identifying the Kabanero instance, set on bindings in other namespaces.
+	instanceLabels map[string]string
 }
 
 func (info targetNamespaceRoleBindingTemplate) generate(targetNamespace string) rbacv1.RoleBinding {
+	var labels map[string]string
+	if targetNamespace != info.saNamespace {
+		labels = info.instanceLabels
+	}
+
 	return rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      info.name,
 			Namespace: targetNamespace,
+			Labels:    labels,
 		},
 		Subjects: []rbacv1.Subject{
 			rbacv1.Subject{
@@ -61,19 +71,23 @@ func getTargetNamespaces(targetNamespaces []string, defaultNamespace string) []s
 }
 
 // Create the binding templates
-func createBindingTemplates(saNamespace string) []targetNamespaceRoleBindingTemplate {
+func createBindingTemplates(k *kabanerov1alpha2.Kabanero) []targetNamespaceRoleBindingTemplate {
+	saNamespace := k.GetNamespace()
+	instanceLabels := cutils.InstanceLabels(k)
 	return []targetNamespaceRoleBindingTemplate{
 		{
 			name:            "kabanero-pipeline-deploy-rolebinding",
 			saName:          "kabanero-pipeline",
 			saNamespace:     saNamespace,
 			clusterRoleName: "kabanero-pipeline-deploy-role",
+			instanceLabels:  instanceLabels,
 		},
 		{
 			name:            "kabanero-cli-deploy-rolebinding",
 			saName:          "kabanero-cli",
 			saNamespace:     saNamespace,
 			clusterRoleName: "kabanero-cli-service-deployments-role",
+			instanceLabels:  instanceLabels,
 		},
 	}
 }
@@ -121,7 +135,7 @@ func reconcileTargetNamespaces(ctx context.Context, k *kabanerov1alpha2.Kabanero
 	unchangedNamespaces := specTargetNamespaces.Intersection(statusTargetNamespaces)
 
 	// Create the templates
-	bindingTemplates := createBindingTemplates(k.GetNamespace())
+	bindingTemplates := createBindingTemplates(k)
 
 	// For removed namespaces, delete the role bindings
 	for namespace, _ := range oldNamespaces {
@@ -215,7 +229,7 @@ func getTargetNamespacesStatus(k *kabanerov1alpha2.Kabanero) (bool, error) {
 // references are not allowed by Kubernetes).
 func cleanupTargetNamespaces(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client) error {
 	// Create the templates
-	bindingTemplates := createBindingTemplates(k.GetNamespace())
+	bindingTemplates := createBindingTemplates(k)
 
 	for _, namespace := range getTargetNamespaces(k.Status.TargetNamespaces.Namespaces, k.GetNamespace()) {
 		for _, bindingTemplate := range bindingTemplates {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -345,3 +346,35 @@ func TestCleanupTargetNamespaces(t *testing.T) {
 		t.Fatal(fmt.Sprintf("There were %v bindings left in the map after cleanup: %#v", len(existingRoleBindings), existingRoleBindings))
 	}
 }
+
+// Bindings outside the namespace of the Kabanero instance are labeled with the instance.
+func TestBindingTemplateInstanceLabels(t *testing.T) {
+	k := kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+	template := createBindingTemplates(&k)[0]
+
+	binding := template.generate("kabanero")
+	if len(binding.GetLabels()) != 0 {
+		t.Fatal(fmt.Sprintf("The binding in the instance namespace should not be labeled: %v", binding.GetLabels()))
+	}
+
+	binding = template.generate("fred")
+	if binding.GetLabels()[cutils.AssetNamespaceInstanceLabel] != "kabanero" || binding.GetLabels()[cutils.AssetNamespaceInstanceNamespaceLabel] != "kabanero" {
+		t.Fatal(fmt.Sprintf("The binding in namespace fred should be labeled with the instance: %v", binding.GetLabels()))
+	}
+}
+
+// The operator watches a comma separated list of namespaces, or all namespaces.
+func TestWatchNamespaces(t *testing.T) {
+	watchNamespaces := getWatchNamespaces("kabanero, team-a,")
+	if len(watchNamespaces) != 2 || watchNamespaces[0] != "kabanero" || watchNamespaces[1] != "team-a" {
+		t.Fatal(fmt.Sprintf("Unexpected watch namespaces: %#v", watchNamespaces))
+	}
+	if !isWatchedNamespace(watchNamespaces, "team-a") || isWatchedNamespace(watchNamespaces, "team-b") {
+		t.Fatal(fmt.Sprintf("Unexpected watched namespaces for %#v", watchNamespaces))
+	}
+
+	watchNamespaces = getWatchNamespaces("")
+	if len(watchNamespaces) != 1 || !isWatchedNamespace(watchNamespaces, "team-b") {
+		t.Fatal(fmt.Sprintf("All namespaces should be watched: %#v", watchNamespaces))
+	}
+}
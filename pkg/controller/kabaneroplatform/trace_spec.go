@@ -0,0 +1,40 @@
+package kabaneroplatform
+
+import (
+	"context"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Sets the log level of the operator components from the trace specification of the
+// Kabanero instance, followed by the entries of the kabanero-operator-trace ConfigMap in
+// its namespace.  The entries of the ConfigMap take precedence.  When the ConfigMap is
+// removed, the components go back to the levels of the instance, or to the default level.
+func reconcileTraceSpec(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Reader) error {
+	spec := k.Spec.Logging.TraceSpec
+
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, client.ObjectKey{Name: cutils.TraceConfigMapName, Namespace: k.GetNamespace()}, cm)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	} else {
+		traceSpec, err := cutils.TraceSpecFromConfigMap(cm.Data)
+		if err != nil {
+			return err
+		}
+		spec = spec + ":" + traceSpec
+	}
+
+	levels, err := cutils.ParseTraceSpec(spec)
+	if err != nil {
+		return err
+	}
+	cutils.SetTraceComponents(levels)
+	return nil
+}
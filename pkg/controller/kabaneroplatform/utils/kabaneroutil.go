@@ -2,11 +2,13 @@ package utils
 
 import (
 	"fmt"
+	"strings"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 )
 
-// Validates that the stack policy configured in the kabanero CR instance yaml is one of the allowed values.
+// Validates that the stack policy configured in the kabanero CR instance yaml is one of the allowed values,
+// and that the rules of the governance policy are well formed.
 func ValidateGovernanceStackPolicy(kab *kabanerov1alpha2.Kabanero) (bool, string, error) {
 	if len(kab.Spec.GovernancePolicy.StackPolicy) != 0 &&
 		!(kab.Spec.GovernancePolicy.StackPolicy == kabanerov1alpha2.StackPolicyActiveDigest ||
@@ -20,5 +22,56 @@ func ValidateGovernanceStackPolicy(kab *kabanerov1alpha2.Kabanero) (bool, string
 		return false, reason, nil
 	}
 
+	for _, registry := range kab.Spec.GovernancePolicy.AllowedRegistries {
+		if len(registry) == 0 || strings.ContainsAny(registry, "/@ ") {
+			reason := fmt.Sprintf("The value %v associated with kabanero CR entry spec.governancePolicy.allowedRegistries is not valid. It must be a registry host name, optionally with a port, as in quay.io or registry.example.com:5000.", registry)
+			return false, reason, nil
+		}
+	}
+
+	for _, id := range kab.Spec.GovernancePolicy.BannedStackIds {
+		if !kabanerov1alpha2.IsValidStackId(id) {
+			reason := fmt.Sprintf("The value %v associated with kabanero CR entry spec.governancePolicy.bannedStackIds is not a valid stack id.", id)
+			return false, reason, nil
+		}
+	}
+
+	if kab.Spec.GovernancePolicy.MaxActiveVersions < 0 {
+		reason := fmt.Sprintf("The value %v associated with kabanero CR entry spec.governancePolicy.maxActiveVersions is not valid. It must not be negative.", kab.Spec.GovernancePolicy.MaxActiveVersions)
+		return false, reason, nil
+	}
+
+	if !kabanerov1alpha2.IsValidMaxActiveVersionsPolicy(kab.Spec.GovernancePolicy.MaxActiveVersionsPolicy) {
+		reason := fmt.Sprintf("The value %v associated with kabanero CR entry spec.governancePolicy.maxActiveVersionsPolicy is not valid. The following are allowed values: %v, %v",
+			kab.Spec.GovernancePolicy.MaxActiveVersionsPolicy, kabanerov1alpha2.MaxActiveVersionsPolicyRefuse, kabanerov1alpha2.MaxActiveVersionsPolicyDeactivateOldest)
+		return false, reason, nil
+	}
+
 	return true, "", nil
 }
+
+// Validates that the stack conflict policy configured in the kabanero CR instance yaml is one of the allowed values.
+// A prefer-repository policy must name one of the configured repositories.
+func ValidateStackConflictPolicy(kab *kabanerov1alpha2.Kabanero) (bool, string, error) {
+	policy := kab.Spec.Stacks.ConflictPolicy
+	if len(policy) == 0 || policy == kabanerov1alpha2.StackConflictPolicyFirstWins || policy == kabanerov1alpha2.StackConflictPolicyError {
+		return true, "", nil
+	}
+
+	if strings.HasPrefix(policy, kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix) {
+		repositoryName := strings.TrimPrefix(policy, kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix)
+		for _, repository := range kab.Spec.Stacks.Repositories {
+			if repository.Name == repositoryName {
+				return true, "", nil
+			}
+		}
+
+		reason := fmt.Sprintf("The value %v associated with kabanero CR entry spec.stacks.conflictPolicy is not valid. Repository %v is not listed in spec.stacks.repositories.", policy, repositoryName)
+		return false, reason, nil
+	}
+
+	reason := fmt.Sprintf("The value %v associated with kabanero CR entry spec.stacks.conflictPolicy is not valid. The following are allowed values: %v, %v, %v<repository name>",
+		policy, kabanerov1alpha2.StackConflictPolicyFirstWins, kabanerov1alpha2.StackConflictPolicyError,
+		kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix)
+	return false, reason, nil
+}
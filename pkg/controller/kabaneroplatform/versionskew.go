@@ -0,0 +1,183 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The largest difference in minor versions that is tolerated between the version of
+// a component that the operator expects, and the version that is running.
+const maxMinorVersionSkew = 1
+
+// The label that holds the version of the components deployed by the operator.
+const versionLabel = "app.kubernetes.io/version"
+
+// The name of the admission webhook configurations.
+const webhookConfigurationName = "webhook.operator.kabanero.io"
+
+// A component whose running version is checked against the version the operator expects.
+type skewCheckedComponent struct {
+	name              string
+	softwareComponent string
+	podLabels         client.MatchingLabels
+	versionOverride   func(k *kabanerov1alpha2.Kabanero) string
+}
+
+var skewCheckedComponents = []skewCheckedComponent{
+	{
+		name:              "stack controller",
+		softwareComponent: scVersionSoftCompName,
+		podLabels:         client.MatchingLabels{"app": scDeploymentResourceName},
+		versionOverride:   func(k *kabanerov1alpha2.Kabanero) string { return k.Spec.StackController.Version },
+	},
+	{
+		name:              "admission webhook",
+		softwareComponent: "admission-webhook",
+		podLabels:         client.MatchingLabels{"name": "kabanero-operator-admission-webhook"},
+		versionOverride:   func(k *kabanerov1alpha2.Kabanero) string { return k.Spec.AdmissionControllerWebhook.Version },
+	},
+}
+
+// Compares the versions of the running stack controller, admission webhook and webhook
+// configurations with the versions the operator expects, and reports the result in the
+// Kabanero instance status.  An error is returned if the versions are too far apart for
+// stacks to be safely activated, for example during a partial upgrade.
+func checkVersionSkew(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	k.Status.VersionSkew.OperatorVersion = versioning.Data.DefaultKabaneroRevision
+	k.Status.VersionSkew.Skewed = "False"
+	k.Status.VersionSkew.Message = ""
+
+	skewed := []string{}
+	tooFar := []string{}
+	for _, component := range skewCheckedComponents {
+		rev, err := resolveSoftwareRevision(k, component.softwareComponent, component.versionOverride(k))
+		if err != nil {
+			return err
+		}
+
+		runningVersions, err := getRunningVersions(ctx, k, c, component)
+		if err != nil {
+			// The versions cannot be determined, so don't block anything.
+			reqLogger.Error(err, fmt.Sprintf("Unable to determine the running versions of the %v", component.name))
+			k.Status.VersionSkew.Skewed = "Unknown"
+			k.Status.VersionSkew.Message = fmt.Sprintf("Unable to determine the running versions of the %v: %v", component.name, err)
+			return nil
+		}
+
+		for _, runningVersion := range runningVersions {
+			if runningVersion == rev.Version {
+				continue
+			}
+
+			description := fmt.Sprintf("%v %v (expected %v)", component.name, runningVersion, rev.Version)
+			skewed = append(skewed, description)
+
+			isTooFar, err := versionsTooFarApart(rev.Version, runningVersion)
+			if err != nil {
+				reqLogger.Info(fmt.Sprintf("Unable to compare the %v version %v with the expected version %v: %v", component.name, runningVersion, rev.Version, err))
+				continue
+			}
+			if isTooFar {
+				tooFar = append(tooFar, description)
+			}
+		}
+	}
+
+	if len(skewed) == 0 {
+		return nil
+	}
+
+	k.Status.VersionSkew.Skewed = "True"
+	k.Status.VersionSkew.Message = fmt.Sprintf("The following components are not at the expected version: %v", strings.Join(skewed, ", "))
+
+	if len(tooFar) != 0 {
+		return fmt.Errorf("Stack activation is suspended because the following components are more than %v minor version(s) away from the expected version: %v", maxMinorVersionSkew, strings.Join(tooFar, ", "))
+	}
+
+	return nil
+}
+
+// Returns the distinct versions of the input component that are running.  During a
+// rolling upgrade, more than one version may be running.
+func getRunningVersions(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, component skewCheckedComponent) ([]string, error) {
+	versions := make(map[string]bool)
+
+	pods := &corev1.PodList{}
+	err := c.List(ctx, pods, client.InNamespace(k.GetNamespace()), component.podLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		if version, ok := pod.GetLabels()[versionLabel]; ok {
+			versions[version] = true
+		}
+	}
+
+	// The webhook configurations are updated separately from the webhook deployment.
+	if component.softwareComponent == "admission-webhook" {
+		mutating := &admissionregistrationv1beta1.MutatingWebhookConfiguration{}
+		err = c.Get(ctx, client.ObjectKey{Name: webhookConfigurationName}, mutating)
+		if err == nil {
+			if version, ok := mutating.GetLabels()[versionLabel]; ok {
+				versions[version] = true
+			}
+		} else if !errors.IsNotFound(err) {
+			return nil, err
+		}
+
+		validating := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{}
+		err = c.Get(ctx, client.ObjectKey{Name: webhookConfigurationName}, validating)
+		if err == nil {
+			if version, ok := validating.GetLabels()[versionLabel]; ok {
+				versions[version] = true
+			}
+		} else if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	result := []string{}
+	for version := range versions {
+		result = append(result, version)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// Returns true if the major versions differ, or if the minor versions differ by more
+// than the allowed skew.
+func versionsTooFarApart(expected string, running string) (bool, error) {
+	expectedVersion, err := semver.ParseTolerant(expected)
+	if err != nil {
+		return false, err
+	}
+
+	runningVersion, err := semver.ParseTolerant(running)
+	if err != nil {
+		return false, err
+	}
+
+	if expectedVersion.Major != runningVersion.Major {
+		return true, nil
+	}
+
+	minorSkew := int64(expectedVersion.Minor) - int64(runningVersion.Minor)
+	if minorSkew < 0 {
+		minorSkew = -minorSkew
+	}
+
+	return minorSkew > maxMinorVersionSkew, nil
+}
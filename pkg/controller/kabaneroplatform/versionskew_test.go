@@ -0,0 +1,93 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestVersionsTooFarApart(t *testing.T) {
+	tests := []struct {
+		expected string
+		running  string
+		tooFar   bool
+	}{
+		{expected: "0.10.0", running: "0.10.0", tooFar: false},
+		{expected: "0.10.0", running: "0.9.1", tooFar: false},
+		{expected: "0.9.1", running: "0.10.0", tooFar: false},
+		{expected: "0.10.0", running: "0.8.0", tooFar: true},
+		{expected: "1.0.0", running: "0.10.0", tooFar: true},
+	}
+
+	for _, test := range tests {
+		tooFar, err := versionsTooFarApart(test.expected, test.running)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tooFar != test.tooFar {
+			t.Errorf("Expected %v for expected version %v and running version %v, but was %v", test.tooFar, test.expected, test.running, tooFar)
+		}
+	}
+
+	_, err := versionsTooFarApart("0.10.0", "latest")
+	if err == nil {
+		t.Fatal("Expected an error for a version that is not semver")
+	}
+}
+
+// Unit test client that returns pods with a fixed version label.
+type versionSkewTestClient struct {
+	client.Client
+	version string
+}
+
+func (c versionSkewTestClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	pods, ok := list.(*corev1.PodList)
+	if !ok {
+		return apierrors.NewBadRequest("List only supports PodList")
+	}
+	pods.Items = []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "pod", Labels: map[string]string{versionLabel: c.version}}}}
+	return nil
+}
+
+func (c versionSkewTestClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+// Test that the skew is reported, and that an error is returned when the versions are too far apart.
+func TestCheckVersionSkew(t *testing.T) {
+	logger := logf.Log.WithName("versionskew_test")
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{Version: "0.9.1"},
+	}
+
+	err := checkVersionSkew(context.Background(), k, versionSkewTestClient{version: "0.9.1"}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Status.VersionSkew.Skewed != "False" {
+		t.Fatalf("Expected no skew, but status was: %v", k.Status.VersionSkew)
+	}
+
+	err = checkVersionSkew(context.Background(), k, versionSkewTestClient{version: "0.8.0"}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Status.VersionSkew.Skewed != "True" {
+		t.Fatalf("Expected skew, but status was: %v", k.Status.VersionSkew)
+	}
+
+	err = checkVersionSkew(context.Background(), k, versionSkewTestClient{version: "0.7.0"}, logger)
+	if err == nil {
+		t.Fatal("Expected an error because the versions are too far apart")
+	}
+}
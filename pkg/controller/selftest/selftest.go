@@ -0,0 +1,261 @@
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The self-test validates a Kabanero instance and a set of Stack instances read from files,
+// without reading from or writing to a cluster.  Stack indexes are resolved, and each pipeline
+// archive is downloaded, checked against its digest, and rendered.  It is meant to be run by
+// CI systems before changes to a stack catalog are merged.
+
+// Same rule that the stack controller applies to stack ids.
+var stackIdRegex = regexp.MustCompile("^[a-z]([a-z0-9-]*[a-z0-9])?$")
+
+// Files to validate.
+type Options struct {
+	// A file containing a Kabanero instance.  Optional.
+	KabaneroFile string
+
+	// Files containing Stack instances.  A file may contain several YAML documents.
+	StackFiles []string
+}
+
+// The outcome of a single check.
+type Result struct {
+	Subject string
+	Err     error
+}
+
+// Runs the self-test, and writes a line for each check to out.  Returns true if
+// every check passed.
+func Run(opts Options, out io.Writer, logger logr.Logger) bool {
+	results := Validate(opts, logger)
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL %v: %v\n", result.Subject, result.Err)
+		} else {
+			fmt.Fprintf(out, "PASS %v\n", result.Subject)
+		}
+	}
+
+	fmt.Fprintf(out, "%v checks, %v failed\n", len(results), failed)
+	return failed == 0
+}
+
+// Validates the input files, and returns the result of each check.
+func Validate(opts Options, logger logr.Logger) []Result {
+	results := []Result{}
+	c := offlineClient{}
+
+	if len(opts.KabaneroFile) != 0 {
+		k := &kabanerov1alpha2.Kabanero{}
+		err := readObject(opts.KabaneroFile, k)
+		results = append(results, Result{Subject: fmt.Sprintf("read Kabanero instance %v", opts.KabaneroFile), Err: err})
+		if err == nil {
+			results = append(results, validateKabanero(c, k, logger)...)
+		}
+	}
+
+	for _, stackFile := range opts.StackFiles {
+		stacks, err := readStacks(stackFile)
+		results = append(results, Result{Subject: fmt.Sprintf("read Stack instances %v", stackFile), Err: err})
+		for i := range stacks {
+			results = append(results, validateStack(c, &stacks[i], nil, logger)...)
+		}
+	}
+
+	return results
+}
+
+// Resolves each repository of the Kabanero instance, and validates the stacks listed by its index.
+func validateKabanero(c client.Client, k *kabanerov1alpha2.Kabanero, logger logr.Logger) []Result {
+	results := []Result{}
+	for _, r := range k.Spec.Stacks.Repositories {
+		pipelines := r.Pipelines
+		if len(pipelines) == 0 {
+			pipelines = k.Spec.Stacks.Pipelines
+		}
+
+		indexPipelines := []stack.Pipelines{}
+		for _, pipeline := range pipelines {
+			indexPipelines = append(indexPipelines, stack.Pipelines{Id: pipeline.Id, Sha256: pipeline.Sha256, Url: pipeline.Https.Url, GitRelease: pipeline.GitRelease, SkipCertVerification: pipeline.Https.SkipCertVerification})
+		}
+
+		index, err := stack.ResolveIndex(context.Background(), c, r, k.GetNamespace(), indexPipelines, []stack.Trigger{}, "", nil, logger)
+		results = append(results, Result{Subject: fmt.Sprintf("resolve repository %v (%v)", r.Name, stack.GetRepositoryUrl(r)), Err: err})
+		if err != nil {
+			continue
+		}
+
+		for _, s := range index.Stacks {
+			stackResource := &kabanerov1alpha2.Stack{
+				Spec: kabanerov1alpha2.StackSpec{
+					Name:     s.Id,
+					Versions: []kabanerov1alpha2.StackVersion{s.GetStackVersion(stack.GetRepositoryUrl(r), k.Spec.Stacks.SkipRegistryCertVerification)},
+				},
+			}
+			stackResource.SetNamespace(k.GetNamespace())
+			results = append(results, validateStack(c, stackResource, k.Spec.RegistryMirrors, logger)...)
+		}
+	}
+
+	return results
+}
+
+// Downloads, checks and renders each pipeline of each version of the stack.  The image
+// of each version is rewritten for the input registry mirrors, as it is when activated.
+func validateStack(c client.Client, s *kabanerov1alpha2.Stack, registryMirrors map[string]string, logger logr.Logger) []Result {
+	cID := s.Spec.Name
+	var err error
+	if len(cID) > 68 || !stackIdRegex.MatchString(cID) {
+		err = fmt.Errorf("The stack id %v is not valid. It must be 68 characters or less, and follow stack creation name rules.", cID)
+	}
+	results := []Result{{Subject: fmt.Sprintf("stack %v id", cID), Err: err}}
+
+	for _, version := range s.Spec.Versions {
+		renderingContext := map[string]interface{}{"CollectionId": cID, "StackId": cID}
+		if len(version.Images) != 0 {
+			stackImage, err := sutils.ApplyRegistryMirrors(version.Images[0].Image, registryMirrors)
+			if err != nil {
+				results = append(results, Result{Subject: fmt.Sprintf("stack %v %v image", cID, version.Version), Err: err})
+				continue
+			}
+			renderingContext["StackImage"] = stackImage
+		}
+
+		for _, pipeline := range version.Pipelines {
+			subject := fmt.Sprintf("stack %v %v pipeline %v", cID, version.Version, pipeline.Id)
+			results = append(results, Result{Subject: subject, Err: validatePipeline(c, s.GetNamespace(), pipeline, renderingContext, logger)})
+		}
+	}
+
+	return results
+}
+
+// Downloads a pipeline archive, checks its digest, and renders its manifests.
+func validatePipeline(c client.Client, namespace string, pipeline kabanerov1alpha2.PipelineSpec, renderingContext map[string]interface{}, logger logr.Logger) error {
+	pipelineStatus := kabanerov1alpha2.PipelineStatus{
+		Name:      pipeline.Id,
+		Url:       pipeline.ArchiveUrl(),
+		Digest:    pipeline.Sha256,
+		Signature: pipeline.Signature,
+	}
+	if pipeline.GitRelease.IsUsable() {
+		pipelineStatus.GitRelease = kabanerov1alpha2.GitReleaseInfo{Hostname: pipeline.GitRelease.Hostname, Organization: pipeline.GitRelease.Organization, Project: pipeline.GitRelease.Project, Release: pipeline.GitRelease.Release, AssetName: pipeline.GitRelease.AssetName}
+	}
+	skipCertVerification := pipeline.Https.SkipCertVerification || pipeline.GitRelease.SkipCertVerification || pipeline.Oci.SkipCertVerification
+
+	// The digest of a Tekton bundle is checked against its image manifest when it is pulled.
+	if len(pipeline.Oci.Bundle) == 0 {
+		b, err := cutils.DownloadToByte(context.Background(), c, namespace, pipelineStatus.Url, pipelineStatus.GitRelease, skipCertVerification, nil, logger)
+		if err != nil {
+			return err
+		}
+
+		matched, sum, err := cutils.MatchDigest(pipelineStatus.Digest, b)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			return fmt.Errorf("The digest %v does not match the download checksum %v", pipelineStatus.Digest, sum)
+		}
+	}
+
+	renderingContext["Digest"] = cutils.RenderingDigest(pipelineStatus.Digest)
+
+	manifests, err := cutils.GetManifests(context.Background(), c, namespace, pipelineStatus, renderingContext, skipCertVerification, nil, logger)
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return fmt.Errorf("The pipeline archive does not contain any manifests")
+	}
+
+	return nil
+}
+
+// Reads a single object from a YAML or JSON file.
+func readObject(fileName string, obj runtime.Object) error {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	return yaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 4096).Decode(obj)
+}
+
+// Reads the Stack instances from a file that may contain several YAML documents.
+func readStacks(fileName string) ([]kabanerov1alpha2.Stack, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := []kabanerov1alpha2.Stack{}
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 4096)
+	for {
+		s := kabanerov1alpha2.Stack{}
+		err = decoder.Decode(&s)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stacks, err
+		}
+		if len(s.Spec.Name) != 0 {
+			stacks = append(stacks, s)
+		}
+	}
+
+	return stacks, nil
+}
+
+// A client that has no objects, and refuses to modify anything.  Code that looks up
+// optional objects (such as secrets) finds nothing, and carries on.
+type offlineClient struct{}
+
+var errOffline = errors.New("The self-test does not modify the cluster")
+
+func (c offlineClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+func (c offlineClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	return nil
+}
+func (c offlineClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	return errOffline
+}
+func (c offlineClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	return errOffline
+}
+func (c offlineClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	return errOffline
+}
+func (c offlineClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return errOffline
+}
+func (c offlineClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return errOffline
+}
+func (c offlineClient) Status() client.StatusWriter { return c }
@@ -0,0 +1,117 @@
+package selftest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var selftestLogger = logf.Log.WithName("selftest_test")
+
+// Serves the files in the utils testdata directory.
+func newArchiveServer(t *testing.T) (*httptest.Server, string) {
+	archive, err := ioutil.ReadFile("../utils/testdata/basic.pipeline.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(archive)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/basic.pipeline.tar.gz" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.Write(archive)
+	}))
+
+	return server, hex.EncodeToString(sum[:])
+}
+
+func writeStackFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "stack*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+const stackTemplate = `apiVersion: kabanero.io/v1alpha2
+kind: Stack
+metadata:
+  name: %v
+spec:
+  name: %v
+  versions:
+  - version: 0.2.0
+    pipelines:
+    - id: default
+      sha256: %v
+      https:
+        url: %v/basic.pipeline.tar.gz
+`
+
+// A stack whose pipeline archive matches its digest passes.
+func TestSelfTestValidStack(t *testing.T) {
+	server, digest := newArchiveServer(t)
+	defer server.Close()
+
+	stackFile := writeStackFile(t, fmt.Sprintf(stackTemplate, "java-microprofile", "java-microprofile", digest, server.URL))
+	defer os.Remove(stackFile)
+
+	out := &bytes.Buffer{}
+	if !Run(Options{StackFiles: []string{stackFile}}, out, selftestLogger) {
+		t.Fatalf("Expected the self-test to pass:\n%v", out.String())
+	}
+}
+
+// A digest mismatch and an invalid stack id are both reported, in the same file.
+func TestSelfTestInvalidStacks(t *testing.T) {
+	server, _ := newArchiveServer(t)
+	defer server.Close()
+
+	badDigest := strings.Repeat("0", 64)
+	content := fmt.Sprintf(stackTemplate, "nodejs", "nodejs", badDigest, server.URL) + "---\n" +
+		fmt.Sprintf(stackTemplate, "bad-id", "Bad_Id", badDigest, server.URL)
+	stackFile := writeStackFile(t, content)
+	defer os.Remove(stackFile)
+
+	results := Validate(Options{StackFiles: []string{stackFile}}, selftestLogger)
+	failures := map[string]bool{}
+	for _, result := range results {
+		if result.Err != nil {
+			failures[result.Subject] = true
+		}
+	}
+
+	for _, subject := range []string{"stack nodejs 0.2.0 pipeline default", "stack Bad_Id id", "stack Bad_Id 0.2.0 pipeline default"} {
+		if !failures[subject] {
+			t.Errorf("Expected check %v to fail. Results: %v", subject, results)
+		}
+	}
+	if len(failures) != 3 {
+		t.Errorf("Expected 3 failures, but found %v: %v", len(failures), results)
+	}
+}
+
+// A missing input file fails the self-test.
+func TestSelfTestMissingFile(t *testing.T) {
+	out := &bytes.Buffer{}
+	if Run(Options{KabaneroFile: "does-not-exist.yaml"}, out, selftestLogger) {
+		t.Fatalf("Expected the self-test to fail:\n%v", out.String())
+	}
+}
@@ -0,0 +1,107 @@
+package stack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// A stack index can mark a stack version as deprecated, and give the date its support ends.
+// The Deprecated condition reports the active versions that are deprecated, or that are
+// past their end of support date.
+
+// The condition reasons.
+const (
+	versionsDeprecatedReason = "VersionsDeprecated"
+	endOfSupportReason       = "EndOfSupport"
+	versionsSupportedReason  = "VersionsSupported"
+)
+
+// Returns the active versions that are deprecated, and the active versions that are past
+// their end of support date.
+func getDeprecatedVersions(status kabanerov1alpha2.StackStatus, now time.Time) ([]string, []string) {
+	deprecated := []string{}
+	unsupported := []string{}
+	for _, version := range status.Versions {
+		if version.Status != kabanerov1alpha2.StackDesiredStateActive {
+			continue
+		}
+		if len(version.EndOfSupport) != 0 {
+			endOfSupport, err := kabanerov1alpha2.ParseEndOfSupport(version.EndOfSupport)
+			if err == nil && !now.Before(endOfSupport) {
+				unsupported = append(unsupported, fmt.Sprintf("%v (%v)", version.Version, version.EndOfSupport))
+				continue
+			}
+		}
+		if version.Deprecated {
+			deprecated = append(deprecated, version.Version)
+		}
+	}
+	return deprecated, unsupported
+}
+
+// Sets the Deprecated condition to reflect the versions in the input status.
+func setDeprecatedCondition(status *kabanerov1alpha2.StackStatus, now time.Time) {
+	transition := metav1.NewTime(now)
+	condition := kabanerov1alpha2.StackCondition{
+		Type:               kabanerov1alpha2.StackConditionDeprecated,
+		Status:             string(corev1.ConditionFalse),
+		LastTransitionTime: &transition,
+		Reason:             versionsSupportedReason,
+	}
+
+	deprecated, unsupported := getDeprecatedVersions(*status, now)
+	messages := []string{}
+	if len(unsupported) != 0 {
+		condition.Status = string(corev1.ConditionTrue)
+		condition.Reason = endOfSupportReason
+		messages = append(messages, fmt.Sprintf("Active stack versions are past their end of support date: %v", strings.Join(unsupported, ", ")))
+	}
+	if len(deprecated) != 0 {
+		condition.Status = string(corev1.ConditionTrue)
+		if len(unsupported) == 0 {
+			condition.Reason = versionsDeprecatedReason
+		}
+		messages = append(messages, fmt.Sprintf("Active stack versions are deprecated: %v", strings.Join(deprecated, ", ")))
+	}
+	condition.Message = strings.Join(messages, ". ")
+
+	status.SetCondition(condition)
+}
+
+// Emits an event if an active version went past its end of support date since the previous
+// condition was set.
+func reportEndOfSupport(recorder record.EventRecorder, stackResource *kabanerov1alpha2.Stack, previous *kabanerov1alpha2.StackCondition) {
+	current := stackResource.Status.GetCondition(kabanerov1alpha2.StackConditionDeprecated)
+	if recorder == nil || current == nil || current.Reason != endOfSupportReason {
+		return
+	}
+
+	if previous != nil && previous.Reason == current.Reason && previous.Message == current.Message {
+		return
+	}
+
+	recorder.Event(stackResource, corev1.EventTypeWarning, endOfSupportReason, current.Message)
+}
+
+// Returns the time until the next active version reaches its end of support date, or zero
+// if none will.
+func timeUntilEndOfSupport(status kabanerov1alpha2.StackStatus, now time.Time) time.Duration {
+	var next time.Duration
+	for _, version := range status.Versions {
+		if version.Status != kabanerov1alpha2.StackDesiredStateActive || len(version.EndOfSupport) == 0 {
+			continue
+		}
+		endOfSupport, err := kabanerov1alpha2.ParseEndOfSupport(version.EndOfSupport)
+		if err != nil || !now.Before(endOfSupport) {
+			continue
+		}
+		next = shorterRequeue(next, endOfSupport.Sub(now))
+	}
+	return next
+}
@@ -0,0 +1,94 @@
+package stack
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Test that index entries carry their deprecation into the stack version.
+func TestGetStackVersionDeprecation(t *testing.T) {
+	entry := Stack{Id: "nodejs", Version: "0.2.5", Deprecated: true, EndOfSupport: "2021-06-30"}
+	version := entry.GetStackVersion("https://example.com/index.yaml", false)
+	if !version.Deprecated || version.EndOfSupport != "2021-06-30" {
+		t.Fatalf("Expected the deprecation to be copied, but found %+v", version)
+	}
+
+	entry.EndOfSupport = "next summer"
+	version = entry.GetStackVersion("https://example.com/index.yaml", false)
+	if len(version.EndOfSupport) != 0 {
+		t.Fatalf("Expected an end of support that cannot be parsed to be left out, but found %v", version.EndOfSupport)
+	}
+}
+
+// Test that the Deprecated condition reports active versions that are deprecated or no longer supported.
+func TestDeprecatedCondition(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	stackResource := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero"},
+		Status: kabanerov1alpha2.StackStatus{
+			Versions: []kabanerov1alpha2.StackVersionStatus{
+				{Version: "0.1.0", Status: kabanerov1alpha2.StackDesiredStateInactive, Deprecated: true, EndOfSupport: "2021-01-01"},
+				{Version: "0.2.0", Status: kabanerov1alpha2.StackDesiredStateActive, EndOfSupport: "2021-06-30"},
+				{Version: "0.3.0", Status: kabanerov1alpha2.StackDesiredStateActive},
+			},
+		},
+	}
+
+	setDeprecatedCondition(&stackResource.Status, now)
+	condition := stackResource.Status.GetCondition(kabanerov1alpha2.StackConditionDeprecated)
+	if condition == nil || condition.Status != "False" {
+		t.Fatalf("Expected the Deprecated condition to be False, but found %v", condition)
+	}
+	if requeue := timeUntilEndOfSupport(stackResource.Status, now); requeue != 29*24*time.Hour-12*time.Hour {
+		t.Errorf("Expected to requeue at the end of support of 0.2.0, but found %v", requeue)
+	}
+	previous := *condition
+
+	stackResource.Status.Versions[2].Deprecated = true
+	setDeprecatedCondition(&stackResource.Status, now)
+	condition = stackResource.Status.GetCondition(kabanerov1alpha2.StackConditionDeprecated)
+	if condition.Status != "True" || condition.Reason != versionsDeprecatedReason || !strings.Contains(condition.Message, "0.3.0") {
+		t.Fatalf("Expected the Deprecated condition to report 0.3.0, but found %v", condition)
+	}
+
+	// A deprecated version is not an event, but a version past its end of support is.
+	recorder := record.NewFakeRecorder(10)
+	reportEndOfSupport(recorder, stackResource, &previous)
+	if len(recorder.Events) != 0 {
+		t.Fatal("Expected no event for a deprecated version")
+	}
+	previous = *condition
+
+	later := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	setDeprecatedCondition(&stackResource.Status, later)
+	reportEndOfSupport(recorder, stackResource, &previous)
+	condition = stackResource.Status.GetCondition(kabanerov1alpha2.StackConditionDeprecated)
+	if condition.Reason != endOfSupportReason || !strings.Contains(condition.Message, "0.2.0 (2021-06-30)") {
+		t.Fatalf("Expected the Deprecated condition to report the end of support of 0.2.0, but found %v", condition)
+	}
+	if requeue := timeUntilEndOfSupport(stackResource.Status, later); requeue != 0 {
+		t.Errorf("Expected no requeue after the end of support, but found %v", requeue)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, endOfSupportReason) {
+			t.Fatalf("Unexpected event: %v", event)
+		}
+	default:
+		t.Fatal("Expected an event to be recorded")
+	}
+
+	// No new event while the versions stay the same.
+	previous = *condition
+	setDeprecatedCondition(&stackResource.Status, later)
+	reportEndOfSupport(recorder, stackResource, &previous)
+	if len(recorder.Events) != 0 {
+		t.Fatal("Expected no event when the versions did not change")
+	}
+}
@@ -0,0 +1,106 @@
+package stack
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"golang.org/x/sync/singleflight"
+)
+
+// The amount of time an image digest is cached if the Kabanero instance does not say otherwise.
+const DefaultDigestCacheTTL = 5 * time.Minute
+
+// Value in the digest cache map.
+type digestCacheValue struct {
+	digest string
+	expiry time.Time
+}
+
+// Caches the digests that image tags refer to.  Many stack versions usually share the
+// same images, and each registry request counts against the registry's rate limits.
+// Only successful lookups are cached, and concurrent lookups of the same image share
+// a single registry request.
+type digestCache struct {
+	lock    sync.Mutex
+	ttl     time.Duration
+	digests map[string]digestCacheValue
+	lookups singleflight.Group
+}
+
+// The digest cache shared by all stacks.
+var imageDigestCache = &digestCache{ttl: DefaultDigestCacheTTL, digests: make(map[string]digestCacheValue)}
+
+// Returns the digest cache TTL configured in the Kabanero instance.
+func GetDigestCacheTTL(k *kabanerov1alpha2.Kabanero) time.Duration {
+	if k == nil || k.Spec.Stacks.DigestCacheTTL == nil {
+		return DefaultDigestCacheTTL
+	}
+	if k.Spec.Stacks.DigestCacheTTL.Duration < 0 {
+		return 0
+	}
+	return k.Spec.Stacks.DigestCacheTTL.Duration
+}
+
+// Sets how long new entries are cached.  A TTL of zero disables the cache and empties it.
+func (dc *digestCache) setTTL(ttl time.Duration) {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+	dc.ttl = ttl
+	if ttl <= 0 {
+		dc.digests = make(map[string]digestCacheValue)
+	}
+}
+
+// Returns the cache key of the input image.  Images are cached per namespace and pull
+// secrets, since they determine the credentials used to read the image, and a digest
+// read with one set of credentials must not be handed to a stack that uses another.
+func digestCacheKey(namespace string, pullSecrets []string, image string) string {
+	return namespace + "/" + strings.Join(pullSecrets, ",") + "/" + image
+}
+
+// Returns the cached digest for the input key, if it has not expired.
+func (dc *digestCache) get(key string, now time.Time) (string, bool) {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+	value, found := dc.digests[key]
+	if !found || !now.Before(value.expiry) {
+		return "", false
+	}
+	return value.digest, true
+}
+
+// Caches the digest for the input key.  Expired entries are removed.
+func (dc *digestCache) put(key string, digest string, now time.Time) {
+	dc.lock.Lock()
+	defer dc.lock.Unlock()
+	if dc.ttl <= 0 {
+		return
+	}
+
+	for key, value := range dc.digests {
+		if !now.Before(value.expiry) {
+			delete(dc.digests, key)
+		}
+	}
+	dc.digests[key] = digestCacheValue{digest: digest, expiry: now.Add(dc.ttl)}
+}
+
+// Returns the cached digest for the input key, or calls lookup and caches its result.
+// Callers asking for the same key while a lookup is running wait for its result.
+func (dc *digestCache) getOrLookup(key string, lookup func() (string, error)) (string, error) {
+	if digest, found := dc.get(key, time.Now()); found {
+		return digest, nil
+	}
+
+	digest, err, _ := dc.lookups.Do(key, func() (interface{}, error) {
+		digest, err := lookup()
+		if err != nil {
+			return "", err
+		}
+		dc.put(key, digest, time.Now())
+		return digest, nil
+	})
+	return digest.(string), err
+}
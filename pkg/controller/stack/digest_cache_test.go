@@ -0,0 +1,110 @@
+package stack
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that cached digests expire, and are kept per namespace and pull secrets.
+func TestDigestCache(t *testing.T) {
+	dc := &digestCache{ttl: time.Minute, digests: make(map[string]digestCacheValue)}
+	now := time.Now()
+	key := digestCacheKey("kabanero", []string{"quay"}, "docker.io/kabanero/java:0.2")
+
+	dc.put(key, "1234", now)
+	if digest, found := dc.get(key, now.Add(30*time.Second)); !found || digest != "1234" {
+		t.Fatalf("Expected the cached digest 1234, but found %v (%v)", digest, found)
+	}
+	if _, found := dc.get(digestCacheKey("other", []string{"quay"}, "docker.io/kabanero/java:0.2"), now); found {
+		t.Fatal("Expected digests to be cached per namespace")
+	}
+	if _, found := dc.get(digestCacheKey("kabanero", nil, "docker.io/kabanero/java:0.2"), now); found {
+		t.Fatal("Expected digests to be cached per pull secrets")
+	}
+	if _, found := dc.get(key, now.Add(time.Minute)); found {
+		t.Fatal("Expected the cached digest to expire")
+	}
+
+	// Expired entries are removed when new ones are added.
+	dc.put(digestCacheKey("kabanero", nil, "docker.io/kabanero/nodejs:0.2"), "5678", now.Add(2*time.Minute))
+	if len(dc.digests) != 1 {
+		t.Fatalf("Expected the expired entry to be removed: %v", dc.digests)
+	}
+
+	// A TTL of zero disables the cache.
+	dc.setTTL(0)
+	dc.put(key, "1234", now)
+	if len(dc.digests) != 0 {
+		t.Fatalf("Expected the cache to be empty: %v", dc.digests)
+	}
+}
+
+// Test that concurrent lookups of the same image share one registry request, and that
+// the result is cached.
+func TestDigestCacheGetOrLookup(t *testing.T) {
+	dc := &digestCache{ttl: time.Minute, digests: make(map[string]digestCacheValue)}
+	key := digestCacheKey("kabanero", nil, "docker.io/kabanero/java:0.2")
+
+	var lookups int32
+	release := make(chan struct{})
+	lookup := func() (string, error) {
+		atomic.AddInt32(&lookups, 1)
+		<-release
+		return "1234", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = dc.getOrLookup(key, lookup)
+		}(i)
+	}
+
+	// Give the lookups a chance to start before the registry answers.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, digest := range results {
+		if digest != "1234" {
+			t.Fatalf("Expected the digest 1234, but found %v", results)
+		}
+	}
+	if digest, err := dc.getOrLookup(key, lookup); err != nil || digest != "1234" {
+		t.Fatalf("Expected the cached digest 1234, but found %v (%v)", digest, err)
+	}
+	if lookups != 1 {
+		t.Fatalf("Expected a single registry lookup, but found %v", lookups)
+	}
+
+	// Failed lookups are not cached.
+	failing := func() (string, error) { return "", fmt.Errorf("unauthorized") }
+	if _, err := dc.getOrLookup(digestCacheKey("other", nil, "docker.io/kabanero/java:0.2"), failing); err == nil {
+		t.Fatal("Expected the lookup error to be returned")
+	}
+	if len(dc.digests) != 1 {
+		t.Fatalf("Expected only the successful lookup to be cached: %v", dc.digests)
+	}
+}
+
+// Test the digest cache TTL configured in the Kabanero instance.
+func TestGetDigestCacheTTL(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{}
+	if ttl := GetDigestCacheTTL(k); ttl != DefaultDigestCacheTTL {
+		t.Errorf("Expected the default TTL, but found %v", ttl)
+	}
+
+	k.Spec.Stacks.DigestCacheTTL = &metav1.Duration{Duration: -time.Minute}
+	if ttl := GetDigestCacheTTL(k); ttl != 0 {
+		t.Errorf("Expected a negative TTL to disable the cache, but found %v", ttl)
+	}
+}
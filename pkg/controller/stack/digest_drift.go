@@ -0,0 +1,137 @@
+package stack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Stack images are usually referenced by a tag, which can be moved to a different image
+// after the stack version was activated.  The tags of active versions are checked
+// periodically, and the DigestDrifted condition reports the ones that moved.
+
+// The default interval between checks of an image tag.
+const defaultDigestDriftCheckInterval = time.Hour
+
+// The condition reasons.
+const (
+	digestDriftedReason   = "ImageTagMoved"
+	digestUnchangedReason = "ImageTagsUnchanged"
+)
+
+// Returns how often the image tags of active stack versions are checked.  Zero means
+// they are not checked.
+func GetDigestDriftCheckInterval(k *kabanerov1alpha2.Kabanero) time.Duration {
+	if k == nil || k.Spec.Stacks.DigestDriftCheckInterval == nil {
+		return defaultDigestDriftCheckInterval
+	}
+	if k.Spec.Stacks.DigestDriftCheckInterval.Duration < 0 {
+		return 0
+	}
+	return k.Spec.Stacks.DigestDriftCheckInterval.Duration
+}
+
+// Checks the image tag again, using the input resolve function, if it was last checked more
+// than interval ago, or if the refresh schedule fired since.  Returns the updated digest.  If the tag cannot be resolved, the previous
+// result is kept, and the tag is checked again on the next reconcile.
+func checkImageDigestDrift(digest kabanerov1alpha2.ImageDigest, interval time.Duration, schedule *timer.CronSchedule, now time.Time, resolve func() (string, error), logger logr.Logger) kabanerov1alpha2.ImageDigest {
+	if (interval <= 0 && schedule == nil) || len(digest.Activation) == 0 {
+		return digest
+	}
+
+	// Digests recorded before the tags were checked are assumed to be current.  They are
+	// checked when the next interval expires.
+	if digest.LastChecked == nil {
+		checked := metav1.NewTime(now)
+		digest.LastChecked = &checked
+		if len(digest.Current) == 0 {
+			digest.Current = digest.Activation
+		}
+		return digest
+	}
+
+	intervalExpired := interval > 0 && now.Sub(digest.LastChecked.Time) >= interval
+	if !intervalExpired && !isScheduledRefreshDue(schedule, digest.LastChecked.Time, now) {
+		return digest
+	}
+
+	current, err := resolve()
+	if err != nil {
+		logger.Info(fmt.Sprintf("Unable to check the image digest for drift. Error: %v", err))
+		return digest
+	}
+
+	checked := metav1.NewTime(now)
+	digest.Current = current
+	digest.LastChecked = &checked
+	return digest
+}
+
+// Returns the images whose tags refer to a different digest than when they were activated.
+func getDriftedImages(status kabanerov1alpha2.StackStatus) []string {
+	drifted := []string{}
+	for _, version := range status.Versions {
+		if version.Status != kabanerov1alpha2.StackDesiredStateActive {
+			continue
+		}
+		for _, img := range version.Images {
+			if len(img.Digest.Current) != 0 && len(img.Digest.Activation) != 0 && img.Digest.Current != img.Digest.Activation {
+				drifted = append(drifted, fmt.Sprintf("%v:%v (activated with %v, now %v)", img.Image, version.Version, img.Digest.Activation, img.Digest.Current))
+			}
+		}
+	}
+	return drifted
+}
+
+// Sets the DigestDrifted condition to reflect the images in the input status.
+func setDigestDriftCondition(status *kabanerov1alpha2.StackStatus) {
+	now := metav1.Now()
+	condition := kabanerov1alpha2.StackCondition{
+		Type:               kabanerov1alpha2.StackConditionDigestDrifted,
+		Status:             string(corev1.ConditionFalse),
+		LastTransitionTime: &now,
+		Reason:             digestUnchangedReason,
+	}
+
+	drifted := getDriftedImages(*status)
+	if len(drifted) != 0 {
+		condition.Status = string(corev1.ConditionTrue)
+		condition.Reason = digestDriftedReason
+		condition.Message = fmt.Sprintf("The image tags of active stack versions refer to different images than when the versions were activated: %v", strings.Join(drifted, ", "))
+	}
+
+	status.SetCondition(condition)
+}
+
+// Emits an event if the DigestDrifted condition became true, or the images it reports changed.
+func reportDigestDrift(recorder record.EventRecorder, stackResource *kabanerov1alpha2.Stack, previous *kabanerov1alpha2.StackCondition) {
+	current := stackResource.Status.GetCondition(kabanerov1alpha2.StackConditionDigestDrifted)
+	if recorder == nil || current == nil || current.Status != string(corev1.ConditionTrue) {
+		return
+	}
+
+	if previous != nil && previous.Status == current.Status && previous.Message == current.Message {
+		return
+	}
+
+	recorder.Event(stackResource, corev1.EventTypeWarning, kabanerov1alpha2.StackConditionDigestDrifted, current.Message)
+}
+
+// Returns true if the status has activation digests whose tags should be checked periodically.
+func hasActivationDigests(status kabanerov1alpha2.StackStatus) bool {
+	for _, version := range status.Versions {
+		for _, img := range version.Images {
+			if len(img.Digest.Activation) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package stack
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Test that image tags are only checked when the interval expires.
+func TestCheckImageDigestDrift(t *testing.T) {
+	now := time.Now()
+	resolved := 0
+	resolve := func() (string, error) {
+		resolved++
+		return "2222", nil
+	}
+
+	// Digests recorded before the checks existed are assumed to be current.
+	digest := checkImageDigestDrift(kabanerov1alpha2.ImageDigest{Activation: "1111"}, time.Hour, nil, now, resolve, sctlog)
+	if resolved != 0 || digest.Current != "1111" || digest.LastChecked == nil {
+		t.Fatalf("Expected the digest to be assumed current, but found %v (resolved %v times)", digest, resolved)
+	}
+
+	// Not due yet.
+	digest = checkImageDigestDrift(digest, time.Hour, nil, now.Add(30*time.Minute), resolve, sctlog)
+	if resolved != 0 || digest.Current != "1111" {
+		t.Fatalf("Expected the tag not to be checked, but found %v (resolved %v times)", digest, resolved)
+	}
+
+	// Due.
+	later := now.Add(2 * time.Hour)
+	digest = checkImageDigestDrift(digest, time.Hour, nil, later, resolve, sctlog)
+	if resolved != 1 || digest.Current != "2222" || digest.Activation != "1111" || !digest.LastChecked.Time.Equal(later) {
+		t.Fatalf("Expected the tag to be checked, but found %v (resolved %v times)", digest, resolved)
+	}
+
+	// A failed check keeps the previous result.
+	failed := checkImageDigestDrift(digest, time.Hour, nil, later.Add(2*time.Hour), func() (string, error) { return "", errors.New("registry unavailable") }, sctlog)
+	if failed.Current != "2222" || failed.LastChecked != digest.LastChecked {
+		t.Fatalf("Expected the previous check to be kept, but found %v", failed)
+	}
+
+	// Disabled.
+	disabled := checkImageDigestDrift(kabanerov1alpha2.ImageDigest{Activation: "1111"}, 0, nil, now, resolve, sctlog)
+	if disabled.LastChecked != nil {
+		t.Fatalf("Expected the check to be disabled, but found %v", disabled)
+	}
+}
+
+// Test that image tags are checked when the refresh schedule fires, without an interval.
+func TestCheckImageDigestDriftSchedule(t *testing.T) {
+	schedule, err := timer.ParseCronSchedule("0 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved := 0
+	resolve := func() (string, error) {
+		resolved++
+		return "2222", nil
+	}
+
+	checked := metav1.NewTime(time.Date(2020, 5, 1, 1, 0, 0, 0, time.UTC))
+	digest := kabanerov1alpha2.ImageDigest{Activation: "1111", Current: "1111", LastChecked: &checked}
+
+	// Before the window.
+	digest = checkImageDigestDrift(digest, 0, schedule, time.Date(2020, 5, 1, 1, 59, 0, 0, time.UTC), resolve, sctlog)
+	if resolved != 0 || digest.Current != "1111" {
+		t.Fatalf("Expected the tag not to be checked, but found %v (resolved %v times)", digest, resolved)
+	}
+
+	// In the window.
+	digest = checkImageDigestDrift(digest, 0, schedule, time.Date(2020, 5, 1, 2, 0, 30, 0, time.UTC), resolve, sctlog)
+	if resolved != 1 || digest.Current != "2222" {
+		t.Fatalf("Expected the tag to be checked, but found %v (resolved %v times)", digest, resolved)
+	}
+
+	// Not again until the next day.
+	digest = checkImageDigestDrift(digest, 0, schedule, time.Date(2020, 5, 1, 23, 0, 0, 0, time.UTC), resolve, sctlog)
+	if resolved != 1 {
+		t.Fatalf("Expected the tag not to be checked again, but it was resolved %v times", resolved)
+	}
+}
+
+// Test that the condition and the event report the images whose tags moved.
+func TestDigestDriftCondition(t *testing.T) {
+	stackResource := &kabanerov1alpha2.Stack{
+		Status: kabanerov1alpha2.StackStatus{
+			Versions: []kabanerov1alpha2.StackVersionStatus{{
+				Version: "0.2.5",
+				Status:  kabanerov1alpha2.StackDesiredStateActive,
+				Images: []kabanerov1alpha2.ImageStatus{
+					{Id: "nodejs", Image: "docker.io/kabanero/nodejs", Digest: kabanerov1alpha2.ImageDigest{Activation: "1111", Current: "1111"}},
+				},
+			}},
+		},
+	}
+
+	setDigestDriftCondition(&stackResource.Status)
+	condition := stackResource.Status.GetCondition(kabanerov1alpha2.StackConditionDigestDrifted)
+	if condition == nil || condition.Status != "False" {
+		t.Fatalf("Expected the DigestDrifted condition to be False, but found %v", condition)
+	}
+	previous := *condition
+
+	recorder := record.NewFakeRecorder(10)
+	stackResource.Status.Versions[0].Images[0].Digest.Current = "2222"
+	setDigestDriftCondition(&stackResource.Status)
+	reportDigestDrift(recorder, stackResource, &previous)
+
+	condition = stackResource.Status.GetCondition(kabanerov1alpha2.StackConditionDigestDrifted)
+	if condition.Status != "True" || !strings.Contains(condition.Message, "docker.io/kabanero/nodejs:0.2.5") {
+		t.Fatalf("Expected the DigestDrifted condition to be True, but found %v", condition)
+	}
+	if len(stackResource.Status.Conditions) != 1 {
+		t.Fatalf("Expected one condition, but found %v", stackResource.Status.Conditions)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, kabanerov1alpha2.StackConditionDigestDrifted) {
+			t.Fatalf("Unexpected event: %v", event)
+		}
+	default:
+		t.Fatal("Expected an event to be recorded")
+	}
+
+	// No new event while the drift stays the same.
+	previous = *condition
+	setDigestDriftCondition(&stackResource.Status)
+	reportDigestDrift(recorder, stackResource, &previous)
+	if len(recorder.Events) != 0 {
+		t.Fatal("Expected no event when the drift did not change")
+	}
+}
@@ -0,0 +1,142 @@
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+)
+
+// The number of image digests of a stack that are retrieved at the same time.
+const maxConcurrentDigestLookups = 8
+
+// The minimum time between the start of two requests to the same image registry,
+// across all stacks.
+const registryRequestInterval = 100 * time.Millisecond
+
+// How registry requests that fail with transient errors are retried.
+var registryRetryBackoff = timer.Backoff{
+	Attempts: 5,
+	Initial:  500 * time.Millisecond,
+	Factor:   2,
+	Max:      8 * time.Second,
+	Budget:   15 * time.Second,
+
+	// Slow registries time out each request, so the attempts themselves are limited too.
+	MaxElapsed: time.Minute,
+}
+
+// An image whose digest is needed for the status of a stack version.
+type digestLookup struct {
+	// The index of the version, and of the image within the version, in the new status.
+	versionIndex int
+	imageIndex   int
+
+	curSpec kabanerov1alpha2.StackVersion
+	image   string
+}
+
+// The outcome of a digest lookup.
+type digestLookupResult struct {
+	digest kabanerov1alpha2.ImageDigest
+	err    error
+}
+
+// Runs the lookups using a bounded number of workers.  The results are returned in the
+// same order as the lookups.
+func lookupImageDigests(lookups []digestLookup, workers int, lookup func(digestLookup) (kabanerov1alpha2.ImageDigest, error)) []digestLookupResult {
+	results := make([]digestLookupResult, len(lookups))
+	if workers < 1 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers && w < len(lookups); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				digest, err := lookup(lookups[i])
+				results[i] = digestLookupResult{digest: digest, err: err}
+			}
+		}()
+	}
+
+	for i := range lookups {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// Spaces out the requests made to each image registry, so that retrieving the digests
+// of many stack versions at once does not trip the registry's rate limits.
+type registryThrottle struct {
+	lock     sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+var digestThrottle = &registryThrottle{interval: registryRequestInterval, next: make(map[string]time.Time)}
+
+// Waits until a request to the input registry may start.
+func (t *registryThrottle) wait(registry string) {
+	t.lock.Lock()
+	now := time.Now()
+	start := t.next[registry]
+	if start.Before(now) {
+		start = now
+	}
+	t.next[registry] = start.Add(t.interval)
+	t.lock.Unlock()
+
+	time.Sleep(start.Sub(now))
+}
+
+// Returns true if the registry error is likely to go away on its own: the registry is
+// rate limiting requests, is unavailable, or the network timed out.
+func isTransientRegistryError(err error) bool {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return terr.StatusCode == http.StatusTooManyRequests || terr.StatusCode >= http.StatusInternalServerError
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) {
+		return nerr.Timeout() || nerr.Temporary()
+	}
+	return false
+}
+
+// Returns the StackReason reported for an error retrieving an image digest.
+func digestErrorReason(err error) string {
+	var terr *transport.Error
+	if errors.As(err, &terr) && (terr.StatusCode == http.StatusUnauthorized || terr.StatusCode == http.StatusForbidden) {
+		return kabanerov1alpha2.StackReasonRegistryUnauthorized
+	}
+	if isTransientRegistryError(err) {
+		return kabanerov1alpha2.StackReasonRegistryUnavailable
+	}
+	return kabanerov1alpha2.StackReasonDigestLookupFailed
+}
+
+// Runs the registry request, retrying it with exponential backoff while it fails with a
+// transient error.  The last error is returned once the retry budget is exhausted.
+func retryRegistryRequest(logger logr.Logger, request func() error) error {
+	return retryRegistryRequestWithBackoff(registryRetryBackoff, logger, request)
+}
+
+func retryRegistryRequestWithBackoff(backoff timer.Backoff, logger logr.Logger, request func() error) error {
+	return timer.RetryOnError(backoff, isTransientRegistryError, func(attempt int, err error, waitTime time.Duration) {
+		logger.Info(fmt.Sprintf("Transient image registry error. The request will be retried. Error: %v", err))
+	}, request)
+}
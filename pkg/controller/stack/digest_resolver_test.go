@@ -0,0 +1,133 @@
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+)
+
+// Test that the lookups run concurrently, within the worker limit, and that the results
+// keep the order of the lookups.
+func TestLookupImageDigests(t *testing.T) {
+	lookups := []digestLookup{}
+	for i := 0; i < 20; i++ {
+		lookups = append(lookups, digestLookup{versionIndex: i, image: "kabanero/java"})
+	}
+
+	lock := sync.Mutex{}
+	running, maxRunning := 0, 0
+	results := lookupImageDigests(lookups, 4, func(l digestLookup) (kabanerov1alpha2.ImageDigest, error) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		running--
+		lock.Unlock()
+
+		if l.versionIndex%5 == 0 {
+			return kabanerov1alpha2.ImageDigest{Message: "failed"}, errors.New("failed")
+		}
+		return kabanerov1alpha2.ImageDigest{Activation: string(rune('a' + l.versionIndex))}, nil
+	})
+
+	if maxRunning > 4 || maxRunning < 2 {
+		t.Errorf("Expected at most 4 concurrent lookups, and some concurrency, but found %v", maxRunning)
+	}
+
+	if len(results) != len(lookups) {
+		t.Fatalf("Expected %v results, but found %v", len(lookups), len(results))
+	}
+	for i, result := range results {
+		if i%5 == 0 {
+			if result.err == nil {
+				t.Errorf("Expected lookup %v to fail", i)
+			}
+		} else if result.err != nil || result.digest.Activation != string(rune('a'+i)) {
+			t.Errorf("Unexpected result for lookup %v: %+v", i, result)
+		}
+	}
+}
+
+// Test that requests to the same registry are spaced out, and other registries are not delayed.
+func TestRegistryThrottle(t *testing.T) {
+	throttle := &registryThrottle{interval: 20 * time.Millisecond, next: make(map[string]time.Time)}
+
+	start := time.Now()
+	throttle.wait("docker.io")
+	throttle.wait("quay.io")
+	if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+		t.Errorf("Requests to different registries should not be delayed, but took %v", elapsed)
+	}
+
+	throttle.wait("docker.io")
+	throttle.wait("docker.io")
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected requests to the same registry to be spaced out, but took %v", elapsed)
+	}
+}
+
+// Test that rate limiting and server errors are retried, and other errors are not.
+func TestRetryRegistryRequest(t *testing.T) {
+	backoff := timer.Backoff{Attempts: 3, Initial: time.Millisecond, Factor: 2}
+
+	calls := 0
+	err := retryRegistryRequestWithBackoff(backoff, sctlog, func() error {
+		calls++
+		if calls == 1 {
+			return &transport.Error{StatusCode: http.StatusTooManyRequests}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("Expected the request to succeed on the second attempt, but found %v after %v attempts", err, calls)
+	}
+
+	calls = 0
+	unavailable := &transport.Error{StatusCode: http.StatusServiceUnavailable}
+	err = retryRegistryRequestWithBackoff(backoff, sctlog, func() error {
+		calls++
+		return unavailable
+	})
+	if err != unavailable || calls != 3 {
+		t.Fatalf("Expected the registry error after 3 attempts, but found %v after %v attempts", err, calls)
+	}
+
+	calls = 0
+	unauthorized := &transport.Error{StatusCode: http.StatusUnauthorized}
+	err = retryRegistryRequestWithBackoff(backoff, sctlog, func() error {
+		calls++
+		return unauthorized
+	})
+	if err != unauthorized || calls != 1 {
+		t.Fatalf("Expected the registry error after 1 attempt, but found %v after %v attempts", err, calls)
+	}
+}
+
+// Test the status reasons reported for digest lookup errors.
+func TestDigestErrorReason(t *testing.T) {
+	reasons := map[error]string{
+		&transport.Error{StatusCode: http.StatusUnauthorized}:                         kabanerov1alpha2.StackReasonRegistryUnauthorized,
+		fmt.Errorf("wrapped: %w", &transport.Error{StatusCode: http.StatusForbidden}): kabanerov1alpha2.StackReasonRegistryUnauthorized,
+		&transport.Error{StatusCode: http.StatusTooManyRequests}:                      kabanerov1alpha2.StackReasonRegistryUnavailable,
+		errors.New("manifest unknown"):                                                kabanerov1alpha2.StackReasonDigestLookupFailed,
+	}
+
+	for err, expected := range reasons {
+		if reason := digestErrorReason(err); reason != expected {
+			t.Errorf("Expected reason %v for error %v, but found %v", expected, err, reason)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	k8runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	k8types "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// A stack can expose the EventListeners in its pipeline archives with a Route or an
+// Ingress, so that webhooks can be configured without creating routes by hand.  The Route
+// or Ingress has the name of the service that Tekton Triggers creates for the EventListener.
+// It is usually in the trigger namespace, so its owners are recorded like those of the
+// trigger assets, and an EventListener shared by several stacks has a single Route.
+
+// Label set on the Routes and Ingresses of EventListeners, to the name of the EventListener.
+const eventListenerRouteLabel = "kabanero.io/event-listener"
+
+var (
+	routeGVK   = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+	ingressGVK = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"}
+)
+
+// The port of the service that Tekton Triggers creates for an EventListener.
+const eventListenerServicePort = 8080
+
+// How long to wait before checking again whether a Route was admitted.
+const eventListenerRouteRetryInterval = 30 * time.Second
+
+// Returns the name of the service that Tekton Triggers creates for an EventListener.
+func eventListenerServiceName(eventListener string) string {
+	return "el-" + eventListener
+}
+
+// Creates or updates the Routes or Ingresses of the active EventListeners of the stack, as
+// configured by the stack, and records their URLs in the pipeline status.  The Routes and
+// Ingresses that the stack no longer needs are removed from the input namespaces.
+func reconcileEventListenerRoutes(ctx context.Context, c cutils.AssetClient, stackResource *kabanerov1alpha2.Stack, status *kabanerov1alpha2.StackStatus, namespaces []string, assetOwner metav1.OwnerReference, logger logr.Logger) {
+	spec := stackResource.Spec.EventListenerRoute
+	exposed := make(map[k8types.NamespacedName]kabanerov1alpha2.EventListenerStatus)
+
+	for i := range status.Versions {
+		for j := range status.Versions[i].Pipelines {
+			pipeline := &status.Versions[i].Pipelines[j]
+			pipeline.EventListeners = nil
+			if spec == nil {
+				continue
+			}
+
+			for _, asset := range pipeline.ActiveAssets {
+				if asset.Kind != "EventListener" || asset.Status != cutils.AssetStatusActive {
+					continue
+				}
+
+				key := k8types.NamespacedName{Namespace: asset.Namespace, Name: asset.Name}
+				listenerStatus, found := exposed[key]
+				if !found {
+					listenerStatus = reconcileEventListenerRoute(ctx, c, *spec, key, assetOwner, logger)
+					exposed[key] = listenerStatus
+				}
+				pipeline.EventListeners = append(pipeline.EventListeners, listenerStatus)
+			}
+		}
+	}
+
+	kind := ""
+	if spec != nil {
+		kind = eventListenerRouteKind(*spec)
+	}
+	for key := range exposed {
+		namespaces = append(namespaces, key.Namespace)
+	}
+	pruneEventListenerRoutes(ctx, c, namespaces, kind, exposed, assetOwner, logger)
+}
+
+// Returns the kind of object that exposes the EventListeners.
+func eventListenerRouteKind(spec kabanerov1alpha2.EventListenerRouteSpec) string {
+	if spec.Kind == kabanerov1alpha2.EventListenerRouteKindIngress {
+		return kabanerov1alpha2.EventListenerRouteKindIngress
+	}
+	return kabanerov1alpha2.EventListenerRouteKindRoute
+}
+
+// Creates or updates the Route or Ingress of an EventListener.  The spec is only updated
+// when the stack is the first owner, so that stacks sharing an EventListener do not
+// undo each other's changes.
+func reconcileEventListenerRoute(ctx context.Context, c cutils.AssetClient, spec kabanerov1alpha2.EventListenerRouteSpec, eventListener k8types.NamespacedName, assetOwner metav1.OwnerReference, logger logr.Logger) kabanerov1alpha2.EventListenerStatus {
+	listenerStatus := kabanerov1alpha2.EventListenerStatus{Name: eventListener.Name, Namespace: eventListener.Namespace}
+	key := k8types.NamespacedName{Namespace: eventListener.Namespace, Name: eventListenerServiceName(eventListener.Name)}
+
+	var err error
+	if eventListenerRouteKind(spec) == kabanerov1alpha2.EventListenerRouteKindIngress {
+		ingress := &networkingv1beta1.Ingress{}
+		err = getEventListenerRouteObject(ctx, c, ingressGVK, key, ingress)
+		if errors.IsNotFound(err) {
+			ingress = newEventListenerIngress(spec, eventListener)
+			cutils.AddCrossNamespaceOwner(ingress, assetOwner)
+			logger.Info(fmt.Sprintf("Creating Ingress %v in namespace %v for EventListener %v", key.Name, key.Namespace, eventListener.Name))
+			err = writeEventListenerRouteObject(ingressGVK, ingress, func(u *unstructured.Unstructured) error { return c.Create(ctx, u) })
+		} else if err == nil {
+			changed := cutils.AddCrossNamespaceOwner(ingress, assetOwner)
+			desired := newEventListenerIngress(spec, eventListener)
+			// Only the host and TLS are compared, since the cluster may fill in defaults.
+			if isFirstOwner(ingress, assetOwner) && (getEventListenerIngressURL(ingress) != getEventListenerIngressURL(desired) || !equality.Semantic.DeepEqual(ingress.Spec.TLS, desired.Spec.TLS)) {
+				ingress.Spec = desired.Spec
+				changed = true
+			}
+			if changed {
+				err = writeEventListenerRouteObject(ingressGVK, ingress, func(u *unstructured.Unstructured) error { return c.Update(ctx, u) })
+			}
+		}
+		if err == nil {
+			listenerStatus.Url = getEventListenerIngressURL(ingress)
+		}
+	} else {
+		route := &routev1.Route{}
+		err = getEventListenerRouteObject(ctx, c, routeGVK, key, route)
+		if errors.IsNotFound(err) {
+			route = newEventListenerRoute(spec, eventListener)
+			cutils.AddCrossNamespaceOwner(route, assetOwner)
+			logger.Info(fmt.Sprintf("Creating Route %v in namespace %v for EventListener %v", key.Name, key.Namespace, eventListener.Name))
+			err = writeEventListenerRouteObject(routeGVK, route, func(u *unstructured.Unstructured) error { return c.Create(ctx, u) })
+		} else if err == nil {
+			changed := cutils.AddCrossNamespaceOwner(route, assetOwner)
+			// The router fills in the host when it is not requested.
+			if isFirstOwner(route, assetOwner) && len(spec.Host) != 0 && route.Spec.Host != spec.Host {
+				route.Spec.Host = spec.Host
+				changed = true
+			}
+			if changed {
+				err = writeEventListenerRouteObject(routeGVK, route, func(u *unstructured.Unstructured) error { return c.Update(ctx, u) })
+			}
+		}
+		if err == nil {
+			listenerStatus.Url, listenerStatus.Message = getEventListenerRouteURL(route, spec.Host)
+		}
+	}
+
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to expose EventListener %v in namespace %v", eventListener.Name, eventListener.Namespace))
+		listenerStatus.Url = ""
+		listenerStatus.Message = fmt.Sprintf("Unable to expose the EventListener: %v", err)
+	}
+
+	return listenerStatus
+}
+
+// Reads a Route or Ingress into the input typed object.  They are read as unstructured
+// objects, since the cache of the stack controller does not cover the trigger namespace,
+// and Routes are not in its scheme.
+func getEventListenerRouteObject(ctx context.Context, c client.Reader, gvk schema.GroupVersionKind, key k8types.NamespacedName, obj interface{}) error {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	err := c.Get(ctx, key, u)
+	if err != nil {
+		return err
+	}
+	return k8runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj)
+}
+
+// Converts the input typed Route or Ingress to an unstructured object, and writes it.
+func writeEventListenerRouteObject(gvk schema.GroupVersionKind, obj interface{}, write func(*unstructured.Unstructured) error) error {
+	content, err := k8runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	return write(u)
+}
+
+// Returns true if the input owner is the first owner of a Route or Ingress.
+func isFirstOwner(o metav1.Object, assetOwner metav1.OwnerReference) bool {
+	return o.GetLabels()[cutils.AssetOwnerUIDLabel] == string(assetOwner.UID)
+}
+
+// Returns a Route that exposes an EventListener, with edge TLS termination.
+func newEventListenerRoute(spec kabanerov1alpha2.EventListenerRouteSpec, eventListener k8types.NamespacedName) *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      eventListenerServiceName(eventListener.Name),
+			Namespace: eventListener.Namespace,
+			Labels:    map[string]string{eventListenerRouteLabel: eventListener.Name},
+		},
+		Spec: routev1.RouteSpec{
+			Host: spec.Host,
+			To: routev1.RouteTargetReference{
+				Kind: "Service",
+				Name: eventListenerServiceName(eventListener.Name),
+			},
+			TLS: &routev1.TLSConfig{
+				Termination:                   routev1.TLSTerminationEdge,
+				InsecureEdgeTerminationPolicy: routev1.InsecureEdgeTerminationPolicyRedirect,
+			},
+		},
+	}
+}
+
+// Returns an Ingress that exposes an EventListener.
+func newEventListenerIngress(spec kabanerov1alpha2.EventListenerRouteSpec, eventListener k8types.NamespacedName) *networkingv1beta1.Ingress {
+	ingress := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      eventListenerServiceName(eventListener.Name),
+			Namespace: eventListener.Namespace,
+			Labels:    map[string]string{eventListenerRouteLabel: eventListener.Name},
+		},
+		Spec: networkingv1beta1.IngressSpec{
+			Rules: []networkingv1beta1.IngressRule{{
+				Host: spec.Host,
+				IngressRuleValue: networkingv1beta1.IngressRuleValue{
+					HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+						Paths: []networkingv1beta1.HTTPIngressPath{{
+							Path: "/",
+							Backend: networkingv1beta1.IngressBackend{
+								ServiceName: eventListenerServiceName(eventListener.Name),
+								ServicePort: intstr.FromInt(eventListenerServicePort),
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+
+	if len(spec.TlsSecretName) != 0 {
+		ingress.Spec.TLS = []networkingv1beta1.IngressTLS{{Hosts: []string{spec.Host}, SecretName: spec.TlsSecretName}}
+	}
+
+	return ingress
+}
+
+// Returns the URL of a Route once a router admitted it.  When the requested host is not
+// admitted, the message explains why.
+func getEventListenerRouteURL(route *routev1.Route, requestedHost string) (string, string) {
+	message := "The Route has not been admitted by a router yet"
+	for _, ingress := range route.Status.Ingress {
+		if len(requestedHost) != 0 && ingress.Host != requestedHost {
+			continue
+		}
+		for _, condition := range ingress.Conditions {
+			if condition.Type != routev1.RouteAdmitted {
+				continue
+			}
+			if condition.Status == corev1.ConditionTrue && len(ingress.Host) != 0 {
+				return "https://" + ingress.Host, ""
+			}
+			if len(condition.Message) != 0 {
+				message = fmt.Sprintf("The host %v was not admitted by router %v: %v", ingress.Host, ingress.RouterName, condition.Message)
+			}
+		}
+	}
+	return "", message
+}
+
+// Returns the URL of an Ingress.
+func getEventListenerIngressURL(ingress *networkingv1beta1.Ingress) string {
+	if len(ingress.Spec.Rules) == 0 || len(ingress.Spec.Rules[0].Host) == 0 {
+		return ""
+	}
+	if len(ingress.Spec.TLS) != 0 {
+		return "https://" + ingress.Spec.Rules[0].Host
+	}
+	return "http://" + ingress.Spec.Rules[0].Host
+}
+
+// Removes the input owner from the Routes and Ingresses of EventListeners in the input
+// namespaces, except the ones of the input kind that expose the input EventListeners.
+// A Route or Ingress is deleted when its last owner is removed.
+func pruneEventListenerRoutes(ctx context.Context, c cutils.AssetClient, namespaces []string, exposedKind string, exposed map[k8types.NamespacedName]kabanerov1alpha2.EventListenerStatus, assetOwner metav1.OwnerReference, logger logr.Logger) {
+	requirement, err := labels.NewRequirement(eventListenerRouteLabel, selection.Exists, nil)
+	if err != nil {
+		logger.Error(err, "Unable to select the EventListener routes")
+		return
+	}
+	selector := client.MatchingLabelsSelector{Selector: labels.NewSelector().Add(*requirement)}
+
+	visited := make(map[string]bool)
+	for _, namespace := range namespaces {
+		if visited[namespace] {
+			continue
+		}
+		visited[namespace] = true
+
+		for kind, gvk := range map[string]schema.GroupVersionKind{kabanerov1alpha2.EventListenerRouteKindRoute: routeGVK, kabanerov1alpha2.EventListenerRouteKindIngress: ingressGVK} {
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind + "List"})
+			err := c.List(ctx, list, client.InNamespace(namespace), selector)
+			if err != nil {
+				// Routes only exist on OpenShift.
+				if !meta.IsNoMatchError(err) {
+					logger.Error(err, fmt.Sprintf("Unable to list the EventListener %v objects in namespace %v", kind, namespace))
+				}
+				continue
+			}
+
+			for i := range list.Items {
+				u := &list.Items[i]
+				key := k8types.NamespacedName{Namespace: u.GetNamespace(), Name: u.GetLabels()[eventListenerRouteLabel]}
+				if _, found := exposed[key]; (found && kind == exposedKind) || !cutils.IsCrossNamespaceOwner(u, assetOwner) {
+					continue
+				}
+
+				if cutils.RemoveCrossNamespaceOwner(u, assetOwner) == 0 {
+					logger.Info(fmt.Sprintf("Deleting %v %v in namespace %v", kind, u.GetName(), u.GetNamespace()))
+					err = c.Delete(ctx, u)
+				} else {
+					err = c.Update(ctx, u)
+				}
+				if err != nil && !errors.IsNotFound(err) {
+					logger.Error(err, fmt.Sprintf("Unable to remove %v %v in namespace %v", kind, u.GetName(), u.GetNamespace()))
+				}
+			}
+		}
+	}
+}
+
+// Returns true if the input status reports the URL of an EventListener.  When
+// waitingOnly is true, only EventListeners whose URL is not known yet are considered.
+func hasEventListenerStatus(status kabanerov1alpha2.StackStatus, waitingOnly bool) bool {
+	for _, version := range status.Versions {
+		for _, pipeline := range version.Pipelines {
+			for _, listener := range pipeline.EventListeners {
+				if !waitingOnly || len(listener.Url) == 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package stack
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8types "k8s.io/apimachinery/pkg/types"
+)
+
+// Test that the Route of an EventListener targets its service, and reports the admitted host.
+func TestEventListenerRouteURL(t *testing.T) {
+	eventListener := k8types.NamespacedName{Namespace: "tekton-pipelines", Name: "listener"}
+	route := newEventListenerRoute(kabanerov1alpha2.EventListenerRouteSpec{}, eventListener)
+	if route.Name != "el-listener" || route.Spec.To.Name != "el-listener" || route.Labels[eventListenerRouteLabel] != "listener" {
+		t.Fatalf("The route should target the EventListener service: %v", route)
+	}
+
+	url, message := getEventListenerRouteURL(route, "")
+	if len(url) != 0 || len(message) == 0 {
+		t.Fatalf("A route that was not admitted should have no URL, but found %v (%v)", url, message)
+	}
+
+	route.Status.Ingress = []routev1.RouteIngress{{
+		Host:       "el-listener-tekton-pipelines.apps.example.com",
+		Conditions: []routev1.RouteIngressCondition{{Type: routev1.RouteAdmitted, Status: corev1.ConditionTrue}},
+	}}
+	url, message = getEventListenerRouteURL(route, "")
+	if url != "https://el-listener-tekton-pipelines.apps.example.com" || len(message) != 0 {
+		t.Fatalf("Expected the admitted host, but found %v (%v)", url, message)
+	}
+
+	// A requested host must be the one admitted.
+	url, _ = getEventListenerRouteURL(route, "webhooks.example.com")
+	if len(url) != 0 {
+		t.Fatalf("Expected no URL for a host that was not admitted, but found %v", url)
+	}
+}
+
+// Test that the URL of an Ingress depends on its TLS configuration.
+func TestEventListenerIngressURL(t *testing.T) {
+	eventListener := k8types.NamespacedName{Namespace: "tekton-pipelines", Name: "listener"}
+	spec := kabanerov1alpha2.EventListenerRouteSpec{Kind: kabanerov1alpha2.EventListenerRouteKindIngress, Host: "webhooks.example.com"}
+
+	ingress := newEventListenerIngress(spec, eventListener)
+	if url := getEventListenerIngressURL(ingress); url != "http://webhooks.example.com" {
+		t.Fatalf("Expected an http URL, but found %v", url)
+	}
+	if ingress.Spec.Rules[0].HTTP.Paths[0].Backend.ServiceName != "el-listener" {
+		t.Fatalf("The ingress should target the EventListener service: %v", ingress)
+	}
+
+	spec.TlsSecretName = "webhooks-tls"
+	ingress = newEventListenerIngress(spec, eventListener)
+	if url := getEventListenerIngressURL(ingress); url != "https://webhooks.example.com" {
+		t.Fatalf("Expected an https URL, but found %v", url)
+	}
+}
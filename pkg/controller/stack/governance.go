@@ -0,0 +1,71 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The reason of the event recorded when versions of a stack are deactivated.
+const versionsDeactivatedReason = "VersionsDeactivated"
+
+// Returns the versions of the stack that must be deactivated because the stack has more
+// active versions than the governance policy allows, and the policy asks for the oldest
+// versions to be deactivated.
+func getSurplusVersions(policy kabanerov1alpha2.GovernancePolicyConfig, spec kabanerov1alpha2.StackSpec) []string {
+	if policy.MaxActiveVersionsPolicy != kabanerov1alpha2.MaxActiveVersionsPolicyDeactivateOldest {
+		return nil
+	}
+
+	surplus := []string{}
+	violations := sutils.CheckGovernancePolicy(policy, spec)
+	for _, version := range spec.Versions {
+		if violation, violated := violations[version.Version]; violated && violation.Reason == kabanerov1alpha2.StackReasonTooManyActiveVersions {
+			surplus = append(surplus, version.Version)
+		}
+	}
+	return surplus
+}
+
+// Sets the desired state of the oldest active versions of the stack to inactive, when the
+// stack has more active versions than the governance policy of the Kabanero instance allows.
+// The stack is updated, so a concurrent change to it fails the update, and is retried.
+func deactivateSurplusVersions(ctx context.Context, c client.Client, recorder record.EventRecorder, stackResource *kabanerov1alpha2.Stack, logger logr.Logger) error {
+	k, err := getKabaneroInstance(c, stackResource.GetNamespace())
+	if err != nil || k == nil {
+		return err
+	}
+
+	surplus := getSurplusVersions(k.Spec.GovernancePolicy, stackResource.Spec)
+	if len(surplus) == 0 {
+		return nil
+	}
+
+	for _, version := range surplus {
+		for i := range stackResource.Spec.Versions {
+			if stackResource.Spec.Versions[i].Version == version {
+				stackResource.Spec.Versions[i].DesiredState = kabanerov1alpha2.StackDesiredStateInactive
+			}
+		}
+	}
+
+	err = c.Update(ctx, stackResource)
+	if err != nil {
+		return fmt.Errorf("Unable to deactivate versions %v of stack %v: %v", strings.Join(surplus, ", "), stackResource.Spec.Name, err.Error())
+	}
+
+	message := fmt.Sprintf("Versions %v of stack %v were deactivated because the governance policy of Kabanero instance %v allows %v active versions of a stack.", strings.Join(surplus, ", "), stackResource.Spec.Name, k.Name, k.Spec.GovernancePolicy.MaxActiveVersions)
+	logger.Info(message)
+	if recorder != nil {
+		recorder.Event(stackResource, corev1.EventTypeNormal, versionsDeactivatedReason, message)
+	}
+
+	return nil
+}
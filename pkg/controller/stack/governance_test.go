@@ -0,0 +1,38 @@
+package stack
+
+import (
+	"reflect"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Test that the oldest active versions are deactivated only when the policy asks for it.
+func TestGetSurplusVersions(t *testing.T) {
+	spec := kabanerov1alpha2.StackSpec{
+		Name: "nodejs",
+		Versions: []kabanerov1alpha2.StackVersion{
+			{Version: "0.3.0"},
+			{Version: "0.1.0"},
+			{Version: "0.2.0", DesiredState: kabanerov1alpha2.StackDesiredStateInactive},
+			{Version: "0.4.0", DesiredState: kabanerov1alpha2.StackDesiredStateActive},
+		},
+	}
+
+	policy := kabanerov1alpha2.GovernancePolicyConfig{MaxActiveVersions: 1}
+	if surplus := getSurplusVersions(policy, spec); len(surplus) != 0 {
+		t.Fatalf("Expected no versions to be deactivated by the Refuse policy, but found %v", surplus)
+	}
+
+	policy.MaxActiveVersionsPolicy = kabanerov1alpha2.MaxActiveVersionsPolicyDeactivateOldest
+	expected := []string{"0.3.0", "0.1.0"}
+	if surplus := getSurplusVersions(policy, spec); !reflect.DeepEqual(surplus, expected) {
+		t.Errorf("Expected versions %v to be deactivated, but found %v", expected, surplus)
+	}
+
+	// Versions that break another rule are not active anyway, and are not deactivated.
+	policy.BannedStackIds = []string{"nodejs"}
+	if surplus := getSurplusVersions(policy, spec); len(surplus) != 0 {
+		t.Errorf("Expected no versions of a banned stack to be deactivated, but found %v", surplus)
+	}
+}
@@ -0,0 +1,186 @@
+package stack
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Annotation on a Kabanero or Stack instance that forces the stack index to be re-read
+// instead of being retrieved from the index cache.  Each new annotation value forces
+// another refresh, so a timestamp is a good choice of value.
+const RefreshIndexAnnotation = "kabanero.io/refresh-index"
+
+// The amount of time a resolved index is cached if the Kabanero instance does not say otherwise.
+const DefaultIndexCacheTTL = 5 * time.Minute
+
+// Value in the index cache map.
+type indexCacheValue struct {
+	index  Index
+	expiry time.Time
+}
+
+// Resolved indexes, keyed by repository location and a digest of the resolution inputs.
+var indexCache = make(map[string]indexCacheValue)
+
+// The last refresh annotation value processed for an instance.
+type processedRefresh struct {
+	kind      string
+	namespace string
+	value     string
+}
+
+// The last refresh annotation value processed for each Kabanero, Stack and StackHub instance.
+// Entries of deleted instances are removed by PruneIndexRefreshes.
+var processedRefreshes = make(map[types.UID]processedRefresh)
+
+// Mutex for concurrent map access
+var indexCacheLock sync.Mutex
+
+// Returns the resolved index from the index cache if it has not expired.  Otherwise the index
+// is resolved and cached for the input TTL.  A TTL of zero disables caching.  If refresh is
+// true, the index is always resolved.  The returned index is a copy that the caller may
+// modify without affecting the cache.
+func ResolveIndexUsingCache(ctx context.Context, c client.Client, repoConf kabanerov1alpha2.RepositoryConfig, namespace string, pipelines []Pipelines, triggers []Trigger, imagePrefix string, proxy *cache.ArtifactProxy, ttl time.Duration, refresh bool, reqLogger logr.Logger) (*Index, error) {
+	key, cacheable := indexCacheKey(repoConf, namespace, pipelines, triggers, imagePrefix)
+
+	// The resolver decides whether its indexes can be cached.  For example, changes to
+	// an index stored in a ConfigMap trigger a reconcile.
+	if !cacheable {
+		ttl = 0
+	}
+
+	if !refresh && ttl > 0 {
+		indexCacheLock.Lock()
+		value, found := indexCache[key]
+		indexCacheLock.Unlock()
+		if found && time.Now().Before(value.expiry) {
+			reqLogger.Info(fmt.Sprintf("Stack index for repository %v retrieved from the index cache.", repoConf.Name))
+			index := value.index.deepCopy()
+			return &index, nil
+		}
+	}
+
+	index, err := ResolveIndex(ctx, c, repoConf, namespace, pipelines, triggers, imagePrefix, proxy, reqLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	storeIndex(key, index, ttl, time.Now())
+	return index, nil
+}
+
+// Caches the index for the input key and TTL.  A TTL of zero removes the key.  The key
+// changes with the pipelines, triggers and image prefix, so expired entries are removed
+// rather than waiting for the same key to be resolved again.
+func storeIndex(key string, index *Index, ttl time.Duration, now time.Time) {
+	indexCacheLock.Lock()
+	defer indexCacheLock.Unlock()
+
+	for key, value := range indexCache {
+		if !now.Before(value.expiry) {
+			delete(indexCache, key)
+		}
+	}
+
+	if ttl > 0 {
+		indexCache[key] = indexCacheValue{index: index.deepCopy(), expiry: now.Add(ttl)}
+	} else {
+		delete(indexCache, key)
+	}
+}
+
+// Builds the index cache key, from the key of the repository's resolver.  The pipelines,
+// triggers and image prefix are applied to the index after it is read, so they are part of
+// the key.  Returns false if the resolver does not allow its indexes to be cached.
+func indexCacheKey(repoConf kabanerov1alpha2.RepositoryConfig, namespace string, pipelines []Pipelines, triggers []Trigger, imagePrefix string) (string, bool) {
+	location := repoConf.Https.Url
+	cacheable := true
+	if resolver, name, err := getIndexResolver(repoConf); err == nil {
+		location, cacheable = resolver.CacheKey(repoConf)
+		location = name + ":" + location
+	}
+
+	digest := sha256.Sum256([]byte(fmt.Sprintf("%v|%+v|%+v|%v|%v", namespace, pipelines, triggers, imagePrefix, repoConf.ResolverParameters)))
+	return location + "@" + hex.EncodeToString(digest[:]), cacheable
+}
+
+// Returns the index cache TTL configured in the Kabanero instance.
+func GetIndexCacheTTL(k *kabanerov1alpha2.Kabanero) time.Duration {
+	if k.Spec.Stacks.IndexCacheTTL == nil {
+		return DefaultIndexCacheTTL
+	}
+
+	return k.Spec.Stacks.IndexCacheTTL.Duration
+}
+
+// Returns how long an index resolved at the input time is cached.  The index expires at
+// the next scheduled refresh, if that comes before the TTL.
+func GetScheduledIndexCacheTTL(k *kabanerov1alpha2.Kabanero, now time.Time) time.Duration {
+	ttl := GetIndexCacheTTL(k)
+	if untilRefresh := TimeUntilScheduledRefresh(k, now); untilRefresh > 0 && untilRefresh < ttl {
+		return untilRefresh
+	}
+	return ttl
+}
+
+// Returns true if the input Kabanero or Stack instance has a refresh annotation value
+// that has not been processed yet.
+func IsIndexRefreshRequested(obj metav1.Object) bool {
+	value, found := obj.GetAnnotations()[RefreshIndexAnnotation]
+	if !found {
+		return false
+	}
+
+	indexCacheLock.Lock()
+	defer indexCacheLock.Unlock()
+	processed, found := processedRefreshes[obj.GetUID()]
+	return !found || processed.value != value
+}
+
+// Records that the refresh requested by the input Kabanero or Stack instance was processed.
+func IndexRefreshCompleted(obj metav1.Object) {
+	value, found := obj.GetAnnotations()[RefreshIndexAnnotation]
+	if !found {
+		return
+	}
+
+	indexCacheLock.Lock()
+	defer indexCacheLock.Unlock()
+	processedRefreshes[obj.GetUID()] = processedRefresh{kind: refreshKind(obj), namespace: obj.GetNamespace(), value: value}
+}
+
+// Forgets the processed refreshes of the instances of a kind in a namespace that are not in
+// the input set, because they were deleted.
+func PruneIndexRefreshes(kind string, namespace string, existing map[types.UID]bool) {
+	indexCacheLock.Lock()
+	defer indexCacheLock.Unlock()
+	for uid, processed := range processedRefreshes {
+		if processed.kind == kind && processed.namespace == namespace && !existing[uid] {
+			delete(processedRefreshes, uid)
+		}
+	}
+}
+
+// Returns the kind of an instance that can request a refresh.
+func refreshKind(obj metav1.Object) string {
+	switch obj.(type) {
+	case *kabanerov1alpha2.Kabanero:
+		return "Kabanero"
+	case *kabanerov1alpha2.Stack:
+		return "Stack"
+	case *kabanerov1alpha2.StackHub:
+		return "StackHub"
+	}
+	return ""
+}
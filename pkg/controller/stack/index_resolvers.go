@@ -0,0 +1,175 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IndexResolver reads the stack index of a repository.  The operator includes resolvers
+// for indexes served over HTTPS, attached to a Git release, held in an OCI image or stored
+// in a ConfigMap.  A distribution of the operator can compile in other resolvers, without
+// changing the stack controller, by registering them with RegisterIndexResolver from the
+// init function of a package imported by its main package:
+//
+//	func init() {
+//		stack.RegisterIndexResolver("vault", vaultResolver{})
+//	}
+//
+// A repository selects the resolver by name, in its resolver field, and can pass settings
+// to it in its resolverParameters field.
+type IndexResolver interface {
+	// Returns true if the repository has the settings that the resolver needs.  A
+	// repository that does not name a resolver uses the first one, in registration
+	// order, that can resolve it.
+	CanResolve(repoConf kabanerov1alpha2.RepositoryConfig) bool
+
+	// Returns the key that the stack index of the repository is cached by, or false if
+	// the index must not be cached, for example because changes to it already trigger a
+	// reconcile.
+	CacheKey(repoConf kabanerov1alpha2.RepositoryConfig) (string, bool)
+
+	// Returns the content of the stack index, in the stack index yaml format.
+	ReadIndex(ctx context.Context, request IndexRequest) ([]byte, error)
+}
+
+// IndexRequest holds what an IndexResolver needs to read the stack index of a repository.
+type IndexRequest struct {
+	// The client of the controller.  Objects are read from its cache, so resolvers
+	// should only read objects in the namespace of the request.
+	Client client.Client
+
+	// The repository, as configured in the Kabanero instance or StackHub.
+	Repository kabanerov1alpha2.RepositoryConfig
+
+	// The namespace of the Kabanero instance or StackHub.
+	Namespace string
+
+	// The proxy that downloads are routed through, or nil.
+	Proxy *cache.ArtifactProxy
+
+	Logger logr.Logger
+}
+
+// A registered resolver.
+type namedIndexResolver struct {
+	name     string
+	resolver IndexResolver
+}
+
+// The registered resolvers, in registration order.
+var indexResolvers = []namedIndexResolver{
+	{name: kabanerov1alpha2.IndexResolverGit, resolver: gitIndexResolver{}},
+	{name: kabanerov1alpha2.IndexResolverConfigMap, resolver: configMapIndexResolver{}},
+	{name: kabanerov1alpha2.IndexResolverHttps, resolver: httpsIndexResolver{}},
+	{name: kabanerov1alpha2.IndexResolverOci, resolver: ociIndexResolver{}},
+}
+
+// Mutex for concurrent access to the registered resolvers.
+var indexResolversLock sync.Mutex
+
+// Registers a stack index resolver under the input name.  Registering a resolver under
+// the name of an existing one, such as https, replaces it.
+func RegisterIndexResolver(name string, resolver IndexResolver) {
+	indexResolversLock.Lock()
+	defer indexResolversLock.Unlock()
+
+	for i := range indexResolvers {
+		if indexResolvers[i].name == name {
+			indexResolvers[i].resolver = resolver
+			return
+		}
+	}
+	indexResolvers = append(indexResolvers, namedIndexResolver{name: name, resolver: resolver})
+}
+
+// Returns the resolver of the input repository, and its name.
+func getIndexResolver(repoConf kabanerov1alpha2.RepositoryConfig) (IndexResolver, string, error) {
+	indexResolversLock.Lock()
+	defer indexResolversLock.Unlock()
+
+	for _, r := range indexResolvers {
+		if len(repoConf.Resolver) != 0 {
+			if r.name == repoConf.Resolver {
+				return r.resolver, r.name, nil
+			}
+		} else if r.resolver.CanResolve(repoConf) {
+			return r.resolver, r.name, nil
+		}
+	}
+
+	if len(repoConf.Resolver) != 0 {
+		return nil, "", fmt.Errorf("The stack index resolver %v of the repository identified as %v is not known to this operator.", repoConf.Resolver, repoConf.Name)
+	}
+	return nil, "", fmt.Errorf("No information was provided to retrieve the stack's index file from the repository identified as %v. Specify a stack repository that includes a HTTP URL location, GitHub release information, an OCI image, or a ConfigMap reference.", repoConf.Name)
+}
+
+// Reads the index attached to a Git release.
+type gitIndexResolver struct{}
+
+func (gitIndexResolver) CanResolve(repoConf kabanerov1alpha2.RepositoryConfig) bool {
+	return repoConf.GitRelease.IsUsable()
+}
+
+func (gitIndexResolver) CacheKey(repoConf kabanerov1alpha2.RepositoryConfig) (string, bool) {
+	return fmt.Sprintf("%v:%v:%v:%v:%v", repoConf.GitRelease.Hostname, repoConf.GitRelease.Organization, repoConf.GitRelease.Project, repoConf.GitRelease.Release, repoConf.GitRelease.AssetName), true
+}
+
+func (gitIndexResolver) ReadIndex(ctx context.Context, request IndexRequest) ([]byte, error) {
+	gitRelease := request.Repository.GitRelease
+	return cache.GetStackDataUsingGit(ctx, request.Client, gitReleaseSpecToGitReleaseInfo(gitRelease), gitRelease.SkipCertVerification, request.Namespace, request.Proxy, request.Logger)
+}
+
+// Reads the index from a ConfigMap in the namespace of the request.
+type configMapIndexResolver struct{}
+
+func (configMapIndexResolver) CanResolve(repoConf kabanerov1alpha2.RepositoryConfig) bool {
+	return repoConf.ConfigMapRef.IsUsable()
+}
+
+// ConfigMaps are read from the client cache, and changes to them trigger a reconcile, so
+// an index stored in a ConfigMap is never held in the index cache.
+func (configMapIndexResolver) CacheKey(repoConf kabanerov1alpha2.RepositoryConfig) (string, bool) {
+	return fmt.Sprintf("configmap:%v:%v", repoConf.ConfigMapRef.Name, repoConf.ConfigMapRef.GetKey()), false
+}
+
+func (configMapIndexResolver) ReadIndex(ctx context.Context, request IndexRequest) ([]byte, error) {
+	return getStackIndexUsingConfigMap(request.Client, request.Repository.ConfigMapRef, request.Namespace)
+}
+
+// Reads the index over HTTP, or from object storage.
+type httpsIndexResolver struct{}
+
+func (httpsIndexResolver) CanResolve(repoConf kabanerov1alpha2.RepositoryConfig) bool {
+	return len(repoConf.Https.Url) != 0
+}
+
+func (httpsIndexResolver) CacheKey(repoConf kabanerov1alpha2.RepositoryConfig) (string, bool) {
+	return repoConf.Https.Url, true
+}
+
+func (httpsIndexResolver) ReadIndex(ctx context.Context, request IndexRequest) ([]byte, error) {
+	return getStackIndexUsingHttp(ctx, request.Client, request.Repository, request.Namespace, request.Proxy, request.Logger)
+}
+
+// Reads the index from an OCI image, pulled with the credentials of the stack images.
+type ociIndexResolver struct{}
+
+func (ociIndexResolver) CanResolve(repoConf kabanerov1alpha2.RepositoryConfig) bool {
+	return repoConf.Oci.IsUsable()
+}
+
+func (ociIndexResolver) CacheKey(repoConf kabanerov1alpha2.RepositoryConfig) (string, bool) {
+	return fmt.Sprintf("oci:%v:%v", repoConf.Oci.Image, repoConf.Oci.GetPath()), true
+}
+
+func (ociIndexResolver) ReadIndex(ctx context.Context, request IndexRequest) ([]byte, error) {
+	oci := request.Repository.Oci
+	return cutils.GetImageFile(ctx, request.Client, request.Namespace, oci.Image, oci.GetPath(), oci.SkipCertVerification, request.Logger)
+}
@@ -0,0 +1,133 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/authn"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/secret"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Returns the pull secrets to try for a stack version: the version's own secrets,
+// followed by the input defaults.  Names listed more than once are only tried once.
+func getImagePullSecrets(versionSecrets []string, defaultSecrets []string) []string {
+	secrets := []string{}
+	found := make(map[string]bool)
+	for _, lists := range [][]string{versionSecrets, defaultSecrets} {
+		for _, name := range lists {
+			if len(name) == 0 || found[name] {
+				continue
+			}
+			found[name] = true
+			secrets = append(secrets, name)
+		}
+	}
+	return secrets
+}
+
+// Returns an authenticator for each of the named secrets that holds credentials
+// for the input registry, in the order the secrets were listed.  Secrets that do
+// not exist, or that hold no credentials for the registry, are skipped.
+func getPullSecretAuthenticators(c client.Reader, namespace string, secretNames []string, imgRegistry string, logr logr.Logger) ([]authn.Authenticator, error) {
+	authenticators := []authn.Authenticator{}
+	for _, secretName := range secretNames {
+		secret := &corev1.Secret{}
+		err := c.Get(context.Background(), client.ObjectKey{Name: secretName, Namespace: namespace}, secret)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				logr.Info(fmt.Sprintf("Image pull secret %v was not found in namespace %v", secretName, namespace))
+				continue
+			}
+			return nil, fmt.Errorf("Unable to retrieve image pull secret %v in namespace %v. Error: %v", secretName, namespace, err)
+		}
+
+		authenticator, err := getSecretAuth(secret, imgRegistry, logr)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the credentials in image pull secret %v in namespace %v. Error: %v", secretName, namespace, err)
+		}
+		if authenticator == authn.Anonymous {
+			continue
+		}
+
+		logr.Info(fmt.Sprintf("Secret listed for image registry %v access: %v", imgRegistry, secretName))
+		authenticators = append(authenticators, authenticator)
+	}
+
+	return authenticators, nil
+}
+
+// Returns an authenticator for the credentials in the input secret.  Returns
+// authn.Anonymous if the secret holds no credentials for the input registry.
+func getSecretAuth(secret *corev1.Secret, imgRegistry string, logr logr.Logger) (authn.Authenticator, error) {
+	username, _ := secret.Data[corev1.BasicAuthUsernameKey]
+	password, _ := secret.Data[corev1.BasicAuthPasswordKey]
+	dockerconfig, _ := secret.Data[corev1.DockerConfigKey]
+	dockerconfigjson, _ := secret.Data[corev1.DockerConfigJsonKey]
+
+	if len(username) != 0 && len(password) != 0 {
+		return getBasicSecAuth(username, password)
+	} else if len(dockerconfig) != 0 || len(dockerconfigjson) != 0 {
+		return getDockerCfgSecAuth(dockerconfigjson, dockerconfig, imgRegistry, logr)
+	}
+
+	return authn.Anonymous, nil
+}
+
+// Returns the authenticators to try, in order, for the input registry.  The named pull
+// secrets that hold credentials for the registry are tried first.  If there are none, the
+// secret annotated for the registry is used, or else a token for a cloud registry, or else
+// anonymous access.
+func GetRegistryAuthenticators(c client.Reader, namespace string, pullSecrets []string, imgRegistry string, logr logr.Logger) ([]authn.Authenticator, error) {
+	// The secrets that were listed explicitly are tried first, in order.  Several of them
+	// may hold credentials for the same registry.
+	authenticators, err := getPullSecretAuthenticators(c, namespace, pullSecrets, imgRegistry, logr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(authenticators) == 0 {
+		// Search all secrets under the given namespace for the one containing the required hostname.
+		annotationKey := "kabanero.io/docker-"
+		secret, err := secret.GetMatchingSecret(c, namespace, sutils.SecretAnnotationFilter, imgRegistry, annotationKey)
+		if err != nil {
+			newError := fmt.Errorf("Unable to find secret matching annotation values: %v and %v in namespace %v Error: %v", annotationKey, imgRegistry, namespace, err)
+			return nil, newError
+		}
+
+		// Create the authenticator mechanism to use for authentication.
+		authenticator := authn.Anonymous
+		if secret != nil {
+			logr.Info(fmt.Sprintf("Secret used for image registry access: %v. Secret annotations: %v", secret.GetName(), secret.Annotations))
+			authenticator, err = getSecretAuth(secret, imgRegistry, logr)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if authenticator == authn.Anonymous && isCloudRegistry(imgRegistry) {
+			// Cloud registries hand out short-lived tokens based on the identity of the operator pod.
+			// If no token can be obtained, try anonymous access, which works for public images.
+			cloudAuthenticator, err := getCloudRegistryAuth(imgRegistry, logr)
+			if err != nil {
+				logr.Info(fmt.Sprintf("Using anonymous access to registry %v. %v", imgRegistry, err))
+			} else {
+				authenticator = cloudAuthenticator
+			}
+		}
+
+		authenticators = append(authenticators, authenticator)
+	}
+
+	return authenticators, nil
+}
+
+// Returns the authenticators used to pull Tekton bundles from the input registry: those
+// of the secret annotated for the registry, of a cloud registry token, or anonymous access.
+func GetBundleRegistryAuthenticators(c client.Reader, namespace string, imgRegistry string, logr logr.Logger) ([]authn.Authenticator, error) {
+	return GetRegistryAuthenticators(c, namespace, nil, imgRegistry, logr)
+}
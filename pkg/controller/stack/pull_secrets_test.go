@@ -0,0 +1,84 @@
+package stack
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Unit test client that knows about a set of secrets.
+type secretTestClient struct {
+	resolverTestClient
+	secrets []corev1.Secret
+}
+
+func (c secretTestClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	s, ok := obj.(*corev1.Secret)
+	if ok {
+		for _, secret := range c.secrets {
+			if key.Name == secret.Name && key.Namespace == secret.Namespace {
+				secret.DeepCopyInto(s)
+				return nil
+			}
+		}
+	}
+	return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+// Test that the version's secrets come first, and that names are not repeated.
+func TestGetImagePullSecrets(t *testing.T) {
+	secrets := getImagePullSecrets([]string{"a", "b"}, []string{"b", "", "c"})
+	if !reflect.DeepEqual(secrets, []string{"a", "b", "c"}) {
+		t.Fatalf("Expected secrets [a b c], but found %v", secrets)
+	}
+
+	secrets = getImagePullSecrets(nil, nil)
+	if len(secrets) != 0 {
+		t.Fatalf("Expected no secrets, but found %v", secrets)
+	}
+}
+
+// Test that only the secrets that hold credentials for the registry are used, in order.
+func TestGetPullSecretAuthenticators(t *testing.T) {
+	c := secretTestClient{secrets: []corev1.Secret{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-registry", Namespace: "kabanero"},
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"quay.io":{"auth":"b3RoZXI6c2VjcmV0"}}}`)},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "docker-config", Namespace: "kabanero"},
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"auth":"ZG9ja2VyOnNlY3JldA=="}}}`)},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "basic-auth", Namespace: "kabanero"},
+			Data:       map[string][]byte{corev1.BasicAuthUsernameKey: []byte("basic"), corev1.BasicAuthPasswordKey: []byte("secret")},
+		},
+	}}
+
+	secretNames := []string{"missing", "other-registry", "basic-auth", "docker-config"}
+	authenticators, err := getPullSecretAuthenticators(c, "kabanero", secretNames, "registry.example.com", sctlog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(authenticators) != 2 {
+		t.Fatalf("Expected 2 authenticators, but found %v", len(authenticators))
+	}
+
+	for i, expectedUser := range []string{"basic", "docker"} {
+		authConfig, err := authenticators[i].Authorization()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if authConfig.Username != expectedUser {
+			t.Errorf("Expected authenticator %v to use the credentials of %v, but found %v", i, expectedUser, authConfig)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package stack
+
+import (
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+)
+
+// Returns the schedule on which the repository indexes are re-read and the image tags
+// of active stack versions are checked, or nil if the Kabanero instance has none.  An
+// expression that cannot be parsed is rejected by the webhook, and ignored here.
+func GetRefreshSchedule(k *kabanerov1alpha2.Kabanero) *timer.CronSchedule {
+	if k == nil || len(k.Spec.Stacks.RefreshSchedule) == 0 {
+		return nil
+	}
+	schedule, err := timer.ParseCronSchedule(k.Spec.Stacks.RefreshSchedule)
+	if err != nil {
+		return nil
+	}
+	return schedule
+}
+
+// Returns how long after the input time the next scheduled refresh is, or zero if there
+// is no schedule.
+func TimeUntilScheduledRefresh(k *kabanerov1alpha2.Kabanero, now time.Time) time.Duration {
+	schedule := GetRefreshSchedule(k)
+	if schedule == nil {
+		return 0
+	}
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return 0
+	}
+	return next.Sub(now)
+}
+
+// Returns true if a scheduled refresh was due between the two input times.
+func isScheduledRefreshDue(schedule *timer.CronSchedule, since time.Time, now time.Time) bool {
+	if schedule == nil {
+		return false
+	}
+	next := schedule.Next(since)
+	return !next.IsZero() && !next.After(now)
+}
+
+// Returns the shorter of two requeue delays, where zero means no requeue.
+func shorterRequeue(a time.Duration, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,60 @@
+package stack
+
+import (
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that cached indexes expire at the next scheduled refresh.
+func TestScheduledIndexCacheTTL(t *testing.T) {
+	now := time.Date(2020, 5, 1, 1, 30, 0, 0, time.UTC)
+	k := &kabanerov1alpha2.Kabanero{
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Stacks: kabanerov1alpha2.StacksSpec{
+				IndexCacheTTL:   &metav1.Duration{Duration: 24 * time.Hour},
+				RefreshSchedule: "0 2 * * *",
+			},
+		},
+	}
+
+	if until := TimeUntilScheduledRefresh(k, now); until != 30*time.Minute {
+		t.Fatalf("Expected the next refresh in 30m, but found %v", until)
+	}
+	if ttl := GetScheduledIndexCacheTTL(k, now); ttl != 30*time.Minute {
+		t.Fatalf("Expected the index to be cached for 30m, but found %v", ttl)
+	}
+
+	// A TTL shorter than the time until the refresh is kept.
+	k.Spec.Stacks.IndexCacheTTL = &metav1.Duration{Duration: 10 * time.Minute}
+	if ttl := GetScheduledIndexCacheTTL(k, now); ttl != 10*time.Minute {
+		t.Fatalf("Expected the index to be cached for 10m, but found %v", ttl)
+	}
+
+	// Caching stays disabled.
+	k.Spec.Stacks.IndexCacheTTL = &metav1.Duration{Duration: 0}
+	if ttl := GetScheduledIndexCacheTTL(k, now); ttl != 0 {
+		t.Fatalf("Expected the index not to be cached, but found %v", ttl)
+	}
+
+	// No schedule.
+	k.Spec.Stacks.RefreshSchedule = ""
+	if until := TimeUntilScheduledRefresh(k, now); until != 0 {
+		t.Fatalf("Expected no scheduled refresh, but found %v", until)
+	}
+}
+
+// Test the requeue delay when both an interval and a schedule are set.
+func TestShorterRequeue(t *testing.T) {
+	if d := shorterRequeue(time.Hour, 0); d != time.Hour {
+		t.Fatalf("Expected 1h, but found %v", d)
+	}
+	if d := shorterRequeue(0, time.Minute); d != time.Minute {
+		t.Fatalf("Expected 1m, but found %v", d)
+	}
+	if d := shorterRequeue(time.Hour, time.Minute); d != time.Minute {
+		t.Fatalf("Expected 1m, but found %v", d)
+	}
+}
@@ -2,5 +2,5 @@ package stack
 
 func init() {
 	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
-	AddToManagerFuncs = append(AddToManagerFuncs, Add)
+	AddToManagerFuncs = append(AddToManagerFuncs, Add, addRunRetention)
 }
@@ -0,0 +1,42 @@
+package stack
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Returns the certificates trusted when connecting to image registries: the system
+// certificates, plus the certificates in the input ConfigMap.  Returns nil if no
+// ConfigMap is specified, in which case the default trust applies.
+func getRegistryRootCAs(c client.Reader, namespace string, configMapName string) (*x509.CertPool, error) {
+	if len(configMapName) == 0 {
+		return nil, nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := c.Get(context.Background(), client.ObjectKey{Name: configMapName, Namespace: namespace}, cm)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the registry CA bundle ConfigMap %v. Namespace: %v. Error: %v", configMapName, namespace, err)
+	}
+
+	bundle, found := cm.Data[kabanerov1alpha2.RegistryCABundleKey]
+	if !found {
+		return nil, fmt.Errorf("The %v entry was not found in the registry CA bundle ConfigMap %v. Namespace: %v", kabanerov1alpha2.RegistryCABundleKey, configMapName, namespace)
+	}
+
+	certPool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+
+	if !certPool.AppendCertsFromPEM([]byte(bundle)) {
+		return nil, fmt.Errorf("No certificates could be read from the %v entry of the registry CA bundle ConfigMap %v. Namespace: %v", kabanerov1alpha2.RegistryCABundleKey, configMapName, namespace)
+	}
+
+	return certPool, nil
+}
@@ -0,0 +1,60 @@
+package stack
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that the certificates in the CA bundle ConfigMap are trusted.
+func TestGetRegistryRootCAs(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	c := configMapTestClient{configMap: corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-ca", Namespace: "kabanero"},
+		Data:       map[string]string{kabanerov1alpha2.RegistryCABundleKey: string(bundle)},
+	}}
+
+	rootCAs, err := getRegistryRootCAs(c, "kabanero", "registry-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: rootCAs}}}
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the server certificate to be trusted: %v", err)
+	}
+	resp.Body.Close()
+}
+
+// Test that no ConfigMap means the default trust, and that a bad ConfigMap is an error.
+func TestGetRegistryRootCAsErrors(t *testing.T) {
+	c := configMapTestClient{configMap: corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-ca", Namespace: "kabanero"},
+		Data:       map[string]string{kabanerov1alpha2.RegistryCABundleKey: "not a certificate"},
+	}}
+
+	rootCAs, err := getRegistryRootCAs(c, "kabanero", "")
+	if err != nil || rootCAs != nil {
+		t.Fatalf("Expected no certificates and no error, but found %v and %v", rootCAs, err)
+	}
+
+	_, err = getRegistryRootCAs(c, "kabanero", "missing")
+	if err == nil {
+		t.Fatal("Expected an error for a missing ConfigMap")
+	}
+
+	_, err = getRegistryRootCAs(c, "kabanero", "registry-ca")
+	if err == nil {
+		t.Fatal("Expected an error for a ConfigMap without certificates")
+	}
+}
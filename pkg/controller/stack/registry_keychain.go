@@ -0,0 +1,122 @@
+package stack
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	ecr "github.com/awslabs/amazon-ecr-credential-helper/ecr-login"
+	"github.com/awslabs/amazon-ecr-credential-helper/ecr-login/api"
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/google"
+)
+
+// Cloud registries that hand out short-lived tokens in exchange for the identity of the
+// workload (AWS ECR through IAM roles for service accounts, Google Container Registry and
+// Artifact Registry through workload identity).  When no secret provides credentials for
+// one of these registries, a token is requested using the identity of the operator pod.
+
+// Matches an ECR registry host name.
+var ecrRegistryRegex = regexp.MustCompile(`^[0-9]+\.dkr\.ecr(-fips)?\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+
+// Matches a GCR or Artifact Registry host name.
+var gcrRegistryRegex = regexp.MustCompile(`^(gcr\.io|[a-z]+\.gcr\.io|[a-z0-9-]+-docker\.pkg\.dev)$`)
+
+// Returns the user name and password for a registry host.  Implemented by the ECR credential helper.
+type registryCredentialGetter interface {
+	Get(serverURL string) (string, string, error)
+}
+
+// The credential sources of the cloud registries.  Variables so that tests can replace them.
+var gcrKeychain authn.Keychain = google.Keychain
+var ecrCredentials registryCredentialGetter = ecr.ECRHelper{ClientFactory: api.DefaultClientFactory{}}
+
+// How long the authenticator obtained for a registry is reused.  The Google authenticators
+// refresh their own tokens.  The ECR authenticator holds a static token, which is valid for
+// 12 hours, so it must be replaced well before then.  The TTL also bounds how long a revoked
+// identity goes unnoticed.
+var cloudAuthTTL = 30 * time.Minute
+
+// How long a failure to obtain credentials for a registry is remembered, so that a pod
+// without a cloud identity does not ask for one on every reconcile.
+var cloudAuthFailureTTL = 5 * time.Minute
+
+type cloudAuth struct {
+	authenticator authn.Authenticator
+	err           error
+	expiresAt     time.Time
+}
+
+// Authenticators, or failures, obtained for each registry host.
+var cloudAuthCache = struct {
+	sync.Mutex
+	entries map[string]cloudAuth
+}{entries: make(map[string]cloudAuth)}
+
+// Returns true if the input registry hands out tokens based on the workload identity.
+func isCloudRegistry(imgRegistry string) bool {
+	return ecrRegistryRegex.MatchString(imgRegistry) || gcrRegistryRegex.MatchString(imgRegistry)
+}
+
+// Returns an authenticator holding a token for the input cloud registry.  Anonymous
+// authentication is returned if the registry is not a cloud registry.
+func getCloudRegistryAuth(imgRegistry string, reqLogger logr.Logger) (authn.Authenticator, error) {
+	if !isCloudRegistry(imgRegistry) {
+		return authn.Anonymous, nil
+	}
+
+	cloudAuthCache.Lock()
+	entry, found := cloudAuthCache.entries[imgRegistry]
+	cloudAuthCache.Unlock()
+	if found && time.Now().Before(entry.expiresAt) {
+		return entry.authenticator, entry.err
+	}
+
+	// The credentials are obtained outside of the lock, so that the digest lookups of other
+	// registries are not held up.  Concurrent lookups of the same registry may each ask for
+	// credentials, and the last one obtained is kept.
+	entry = cloudAuth{}
+	entry.authenticator, entry.err = resolveCloudRegistryAuth(imgRegistry)
+	if entry.err != nil {
+		entry.err = fmt.Errorf("Unable to obtain a token for registry %v: %v", imgRegistry, entry.err)
+		entry.expiresAt = time.Now().Add(cloudAuthFailureTTL)
+	} else {
+		reqLogger.Info(fmt.Sprintf("Obtained credentials for registry %v", imgRegistry))
+		entry.expiresAt = time.Now().Add(cloudAuthTTL)
+	}
+
+	cloudAuthCache.Lock()
+	cloudAuthCache.entries[imgRegistry] = entry
+	cloudAuthCache.Unlock()
+
+	return entry.authenticator, entry.err
+}
+
+// Asks the credential source of the input cloud registry for an authenticator.
+func resolveCloudRegistryAuth(imgRegistry string) (authn.Authenticator, error) {
+	if ecrRegistryRegex.MatchString(imgRegistry) {
+		username, password, err := ecrCredentials.Get(imgRegistry)
+		if err != nil {
+			return nil, err
+		}
+		return authn.FromConfig(authn.AuthConfig{Username: username, Password: password}), nil
+	}
+
+	registry, err := name.NewRegistry(imgRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	// The keychain falls back to anonymous access when no Google identity is available.
+	authenticator, err := gcrKeychain.Resolve(registry)
+	if err != nil {
+		return nil, err
+	}
+	if authenticator == authn.Anonymous {
+		return nil, fmt.Errorf("No Google identity is available")
+	}
+	return authenticator, nil
+}
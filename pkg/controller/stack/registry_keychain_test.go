@@ -0,0 +1,180 @@
+package stack
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// Tests which registry host names are recognized as cloud registries.
+func TestIsCloudRegistry(t *testing.T) {
+	cloudRegistries := []string{"123456789012.dkr.ecr.us-east-1.amazonaws.com", "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", "gcr.io", "eu.gcr.io", "us-central1-docker.pkg.dev"}
+	for _, r := range cloudRegistries {
+		if !isCloudRegistry(r) {
+			t.Errorf("Expected %v to be a cloud registry", r)
+		}
+	}
+
+	otherRegistries := []string{"docker.io", "quay.io", "image-registry.openshift-image-registry.svc:5000", "gcr.io.example.com", "dkr.ecr.us-east-1.amazonaws.com"}
+	for _, r := range otherRegistries {
+		if isCloudRegistry(r) {
+			t.Errorf("Expected %v not to be a cloud registry", r)
+		}
+	}
+}
+
+// Keychain that counts its lookups, and returns the input authenticator.
+type countingKeychain struct {
+	authenticator authn.Authenticator
+	lookups       *int
+}
+
+func (k countingKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	*k.lookups++
+	return k.authenticator, nil
+}
+
+// Credential getter that counts its lookups, and returns the input credentials or error.
+type countingCredentialGetter struct {
+	username string
+	password string
+	err      error
+	lookups  *int
+}
+
+func (g countingCredentialGetter) Get(serverURL string) (string, string, error) {
+	*g.lookups++
+	return g.username, g.password, g.err
+}
+
+func resetCloudAuthCache() {
+	cloudAuthCache.Lock()
+	cloudAuthCache.entries = make(map[string]cloudAuth)
+	cloudAuthCache.Unlock()
+}
+
+// Tests that GCR credentials come from the Google keychain, and are cached.
+func TestGetCloudRegistryAuthGcr(t *testing.T) {
+	defer func(k authn.Keychain) { gcrKeychain = k }(gcrKeychain)
+	resetCloudAuthCache()
+	defer resetCloudAuthCache()
+
+	lookups := 0
+	gcrKeychain = countingKeychain{authenticator: authn.FromConfig(authn.AuthConfig{Username: "oauth2accesstoken", Password: "gcr-token"}), lookups: &lookups}
+
+	for i := 0; i < 2; i++ {
+		auth, err := getCloudRegistryAuth("gcr.io", sctlog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := auth.Authorization()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Password != "gcr-token" {
+			t.Fatalf("Unexpected GCR credentials: %v", cfg)
+		}
+	}
+
+	if lookups != 1 {
+		t.Fatalf("Expected the GCR credentials to be looked up once, but they were looked up %v times", lookups)
+	}
+}
+
+// Tests that an anonymous authenticator from the Google keychain is reported as a failure.
+func TestGetCloudRegistryAuthGcrNoIdentity(t *testing.T) {
+	defer func(k authn.Keychain) { gcrKeychain = k }(gcrKeychain)
+	resetCloudAuthCache()
+	defer resetCloudAuthCache()
+
+	lookups := 0
+	gcrKeychain = countingKeychain{authenticator: authn.Anonymous, lookups: &lookups}
+
+	if _, err := getCloudRegistryAuth("us-central1-docker.pkg.dev", sctlog); err == nil {
+		t.Fatal("Expected an error when no Google identity is available")
+	}
+}
+
+// Tests that ECR credentials come from the ECR credential helper, and that failures are
+// remembered until they expire.
+func TestGetCloudRegistryAuthEcr(t *testing.T) {
+	defer func(g registryCredentialGetter) { ecrCredentials = g }(ecrCredentials)
+	resetCloudAuthCache()
+	defer resetCloudAuthCache()
+
+	registry := "123456789012.dkr.ecr.us-west-2.amazonaws.com"
+	lookups := 0
+	ecrCredentials = countingCredentialGetter{err: fmt.Errorf("no credentials"), lookups: &lookups}
+
+	for i := 0; i < 2; i++ {
+		if _, err := getCloudRegistryAuth(registry, sctlog); err == nil {
+			t.Fatal("Expected an error when the ECR credential helper fails")
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("Expected the failed lookup to be cached, but the ECR credentials were looked up %v times", lookups)
+	}
+
+	// Once the failure expires, the credentials are looked up again.
+	cloudAuthCache.Lock()
+	entry := cloudAuthCache.entries[registry]
+	entry.expiresAt = time.Now().Add(-time.Second)
+	cloudAuthCache.entries[registry] = entry
+	cloudAuthCache.Unlock()
+
+	ecrCredentials = countingCredentialGetter{username: "AWS", password: "ecr-password", lookups: &lookups}
+	auth, err := getCloudRegistryAuth(registry, sctlog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := auth.Authorization()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Username != "AWS" || cfg.Password != "ecr-password" {
+		t.Fatalf("Unexpected ECR credentials: %v", cfg)
+	}
+	if lookups != 2 {
+		t.Fatalf("Expected the ECR credentials to be looked up again after the failure expired, but they were looked up %v times", lookups)
+	}
+}
+
+// Credential getter that reports whether the cloud credential cache could be locked while
+// credentials were being obtained.
+type lockCheckingCredentialGetter struct {
+	locked *bool
+}
+
+func (g lockCheckingCredentialGetter) Get(serverURL string) (string, string, error) {
+	done := make(chan struct{})
+	go func() {
+		cloudAuthCache.Lock()
+		cloudAuthCache.Unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+		*g.locked = true
+	case <-time.After(5 * time.Second):
+	}
+	return "AWS", "ecr-password", nil
+}
+
+// Tests that the credential cache is not locked while credentials are obtained, so that
+// the digest lookups of other registries are not held up.
+func TestGetCloudRegistryAuthUnlocked(t *testing.T) {
+	defer func(g registryCredentialGetter) { ecrCredentials = g }(ecrCredentials)
+	resetCloudAuthCache()
+	defer resetCloudAuthCache()
+
+	locked := false
+	ecrCredentials = lockCheckingCredentialGetter{locked: &locked}
+	if _, err := getCloudRegistryAuth("123456789012.dkr.ecr.us-west-2.amazonaws.com", sctlog); err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Fatal("Expected the credential cache to be unlocked while the ECR credentials were obtained")
+	}
+}
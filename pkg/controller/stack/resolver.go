@@ -1,42 +1,44 @@
 package stack
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // ResolveIndex returns a structure representation of the yaml file represented by the index.
-func ResolveIndex(c client.Client, repoConf kabanerov1alpha2.RepositoryConfig, namespace string, pipelines []Pipelines, triggers []Trigger, imagePrefix string, reqLogger logr.Logger) (*Index, error) {
-	var indexBytes []byte
-
-	switch {
-	// GIT:
-	case repoConf.GitRelease.IsUsable():
-		bytes, err := cache.GetStackDataUsingGit(c, gitReleaseSpecToGitReleaseInfo(repoConf.GitRelease), repoConf.GitRelease.SkipCertVerification, namespace, reqLogger)
-		if err != nil {
-			return nil, err
-		}
-		indexBytes = bytes
-	// HTTPS:
-	case len(repoConf.Https.Url) != 0:
-		bytes, err := getStackIndexUsingHttp(c, repoConf)
-		if err != nil {
-			return nil, err
-		}
-		indexBytes = bytes
-	// NOT SUPPORTED:
-	default:
-		return nil, fmt.Errorf("No information was provided to retrieve the stack's index file from the repository identified as %v. Specify a stack repository that includes a HTTP URL location or GitHub release information.", repoConf.Name)
+// The index is read by the resolver that the repository names, or otherwise by the first
+// registered resolver that can resolve it.  If a proxy is specified, the index is retrieved
+// through it.  The download is abandoned when the input context is done.
+func ResolveIndex(ctx context.Context, c client.Client, repoConf kabanerov1alpha2.RepositoryConfig, namespace string, pipelines []Pipelines, triggers []Trigger, imagePrefix string, proxy *cache.ArtifactProxy, reqLogger logr.Logger) (*Index, error) {
+	ctx, span := cutils.StartSpan(ctx, "ResolveIndex", attribute.String("repository.name", repoConf.Name))
+	index, err := resolveIndex(ctx, c, repoConf, namespace, pipelines, triggers, imagePrefix, proxy, reqLogger)
+	cutils.EndSpan(span, err)
+	return index, err
+}
+
+func resolveIndex(ctx context.Context, c client.Client, repoConf kabanerov1alpha2.RepositoryConfig, namespace string, pipelines []Pipelines, triggers []Trigger, imagePrefix string, proxy *cache.ArtifactProxy, reqLogger logr.Logger) (*Index, error) {
+	resolver, _, err := getIndexResolver(repoConf)
+	if err != nil {
+		return nil, err
+	}
+
+	indexBytes, err := resolver.ReadIndex(ctx, IndexRequest{Client: c, Repository: repoConf, Namespace: namespace, Proxy: proxy, Logger: reqLogger})
+	if err != nil {
+		return nil, err
 	}
 
 	var index Index
-	err := yaml.Unmarshal(indexBytes, &index)
+	err = yaml.Unmarshal(indexBytes, &index)
 	if err != nil {
 		return nil, err
 	}
@@ -116,8 +118,9 @@ func SearchStack(stackName string, index *Index) ([]Stack, error) {
 	return stackRefs, nil
 }
 
-// Retrieves a stack index file content using HTTP.
-func getStackIndexUsingHttp(c client.Client, repoConf kabanerov1alpha2.RepositoryConfig) ([]byte, error) {
+// Retrieves a stack index file content using HTTP, or from object storage if the URL
+// uses the s3, gs or azblob scheme.
+func getStackIndexUsingHttp(ctx context.Context, c client.Client, repoConf kabanerov1alpha2.RepositoryConfig, namespace string, proxy *cache.ArtifactProxy, reqLogger logr.Logger) ([]byte, error) {
 	url := repoConf.Https.Url
 
 	// user may specify url to yaml file or directory
@@ -129,5 +132,24 @@ func getStackIndexUsingHttp(c client.Client, repoConf kabanerov1alpha2.Repositor
 		url = url + "/index.yaml"
 	}
 
-	return cache.GetFromCache(c, url, repoConf.Https.SkipCertVerification)
+	if cache.IsObjectStorageUrl(url) {
+		return cache.GetFromObjectStorage(ctx, c, namespace, url, repoConf.Https.SkipCertVerification, proxy, reqLogger)
+	}
+	return cache.GetFromCache(ctx, c, url, repoConf.Https.SkipCertVerification, proxy)
+}
+
+// Retrieves a stack index file content from a ConfigMap in the input namespace.
+func getStackIndexUsingConfigMap(c client.Client, ref kabanerov1alpha2.ConfigMapReference, namespace string) ([]byte, error) {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(context.Background(), client.ObjectKey{Name: ref.Name, Namespace: namespace}, cm)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the stack index ConfigMap %v. Namespace: %v. Error: %v", ref.Name, namespace, err)
+	}
+
+	index, found := cm.Data[ref.GetKey()]
+	if !found {
+		return nil, fmt.Errorf("The %v entry was not found in the stack index ConfigMap %v. Namespace: %v", ref.GetKey(), ref.Name, namespace)
+	}
+
+	return []byte(index), nil
 }
@@ -3,12 +3,19 @@ package stack
 import (
 	"context"
 	"errors"
-	"net/http/httptest"
+	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/testutils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -43,7 +50,7 @@ var resolverTestLogger logr.Logger = log.WithValues("Request.Namespace", "test",
 
 func TestResolveIndex(t *testing.T) {
 	// The server that will host the stack hub index
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
 	repoConfig := kabanerov1alpha2.RepositoryConfig{
@@ -54,7 +61,7 @@ func TestResolveIndex(t *testing.T) {
 		},
 	}
 
-	index, err := ResolveIndex(resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", resolverTestLogger)
+	index, err := ResolveIndex(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, resolverTestLogger)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -68,9 +75,256 @@ func TestResolveIndex(t *testing.T) {
 	}
 }
 
+// Unit test client that knows about a single ConfigMap.
+type configMapTestClient struct {
+	resolverTestClient
+	configMap corev1.ConfigMap
+}
+
+func (c configMapTestClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || key.Name != c.configMap.Name || key.Namespace != c.configMap.Namespace {
+		return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	c.configMap.DeepCopyInto(cm)
+	return nil
+}
+
+// Test that an index is read from a ConfigMap.
+func TestResolveIndexUsingConfigMap(t *testing.T) {
+	indexBytes, err := ioutil.ReadFile("testdata/incubator-index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := configMapTestClient{configMap: corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack-index", Namespace: "kabanero"},
+		Data:       map[string]string{"index.yaml": string(indexBytes), "other.yaml": string(indexBytes)},
+	}}
+
+	repoConfig := kabanerov1alpha2.RepositoryConfig{
+		Name:         "name",
+		ConfigMapRef: kabanerov1alpha2.ConfigMapReference{Name: "stack-index"},
+	}
+
+	index, err := ResolveIndex(context.Background(), c, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, resolverTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Stacks) == 0 {
+		t.Fatal("Expected stacks in the index read from the ConfigMap")
+	}
+
+	// A key other than the default.
+	repoConfig.ConfigMapRef.Key = "other.yaml"
+	_, err = ResolveIndex(context.Background(), c, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, resolverTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A key that does not exist.
+	repoConfig.ConfigMapRef.Key = "missing.yaml"
+	_, err = ResolveIndex(context.Background(), c, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, resolverTestLogger)
+	if err == nil {
+		t.Fatal("Expected an error for a missing ConfigMap key")
+	}
+
+	// A ConfigMap in another namespace is not found.
+	repoConfig.ConfigMapRef.Key = ""
+	_, err = ResolveIndex(context.Background(), c, repoConfig, "other-namespace", []Pipelines{}, []Trigger{}, "", nil, resolverTestLogger)
+	if err == nil {
+		t.Fatal("Expected an error for a ConfigMap in another namespace")
+	}
+}
+
+// Test that a resolved index is served from the index cache until a refresh is requested.
+func TestResolveIndexUsingCache(t *testing.T) {
+	// The server that will host the stack hub index
+	server := testutils.NewServer("testdata")
+
+	repoConfig := kabanerov1alpha2.RepositoryConfig{
+		Name: "name",
+		Https: kabanerov1alpha2.HttpsProtocolFile{
+			Url:                  server.URL + "/incubator-index-collections.yaml",
+			SkipCertVerification: true,
+		},
+	}
+
+	index, err := ResolveIndexUsingCache(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, time.Minute, false, resolverTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the server gone, the index must come from the cache.
+	server.Close()
+	index, err = ResolveIndexUsingCache(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, time.Minute, false, resolverTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if index.APIVersion != "v2" {
+		t.Fatal("Expected apiVersion == v2")
+	}
+
+	// Changes to the returned index must not reach the cache.
+	if len(index.Stacks) == 0 || len(index.Stacks[0].Images) == 0 {
+		t.Fatalf("Expected stacks with images in the index, but found %v", index.Stacks)
+	}
+	image := index.Stacks[0].Images[0].Image
+	index.Stacks[0].Images[0].Image = "modified"
+	index.Stacks = index.Stacks[1:]
+	index, err = ResolveIndexUsingCache(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, time.Minute, false, resolverTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Stacks[0].Images[0].Image != image {
+		t.Errorf("Expected the cached image %v, but found %v", image, index.Stacks[0].Images[0].Image)
+	}
+
+	// A refresh must go to the server.
+	index, err = ResolveIndexUsingCache(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, time.Minute, true, resolverTestLogger)
+	if err == nil {
+		t.Fatal("Expected an error refreshing the index from a server that is gone")
+	}
+
+	// A TTL of zero disables the cache.
+	index, err = ResolveIndexUsingCache(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, 0, false, resolverTestLogger)
+	if err == nil {
+		t.Fatal("Expected an error reading the index from a server that is gone")
+	}
+}
+
+// Test that expired indexes are removed from the cache when another index is cached.
+func TestStoreIndexPrunesExpiredEntries(t *testing.T) {
+	indexCacheLock.Lock()
+	indexCache = make(map[string]indexCacheValue)
+	indexCacheLock.Unlock()
+
+	now := time.Now()
+	index := &Index{APIVersion: "v2"}
+	storeIndex("expired", index, time.Minute, now)
+	storeIndex("current", index, time.Hour, now)
+	storeIndex("new", index, time.Minute, now.Add(2*time.Minute))
+
+	indexCacheLock.Lock()
+	defer indexCacheLock.Unlock()
+	if _, found := indexCache["expired"]; found {
+		t.Error("Expected the expired index to be removed")
+	}
+	if _, found := indexCache["current"]; !found {
+		t.Error("Expected the index that has not expired to be kept")
+	}
+	if _, found := indexCache["new"]; !found {
+		t.Error("Expected the new index to be cached")
+	}
+}
+
+// A resolver that serves the index in its parameters.
+type parameterIndexResolver struct{}
+
+func (parameterIndexResolver) CanResolve(repoConf kabanerov1alpha2.RepositoryConfig) bool {
+	return false
+}
+
+func (parameterIndexResolver) CacheKey(repoConf kabanerov1alpha2.RepositoryConfig) (string, bool) {
+	return repoConf.Name, false
+}
+
+func (parameterIndexResolver) ReadIndex(ctx context.Context, request IndexRequest) ([]byte, error) {
+	return []byte(request.Repository.ResolverParameters["index"]), nil
+}
+
+// Test that a repository can name a registered resolver.
+func TestResolveIndexUsingRegisteredResolver(t *testing.T) {
+	indexBytes, err := ioutil.ReadFile("testdata/incubator-index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repoConfig := kabanerov1alpha2.RepositoryConfig{
+		Name:               "name",
+		Resolver:           "parameters",
+		ResolverParameters: map[string]string{"index": string(indexBytes)},
+	}
+
+	_, err = ResolveIndex(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, resolverTestLogger)
+	if err == nil {
+		t.Fatal("Expected an error for a resolver that is not registered")
+	}
+
+	RegisterIndexResolver("parameters", parameterIndexResolver{})
+	defer func() {
+		indexResolversLock.Lock()
+		indexResolvers = indexResolvers[:len(indexResolvers)-1]
+		indexResolversLock.Unlock()
+	}()
+
+	index, err := ResolveIndex(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, resolverTestLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Stacks) == 0 {
+		t.Fatal("Expected stacks in the index read by the registered resolver")
+	}
+
+	// The resolver is only used when it is named.
+	repoConfig.Resolver = ""
+	_, err = ResolveIndex(context.Background(), resolverTestClient{}, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "", nil, resolverTestLogger)
+	if err == nil {
+		t.Fatal("Expected an error for a repository without a location")
+	}
+}
+
+// Test that each refresh annotation value is processed once.
+func TestIsIndexRefreshRequested(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{UID: "refresh-test"}}
+	if IsIndexRefreshRequested(k) {
+		t.Fatal("A refresh should not be requested without the annotation")
+	}
+
+	k.SetAnnotations(map[string]string{RefreshIndexAnnotation: "1"})
+	if !IsIndexRefreshRequested(k) {
+		t.Fatal("A refresh should be requested by the annotation")
+	}
+
+	IndexRefreshCompleted(k)
+	if IsIndexRefreshRequested(k) {
+		t.Fatal("A refresh should not be requested after it was completed")
+	}
+
+	k.SetAnnotations(map[string]string{RefreshIndexAnnotation: "2"})
+	if !IsIndexRefreshRequested(k) {
+		t.Fatal("A refresh should be requested by a new annotation value")
+	}
+}
+
+// Test that only the processed refreshes of deleted instances of the same kind and
+// namespace are forgotten.
+func TestPruneIndexRefreshes(t *testing.T) {
+	annotations := map[string]string{RefreshIndexAnnotation: "1"}
+	kept := &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{UID: "prune-kept", Namespace: "kabanero", Annotations: annotations}}
+	deleted := &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{UID: "prune-deleted", Namespace: "kabanero", Annotations: annotations}}
+	other := &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{UID: "prune-other", Namespace: "other", Annotations: annotations}}
+	hub := &kabanerov1alpha2.StackHub{ObjectMeta: metav1.ObjectMeta{UID: "prune-hub", Namespace: "kabanero", Annotations: annotations}}
+	for _, obj := range []metav1.Object{kept, deleted, other, hub} {
+		IndexRefreshCompleted(obj)
+	}
+
+	PruneIndexRefreshes("Stack", "kabanero", map[types.UID]bool{kept.UID: true})
+
+	if !IsIndexRefreshRequested(deleted) {
+		t.Fatal("The refresh of the deleted stack should have been forgotten")
+	}
+	for _, obj := range []metav1.Object{kept, other, hub} {
+		if IsIndexRefreshRequested(obj) {
+			t.Fatalf("The refresh of %v should not have been forgotten", obj.GetUID())
+		}
+	}
+}
+
 func TestResolveIndexForStacks(t *testing.T) {
 	// The server that will host the stack hub index
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
 	repoConfig := kabanerov1alpha2.RepositoryConfig{
@@ -80,7 +334,7 @@ func TestResolveIndexForStacks(t *testing.T) {
 
 	pipelines := []Pipelines{{Id: "testPipeline", Sha256: "513090b303ba8711c93ab1e2eacc66769086e0e18fe11a10140aaf6a70c8be78", Url: server.URL + "/0.5.0-rc.2/incubator.common.pipeline.default.tar.gz"}}
 	triggers := []Trigger{{Id: "testTrigger", Sha256: "9b11091f295fb6706a8dbca62f57adf26b55d6f35eb0d5b0988129db91d295c0", Url: server.URL + "/0.5.0-rc.2/incubator.trigger.tar.gz"}}
-	index, err := ResolveIndex(resolverTestClient{}, repoConfig, "kabanero", pipelines, triggers, "kabanerobeta", resolverTestLogger)
+	index, err := ResolveIndex(context.Background(), resolverTestClient{}, repoConfig, "kabanero", pipelines, triggers, "kabanerobeta", nil, resolverTestLogger)
 
 	if err != nil {
 		t.Fatal(err)
@@ -139,7 +393,7 @@ func TestResolveIndexForStacks(t *testing.T) {
 // the Kabanero CR instance yaml.
 func TestResolveIndexForStacksInPublicGitFailure1(t *testing.T) {
 	// The server that will host the stack hub index
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
 	repoConfig := kabanerov1alpha2.RepositoryConfig{
@@ -148,7 +402,7 @@ func TestResolveIndexForStacksInPublicGitFailure1(t *testing.T) {
 
 	pipelines := []Pipelines{{Id: "testPipeline", Sha256: "513090b303ba8711c93ab1e2eacc66769086e0e18fe11a10140aaf6a70c8be78", Url: server.URL + "/0.5.0-rc.2/incubator.common.pipeline.default.tar.gz"}}
 	triggers := []Trigger{{Id: "testTrigger", Sha256: "9b11091f295fb6706a8dbca62f57adf26b55d6f35eb0d5b0988129db91d295c0", Url: server.URL + "/0.5.0-rc.2/incubator.trigger.tar.gz"}}
-	index, err := ResolveIndex(resolverTestClient{}, repoConfig, "kabanero", pipelines, triggers, "kabanerobeta", resolverTestLogger)
+	index, err := ResolveIndex(context.Background(), resolverTestClient{}, repoConfig, "kabanero", pipelines, triggers, "kabanerobeta", nil, resolverTestLogger)
 
 	if err == nil {
 		t.Fatal("No Git release or Http url were specified. An error was expected. Index: ", index)
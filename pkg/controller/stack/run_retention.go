@@ -0,0 +1,196 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// The completed PipelineRuns and TaskRuns of the pipelines and tasks created by Kabanero
+// are deleted as configured by the pipelineRunRetention of the Kabanero instance, so that
+// busy build clusters do not fill etcd with old runs.  The runs are read as unstructured
+// objects, so that they are not held in the cache of the controller.
+
+var retentionlog = logf.Log.WithName("run_retention")
+
+// How often the completed runs are checked.
+const runRetentionInterval = 10 * time.Minute
+
+// The kinds of the runs, and of the pipelines and tasks they run.
+var (
+	pipelineGVK    = schema.GroupVersionKind{Group: "tekton.dev", Version: "v1alpha1", Kind: "Pipeline"}
+	taskGVK        = schema.GroupVersionKind{Group: "tekton.dev", Version: "v1alpha1", Kind: "Task"}
+	pipelineRunGVK = schema.GroupVersionKind{Group: "tekton.dev", Version: "v1alpha1", Kind: "PipelineRun"}
+	taskRunGVK     = schema.GroupVersionKind{Group: "tekton.dev", Version: "v1alpha1", Kind: "TaskRun"}
+)
+
+// Deletes the expired runs periodically, while the manager runs.
+type runRetentionRunnable struct {
+	client client.Client
+}
+
+func (r runRetentionRunnable) Start(stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	work := timer.ScheduleWork(ctx, runRetentionInterval, false, retentionlog, func(time.Duration) {
+		pruneRuns(ctx, r.client)
+	}, 0)
+
+	<-stop
+	work.Stop()
+	return nil
+}
+
+// Only one instance of the controller deletes runs.
+func (runRetentionRunnable) NeedLeaderElection() bool {
+	return true
+}
+
+// Adds the periodic deletion of expired runs to the manager.
+func addRunRetention(mgr manager.Manager) error {
+	return mgr.Add(runRetentionRunnable{client: mgr.GetClient()})
+}
+
+// Deletes the expired runs in the namespace of each Kabanero instance.
+func pruneRuns(ctx context.Context, c client.Client) {
+	kabaneroList := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(ctx, kabaneroList)
+	if err != nil {
+		retentionlog.Error(err, "Unable to list the Kabanero instances")
+		return
+	}
+
+	// The runs of a namespace are pruned by the instance that manages it.
+	namespaceInstances := map[string][]kabanerov1alpha2.Kabanero{}
+	for _, k := range kabaneroList.Items {
+		namespaceInstances[k.GetNamespace()] = append(namespaceInstances[k.GetNamespace()], k)
+	}
+
+	for _, instances := range namespaceInstances {
+		k := cutils.OldestKabaneroInstance(instances)
+		if !k.Spec.PipelineRunRetention.IsEnabled() {
+			continue
+		}
+		maxAge, err := k.Spec.PipelineRunRetention.GetMaxAge()
+		if err != nil {
+			retentionlog.Error(err, fmt.Sprintf("Kabanero instance %v has an invalid pipelineRunRetention maxAge", k.Name))
+			continue
+		}
+
+		pruneNamespaceRuns(ctx, c, k.GetNamespace(), pipelineGVK, pipelineRunGVK, "pipelineRef", "tekton.dev/pipeline", k.Spec.PipelineRunRetention.MaxCount, maxAge)
+		pruneNamespaceRuns(ctx, c, k.GetNamespace(), taskGVK, taskRunGVK, "taskRef", "tekton.dev/task", k.Spec.PipelineRunRetention.MaxCount, maxAge)
+	}
+}
+
+// Deletes the expired runs of the Kabanero pipelines or tasks in the input namespace.
+func pruneNamespaceRuns(ctx context.Context, c client.Client, namespace string, definitionGVK schema.GroupVersionKind, runGVK schema.GroupVersionKind, refField string, nameLabel string, maxCount int, maxAge time.Duration) {
+	definitions := &unstructured.UnstructuredList{}
+	definitions.SetGroupVersionKind(schema.GroupVersionKind{Group: definitionGVK.Group, Version: definitionGVK.Version, Kind: definitionGVK.Kind + "List"})
+	err := c.List(ctx, definitions, client.InNamespace(namespace))
+	if err != nil {
+		retentionlog.Error(err, fmt.Sprintf("Unable to list the %v objects in namespace %v", definitionGVK.Kind, namespace))
+		return
+	}
+
+	managed := make(map[string]bool)
+	for _, definition := range definitions.Items {
+		if isKabaneroManaged(&definition) {
+			managed[definition.GetName()] = true
+		}
+	}
+	if len(managed) == 0 {
+		return
+	}
+
+	runs := &unstructured.UnstructuredList{}
+	runs.SetGroupVersionKind(schema.GroupVersionKind{Group: runGVK.Group, Version: runGVK.Version, Kind: runGVK.Kind + "List"})
+	err = c.List(ctx, runs, client.InNamespace(namespace))
+	if err != nil {
+		retentionlog.Error(err, fmt.Sprintf("Unable to list the %v objects in namespace %v", runGVK.Kind, namespace))
+		return
+	}
+
+	background := metav1.DeletePropagationBackground
+	for _, run := range selectExpiredRuns(runs.Items, managed, refField, nameLabel, maxCount, maxAge, time.Now()) {
+		retentionlog.Info(fmt.Sprintf("Deleting %v %v in namespace %v", runGVK.Kind, run.GetName(), namespace))
+		err = c.Delete(ctx, run, &client.DeleteOptions{PropagationPolicy: &background})
+		if err != nil && !errors.IsNotFound(err) {
+			retentionlog.Error(err, fmt.Sprintf("Unable to delete %v %v in namespace %v", runGVK.Kind, run.GetName(), namespace))
+		}
+	}
+}
+
+// Returns true if the input pipeline or task is owned by a Stack or a Kabanero instance.
+func isKabaneroManaged(u *unstructured.Unstructured) bool {
+	for _, ownerRef := range u.GetOwnerReferences() {
+		if strings.HasPrefix(ownerRef.APIVersion, kabanerov1alpha2.SchemeGroupVersion.Group+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the completed runs of the input pipelines or tasks that are beyond the maximum
+// count for their pipeline or task, or older than the maximum age.  Runs owned by another
+// object, such as the TaskRuns of a PipelineRun, are deleted with their owner instead.
+func selectExpiredRuns(runs []unstructured.Unstructured, managed map[string]bool, refField string, nameLabel string, maxCount int, maxAge time.Duration, now time.Time) []*unstructured.Unstructured {
+	type completedRun struct {
+		run        *unstructured.Unstructured
+		completion time.Time
+	}
+
+	byName := make(map[string][]completedRun)
+	for i := range runs {
+		run := &runs[i]
+		if len(run.GetOwnerReferences()) != 0 {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(run.Object, "spec", refField, "name")
+		if len(name) == 0 {
+			name = run.GetLabels()[nameLabel]
+		}
+		if !managed[name] {
+			continue
+		}
+
+		completionTime, found, _ := unstructured.NestedString(run.Object, "status", "completionTime")
+		if !found {
+			continue
+		}
+		completion, err := time.Parse(time.RFC3339, completionTime)
+		if err != nil {
+			continue
+		}
+		byName[name] = append(byName[name], completedRun{run: run, completion: completion})
+	}
+
+	expired := []*unstructured.Unstructured{}
+	for _, completed := range byName {
+		// Newest first.
+		sort.Slice(completed, func(i, j int) bool {
+			return completed[i].completion.After(completed[j].completion)
+		})
+		for i, c := range completed {
+			if (maxCount > 0 && i >= maxCount) || (maxAge > 0 && now.Sub(c.completion) > maxAge) {
+				expired = append(expired, c.run)
+			}
+		}
+	}
+
+	return expired
+}
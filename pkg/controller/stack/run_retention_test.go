@@ -0,0 +1,64 @@
+package stack
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Returns a PipelineRun of the input pipeline, completed at the input time if it is not zero.
+func newTestPipelineRun(name string, pipeline string, completion time.Time) unstructured.Unstructured {
+	run := unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"pipelineRef": map[string]interface{}{"name": pipeline}},
+	}}
+	run.SetName(name)
+	if !completion.IsZero() {
+		unstructured.SetNestedField(run.Object, completion.UTC().Format(time.RFC3339), "status", "completionTime")
+	}
+	return run
+}
+
+// Test that only the completed runs of Kabanero pipelines beyond the limits are deleted.
+func TestSelectExpiredRuns(t *testing.T) {
+	now := time.Now()
+	runs := []unstructured.Unstructured{
+		newTestPipelineRun("java-build-1", "java-build", now.Add(-3*time.Hour)),
+		newTestPipelineRun("java-build-2", "java-build", now.Add(-2*time.Hour)),
+		newTestPipelineRun("java-build-3", "java-build", now.Add(-1*time.Hour)),
+		newTestPipelineRun("java-build-4", "java-build", time.Time{}),
+		newTestPipelineRun("nodejs-build-1", "nodejs-build", now.Add(-3*time.Hour)),
+		newTestPipelineRun("custom-1", "custom", now.Add(-72*time.Hour)),
+	}
+	runs[4].SetLabels(map[string]string{"tekton.dev/pipeline": "nodejs-build"})
+	unstructured.RemoveNestedField(runs[4].Object, "spec", "pipelineRef")
+	managed := map[string]bool{"java-build": true, "nodejs-build": true}
+
+	names := func(expired []*unstructured.Unstructured) map[string]bool {
+		result := make(map[string]bool)
+		for _, run := range expired {
+			result[run.GetName()] = true
+		}
+		return result
+	}
+
+	// Keep the newest completed run of each pipeline.
+	expired := names(selectExpiredRuns(runs, managed, "pipelineRef", "tekton.dev/pipeline", 1, 0, now))
+	if len(expired) != 2 || !expired["java-build-1"] || !expired["java-build-2"] {
+		t.Fatalf("Expected the two oldest java-build runs to expire, but found %v", expired)
+	}
+
+	// Keep the runs of the last 150 minutes.
+	expired = names(selectExpiredRuns(runs, managed, "pipelineRef", "tekton.dev/pipeline", 0, 150*time.Minute, now))
+	if len(expired) != 2 || !expired["java-build-1"] || !expired["nodejs-build-1"] {
+		t.Fatalf("Expected the runs older than 150 minutes to expire, but found %v", expired)
+	}
+
+	// Runs owned by another object are deleted with it.
+	runs[0].SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "tekton.dev/v1alpha1", Kind: "PipelineRun", Name: "parent", UID: "1"}})
+	expired = names(selectExpiredRuns(runs, managed, "pipelineRef", "tekton.dev/pipeline", 1, 0, now))
+	if len(expired) != 1 || !expired["java-build-2"] {
+		t.Fatalf("Expected only java-build-2 to expire, but found %v", expired)
+	}
+}
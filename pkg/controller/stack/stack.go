@@ -8,7 +8,9 @@ type Stack struct {
 	DefaultImage     string        `yaml:"default-image,omitempty"`
 	DefaultPipeline  string        `yaml:"default-pipeline,omitempty"`
 	DefaultTemplate  string        `yaml:"default-template,omitempty"`
+	Deprecated       bool          `yaml:"deprecated,omitempty"`
 	Description      string        `yaml:"description,omitempty"`
+	EndOfSupport     string        `yaml:"endOfSupport,omitempty"`
 	Id               string        `yaml:"id,omitempty"`
 	Image            string        `yaml:"image,omitempty"`
 	Images           []Images      `yaml:"images,omitempty"`
@@ -3,9 +3,8 @@ package stack
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
-	"net/http"
-	"regexp"
 	"runtime"
 	"strings"
 	"time"
@@ -21,14 +20,18 @@ import (
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
 	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
-	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/secret"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/docker/docker/registry"
 	pipelinev1alpha1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8runtime "k8s.io/apimachinery/pkg/runtime"
+	k8types "k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -42,18 +45,21 @@ import (
 	reference "github.com/docker/distribution/reference"
 )
 
-var log = logf.Log.WithName("controller_stack")
-var cIDRegex = regexp.MustCompile("^[a-z]([a-z0-9-]*[a-z0-9])?$")
+// Stacks are reconciled often, so repeated messages are sampled.
+var log = cutils.NewSampledLogger(logf.Log.WithName("controller_stack"), "controller_stack", cutils.DefaultLogSampleLimit, cutils.DefaultLogSampleInterval)
 
 // Add creates a new Stack Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
+	// Asset namespaces are cluster scoped, and must not be cached.
+	cutils.SetNamespaceReader(mgr.GetAPIReader())
+	cutils.SetRegistryAuthenticator(GetBundleRegistryAuthenticators)
 	return add(mgr, newReconciler(mgr))
 }
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileStack{client: mgr.GetClient(), scheme: mgr.GetScheme(), indexResolver: ResolveIndex}
+	return &ReconcileStack{client: mgr.GetClient(), scheme: mgr.GetScheme(), indexResolver: ResolveIndex, recorder: mgr.GetEventRecorderFor("stack-controller")}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -125,6 +131,29 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch the Kabanero instances, so that a change to the governance policy is applied to
+	// the stacks in their namespace.
+	kPred := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isGovernancePolicyChanged(e.ObjectOld, e.ObjectNew)
+		},
+	}
+
+	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.Kabanero{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: governancePolicyMapFunc(mgr.GetClient())}, kPred)
+	if err != nil {
+		return err
+	}
+
 	// Index ImageStreams by status.publicDockerImageRepository
 	if err := mgr.GetFieldIndexer().IndexField(&imagev1.ImageStream{}, "status.publicDockerImageRepository", func(rawObj k8runtime.Object) []string {
 		imagestream := rawObj.(*imagev1.ImageStream)
@@ -137,6 +166,39 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	return nil
 }
 
+// Returns true if the governance policy or the vulnerability scan of the input Kabanero
+// instances differs.
+func isGovernancePolicyChanged(oldObj k8runtime.Object, newObj k8runtime.Object) bool {
+	oldKabanero, ok := oldObj.(*kabanerov1alpha2.Kabanero)
+	if !ok {
+		return false
+	}
+	newKabanero, ok := newObj.(*kabanerov1alpha2.Kabanero)
+	if !ok {
+		return false
+	}
+	return !equality.Semantic.DeepEqual(oldKabanero.Spec.GovernancePolicy, newKabanero.Spec.GovernancePolicy) ||
+		!equality.Semantic.DeepEqual(oldKabanero.Spec.VulnerabilityScan, newKabanero.Spec.VulnerabilityScan)
+}
+
+// Returns a function that maps a Kabanero instance to the stacks in its namespace.
+func governancePolicyMapFunc(c client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		stacks := &kabanerov1alpha2.StackList{}
+		err := c.List(context.TODO(), stacks, client.InNamespace(a.Meta.GetNamespace()))
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Could not process the governance policy change of Kabanero instance \"%v\"", a.Meta.GetName()))
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+		for _, stack := range stacks.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: k8types.NamespacedName{Name: stack.Name, Namespace: stack.Namespace}})
+		}
+		return requests
+	}
+}
+
 // blank assignment to verify that ReconcileStack implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ReconcileStack{}
 
@@ -148,7 +210,10 @@ type ReconcileStack struct {
 	scheme *k8runtime.Scheme
 
 	//The indexResolver which will be used during reconciliation
-	indexResolver func(client.Client, kabanerov1alpha2.RepositoryConfig, string, []Pipelines, []Trigger, string, logr.Logger) (*Index, error)
+	indexResolver func(context.Context, client.Client, kabanerov1alpha2.RepositoryConfig, string, []Pipelines, []Trigger, string, *cache.ArtifactProxy, logr.Logger) (*Index, error)
+
+	// Records events about stacks.  May be nil.
+	recorder record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a Stack object and makes changes based on the state read
@@ -159,10 +224,10 @@ type ReconcileStack struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileStack) Reconcile(request reconcile.Request) (reconcile.Result, error) {
-	ctx := context.Background()
-
-	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	// Every message of this reconcile, including those of the downloads, carries its ID.
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name, cutils.ReconcileIDKey, cutils.NewReconcileID())
 	reqLogger.Info("Reconciling Stack")
+	ctx := cache.WithLogger(context.Background(), reqLogger)
 
 	// Fetch the Stack instance
 	instance := &kabanerov1alpha2.Stack{}
@@ -178,6 +243,17 @@ func (r *ReconcileStack) Reconcile(request reconcile.Request) (reconcile.Result,
 		return reconcile.Result{}, err
 	}
 
+	// The status as it was read, used to decide whether the status must be written.
+	original := instance.DeepCopy()
+
+	// Don't start a new activation if the operator is stopping.  The request is
+	// requeued so that it is picked up when the operator runs again.
+	if !cutils.BeginActivation() {
+		reqLogger.Info("The operator is stopping. Deferring stack reconciliation.")
+		return reconcile.Result{Requeue: true}, nil
+	}
+	defer cutils.EndActivation()
+
 	// If the stack is being deleted, and our finalizer is set, process it.
 	beingDeleted, err := processDeletion(ctx, instance, r.client, reqLogger)
 	if err != nil {
@@ -188,9 +264,24 @@ func (r *ReconcileStack) Reconcile(request reconcile.Request) (reconcile.Result,
 		return reconcile.Result{}, nil
 	}
 
-	rr, err := r.ReconcileStack(instance)
+	// Deactivate the oldest versions if the stack has more active versions than allowed.
+	err = deactivateSurplusVersions(ctx, r.client, r.recorder, instance, reqLogger)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	rr, err := r.ReconcileStack(ctx, instance, reqLogger)
 
-	r.client.Status().Update(ctx, instance)
+	// Keep the status small enough to be written.
+	cerr := compactStackStatus(ctx, r.client, instance, reqLogger)
+	if cerr != nil {
+		reqLogger.Error(cerr, "Unable to compact the stack status")
+	}
+
+	perr := patchStackStatus(ctx, r.client, original, instance)
+	if perr != nil {
+		reqLogger.Error(perr, "Unable to update the stack status")
+	}
 
 	// Force a requeue if there are failed assets.  These should be retried, and since
 	// they are hosted outside of Kubernetes, the controller will not see when they
@@ -214,12 +305,25 @@ func (r *ReconcileStack) Reconcile(request reconcile.Request) (reconcile.Result,
 	return rr, err
 }
 
-// Check to see if the status contains any assets that are failed
+// Writes the status of the stack if it differs from the original status.  A merge patch
+// of the status subresource is used, so that concurrent changes to the stack do not cause
+// conflicts.
+func patchStackStatus(ctx context.Context, c cutils.StatusWriter, original *kabanerov1alpha2.Stack, stack *kabanerov1alpha2.Stack) error {
+	if equality.Semantic.DeepEqual(original.Status, stack.Status) {
+		return nil
+	}
+
+	patched := original.DeepCopy()
+	patched.Status = stack.Status
+	return c.Status().Patch(ctx, patched, client.MergeFrom(original))
+}
+
+// Check to see if the status contains any assets that are failed or drifted
 func failedAssets(status kabanerov1alpha2.StackStatus) bool {
 	for _, version := range status.Versions {
 		for _, pipeline := range version.Pipelines {
 			for _, asset := range pipeline.ActiveAssets {
-				if asset.Status == cutils.AssetStatusFailed {
+				if cutils.IsFailedAssetStatus(asset.Status) {
 					return true
 				}
 			}
@@ -241,6 +345,36 @@ func stackSummary(status kabanerov1alpha2.StackStatus) (string, string) {
 	return fmt.Sprintf("[ %v ]", strings.Join(summary, ", ")), fmt.Sprintf(strings.Join(errorSummary, ", "))
 }
 
+// Sets the Ready condition.  The stack is ready if none of its versions or assets failed.
+func setReadyCondition(status *kabanerov1alpha2.StackStatus) {
+	now := metav1.Now()
+	condition := kabanerov1alpha2.StackCondition{
+		Type:               kabanerov1alpha2.StackConditionReady,
+		Status:             string(corev1.ConditionTrue),
+		LastTransitionTime: &now,
+		Reason:             "VersionsReady",
+	}
+
+	_, errorSummary := stackSummary(*status)
+	if len(errorSummary) != 0 {
+		condition.Status = string(corev1.ConditionFalse)
+		condition.Reason = "VersionsFailed"
+		condition.Message = fmt.Sprintf("The following stack versions have errors: %v", errorSummary)
+		for _, version := range status.Versions {
+			if version.Status == kabanerov1alpha2.StackStateError && len(version.Reason) != 0 {
+				condition.Reason = version.Reason
+				break
+			}
+		}
+	} else if failedAssets(*status) {
+		condition.Status = string(corev1.ConditionFalse)
+		condition.Reason = "AssetsFailed"
+		condition.Message = "One or more pipeline assets could not be applied, or were modified after they were applied."
+	}
+
+	status.SetCondition(condition)
+}
+
 // Used internally by ReconcileStack to store matching stacks
 // Could be less cumbersome to just use kabanerov1alpha2.Stack
 type resolvedStack struct {
@@ -248,9 +382,11 @@ type resolvedStack struct {
 	stack         Stack
 }
 
-// ReconcileStack activates or deactivates the input stack.
-func (r *ReconcileStack) ReconcileStack(c *kabanerov1alpha2.Stack) (reconcile.Result, error) {
-	r_log := log.WithValues("Request.Namespace", c.GetNamespace()).WithValues("Request.Name", c.GetName())
+// ReconcileStack activates or deactivates the input stack.  Downloads and registry
+// lookups are abandoned when the input context is done.  Messages are logged to the
+// input request logger.
+func (r *ReconcileStack) ReconcileStack(ctx context.Context, c *kabanerov1alpha2.Stack, reqLogger logr.Logger) (reconcile.Result, error) {
+	r_log := reqLogger
 
 	// Clear the status message, we'll generate a new one if necessary
 	c.Status.StatusMessage = ""
@@ -266,34 +402,82 @@ func (r *ReconcileStack) ReconcileStack(c *kabanerov1alpha2.Stack) (reconcile.Re
 
 	r_log = r_log.WithValues("Stack.Name", stackName)
 
+	// The downloads, renders and applies of the activation are children of this span.
+	ctx, span := cutils.StartSpan(ctx, "ReconcileStack", attribute.String("stack.namespace", c.GetNamespace()), attribute.String("stack.name", stackName))
+
 	// Process the versions array and activate (or deactivate) the desired versions.
-	err := reconcileActiveVersions(c, r.client, r_log)
+	previousDrift := c.Status.GetCondition(kabanerov1alpha2.StackConditionDigestDrifted)
+	previousDeprecated := c.Status.GetCondition(kabanerov1alpha2.StackConditionDeprecated)
+	err := reconcileActiveVersions(ctx, c, r.client, r_log)
+	cutils.EndSpan(span, err)
 	if err != nil {
 		// TODO - what is useful to print?
 		log.Error(err, fmt.Sprintf("Error during reconcileActiveVersions"))
 	}
 
+	// Tell the administrator if an image tag was moved underneath an active version.
+	reportDigestDrift(r.recorder, c, previousDrift)
+
+	// Tell the administrator if an active version is no longer supported.
+	reportEndOfSupport(r.recorder, c, previousDeprecated)
+
+	// Come back when an active version reaches its end of support date.
+	requeueAfter := timeUntilEndOfSupport(c.Status, time.Now())
+
+	// Come back to check the image tags again.
+	if hasActivationDigests(c.Status) {
+		k, err := getKabaneroInstance(r.client, c.GetNamespace())
+		if err == nil {
+			requeueAfter = shorterRequeue(requeueAfter, shorterRequeue(GetDigestDriftCheckInterval(k), TimeUntilScheduledRefresh(k, time.Now())))
+		}
+	}
+
+	// Come back to see if the routers admitted the EventListener Routes.
+	if hasEventListenerStatus(c.Status, true) {
+		requeueAfter = shorterRequeue(requeueAfter, eventListenerRouteRetryInterval)
+	}
+
+	// Come back to ask for the reports of the pending vulnerability scans.
+	if condition := c.Status.GetCondition(kabanerov1alpha2.StackConditionVulnerable); condition != nil && condition.Reason == kabanerov1alpha2.StackReasonScanPending {
+		requeueAfter = shorterRequeue(requeueAfter, vulnerabilityScanRefreshAfter)
+	}
+
+	if requeueAfter > 0 {
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
+
 	return reconcile.Result{}, nil
 }
 
 func gitReleaseSpecToGitReleaseInfo(gitRelease kabanerov1alpha2.GitReleaseSpec) kabanerov1alpha2.GitReleaseInfo {
 	return kabanerov1alpha2.GitReleaseInfo{Hostname: gitRelease.Hostname, Organization: gitRelease.Organization, Project: gitRelease.Project, Release: gitRelease.Release, AssetName: gitRelease.AssetName}
 }
-func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Client, logger logr.Logger) error {
+
+// Returns the Kabanero instance that manages the input namespace, the oldest one, or nil if
+// there is none.
+func getKabaneroInstance(c client.Reader, namespace string) (*kabanerov1alpha2.Kabanero, error) {
+	kabaneroList := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(context.TODO(), kabaneroList, client.InNamespace(namespace))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list the Kabanero instances in namespace %v: %v", namespace, err.Error())
+	}
+
+	return cutils.OldestKabaneroInstance(kabaneroList.Items), nil
+}
+
+func reconcileActiveVersions(ctx context.Context, stackResource *kabanerov1alpha2.Stack, c cutils.AssetClient, logger logr.Logger) error {
 
 	// Gather the known stack asset (*-tasks, *-pipeline) substitution data.
 	renderingContext := make(map[string]interface{})
 
 	// The stack id is the name of the Appsody stack directory ("the stack name from the stack path").
-	// Appsody stack creation namimg constrains the length to 68 characters:
-	// "The name must start with a lowercase letter, contain only lowercase letters, numbers, or dashes,
-	// and cannot end in a dash."
+	// The webhook rejects invalid ids, but stacks created before it was installed may have them.
 	cID := stackResource.Spec.Name
-	if len(cID) > 68 {
+	if len(cID) > kabanerov1alpha2.MaxStackIdLength {
 		return fmt.Errorf("Failed to reconcile stack because an invalid stack id of %v was found. The stack id must must be 68 characters or less. For more details see the Appsody stack create command documentation", cID)
 	}
 
-	if !cIDRegex.MatchString(cID) {
+	if !kabanerov1alpha2.IsValidStackId(cID) {
 		return fmt.Errorf("Failed to reconcile stack because an invalid stack id of %v was found. The stack id value must follow stack creation name rules. For more details see the Appsody stack create command documentation", cID)
 	}
 
@@ -309,8 +493,119 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 		Controller: &ownerIsController,
 	}
 
+	// The Kabanero instance in the stack's namespace controls how the pipelines are activated.
+	k, err := getKabaneroInstance(c, stackResource.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	// Versions that the governance policy of the Kabanero instance does not allow are not
+	// activated, and their pipelines are removed if they were activated before.
+	governanceViolations := make(map[string]sutils.GovernanceViolation)
+	if k != nil {
+		governanceViolations = sutils.CheckGovernancePolicy(k.Spec.GovernancePolicy, stackResource.Spec)
+	}
+
+	activationOptions, err := cutils.GetActivationOptions(c, k)
+	if err != nil {
+		return err
+	}
+
+	// The stack's deletion policy takes precedence over the Kabanero instance's.
+	if len(stackResource.Spec.DeletionPolicy) != 0 {
+		activationOptions.DeletionPolicy = stackResource.Spec.DeletionPolicy
+	}
+
+	// So do the stack's rendering context values.
+	stackRenderingValues, err := cutils.GetRenderingValues(c, stackResource.GetNamespace(), stackResource.Spec.RenderingContextConfigMap)
+	if err != nil {
+		return err
+	}
+	activationOptions.RenderingValues = cutils.MergeRenderingValues(activationOptions.RenderingValues, stackRenderingValues)
+	activationOptions.Workspaces = kabanerov1alpha2.MergeWorkspaceBindings(activationOptions.Workspaces, stackResource.Spec.Workspaces)
+
+	var registryMirrors map[string]string
+	var registryRootCAs *x509.CertPool
+	var defaultPullSecrets []string
+	driftCheckInterval := GetDigestDriftCheckInterval(k)
+	refreshSchedule := GetRefreshSchedule(k)
+	imageDigestCache.setTTL(GetDigestCacheTTL(k))
+	if k != nil {
+		// The pipeline archives are downloaded by this controller, not the Kabanero controller.
+		cache.SetDownloadLimits(k.Spec.Downloads.MaxConcurrent, k.Spec.Downloads.MaxConcurrentPerHost)
+		cache.SetCacheLimits(k.Spec.Downloads.MaxCacheSize, k.Spec.Downloads.MaxCacheEntries)
+		cutils.SetArchiveLimits(k.Spec.Downloads)
+		if err := cache.SetEgressProxy(ctx, c, k.Spec.EgressProxy); err != nil {
+			logger.Error(err, "Unable to set the egress proxy")
+		}
+
+		registryMirrors = k.Spec.RegistryMirrors
+		defaultPullSecrets = k.Spec.Stacks.ImagePullSecrets
+
+		// A CA bundle that cannot be read is reported, and the default trust is used instead.
+		registryRootCAs, err = getRegistryRootCAs(c, stackResource.GetNamespace(), k.Spec.RegistryCABundleConfigMap)
+		if err != nil {
+			logger.Error(err, "Unable to read the registry CA bundle")
+		}
+	}
+
+	// The images of the versions to be activated are checked by the vulnerability scanner,
+	// which may block the versions with too many critical vulnerabilities, whose images
+	// could not be scanned, or whose scans are pending.
+	var vulnerabilityScans map[string]versionScan
+	if k != nil && k.Spec.VulnerabilityScan.IsEnabled() {
+		scanSpec := k.Spec.VulnerabilityScan
+		scanner, scannerErr := newVulnerabilityScanner(c, stackResource.GetNamespace(), scanSpec)
+		vulnerabilityScans = scanStackVersions(stackResource.Spec, governanceViolations, scanSpec.MaxCriticalVulnerabilities, func(version kabanerov1alpha2.StackVersion, image string) (string, error) {
+			if digest := getActivationDigest(stackResource.Status, version.Version, image); len(digest) != 0 {
+				return digest, nil
+			}
+			return resolveImageDigest(ctx, c, *stackResource, version, image, registryMirrors, registryRootCAs, defaultPullSecrets, logger)
+		}, func(version kabanerov1alpha2.StackVersion, image string) (vulnerabilityReport, error) {
+			if scannerErr != nil {
+				return vulnerabilityReport{}, scannerErr
+			}
+			// The Trivy adapter pulls the image with the credentials the operator uses.
+			authorization := ""
+			if scanSpec.Scanner == kabanerov1alpha2.VulnerabilityScannerTrivy {
+				var err error
+				pullSecrets := getImagePullSecrets(version.ImagePullSecrets, defaultPullSecrets)
+				authorization, err = getScannerRegistryAuthorization(c, stackResource.GetNamespace(), pullSecrets, image, logger)
+				if err != nil {
+					return vulnerabilityReport{}, err
+				}
+			}
+			return scanner.scan(ctx, image, authorization)
+		})
+		blockVulnerableVersions(scanSpec, vulnerabilityScans, governanceViolations)
+	}
+
+	governedSpec := stackResource.Spec
+	if len(governanceViolations) != 0 {
+		governedSpec = *stackResource.Spec.DeepCopy()
+		for i, version := range governedSpec.Versions {
+			if _, violated := governanceViolations[version.Version]; violated {
+				governedSpec.Versions[i].DesiredState = kabanerov1alpha2.StackDesiredStateInactive
+			}
+		}
+	}
+
+	// Pass the image of each version to its pipelines, rewritten for any registry mirror.
+	activationOptions.VersionRenderingContext = make(map[string]map[string]interface{})
+	for _, curSpec := range stackResource.Spec.Versions {
+		if len(curSpec.Images) == 0 {
+			continue
+		}
+		stackImage, err := sutils.ApplyRegistryMirrors(curSpec.Images[0].Image, registryMirrors)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to apply the registry mirrors to image %v", curSpec.Images[0].Image))
+			continue
+		}
+		activationOptions.VersionRenderingContext[curSpec.Version] = map[string]interface{}{"StackImage": stackImage}
+	}
+
 	// Activate the pipelines used by this stack.
-	assetUseMap, err := cutils.ActivatePipelines(stackResource.Spec, stackResource.Status, stackResource.GetNamespace(), renderingContext, assetOwner, c, logger)
+	assetUseMap, err := cutils.ActivatePipelines(ctx, governedSpec, stackResource.Status, stackResource.GetNamespace(), activationOptions, renderingContext, assetOwner, c, logger)
 
 	if err != nil {
 		return err
@@ -318,11 +613,21 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 
 	// Now update the StackStatus to reflect the current state of things.
 	newStackStatus := kabanerov1alpha2.StackStatus{}
+	digestLookups := []digestLookup{}
 	for i, curSpec := range stackResource.Spec.Versions {
-		newStackVersionStatus := kabanerov1alpha2.StackVersionStatus{Version: curSpec.Version}
+		newStackVersionStatus := kabanerov1alpha2.StackVersionStatus{Version: curSpec.Version, Location: curSpec.RepositoryUrl, Deprecated: curSpec.Deprecated, EndOfSupport: curSpec.EndOfSupport}
+		if violation, violated := governanceViolations[curSpec.Version]; violated {
+			newStackVersionStatus.Status = kabanerov1alpha2.StackStateError
+			newStackVersionStatus.StatusMessage = violation.Message
+			newStackVersionStatus.Reason = violation.Reason
+			newStackStatus.Versions = append(newStackStatus.Versions, newStackVersionStatus)
+			continue
+		}
+
 		if !strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
 			if (len(curSpec.DesiredState) > 0) && (!strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateActive)) {
 				newStackVersionStatus.StatusMessage = "An invalid desiredState value of " + curSpec.DesiredState + " was specified. The stack is activated by default."
+				newStackVersionStatus.Reason = kabanerov1alpha2.StackReasonInvalidDesiredState
 			}
 			newStackVersionStatus.Status = kabanerov1alpha2.StackDesiredStateActive
 
@@ -331,7 +636,7 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 				if pipeline.GitRelease.IsUsable() {
 					key.GitRelease = gitReleaseSpecToGitReleaseInfo(pipeline.GitRelease)
 				} else {
-					key.Url = pipeline.Https.Url
+					key.Url = pipeline.ArchiveUrl()
 				}
 				value := assetUseMap[key]
 				if value == nil {
@@ -344,6 +649,7 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 					// If we had a problem loading the pipeline manifests, say so.
 					if value.ManifestError != nil {
 						newStackVersionStatus.StatusMessage = value.ManifestError.Error()
+						newStackVersionStatus.Reason = cutils.ManifestErrorReason(value.ManifestError)
 						newStackVersionStatus.Status = kabanerov1alpha2.StackStateError
 					}
 				}
@@ -359,25 +665,77 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 			}
 			stackResource.Spec.Versions[i] = curSpec
 
-			// Update the status of the Stack object to reflect the images used
+			// Update the status of the Stack object to reflect the images used.  The digests
+			// are retrieved below, for all versions at once.
 			for _, img := range curSpec.Images {
-				digest, err := getStatusImageDigest(c, *stackResource, curSpec, img.Image, logger)
-				if err != nil {
-					newStackVersionStatus.Status = kabanerov1alpha2.StackStateError
-				}
-				newStackVersionStatus.Images = append(newStackVersionStatus.Images, kabanerov1alpha2.ImageStatus{Id: img.Id, Image: img.Image, Digest: digest})
+				digestLookups = append(digestLookups, digestLookup{versionIndex: len(newStackStatus.Versions), imageIndex: len(newStackVersionStatus.Images), curSpec: curSpec, image: img.Image})
+				newStackVersionStatus.Images = append(newStackVersionStatus.Images, kabanerov1alpha2.ImageStatus{Id: img.Id, Image: img.Image})
 			}
 		} else {
 			newStackVersionStatus.Status = kabanerov1alpha2.StackDesiredStateInactive
 			newStackVersionStatus.StatusMessage = "The stack has been deactivated."
 		}
 
-		log.Info(fmt.Sprintf("Updated stack status: %+v", newStackVersionStatus))
 		newStackStatus.Versions = append(newStackStatus.Versions, newStackVersionStatus)
 	}
 
+	// Retrieve the image digests.  Registries can be slow, so the lookups run concurrently.
+	digestResults := lookupImageDigests(digestLookups, maxConcurrentDigestLookups, func(l digestLookup) (kabanerov1alpha2.ImageDigest, error) {
+		digest, err := getStatusImageDigest(ctx, c, *stackResource, l.curSpec, l.image, registryMirrors, registryRootCAs, defaultPullSecrets, logger)
+		if err != nil {
+			return digest, err
+		}
+
+		// Periodically check whether the image tag was moved to a different digest.
+		return checkImageDigestDrift(digest, driftCheckInterval, refreshSchedule, time.Now(), func() (string, error) {
+			return resolveImageDigest(ctx, c, *stackResource, l.curSpec, l.image, registryMirrors, registryRootCAs, defaultPullSecrets, logger)
+		}, logger), nil
+	})
+
+	digestErrors := make(map[int][]string)
+	digestReasons := make(map[int]string)
+	for i, result := range digestResults {
+		l := digestLookups[i]
+		newStackStatus.Versions[l.versionIndex].Images[l.imageIndex].Digest = result.digest
+		if result.err != nil {
+			newStackStatus.Versions[l.versionIndex].Status = kabanerov1alpha2.StackStateError
+			digestErrors[l.versionIndex] = append(digestErrors[l.versionIndex], result.digest.Message)
+			if len(digestReasons[l.versionIndex]) == 0 {
+				digestReasons[l.versionIndex] = digestErrorReason(result.err)
+			}
+		}
+	}
+
+	for i := range newStackStatus.Versions {
+		// Report the digest errors, unless something more important is being reported.
+		if messages := digestErrors[i]; len(messages) != 0 && len(newStackStatus.Versions[i].StatusMessage) == 0 {
+			newStackStatus.Versions[i].StatusMessage = fmt.Sprintf("Unable to retrieve the digests of %v images: %v", len(messages), strings.Join(messages, "; "))
+			newStackStatus.Versions[i].Reason = digestReasons[i]
+		}
+		log.Info(fmt.Sprintf("Updated stack status: %+v", newStackStatus.Versions[i]))
+	}
+
+	// Expose the EventListeners of the stack, if it asks for it, or stop exposing them.
+	if stackResource.Spec.EventListenerRoute != nil || hasEventListenerStatus(stackResource.Status, false) {
+		namespaces := []string{stackResource.GetNamespace(), cutils.GetTriggerNamespace(k)}
+		reconcileEventListenerRoutes(ctx, c, stackResource, &newStackStatus, namespaces, assetOwner, logger)
+	}
+
 	newStackStatus.Summary, _ = stackSummary(newStackStatus)
 
+	// Conditions are carried over, and then updated to reflect the new status.
+	newStackStatus.Conditions = append([]kabanerov1alpha2.StackCondition{}, stackResource.Status.Conditions...)
+	setDigestDriftCondition(&newStackStatus)
+	setDeprecatedCondition(&newStackStatus, time.Now())
+	if vulnerabilityScans != nil {
+		setVulnerableCondition(&newStackStatus, k.Spec.VulnerabilityScan, vulnerabilityScans, time.Now())
+	}
+	setReadyCondition(&newStackStatus)
+	newStackStatus.GetCondition(kabanerov1alpha2.StackConditionReady).ObservedGeneration = stackResource.GetGeneration()
+
+	// The details ConfigMap is managed when the status is compacted.
+	newStackStatus.DetailsConfigMap = stackResource.Status.DetailsConfigMap
+
 	stackResource.Status = newStackStatus
 
 	return nil
@@ -396,8 +754,11 @@ func getStackForSpecVersion(spec kabanerov1alpha2.StackVersion, stacks []resolve
 // of the stacks. If there is an error during first retrieval, a subsequent successful retry may set the current digest and
 // not the activation digest. More precisely, the digest may not necessarily be the initial activation digest
 // because we allow stack activation despite there being a failure when retrieving the digest and the
-// image/digest may have changed before the next successful retry.
-func getStatusImageDigest(c client.Client, stackResource kabanerov1alpha2.Stack, curSpec kabanerov1alpha2.StackVersion, targetImg string, logger logr.Logger) (kabanerov1alpha2.ImageDigest, error) {
+// image/digest may have changed before the next successful retry.  If the image registry is mirrored,
+// the digest is retrieved from the mirror.  If rootCAs is not nil, it holds the certificates trusted
+// when connecting to the registry.  The version's image pull secrets are tried first, followed by
+// the input defaults.
+func getStatusImageDigest(ctx context.Context, c client.Reader, stackResource kabanerov1alpha2.Stack, curSpec kabanerov1alpha2.StackVersion, targetImg string, registryMirrors map[string]string, rootCAs *x509.CertPool, defaultPullSecrets []string, logger logr.Logger) (kabanerov1alpha2.ImageDigest, error) {
 	digest := kabanerov1alpha2.ImageDigest{}
 	foundTargetImage := false
 
@@ -424,27 +785,51 @@ func getStatusImageDigest(c client.Client, stackResource kabanerov1alpha2.Stack,
 	// If the activation digest was not set, find it.
 	if digest == (kabanerov1alpha2.ImageDigest{}) {
 		digest.Message = ""
-		img := targetImg + ":" + curSpec.Version
-		registry, err := sutils.GetImageRegistry(img)
+		imgDig, err := resolveImageDigest(ctx, c, stackResource, curSpec, targetImg, registryMirrors, rootCAs, defaultPullSecrets, logger)
 		if err != nil {
-			digest.Message = fmt.Sprintf("Unable to parse registry from image: %v. Associated stack: %v %v. Error: %v", img, stackResource.Spec.Name, curSpec.Version, err)
+			digest.Message = err.Error()
 			return digest, err
-		} else {
-			imgDig, err := retrieveImageDigest(c, stackResource.GetNamespace(), registry, curSpec.SkipRegistryCertVerification, logger, img)
-			if err != nil {
-				digest.Message = fmt.Sprintf("Unable to retrieve stack activation digest for image: %v. Associated stack: %v %v. Error: %v", img, stackResource.Spec.Name, curSpec.Version, err)
-				return digest, err
-			} else {
-				digest.Activation = imgDig
-			}
 		}
+
+		now := metav1.Now()
+		digest.Activation = imgDig
+		digest.Current = imgDig
+		digest.LastChecked = &now
 	}
 
 	return digest, nil
 }
 
+// Retrieves the digest that the input image of a stack version currently refers to.  If the image
+// registry is mirrored, the digest is retrieved from the mirror.  Recent results are taken from
+// the digest cache.
+func resolveImageDigest(ctx context.Context, c client.Reader, stackResource kabanerov1alpha2.Stack, curSpec kabanerov1alpha2.StackVersion, targetImg string, registryMirrors map[string]string, rootCAs *x509.CertPool, defaultPullSecrets []string, logger logr.Logger) (string, error) {
+	img := targetImg + ":" + curSpec.Version
+	img, err := sutils.ApplyRegistryMirrors(img, registryMirrors)
+	if err != nil {
+		return "", fmt.Errorf("Unable to apply the registry mirrors to image: %v. Associated stack: %v %v. Error: %v", targetImg+":"+curSpec.Version, stackResource.Spec.Name, curSpec.Version, err)
+	}
+
+	registry, err := sutils.GetImageRegistry(img)
+	if err != nil {
+		return "", fmt.Errorf("Unable to parse registry from image: %v. Associated stack: %v %v. Error: %v", img, stackResource.Spec.Name, curSpec.Version, err)
+	}
+
+	pullSecrets := getImagePullSecrets(curSpec.ImagePullSecrets, defaultPullSecrets)
+	key := digestCacheKey(stackResource.GetNamespace(), pullSecrets, img)
+	imgDig, err := imageDigestCache.getOrLookup(key, func() (string, error) {
+		digestThrottle.wait(registry)
+		return retrieveImageDigest(ctx, c, stackResource.GetNamespace(), registry, curSpec.SkipRegistryCertVerification, rootCAs, pullSecrets, logger, img)
+	})
+	if err != nil {
+		return "", fmt.Errorf("Unable to retrieve stack activation digest for image: %v. Associated stack: %v %v. Error: %w", img, stackResource.Spec.Name, curSpec.Version, err)
+	}
+
+	return imgDig, nil
+}
+
 // Retrieves the input image digest from the hosting repository.
-func retrieveImageDigest(c client.Client, namespace string, imgRegistry string, skipCertVerification bool, logr logr.Logger, image string) (string, error) {
+func retrieveImageDigest(ctx context.Context, c client.Reader, namespace string, imgRegistry string, skipCertVerification bool, rootCAs *x509.CertPool, pullSecrets []string, logr logr.Logger, image string) (string, error) {
 	// Check if the image is in the local registry - imagestream using the external route
 	iref, err := reference.ParseAnyReference(image)
 	if err != nil {
@@ -486,40 +871,9 @@ func retrieveImageDigest(c client.Client, namespace string, imgRegistry string,
 		}
 	}
 	
-	// Search all secrets under the given namespace for the one containing the required hostname.
-	annotationKey := "kabanero.io/docker-"
-	secret, err := secret.GetMatchingSecret(c, namespace, sutils.SecretAnnotationFilter, imgRegistry, annotationKey)
+	authenticators, err := GetRegistryAuthenticators(c, namespace, pullSecrets, imgRegistry, logr)
 	if err != nil {
-		newError := fmt.Errorf("Unable to find secret matching annotation values: %v and %v in namespace %v Error: %v", annotationKey, imgRegistry, namespace, err)
-		return "", newError
-	}
-
-	// If a secret was found, retrieve the needed information from it.
-	var password []byte
-	var username []byte
-	var dockerconfig []byte
-	var dockerconfigjson []byte
-
-	if secret != nil {
-		logr.Info(fmt.Sprintf("Secret used for image registry access: %v. Secret annotations: %v", secret.GetName(), secret.Annotations))
-		username, _ = secret.Data[corev1.BasicAuthUsernameKey]
-		password, _ = secret.Data[corev1.BasicAuthPasswordKey]
-		dockerconfig, _ = secret.Data[corev1.DockerConfigKey]
-		dockerconfigjson, _ = secret.Data[corev1.DockerConfigJsonKey]
-	}
-
-	// Create the authenticator mechanism to use for authentication.
-	authenticator := authn.Anonymous
-	if len(username) != 0 && len(password) != 0 {
-		authenticator, err = getBasicSecAuth(username, password)
-		if err != nil {
-			return "", err
-		}
-	} else if len(dockerconfig) != 0 || len(dockerconfigjson) != 0 {
-		authenticator, err = getDockerCfgSecAuth(dockerconfigjson, dockerconfig, imgRegistry, logr)
-		if err != nil {
-			return "", err
-		}
+		return "", err
 	}
 
 	// Retrieve the image manifest.
@@ -528,16 +882,32 @@ func retrieveImageDigest(c client.Client, namespace string, imgRegistry string,
 		return "", err
 	}
 
-	transport := &http.Transport{}
+	var tlsConf *tls.Config
 	if skipCertVerification {
-		tlsConf := &tls.Config{InsecureSkipVerify: skipCertVerification}
-		transport.TLSClientConfig = tlsConf
+		tlsConf = &tls.Config{InsecureSkipVerify: skipCertVerification}
+	} else if rootCAs != nil {
+		tlsConf = &tls.Config{RootCAs: rootCAs}
+	}
+	transport := cache.WithContext(ctx, cache.NewTransport(tlsConf))
+
+	// Use the first credentials that work.
+	var img v1.Image
+	for i, authenticator := range authenticators {
+		err = retryRegistryRequest(logr, func() error {
+			var rerr error
+			img, rerr = remote.Image(ref,
+				remote.WithAuth(authenticator),
+				remote.WithPlatform(v1.Platform{Architecture: runtime.GOARCH, OS: runtime.GOOS}),
+				remote.WithTransport(transport))
+			return rerr
+		})
+		if err == nil {
+			break
+		}
+		if i < len(authenticators)-1 {
+			logr.Info(fmt.Sprintf("Unable to retrieve image %v using image pull secret %v of %v. Trying the next one. Error: %v", image, i+1, len(authenticators), err))
+		}
 	}
-
-	img, err := remote.Image(ref,
-		remote.WithAuth(authenticator),
-		remote.WithPlatform(v1.Platform{Architecture: runtime.GOARCH, OS: runtime.GOOS}),
-		remote.WithTransport(transport))
 	if err != nil {
 		return "", err
 	}
@@ -705,6 +1075,17 @@ func cleanup(ctx context.Context, stack *kabanerov1alpha2.Stack, c client.Client
 		Controller: &ownerIsController,
 	}
 
+	k, err := getKabaneroInstance(c, stack.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	// The stack's deletion policy takes precedence over the Kabanero instance's.
+	deletionPolicy := stack.Spec.DeletionPolicy
+	if len(deletionPolicy) == 0 && k != nil {
+		deletionPolicy = k.Spec.AssetDeletionPolicy
+	}
+
 	// Run thru the status and delete everything.... we're just going to try once since it's unlikely
 	// that anything that goes wrong here would be rectified by a retry.
 	for _, version := range stack.Status.Versions {
@@ -715,10 +1096,20 @@ func cleanup(ctx context.Context, stack *kabanerov1alpha2.Stack, c client.Client
 					asset.Namespace = stack.GetNamespace()
 				}
 
-				cutils.DeleteAsset(c, asset, assetOwner, reqLogger)
+				cutils.DeleteAsset(c, asset, assetOwner, deletionPolicy, reqLogger)
 			}
 		}
 	}
 
+	// Assets in the trigger namespace have no owner reference to the stack, so the garbage
+	// collector will not remove them.  Find any that the status no longer lists by their labels.
+	triggerNamespace := cutils.GetTriggerNamespace(k)
+	if triggerNamespace != stack.GetNamespace() {
+		cutils.DeleteCrossNamespaceAssets(c, triggerNamespace, assetOwner, deletionPolicy, reqLogger)
+	}
+
+	// Remove the stack from the Routes and Ingresses of its EventListeners.
+	pruneEventListenerRoutes(ctx, c, []string{stack.GetNamespace(), triggerNamespace}, "", nil, assetOwner, reqLogger)
+
 	return nil
 }
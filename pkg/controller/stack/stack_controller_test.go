@@ -5,15 +5,16 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	utesting "github.com/kabanero-io/kabanero-operator/pkg/controller/utils/testing"
+	"github.com/kabanero-io/kabanero-operator/pkg/testutils"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -46,7 +47,7 @@ func init() {
 var sctlog = logf.Log.WithName("stack_controller_test")
 
 func TestReconcileStack(t *testing.T) {
-	r := &ReconcileStack{indexResolver: func(client.Client, kabanerov1alpha2.RepositoryConfig, string, []Pipelines, []Trigger, string, logr.Logger) (*Index, error) {
+	r := &ReconcileStack{client: utesting.NewClient(), indexResolver: func(context.Context, client.Client, kabanerov1alpha2.RepositoryConfig, string, []Pipelines, []Trigger, string, *cache.ArtifactProxy, logr.Logger) (*Index, error) {
 		return &Index{
 			APIVersion: "v2",
 			Stacks: []Stack{
@@ -102,7 +103,7 @@ func TestReconcileStack(t *testing.T) {
 		},
 	}
 
-	r.ReconcileStack(c)
+	r.ReconcileStack(context.Background(), c, sctlog)
 }
 
 // Test that failed assets are detected in the Stack instance status
@@ -120,6 +121,21 @@ func TestFailedAssets(t *testing.T) {
 	}
 }
 
+// Test that a drifted asset is detected in the Stack instance status
+func TestFailedAssetsDrifted(t *testing.T) {
+	var sampleAsset = []kabanerov1alpha2.RepositoryAssetStatus{{Name: "myAsset", Digest: "678910", Status: "active"},
+		{Name: "myAsset2", Digest: "678911", Status: "drifted"},
+	}
+
+	var samplePipelineStatus = []kabanerov1alpha2.PipelineStatus{{Name: "myAsset", Url: "http://myurl.com", Digest: "1234", ActiveAssets: sampleAsset}}
+	var sampleStackVersionStatus = []kabanerov1alpha2.StackVersionStatus{{Version: "", Location: "", Pipelines: samplePipelineStatus, Status: "", StatusMessage: ""}}
+	status := kabanerov1alpha2.StackStatus{Versions: sampleStackVersionStatus}
+
+	if failedAssets(status) == false {
+		t.Fatal("Should be one drifted asset in the status")
+	}
+}
+
 // Test that no failed assets are detected in the Stack instance status
 func TestNoFailedAssets(t *testing.T) {
 	var sampleAsset = []kabanerov1alpha2.RepositoryAssetStatus{{Name: "myAsset", Digest: "678910", Status: "active"},
@@ -146,6 +162,34 @@ func TestNoFailedAssetsEmptyStatus(t *testing.T) {
 	}
 }
 
+// Test that the Ready condition reflects failed versions and assets
+func TestSetReadyCondition(t *testing.T) {
+	status := kabanerov1alpha2.StackStatus{Versions: []kabanerov1alpha2.StackVersionStatus{{Version: "0.2.1", Status: "active"}}}
+	setReadyCondition(&status)
+	ready := status.GetCondition(kabanerov1alpha2.StackConditionReady)
+	if ready == nil || ready.Status != "True" {
+		t.Fatalf("Expected the stack to be ready: %+v", ready)
+	}
+
+	status.Versions = append(status.Versions, kabanerov1alpha2.StackVersionStatus{Version: "0.2.2", Status: kabanerov1alpha2.StackStateError, Reason: kabanerov1alpha2.StackReasonRegistryUnauthorized})
+	setReadyCondition(&status)
+	ready = status.GetCondition(kabanerov1alpha2.StackConditionReady)
+	if ready.Status != "False" || ready.Reason != kabanerov1alpha2.StackReasonRegistryUnauthorized || !strings.Contains(ready.Message, "0.2.2") {
+		t.Fatalf("Expected the stack not to be ready because of version 0.2.2: %+v", ready)
+	}
+
+	var sampleAsset = []kabanerov1alpha2.RepositoryAssetStatus{{Name: "myAsset2", Digest: "678911", Status: "failed", StatusMessage: "some failure"}}
+	status.Versions = []kabanerov1alpha2.StackVersionStatus{{Version: "0.2.1", Status: "active", Pipelines: []kabanerov1alpha2.PipelineStatus{{Name: "myAsset", ActiveAssets: sampleAsset}}}}
+	setReadyCondition(&status)
+	ready = status.GetCondition(kabanerov1alpha2.StackConditionReady)
+	if ready.Status != "False" || ready.Reason != "AssetsFailed" {
+		t.Fatalf("Expected the stack not to be ready because of a failed asset: %+v", ready)
+	}
+	if len(status.Conditions) != 1 {
+		t.Fatalf("Expected a single condition: %+v", status.Conditions)
+	}
+}
+
 func TestImageActivationDigestInStackStatus(t *testing.T) {
 	v026Digest := "026abcde"
 	v027Digest := "027abcde"
@@ -203,7 +247,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 	// Test 1. Stack with activation digest already set in status. Expectation: The same digest continues to be set.
 	stackResourceT1 := stackResource.DeepCopy()
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
-	err := reconcileActiveVersions(stackResourceT1, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), stackResourceT1, client, sctlog)
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
 	}
@@ -223,7 +267,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 	stackResourceT2.Spec.Versions = append(stackResourceT2.Spec.Versions, stackVersion027T2)
 	stackResourceT2.Status.Versions = append(stackResourceT2.Status.Versions, stackVersion027StatusT2)
 
-	err = reconcileActiveVersions(stackResourceT2, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), stackResourceT2, client, sctlog)
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
 	}
@@ -243,7 +287,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 	stackResourceT3.Spec.Versions[0].Images[0].Image = badImage026
 	stackResourceT3.Status.Versions[0].Images[0].Digest.Activation = ""
 	stackResourceT3.Status.Versions[0].Images[0].Digest.Message = ""
-	digest, err := getStatusImageDigest(client, *stackResourceT3, stackVersion026, badImage026, sctlog)
+	digest, err := getStatusImageDigest(context.Background(), client, *stackResourceT3, stackVersion026, badImage026, nil, nil, nil, sctlog)
 	if err == nil {
 		t.Fatal("An error should have been reported. Digest: ", digest)
 	}
@@ -267,7 +311,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 	stackResourceT4.Spec.Versions[0].Images[0].Image = badImage026
 	stackResourceT4.Status = kabanerov1alpha2.StackStatus{}
 
-	digest, err = getStatusImageDigest(client, *stackResourceT4, stackVersion026, badImage026, sctlog)
+	digest, err = getStatusImageDigest(context.Background(), client, *stackResourceT4, stackVersion026, badImage026, nil, nil, nil, sctlog)
 	if err == nil {
 		t.Fatal("An error should have been reported. Digest: ", digest)
 	}
@@ -293,7 +337,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 	stackResourceT5.Status.Versions[0].Images[0].Digest.Activation = ""
 	stackResourceT5.Status.Versions[0].Images[0].Digest.Message = testMsg6
 
-	digest, err = getStatusImageDigest(client, *stackResourceT5, stackVersion026, badImage026, sctlog)
+	digest, err = getStatusImageDigest(context.Background(), client, *stackResourceT5, stackVersion026, badImage026, nil, nil, nil, sctlog)
 	if err == nil {
 		t.Fatal("An error should have been reported. Digest: ", digest)
 	}
@@ -330,7 +374,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 	stackResourceT6.Spec.Versions[1].DesiredState = "inactive"
 
 	// Deactivate:
-	err = reconcileActiveVersions(stackResourceT6, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), stackResourceT6, client, sctlog)
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
 	}
@@ -344,7 +388,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 	stackResourceT6.Spec.Versions[0].DesiredState = "active"
 	stackResourceT6.Spec.Versions[1].DesiredState = "active"
 
-	err = reconcileActiveVersions(stackResourceT6, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), stackResourceT6, client, sctlog)
 	if err == nil {
 		t.Fatal("An error should have been reported.")
 	} else if !(strings.Contains(err.Error(), "image") && strings.Contains(err.Error(), "invalid reference format")) {
@@ -357,7 +401,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 	}
 
 	// Make targetted calls to getStatusImageDigest.
-	digest, err = getStatusImageDigest(client, *stackResourceT6, stackVersion026, badImage026, sctlog)
+	digest, err = getStatusImageDigest(context.Background(), client, *stackResourceT6, stackVersion026, badImage026, nil, nil, nil, sctlog)
 	if err == nil {
 		t.Fatal("An error should have been reported. Digest: ", digest)
 	}
@@ -374,7 +418,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 		t.Fatal("The message in stackResourceT6.Status.Versions[0].Images[0].Digest.Message does not have the expected content. Message: ", digest.Message)
 	}
 
-	digest, err = getStatusImageDigest(client, *stackResourceT6, stackVersion027, badImage027, sctlog)
+	digest, err = getStatusImageDigest(context.Background(), client, *stackResourceT6, stackVersion027, badImage027, nil, nil, nil, sctlog)
 	if err == nil {
 		t.Fatal("An error should have been reported. Digest: ", digest)
 	}
@@ -396,6 +440,7 @@ func TestImageActivationDigestInStackStatus(t *testing.T) {
 // Asset reuse tests
 // -------------------------------------------------------------------------------
 
+// An asset client that only keeps track of the owners of the objects.
 type unitTestClient struct {
 	// Objects that the client knows about.  This is real simple.... for now.  We just
 	// keep the name, and any owner references.
@@ -463,9 +508,6 @@ func (c unitTestClient) Delete(ctx context.Context, obj runtime.Object, opts ...
 	delete(c.objs, key)
 	return nil
 }
-func (c unitTestClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
-	return errors.New("DeleteAllOf is not supported")
-}
 func (c unitTestClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
 	u, ok := obj.(*unstructured.Unstructured)
 	if !ok {
@@ -483,57 +525,12 @@ func (c unitTestClient) Update(ctx context.Context, obj runtime.Object, opts ...
 	c.objs[key] = u.GetOwnerReferences()
 	return nil
 }
-func (c unitTestClient) Status() client.StatusWriter { return c }
-
-func (c unitTestClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
-	return errors.New("Patch is not supported")
-}
-
-// HTTP handler that serves pipeline zips
-type stackHandler struct {
-}
-
-func (ch stackHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	filename := fmt.Sprintf("testdata/%v", req.URL.String())
-	fmt.Printf("Serving %v\n", filename)
-	d, err := ioutil.ReadFile(filename)
-	if err != nil {
-		rw.WriteHeader(http.StatusNotFound)
-	} else {
-		rw.Write(d)
-	}
-}
-
-type fileInfo struct {
-	name   string
-	sha256 string
-}
 
 const (
 	myuid    = "MYUID"
 	otheruid = "OTHERUID"
 )
 
-var basicPipeline = fileInfo{
-	name:   "/basic.pipeline.tar.gz",
-	sha256: "8080076acd8f54ecbb7de132df148d964e5e93921cce983a0f781418b0871573"}
-
-var badPipeline = fileInfo{
-	name:   "/bad.pipeline.tar.gz",
-	sha256: "eca24c909ee2b463abcae7c3b8d1be406297e0e1958e43dff1185dc765af985b"}
-
-var digest1Pipeline = fileInfo{
-	name:   "/digest1.pipeline.tar.gz",
-	sha256: "0238ff31f191396ca4bf5e0ebeea323d012d5dbc7e3f0997e1bf66b017228aaf"}
-
-var digest2Pipeline = fileInfo{
-	name:   "/digest2.pipeline.tar.gz",
-	sha256: "c3f28ffca707942a8b351000722f1aebda080e3706aa006650a29d10f4aa226b"}
-
-var triggerPipeline = fileInfo{
-	name:   "/trigger.pipeline.tar.gz",
-	sha256: "901435c796815bbfdf7dd2f8fd44824c8d76535144af80b84ba0ae2fb65113f1"}
-
 // --------------------------------------------------------------------------------------------------
 // Test stack/stack id validation.
 // --------------------------------------------------------------------------------------------------
@@ -550,7 +547,7 @@ func TestStackIDValidation(t *testing.T) {
 	invalidID := "java-microprofile-"
 	stackResource.Spec.Name = invalidID
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err == nil {
 		t.Fatal(fmt.Sprintf("An error was expected because stack id %v is invalid. No error was issued.", invalidID))
@@ -563,7 +560,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test invalid id containing an upper case char.
 	invalidID = "java-Microprofile"
 	stackResource.Spec.Name = invalidID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err == nil {
 		t.Fatal(fmt.Sprintf("An error was expected because stack id %v is invalid. No error was issued.", invalidID))
@@ -576,7 +573,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test invalid id staritng with a number.
 	invalidID = "0-java-microprofile"
 	stackResource.Spec.Name = invalidID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err == nil {
 		t.Fatal(fmt.Sprintf("An error was expected because stack id %v is invalid. No error was issued.", invalidID))
@@ -589,7 +586,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test invalid id staritng with a dot char.
 	invalidID = "java-microprofile.1-0"
 	stackResource.Spec.Name = invalidID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err == nil {
 		t.Fatal(fmt.Sprintf("An error was expected because stack id %v is invalid. No error was issued.", invalidID))
@@ -602,7 +599,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test invalid id starting with invalid chars.
 	invalidID = "java#-microprofile@1-0"
 	stackResource.Spec.Name = invalidID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err == nil {
 		t.Fatal(fmt.Sprintf("An error was expected because stack id %v is invalid. No error was issued.", invalidID))
@@ -615,7 +612,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test invalid id containing a single '-'.
 	invalidID = "-"
 	stackResource.Spec.Name = invalidID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err == nil {
 		t.Fatal(fmt.Sprintf("An error was expected because stack id %v is invalid. No error was issued.", invalidID))
@@ -628,7 +625,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test invalid id containing a single number.
 	invalidID = "9"
 	stackResource.Spec.Name = invalidID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err == nil {
 		t.Fatal(fmt.Sprintf("An error was expected because stack id %v is invalid. No error was issued.", invalidID))
@@ -641,7 +638,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test invalid id with a length greater than 68 characters.
 	invalidID = "abcdefghij-abcdefghij-abcdefghij-abcdefghij-abcdefghij-abcdefghij-69c"
 	stackResource.Spec.Name = invalidID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err == nil {
 		t.Fatal(fmt.Sprintf("An error was expected because stack id %v is invalid. No error was issued.", invalidID))
@@ -654,7 +651,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test a valid id containing multiple [a-z0-9-] chars.
 	validID := "j-m-1-2-3"
 	stackResource.Spec.Name = validID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal(fmt.Sprintf("An error was NOT expected. Stack Id: %v is valid. Error: %v", validID, err))
@@ -663,7 +660,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test a valid id containing several '-' chars.
 	validID = "n---0"
 	stackResource.Spec.Name = validID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal(fmt.Sprintf("An error was NOT expected. Stack Id: %v is valid. Error: %v", validID, err))
@@ -672,7 +669,7 @@ func TestStackIDValidation(t *testing.T) {
 	// Test a valid id containing only one valid char.
 	validID = "x"
 	stackResource.Spec.Name = validID
-	err = reconcileActiveVersions(&stackResource, client, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal(fmt.Sprintf("An error was NOT expected. Stack Id: %v is valid. Error: %v", validID, err))
@@ -858,10 +855,10 @@ func TestDockerCfgSecAuth(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsInitial(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + basicPipeline.name
+	pipelineZipUrl := server.URL + testutils.BasicPipeline.Name
 
 	stackResource := kabanerov1alpha2.Stack{
 		ObjectMeta: metav1.ObjectMeta{UID: myuid, Namespace: "kabanero"},
@@ -872,7 +869,7 @@ func TestReconcileActiveVersionsInitial(t *testing.T) {
 				DesiredState: "active",
 				Pipelines: []kabanerov1alpha2.PipelineSpec{{
 					Id:     "default",
-					Sha256: basicPipeline.sha256,
+					Sha256: testutils.BasicPipeline.Sha256,
 					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: pipelineZipUrl, SkipCertVerification: true},
 				}},
 				Images: []kabanerov1alpha2.Image{{
@@ -886,7 +883,7 @@ func TestReconcileActiveVersionsInitial(t *testing.T) {
 
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -981,15 +978,15 @@ func TestReconcileActiveVersionsInitial(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsUpgrade(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + basicPipeline.name
+	pipelineZipUrl := server.URL + testutils.BasicPipeline.Name
 	desiredStack := Stack{
 		Name:      "java-microprofile",
 		Id:        "java-microprofile",
 		Version:   "0.2.5",
-		Pipelines: []Pipelines{{Id: "default", Sha256: basicPipeline.sha256, Url: pipelineZipUrl}},
+		Pipelines: []Pipelines{{Id: "default", Sha256: testutils.BasicPipeline.Sha256, Url: pipelineZipUrl}},
 	}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -1034,7 +1031,7 @@ func TestReconcileActiveVersionsUpgrade(t *testing.T) {
 		client.ObjectKey{Name: "java-microprofile-build-pipeline", Namespace: "kabanero"}: []metav1.OwnerReference{{UID: myuid}},
 		client.ObjectKey{Name: "java-microprofile-old-asset", Namespace: "kabanero"}:      []metav1.OwnerReference{{UID: myuid}}}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1129,15 +1126,15 @@ func TestReconcileActiveVersionsUpgrade(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsDeactivate(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + basicPipeline.name
+	pipelineZipUrl := server.URL + testutils.BasicPipeline.Name
 	desiredStack := Stack{
 		Name:      "java-microprofile",
 		Id:        "java-microprofile",
 		Version:   "0.2.5",
-		Pipelines: []Pipelines{{Id: "default", Sha256: basicPipeline.sha256, Url: pipelineZipUrl}},
+		Pipelines: []Pipelines{{Id: "default", Sha256: testutils.BasicPipeline.Sha256, Url: pipelineZipUrl}},
 	}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -1159,7 +1156,7 @@ func TestReconcileActiveVersionsDeactivate(t *testing.T) {
 				Version: "0.2.5",
 				Pipelines: []kabanerov1alpha2.PipelineStatus{{
 					Url:    pipelineZipUrl,
-					Digest: basicPipeline.sha256,
+					Digest: testutils.BasicPipeline.Sha256,
 					Name:   "default",
 					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
 						Name:   "java-microprofile-build-task",
@@ -1178,7 +1175,7 @@ func TestReconcileActiveVersionsDeactivate(t *testing.T) {
 		client.ObjectKey{Name: "java-microprofile-build-task", Namespace: "kabanero"}:     []metav1.OwnerReference{{UID: myuid}},
 		client.ObjectKey{Name: "java-microprofile-build-pipeline", Namespace: "kabanero"}: []metav1.OwnerReference{{UID: myuid}}}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1226,15 +1223,15 @@ func TestReconcileActiveVersionsDeactivate(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsSharedAsset(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + basicPipeline.name
+	pipelineZipUrl := server.URL + testutils.BasicPipeline.Name
 	desiredStack := Stack{
 		Name:      "java-microprofile",
 		Id:        "java-microprofile",
 		Version:   "0.2.5",
-		Pipelines: []Pipelines{{Id: "default", Sha256: basicPipeline.sha256, Url: pipelineZipUrl}},
+		Pipelines: []Pipelines{{Id: "default", Sha256: testutils.BasicPipeline.Sha256, Url: pipelineZipUrl}},
 	}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -1259,7 +1256,7 @@ func TestReconcileActiveVersionsSharedAsset(t *testing.T) {
 		client.ObjectKey{Name: "java-microprofile-build-task", Namespace: "kabanero"}:     []metav1.OwnerReference{{UID: otheruid}},
 		client.ObjectKey{Name: "java-microprofile-build-pipeline", Namespace: "kabanero"}: []metav1.OwnerReference{{UID: otheruid}}}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1327,15 +1324,15 @@ func TestReconcileActiveVersionsSharedAsset(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsSharedAssetDeactivate(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + basicPipeline.name
+	pipelineZipUrl := server.URL + testutils.BasicPipeline.Name
 	desiredStack := Stack{
 		Name:      "java-microprofile",
 		Id:        "java-microprofile",
 		Version:   "0.2.5",
-		Pipelines: []Pipelines{{Id: "default", Sha256: basicPipeline.sha256, Url: pipelineZipUrl}},
+		Pipelines: []Pipelines{{Id: "default", Sha256: testutils.BasicPipeline.Sha256, Url: pipelineZipUrl}},
 	}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -1357,7 +1354,7 @@ func TestReconcileActiveVersionsSharedAssetDeactivate(t *testing.T) {
 				Version: "0.2.5",
 				Pipelines: []kabanerov1alpha2.PipelineStatus{{
 					Url:    pipelineZipUrl,
-					Digest: basicPipeline.sha256,
+					Digest: testutils.BasicPipeline.Sha256,
 					Name:   "default",
 					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
 						Name:   "java-microprofile-build-task",
@@ -1376,7 +1373,7 @@ func TestReconcileActiveVersionsSharedAssetDeactivate(t *testing.T) {
 		client.ObjectKey{Name: "java-microprofile-build-task", Namespace: "kabanero"}:     []metav1.OwnerReference{{UID: otheruid}, {UID: myuid}},
 		client.ObjectKey{Name: "java-microprofile-build-pipeline", Namespace: "kabanero"}: []metav1.OwnerReference{{UID: otheruid}, {UID: myuid}}}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1418,15 +1415,15 @@ func TestReconcileActiveVersionsSharedAssetDeactivate(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsRecreatedDeletedAssets(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + basicPipeline.name
+	pipelineZipUrl := server.URL + testutils.BasicPipeline.Name
 	desiredStack := Stack{
 		Name:      "java-microprofile",
 		Id:        "java-microprofile",
 		Version:   "0.2.5",
-		Pipelines: []Pipelines{{Id: "default", Sha256: basicPipeline.sha256, Url: pipelineZipUrl}},
+		Pipelines: []Pipelines{{Id: "default", Sha256: testutils.BasicPipeline.Sha256, Url: pipelineZipUrl}},
 	}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -1448,7 +1445,7 @@ func TestReconcileActiveVersionsRecreatedDeletedAssets(t *testing.T) {
 				Version: "0.2.5",
 				Pipelines: []kabanerov1alpha2.PipelineStatus{{
 					Url:    pipelineZipUrl,
-					Digest: basicPipeline.sha256,
+					Digest: testutils.BasicPipeline.Sha256,
 					Name:   "default",
 					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
 						Name:   "java-microprofile-build-task",
@@ -1466,7 +1463,7 @@ func TestReconcileActiveVersionsRecreatedDeletedAssets(t *testing.T) {
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{
 		client.ObjectKey{Name: "java-microprofile-build-task", Namespace: "kabanero"}: []metav1.OwnerReference{{UID: myuid}}}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1526,19 +1523,19 @@ func TestReconcileActiveVersionsRecreatedDeletedAssets(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsRecreatedDeletedAssetsNoManifest(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	deletedPipeline := fileInfo{
-		name:   "/deleted.pipeline.tar.gz",
-		sha256: "aaaabbbbccccdddd"}
+	deletedPipeline := testutils.Archive{
+		Name:   "/deleted.pipeline.tar.gz",
+		Sha256: "aaaabbbbccccdddd"}
 
-	pipelineZipUrl := server.URL + deletedPipeline.name
+	pipelineZipUrl := server.URL + deletedPipeline.Name
 	desiredStack := Stack{
 		Name:      "java-microprofile",
 		Id:        "java-microprofile",
 		Version:   "0.2.5",
-		Pipelines: []Pipelines{{Id: "default", Sha256: deletedPipeline.sha256, Url: pipelineZipUrl}},
+		Pipelines: []Pipelines{{Id: "default", Sha256: deletedPipeline.Sha256, Url: pipelineZipUrl}},
 	}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -1560,7 +1557,7 @@ func TestReconcileActiveVersionsRecreatedDeletedAssetsNoManifest(t *testing.T) {
 				Version: "0.2.5",
 				Pipelines: []kabanerov1alpha2.PipelineStatus{{
 					Url:    pipelineZipUrl,
-					Digest: deletedPipeline.sha256,
+					Digest: deletedPipeline.Sha256,
 					Name:   "default",
 					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
 						Name:   "java-microprofile-build-task",
@@ -1578,7 +1575,7 @@ func TestReconcileActiveVersionsRecreatedDeletedAssetsNoManifest(t *testing.T) {
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{
 		client.ObjectKey{Name: "java-microprofile-build-task", Namespace: "kabanero"}: []metav1.OwnerReference{{UID: myuid}}}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1654,15 +1651,15 @@ func TestReconcileActiveVersionsRecreatedDeletedAssetsNoManifest(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsBadAsset(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + badPipeline.name
+	pipelineZipUrl := server.URL + testutils.BadPipeline.Name
 	desiredStack := Stack{
 		Name:      "java-microprofile",
 		Id:        "java-microprofile",
 		Version:   "0.2.5",
-		Pipelines: []Pipelines{{Id: "default", Sha256: badPipeline.sha256, Url: pipelineZipUrl}},
+		Pipelines: []Pipelines{{Id: "default", Sha256: testutils.BadPipeline.Sha256, Url: pipelineZipUrl}},
 	}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -1684,7 +1681,7 @@ func TestReconcileActiveVersionsBadAsset(t *testing.T) {
 
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1752,18 +1749,18 @@ func TestReconcileActiveVersionsBadAsset(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsWithTriggers(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
 	defaultImage := Images{Id: "default", Image: "kabanero/kabanero-image:latest"}
 	desiredImage := Images{Id: "default", Image: "docker.io/kabanero/kabanero-image"}
 
-	pipelineZipUrl := server.URL + triggerPipeline.name
+	pipelineZipUrl := server.URL + testutils.TriggerPipeline.Name
 	desiredStack := Stack{
 		Name:      "java-microprofile",
 		Id:        "java-microprofile",
 		Version:   "0.2.5",
-		Pipelines: []Pipelines{{Id: "default", Sha256: triggerPipeline.sha256, Url: pipelineZipUrl}},
+		Pipelines: []Pipelines{{Id: "default", Sha256: testutils.TriggerPipeline.Sha256, Url: pipelineZipUrl}},
 		Images:    []Images{desiredImage},
 	}
 
@@ -1790,7 +1787,7 @@ func TestReconcileActiveVersionsWithTriggers(t *testing.T) {
 
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1917,10 +1914,10 @@ func TestReconcileActiveVersionsWithTriggers(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsSkipCertVerify(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewTLSServer(stackHandler{})
+	server := testutils.NewTLSServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + basicPipeline.name
+	pipelineZipUrl := server.URL + testutils.BasicPipeline.Name
 
 	stackResource := kabanerov1alpha2.Stack{
 		ObjectMeta: metav1.ObjectMeta{UID: myuid, Namespace: "kabanero"},
@@ -1931,7 +1928,7 @@ func TestReconcileActiveVersionsSkipCertVerify(t *testing.T) {
 				DesiredState: "active",
 				Pipelines: []kabanerov1alpha2.PipelineSpec{{
 					Id:     "default",
-					Sha256: basicPipeline.sha256,
+					Sha256: testutils.BasicPipeline.Sha256,
 					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: pipelineZipUrl},
 				}},
 				Images: []kabanerov1alpha2.Image{{
@@ -1945,7 +1942,7 @@ func TestReconcileActiveVersionsSkipCertVerify(t *testing.T) {
 
 	kubeClient := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
 
-	err := reconcileActiveVersions(&stackResource, kubeClient, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, kubeClient, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -1979,7 +1976,7 @@ func TestReconcileActiveVersionsSkipCertVerify(t *testing.T) {
 	stackResource.Spec.Versions[0].Pipelines[0].Https.SkipCertVerification = true
 
 	kubeClient = unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
-	err = reconcileActiveVersions(&stackResource, kubeClient, sctlog)
+	err = reconcileActiveVersions(context.Background(), &stackResource, kubeClient, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -2017,24 +2014,24 @@ func TestReconcileActiveVersionsSkipCertVerify(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsInternalTwoInitial(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipelineZipUrl := server.URL + basicPipeline.name
+	pipelineZipUrl := server.URL + testutils.BasicPipeline.Name
 	stacks := []resolvedStack{{
 		repositoryURL: "",
 		stack: Stack{
 			Name:      "java-microprofile",
 			Id:        "java-microprofile",
 			Version:   "0.2.5",
-			Pipelines: []Pipelines{{Id: "default", Sha256: basicPipeline.sha256, Url: pipelineZipUrl}}},
+			Pipelines: []Pipelines{{Id: "default", Sha256: testutils.BasicPipeline.Sha256, Url: pipelineZipUrl}}},
 	}, {
 		repositoryURL: "",
 		stack: Stack{
 			Name:      "java-microprofile",
 			Id:        "java-microprofile",
 			Version:   "0.2.6",
-			Pipelines: []Pipelines{{Id: "default", Sha256: basicPipeline.sha256, Url: pipelineZipUrl}}},
+			Pipelines: []Pipelines{{Id: "default", Sha256: testutils.BasicPipeline.Sha256, Url: pipelineZipUrl}}},
 	}}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -2067,7 +2064,7 @@ func TestReconcileActiveVersionsInternalTwoInitial(t *testing.T) {
 
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -2145,25 +2142,25 @@ func TestReconcileActiveVersionsInternalTwoInitial(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsInternalTwoInitialDiffPipelines(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipeline1ZipUrl := server.URL + digest1Pipeline.name
-	pipeline2ZipUrl := server.URL + digest2Pipeline.name
+	pipeline1ZipUrl := server.URL + testutils.Digest1Pipeline.Name
+	pipeline2ZipUrl := server.URL + testutils.Digest2Pipeline.Name
 	stacks := []resolvedStack{{
 		repositoryURL: "",
 		stack: Stack{
 			Name:      "java-microprofile",
 			Id:        "java-microprofile",
 			Version:   "0.2.5",
-			Pipelines: []Pipelines{{Id: "default", Sha256: digest1Pipeline.sha256, Url: pipeline1ZipUrl}}},
+			Pipelines: []Pipelines{{Id: "default", Sha256: testutils.Digest1Pipeline.Sha256, Url: pipeline1ZipUrl}}},
 	}, {
 		repositoryURL: "",
 		stack: Stack{
 			Name:      "java-microprofile",
 			Id:        "java-microprofile",
 			Version:   "0.2.6",
-			Pipelines: []Pipelines{{Id: "default", Sha256: digest2Pipeline.sha256, Url: pipeline2ZipUrl}}},
+			Pipelines: []Pipelines{{Id: "default", Sha256: testutils.Digest2Pipeline.Sha256, Url: pipeline2ZipUrl}}},
 	}}
 
 	stackResource := kabanerov1alpha2.Stack{
@@ -2196,7 +2193,7 @@ func TestReconcileActiveVersionsInternalTwoInitialDiffPipelines(t *testing.T) {
 
 	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -2261,11 +2258,11 @@ func TestReconcileActiveVersionsInternalTwoInitialDiffPipelines(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsInternalTwoDeactivateOne(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipeline1ZipUrl := server.URL + digest1Pipeline.name
-	pipeline2ZipUrl := server.URL + digest2Pipeline.name
+	pipeline1ZipUrl := server.URL + testutils.Digest1Pipeline.Name
+	pipeline2ZipUrl := server.URL + testutils.Digest2Pipeline.Name
 
 	stacks := []resolvedStack{{
 		repositoryURL: "",
@@ -2273,14 +2270,14 @@ func TestReconcileActiveVersionsInternalTwoDeactivateOne(t *testing.T) {
 			Name:      "java-microprofile",
 			Id:        "java-microprofile",
 			Version:   "0.2.5",
-			Pipelines: []Pipelines{{Id: "default", Sha256: digest1Pipeline.sha256, Url: pipeline1ZipUrl}}},
+			Pipelines: []Pipelines{{Id: "default", Sha256: testutils.Digest1Pipeline.Sha256, Url: pipeline1ZipUrl}}},
 	}, {
 		repositoryURL: "",
 		stack: Stack{
 			Name:      "java-microprofile",
 			Id:        "java-microprofile",
 			Version:   "0.2.6",
-			Pipelines: []Pipelines{{Id: "default", Sha256: digest2Pipeline.sha256, Url: pipeline2ZipUrl}},
+			Pipelines: []Pipelines{{Id: "default", Sha256: testutils.Digest2Pipeline.Sha256, Url: pipeline2ZipUrl}},
 		}},
 	}
 
@@ -2303,7 +2300,7 @@ func TestReconcileActiveVersionsInternalTwoDeactivateOne(t *testing.T) {
 				Version: "0.2.5",
 				Pipelines: []kabanerov1alpha2.PipelineStatus{{
 					Url:    pipeline1ZipUrl,
-					Digest: digest1Pipeline.sha256,
+					Digest: testutils.Digest1Pipeline.Sha256,
 					Name:   "default",
 					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
 						Name:   "build-task-0238ff31",
@@ -2317,7 +2314,7 @@ func TestReconcileActiveVersionsInternalTwoDeactivateOne(t *testing.T) {
 				Version: "0.2.6",
 				Pipelines: []kabanerov1alpha2.PipelineStatus{{
 					Url:    pipeline2ZipUrl,
-					Digest: digest2Pipeline.sha256,
+					Digest: testutils.Digest2Pipeline.Sha256,
 					Name:   "default",
 					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
 						Name:   "build-task-c3f28ffc",
@@ -2337,7 +2334,7 @@ func TestReconcileActiveVersionsInternalTwoDeactivateOne(t *testing.T) {
 		client.ObjectKey{Name: "build-task-c3f28ffc", Namespace: "kabanero"}:     []metav1.OwnerReference{{UID: myuid}},
 		client.ObjectKey{Name: "build-pipeline-c3f28ffc", Namespace: "kabanero"}: []metav1.OwnerReference{{UID: myuid}}}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -2395,18 +2392,18 @@ func TestReconcileActiveVersionsInternalTwoDeactivateOne(t *testing.T) {
 // --------------------------------------------------------------------------------------------------
 func TestReconcileActiveVersionsInternalTwoDeleteOne(t *testing.T) {
 	// The server that will host the pipeline zip
-	server := httptest.NewServer(stackHandler{})
+	server := testutils.NewServer("testdata")
 	defer server.Close()
 
-	pipeline1ZipUrl := server.URL + digest1Pipeline.name
-	pipeline2ZipUrl := server.URL + digest2Pipeline.name
+	pipeline1ZipUrl := server.URL + testutils.Digest1Pipeline.Name
+	pipeline2ZipUrl := server.URL + testutils.Digest2Pipeline.Name
 	stacks := []resolvedStack{{
 		repositoryURL: "",
 		stack: Stack{
 			Name:      "java-microprofile",
 			Id:        "java-microprofile",
 			Version:   "0.2.5",
-			Pipelines: []Pipelines{{Id: "default", Sha256: digest1Pipeline.sha256, Url: pipeline1ZipUrl}},
+			Pipelines: []Pipelines{{Id: "default", Sha256: testutils.Digest1Pipeline.Sha256, Url: pipeline1ZipUrl}},
 		},
 	}, {
 		repositoryURL: "",
@@ -2414,7 +2411,7 @@ func TestReconcileActiveVersionsInternalTwoDeleteOne(t *testing.T) {
 			Name:      "java-microprofile",
 			Id:        "java-microprofile",
 			Version:   "0.2.6",
-			Pipelines: []Pipelines{{Id: "default", Sha256: digest2Pipeline.sha256, Url: pipeline2ZipUrl}},
+			Pipelines: []Pipelines{{Id: "default", Sha256: testutils.Digest2Pipeline.Sha256, Url: pipeline2ZipUrl}},
 		},
 	}}
 
@@ -2448,7 +2445,7 @@ func TestReconcileActiveVersionsInternalTwoDeleteOne(t *testing.T) {
 				Version: "0.2.5",
 				Pipelines: []kabanerov1alpha2.PipelineStatus{{
 					Url:    pipeline1ZipUrl,
-					Digest: digest1Pipeline.sha256,
+					Digest: testutils.Digest1Pipeline.Sha256,
 					Name:   "default",
 					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
 						Name:   "build-task-0238ff31",
@@ -2462,7 +2459,7 @@ func TestReconcileActiveVersionsInternalTwoDeleteOne(t *testing.T) {
 				Version: "0.2.6",
 				Pipelines: []kabanerov1alpha2.PipelineStatus{{
 					Url:    pipeline2ZipUrl,
-					Digest: digest2Pipeline.sha256,
+					Digest: testutils.Digest2Pipeline.Sha256,
 					Name:   "default",
 					ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{
 						Name:   "build-task-c3f28ffc",
@@ -2482,7 +2479,7 @@ func TestReconcileActiveVersionsInternalTwoDeleteOne(t *testing.T) {
 		client.ObjectKey{Name: "build-task-c3f28ffc", Namespace: "kabanero"}:     []metav1.OwnerReference{{UID: myuid}},
 		client.ObjectKey{Name: "build-pipeline-c3f28ffc", Namespace: "kabanero"}: []metav1.OwnerReference{{UID: myuid}}}}
 
-	err := reconcileActiveVersions(&stackResource, client, sctlog)
+	err := reconcileActiveVersions(context.Background(), &stackResource, client, sctlog)
 
 	if err != nil {
 		t.Fatal("Returned error: " + err.Error())
@@ -2551,3 +2548,93 @@ func TestReconcileActiveVersionsInternalTwoDeleteOne(t *testing.T) {
 }
 
 // TODO: More "multiple stack" tests...
+
+// Client that records status patches.
+type statusPatchTestClient struct {
+	patches *[]string
+}
+
+func (c statusPatchTestClient) Status() client.StatusWriter { return c }
+
+func (c statusPatchTestClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return errors.New("Update is not supported")
+}
+
+func (c statusPatchTestClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	*c.patches = append(*c.patches, string(data))
+	return nil
+}
+
+// Test that the status is only written when it changes, and only the status is patched.
+func TestPatchStackStatus(t *testing.T) {
+	patches := []string{}
+	c := statusPatchTestClient{patches: &patches}
+
+	original := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "java-microprofile", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.StackSpec{Name: "java-microprofile"},
+		Status:     kabanerov1alpha2.StackStatus{Summary: "[ 0.2.5: active ]"},
+	}
+
+	stack := original.DeepCopy()
+	stack.Spec.Name = "changed"
+	err := patchStackStatus(context.TODO(), c, original, stack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 0 {
+		t.Fatalf("Expected no patch when the status is unchanged, but found %v", patches)
+	}
+
+	stack.Status.Summary = "[ 0.2.5: error ]"
+	err = patchStackStatus(context.TODO(), c, original, stack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 1 || patches[0] != `{"status":{"summary":"[ 0.2.5: error ]"}}` {
+		t.Fatalf("Expected a patch of the summary only, but found %v", patches)
+	}
+}
+
+// Test that the digest of an image is retrieved from its registry, and follows the tag.
+func TestRetrieveImageDigestFromRegistry(t *testing.T) {
+	server := testutils.NewServer("")
+	defer server.Close()
+
+	c := utesting.NewClient()
+	image := server.Host() + "/kabanero/nodejs:0.3"
+	digest := server.AddImage("kabanero/nodejs", "0.3", "nodejs 0.3.1")
+
+	found, err := retrieveImageDigest(context.TODO(), c, "kabanero", server.Host(), false, nil, nil, sctlog, image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if "sha256:"+found != digest {
+		t.Fatalf("Expected digest %v, but found %v", digest, found)
+	}
+
+	// The tag moves to a new image.
+	digest = server.AddImage("kabanero/nodejs", "0.3", "nodejs 0.3.2")
+	found, err = retrieveImageDigest(context.TODO(), c, "kabanero", server.Host(), false, nil, nil, sctlog, image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if "sha256:"+found != digest {
+		t.Fatalf("Expected digest %v after the tag moved, but found %v", digest, found)
+	}
+
+	// A missing manifest is reported, and not retried.
+	server.FailOn("/v2/kabanero/nodejs/manifests/0.3", http.StatusNotFound, 1)
+	_, err = retrieveImageDigest(context.TODO(), c, "kabanero", server.Host(), false, nil, nil, sctlog, image)
+	if err == nil {
+		t.Fatal("Expected the retrieval of a missing manifest to fail")
+	}
+	_, err = retrieveImageDigest(context.TODO(), c, "kabanero", server.Host(), false, nil, nil, sctlog, image)
+	if err != nil {
+		t.Fatalf("Expected the retrieval to succeed after the injected failure, but found %v", err)
+	}
+}
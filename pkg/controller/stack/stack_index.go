@@ -1,5 +1,11 @@
 package stack
 
+import (
+	"fmt"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
 // Index holds data pertaining to an index referencing a set of stacks.
 type Index struct {
 	// API Version.
@@ -12,9 +18,68 @@ type Index struct {
 	Triggers []Trigger `yaml:"triggers,omitempty"`
 }
 
+// Returns a copy of the index that shares no slices with it.
+func (i Index) deepCopy() Index {
+	out := i
+	out.Triggers = append([]Trigger(nil), i.Triggers...)
+	if i.Stacks != nil {
+		out.Stacks = make([]Stack, len(i.Stacks))
+		for j, s := range i.Stacks {
+			s.Images = append([]Images(nil), s.Images...)
+			s.Maintainers = append([]Maintainers(nil), s.Maintainers...)
+			s.Pipelines = append([]Pipelines(nil), s.Pipelines...)
+			s.Templates = append([]Templates(nil), s.Templates...)
+			out.Stacks[j] = s
+		}
+	}
+	return out
+}
+
 // Trigger holds Trigger information.
 type Trigger struct {
 	Id     string `yaml:"id,omitempty"`
 	Url    string `yaml:"url,omitempty"`
 	Sha256 string `yaml:"sha256,omitempty"`
 }
+
+// Returns the Stack CR version described by an index entry.
+func (c Stack) GetStackVersion(repositoryUrl string, skipRegistryCertVerification bool) kabanerov1alpha2.StackVersion {
+	// The pipeline information will be in the stack, either because this is a legacy hub and the information was already there, or
+	// because we provided it at the time we read the appsody stack index (in ResolveIndex).
+	pipelines := []kabanerov1alpha2.PipelineSpec{}
+	for _, pipeline := range c.Pipelines {
+		pipelineUrl := kabanerov1alpha2.HttpsProtocolFile{Url: pipeline.Url, SkipCertVerification: pipeline.SkipCertVerification}
+		pipelines = append(pipelines, kabanerov1alpha2.PipelineSpec{Id: pipeline.Id, Sha256: pipeline.Sha256, Https: pipelineUrl, GitRelease: pipeline.GitRelease})
+	}
+
+	// The image information will be in the stack.  Today we just support reading the legacy field from the collection hub.
+	images := []kabanerov1alpha2.Image{}
+	for _, image := range c.Images {
+		images = append(images, kabanerov1alpha2.Image{Id: image.Id, Image: image.Image})
+	}
+
+	stackVersion := kabanerov1alpha2.StackVersion{Pipelines: pipelines, Version: c.Version, Images: images, SkipRegistryCertVerification: skipRegistryCertVerification, RepositoryUrl: repositoryUrl, Deprecated: c.Deprecated}
+
+	// An end of support date that cannot be parsed is left out, so that it does not prevent
+	// the stack from being updated.
+	if _, err := kabanerov1alpha2.ParseEndOfSupport(c.EndOfSupport); err == nil {
+		stackVersion.EndOfSupport = c.EndOfSupport
+	}
+
+	return stackVersion
+}
+
+// Returns the location of a stack repository, for reporting in the stack status.
+func GetRepositoryUrl(r kabanerov1alpha2.RepositoryConfig) string {
+	_, resolverName, _ := getIndexResolver(r)
+	switch resolverName {
+	case kabanerov1alpha2.IndexResolverGit:
+		return fmt.Sprintf("https://%v/%v/%v/releases/%v/%v", r.GitRelease.Hostname, r.GitRelease.Organization, r.GitRelease.Project, r.GitRelease.Release, r.GitRelease.AssetName)
+	case kabanerov1alpha2.IndexResolverConfigMap:
+		return fmt.Sprintf("configmap://%v/%v", r.ConfigMapRef.Name, r.ConfigMapRef.GetKey())
+	case kabanerov1alpha2.IndexResolverOci:
+		return fmt.Sprintf("%v%v/%v", kabanerov1alpha2.OciBundleUrlPrefix, r.Oci.Image, r.Oci.GetPath())
+	}
+
+	return r.Https.Url
+}
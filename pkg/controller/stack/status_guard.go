@@ -0,0 +1,245 @@
+package stack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The largest serialized stack status that is written without compaction.  The
+// status is part of the Stack object, which must stay well below the etcd limit.
+const statusSizeLimit = 512 * 1024
+
+// The length that asset status messages are truncated to when the status is compacted.
+const truncatedMessageLength = 128
+
+// The key in the companion ConfigMap that holds the full asset status messages.
+const assetStatusDetailsKey = "assetStatus.json"
+
+// Appended to an asset status message that was truncated.
+const truncatedMessageSuffix = "... (truncated)"
+
+// The full status message of an asset whose message was truncated.
+type assetStatusDetail struct {
+	Version       string `json:"version"`
+	Pipeline      string `json:"pipeline"`
+	Asset         string `json:"asset"`
+	Namespace     string `json:"namespace,omitempty"`
+	Status        string `json:"status,omitempty"`
+	StatusMessage string `json:"statusMessage"`
+}
+
+// Keeps the stack status below the size limit.  If the status is too large, the
+// asset status messages are truncated, and the full messages are written to a
+// companion ConfigMap owned by the stack.  The ConfigMap is deleted once the
+// status fits again.
+func compactStackStatus(ctx context.Context, c client.Client, stack *kabanerov1alpha2.Stack, reqLogger logr.Logger) error {
+	size, err := statusSize(stack.Status)
+	if err != nil {
+		return err
+	}
+
+	configMapName := stack.GetName() + "-status"
+
+	// A status that was compacted before keeps its ConfigMap while it still holds
+	// truncated messages.
+	if size <= statusSizeLimit {
+		if stack.Status.DetailsConfigMap == "" || hasTruncatedMessages(stack.Status) {
+			return nil
+		}
+
+		cm := &corev1.ConfigMap{}
+		err = c.Get(ctx, client.ObjectKey{Name: stack.Status.DetailsConfigMap, Namespace: stack.GetNamespace()}, cm)
+		if err == nil {
+			reqLogger.Info(fmt.Sprintf("Deleting stack status details ConfigMap %v", cm.GetName()))
+			err = c.Delete(ctx, cm)
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+
+		stack.Status.DetailsConfigMap = ""
+		return nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = c.Get(ctx, client.ObjectKey{Name: configMapName, Namespace: stack.GetNamespace()}, cm)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	// Messages truncated by an earlier compaction are still in the status.  Their full
+	// text is only in the ConfigMap.
+	previous := []assetStatusDetail{}
+	if found && len(cm.Data[assetStatusDetailsKey]) != 0 {
+		err = json.Unmarshal([]byte(cm.Data[assetStatusDetailsKey]), &previous)
+		if err != nil {
+			reqLogger.Error(err, fmt.Sprintf("Unable to read the stack status details in ConfigMap %v", configMapName))
+		}
+	}
+
+	details := truncateAssetMessages(&stack.Status, truncatedMessageLength, previous)
+
+	// If the truncated messages are still too large, they are dropped from the status.
+	// Only their full text in the ConfigMap is kept.
+	size, err = statusSize(stack.Status)
+	if err != nil {
+		return err
+	}
+	if size > statusSizeLimit {
+		details = truncateAssetMessages(&stack.Status, 0, details)
+		size, err = statusSize(stack.Status)
+		if err != nil {
+			return err
+		}
+	}
+
+	detailBytes, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		ownerIsController := true
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapName,
+				Namespace: stack.GetNamespace(),
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: stack.TypeMeta.APIVersion,
+						Kind:       stack.TypeMeta.Kind,
+						Name:       stack.GetName(),
+						UID:        stack.GetUID(),
+						Controller: &ownerIsController,
+					},
+				},
+			},
+			Data: map[string]string{assetStatusDetailsKey: string(detailBytes)},
+		}
+
+		reqLogger.Info(fmt.Sprintf("Creating stack status details ConfigMap %v", configMapName))
+		err = c.Create(ctx, cm)
+	} else {
+		cm.Data = map[string]string{assetStatusDetailsKey: string(detailBytes)}
+		err = c.Update(ctx, cm)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to write the stack status details to ConfigMap %v: %v", configMapName, err)
+	}
+
+	stack.Status.DetailsConfigMap = configMapName
+	message := fmt.Sprintf("The stack status was compacted because it exceeded %v bytes. %v asset status messages were truncated. The full messages are in ConfigMap %v.", statusSizeLimit, len(details), configMapName)
+	if stack.Status.StatusMessage != "" {
+		message = stack.Status.StatusMessage + " " + message
+	}
+	stack.Status.StatusMessage = message
+
+	if size > statusSizeLimit {
+		return fmt.Errorf("The stack status is %v bytes after it was compacted, which exceeds the limit of %v bytes", size, statusSizeLimit)
+	}
+
+	return nil
+}
+
+// Returns the size of the serialized stack status.
+func statusSize(status kabanerov1alpha2.StackStatus) (int, error) {
+	b, err := json.Marshal(status)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Identifies the asset of an asset status detail.
+type assetStatusDetailKey struct {
+	version   string
+	pipeline  string
+	asset     string
+	namespace string
+}
+
+// Truncates the asset status messages longer than the input length, and returns
+// the full messages that were truncated.  Messages that are already truncated get
+// their full text from the previous details, if it is there.
+func truncateAssetMessages(status *kabanerov1alpha2.StackStatus, length int, previous []assetStatusDetail) []assetStatusDetail {
+	previousDetails := make(map[assetStatusDetailKey]assetStatusDetail)
+	for _, detail := range previous {
+		previousDetails[assetStatusDetailKey{detail.Version, detail.Pipeline, detail.Asset, detail.Namespace}] = detail
+	}
+
+	details := []assetStatusDetail{}
+	for i, _ := range status.Versions {
+		version := &status.Versions[i]
+		for j, _ := range version.Pipelines {
+			pipeline := &version.Pipelines[j]
+			for k, _ := range pipeline.ActiveAssets {
+				asset := &pipeline.ActiveAssets[k]
+				if strings.HasSuffix(asset.StatusMessage, truncatedMessageSuffix) {
+					// The full text is gone if it is not in the previous details, so
+					// only the remaining prefix can be shortened.
+					detail, ok := previousDetails[assetStatusDetailKey{version.Version, pipeline.Name, asset.Name, asset.Namespace}]
+					if !ok {
+						prefix := strings.TrimSuffix(asset.StatusMessage, truncatedMessageSuffix)
+						asset.StatusMessage = truncateMessage(prefix, length) + truncatedMessageSuffix
+						continue
+					}
+					details = append(details, detail)
+					asset.StatusMessage = truncateMessage(detail.StatusMessage, length) + truncatedMessageSuffix
+					continue
+				}
+				if len(asset.StatusMessage) <= length {
+					continue
+				}
+
+				details = append(details, assetStatusDetail{
+					Version:       version.Version,
+					Pipeline:      pipeline.Name,
+					Asset:         asset.Name,
+					Namespace:     asset.Namespace,
+					Status:        asset.Status,
+					StatusMessage: asset.StatusMessage,
+				})
+				asset.StatusMessage = truncateMessage(asset.StatusMessage, length) + truncatedMessageSuffix
+			}
+		}
+	}
+
+	return details
+}
+
+// Returns the longest prefix of the message that is at most length bytes long and does
+// not split a multi-byte character.
+func truncateMessage(message string, length int) string {
+	if len(message) <= length {
+		return message
+	}
+	for length > 0 && !utf8.RuneStart(message[length]) {
+		length--
+	}
+	return message[:length]
+}
+
+// Returns true if an asset status message in the status was truncated.
+func hasTruncatedMessages(status kabanerov1alpha2.StackStatus) bool {
+	for _, version := range status.Versions {
+		for _, pipeline := range version.Pipelines {
+			for _, asset := range pipeline.ActiveAssets {
+				if strings.HasSuffix(asset.StatusMessage, truncatedMessageSuffix) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package stack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	utesting "github.com/kabanero-io/kabanero-operator/pkg/controller/utils/testing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Test that only the long asset status messages are truncated, and that the full
+// messages are returned.
+func TestTruncateAssetMessages(t *testing.T) {
+	longMessage := strings.Repeat("x", 200)
+	status := kabanerov1alpha2.StackStatus{
+		Versions: []kabanerov1alpha2.StackVersionStatus{{
+			Version: "1.2.3",
+			Pipelines: []kabanerov1alpha2.PipelineStatus{{
+				Name: "default",
+				ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{
+					{Name: "short", Status: "failed", StatusMessage: "short message"},
+					{Name: "long", Namespace: "kabanero", Status: "failed", StatusMessage: longMessage},
+				},
+			}},
+		}},
+	}
+
+	details := truncateAssetMessages(&status, truncatedMessageLength, nil)
+	if len(details) != 1 {
+		t.Fatalf("Expected one truncated message, but found %v: %v", len(details), details)
+	}
+	if details[0].Asset != "long" || details[0].Version != "1.2.3" || details[0].Pipeline != "default" || details[0].StatusMessage != longMessage {
+		t.Fatalf("Unexpected asset status detail: %v", details[0])
+	}
+
+	assets := status.Versions[0].Pipelines[0].ActiveAssets
+	if assets[0].StatusMessage != "short message" {
+		t.Fatalf("The short message should not have been truncated: %v", assets[0].StatusMessage)
+	}
+	if !strings.HasPrefix(assets[1].StatusMessage, longMessage[:truncatedMessageLength]) || len(assets[1].StatusMessage) >= len(longMessage) {
+		t.Fatalf("The long message was not truncated: %v", assets[1].StatusMessage)
+	}
+}
+
+// Test that messages are truncated on character boundaries, and that messages that were
+// already truncated are shortened from their full text.
+func TestTruncateAssetMessagesMultiByte(t *testing.T) {
+	longMessage := strings.Repeat("é", 100)
+	status := kabanerov1alpha2.StackStatus{
+		Versions: []kabanerov1alpha2.StackVersionStatus{{
+			Version: "1.2.3",
+			Pipelines: []kabanerov1alpha2.PipelineStatus{{
+				Name:         "default",
+				ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{Name: "long", Status: "failed", StatusMessage: longMessage}},
+			}},
+		}},
+	}
+
+	details := truncateAssetMessages(&status, 9, nil)
+	message := status.Versions[0].Pipelines[0].ActiveAssets[0].StatusMessage
+	if !utf8.ValidString(message) || message != strings.Repeat("é", 4)+truncatedMessageSuffix {
+		t.Fatalf("Expected the message to be truncated to 4 characters, but found %v", message)
+	}
+
+	details = truncateAssetMessages(&status, 0, details)
+	message = status.Versions[0].Pipelines[0].ActiveAssets[0].StatusMessage
+	if message != truncatedMessageSuffix || len(details) != 1 || details[0].StatusMessage != longMessage {
+		t.Fatalf("Expected the message to be dropped and its full text kept, but found %v and %v", message, details)
+	}
+}
+
+// Test that a small status is left alone.
+func TestCompactStackStatusSmallStatus(t *testing.T) {
+	stack := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "java-microprofile", Namespace: "kabanero"},
+		Status: kabanerov1alpha2.StackStatus{
+			StatusMessage: "A message",
+		},
+	}
+
+	err := compactStackStatus(context.Background(), utesting.NewClient(), stack, logf.Log.WithName("status_guard_test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stack.Status.StatusMessage != "A message" || stack.Status.DetailsConfigMap != "" {
+		t.Fatalf("The status should not have changed: %v", stack.Status)
+	}
+}
+
+// Test that the full messages survive a second compaction of an already compacted
+// status, and that the ConfigMap is deleted once the status is small again.
+func TestCompactStackStatusDetailsConfigMap(t *testing.T) {
+	logger := logf.Log.WithName("status_guard_test")
+	longMessage := strings.Repeat("x", 300)
+	assets := []kabanerov1alpha2.RepositoryAssetStatus{}
+	for i := 0; i < 4000; i++ {
+		assets = append(assets, kabanerov1alpha2.RepositoryAssetStatus{Name: fmt.Sprintf("asset-%v", i), Status: "failed", StatusMessage: longMessage})
+	}
+	stack := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "java-microprofile", Namespace: "kabanero"},
+		Status: kabanerov1alpha2.StackStatus{
+			Versions: []kabanerov1alpha2.StackVersionStatus{{
+				Version:   "1.2.3",
+				Pipelines: []kabanerov1alpha2.PipelineStatus{{Name: "default", ActiveAssets: assets}},
+			}},
+		},
+	}
+
+	c := utesting.NewClient()
+	checkDetails := func() {
+		cm := &corev1.ConfigMap{}
+		err := c.Get(context.Background(), client.ObjectKey{Name: "java-microprofile-status", Namespace: "kabanero"}, cm)
+		if err != nil || stack.Status.DetailsConfigMap != "java-microprofile-status" {
+			t.Fatalf("Expected the details ConfigMap to be written: %v", stack.Status.DetailsConfigMap)
+		}
+		details := []assetStatusDetail{}
+		if err := json.Unmarshal([]byte(cm.Data[assetStatusDetailsKey]), &details); err != nil {
+			t.Fatal(err)
+		}
+		if len(details) != len(assets) || details[0].StatusMessage != longMessage {
+			t.Fatalf("Expected %v full messages, but found %v", len(assets), len(details))
+		}
+	}
+
+	if err := compactStackStatus(context.Background(), c, stack, logger); err != nil {
+		t.Fatal(err)
+	}
+	checkDetails()
+
+	// The next reconcile starts from the compacted status, whose messages were dropped
+	// because truncating them was not enough.
+	stack.Status.StatusMessage = ""
+	if err := compactStackStatus(context.Background(), c, stack, logger); err != nil {
+		t.Fatal(err)
+	}
+	checkDetails()
+
+	// The status is small again.
+	stack.Status.Versions = nil
+	if err := compactStackStatus(context.Background(), c, stack, logger); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Objects()) != 0 || stack.Status.DetailsConfigMap != "" {
+		t.Fatalf("Expected the details ConfigMap to be deleted: %v", stack.Status.DetailsConfigMap)
+	}
+}
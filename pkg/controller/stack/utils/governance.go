@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// A stack version that may not be activated under the governance policy of the Kabanero instance.
+type GovernanceViolation struct {
+	// One of the StackReason values.
+	Reason  string
+	Message string
+}
+
+// Checks the active versions of the input stack against the governance policy, and returns
+// the violations by version.  A version that breaks several rules reports the first one:
+// banned stack ids, then allowed registries, then required digests, then the maximum number
+// of active versions, which only counts the versions that broke no other rule.
+func CheckGovernancePolicy(policy kabanerov1alpha2.GovernancePolicyConfig, spec kabanerov1alpha2.StackSpec) map[string]GovernanceViolation {
+	violations := make(map[string]GovernanceViolation)
+	if !policy.HasStackRules() {
+		return violations
+	}
+
+	banned := false
+	for _, id := range policy.BannedStackIds {
+		if id == spec.Name {
+			banned = true
+		}
+	}
+
+	allowed := []kabanerov1alpha2.StackVersion{}
+	for _, version := range spec.Versions {
+		if strings.EqualFold(version.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
+			continue
+		}
+
+		if banned {
+			violations[version.Version] = GovernanceViolation{
+				Reason:  kabanerov1alpha2.StackReasonStackBanned,
+				Message: fmt.Sprintf("Stack %v is banned by the governance policy of the Kabanero instance.", spec.Name)}
+			continue
+		}
+
+		if violation := checkImageRegistries(policy, spec.Name, version); violation != nil {
+			violations[version.Version] = *violation
+			continue
+		}
+
+		if violation := checkPipelineDigests(policy, spec.Name, version); violation != nil {
+			violations[version.Version] = *violation
+			continue
+		}
+
+		allowed = append(allowed, version)
+	}
+
+	// Keep the most recent versions active.  Versions that are not semver are the oldest.
+	if policy.MaxActiveVersions > 0 && len(allowed) > policy.MaxActiveVersions {
+		sort.SliceStable(allowed, func(i, j int) bool {
+			return compareStackVersions(allowed[i].Version, allowed[j].Version) > 0
+		})
+		for _, version := range allowed[policy.MaxActiveVersions:] {
+			violations[version.Version] = GovernanceViolation{
+				Reason:  kabanerov1alpha2.StackReasonTooManyActiveVersions,
+				Message: fmt.Sprintf("Stack %v %v is not activated because the governance policy of the Kabanero instance allows %v active versions of a stack, and more recent versions are active.", spec.Name, version.Version, policy.MaxActiveVersions)}
+		}
+	}
+
+	return violations
+}
+
+// Returns a violation if an image of the stack version is not from an allowed registry.
+func checkImageRegistries(policy kabanerov1alpha2.GovernancePolicyConfig, stackName string, version kabanerov1alpha2.StackVersion) *GovernanceViolation {
+	if len(policy.AllowedRegistries) == 0 {
+		return nil
+	}
+
+	for _, image := range version.Images {
+		registry, err := GetImageRegistry(image.Image)
+		if err != nil {
+			return &GovernanceViolation{
+				Reason:  kabanerov1alpha2.StackReasonRegistryNotAllowed,
+				Message: fmt.Sprintf("Unable to determine the registry of image %v associated with stack %v %v: %v", image.Image, stackName, version.Version, err.Error())}
+		}
+
+		if !isRegistryAllowed(registry, policy.AllowedRegistries) {
+			return &GovernanceViolation{
+				Reason:  kabanerov1alpha2.StackReasonRegistryNotAllowed,
+				Message: fmt.Sprintf("Image %v associated with stack %v %v is from registry %v, which is not allowed by the governance policy of the Kabanero instance. The allowed registries are: %v", image.Image, stackName, version.Version, registry, strings.Join(policy.AllowedRegistries, ", "))}
+		}
+	}
+
+	return nil
+}
+
+// Returns a violation if a pipeline of the stack version does not set a digest, and the
+// policy requires one.
+func checkPipelineDigests(policy kabanerov1alpha2.GovernancePolicyConfig, stackName string, version kabanerov1alpha2.StackVersion) *GovernanceViolation {
+	if !policy.RequireDigests {
+		return nil
+	}
+
+	for _, pipeline := range version.Pipelines {
+		if len(pipeline.Sha256) == 0 {
+			return &GovernanceViolation{
+				Reason:  kabanerov1alpha2.StackReasonDigestRequired,
+				Message: fmt.Sprintf("Pipeline %v associated with stack %v %v does not set a sha256, which the governance policy of the Kabanero instance requires.", pipeline.Id, stackName, version.Version)}
+		}
+	}
+
+	return nil
+}
+
+// Returns true if the input registry is one of the allowed registries.  Registry names are
+// not case sensitive.
+func isRegistryAllowed(registry string, allowedRegistries []string) bool {
+	for _, allowed := range allowedRegistries {
+		if strings.EqualFold(registry, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Compares two stack versions by semver.  A version that is not semver is lower than one
+// that is, and two that are not are equal.
+func compareStackVersions(a string, b string) int {
+	va, errA := semver.ParseTolerant(a)
+	vb, errB := semver.ParseTolerant(b)
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return va.Compare(vb)
+}
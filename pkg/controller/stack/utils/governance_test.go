@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Returns a stack version with one image and one pipeline.
+func governedStackVersion(version string, image string, sha256 string) kabanerov1alpha2.StackVersion {
+	return kabanerov1alpha2.StackVersion{
+		Version:   version,
+		Images:    []kabanerov1alpha2.Image{{Id: "nodejs", Image: image}},
+		Pipelines: []kabanerov1alpha2.PipelineSpec{{Id: "default", Sha256: sha256}},
+	}
+}
+
+// Test that the active versions of a stack are checked against the governance policy.
+func TestCheckGovernancePolicy(t *testing.T) {
+	spec := kabanerov1alpha2.StackSpec{
+		Name: "nodejs",
+		Versions: []kabanerov1alpha2.StackVersion{
+			governedStackVersion("0.1.0", "docker.io/kabanero/nodejs", "1234"),
+			governedStackVersion("0.2.0", "quay.io/kabanero/nodejs", "1234"),
+			governedStackVersion("0.3.0", "kabanero/nodejs", ""),
+			governedStackVersion("0.4.0", "kabanero/nodejs", "1234"),
+			governedStackVersion("0.5.0", "kabanero/nodejs", "1234"),
+		},
+	}
+	spec.Versions[1].DesiredState = kabanerov1alpha2.StackDesiredStateInactive
+
+	// No rules.
+	if violations := CheckGovernancePolicy(kabanerov1alpha2.GovernancePolicyConfig{}, spec); len(violations) != 0 {
+		t.Fatalf("Expected no violations without rules, but found %v", violations)
+	}
+
+	// The inactive version from quay.io is not checked, and docker.io is the default registry.
+	policy := kabanerov1alpha2.GovernancePolicyConfig{AllowedRegistries: []string{"Docker.io"}}
+	if violations := CheckGovernancePolicy(policy, spec); len(violations) != 0 {
+		t.Fatalf("Expected no registry violations, but found %v", violations)
+	}
+
+	policy = kabanerov1alpha2.GovernancePolicyConfig{AllowedRegistries: []string{"quay.io"}, RequireDigests: true, MaxActiveVersions: 1}
+	violations := CheckGovernancePolicy(policy, spec)
+	if len(violations) != 4 {
+		t.Fatalf("Expected 4 violations, but found %v", violations)
+	}
+	for _, version := range []string{"0.1.0", "0.3.0", "0.4.0", "0.5.0"} {
+		if violations[version].Reason != kabanerov1alpha2.StackReasonRegistryNotAllowed {
+			t.Errorf("Expected version %v to violate the allowed registries, but found %v", version, violations[version])
+		}
+	}
+
+	// The version without a digest does not count towards the maximum, and the most recent version stays active.
+	policy = kabanerov1alpha2.GovernancePolicyConfig{RequireDigests: true, MaxActiveVersions: 1}
+	violations = CheckGovernancePolicy(policy, spec)
+	expected := map[string]string{
+		"0.1.0": kabanerov1alpha2.StackReasonTooManyActiveVersions,
+		"0.3.0": kabanerov1alpha2.StackReasonDigestRequired,
+		"0.4.0": kabanerov1alpha2.StackReasonTooManyActiveVersions,
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("Expected %v violations, but found %v", len(expected), violations)
+	}
+	for version, reason := range expected {
+		if violations[version].Reason != reason {
+			t.Errorf("Expected version %v to have reason %v, but found %v", version, reason, violations[version])
+		}
+	}
+
+	// A banned stack cannot activate any version.
+	policy = kabanerov1alpha2.GovernancePolicyConfig{BannedStackIds: []string{"nodejs"}}
+	violations = CheckGovernancePolicy(policy, spec)
+	if len(violations) != 4 || violations["0.5.0"].Reason != kabanerov1alpha2.StackReasonStackBanned {
+		t.Errorf("Expected every active version to be banned, but found %v", violations)
+	}
+}
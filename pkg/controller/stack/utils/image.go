@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"strings"
+
 	reference "github.com/docker/distribution/reference"
 )
 
@@ -44,3 +46,25 @@ func GetImageRegistry(image string) (string, error) {
 
 	return domain, nil
 }
+
+// Rewrites the registry (domain) part of the input image using the input registry mirrors, which
+// map a registry to the registry that mirrors it.  The image is returned unchanged if its
+// registry is not mirrored.  The tag or digest of the image is preserved.
+func ApplyRegistryMirrors(image string, mirrors map[string]string) (string, error) {
+	if len(mirrors) == 0 {
+		return image, nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+
+	domain := reference.Domain(named)
+	mirror, found := mirrors[domain]
+	if !found {
+		return image, nil
+	}
+
+	return strings.TrimSuffix(mirror, "/") + strings.TrimPrefix(named.String(), domain), nil
+}
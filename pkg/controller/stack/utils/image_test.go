@@ -210,3 +210,40 @@ func TestGetImageRegistry(t *testing.T) {
 		t.Fatal(fmt.Sprintf("The registry retrieved was %v, but it was expected to be: %v", registry, expectedReg))
 	}
 }
+
+// Tests that ApplyRegistryMirrors rewrites the registry of mirrored images only.
+func TestApplyRegistryMirrors(t *testing.T) {
+	mirrors := map[string]string{"docker.io": "registry.internal:5000", "quay.io": "registry.internal:5000/quay/"}
+
+	// Test 1. Default registry is mirrored.
+	image := "kabanero/kabanero-image:1.2.3"
+	mirrored, err := ApplyRegistryMirrors(image, mirrors)
+	expectedImage := "registry.internal:5000/kabanero/kabanero-image:1.2.3"
+	if err != nil {
+		t.Fatal(fmt.Sprintf("A mirrored image was expected. An error was received instead. Image: %v. Expected image: %v. Error: %v", image, expectedImage, err))
+	}
+	if mirrored != expectedImage {
+		t.Fatal(fmt.Sprintf("The mirrored image was %v, but it was expected to be: %v", mirrored, expectedImage))
+	}
+
+	// Test 2. Mirror with a path.
+	image = "quay.io/kabanero/kabanero-image@sha256:8080076acd8f54ecbb7de132df148d964e5e93921cce983a0f781418b0871573"
+	mirrored, err = ApplyRegistryMirrors(image, mirrors)
+	expectedImage = "registry.internal:5000/quay/kabanero/kabanero-image@sha256:8080076acd8f54ecbb7de132df148d964e5e93921cce983a0f781418b0871573"
+	if err != nil {
+		t.Fatal(fmt.Sprintf("A mirrored image was expected. An error was received instead. Image: %v. Expected image: %v. Error: %v", image, expectedImage, err))
+	}
+	if mirrored != expectedImage {
+		t.Fatal(fmt.Sprintf("The mirrored image was %v, but it was expected to be: %v", mirrored, expectedImage))
+	}
+
+	// Test 3. Registry is not mirrored.
+	image = "my-registry.io/kabanero/kabanero-image:1.2.3"
+	mirrored, err = ApplyRegistryMirrors(image, mirrors)
+	if err != nil {
+		t.Fatal(fmt.Sprintf("The image was expected. An error was received instead. Image: %v. Error: %v", image, err))
+	}
+	if mirrored != image {
+		t.Fatal(fmt.Sprintf("The image was %v, but it was expected to be unchanged: %v", mirrored, image))
+	}
+}
@@ -0,0 +1,605 @@
+package stack
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	reference "github.com/docker/distribution/reference"
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Before a stack version is activated, the images of the version can be checked by the
+// vulnerability scanner configured in the Kabanero instance.  The Vulnerable condition
+// reports the versions whose images have too many critical vulnerabilities.
+
+// The secret entry that holds the scanner token.
+const vulnerabilityScanTokenKey = "token"
+
+// How long the report of an image is reused.  New vulnerabilities are published for
+// images that do not change, so reports are not kept for long.
+const vulnerabilityReportTTL = 10 * time.Minute
+
+// How long an expired report is kept.  While the image is scanned again, the versions that
+// were activated with the expired report keep it, rather than being deactivated.
+const vulnerabilityReportRetention = time.Hour
+
+// How long the scanner is given to scan an image, and how long to wait between requests
+// for the report when the scanner does not say.  The stack is reconciled again while a
+// scan is pending, rather than waiting for it.
+const (
+	vulnerabilityScanTimeout      = 10 * time.Minute
+	vulnerabilityScanRefreshAfter = 5 * time.Second
+)
+
+// The media types of the Harbor pluggable scanner API, which the Trivy adapter serves.
+const (
+	scannerAdapterScanRequestType  = "application/vnd.scanner.adapter.scan.request+json; version=1.0"
+	scannerAdapterScanResponseType = "application/vnd.scanner.adapter.scan.response+json; version=1.0"
+	scannerAdapterReportType       = "application/vnd.scanner.adapter.vuln.report.harbor+json; version=1.0"
+	dockerManifestType             = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// The number of vulnerabilities of each severity that the scanner reports for an image.
+type vulnerabilityReport struct {
+	Critical int
+	High     int
+	Medium   int
+	Low      int
+}
+
+// Counts a vulnerability of the input severity, as named by Clair or Trivy.  Clair names
+// the most severe vulnerabilities Defcon1.  Unknown severities are not counted.
+func (r *vulnerabilityReport) add(severity string) {
+	switch strings.ToLower(severity) {
+	case "critical", "defcon1":
+		r.Critical++
+	case "high":
+		r.High++
+	case "medium":
+		r.Medium++
+	case "low", "negligible":
+		r.Low++
+	}
+}
+
+// The outcome of the scan of the images of a stack version.
+type versionScan struct {
+	// The images with more critical vulnerabilities than allowed.
+	vulnerable []string
+
+	// The images that could not be scanned, and why.
+	failed []string
+
+	// The images whose scan has not completed.
+	pending []string
+}
+
+// Returned when the scanner has not completed the scan of an image yet.
+type scanPendingError struct {
+	image string
+}
+
+func (e *scanPendingError) Error() string {
+	return fmt.Sprintf("The scan of image %v has not completed", e.image)
+}
+
+// Returns true if the input error reports a scan that has not completed.
+func isScanPending(err error) bool {
+	var pending *scanPendingError
+	return errors.As(err, &pending)
+}
+
+// Reads vulnerability reports from the scanner configured in a Kabanero instance.
+type vulnerabilityScanner struct {
+	url     string
+	scanner string
+	token   string
+	client  *http.Client
+}
+
+// Value in the report cache map.
+type reportCacheValue struct {
+	report vulnerabilityReport
+	expiry time.Time
+}
+
+// The reports shared by all stacks, by scanner URL and image.
+var vulnerabilityReports = struct {
+	sync.Mutex
+	reports map[string]reportCacheValue
+}{reports: make(map[string]reportCacheValue)}
+
+// A scan that the Trivy adapter accepted, and whose report is not ready yet.
+type pendingScan struct {
+	id        string
+	started   time.Time
+	nextCheck time.Time
+}
+
+// The scans that the Trivy adapter has not completed, by scanner URL and image.
+var pendingScans = struct {
+	sync.Mutex
+	scans map[string]pendingScan
+}{scans: make(map[string]pendingScan)}
+
+// Returns a scanner for the input configuration.  The token is read from the secret in
+// the input namespace.
+func newVulnerabilityScanner(c client.Reader, namespace string, spec kabanerov1alpha2.VulnerabilityScanSpec) (*vulnerabilityScanner, error) {
+	scanner := &vulnerabilityScanner{url: strings.TrimSuffix(spec.Url, "/"), scanner: spec.Scanner}
+	if len(spec.TokenSecretName) != 0 {
+		secret := &corev1.Secret{}
+		err := c.Get(context.Background(), client.ObjectKey{Name: spec.TokenSecretName, Namespace: namespace}, secret)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to retrieve vulnerability scanner secret %v in namespace %v. Error: %v", spec.TokenSecretName, namespace, err)
+		}
+		token, ok := secret.Data[vulnerabilityScanTokenKey]
+		if !ok {
+			return nil, fmt.Errorf("Vulnerability scanner secret %v in namespace %v has no %v entry", spec.TokenSecretName, namespace, vulnerabilityScanTokenKey)
+		}
+		scanner.token = strings.TrimSpace(string(token))
+	}
+
+	var tlsConfig *tls.Config
+	if spec.SkipCertVerification {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	scanner.client = &http.Client{Transport: cache.NewTransport(tlsConfig)}
+
+	return scanner, nil
+}
+
+// Returns the report of the input image, which is referenced by digest.  Recent reports are
+// taken from the cache.  The Trivy adapter pulls the image using the input Authorization
+// header value, if there is one.  A scanPendingError is returned while the scanner has not
+// completed the scan, unless there is an expired report of the image, which is returned
+// until the new one is ready.
+func (s *vulnerabilityScanner) scan(ctx context.Context, image string, authorization string) (vulnerabilityReport, error) {
+	key := s.url + "/" + image
+	now := time.Now()
+	vulnerabilityReports.Lock()
+	value, found := vulnerabilityReports.reports[key]
+	vulnerabilityReports.Unlock()
+	if found && now.Before(value.expiry) {
+		return value.report, nil
+	}
+
+	ref, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return vulnerabilityReport{}, fmt.Errorf("Unable to parse image %v: %v", image, err)
+	}
+	digested, ok := ref.(reference.Digested)
+	if !ok {
+		return vulnerabilityReport{}, fmt.Errorf("Image %v is not referenced by digest", image)
+	}
+
+	var report vulnerabilityReport
+	if s.scanner == kabanerov1alpha2.VulnerabilityScannerTrivy {
+		report, err = s.fetchTrivyReport(ctx, key, ref, digested.Digest().String(), authorization, now)
+	} else {
+		report, err = s.fetchQuayReport(ctx, ref, digested.Digest().String())
+	}
+	if err != nil {
+		if found && isScanPending(err) {
+			return value.report, nil
+		}
+		return report, err
+	}
+
+	vulnerabilityReports.Lock()
+	defer vulnerabilityReports.Unlock()
+	for key, value := range vulnerabilityReports.reports {
+		if !now.Before(value.expiry.Add(vulnerabilityReportRetention)) {
+			delete(vulnerabilityReports.reports, key)
+		}
+	}
+	vulnerabilityReports.reports[key] = reportCacheValue{report: report, expiry: now.Add(vulnerabilityReportTTL)}
+
+	return report, nil
+}
+
+// Sends a request to the scanner, with the token if there is one.
+func (s *vulnerabilityScanner) do(ctx context.Context, client *http.Client, method string, requestURL string, contentType string, accept string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", accept)
+	if len(contentType) != 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if len(s.token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return client.Do(req)
+}
+
+// The security scan of a manifest, as returned by the Quay API.  Clair reports the
+// vulnerabilities of each package (feature) found in the layers of the image.
+type quaySecurityScan struct {
+	Status string `json:"status"`
+	Data   *struct {
+		Layer struct {
+			Features []struct {
+				Name            string `json:"Name"`
+				Vulnerabilities []struct {
+					Name     string `json:"Name"`
+					Severity string `json:"Severity"`
+				} `json:"Vulnerabilities"`
+			} `json:"Features"`
+		} `json:"Layer"`
+	} `json:"data"`
+}
+
+// Reads the report of the input image from the security scan of the Quay registry.  The
+// image must be in the registry: Quay only scans the images it holds.
+func (s *vulnerabilityScanner) fetchQuayReport(ctx context.Context, image reference.Named, digest string) (vulnerabilityReport, error) {
+	report := vulnerabilityReport{}
+	scanURL, err := url.Parse(s.url)
+	if err != nil {
+		return report, fmt.Errorf("Unable to parse the vulnerability scanner URL %v: %v", s.url, err)
+	}
+	if reference.Domain(image) != scanURL.Host {
+		return report, fmt.Errorf("Unable to scan image %v: it is not in the Quay registry %v", image, scanURL.Host)
+	}
+
+	securityURL := fmt.Sprintf("%v/api/v1/repository/%v/manifest/%v/security?vulnerabilities=true", s.url, reference.Path(image), digest)
+	resp, err := s.do(ctx, s.client, http.MethodGet, securityURL, "", "application/json", nil)
+	if err != nil {
+		return report, fmt.Errorf("Unable to scan image %v: %v", image, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return report, fmt.Errorf("Unable to scan image %v: Quay returned status %v", image, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return report, fmt.Errorf("Unable to read the report of image %v: %v", image, err)
+	}
+
+	security := quaySecurityScan{}
+	err = json.Unmarshal(body, &security)
+	if err != nil {
+		return report, fmt.Errorf("Unable to parse the report of image %v: %v", image, err)
+	}
+
+	// The image is queued while Quay has not scanned it yet, and the scan fails for images
+	// that Clair does not support.
+	if security.Status == "queued" {
+		return report, &scanPendingError{image: image.String()}
+	}
+	if security.Status != "scanned" || security.Data == nil {
+		return report, fmt.Errorf("Unable to scan image %v: the Quay security scan status is %v", image, security.Status)
+	}
+
+	for _, feature := range security.Data.Layer.Features {
+		for _, vulnerability := range feature.Vulnerabilities {
+			report.add(vulnerability.Severity)
+		}
+	}
+	return report, nil
+}
+
+// A scan request of the Harbor pluggable scanner API.
+type scannerAdapterScanRequest struct {
+	Registry struct {
+		URL           string `json:"url"`
+		Authorization string `json:"authorization,omitempty"`
+	} `json:"registry"`
+	Artifact struct {
+		Repository string `json:"repository"`
+		Digest     string `json:"digest"`
+		MimeType   string `json:"mime_type"`
+	} `json:"artifact"`
+}
+
+// The response to a scan request of the Harbor pluggable scanner API.
+type scannerAdapterScanResponse struct {
+	ID string `json:"id"`
+}
+
+// A vulnerability report of the Harbor pluggable scanner API.
+type scannerAdapterReport struct {
+	Vulnerabilities []struct {
+		ID       string `json:"id"`
+		Severity string `json:"severity"`
+	} `json:"vulnerabilities"`
+}
+
+// Reads the report of the input image from the Trivy scanner adapter.  The adapter is asked
+// to scan the image, which it pulls from its registry using the input Authorization header
+// value.  The report is then requested, at most once per call, until the scan completes.  A
+// scanPendingError is returned until then.  The scan is recorded under the input key.
+func (s *vulnerabilityScanner) fetchTrivyReport(ctx context.Context, key string, image reference.Named, digest string, authorization string, now time.Time) (vulnerabilityReport, error) {
+	report := vulnerabilityReport{}
+
+	pendingScans.Lock()
+	scan, found := pendingScans.scans[key]
+	pendingScans.Unlock()
+	if !found {
+		id, err := s.requestTrivyScan(ctx, image, digest, authorization)
+		if err != nil {
+			return report, err
+		}
+		scan = pendingScan{id: id, started: now, nextCheck: now}
+	}
+	if now.Before(scan.nextCheck) {
+		return report, &scanPendingError{image: image.String()}
+	}
+
+	// The adapter answers 302 Found, with the seconds to wait in Refresh-After, until the
+	// report is ready.  The redirect is not followed.
+	client := *s.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	reportURL := fmt.Sprintf("%v/api/v1/scan/%v/report", s.url, url.PathEscape(scan.id))
+	resp, err := s.do(ctx, &client, http.MethodGet, reportURL, "", scannerAdapterReportType, nil)
+	if err != nil {
+		// The report is requested again on the next reconcile.
+		return report, fmt.Errorf("Unable to read the report of image %v: %v", image, err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusFound {
+		if now.Sub(scan.started) > vulnerabilityScanTimeout {
+			removePendingScan(key)
+			return report, fmt.Errorf("Unable to scan image %v: the Trivy adapter did not complete the scan in %v", image, vulnerabilityScanTimeout)
+		}
+
+		refreshAfter := vulnerabilityScanRefreshAfter
+		if seconds, err := strconv.Atoi(resp.Header.Get("Refresh-After")); err == nil && seconds >= 0 {
+			refreshAfter = time.Duration(seconds) * time.Second
+		}
+		scan.nextCheck = now.Add(refreshAfter)
+		pendingScans.Lock()
+		pendingScans.scans[key] = scan
+		pendingScans.Unlock()
+		return report, &scanPendingError{image: image.String()}
+	}
+
+	// The scan is over, whether or not the report can be read.  A new scan is requested the
+	// next time the image is scanned.
+	removePendingScan(key)
+	if resp.StatusCode != http.StatusOK {
+		return report, fmt.Errorf("Unable to read the report of image %v: the Trivy adapter returned status %v", image, resp.StatusCode)
+	}
+	if err != nil {
+		return report, fmt.Errorf("Unable to read the report of image %v: %v", image, err)
+	}
+	adapterReport := scannerAdapterReport{}
+	err = json.Unmarshal(body, &adapterReport)
+	if err != nil {
+		return report, fmt.Errorf("Unable to parse the report of image %v: %v", image, err)
+	}
+	for _, vulnerability := range adapterReport.Vulnerabilities {
+		report.add(vulnerability.Severity)
+	}
+	return report, nil
+}
+
+// Asks the Trivy adapter to scan the input image, and returns the id of the scan.
+func (s *vulnerabilityScanner) requestTrivyScan(ctx context.Context, image reference.Named, digest string, authorization string) (string, error) {
+	domain := reference.Domain(image)
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+	scanRequest := scannerAdapterScanRequest{}
+	scanRequest.Registry.URL = "https://" + domain
+	scanRequest.Registry.Authorization = authorization
+	scanRequest.Artifact.Repository = reference.Path(image)
+	scanRequest.Artifact.Digest = digest
+	scanRequest.Artifact.MimeType = dockerManifestType
+	body, err := json.Marshal(scanRequest)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.do(ctx, s.client, http.MethodPost, s.url+"/api/v1/scan", scannerAdapterScanRequestType, scannerAdapterScanResponseType, body)
+	if err != nil {
+		return "", fmt.Errorf("Unable to scan image %v: %v", image, err)
+	}
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return "", fmt.Errorf("Unable to scan image %v: the Trivy adapter returned status %v", image, resp.StatusCode)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Unable to read the scan of image %v: %v", image, err)
+	}
+	scanResponse := scannerAdapterScanResponse{}
+	err = json.Unmarshal(body, &scanResponse)
+	if err != nil || len(scanResponse.ID) == 0 {
+		return "", fmt.Errorf("Unable to parse the scan of image %v: %v", image, err)
+	}
+	return scanResponse.ID, nil
+}
+
+// Forgets the pending scan recorded under the input key.
+func removePendingScan(key string) {
+	pendingScans.Lock()
+	defer pendingScans.Unlock()
+	delete(pendingScans.scans, key)
+}
+
+// Returns the Authorization header value that lets a scanner pull images from the registry
+// of the input image: the first credentials for the registry, from the input pull secrets,
+// the secret annotated for the registry, or a cloud registry token.  Returns an empty value
+// if the registry is accessed anonymously.
+func getScannerRegistryAuthorization(c client.Reader, namespace string, pullSecrets []string, image string, logger logr.Logger) (string, error) {
+	registry, err := sutils.GetImageRegistry(image)
+	if err != nil {
+		return "", fmt.Errorf("Unable to parse registry from image %v: %v", image, err)
+	}
+
+	authenticators, err := GetRegistryAuthenticators(c, namespace, pullSecrets, registry, logger)
+	if err != nil || len(authenticators) == 0 {
+		return "", err
+	}
+	auth, err := authenticators[0].Authorization()
+	if err != nil {
+		return "", fmt.Errorf("Unable to read the credentials of registry %v: %v", registry, err)
+	}
+
+	switch {
+	case len(auth.RegistryToken) != 0:
+		return "Bearer " + auth.RegistryToken, nil
+	case len(auth.Auth) != 0:
+		return "Basic " + auth.Auth, nil
+	case len(auth.Username) != 0 || len(auth.Password) != 0:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password)), nil
+	}
+	return "", nil
+}
+
+// Scans the images of the versions of the input stack that are to be activated.  Versions
+// that are inactive, or that violate the governance policy, are not scanned.  The digest
+// of each image is returned by lookupDigest.
+func scanStackVersions(spec kabanerov1alpha2.StackSpec, violations map[string]sutils.GovernanceViolation, maxCritical int, lookupDigest func(kabanerov1alpha2.StackVersion, string) (string, error), scan func(kabanerov1alpha2.StackVersion, string) (vulnerabilityReport, error)) map[string]versionScan {
+	scans := make(map[string]versionScan)
+	for _, version := range spec.Versions {
+		if strings.EqualFold(version.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
+			continue
+		}
+		if _, violated := violations[version.Version]; violated {
+			continue
+		}
+
+		result := versionScan{}
+		for _, image := range version.Images {
+			digest, err := lookupDigest(version, image.Image)
+			if err != nil {
+				result.failed = append(result.failed, err.Error())
+				continue
+			}
+
+			// The activation digests in the stack status are recorded without their algorithm.
+			if !strings.Contains(digest, ":") {
+				digest = "sha256:" + digest
+			}
+			reference := image.Image + "@" + digest
+			report, err := scan(version, reference)
+			if isScanPending(err) {
+				result.pending = append(result.pending, reference)
+				continue
+			}
+			if err != nil {
+				result.failed = append(result.failed, err.Error())
+				continue
+			}
+
+			if report.Critical > maxCritical {
+				result.vulnerable = append(result.vulnerable, fmt.Sprintf("%v (%v critical)", reference, report.Critical))
+			}
+		}
+		scans[version.Version] = result
+	}
+	return scans
+}
+
+// Adds a violation for each version with too many critical vulnerabilities, or whose scan
+// has not completed, if the scan blocks them, and for each version whose images could not
+// be scanned, unless the failure policy ignores them.
+func blockVulnerableVersions(spec kabanerov1alpha2.VulnerabilityScanSpec, scans map[string]versionScan, violations map[string]sutils.GovernanceViolation) {
+	for version, result := range scans {
+		if len(result.vulnerable) != 0 && spec.Action != kabanerov1alpha2.VulnerabilityScanActionWarn {
+			violations[version] = sutils.GovernanceViolation{
+				Reason:  kabanerov1alpha2.StackReasonVulnerabilitiesFound,
+				Message: fmt.Sprintf("Images of the stack version have more than %v critical vulnerabilities: %v", spec.MaxCriticalVulnerabilities, strings.Join(result.vulnerable, ", ")),
+			}
+		} else if len(result.failed) != 0 && spec.FailurePolicy != kabanerov1alpha2.VulnerabilityScanFailurePolicyIgnore {
+			violations[version] = sutils.GovernanceViolation{
+				Reason:  kabanerov1alpha2.StackReasonScanFailed,
+				Message: fmt.Sprintf("Images of the stack version could not be scanned: %v", strings.Join(result.failed, "; ")),
+			}
+		} else if len(result.pending) != 0 && spec.Action != kabanerov1alpha2.VulnerabilityScanActionWarn {
+			violations[version] = sutils.GovernanceViolation{
+				Reason:  kabanerov1alpha2.StackReasonScanPending,
+				Message: fmt.Sprintf("The scans of the images of the stack version have not completed: %v", strings.Join(result.pending, ", ")),
+			}
+		}
+	}
+}
+
+// Sets the Vulnerable condition to reflect the input scans.
+func setVulnerableCondition(status *kabanerov1alpha2.StackStatus, spec kabanerov1alpha2.VulnerabilityScanSpec, scans map[string]versionScan, now time.Time) {
+	transition := metav1.NewTime(now)
+	condition := kabanerov1alpha2.StackCondition{
+		Type:               kabanerov1alpha2.StackConditionVulnerable,
+		Status:             string(corev1.ConditionFalse),
+		LastTransitionTime: &transition,
+		Reason:             kabanerov1alpha2.StackReasonNoVulnerabilities,
+	}
+
+	vulnerable := []string{}
+	failed := []string{}
+	pending := []string{}
+	for _, version := range status.Versions {
+		result, found := scans[version.Version]
+		if !found {
+			continue
+		}
+		if len(result.vulnerable) != 0 {
+			vulnerable = append(vulnerable, fmt.Sprintf("%v: %v", version.Version, strings.Join(result.vulnerable, ", ")))
+		}
+		if len(result.failed) != 0 {
+			failed = append(failed, fmt.Sprintf("%v: %v", version.Version, strings.Join(result.failed, "; ")))
+		}
+		if len(result.pending) != 0 {
+			pending = append(pending, fmt.Sprintf("%v: %v", version.Version, strings.Join(result.pending, ", ")))
+		}
+	}
+
+	if len(vulnerable) != 0 {
+		condition.Status = string(corev1.ConditionTrue)
+		condition.Reason = kabanerov1alpha2.StackReasonVulnerabilitiesFound
+		condition.Message = fmt.Sprintf("Stack versions have images with more than %v critical vulnerabilities: %v", spec.MaxCriticalVulnerabilities, strings.Join(vulnerable, ". "))
+	} else if len(failed) != 0 {
+		condition.Status = string(corev1.ConditionUnknown)
+		condition.Reason = kabanerov1alpha2.StackReasonScanFailed
+		condition.Message = fmt.Sprintf("Stack versions could not be scanned: %v", strings.Join(failed, ". "))
+	} else if len(pending) != 0 {
+		condition.Status = string(corev1.ConditionUnknown)
+		condition.Reason = kabanerov1alpha2.StackReasonScanPending
+		condition.Message = fmt.Sprintf("Waiting for the scans of the images of stack versions: %v", strings.Join(pending, ". "))
+	}
+
+	status.SetCondition(condition)
+}
+
+// Returns the activation digest of the input image from the status of the stack, if it
+// was retrieved before.
+func getActivationDigest(status kabanerov1alpha2.StackStatus, version string, image string) string {
+	for _, versionStatus := range status.Versions {
+		if versionStatus.Version != version {
+			continue
+		}
+		for _, imageStatus := range versionStatus.Images {
+			if imageStatus.Image == image {
+				return imageStatus.Digest.Activation
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,10 @@
+package controller
+
+import (
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stackhub"
+)
+
+func init() {
+	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
+	AddToManagerFuncs = append(AddToManagerFuncs, stackhub.Add)
+}
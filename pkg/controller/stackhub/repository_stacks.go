@@ -0,0 +1,322 @@
+package stackhub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Validates the conflict policy of the StackHub.  The repository a prefer-repository policy
+// names is read by another StackHub, so only the form of the policy is checked.
+func validateConflictPolicy(hub *kabanerov1alpha2.StackHub) error {
+	policy := hub.Spec.ConflictPolicy
+	if len(policy) == 0 || policy == kabanerov1alpha2.StackConflictPolicyFirstWins || policy == kabanerov1alpha2.StackConflictPolicyError {
+		return nil
+	}
+
+	if strings.HasPrefix(policy, kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix) && len(policy) > len(kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix) {
+		return nil
+	}
+
+	return fmt.Errorf("The conflict policy %v of StackHub %v is not valid. The following are allowed values: %v, %v, %v<repository name>",
+		policy, hub.GetName(), kabanerov1alpha2.StackConflictPolicyFirstWins, kabanerov1alpha2.StackConflictPolicyError,
+		kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix)
+}
+
+// Returns true if the conflict policy of the StackHub prefers its own repository over the
+// repositories of other StackHubs.
+func prefersOwnRepository(hub *kabanerov1alpha2.StackHub) bool {
+	return hub.Spec.ConflictPolicy == kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix+hub.Spec.Repository.Name
+}
+
+// Returns how long the indexes read by the StackHub are cached.  The index cache settings of
+// the Kabanero instance apply to the StackHub, if there is one.
+func getIndexCacheTTL(k *kabanerov1alpha2.Kabanero, now time.Time) time.Duration {
+	if k == nil {
+		return stack.DefaultIndexCacheTTL
+	}
+	return stack.GetScheduledIndexCacheTTL(k, now)
+}
+
+// Returns true if the stack is managed by the StackHub: the StackHub is one of its owners, or
+// the stack is controlled by the Kabanero instance that controls the StackHub.  The Kabanero
+// instance controlled the stacks of its repositories itself before it had StackHubs, and
+// takes back the stack versions with a desired state when its StackHubs are deleted.
+func isManagedByStackHub(hub *kabanerov1alpha2.StackHub, stackResource *kabanerov1alpha2.Stack) bool {
+	for _, ownerRef := range stackResource.GetOwnerReferences() {
+		if ownerRef.UID == hub.GetUID() {
+			return true
+		}
+	}
+	owner := metav1.GetControllerOf(stackResource)
+	hubOwner := metav1.GetControllerOf(hub)
+	return owner != nil && hubOwner != nil && hubOwner.Kind == "Kabanero" && owner.UID == hubOwner.UID
+}
+
+// Returns true if the stack is owned by another StackHub, whose repository lists other versions
+// of the stack.  The StackHub adds the versions of its own repository to such a stack.
+func isSharedWithStackHub(stackResource *kabanerov1alpha2.Stack) bool {
+	for _, ownerRef := range stackResource.GetOwnerReferences() {
+		if ownerRef.Kind == "StackHub" {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the stack version was read from the repository of the StackHub.  A version
+// without a repository location predates the StackHubs, and belongs to the StackHub that
+// controls the stack.
+func isFromStackHubRepository(hub *kabanerov1alpha2.StackHub, stackResource *kabanerov1alpha2.Stack, version kabanerov1alpha2.StackVersion) bool {
+	if len(version.RepositoryUrl) == 0 {
+		owner := metav1.GetControllerOf(stackResource)
+		hubOwner := metav1.GetControllerOf(hub)
+		return owner == nil || owner.UID == hub.GetUID() || (hubOwner != nil && owner.UID == hubOwner.UID)
+	}
+	return version.RepositoryUrl == stack.GetRepositoryUrl(hub.Spec.Repository)
+}
+
+// Returns an owner reference to the StackHub.
+func stackHubOwnerReference(hub *kabanerov1alpha2.StackHub, controller bool) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: hub.TypeMeta.APIVersion,
+		Kind:       hub.TypeMeta.Kind,
+		Name:       hub.ObjectMeta.Name,
+		UID:        hub.ObjectMeta.UID,
+		Controller: &controller,
+	}
+}
+
+// Makes the StackHub an owner of the stack.  The StackHub becomes the controller of a stack
+// that is controlled by its Kabanero instance, or by no one.
+func adoptStack(hub *kabanerov1alpha2.StackHub, stackResource *kabanerov1alpha2.Stack) {
+	owner := metav1.GetControllerOf(stackResource)
+	if owner != nil && owner.UID == hub.GetUID() {
+		return
+	}
+
+	hubOwner := metav1.GetControllerOf(hub)
+	adoptFromKabanero := owner != nil && hubOwner != nil && owner.UID == hubOwner.UID
+	ownerRefs := []metav1.OwnerReference{}
+	for _, ownerRef := range stackResource.GetOwnerReferences() {
+		if ownerRef.UID == hub.GetUID() || (adoptFromKabanero && ownerRef.UID == owner.UID) {
+			continue
+		}
+		ownerRefs = append(ownerRefs, ownerRef)
+	}
+	ownerRefs = append(ownerRefs, stackHubOwnerReference(hub, owner == nil || adoptFromKabanero))
+	stackResource.SetOwnerReferences(ownerRefs)
+}
+
+// Removes the StackHub from the owners of a stack that still has versions from other
+// repositories, or versions with a desired state.  If the StackHub controlled the stack,
+// another StackHub that owns it takes over.  If there is none, the Kabanero instance that
+// controls the StackHub takes the stack back.
+func disownStack(hub *kabanerov1alpha2.StackHub, stackResource *kabanerov1alpha2.Stack) {
+	wasController := false
+	ownerRefs := []metav1.OwnerReference{}
+	for _, ownerRef := range stackResource.GetOwnerReferences() {
+		if ownerRef.UID == hub.GetUID() {
+			wasController = ownerRef.Controller != nil && *ownerRef.Controller
+			continue
+		}
+		ownerRefs = append(ownerRefs, ownerRef)
+	}
+
+	if wasController {
+		foundController := false
+		for i := range ownerRefs {
+			if ownerRefs[i].Kind == "StackHub" {
+				controller := true
+				ownerRefs[i].Controller = &controller
+				foundController = true
+				break
+			}
+		}
+
+		if hubOwner := metav1.GetControllerOf(hub); !foundController && hubOwner != nil && hubOwner.Kind == "Kabanero" {
+			ownerRefs = append(ownerRefs, *hubOwner)
+		}
+	}
+	stackResource.SetOwnerReferences(ownerRefs)
+}
+
+// What was last applied to a Stack instance from the repository indexes.
+type appliedIndexEntry struct {
+	// A digest of the stack versions read from the indexes.
+	digest string
+
+	// The generation of the Stack instance after it was updated.  Status updates do
+	// not change the generation.
+	generation int64
+
+	// The namespace of the StackHub instance.
+	namespace string
+}
+
+// The index entries applied to each stack, keyed by StackHub instance UID and stack id.
+var appliedIndexEntries = make(map[string]appliedIndexEntry)
+
+// Mutex for concurrent map access
+var appliedIndexEntriesLock sync.Mutex
+
+// Returns true if the index entries for a stack are the same as the ones last applied to it,
+// and the Stack instance was not changed since.  A stack whose repository index refresh was
+// requested is never unchanged.
+func isIndexEntryUnchanged(hub *kabanerov1alpha2.StackHub, id string, digest string, versions []kabanerov1alpha2.StackVersion, stackResource *kabanerov1alpha2.Stack, refresh indexRefreshRequests) bool {
+	if refresh.all {
+		return false
+	}
+	for _, version := range versions {
+		if refresh.repositoryUrls[version.RepositoryUrl] {
+			return false
+		}
+	}
+
+	appliedIndexEntriesLock.Lock()
+	entry, found := appliedIndexEntries[string(hub.GetUID())+"/"+id]
+	appliedIndexEntriesLock.Unlock()
+
+	return found && entry.generation != 0 && len(digest) != 0 &&
+		entry.generation == stackResource.GetGeneration() &&
+		entry.digest == digest
+}
+
+// Records the index entries applied to a stack.
+func recordIndexEntry(hub *kabanerov1alpha2.StackHub, id string, digest string, stackResource *kabanerov1alpha2.Stack) {
+	appliedIndexEntriesLock.Lock()
+	defer appliedIndexEntriesLock.Unlock()
+	appliedIndexEntries[string(hub.GetUID())+"/"+id] = appliedIndexEntry{digest: digest, generation: stackResource.GetGeneration(), namespace: hub.GetNamespace()}
+}
+
+// Forgets the index entries applied to a stack that was deleted.
+func forgetIndexEntry(hub *kabanerov1alpha2.StackHub, id string) {
+	appliedIndexEntriesLock.Lock()
+	defer appliedIndexEntriesLock.Unlock()
+	delete(appliedIndexEntries, string(hub.GetUID())+"/"+id)
+}
+
+// Forgets the index entries applied by the StackHub instances in a namespace that are not
+// in the input set, because they were deleted.
+func pruneIndexEntries(namespace string, existing map[types.UID]bool) {
+	appliedIndexEntriesLock.Lock()
+	defer appliedIndexEntriesLock.Unlock()
+	for key, entry := range appliedIndexEntries {
+		uid := types.UID(key[:strings.Index(key, "/")])
+		if entry.namespace == namespace && !existing[uid] {
+			delete(appliedIndexEntries, key)
+		}
+	}
+}
+
+// Returns a digest of the stack versions read from the indexes.  The versions are hashed
+// as JSON, since printing them would include the addresses of the fields that are pointers.
+func indexEntryDigest(versions []kabanerov1alpha2.StackVersion) string {
+	data, err := json.Marshal(versions)
+	if err != nil {
+		// A digest that matches nothing, so the stack is always updated.
+		return ""
+	}
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+// The repository indexes that must be re-read instead of coming from the index cache.
+type indexRefreshRequests struct {
+	// All repository indexes must be re-read.
+	all bool
+
+	// The locations of the repositories whose indexes must be re-read.
+	repositoryUrls map[string]bool
+
+	// The StackHub and Stack instances that requested the refresh.
+	requesters []metav1.Object
+}
+
+// Finds the StackHub and Stack instances with a refresh annotation that has not been processed
+// yet.  A StackHub instance refreshes all of its repositories.  A Stack instance refreshes the
+// repositories that its versions were read from.
+func getIndexRefreshRequests(ctx context.Context, hub *kabanerov1alpha2.StackHub, cl client.Client) (indexRefreshRequests, error) {
+	refresh := indexRefreshRequests{repositoryUrls: make(map[string]bool)}
+	if stack.IsIndexRefreshRequested(hub) {
+		refresh.all = true
+		refresh.requesters = append(refresh.requesters, hub)
+	}
+
+	deployedStacks := &kabanerov1alpha2.StackList{}
+	err := cl.List(ctx, deployedStacks, client.InNamespace(hub.GetNamespace()))
+	if err != nil {
+		return refresh, err
+	}
+
+	// Forget the refreshes of instances that were deleted.
+	existing := map[types.UID]bool{}
+	for _, deployedStack := range deployedStacks.Items {
+		existing[deployedStack.GetUID()] = true
+	}
+	stack.PruneIndexRefreshes("Stack", hub.GetNamespace(), existing)
+
+	for i, deployedStack := range deployedStacks.Items {
+		if isManagedByStackHub(hub, &deployedStack) && stack.IsIndexRefreshRequested(&deployedStack) {
+			for _, version := range deployedStack.Spec.Versions {
+				refresh.repositoryUrls[version.RepositoryUrl] = true
+			}
+			refresh.requesters = append(refresh.requesters, &deployedStacks.Items[i])
+		}
+	}
+
+	return refresh, nil
+}
+
+// Resolves all stacks listed by the repository of the StackHub.
+func (r *ReconcileStackHub) resolveStacks(ctx context.Context, hub *kabanerov1alpha2.StackHub, k *kabanerov1alpha2.Kabanero, refresh indexRefreshRequests, reqLogger logr.Logger) (map[string][]kabanerov1alpha2.StackVersion, error) {
+	// An artifact proxy configured in the Kabanero instance applies to the stack hub too.
+	var proxy *cache.ArtifactProxy
+	if k != nil {
+		var err error
+		proxy, err = cache.GetArtifactProxy(r.client, hub.GetNamespace(), k.Spec.ArtifactProxy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Figure out what set of pipelines to use.  The StackHub instance defines a default
+	// set, but this can be over-ridden by the repository.
+	repo := hub.Spec.Repository
+	pipelines := repo.Pipelines
+	if len(pipelines) == 0 {
+		pipelines = hub.Spec.Pipelines
+	}
+
+	indexPipelines := []stack.Pipelines{}
+	for _, pipeline := range pipelines {
+		indexPipelines = append(indexPipelines, stack.Pipelines{Id: pipeline.Id, Sha256: pipeline.Sha256, Url: pipeline.Https.Url, GitRelease: pipeline.GitRelease, SkipCertVerification: pipeline.Https.SkipCertVerification})
+	}
+
+	refreshIndex := refresh.all || refresh.repositoryUrls[stack.GetRepositoryUrl(repo)]
+	index, err := r.indexResolver(ctx, r.client, repo, hub.GetNamespace(), indexPipelines, []stack.Trigger{}, "", proxy, getIndexCacheTTL(k, time.Now()), refreshIndex, reqLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the stack versions
+	stackMap := make(map[string][]kabanerov1alpha2.StackVersion)
+	for _, c := range index.Stacks {
+		stackMap[c.Id] = append(stackMap[c.Id], c.GetStackVersion(stack.GetRepositoryUrl(repo), hub.Spec.SkipRegistryCertVerification))
+	}
+
+	return stackMap, nil
+}
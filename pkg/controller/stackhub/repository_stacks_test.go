@@ -0,0 +1,1006 @@
+package stackhub
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"testing"
+)
+
+// -----------------------------------------------------------------------------------------------
+// HTTP handler that serves pipeline zips
+// -----------------------------------------------------------------------------------------------
+type stackIndexHandler struct {
+}
+
+func (ch stackIndexHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	filename := fmt.Sprintf("testdata/%v", req.URL.String())
+	fmt.Printf("Serving %v\n", filename)
+	d, err := ioutil.ReadFile(filename)
+	if err != nil {
+		rw.WriteHeader(http.StatusNotFound)
+	} else {
+		rw.Write(d)
+	}
+}
+
+var defaultIndexName = "/kabanero-index.yaml"
+var secondIndexName = "/kabanero-index-two.yaml"
+
+var appsodyIndexName = "/appsody-index.yaml"
+
+var defaultIndexPipeline = "https://github.com/kabanero-io/collections/releases/download/0.4.0/incubator.common.pipeline.default.tar.gz"
+var defaultIndexPipelineDigest = "0123456789012345678901234567890123456789012345678901234567890123"
+var secondIndexPipeline = "https://github.com/kabanero-io/collections/releases/download/0.6.0/incubator.common.pipeline.default.tar.gz"
+var secondIndexPipelineDigest = "1234567890123456789012345678901234567890123456789012345678901234"
+var repositoryTestLogger logr.Logger = log.WithValues("Request.Namespace", "test", "Request.Name", "repository_stacks_test")
+
+var stackResourceOwnerIsController = true
+
+var stackResource kabanerov1alpha2.Stack = kabanerov1alpha2.Stack{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:            "nodejs",
+		UID:             "myuid",
+		Namespace:       "kabanero",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "kabanero.io/v1alpha2", Kind: "StackHub", Name: "kabanero", UID: "12345", Controller: &stackResourceOwnerIsController}},
+	},
+	Spec: kabanerov1alpha2.StackSpec{
+		Name: "nodejs",
+		Versions: []kabanerov1alpha2.StackVersion{
+			kabanerov1alpha2.StackVersion{
+				Version: "0.2.4",
+				Pipelines: []kabanerov1alpha2.PipelineSpec{{
+					Id:     "trigger.pipeline.0.2.4.tar.gz",
+					Sha256: "2e8ff2e5c6ce8526edc9ce413876c450383814d4fa6f5f37b690d167433da363",
+					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: "https://pipelines/default/0.2.4"},
+				}},
+			},
+			kabanerov1alpha2.StackVersion{
+				Version: "0.2.5",
+				Pipelines: []kabanerov1alpha2.PipelineSpec{{
+					Id:     "trigger.pipeline.0.2.5.tar.gz",
+					Sha256: "2e8ff2e5c6ce8526edc9ce413876c450383814d4fa6f5f37b690d167433da363",
+					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: "https://pipelines/default/0.2.5"},
+				}},
+			},
+			kabanerov1alpha2.StackVersion{
+				Version: "0.2.6",
+				Pipelines: []kabanerov1alpha2.PipelineSpec{{
+					Id:     "trigger.pipeline.0.2.6.tar.gz",
+					Sha256: "2e8ff2e5c6ce8526edc9ce413876c450383814d4fa6f5f37b690d167433da363",
+					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: "https://pipelines/default/0.2.6"},
+				}},
+			},
+		},
+	},
+	Status: kabanerov1alpha2.StackStatus{},
+}
+
+// -----------------------------------------------------------------------------------------------
+// Test cases
+// -----------------------------------------------------------------------------------------------
+func createStackHub(repositoryUrl string) *kabanerov1alpha2.StackHub {
+	return &kabanerov1alpha2.StackHub{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kabanero.io/v1alpha2",
+			Kind:       "StackHub",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "kabanero",
+			UID:       "12345",
+		},
+		Spec: kabanerov1alpha2.StackHubSpec{
+			Repository: kabanerov1alpha2.RepositoryConfig{
+				Name:  "default",
+				Https: kabanerov1alpha2.HttpsProtocolFile{Url: repositoryUrl, SkipCertVerification: true},
+			},
+		},
+	}
+}
+
+// Returns a reconciler that reads the indexes through the index cache.
+func newTestReconciler(cl client.Client) *ReconcileStackHub {
+	return &ReconcileStackHub{client: cl, indexResolver: stack.ResolveIndexUsingCache}
+}
+
+// Synchronizes the stacks of the StackHub.  Returns an error if a stack could not be
+// synchronized.
+func syncTestStacks(ctx context.Context, hub *kabanerov1alpha2.StackHub, cl client.Client) error {
+	err := newTestReconciler(cl).syncStacks(ctx, hub, nil, repositoryTestLogger)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range hub.Status.Stacks {
+		if status.Status != kabanerov1alpha2.StackHubSyncStatusSynced {
+			return fmt.Errorf("Stack %v was not synchronized: %v", status.Name, status.StatusMessage)
+		}
+	}
+	return nil
+}
+
+// Test that we can read a legacy CollectionHub that contains embedded
+// pipeline and image data.
+func TestSyncRepositoryStacks(t *testing.T) {
+	// The server that will host the pipeline zip
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+
+	ctx := context.Background()
+	cl := stackHubTestClient{make(map[string]*kabanerov1alpha2.Stack)}
+	stackUrl := server.URL + defaultIndexName
+	hub := createStackHub(stackUrl)
+
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Should have been two stacks created
+	javaMicroprofileStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "java-microprofile"}, javaMicroprofileStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	nodejsStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "nodejs"}, nodejsStack)
+	if err != nil {
+		t.Fatal("Could not resolve the nodejs stack", err)
+	}
+
+	// Make sure the stack has an owner set
+	if len(nodejsStack.OwnerReferences) != 1 {
+		t.Fatal(fmt.Sprintf("Expected 1 owner, but found %v: %v", len(nodejsStack.OwnerReferences), nodejsStack))
+	}
+
+	if nodejsStack.OwnerReferences[0].UID != hub.UID {
+		t.Fatal(fmt.Sprintf("Expected owner UID to be %v, but was %v", hub.UID, nodejsStack.OwnerReferences[0].UID))
+	}
+
+	// Make sure the stack is active
+	if len(nodejsStack.Spec.Versions) != 1 {
+		t.Fatal(fmt.Sprintf("Expected 1 stack version, but found %v: %v", len(nodejsStack.Spec.Versions), nodejsStack.Spec.Versions))
+	}
+
+	if nodejsStack.Spec.Versions[0].Version != "0.2.6" {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack version \"0.2.6\", but found %v", nodejsStack.Spec.Versions[0].Version))
+	}
+
+	if len(nodejsStack.Spec.Versions[0].DesiredState) != 0 {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack desiredState to be empty, but was %v", nodejsStack.Spec.Versions[0].DesiredState))
+	}
+
+	if len(nodejsStack.Spec.Versions[0].Pipelines) != 1 {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack to have 1 pipeline zip, but had %v: %v", len(nodejsStack.Spec.Versions[0].Pipelines), nodejsStack.Spec.Versions[0].Pipelines))
+	}
+
+	if nodejsStack.Spec.Versions[0].Pipelines[0].Https.Url != defaultIndexPipeline {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack pipeline zip name to be %v, but was %v", defaultIndexPipeline, nodejsStack.Spec.Versions[0].Pipelines[0].Https.Url))
+	}
+
+	if len(nodejsStack.Spec.Versions[0].Images) != 1 {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack to have one image, but has %v", len(nodejsStack.Spec.Versions[0].Images)))
+	}
+
+	njsExpectedImage := "docker.io/kabanero/nodejs"
+	if nodejsStack.Spec.Versions[0].Images[0].Image != njsExpectedImage {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack image of %v, but was %v", njsExpectedImage, nodejsStack.Spec.Versions[0].Images[0].Image))
+	}
+
+	jmpExpectedImage := "docker.io/kabanero/java-microprofile"
+	if javaMicroprofileStack.Spec.Versions[0].Images[0].Image != jmpExpectedImage {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack image of %v, but was %v", jmpExpectedImage, javaMicroprofileStack.Spec.Versions[0].Images[0].Image))
+	}
+}
+
+// Test that the versions of a stack listed by the repositories of two StackHubs are merged
+// into one Stack instance, which both StackHubs own.
+func TestSyncRepositoryStacksTwoStackHubs(t *testing.T) {
+	// The server that will host the pipeline zip
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+
+	ctx := context.Background()
+	cl := stackHubTestClient{make(map[string]*kabanerov1alpha2.Stack)}
+	stackUrl := server.URL + defaultIndexName
+	stackUrlTwo := server.URL + secondIndexName
+	hub := createStackHub(stackUrl)
+	hubTwo := createStackHub(stackUrlTwo)
+	hubTwo.Name = "kabanero-two"
+	hubTwo.UID = "67890"
+	hubTwo.Spec.Repository.Name = "two"
+
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = syncTestStacks(ctx, hubTwo, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Should have been two stacks created
+	javaMicroprofileStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "java-microprofile"}, javaMicroprofileStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	nodejsStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "nodejs"}, nodejsStack)
+	if err != nil {
+		t.Fatal("Could not resolve the nodejs stack", err)
+	}
+
+	// Make sure the stack is owned by both StackHubs, and controlled by the first one
+	if len(nodejsStack.OwnerReferences) != 2 {
+		t.Fatal(fmt.Sprintf("Expected 2 owners, but found %v: %v", len(nodejsStack.OwnerReferences), nodejsStack))
+	}
+
+	if owner := metav1.GetControllerOf(nodejsStack); owner == nil || owner.UID != hub.UID {
+		t.Fatal(fmt.Sprintf("Expected the stack to be controlled by %v, but owners were %v", hub.UID, nodejsStack.OwnerReferences))
+	}
+
+	// Make sure the stack is in the correct state
+	if len(nodejsStack.Spec.Versions) != 2 {
+		t.Fatal(fmt.Sprintf("Expected 2 stack versions, but found %v: %v", len(nodejsStack.Spec.Versions), nodejsStack.Spec.Versions))
+	}
+
+	foundVersions := make(map[string]bool)
+	for _, cur := range nodejsStack.Spec.Versions {
+		foundVersions[cur.Version] = true
+		if len(cur.Pipelines) != 1 {
+			t.Fatal(fmt.Sprintf("Expected version %v to have 1 pipeline zip, but has %v: %v", cur.Version, len(cur.Pipelines), cur.Pipelines))
+		}
+		if len(cur.DesiredState) != 0 {
+			t.Fatal(fmt.Sprintf("Expected version %v desiredState to be empty, but was %v", cur.Version, cur.DesiredState))
+		}
+		if cur.Version == "0.2.6" {
+			if cur.Pipelines[0].Https.Url != defaultIndexPipeline {
+				t.Fatal(fmt.Sprintf("Expected version \"0.2.6\" pipeline URL to be %v, but was %v", defaultIndexPipeline, cur.Pipelines[0].Https.Url))
+			}
+		} else if cur.Version == "0.4.1" {
+			if cur.Pipelines[0].Https.Url != secondIndexPipeline {
+				t.Fatal(fmt.Sprintf("Expected version \"0.4.1\" pipeline URL to be %v, but was %v", secondIndexPipeline, cur.Pipelines[0].Https.Url))
+			}
+		} else {
+			t.Fatal(fmt.Sprintf("Found unexpected version %v", cur.Version))
+		}
+	}
+
+	if foundVersions["0.2.6"] != true {
+		t.Fatal("Did not find stack version \"0.2.6\"")
+	}
+
+	if foundVersions["0.4.1"] != true {
+		t.Fatal("Did not find stack version \"0.4.1\"")
+	}
+}
+
+// Read an appsody index and specify custom pipelines in the StackHub instance.
+func TestSyncAppsodyStacksCustomPipelines(t *testing.T) {
+	// The server that will host the pipeline zip
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+
+	ctx := context.Background()
+	cl := stackHubTestClient{make(map[string]*kabanerov1alpha2.Stack)}
+	stackUrl := server.URL + appsodyIndexName
+	hub := createStackHub(stackUrl)
+
+	// Need to specify the pipelines information
+	pipelineUrl := kabanerov1alpha2.HttpsProtocolFile{Url: defaultIndexPipeline}
+	hub.Spec.Pipelines = append(hub.Spec.Pipelines, kabanerov1alpha2.PipelineSpec{Id: "default", Sha256: defaultIndexPipelineDigest, Https: pipelineUrl})
+
+	customPipelineUrl := kabanerov1alpha2.HttpsProtocolFile{Url: secondIndexPipeline}
+	hub.Spec.Repository.Pipelines = append(hub.Spec.Repository.Pipelines, kabanerov1alpha2.PipelineSpec{Id: "custom", Sha256: secondIndexPipelineDigest, Https: customPipelineUrl})
+
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Should have been two stacks created
+	javaMicroprofileStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "java-microprofile"}, javaMicroprofileStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	nodejsStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "nodejs"}, nodejsStack)
+	if err != nil {
+		t.Fatal("Could not resolve the nodejs stack", err)
+	}
+
+	// Make sure the stack has an owner set
+	if len(nodejsStack.OwnerReferences) != 1 {
+		t.Fatal(fmt.Sprintf("Expected 1 owner, but found %v: %v", len(nodejsStack.OwnerReferences), nodejsStack))
+	}
+
+	if nodejsStack.OwnerReferences[0].UID != hub.UID {
+		t.Fatal(fmt.Sprintf("Expected owner UID to be %v, but was %v", hub.UID, nodejsStack.OwnerReferences[0].UID))
+	}
+
+	// Make sure the stack is active
+	if len(nodejsStack.Spec.Versions) != 1 {
+		t.Fatal(fmt.Sprintf("Expected 1 stack version, but found %v: %v", len(nodejsStack.Spec.Versions), nodejsStack.Spec.Versions))
+	}
+
+	if nodejsStack.Spec.Versions[0].Version != "0.3.2" {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack version \"0.3.2\", but found %v", nodejsStack.Spec.Versions[0].Version))
+	}
+
+	if len(nodejsStack.Spec.Versions[0].DesiredState) != 0 {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack desiredState to be empty, but was %v", nodejsStack.Spec.Versions[0].DesiredState))
+	}
+
+	if len(nodejsStack.Spec.Versions[0].Pipelines) != 1 {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack to have 1 pipeline zip, but had %v: %v", len(nodejsStack.Spec.Versions[0].Pipelines), nodejsStack.Spec.Versions[0].Pipelines))
+	}
+
+	if nodejsStack.Spec.Versions[0].Pipelines[0].Https.Url != secondIndexPipeline {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack pipeline zip name to be %v, but was %v", secondIndexPipeline, nodejsStack.Spec.Versions[0].Pipelines[0].Https.Url))
+	}
+
+	if nodejsStack.Spec.Versions[0].Pipelines[0].Sha256 != secondIndexPipelineDigest {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack pipeline zip name to be %v, but was %v", secondIndexPipelineDigest, nodejsStack.Spec.Versions[0].Pipelines[0].Sha256))
+	}
+}
+
+// Read an appsody index and specify the pipelines in the StackHub instance.
+func TestSyncAppsodyStacksDefaultPipelines(t *testing.T) {
+	// The server that will host the pipeline zip
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+
+	ctx := context.Background()
+	cl := stackHubTestClient{make(map[string]*kabanerov1alpha2.Stack)}
+	stackUrl := server.URL + appsodyIndexName
+	hub := createStackHub(stackUrl)
+
+	// Need to specify the pipelines information
+	pipelineUrl := kabanerov1alpha2.HttpsProtocolFile{Url: defaultIndexPipeline}
+	hub.Spec.Pipelines = append(hub.Spec.Pipelines, kabanerov1alpha2.PipelineSpec{Id: "default", Sha256: defaultIndexPipelineDigest, Https: pipelineUrl})
+
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Should have been two stacks created
+	javaMicroprofileStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "java-microprofile"}, javaMicroprofileStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	nodejsStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "nodejs"}, nodejsStack)
+	if err != nil {
+		t.Fatal("Could not resolve the nodejs stack", err)
+	}
+
+	// Make sure the stack has an owner set
+	if len(nodejsStack.OwnerReferences) != 1 {
+		t.Fatal(fmt.Sprintf("Expected 1 owner, but found %v: %v", len(nodejsStack.OwnerReferences), nodejsStack))
+	}
+
+	if nodejsStack.OwnerReferences[0].UID != hub.UID {
+		t.Fatal(fmt.Sprintf("Expected owner UID to be %v, but was %v", hub.UID, nodejsStack.OwnerReferences[0].UID))
+	}
+
+	// Make sure the stack is active
+	if len(nodejsStack.Spec.Versions) != 1 {
+		t.Fatal(fmt.Sprintf("Expected 1 stack version, but found %v: %v", len(nodejsStack.Spec.Versions), nodejsStack.Spec.Versions))
+	}
+
+	if nodejsStack.Spec.Versions[0].Version != "0.3.2" {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack version \"0.3.2\", but found %v", nodejsStack.Spec.Versions[0].Version))
+	}
+
+	if len(nodejsStack.Spec.Versions[0].DesiredState) != 0 {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack desiredState to be empty, but was %v", nodejsStack.Spec.Versions[0].DesiredState))
+	}
+
+	if len(nodejsStack.Spec.Versions[0].Pipelines) != 1 {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack to have 1 pipeline zip, but had %v: %v", len(nodejsStack.Spec.Versions[0].Pipelines), nodejsStack.Spec.Versions[0].Pipelines))
+	}
+
+	if nodejsStack.Spec.Versions[0].Pipelines[0].Https.Url != defaultIndexPipeline {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack pipeline zip name to be %v, but was %v", defaultIndexPipeline, nodejsStack.Spec.Versions[0].Pipelines[0].Https.Url))
+	}
+
+	if nodejsStack.Spec.Versions[0].Pipelines[0].Sha256 != defaultIndexPipelineDigest {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack pipeline zip name to be %v, but was %v", defaultIndexPipelineDigest, nodejsStack.Spec.Versions[0].Pipelines[0].Sha256))
+	}
+}
+
+// Attempts to resolve the stacks from the default repository
+func TestResolveRepositoryStacks(t *testing.T) {
+	// The server that will host the pipeline zip
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+
+	stack_index_url := server.URL + defaultIndexName
+	hub := createStackHub(stack_index_url)
+
+	stacks, err := newTestReconciler(stackHubTestClient{}).resolveStacks(context.Background(), hub, nil, indexRefreshRequests{}, repositoryTestLogger)
+	if err != nil {
+		t.Fatal("Could not resolve the stacks from the default index", err)
+	}
+
+	// Should be two stacks
+	if len(stacks) != 2 {
+		t.Fatal(fmt.Sprintf("Was expecting 2 stacks to be found, but found %v: %v", len(stacks), stacks))
+	}
+
+	javaMicroprofileStackVersions, ok := stacks["java-microprofile"]
+	if !ok {
+		t.Fatal(fmt.Sprintf("Could not find java-microprofile stack: %v", stacks))
+	}
+
+	nodejsStackVersions, ok := stacks["nodejs"]
+	if !ok {
+		t.Fatal(fmt.Sprintf("Could not find nodejs stack: %v", stacks))
+	}
+
+	// Make sure each stack has one version
+	if len(javaMicroprofileStackVersions) != 1 {
+		t.Fatal(fmt.Sprintf("Expected one version of java-microprofile stack, but found %v: %v", len(javaMicroprofileStackVersions), javaMicroprofileStackVersions))
+	}
+
+	if len(nodejsStackVersions) != 1 {
+		t.Fatal(fmt.Sprintf("Expected one version of nodejs stack, but found %v: %v", len(nodejsStackVersions), nodejsStackVersions))
+	}
+}
+
+// Test that the conflict policy decides which StackHub provides a stack version listed by the
+// repositories of two StackHubs.
+func TestSyncRepositoryStacksConflictPolicy(t *testing.T) {
+	// The server that will host the pipeline zip
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+
+	ctx := context.Background()
+	stack_index_url := server.URL + defaultIndexName
+	stack_index_url_two := server.URL + "/" + defaultIndexName
+	hub := createStackHub(stack_index_url)
+	hubTwo := createStackHub(stack_index_url_two)
+	hubTwo.Name = "kabanero-two"
+	hubTwo.UID = "67890"
+	hubTwo.Spec.Repository.Name = "two"
+	cl := stackHubTestClient{make(map[string]*kabanerov1alpha2.Stack)}
+
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default policy is first-wins.
+	err = newTestReconciler(cl).syncStacks(ctx, hubTwo, nil, repositoryTestLogger)
+	if err != nil {
+		t.Fatal("Could not synchronize the stacks", err)
+	}
+
+	for _, status := range hubTwo.Status.Stacks {
+		if status.Status != kabanerov1alpha2.StackHubSyncStatusConflict {
+			t.Fatal(fmt.Sprintf("Expected stack %v to have a conflict, but its status was %v", status.Name, status.Status))
+		}
+	}
+
+	nodejsStackVersions := cl.objs["nodejs"].Spec.Versions
+	if len(nodejsStackVersions) != 1 {
+		t.Fatal(fmt.Sprintf("Expected one version of nodejs stack, but found %v: %v", len(nodejsStackVersions), nodejsStackVersions))
+	}
+
+	if nodejsStackVersions[0].RepositoryUrl != stack_index_url {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack from repository %v, but found %v", stack_index_url, nodejsStackVersions[0].RepositoryUrl))
+	}
+
+	// The second repository is preferred.
+	hubTwo.Spec.ConflictPolicy = kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix + "two"
+	err = syncTestStacks(ctx, hubTwo, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodejsStackVersions = cl.objs["nodejs"].Spec.Versions
+	if len(nodejsStackVersions) != 1 {
+		t.Fatal(fmt.Sprintf("Expected one version of nodejs stack, but found %v: %v", len(nodejsStackVersions), nodejsStackVersions))
+	}
+
+	if nodejsStackVersions[0].RepositoryUrl != stack_index_url_two {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack from repository %v, but found %v", stack_index_url_two, nodejsStackVersions[0].RepositoryUrl))
+	}
+
+	// The first StackHub does not take the version back, and does not prune it.
+	hub.Spec.ConflictPolicy = kabanerov1alpha2.StackConflictPolicyPreferRepositoryPrefix + "two"
+	err = newTestReconciler(cl).syncStacks(ctx, hub, nil, repositoryTestLogger)
+	if err != nil {
+		t.Fatal("Could not synchronize the stacks", err)
+	}
+
+	nodejsStackVersions = cl.objs["nodejs"].Spec.Versions
+	if len(nodejsStackVersions) != 1 || nodejsStackVersions[0].RepositoryUrl != stack_index_url_two {
+		t.Fatal(fmt.Sprintf("Expected nodejs stack from repository %v, but found %v", stack_index_url_two, nodejsStackVersions))
+	}
+
+	// Conflicts are errors.
+	hub.Spec.ConflictPolicy = kabanerov1alpha2.StackConflictPolicyError
+	err = newTestReconciler(cl).syncStacks(ctx, hub, nil, repositoryTestLogger)
+	if err == nil {
+		t.Fatal(fmt.Sprintf("Expected an error synchronizing the stacks, but found statuses: %v", hub.Status.Stacks))
+	}
+}
+
+// Tests that if an existing stack version has desired state defined (any allowed string), it should not be deleted or modified.
+// Tests that if an existing stack version has no desired state defined and it matches the version in the index, the existing
+// stack's values are overriden by the ones in the index.
+func TestResolveRepositoryStacksCleanup1(t *testing.T) {
+	stack := stackResource.DeepCopy()
+	stack.Spec.Versions[0].DesiredState = "inactive"
+	stack.Spec.Versions[1].DesiredState = kabanerov1alpha2.StackDesiredStateActive
+
+	deployedStacks := make(map[string]*kabanerov1alpha2.Stack)
+	deployedStacks[stack.Name] = stack
+	cl := stackHubTestClient{deployedStacks}
+
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+	stackUrl := server.URL + defaultIndexName
+	hub := createStackHub(stackUrl)
+
+	ctx := context.Background()
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two stacks should have been created.
+	javaMicroprofileStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "java-microprofile"}, javaMicroprofileStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	nodejsStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "nodejs"}, nodejsStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	// Three nodejs versions are expected to be available.
+	if len(nodejsStack.Spec.Versions) != 3 {
+		t.Fatal(fmt.Sprintf("The nodejs stack did not have the number of expected versions: 3. It has: %v. Stack: %v", len(nodejsStack.Spec.Versions), nodejsStack))
+	}
+
+	// Iterate and validate that the expected versions and content match what we expect them to be.
+	for _, njVersion := range nodejsStack.Spec.Versions {
+		// Existing version 0.2.6 matches what is in the index. The content should have been overriden with what is in the index because
+		// the existing stack version did not have its desired state set.
+		if njVersion.Version == "0.2.6" {
+			if njVersion.Pipelines[0].Https.Url != "https://github.com/kabanero-io/collections/releases/download/0.4.0/incubator.common.pipeline.default.tar.gz" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.6 should have been updated. Stack version: %v", njVersion))
+			}
+		}
+
+		// Existing version 0.2.5 does exist in the new index. However it's desired state is set (active); therefore, this version must remain unchanged.
+		if njVersion.Version == "0.2.5" {
+			if njVersion.Pipelines[0].Https.Url != "https://pipelines/default/0.2.5" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.5 did not contain the expected Url. Url found: %v. Stack version: %v", njVersion.Pipelines[0].Https.Url, njVersion))
+			}
+			if njVersion.DesiredState != "active" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.5 did not contain the expected desired state of active. Desired state found: %v. Stack version: %v", njVersion.DesiredState, njVersion))
+			}
+		}
+
+		// Existing version 0.2.4 should should not have been removed or modified. It defines a non-empty desired state, which under the new definition of a desired state, it
+		// is equivalent to saying do not delete/modify the resource.
+		if njVersion.Version == "0.2.4" {
+			if njVersion.Pipelines[0].Https.Url != "https://pipelines/default/0.2.4" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.4 did not contain the expected Url. Url found: %v. Stack version: %v", njVersion.Pipelines[0].Https.Url, njVersion))
+			}
+			if njVersion.DesiredState != "inactive" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.4 did not contain the expected desired state of inactive. Desired state found: %v. Stack version: %v", njVersion.DesiredState, njVersion))
+			}
+		}
+	}
+}
+
+// Tests that if an existing stack version with a set desired state matches the name/version of a stack in the index, the
+// data associated with the index version is ignored.
+// Tests that if an existing stack version with a set desired state (any string), the existing stack is not deleted/changed;
+// even though, the existing/index stack versions do not match.
+// Tests that a stack versions with an unset desired states are removed if they are not found in the index being deployed.
+func TestResolveRepositoryStacksCleanup2(t *testing.T) {
+	stack := stackResource.DeepCopy()
+	stack.Spec.Versions[0].DesiredState = kabanerov1alpha2.StackDesiredStateActive
+	stack.Spec.Versions[1].DesiredState = ""
+	stack.Spec.Versions[2].DesiredState = kabanerov1alpha2.StackDesiredStateActive
+
+	deployedStacks := make(map[string]*kabanerov1alpha2.Stack)
+	deployedStacks[stack.Name] = stack
+	cl := stackHubTestClient{deployedStacks}
+
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+	stackUrl := server.URL + defaultIndexName
+	hub := createStackHub(stackUrl)
+
+	ctx := context.Background()
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two stacks should have been created.
+	javaMicroprofileStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "java-microprofile"}, javaMicroprofileStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	nodejsStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "nodejs"}, nodejsStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	// Only one nodejs versions are expected to be available.
+	if len(nodejsStack.Spec.Versions) != 2 {
+		t.Fatal(fmt.Sprintf("The nodejs stack did not have the number of expected versions: 2. It has: %v. Stack: %v", len(nodejsStack.Spec.Versions), nodejsStack))
+	}
+
+	// Iterate and validate that the expected versions and content match what we expect them to be.
+	for _, njVersion := range nodejsStack.Spec.Versions {
+		// Existing version 0.2.4 does not what is in the index; however, the existing version has a set desired state (active).
+		// This means that the existing version 0.2.4 should be kept.
+		if njVersion.Version == "0.2.4" {
+			if njVersion.Pipelines[0].Https.Url != "https://pipelines/default/0.2.4" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.4 should not have been updated. Stack version: %v", njVersion))
+			}
+			if njVersion.DesiredState != "active" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.4 did not contain the expected desired state of active. Desired state found: %v. Stack version: %v", njVersion.DesiredState, njVersion))
+			}
+		}
+
+		// Existing version 0.2.5 should have been deleted because it is not in the new index and its current desired state was not set.
+		if njVersion.Version == "0.2.5" {
+			t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.5 should have been deleted. Stack: %v", nodejsStack))
+		}
+
+		// Existing version 0.2.6 matches what is in the index; however, the existing version has a set desired state (active).
+		// This means that the existing 0.2.6 values should not be overriden by the contents of the index.
+		if njVersion.Version == "0.2.6" {
+			if njVersion.Pipelines[0].Https.Url != "https://pipelines/default/0.2.6" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.6 should not have been updated. Stack version: %v", njVersion))
+			}
+			if njVersion.DesiredState != "active" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.6 did not contain the expected desired state of active. Desired state found: %v. Stack version: %v", njVersion.DesiredState, njVersion))
+			}
+		}
+	}
+}
+
+// Tests that an existing stack is not deleted if the index does not have a matching stack and there is at least one existing stack version that defines
+// a desired state. Furthermore, any other versions of the existing stack that do not define a desired state, should be deleted.
+func TestResolveRepositoryStacksCleanup3(t *testing.T) {
+	stack := stackResource.DeepCopy()
+	stack.Spec.Name = "cleanuptest"
+	stack.ObjectMeta.Name = "cleanuptest"
+	stack.Spec.Versions[1].DesiredState = kabanerov1alpha2.StackDesiredStateActive
+
+	deployedStacks := make(map[string]*kabanerov1alpha2.Stack)
+	deployedStacks[stack.Name] = stack
+	cl := stackHubTestClient{deployedStacks}
+
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+	stackUrl := server.URL + defaultIndexName
+	hub := createStackHub(stackUrl)
+
+	ctx := context.Background()
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three stacks should have been deployed. nodejs and java-microprofile were defined in index
+	// and teststack was a pre-existing stack with one version set to active.
+	javaMicroprofileStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "java-microprofile"}, javaMicroprofileStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	// Only one nodejs version is expected to be available.
+	if len(javaMicroprofileStack.Spec.Versions) != 1 {
+		t.Fatal(fmt.Sprintf("The java-microprofile stack did not have the number of expected versions: 1. It has: %v. Stack: %v", len(javaMicroprofileStack.Spec.Versions), javaMicroprofileStack))
+	}
+
+	// Iterate and validate that the expected versions and content match what we expect them to be.
+	for _, jmVersion := range javaMicroprofileStack.Spec.Versions {
+		// Existing version 0.2.19 matches what is in the index; however, the existing version has a set desired state (active).
+		// This means that the existing values should not be overriden by the contents of the index.
+		if jmVersion.Version == "0.2.19" {
+			if jmVersion.Pipelines[0].Https.Url != "https://github.com/kabanero-io/collections/releases/download/0.4.0/incubator.common.pipeline.default.tar.gz" {
+				t.Fatal(fmt.Sprintf("java-microprofile stack version 0.2.19 should not have been updated. Stack version: %v", jmVersion))
+			}
+		}
+	}
+
+	nodejsStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "nodejs"}, nodejsStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	// Only one nodejs version is expected to be available.
+	if len(nodejsStack.Spec.Versions) != 1 {
+		t.Fatal(fmt.Sprintf("The nodejs stack did not have the number of expected versions: 1. It has: %v. Stack: %v", len(nodejsStack.Spec.Versions), nodejsStack))
+	}
+
+	// Iterate and validate that the expected versions and content match what we expect them to be.
+	for _, njVersion := range nodejsStack.Spec.Versions {
+		// Existing version 0.2.6 matches what is in the index; however, the existing version has a set desired state (active).
+		// This means that the existing values should not be overriden by the contents of the index.
+		if njVersion.Version == "0.2.6" {
+			if njVersion.Pipelines[0].Https.Url != "https://github.com/kabanero-io/collections/releases/download/0.4.0/incubator.common.pipeline.default.tar.gz" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.6 should not have been updated. Stack version: %v", njVersion))
+			}
+		}
+	}
+
+	cleanuptestStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "cleanuptest"}, cleanuptestStack)
+	if err != nil {
+		t.Fatal("Could not resolve the cleanuptest stack", err)
+	}
+
+	// Only one nodejs version is expected to be available.
+	if len(cleanuptestStack.Spec.Versions) != 1 {
+		t.Fatal(fmt.Sprintf("The cleanuptest stack did not have the number of expected versions: 1. It has: %v. Stack: %v", len(cleanuptestStack.Spec.Versions), cleanuptestStack))
+	}
+
+	// Iterate and validate that the expected versions and content match what we expect them to be.
+	for _, ctVersion := range cleanuptestStack.Spec.Versions {
+		// Existing version 0.2.4 should have been deleted because it is not in the new index and its current desired state is not set.
+		if ctVersion.Version == "0.2.4" {
+			t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.4 should have been deleted. Stack: %v", cleanuptestStack))
+		}
+
+		// Existing version 0.2.6 should have been deleted because it is not in the new index and its current desired state is not set.
+		if ctVersion.Version == "0.2.6" {
+			t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.6 should have been deleted. Stack: %v", cleanuptestStack))
+		}
+
+		// Existing version 0.2.5 matches what is in the index; however, the existing version has a desired state set (active).
+		// This means that the existing values should not be overriden by the contents of the index.
+		if ctVersion.Version == "0.2.5" {
+			if ctVersion.Pipelines[0].Https.Url != "https://pipelines/default/0.2.5" {
+				t.Fatal(fmt.Sprintf("Cleanuptest stack version 0.2.6 should not have been updated. Stack version: %v", ctVersion))
+			}
+			if ctVersion.DesiredState != "active" {
+				t.Fatal(fmt.Sprintf("Cleanuptest stack version 0.2.6 did not contain the expected desired state of active. Desired state found: %v. Stack version: %v", ctVersion.DesiredState, ctVersion))
+			}
+		}
+	}
+}
+
+// Tests that an existing stack is deleted if the index does not have a matching stack and the existing stack's versions
+// do not define a desired state.
+func TestResolveRepositoryStacksCleanup4(t *testing.T) {
+	stack := stackResource.DeepCopy()
+	stack.Spec.Name = "cleanuptest"
+	stack.ObjectMeta.Name = "cleanuptest"
+
+	deployedStacks := make(map[string]*kabanerov1alpha2.Stack)
+	deployedStacks[stack.Name] = stack
+	cl := stackHubTestClient{deployedStacks}
+
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+	stackUrl := server.URL + defaultIndexName
+	hub := createStackHub(stackUrl)
+
+	ctx := context.Background()
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two stacks should have been deployed from the index: nodejs and java-microprofile.
+	// The cleanuptest stack should have been deleted.
+	javaMicroprofileStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "java-microprofile"}, javaMicroprofileStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	// Only one nodejs version is expected to be available.
+	if len(javaMicroprofileStack.Spec.Versions) != 1 {
+		t.Fatal(fmt.Sprintf("The java-microprofile stack did not have the number of expected versions: 1. It has: %v. Stack: %v", len(javaMicroprofileStack.Spec.Versions), javaMicroprofileStack))
+	}
+
+	// Iterate and validate that the expected versions and content match what we expect them to be.
+	for _, jmVersion := range javaMicroprofileStack.Spec.Versions {
+		// Existing version 0.2.19 matches what is in the index; however, the existing version has a set desired state (active).
+		// This means that the existing values should not be overriden by the contents of the index.
+		if jmVersion.Version == "0.2.19" {
+			if jmVersion.Pipelines[0].Https.Url != "https://github.com/kabanero-io/collections/releases/download/0.4.0/incubator.common.pipeline.default.tar.gz" {
+				t.Fatal(fmt.Sprintf("java-microprofile stack version 0.2.19 should not have been updated. Stack version: %v", jmVersion))
+			}
+		}
+	}
+
+	nodejsStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "nodejs"}, nodejsStack)
+	if err != nil {
+		t.Fatal("Could not resolve the java-microprofile stack", err)
+	}
+
+	// Only one nodejs version is expected to be available.
+	if len(nodejsStack.Spec.Versions) != 1 {
+		t.Fatal(fmt.Sprintf("The nodejsStack stack did not have the number of expected versions: 1. It has: %v. Stack: %v", len(nodejsStack.Spec.Versions), nodejsStack))
+	}
+
+	// Iterate and validate that the expected versions and content match what we expect them to be.
+	for _, njVersion := range nodejsStack.Spec.Versions {
+		// Existing version 0.2.6 matches what is in the index; however, the existing version has a set desired state (active).
+		// This means that the existing values should not be overriden by the contents of the index.
+		if njVersion.Version == "0.2.6" {
+			if njVersion.Pipelines[0].Https.Url != "https://github.com/kabanero-io/collections/releases/download/0.4.0/incubator.common.pipeline.default.tar.gz" {
+				t.Fatal(fmt.Sprintf("Nodejs stack version 0.2.6 should not have been updated. Stack version: %v", njVersion))
+			}
+		}
+	}
+
+	cleanuptestStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "cleanuptest"}, cleanuptestStack)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			t.Fatal("Could not resolve the cleanuptest stack", err)
+		}
+	}
+}
+
+// Tests that a stack controlled by someone else, such as another StackHub, is not deleted even
+// though the index does not have a matching stack.
+func TestResolveRepositoryStacksCleanupNotOwned(t *testing.T) {
+	stack := stackResource.DeepCopy()
+	stack.Spec.Name = "cleanuptest"
+	stack.ObjectMeta.Name = "cleanuptest"
+	stack.OwnerReferences[0].Kind = "StackHub"
+	stack.OwnerReferences[0].UID = "stackhub-uid"
+
+	deployedStacks := make(map[string]*kabanerov1alpha2.Stack)
+	deployedStacks[stack.Name] = stack
+	cl := stackHubTestClient{deployedStacks}
+
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+	stackUrl := server.URL + defaultIndexName
+	hub := createStackHub(stackUrl)
+
+	ctx := context.Background()
+	err := syncTestStacks(ctx, hub, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanuptestStack := &kabanerov1alpha2.Stack{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "cleanuptest"}, cleanuptestStack)
+	if err != nil {
+		t.Fatal("The cleanuptest stack should not have been deleted", err)
+	}
+	if len(cleanuptestStack.Spec.Versions) != 3 {
+		t.Fatal(fmt.Sprintf("The cleanuptest stack should not have been changed: %v", cleanuptestStack))
+	}
+}
+
+// Test that a stack is only skipped when its index entries and its spec are unchanged since
+// they were last applied, and no refresh was requested.
+func TestIsIndexEntryUnchanged(t *testing.T) {
+	hub := &kabanerov1alpha2.StackHub{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "diff-uid"}}
+	versions := []kabanerov1alpha2.StackVersion{{Version: "0.2.0", RepositoryUrl: "https://index/incubator.yaml"}}
+	digest := indexEntryDigest(versions)
+	stackResource := &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Generation: 3}}
+	noRefresh := indexRefreshRequests{repositoryUrls: map[string]bool{}}
+
+	if isIndexEntryUnchanged(hub, "nodejs", digest, versions, stackResource, noRefresh) {
+		t.Fatal("A stack that was never applied should not be unchanged")
+	}
+
+	recordIndexEntry(hub, "nodejs", digest, stackResource)
+	if !isIndexEntryUnchanged(hub, "nodejs", digest, versions, stackResource, noRefresh) {
+		t.Fatal("A stack whose index entries and spec did not change should be unchanged")
+	}
+
+	changedVersions := []kabanerov1alpha2.StackVersion{{Version: "0.2.1", RepositoryUrl: "https://index/incubator.yaml"}}
+	if isIndexEntryUnchanged(hub, "nodejs", indexEntryDigest(changedVersions), changedVersions, stackResource, noRefresh) {
+		t.Fatal("A stack with a new version in the index should not be unchanged")
+	}
+
+	modifiedStack := stackResource.DeepCopy()
+	modifiedStack.Generation = 4
+	if isIndexEntryUnchanged(hub, "nodejs", digest, versions, modifiedStack, noRefresh) {
+		t.Fatal("A stack whose spec was modified should not be unchanged")
+	}
+
+	repositoryRefresh := indexRefreshRequests{repositoryUrls: map[string]bool{"https://index/incubator.yaml": true}}
+	if isIndexEntryUnchanged(hub, "nodejs", digest, versions, stackResource, repositoryRefresh) {
+		t.Fatal("A stack whose repository refresh was requested should not be unchanged")
+	}
+
+	if isIndexEntryUnchanged(hub, "nodejs", digest, versions, stackResource, indexRefreshRequests{all: true}) {
+		t.Fatal("A stack should not be unchanged when all repositories are refreshed")
+	}
+}
+
+// Test that the digest of the index entries does not depend on where the pointer fields of
+// the versions are stored.
+func TestIndexEntryDigestPointers(t *testing.T) {
+	versions := []kabanerov1alpha2.StackVersion{{
+		Version: "0.2.0",
+		Pipelines: []kabanerov1alpha2.PipelineSpec{{
+			Id:             "default",
+			Sha256:         defaultIndexPipelineDigest,
+			ServiceAccount: &kabanerov1alpha2.PipelineServiceAccountSpec{},
+		}},
+	}}
+	copied := []kabanerov1alpha2.StackVersion{*versions[0].DeepCopy()}
+
+	if indexEntryDigest(versions) != indexEntryDigest(copied) {
+		t.Fatal("Expected copies of the same versions to have the same digest")
+	}
+
+	copied[0].Version = "0.2.1"
+	if indexEntryDigest(versions) == indexEntryDigest(copied) {
+		t.Fatal("Expected different versions to have different digests")
+	}
+}
+
+// Test that the index entries of a deleted StackHub are forgotten, and those of the
+// StackHubs that still exist, or are in other namespaces, are kept.
+func TestPruneIndexEntries(t *testing.T) {
+	deleted := &kabanerov1alpha2.StackHub{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "prune", UID: "deleted-uid"}}
+	kept := &kabanerov1alpha2.StackHub{ObjectMeta: metav1.ObjectMeta{Name: "kept", Namespace: "prune", UID: "kept-uid"}}
+	other := &kabanerov1alpha2.StackHub{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other", UID: "other-uid"}}
+	stackResource := &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Generation: 1}}
+	for _, hub := range []*kabanerov1alpha2.StackHub{deleted, kept, other} {
+		recordIndexEntry(hub, "nodejs", "digest", stackResource)
+	}
+
+	pruneIndexEntries("prune", map[types.UID]bool{kept.UID: true})
+
+	noRefresh := indexRefreshRequests{repositoryUrls: map[string]bool{}}
+	if isIndexEntryUnchanged(deleted, "nodejs", "digest", nil, stackResource, noRefresh) {
+		t.Fatal("Expected the index entries of the deleted StackHub to be forgotten")
+	}
+	if !isIndexEntryUnchanged(kept, "nodejs", "digest", nil, stackResource, noRefresh) {
+		t.Fatal("Expected the index entries of the existing StackHub to be kept")
+	}
+	if !isIndexEntryUnchanged(other, "nodejs", "digest", nil, stackResource, noRefresh) {
+		t.Fatal("Expected the index entries of the StackHub in another namespace to be kept")
+	}
+
+	forgetIndexEntry(kept, "nodejs")
+	if isIndexEntryUnchanged(kept, "nodejs", "digest", nil, stackResource, noRefresh) {
+		t.Fatal("Expected the index entries of the deleted stack to be forgotten")
+	}
+}
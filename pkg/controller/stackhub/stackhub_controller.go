@@ -0,0 +1,558 @@
+package stackhub
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_stackhub")
+
+// How often the repository index is re-read if the StackHub instance does not say otherwise.
+const defaultSyncInterval = 5 * time.Minute
+
+// Add creates a new StackHub Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileStackHub{client: mgr.GetClient(), scheme: mgr.GetScheme(), indexResolver: stack.ResolveIndexUsingCache}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("stackhub-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource StackHub.  A new index refresh request is processed too.
+	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.StackHub{}}, &handler.EnqueueRequestForObject{}, getRefreshWatchPredicateFunc())
+	if err != nil {
+		return err
+	}
+
+	// Watch the Stacks that a StackHub owns, so that changed or deleted Stacks are put back,
+	// and a refresh requested by a Stack is processed.  A Stack whose versions come from
+	// several repositories is owned by the StackHub of each.
+	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.Stack{}}, &handler.EnqueueRequestForOwner{
+		IsController: false,
+		OwnerType:    &kabanerov1alpha2.StackHub{},
+	}, getRefreshWatchPredicateFunc())
+	if err != nil {
+		return err
+	}
+
+	// Watch ConfigMaps, so that changes to a stack index held in a ConfigMap are applied
+	// to the StackHubs that read it.
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: indexConfigMapMapFunc(mgr.GetClient())})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Returns a watch predicate that processes a new generation, or a new index refresh request.
+func getRefreshWatchPredicateFunc() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+				e.MetaOld.GetAnnotations()[stack.RefreshIndexAnnotation] != e.MetaNew.GetAnnotations()[stack.RefreshIndexAnnotation]
+		},
+	}
+}
+
+// Returns a map function that makes a reconcile request for each StackHub instance that
+// reads a stack index from the ConfigMap.  A StackHub only reads ConfigMaps in its own
+// namespace.
+func indexConfigMapMapFunc(cl client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		hubs := &kabanerov1alpha2.StackHubList{}
+		err := cl.List(context.TODO(), hubs, client.InNamespace(a.Meta.GetNamespace()))
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Could not process ConfigMap event for \"%v\"", a.Meta.GetName()))
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+		for _, hub := range hubs.Items {
+			if hub.Spec.Repository.ConfigMapRef.Name == a.Meta.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: hub.Name, Namespace: hub.Namespace}})
+			}
+		}
+		return requests
+	}
+}
+
+// The finalizer that removes the versions of the StackHub repository from the stacks when
+// the StackHub is deleted.
+const stackHubFinalizer = "kabanero.io/stackhub-controller"
+
+// blank assignment to verify that ReconcileStackHub implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileStackHub{}
+
+// ReconcileStackHub reconciles a StackHub object
+type ReconcileStackHub struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+
+	//The indexResolver which will be used during reconciliation
+	indexResolver func(context.Context, client.Client, kabanerov1alpha2.RepositoryConfig, string, []stack.Pipelines, []stack.Trigger, string, *cache.ArtifactProxy, time.Duration, bool, logr.Logger) (*stack.Index, error)
+}
+
+// Reconcile reads the repository index of a StackHub, and creates, updates or prunes
+// the Stack instances that the StackHub owns so that they match the index.
+func (r *ReconcileStackHub) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	// The liveness check reports a reconcile that does not complete.
+	defer cutils.BeginReconcile()()
+
+	ctx := context.Background()
+
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling StackHub")
+
+	// Fetch the StackHub instance
+	instance := &kabanerov1alpha2.StackHub{}
+	err := r.client.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.  Forget the index refreshes
+			// and the applied index entries of the StackHubs that no longer exist.
+			hubs := &kabanerov1alpha2.StackHubList{}
+			if lerr := r.client.List(ctx, hubs, client.InNamespace(request.Namespace)); lerr == nil {
+				existing := map[types.UID]bool{}
+				for _, hub := range hubs.Items {
+					existing[hub.GetUID()] = true
+				}
+				stack.PruneIndexRefreshes("StackHub", request.Namespace, existing)
+				pruneIndexEntries(request.Namespace, existing)
+			}
+
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	// If the StackHub is being deleted, remove the versions of its repository from the stacks.
+	beingDeleted, err := r.processDeletion(ctx, instance, reqLogger)
+	if err != nil || beingDeleted {
+		return reconcile.Result{}, err
+	}
+
+	k, err := r.getKabanero(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// The Kabanero instance deletes the stacks of its StackHub before it is deleted.  They
+	// must not be created again.
+	if k != nil && !k.GetDeletionTimestamp().IsZero() && metav1.IsControlledBy(instance, k) {
+		reqLogger.Info(fmt.Sprintf("Kabanero instance %v is being deleted. The stacks are not synchronized.", k.GetName()))
+		return reconcile.Result{}, nil
+	}
+
+	err = r.syncStacks(ctx, instance, k, reqLogger)
+	if err != nil {
+		reqLogger.Error(err, "Error synchronizing the stacks")
+		instance.Status.Ready = "False"
+		instance.Status.Message = err.Error()
+	} else {
+		now := metav1.Now()
+		instance.Status.Ready = "True"
+		instance.Status.Message = ""
+		instance.Status.LastSyncTime = &now
+	}
+
+	err = r.client.Status().Update(ctx, instance)
+	if err != nil {
+		reqLogger.Error(err, "Error updating the StackHub status")
+		return reconcile.Result{}, err
+	}
+
+	// Another StackHub may have updated a stack at the same time.  Try again.
+	for _, status := range instance.Status.Stacks {
+		if status.Status == kabanerov1alpha2.StackHubSyncStatusFailed {
+			return reconcile.Result{Requeue: true}, nil
+		}
+	}
+
+	// The repository is outside of Kubernetes, so check it again later.
+	return reconcile.Result{RequeueAfter: getRequeueInterval(instance, k, time.Now())}, nil
+}
+
+// Drives the deletion of the StackHub.  Adds the finalizer to a StackHub that is not being
+// deleted.  Once a StackHub is deleted, removes the versions of its repository from the
+// stacks, and then the finalizer.  Returns true if the StackHub is being deleted.
+func (r *ReconcileStackHub) processDeletion(ctx context.Context, hub *kabanerov1alpha2.StackHub, reqLogger logr.Logger) (bool, error) {
+	foundFinalizer := false
+	for _, finalizer := range hub.Finalizers {
+		if finalizer == stackHubFinalizer {
+			foundFinalizer = true
+		}
+	}
+
+	beingDeleted := !hub.DeletionTimestamp.IsZero()
+	if !beingDeleted {
+		if !foundFinalizer {
+			hub.Finalizers = append(hub.Finalizers, stackHubFinalizer)
+			err := r.client.Update(ctx, hub)
+			if err != nil {
+				reqLogger.Error(err, "Unable to set the StackHub controller finalizer.")
+				return beingDeleted, err
+			}
+		}
+
+		return beingDeleted, nil
+	}
+
+	if foundFinalizer {
+		// No versions are listed by the repository any more.
+		err := r.pruneStacks(ctx, hub, map[string][]kabanerov1alpha2.StackVersion{}, reqLogger)
+		if err != nil {
+			reqLogger.Error(err, "Error removing the stack versions of the StackHub repository.")
+			return beingDeleted, err
+		}
+
+		var newFinalizerList []string
+		for _, finalizer := range hub.Finalizers {
+			if finalizer == stackHubFinalizer {
+				continue
+			}
+			newFinalizerList = append(newFinalizerList, finalizer)
+		}
+
+		hub.Finalizers = newFinalizerList
+		err = r.client.Update(ctx, hub)
+		if err != nil {
+			reqLogger.Error(err, "Error while attempting to remove the finalizer.")
+			return beingDeleted, err
+		}
+	}
+
+	return beingDeleted, nil
+}
+
+// Returns the Kabanero instance that controls the StackHub, or otherwise the one that manages
+// its namespace.  Its artifact proxy and index cache settings apply to the StackHub.
+// Returns nil if there is none.
+func (r *ReconcileStackHub) getKabanero(ctx context.Context, hub *kabanerov1alpha2.StackHub) (*kabanerov1alpha2.Kabanero, error) {
+	owner := metav1.GetControllerOf(hub)
+	if owner != nil && owner.Kind == "Kabanero" {
+		k := &kabanerov1alpha2.Kabanero{}
+		err := r.client.Get(ctx, types.NamespacedName{Name: owner.Name, Namespace: hub.GetNamespace()}, k)
+		if err == nil {
+			return k, nil
+		}
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	kabaneros := &kabanerov1alpha2.KabaneroList{}
+	err := r.client.List(ctx, kabaneros, client.InNamespace(hub.GetNamespace()))
+	if err != nil {
+		return nil, err
+	}
+	return cutils.OldestKabaneroInstance(kabaneros.Items), nil
+}
+
+// Returns the interval at which the repository index is re-read.
+func getSyncInterval(hub *kabanerov1alpha2.StackHub) time.Duration {
+	if hub.Spec.SyncInterval == nil || hub.Spec.SyncInterval.Duration <= 0 {
+		return defaultSyncInterval
+	}
+
+	return hub.Spec.SyncInterval.Duration
+}
+
+// Returns when the StackHub is reconciled again: after the sync interval, or at the next
+// refresh scheduled by the Kabanero instance if that comes first.
+func getRequeueInterval(hub *kabanerov1alpha2.StackHub, k *kabanerov1alpha2.Kabanero, now time.Time) time.Duration {
+	interval := getSyncInterval(hub)
+	if k != nil {
+		if untilRefresh := stack.TimeUntilScheduledRefresh(k, now); untilRefresh > 0 && untilRefresh < interval {
+			return untilRefresh
+		}
+	}
+	return interval
+}
+
+// Reads the repository index, and makes the Stack instances owned by the StackHub match it.
+// The sync status of each stack is saved in the StackHub status.
+func (r *ReconcileStackHub) syncStacks(ctx context.Context, hub *kabanerov1alpha2.StackHub, k *kabanerov1alpha2.Kabanero, reqLogger logr.Logger) error {
+	err := validateConflictPolicy(hub)
+	if err != nil {
+		return err
+	}
+
+	// Find out if the repository index must be re-read instead of coming from the index cache.
+	refresh, err := getIndexRefreshRequests(ctx, hub, r.client)
+	if err != nil {
+		return err
+	}
+
+	stackMap, err := r.resolveStacks(ctx, hub, k, refresh, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	// The index was re-read, so the refresh requests are done.
+	for _, obj := range refresh.requesters {
+		stack.IndexRefreshCompleted(obj)
+	}
+
+	ids := []string{}
+	for id, versions := range stackMap {
+		// Remove the tag portion of all images associated with the stack versions.
+		for i := range versions {
+			err = sutils.RemoveTagFromStackImages(&versions[i], id)
+			if err != nil {
+				return err
+			}
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	stackStatuses := []kabanerov1alpha2.StackHubStackStatus{}
+	conflicts := []string{}
+	for _, id := range ids {
+		status := r.syncStack(ctx, hub, id, stackMap[id], refresh, reqLogger)
+		if status.Status == kabanerov1alpha2.StackHubSyncStatusConflict {
+			conflicts = append(conflicts, status.StatusMessage)
+		}
+		stackStatuses = append(stackStatuses, status)
+	}
+	hub.Status.Stacks = stackStatuses
+
+	err = r.pruneStacks(ctx, hub, stackMap, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) != 0 && hub.Spec.ConflictPolicy == kabanerov1alpha2.StackConflictPolicyError {
+		return fmt.Errorf("Conflicts are not allowed by the %v conflict policy: %v", hub.Spec.ConflictPolicy, strings.Join(conflicts, " "))
+	}
+	return nil
+}
+
+// Creates or updates the Stack instance for a single stack id.  A Stack instance that is
+// not managed by a StackHub is left alone.  The versions of other repositories in the Stack
+// instance are only replaced if the conflict policy prefers the repository of the StackHub.
+// The Stack instance is only updated if the versions read from the index change it.
+func (r *ReconcileStackHub) syncStack(ctx context.Context, hub *kabanerov1alpha2.StackHub, id string, versions []kabanerov1alpha2.StackVersion, refresh indexRefreshRequests, reqLogger logr.Logger) kabanerov1alpha2.StackHubStackStatus {
+	status := kabanerov1alpha2.StackHubStackStatus{Name: id, Status: kabanerov1alpha2.StackHubSyncStatusSynced}
+	for _, version := range versions {
+		status.Versions = append(status.Versions, version.Version)
+	}
+
+	entryDigest := indexEntryDigest(versions)
+	stackResource := &kabanerov1alpha2.Stack{}
+	err := r.client.Get(ctx, client.ObjectKey{Name: id, Namespace: hub.GetNamespace()}, stackResource)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			status.Status = kabanerov1alpha2.StackHubSyncStatusFailed
+			status.StatusMessage = err.Error()
+			return status
+		}
+
+		stackResource = &kabanerov1alpha2.Stack{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            id,
+				Namespace:       hub.GetNamespace(),
+				OwnerReferences: []metav1.OwnerReference{stackHubOwnerReference(hub, true)},
+			},
+			Spec: kabanerov1alpha2.StackSpec{
+				Name:     id,
+				Versions: versions,
+			},
+		}
+
+		reqLogger.Info(fmt.Sprintf("Creating stack %v", id))
+		err = r.client.Create(ctx, stackResource)
+		if err != nil {
+			status.Status = kabanerov1alpha2.StackHubSyncStatusFailed
+			status.StatusMessage = err.Error()
+			return status
+		}
+		recordIndexEntry(hub, id, entryDigest, stackResource)
+		return status
+	}
+
+	if !isManagedByStackHub(hub, stackResource) && !isSharedWithStackHub(stackResource) {
+		status.Status = kabanerov1alpha2.StackHubSyncStatusConflict
+		status.StatusMessage = fmt.Sprintf("Stack %v is not controlled by StackHub %v, and was not changed.", id, hub.GetName())
+		return status
+	}
+
+	// Skip the stack if its index entries did not change since they were last applied.
+	if isIndexEntryUnchanged(hub, id, entryDigest, versions, stackResource, refresh) {
+		reqLogger.Info(fmt.Sprintf("Stack %v is unchanged since the last index refresh.", id))
+		return status
+	}
+
+	original := stackResource.DeepCopy()
+	adoptStack(hub, stackResource)
+
+	// Add each version to the versions array if it's not already there.  If it's already there,
+	// don't touch a version whose desired state was set.  A version read from the repository
+	// of another StackHub is only replaced if the conflict policy prefers this repository.
+	conflicts := []string{}
+	for _, version := range versions {
+		foundVersion := false
+		for j, stackVersion := range stackResource.Spec.Versions {
+			if stackVersion.Version != version.Version {
+				continue
+			}
+			foundVersion = true
+
+			if !isFromStackHubRepository(hub, original, stackVersion) {
+				if !prefersOwnRepository(hub) || len(stackVersion.DesiredState) != 0 {
+					reqLogger.Info(fmt.Sprintf("Stack %v version %v is also listed by repository %v, whose version is used.", id, version.Version, stackVersion.RepositoryUrl))
+					conflicts = append(conflicts, version.Version)
+					continue
+				}
+				reqLogger.Info(fmt.Sprintf("Stack %v version %v is also listed by repository %v. Using the version from preferred repository %v.", id, version.Version, stackVersion.RepositoryUrl, hub.Spec.Repository.Name))
+			}
+
+			if len(stackVersion.DesiredState) == 0 {
+				stackVersion.Pipelines = version.Pipelines
+				stackVersion.SkipCertVerification = version.SkipCertVerification
+				stackVersion.SkipRegistryCertVerification = version.SkipRegistryCertVerification
+				stackVersion.Images = version.Images
+				stackVersion.RepositoryUrl = version.RepositoryUrl
+			}
+
+			// The deprecation of a version is always taken from the index.
+			stackVersion.Deprecated = version.Deprecated
+			stackVersion.EndOfSupport = version.EndOfSupport
+			stackResource.Spec.Versions[j] = stackVersion
+		}
+
+		if !foundVersion {
+			stackResource.Spec.Versions = append(stackResource.Spec.Versions, version)
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(original.Spec, stackResource.Spec) || !equality.Semantic.DeepEqual(original.OwnerReferences, stackResource.OwnerReferences) {
+		err = r.client.Update(ctx, stackResource)
+		if err != nil {
+			status.Status = kabanerov1alpha2.StackHubSyncStatusFailed
+			status.StatusMessage = err.Error()
+			return status
+		}
+	}
+
+	// A conflict is reported until it is resolved, so the stack is not skipped next time.
+	if len(conflicts) != 0 {
+		status.Status = kabanerov1alpha2.StackHubSyncStatusConflict
+		status.StatusMessage = fmt.Sprintf("Stack %v versions %v are also listed by the repository of another StackHub, whose versions are used.", id, strings.Join(conflicts, ", "))
+		return status
+	}
+
+	recordIndexEntry(hub, id, entryDigest, stackResource)
+	return status
+}
+
+// Removes the versions of the StackHub repository that are no longer in its index from the
+// Stack instances managed by the StackHub.  Versions with a desired state are kept.  A Stack
+// instance with no versions left is deleted.  The StackHub stops owning a Stack instance that
+// has no versions of its repository left, or all of them once the StackHub is deleted.
+func (r *ReconcileStackHub) pruneStacks(ctx context.Context, hub *kabanerov1alpha2.StackHub, stackMap map[string][]kabanerov1alpha2.StackVersion, reqLogger logr.Logger) error {
+	deployedStacks := &kabanerov1alpha2.StackList{}
+	err := r.client.List(ctx, deployedStacks, client.InNamespace(hub.GetNamespace()))
+	if err != nil {
+		return err
+	}
+
+	for i, _ := range deployedStacks.Items {
+		deployedStack := &deployedStacks.Items[i]
+		if !isManagedByStackHub(hub, deployedStack) {
+			continue
+		}
+
+		newStackVersions := []kabanerov1alpha2.StackVersion{}
+		repositoryVersions := 0
+		for _, deployedVersion := range deployedStack.Spec.Versions {
+			if !isFromStackHubRepository(hub, deployedStack, deployedVersion) {
+				newStackVersions = append(newStackVersions, deployedVersion)
+				continue
+			}
+
+			inIndex := false
+			for _, indexVersion := range stackMap[deployedStack.GetName()] {
+				if deployedVersion.Version == indexVersion.Version {
+					inIndex = true
+					break
+				}
+			}
+
+			if inIndex || len(deployedVersion.DesiredState) > 0 {
+				newStackVersions = append(newStackVersions, deployedVersion)
+				repositoryVersions++
+			}
+		}
+
+		if len(newStackVersions) == 0 {
+			reqLogger.Info(fmt.Sprintf("Deleting stack %v because it is no longer in the repository index", deployedStack.GetName()))
+			err = r.client.Delete(ctx, deployedStack)
+			if err != nil {
+				return err
+			}
+			forgetIndexEntry(hub, deployedStack.GetName())
+			continue
+		}
+
+		original := deployedStack.DeepCopy()
+		deployedStack.Spec.Versions = newStackVersions
+		if repositoryVersions == 0 || !hub.GetDeletionTimestamp().IsZero() {
+			disownStack(hub, deployedStack)
+			forgetIndexEntry(hub, deployedStack.GetName())
+		}
+
+		if len(newStackVersions) != len(original.Spec.Versions) || !equality.Semantic.DeepEqual(original.OwnerReferences, deployedStack.OwnerReferences) {
+			reqLogger.Info(fmt.Sprintf("Removing versions of stack %v that are no longer in the repository index", deployedStack.GetName()))
+			err = r.client.Update(ctx, deployedStack)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
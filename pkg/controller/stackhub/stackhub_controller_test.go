@@ -0,0 +1,366 @@
+package stackhub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// -----------------------------------------------------------------------------------------------
+// Client that creates/updates/deletes stacks.
+// -----------------------------------------------------------------------------------------------
+type stackHubTestClient struct {
+	objs map[string]*kabanerov1alpha2.Stack
+}
+
+func (c stackHubTestClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	u, ok := obj.(*kabanerov1alpha2.Stack)
+	if !ok {
+		return errors.New("Get only supports stacks")
+	}
+	stack := c.objs[key.Name]
+	if stack == nil {
+		return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	stack.DeepCopyInto(u)
+	return nil
+}
+func (c stackHubTestClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	l, ok := list.(*kabanerov1alpha2.StackList)
+	if !ok {
+		return errors.New("List only supports stacks")
+	}
+	for _, stack := range c.objs {
+		l.Items = append(l.Items, *stack.DeepCopy())
+	}
+	return nil
+}
+func (c stackHubTestClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	u, ok := obj.(*kabanerov1alpha2.Stack)
+	if !ok {
+		return errors.New("Create only supports stacks")
+	}
+	if c.objs[u.Name] != nil {
+		return apierrors.NewAlreadyExists(schema.GroupResource{}, u.Name)
+	}
+	c.objs[u.Name] = u.DeepCopy()
+	return nil
+}
+func (c stackHubTestClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	u, ok := obj.(*kabanerov1alpha2.Stack)
+	if !ok {
+		return errors.New("Delete only supports stacks")
+	}
+	delete(c.objs, u.Name)
+	return nil
+}
+func (c stackHubTestClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	return errors.New("DeleteAllOf is not supported")
+}
+func (c stackHubTestClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	u, ok := obj.(*kabanerov1alpha2.Stack)
+	if !ok {
+		return errors.New("Update only supports stacks")
+	}
+	if c.objs[u.Name] == nil {
+		return apierrors.NewNotFound(schema.GroupResource{}, u.Name)
+	}
+	c.objs[u.Name] = u.DeepCopy()
+	return nil
+}
+func (c stackHubTestClient) Status() client.StatusWriter { return c }
+func (c stackHubTestClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return errors.New("Patch is not supported")
+}
+
+// Client that counts the updates of stacks.
+type updateCountingClient struct {
+	stackHubTestClient
+	updates *int
+}
+
+func (c updateCountingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	*c.updates++
+	return c.stackHubTestClient.Update(ctx, obj, opts...)
+}
+
+func newTestStackHub() *kabanerov1alpha2.StackHub {
+	return &kabanerov1alpha2.StackHub{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "kabanero.io/v1alpha2", Kind: "StackHub"},
+		ObjectMeta: metav1.ObjectMeta{Name: "hub", Namespace: "kabanero", UID: types.UID("hub-uid")},
+	}
+}
+
+// A stack that is not in the cluster is created, and is controlled by the hub.
+func TestSyncStackCreate(t *testing.T) {
+	cl := stackHubTestClient{map[string]*kabanerov1alpha2.Stack{}}
+	r := &ReconcileStackHub{client: cl}
+	hub := newTestStackHub()
+
+	versions := []kabanerov1alpha2.StackVersion{{Version: "0.2.0"}}
+	status := r.syncStack(context.Background(), hub, "java-microprofile", versions, indexRefreshRequests{}, log)
+	if status.Status != kabanerov1alpha2.StackHubSyncStatusSynced {
+		t.Fatalf("Expected status %v, but was %v: %v", kabanerov1alpha2.StackHubSyncStatusSynced, status.Status, status.StatusMessage)
+	}
+
+	created := cl.objs["java-microprofile"]
+	if created == nil {
+		t.Fatal("Expected stack java-microprofile to be created")
+	}
+	owner := metav1.GetControllerOf(created)
+	if owner == nil || owner.UID != hub.GetUID() {
+		t.Fatalf("Expected stack to be controlled by the hub, but owner was %v", owner)
+	}
+}
+
+// A stack controlled by something else is reported as a conflict, and is not changed.
+func TestSyncStackConflict(t *testing.T) {
+	other := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.StackSpec{Name: "nodejs", Versions: []kabanerov1alpha2.StackVersion{{Version: "0.1.0"}}},
+	}
+	cl := stackHubTestClient{map[string]*kabanerov1alpha2.Stack{"nodejs": other}}
+	r := &ReconcileStackHub{client: cl}
+
+	versions := []kabanerov1alpha2.StackVersion{{Version: "0.2.0"}}
+	status := r.syncStack(context.Background(), newTestStackHub(), "nodejs", versions, indexRefreshRequests{}, log)
+	if status.Status != kabanerov1alpha2.StackHubSyncStatusConflict {
+		t.Fatalf("Expected status %v, but was %v", kabanerov1alpha2.StackHubSyncStatusConflict, status.Status)
+	}
+
+	if len(cl.objs["nodejs"].Spec.Versions) != 1 {
+		t.Fatalf("Expected stack nodejs to be unchanged, but has versions: %v", cl.objs["nodejs"].Spec.Versions)
+	}
+}
+
+// A stack whose versions do not change is not updated.
+func TestSyncStackUnchanged(t *testing.T) {
+	hub := newTestStackHub()
+	ownerIsController := true
+	existing := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero", OwnerReferences: []metav1.OwnerReference{{APIVersion: "kabanero.io/v1alpha2", Kind: "StackHub", Name: hub.Name, UID: hub.UID, Controller: &ownerIsController}}},
+		Spec:       kabanerov1alpha2.StackSpec{Name: "nodejs", Versions: []kabanerov1alpha2.StackVersion{{Version: "0.2.0", RepositoryUrl: "https://index/incubator.yaml"}}},
+	}
+	updates := 0
+	cl := updateCountingClient{stackHubTestClient{map[string]*kabanerov1alpha2.Stack{"nodejs": existing}}, &updates}
+	r := &ReconcileStackHub{client: cl}
+
+	versions := []kabanerov1alpha2.StackVersion{{Version: "0.2.0", RepositoryUrl: "https://index/incubator.yaml"}}
+	status := r.syncStack(context.Background(), hub, "nodejs", versions, indexRefreshRequests{}, log)
+	if status.Status != kabanerov1alpha2.StackHubSyncStatusSynced {
+		t.Fatalf("Expected status %v, but was %v: %v", kabanerov1alpha2.StackHubSyncStatusSynced, status.Status, status.StatusMessage)
+	}
+	if updates != 0 {
+		t.Fatalf("Expected the unchanged stack not to be updated, but it was updated %v times", updates)
+	}
+
+	versions = append(versions, kabanerov1alpha2.StackVersion{Version: "0.3.0", RepositoryUrl: "https://index/incubator.yaml"})
+	r.syncStack(context.Background(), hub, "nodejs", versions, indexRefreshRequests{}, log)
+	if updates != 1 {
+		t.Fatalf("Expected the stack with a new version to be updated once, but it was updated %v times", updates)
+	}
+}
+
+// A stack controlled by the Kabanero instance that controls the hub is adopted by the hub.
+func TestSyncStackAdopt(t *testing.T) {
+	hub := newTestStackHub()
+	ownerIsController := true
+	hub.OwnerReferences = []metav1.OwnerReference{{APIVersion: "kabanero.io/v1alpha2", Kind: "Kabanero", Name: "kabanero", UID: "kabanero-uid", Controller: &ownerIsController}}
+	existing := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero", OwnerReferences: hub.OwnerReferences},
+		Spec:       kabanerov1alpha2.StackSpec{Name: "nodejs", Versions: []kabanerov1alpha2.StackVersion{{Version: "0.2.0"}}},
+	}
+	cl := stackHubTestClient{map[string]*kabanerov1alpha2.Stack{"nodejs": existing}}
+	r := &ReconcileStackHub{client: cl}
+
+	versions := []kabanerov1alpha2.StackVersion{{Version: "0.2.0"}}
+	status := r.syncStack(context.Background(), hub, "nodejs", versions, indexRefreshRequests{}, log)
+	if status.Status != kabanerov1alpha2.StackHubSyncStatusSynced {
+		t.Fatalf("Expected status %v, but was %v: %v", kabanerov1alpha2.StackHubSyncStatusSynced, status.Status, status.StatusMessage)
+	}
+
+	owner := metav1.GetControllerOf(cl.objs["nodejs"])
+	if owner == nil || owner.UID != hub.GetUID() || len(cl.objs["nodejs"].OwnerReferences) != 1 {
+		t.Fatalf("Expected stack to be controlled by the hub, but owners were %v", cl.objs["nodejs"].OwnerReferences)
+	}
+}
+
+// Versions no longer in the index are removed, unless they have a desired state.  A stack
+// left with no versions is deleted.
+func TestPruneStacks(t *testing.T) {
+	hub := newTestStackHub()
+	ownerIsController := true
+	ownerRefs := []metav1.OwnerReference{{APIVersion: "kabanero.io/v1alpha2", Kind: "StackHub", Name: hub.Name, UID: hub.UID, Controller: &ownerIsController}}
+
+	cl := stackHubTestClient{map[string]*kabanerov1alpha2.Stack{
+		"java-microprofile": &kabanerov1alpha2.Stack{
+			ObjectMeta: metav1.ObjectMeta{Name: "java-microprofile", Namespace: "kabanero", OwnerReferences: ownerRefs},
+			Spec: kabanerov1alpha2.StackSpec{Versions: []kabanerov1alpha2.StackVersion{
+				{Version: "0.1.0"},
+				{Version: "0.1.1", DesiredState: kabanerov1alpha2.StackDesiredStateActive},
+				{Version: "0.2.0"},
+			}},
+		},
+		"nodejs": &kabanerov1alpha2.Stack{
+			ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero", OwnerReferences: ownerRefs},
+			Spec:       kabanerov1alpha2.StackSpec{Versions: []kabanerov1alpha2.StackVersion{{Version: "0.1.0"}}},
+		},
+	}}
+	r := &ReconcileStackHub{client: cl}
+
+	stackMap := map[string][]kabanerov1alpha2.StackVersion{"java-microprofile": {{Version: "0.2.0"}}}
+	err := r.pruneStacks(context.Background(), hub, stackMap, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cl.objs["nodejs"] != nil {
+		t.Fatal("Expected stack nodejs to be deleted")
+	}
+
+	versions := cl.objs["java-microprofile"].Spec.Versions
+	if len(versions) != 2 || versions[0].Version != "0.1.1" || versions[1].Version != "0.2.0" {
+		t.Fatalf("Expected versions 0.1.1 and 0.2.0, but found: %v", versions)
+	}
+}
+
+// A stack controlled by another hub gets the versions of this hub's repository, and is owned by
+// both hubs.
+func TestSyncStackShared(t *testing.T) {
+	hub := newTestStackHub()
+	hub.Spec.Repository = kabanerov1alpha2.RepositoryConfig{Name: "one", Https: kabanerov1alpha2.HttpsProtocolFile{Url: "https://example.com/one/index.yaml"}}
+	ownerIsController := true
+	existing := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero", OwnerReferences: []metav1.OwnerReference{{APIVersion: "kabanero.io/v1alpha2", Kind: "StackHub", Name: "other", UID: "other-uid", Controller: &ownerIsController}}},
+		Spec:       kabanerov1alpha2.StackSpec{Name: "nodejs", Versions: []kabanerov1alpha2.StackVersion{{Version: "0.1.0", RepositoryUrl: "https://example.com/two/index.yaml"}}},
+	}
+	cl := stackHubTestClient{map[string]*kabanerov1alpha2.Stack{"nodejs": existing}}
+	r := &ReconcileStackHub{client: cl}
+
+	versions := []kabanerov1alpha2.StackVersion{{Version: "0.2.0", RepositoryUrl: "https://example.com/one/index.yaml"}}
+	status := r.syncStack(context.Background(), hub, "nodejs", versions, indexRefreshRequests{}, log)
+	if status.Status != kabanerov1alpha2.StackHubSyncStatusSynced {
+		t.Fatalf("Expected status %v, but was %v: %v", kabanerov1alpha2.StackHubSyncStatusSynced, status.Status, status.StatusMessage)
+	}
+
+	stackResource := cl.objs["nodejs"]
+	if len(stackResource.Spec.Versions) != 2 {
+		t.Fatalf("Expected versions 0.1.0 and 0.2.0, but found: %v", stackResource.Spec.Versions)
+	}
+
+	owner := metav1.GetControllerOf(stackResource)
+	if owner == nil || owner.UID != "other-uid" || len(stackResource.OwnerReferences) != 2 {
+		t.Fatalf("Expected stack to be owned by both hubs and controlled by the other hub, but owners were %v", stackResource.OwnerReferences)
+	}
+}
+
+// A hub only prunes the versions of its own repository.  It stops owning a stack that has no
+// versions of its repository left, and the other hub that owns the stack takes control.
+func TestPruneStacksShared(t *testing.T) {
+	hub := newTestStackHub()
+	hub.Spec.Repository = kabanerov1alpha2.RepositoryConfig{Name: "one", Https: kabanerov1alpha2.HttpsProtocolFile{Url: "https://example.com/one/index.yaml"}}
+	isController := true
+	isNotController := false
+	existing := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero", OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "kabanero.io/v1alpha2", Kind: "StackHub", Name: hub.Name, UID: hub.UID, Controller: &isController},
+			{APIVersion: "kabanero.io/v1alpha2", Kind: "StackHub", Name: "other", UID: "other-uid", Controller: &isNotController},
+		}},
+		Spec: kabanerov1alpha2.StackSpec{Name: "nodejs", Versions: []kabanerov1alpha2.StackVersion{
+			{Version: "0.1.0", RepositoryUrl: "https://example.com/two/index.yaml"},
+			{Version: "0.2.0", RepositoryUrl: "https://example.com/one/index.yaml"},
+		}},
+	}
+	cl := stackHubTestClient{map[string]*kabanerov1alpha2.Stack{"nodejs": existing}}
+	r := &ReconcileStackHub{client: cl}
+
+	err := r.pruneStacks(context.Background(), hub, map[string][]kabanerov1alpha2.StackVersion{}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stackResource := cl.objs["nodejs"]
+	if len(stackResource.Spec.Versions) != 1 || stackResource.Spec.Versions[0].Version != "0.1.0" {
+		t.Fatalf("Expected version 0.1.0, but found: %v", stackResource.Spec.Versions)
+	}
+
+	owner := metav1.GetControllerOf(stackResource)
+	if owner == nil || owner.UID != "other-uid" || len(stackResource.OwnerReferences) != 1 {
+		t.Fatalf("Expected stack to be controlled by the other hub only, but owners were %v", stackResource.OwnerReferences)
+	}
+}
+
+// A hub that is deleted removes the versions of its repository, except the ones with a desired
+// state, and returns the stack to the Kabanero instance that controls the hub.
+func TestPruneStacksDeletedStackHub(t *testing.T) {
+	hub := newTestStackHub()
+	isController := true
+	now := metav1.Now()
+	hub.DeletionTimestamp = &now
+	hub.OwnerReferences = []metav1.OwnerReference{{APIVersion: "kabanero.io/v1alpha2", Kind: "Kabanero", Name: "kabanero", UID: "kabanero-uid", Controller: &isController}}
+	existing := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero", OwnerReferences: []metav1.OwnerReference{{APIVersion: "kabanero.io/v1alpha2", Kind: "StackHub", Name: hub.Name, UID: hub.UID, Controller: &isController}}},
+		Spec: kabanerov1alpha2.StackSpec{Name: "nodejs", Versions: []kabanerov1alpha2.StackVersion{
+			{Version: "0.1.0"},
+			{Version: "0.2.0", DesiredState: kabanerov1alpha2.StackDesiredStateActive},
+		}},
+	}
+	cl := stackHubTestClient{map[string]*kabanerov1alpha2.Stack{"nodejs": existing}}
+	r := &ReconcileStackHub{client: cl}
+
+	err := r.pruneStacks(context.Background(), hub, map[string][]kabanerov1alpha2.StackVersion{}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stackResource := cl.objs["nodejs"]
+	if len(stackResource.Spec.Versions) != 1 || stackResource.Spec.Versions[0].Version != "0.2.0" {
+		t.Fatalf("Expected version 0.2.0, but found: %v", stackResource.Spec.Versions)
+	}
+
+	owner := metav1.GetControllerOf(stackResource)
+	if owner == nil || owner.UID != "kabanero-uid" || len(stackResource.OwnerReferences) != 1 {
+		t.Fatalf("Expected stack to be controlled by the Kabanero instance only, but owners were %v", stackResource.OwnerReferences)
+	}
+}
+
+// The hub is reconciled at the next refresh scheduled by the Kabanero instance, if that
+// comes before the sync interval.
+func TestGetRequeueInterval(t *testing.T) {
+	hub := newTestStackHub()
+	if getRequeueInterval(hub, nil, time.Now()) != defaultSyncInterval {
+		t.Fatalf("Expected the default sync interval, but was %v", getRequeueInterval(hub, nil, time.Now()))
+	}
+
+	k := &kabanerov1alpha2.Kabanero{}
+	if getRequeueInterval(hub, k, time.Now()) != defaultSyncInterval {
+		t.Fatalf("Expected the default sync interval without a refresh schedule, but was %v", getRequeueInterval(hub, k, time.Now()))
+	}
+
+	k.Spec.Stacks.RefreshSchedule = "0 2 * * *"
+	now := time.Date(2020, 5, 1, 1, 58, 0, 0, time.UTC)
+	if getRequeueInterval(hub, k, now) != 2*time.Minute {
+		t.Fatalf("Expected the hub to be reconciled at the scheduled refresh, but was %v", getRequeueInterval(hub, k, now))
+	}
+}
+
+func TestGetSyncInterval(t *testing.T) {
+	hub := newTestStackHub()
+	if getSyncInterval(hub) != defaultSyncInterval {
+		t.Fatalf("Expected the default sync interval, but was %v", getSyncInterval(hub))
+	}
+
+	hub.Spec.SyncInterval = &metav1.Duration{Duration: 30 * time.Second}
+	if getSyncInterval(hub) != 30*time.Second {
+		t.Fatalf("Expected a 30 second sync interval, but was %v", getSyncInterval(hub))
+	}
+}
@@ -41,6 +41,30 @@ func ReplaceEnvVariable(variableName string, variableValue interface{}) func(u *
 
 // AddEnvVariable produces a transformation capable of adding an environment variable value
 func AddEnvVariable(variableName string, variableValue interface{}) func(u *unstructured.Unstructured) error {
+	return addEnvVar(variableName, func() map[string]interface{} {
+		newVar := make(map[string]interface{})
+		newVar["name"] = variableName
+		newVar["value"] = variableValue
+		return newVar
+	})
+}
+
+// AddEnvVariableFromSecret produces a transformation capable of adding an environment variable
+// whose value is read from a key of a secret
+func AddEnvVariableFromSecret(variableName string, secretName string, secretKey string) func(u *unstructured.Unstructured) error {
+	return addEnvVar(variableName, func() map[string]interface{} {
+		newVar := make(map[string]interface{})
+		newVar["name"] = variableName
+		newVar["valueFrom"] = map[string]interface{}{
+			"secretKeyRef": map[string]interface{}{"name": secretName, "key": secretKey},
+		}
+		return newVar
+	})
+}
+
+// Produces a transformation that adds the environment variable built by newVar to each
+// container, replacing any variable with the same name.
+func addEnvVar(variableName string, newVar func() map[string]interface{}) func(u *unstructured.Unstructured) error {
 	return func(u *unstructured.Unstructured) error {
 		// Only apply this to deployments
 		if u.GetKind() != "Deployment" && u.GetAPIVersion() != "apps/v1" {
@@ -86,11 +110,7 @@ func AddEnvVariable(variableName string, variableValue interface{}) func(u *unst
 			}
 			
 			// Now add the one we wanted
-			newVar := make(map[string]interface{})
-			newVar["name"] = variableName
-			newVar["value"] = variableValue
-
-			newEnvVars = append(newEnvVars, newVar)
+			newEnvVars = append(newEnvVars, newVar())
 
 			err = unstructured.SetNestedSlice(container, newEnvVars, "env")
 			if err != nil {
@@ -331,3 +331,61 @@ spec:
 		})
 	}
 }
+
+func TestAddEnvVariableFromSecret(t *testing.T) {
+	inputYaml := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+        - name: kabanero-cli
+          image: image
+          env:
+            - name: CLIENT_SECRET
+              value: "old"
+            - name: OPERATOR_NAME
+              value: "kabanero-cli"`
+
+	expectedOutput := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+      - env:
+        - name: OPERATOR_NAME
+          value: kabanero-cli
+        - name: CLIENT_SECRET
+          valueFrom:
+            secretKeyRef:
+              key: clientSecret
+              name: oidc-client
+        image: image
+        name: kabanero-cli`
+
+	u, err := unmarshal([]byte(inputYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := &u[0]
+	err = AddEnvVariableFromSecret("CLIENT_SECRET", "oidc-client", "clientSecret")(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := marshal(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(expectedOutput) != strings.TrimSpace(string(b)) {
+		t.Log("Expected: ", expectedOutput)
+		t.Log("Found: ", string(b))
+
+		t.Fatal("Expected output did not match")
+	}
+}
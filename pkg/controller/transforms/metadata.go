@@ -0,0 +1,57 @@
+package transforms
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// InjectCommonMetadata produces a transformation that adds the input labels and
+// annotations to a resource.  Each entry replaces the entry with the same key in the
+// manifest, and the other labels and annotations of the resource are kept.
+func InjectCommonMetadata(labels map[string]string, annotations map[string]string) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		if len(labels) != 0 {
+			newLabels := u.GetLabels()
+			if newLabels == nil {
+				newLabels = make(map[string]string)
+			}
+			for key, value := range labels {
+				newLabels[key] = value
+			}
+			u.SetLabels(newLabels)
+		}
+
+		if len(annotations) != 0 {
+			newAnnotations := u.GetAnnotations()
+			if newAnnotations == nil {
+				newAnnotations = make(map[string]string)
+			}
+			for key, value := range annotations {
+				newAnnotations[key] = value
+			}
+			u.SetAnnotations(newAnnotations)
+		}
+
+		return nil
+	}
+}
+
+// SetPodTemplateAnnotation produces a transformation that sets an annotation on the pod
+// template of a deployment.  A changed value rolls the pods of the deployment.  Resources
+// other than deployments and OpenShift deployment configs are skipped.
+func SetPodTemplateAnnotation(key string, value string) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		// Only apply this to deployments
+		if u.GetKind() != "Deployment" && u.GetKind() != "DeploymentConfig" {
+			return nil
+		}
+
+		err := unstructured.SetNestedField(u.Object, value, "spec", "template", "metadata", "annotations", key)
+		if err != nil {
+			return fmt.Errorf("Unable to set pod template annotation %v into unstructured: %v", key, err)
+		}
+
+		return nil
+	}
+}
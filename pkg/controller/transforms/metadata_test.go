@@ -0,0 +1,150 @@
+package transforms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInjectCommonMetadata(t *testing.T) {
+	labels := map[string]string{"cost-center": "1234", "app": "kabanero"}
+	annotations := map[string]string{"backup.example.com/include": "true"}
+
+	tests := []struct {
+		name           string
+		inputYaml      string
+		expectedOutput string
+	}{
+		{
+			name: "no metadata",
+			inputYaml: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli`,
+			expectedOutput: `apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    backup.example.com/include: "true"
+  labels:
+    app: kabanero
+    cost-center: "1234"
+  name: kabanero-cli`,
+		},
+		{
+			name: "existing metadata",
+			inputYaml: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+  annotations:
+    description: CLI services
+  labels:
+    app: kabanero-cli
+    tier: backend`,
+			expectedOutput: `apiVersion: v1
+kind: Service
+metadata:
+  annotations:
+    backup.example.com/include: "true"
+    description: CLI services
+  labels:
+    app: kabanero
+    cost-center: "1234"
+    tier: backend
+  name: kabanero-cli`,
+		}}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s", tc.name), func(t *testing.T) {
+			u, err := unmarshal([]byte(tc.inputYaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := &u[0]
+			err = InjectCommonMetadata(labels, annotations)(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := marshal(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(tc.expectedOutput) != strings.TrimSpace(string(b)) {
+				t.Log("Expected: ", tc.expectedOutput)
+				t.Log("Found: ", string(b))
+
+				t.Fatal("Expected output did not match")
+			}
+		})
+	}
+}
+
+func TestSetPodTemplateAnnotation(t *testing.T) {
+	tests := []struct {
+		name           string
+		inputYaml      string
+		expectedOutput string
+	}{
+		{
+			name: "service",
+			inputYaml: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli`,
+			expectedOutput: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli`,
+		},
+		{
+			name: "deployment",
+			inputYaml: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    metadata:
+      annotations:
+        description: CLI services
+      labels:
+        app: kabanero-cli`,
+			expectedOutput: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    metadata:
+      annotations:
+        description: CLI services
+        kabanero.io/encryption-key-rotated-at: "2020-06-01T12:00:00Z"
+      labels:
+        app: kabanero-cli`,
+		}}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s", tc.name), func(t *testing.T) {
+			u, err := unmarshal([]byte(tc.inputYaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := &u[0]
+			err = SetPodTemplateAnnotation("kabanero.io/encryption-key-rotated-at", "2020-06-01T12:00:00Z")(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := marshal(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(tc.expectedOutput) != strings.TrimSpace(string(b)) {
+				t.Log("Expected: ", tc.expectedOutput)
+				t.Log("Found: ", string(b))
+
+				t.Fatal("Expected output did not match")
+			}
+		})
+	}
+}
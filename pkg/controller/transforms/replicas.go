@@ -0,0 +1,25 @@
+package transforms
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SetReplicas produces a transformation that sets the replica count of a deployment.
+// Resources other than deployments and OpenShift deployment configs are skipped.
+func SetReplicas(replicas int32) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		// Only apply this to deployments
+		if u.GetKind() != "Deployment" && u.GetKind() != "DeploymentConfig" {
+			return nil
+		}
+
+		err := unstructured.SetNestedField(u.Object, int64(replicas), "spec", "replicas")
+		if err != nil {
+			return fmt.Errorf("Unable to set replicas into unstructured: %v", err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package transforms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSetReplicas(t *testing.T) {
+	tests := []struct {
+		name           string
+		inputYaml      string
+		expectedOutput string
+	}{
+		{
+			name: "service",
+			inputYaml: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+spec:
+  ports:
+  - port: 443`,
+			expectedOutput: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+spec:
+  ports:
+  - port: 443`,
+		},
+		{
+			name: "deployment",
+			inputYaml: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: kabanero-cli`,
+			expectedOutput: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: kabanero-cli`,
+		}}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s", tc.name), func(t *testing.T) {
+			u, err := unmarshal([]byte(tc.inputYaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := &u[0]
+			err = SetReplicas(3)(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := marshal(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(tc.expectedOutput) != strings.TrimSpace(string(b)) {
+				t.Log("Expected: ", tc.expectedOutput)
+				t.Log("Found: ", string(b))
+
+				t.Fatal("Expected output did not match")
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package transforms
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SetContainerResources produces a transformation that sets the input compute resource
+// requests and limits on each container of a deployment or OpenShift deployment config.
+// Each entry replaces the entry with the same name in the manifest, and entries that are
+// not in the input are left alone.  Init containers and other resources are skipped.
+func SetContainerResources(resources corev1.ResourceRequirements) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		if u.GetKind() != "Deployment" && u.GetKind() != "DeploymentConfig" {
+			return nil
+		}
+
+		if len(resources.Requests) == 0 && len(resources.Limits) == 0 {
+			return nil
+		}
+
+		containers, ok, err := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "containers")
+		if err != nil {
+			return fmt.Errorf("Unable to retrieve containers from unstructured: %v", err)
+		}
+		if !ok {
+			return fmt.Errorf("No containers entry in deployment spec: %v", u)
+		}
+
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("Unable to retrieve container from unstructured: %v", c)
+			}
+
+			err = mergeResourceList(container, resources.Requests, "resources", "requests")
+			if err != nil {
+				return err
+			}
+
+			err = mergeResourceList(container, resources.Limits, "resources", "limits")
+			if err != nil {
+				return err
+			}
+		}
+
+		err = unstructured.SetNestedSlice(u.Object, containers, "spec", "template", "spec", "containers")
+		if err != nil {
+			return fmt.Errorf("Unable to set containers into unstructured: %v", err)
+		}
+
+		return nil
+	}
+}
+
+// Sets each entry of the input resource list at the input path of the container.
+func mergeResourceList(container map[string]interface{}, list corev1.ResourceList, fields ...string) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	quantities, _, err := unstructured.NestedMap(container, fields...)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve %v from container: %v", fields, err)
+	}
+	if quantities == nil {
+		quantities = make(map[string]interface{})
+	}
+	for name, quantity := range list {
+		quantities[string(name)] = quantity.String()
+	}
+
+	err = unstructured.SetNestedMap(container, quantities, fields...)
+	if err != nil {
+		return fmt.Errorf("Unable to set %v into container: %v", fields, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package transforms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestSetContainerResources(t *testing.T) {
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("100m"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+	}
+
+	tests := []struct {
+		name           string
+		inputYaml      string
+		expectedOutput string
+	}{
+		{
+			name: "service",
+			inputYaml: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+spec:
+  ports:
+  - port: 443`,
+			expectedOutput: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+spec:
+  ports:
+  - port: 443`,
+		},
+		{
+			name: "deployment",
+			inputYaml: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+      - name: kabanero-cli
+        image: image
+      initContainers:
+      - name: init-kabanero-cli
+        image: image`,
+			expectedOutput: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+      - image: image
+        name: kabanero-cli
+        resources:
+          limits:
+            memory: 512Mi
+          requests:
+            cpu: 100m
+      initContainers:
+      - image: image
+        name: init-kabanero-cli`,
+		},
+		{
+			name: "deployment-existing",
+			inputYaml: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+      - name: kabanero-cli
+        image: image
+        resources:
+          limits:
+            cpu: "1"
+            memory: 256Mi
+          requests:
+            cpu: 50m`,
+			expectedOutput: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+      - image: image
+        name: kabanero-cli
+        resources:
+          limits:
+            cpu: "1"
+            memory: 512Mi
+          requests:
+            cpu: 100m`,
+		}}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s", tc.name), func(t *testing.T) {
+			u, err := unmarshal([]byte(tc.inputYaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := &u[0]
+			err = SetContainerResources(resources)(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := marshal(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(tc.expectedOutput) != strings.TrimSpace(string(b)) {
+				t.Log("Expected: ", tc.expectedOutput)
+				t.Log("Found: ", string(b))
+
+				t.Fatal("Expected output did not match")
+			}
+		})
+	}
+}
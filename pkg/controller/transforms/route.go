@@ -0,0 +1,74 @@
+package transforms
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SetRouteCertificate produces a transformation that sets the certificate and key that a
+// route presents.  The CA certificate is left out when it is empty.
+func SetRouteCertificate(certificate string, key string, caCertificate string) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		// Only apply this to routes
+		if u.GetKind() != "Route" {
+			return nil
+		}
+
+		err := unstructured.SetNestedField(u.Object, certificate, "spec", "tls", "certificate")
+		if err != nil {
+			return fmt.Errorf("Unable to set the certificate of route %v: %v", u.GetName(), err)
+		}
+
+		err = unstructured.SetNestedField(u.Object, key, "spec", "tls", "key")
+		if err != nil {
+			return fmt.Errorf("Unable to set the key of route %v: %v", u.GetName(), err)
+		}
+
+		if len(caCertificate) == 0 {
+			unstructured.RemoveNestedField(u.Object, "spec", "tls", "caCertificate")
+			return nil
+		}
+
+		err = unstructured.SetNestedField(u.Object, caCertificate, "spec", "tls", "caCertificate")
+		if err != nil {
+			return fmt.Errorf("Unable to set the CA certificate of route %v: %v", u.GetName(), err)
+		}
+
+		return nil
+	}
+}
+
+// SetRoute produces a transformation that sets the hostname, TLS termination and insecure
+// connection policy of a route.  Empty values keep the value of the manifest.
+func SetRoute(hostname string, termination string, insecureEdgeTerminationPolicy string) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		// Only apply this to routes
+		if u.GetKind() != "Route" {
+			return nil
+		}
+
+		if len(hostname) != 0 {
+			err := unstructured.SetNestedField(u.Object, hostname, "spec", "host")
+			if err != nil {
+				return fmt.Errorf("Unable to set the hostname of route %v: %v", u.GetName(), err)
+			}
+		}
+
+		if len(termination) != 0 {
+			err := unstructured.SetNestedField(u.Object, termination, "spec", "tls", "termination")
+			if err != nil {
+				return fmt.Errorf("Unable to set the TLS termination of route %v: %v", u.GetName(), err)
+			}
+		}
+
+		if len(insecureEdgeTerminationPolicy) != 0 {
+			err := unstructured.SetNestedField(u.Object, insecureEdgeTerminationPolicy, "spec", "tls", "insecureEdgeTerminationPolicy")
+			if err != nil {
+				return fmt.Errorf("Unable to set the insecure connection policy of route %v: %v", u.GetName(), err)
+			}
+		}
+
+		return nil
+	}
+}
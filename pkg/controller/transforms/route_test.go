@@ -0,0 +1,204 @@
+package transforms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSetRouteCertificate(t *testing.T) {
+	tests := []struct {
+		name           string
+		inputYaml      string
+		caCertificate  string
+		expectedOutput string
+	}{
+		{
+			name: "service",
+			inputYaml: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+spec:
+  ports:
+  - port: 443`,
+			caCertificate: "ca",
+			expectedOutput: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+spec:
+  ports:
+  - port: 443`,
+		},
+		{
+			name: "route",
+			inputYaml: `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-cli
+spec:
+  to:
+    kind: Service
+    name: kabanero-cli
+  tls:
+    termination: reencrypt
+    insecureEdgeTerminationPolicy: Redirect`,
+			caCertificate: "ca",
+			expectedOutput: `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-cli
+spec:
+  tls:
+    caCertificate: ca
+    certificate: cert
+    insecureEdgeTerminationPolicy: Redirect
+    key: key
+    termination: reencrypt
+  to:
+    kind: Service
+    name: kabanero-cli`,
+		},
+		{
+			name: "route-no-ca",
+			inputYaml: `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-cli
+spec:
+  tls:
+    termination: reencrypt
+    caCertificate: old`,
+			expectedOutput: `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-cli
+spec:
+  tls:
+    certificate: cert
+    key: key
+    termination: reencrypt`,
+		}}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s", tc.name), func(t *testing.T) {
+			u, err := unmarshal([]byte(tc.inputYaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := &u[0]
+			err = SetRouteCertificate("cert", "key", tc.caCertificate)(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := marshal(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(tc.expectedOutput) != strings.TrimSpace(string(b)) {
+				t.Log("Expected: ", tc.expectedOutput)
+				t.Log("Found: ", string(b))
+
+				t.Fatal("Expected output did not match")
+			}
+		})
+	}
+}
+
+func TestSetRoute(t *testing.T) {
+	tests := []struct {
+		name                          string
+		inputYaml                     string
+		hostname                      string
+		termination                   string
+		insecureEdgeTerminationPolicy string
+		expectedOutput                string
+	}{
+		{
+			name: "service",
+			inputYaml: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli`,
+			hostname: "cli.apps.example.com",
+			expectedOutput: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli`,
+		},
+		{
+			name: "route-all",
+			inputYaml: `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-cli
+spec:
+  tls:
+    insecureEdgeTerminationPolicy: Redirect
+    termination: reencrypt
+  to:
+    kind: Service
+    name: kabanero-cli`,
+			hostname:                      "cli.apps.example.com",
+			termination:                   "passthrough",
+			insecureEdgeTerminationPolicy: "None",
+			expectedOutput: `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-cli
+spec:
+  host: cli.apps.example.com
+  tls:
+    insecureEdgeTerminationPolicy: None
+    termination: passthrough
+  to:
+    kind: Service
+    name: kabanero-cli`,
+		},
+		{
+			name: "route-hostname",
+			inputYaml: `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-cli
+spec:
+  tls:
+    insecureEdgeTerminationPolicy: Redirect
+    termination: reencrypt`,
+			hostname: "cli.apps.example.com",
+			expectedOutput: `apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-cli
+spec:
+  host: cli.apps.example.com
+  tls:
+    insecureEdgeTerminationPolicy: Redirect
+    termination: reencrypt`,
+		}}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s", tc.name), func(t *testing.T) {
+			u, err := unmarshal([]byte(tc.inputYaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := &u[0]
+			err = SetRoute(tc.hostname, tc.termination, tc.insecureEdgeTerminationPolicy)(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := marshal(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(tc.expectedOutput) != strings.TrimSpace(string(b)) {
+				t.Log("Expected: ", tc.expectedOutput)
+				t.Log("Found: ", string(b))
+
+				t.Fatal("Expected output did not match")
+			}
+		})
+	}
+}
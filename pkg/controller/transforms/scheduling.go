@@ -0,0 +1,86 @@
+package transforms
+
+import (
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// InjectScheduling produces a transformation that adds the input node selector and
+// tolerations to the pod template of a deployment, and replaces its affinity.  Empty
+// values leave the manifest unchanged.  Resources other than deployments and OpenShift
+// deployment configs, which keep the pod template at the same path, are skipped.
+func InjectScheduling(nodeSelector map[string]string, tolerations []corev1.Toleration, affinity *corev1.Affinity) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		// Only apply this to deployments
+		if u.GetKind() != "Deployment" && u.GetKind() != "DeploymentConfig" {
+			return nil
+		}
+
+		if len(nodeSelector) != 0 {
+			newNodeSelector, _, err := unstructured.NestedStringMap(u.Object, "spec", "template", "spec", "nodeSelector")
+			if err != nil {
+				return fmt.Errorf("Unable to retrieve node selector from unstructured: %v", err)
+			}
+			if newNodeSelector == nil {
+				newNodeSelector = make(map[string]string)
+			}
+			for key, value := range nodeSelector {
+				newNodeSelector[key] = value
+			}
+
+			err = unstructured.SetNestedStringMap(u.Object, newNodeSelector, "spec", "template", "spec", "nodeSelector")
+			if err != nil {
+				return fmt.Errorf("Unable to set node selector into unstructured: %v", err)
+			}
+		}
+
+		if len(tolerations) != 0 {
+			newTolerations, _, err := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "tolerations")
+			if err != nil {
+				return fmt.Errorf("Unable to retrieve tolerations from unstructured: %v", err)
+			}
+
+			for i := range tolerations {
+				toleration, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&tolerations[i])
+				if err != nil {
+					return fmt.Errorf("Unable to convert toleration to unstructured: %v", err)
+				}
+
+				// Applying the manifest again must not add the same toleration twice.
+				found := false
+				for _, existing := range newTolerations {
+					if reflect.DeepEqual(existing, toleration) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					newTolerations = append(newTolerations, toleration)
+				}
+			}
+
+			err = unstructured.SetNestedSlice(u.Object, newTolerations, "spec", "template", "spec", "tolerations")
+			if err != nil {
+				return fmt.Errorf("Unable to set tolerations into unstructured: %v", err)
+			}
+		}
+
+		if affinity != nil {
+			newAffinity, err := runtime.DefaultUnstructuredConverter.ToUnstructured(affinity)
+			if err != nil {
+				return fmt.Errorf("Unable to convert affinity to unstructured: %v", err)
+			}
+
+			err = unstructured.SetNestedMap(u.Object, newAffinity, "spec", "template", "spec", "affinity")
+			if err != nil {
+				return fmt.Errorf("Unable to set affinity into unstructured: %v", err)
+			}
+		}
+
+		return nil
+	}
+}
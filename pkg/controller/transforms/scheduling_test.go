@@ -0,0 +1,194 @@
+package transforms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestInjectScheduling(t *testing.T) {
+	nodeSelector := map[string]string{"node-role.kubernetes.io/infra": ""}
+	tolerations := []corev1.Toleration{{Key: "infra", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}}
+	affinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key:      "kubernetes.io/arch",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{"amd64"},
+					}},
+				}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		inputYaml      string
+		expectedOutput string
+	}{
+		{
+			name: "service",
+			inputYaml: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+spec:
+  ports:
+  - port: 443`,
+			expectedOutput: `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-cli
+spec:
+  ports:
+  - port: 443`,
+		},
+		{
+			name: "deployment",
+			inputYaml: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+      - name: kabanero-cli
+        image: image`,
+			expectedOutput: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: kubernetes.io/arch
+                operator: In
+                values:
+                - amd64
+      containers:
+      - image: image
+        name: kabanero-cli
+      nodeSelector:
+        node-role.kubernetes.io/infra: ""
+      tolerations:
+      - effect: NoSchedule
+        key: infra
+        operator: Exists`,
+		},
+		{
+			name: "deployment-existing",
+			inputYaml: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+      - name: kabanero-cli
+        image: image
+      nodeSelector:
+        kubernetes.io/os: linux
+      tolerations:
+      - key: infra
+        operator: Exists
+        effect: NoSchedule
+      - key: other
+        operator: Exists`,
+			expectedOutput: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: kubernetes.io/arch
+                operator: In
+                values:
+                - amd64
+      containers:
+      - image: image
+        name: kabanero-cli
+      nodeSelector:
+        kubernetes.io/os: linux
+        node-role.kubernetes.io/infra: ""
+      tolerations:
+      - effect: NoSchedule
+        key: infra
+        operator: Exists
+      - key: other
+        operator: Exists`,
+		}}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s", tc.name), func(t *testing.T) {
+			u, err := unmarshal([]byte(tc.inputYaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resource := &u[0]
+			err = InjectScheduling(nodeSelector, tolerations, affinity)(resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := marshal(resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(tc.expectedOutput) != strings.TrimSpace(string(b)) {
+				t.Log("Expected: ", tc.expectedOutput)
+				t.Log("Found: ", string(b))
+
+				t.Fatal("Expected output did not match")
+			}
+		})
+	}
+}
+
+func TestInjectSchedulingEmpty(t *testing.T) {
+	inputYaml := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-cli
+spec:
+  template:
+    spec:
+      containers:
+      - image: image
+        name: kabanero-cli`
+
+	u, err := unmarshal([]byte(inputYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := &u[0]
+	err = InjectScheduling(nil, nil, nil)(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := marshal(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(inputYaml) != strings.TrimSpace(string(b)) {
+		t.Log("Expected: ", inputYaml)
+		t.Log("Found: ", string(b))
+
+		t.Fatal("Expected output did not match")
+	}
+}
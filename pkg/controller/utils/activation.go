@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// The status message set on assets that were not applied because the operator
+// was stopping.  The asset list is persisted in the status, so the next
+// reconcile (possibly in a new leader) picks up where this one left off.
+const AssetStatusMessageInterrupted = "Asset activation was interrupted by an operator shutdown. It will be resumed during the next reconcile."
+
+// Tracks the pipeline activations that are in progress, so that they can be
+// drained when the manager is stopped.
+var activationsInFlight sync.WaitGroup
+
+// Set when the operator is stopping.  No new activations are started after
+// this is set, and activations in progress stop applying new assets.
+var activationsStopping bool
+
+// Mutex for the stopping flag.  Held while adding to the wait group so that
+// no activation is added once draining has started.
+var activationLock sync.Mutex
+
+// Marks the start of an activation.  Returns false if the operator is stopping,
+// in which case the activation must not be started.  EndActivation must be
+// called when an activation that was started completes.
+func BeginActivation() bool {
+	activationLock.Lock()
+	defer activationLock.Unlock()
+	if activationsStopping {
+		return false
+	}
+
+	activationsInFlight.Add(1)
+	return true
+}
+
+// Marks the end of an activation that was started with BeginActivation.
+func EndActivation() {
+	activationsInFlight.Done()
+}
+
+// Returns true if the operator is stopping and activations should not
+// download or apply anything else.
+func IsActivationStopping() bool {
+	activationLock.Lock()
+	defer activationLock.Unlock()
+	return activationsStopping
+}
+
+// Stops new activations from being started, and waits for the activations in
+// progress to finish, up to the input timeout.  Returns true if all activations
+// finished before the timeout expired.
+func DrainActivations(timeout time.Duration, logger logr.Logger) bool {
+	activationLock.Lock()
+	activationsStopping = true
+	activationLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		activationsInFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("All in-flight pipeline activations were drained.")
+		return true
+	case <-time.After(timeout):
+		logger.Info(fmt.Sprintf("Timed out after %v waiting for in-flight pipeline activations to drain.", timeout))
+		return false
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Test that draining waits for in-flight activations, and that no new activations
+// can start once draining has begun.
+func TestDrainActivations(t *testing.T) {
+	logger := logf.Log.WithName("activation_test")
+	defer func() { activationsStopping = false }()
+
+	if !BeginActivation() {
+		t.Fatal("The activation should have been allowed to start")
+	}
+
+	// The activation is still running, so the drain should time out.
+	if DrainActivations(10*time.Millisecond, logger) {
+		t.Fatal("The drain should have timed out while an activation was in progress")
+	}
+
+	if !IsActivationStopping() {
+		t.Fatal("Activations should be stopping after a drain was requested")
+	}
+
+	if BeginActivation() {
+		t.Fatal("A new activation should not be allowed to start after a drain was requested")
+	}
+
+	EndActivation()
+
+	if !DrainActivations(time.Second, logger) {
+		t.Fatal("The drain should have completed after the activation ended")
+	}
+}
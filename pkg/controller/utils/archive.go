@@ -4,18 +4,20 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	"go.opentelemetry.io/otel/attribute"
 	yml "gopkg.in/yaml.v2"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/yaml"
@@ -30,9 +32,19 @@ type StackManifest struct {
 type StackContents struct {
 	File   string `yaml:"file,omitempty"`
 	Sha256 string `yaml:"sha256,omitempty"`
+	Sha512 string `yaml:"sha512,omitempty"`
 }
 
-// This is the rendered asset, including its sha256 from the manifest.
+// Returns the digest of the file, preferring sha512 when both are listed.  The sha256
+// entry may also hold a digest prefixed with its algorithm.
+func (content StackContents) digest() string {
+	if len(content.Sha512) != 0 {
+		return digestAlgorithmSha512 + ":" + strings.TrimPrefix(content.Sha512, digestAlgorithmSha512+":")
+	}
+	return content.Sha256
+}
+
+// This is the rendered asset, including its sha256 or sha512 digest from the manifest.
 type StackAsset struct {
 	Name    string
 	Group   string
@@ -42,19 +54,103 @@ type StackAsset struct {
 	Yaml    unstructured.Unstructured
 }
 
-func DownloadToByte(c client.Client, namespace string, url string, gitRelease kabanerov1alpha2.GitReleaseInfo, skipCertVerification bool, reqLogger logr.Logger) ([]byte, error) {
+// The default limits on the contents of a pipeline archive, used when the Kabanero
+// instance does not specify them.
+const (
+	DefaultMaxArchiveFileSize      int64 = 5 * 1024 * 1024
+	DefaultMaxArchiveExtractedSize int64 = 100 * 1024 * 1024
+)
+
+// The limits on the contents of a pipeline archive.
+var archiveLimits = struct {
+	sync.Mutex
+	maxFileSize      int64
+	maxExtractedSize int64
+}{maxFileSize: DefaultMaxArchiveFileSize, maxExtractedSize: DefaultMaxArchiveExtractedSize}
+
+// Sets the limits on the size, duration and retries of downloads, and on the contents of
+// pipeline archives, from the input download limits.  A value of zero or less selects the default.
+func SetArchiveLimits(limits kabanerov1alpha2.DownloadLimitsSpec) {
+	cache.SetMaxDownloadSize(limits.MaxDownloadSize)
+
+	var connectTimeout, readTimeout time.Duration
+	if limits.ConnectTimeout != nil {
+		connectTimeout = limits.ConnectTimeout.Duration
+	}
+	if limits.ReadTimeout != nil {
+		readTimeout = limits.ReadTimeout.Duration
+	}
+	cache.SetTimeouts(connectTimeout, readTimeout)
+	cache.SetDownloadRetry(limits.Retry.Attempts, limits.Retry.JitterPercent, limits.Retry.RetryOnStatus)
+
+	maxFileSize := limits.MaxArchiveFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = DefaultMaxArchiveFileSize
+	}
+	maxExtractedSize := limits.MaxArchiveExtractedSize
+	if maxExtractedSize <= 0 {
+		maxExtractedSize = DefaultMaxArchiveExtractedSize
+	}
+
+	archiveLimits.Lock()
+	defer archiveLimits.Unlock()
+	archiveLimits.maxFileSize = maxFileSize
+	archiveLimits.maxExtractedSize = maxExtractedSize
+}
+
+// Returns the maximum size of a file in a pipeline archive, and of all of its files.
+func getArchiveLimits() (int64, int64) {
+	archiveLimits.Lock()
+	defer archiveLimits.Unlock()
+	return archiveLimits.maxFileSize, archiveLimits.maxExtractedSize
+}
+
+// An error reading the manifests of a pipeline archive, with the reason reported in the
+// stack status.
+type manifestError struct {
+	reason string
+	err    error
+}
+
+func (e manifestError) Error() string {
+	return e.err.Error()
+}
+
+// Returns the StackReason for an error returned by GetManifests.
+func ManifestErrorReason(err error) string {
+	if merr, ok := err.(manifestError); ok {
+		return merr.reason
+	}
+	return kabanerov1alpha2.StackReasonManifestRejected
+}
+
+func DownloadToByte(ctx context.Context, c client.Reader, namespace string, url string, gitRelease kabanerov1alpha2.GitReleaseInfo, skipCertVerification bool, proxy *cache.ArtifactProxy, reqLogger logr.Logger) ([]byte, error) {
+	ctx, span := StartSpan(ctx, "Download", attribute.String("url", url))
+	b, err := downloadToByte(ctx, c, namespace, url, gitRelease, skipCertVerification, proxy, reqLogger)
+	EndSpan(span, err)
+	return b, err
+}
+
+func downloadToByte(ctx context.Context, c client.Reader, namespace string, url string, gitRelease kabanerov1alpha2.GitReleaseInfo, skipCertVerification bool, proxy *cache.ArtifactProxy, reqLogger logr.Logger) ([]byte, error) {
 	var archiveBytes []byte
 	switch {
 	// GIT:
 	case gitRelease.IsUsable():
-		bytes, err := cache.GetStackDataUsingGit(c, gitRelease, skipCertVerification, namespace, reqLogger)
+		bytes, err := cache.GetStackDataUsingGit(ctx, c, gitRelease, skipCertVerification, namespace, proxy, reqLogger)
+		if err != nil {
+			return nil, err
+		}
+		archiveBytes = bytes
+	// OBJECT STORAGE:
+	case cache.IsObjectStorageUrl(url):
+		bytes, err := cache.GetFromObjectStorage(ctx, c, namespace, url, skipCertVerification, proxy, reqLogger)
 		if err != nil {
 			return nil, err
 		}
 		archiveBytes = bytes
 	// HTTPS:
 	case len(url) != 0:
-		bytes, err := cache.GetFromCache(c, url, skipCertVerification)
+		bytes, err := cache.GetFromCache(ctx, c, url, skipCertVerification, proxy)
 		if err != nil {
 			return nil, err
 		}
@@ -131,14 +227,22 @@ func readBytesFromReader(size int64, r io.Reader) ([]byte, error) {
 	return b, nil
 }
 
+// A yaml file read from a pipeline archive.
+type archiveFile struct {
+	name string
+	data []byte
+}
+
 //Read the manifests from a tar.gz archive
 //It would be better to use the manifest.yaml as the index, and check the signatures
 //For now, ignore manifest.yaml and return all other yaml files from the archive
-func decodeManifests(archive []byte, renderingContext map[string]interface{}, reqLogger logr.Logger) ([]StackAsset, error) {
+func decodeManifests(archive []byte, renderer string, renderingContext map[string]interface{}, reqLogger logr.Logger) ([]StackAsset, error) {
 	manifests := []StackAsset{}
 	var stackmanifest StackManifest
+	maxFileSize, maxExtractedSize := getArchiveLimits()
 
-	// Read the manifest.yaml from the stack archive
+	// Read the archive once.  The yaml files are kept until the manifest.yaml, which may
+	// be anywhere in the archive, has been read.
 	r := bytes.NewReader(archive)
 	gzReader, err := gzip.NewReader(r)
 	if err != nil {
@@ -148,6 +252,8 @@ func decodeManifests(archive []byte, renderingContext map[string]interface{}, re
 
 	foundManifest := false
 	var headers []string
+	var files []archiveFile
+	var extractedSize int64
 	for {
 		header, err := tarReader.Next()
 
@@ -161,6 +267,16 @@ func decodeManifests(archive []byte, renderingContext map[string]interface{}, re
 
 		headers = append(headers, header.Name)
 
+		// Check the sizes before anything is extracted, so that a small archive that
+		// expands to a huge size is rejected without exhausting the operator's memory.
+		if header.Size > maxFileSize {
+			return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveTooLarge, err: fmt.Errorf("Archive file %v is %v bytes, which is larger than the limit of %v bytes", header.Name, header.Size, maxFileSize)}
+		}
+		extractedSize += header.Size
+		if extractedSize > maxExtractedSize {
+			return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveTooLarge, err: fmt.Errorf("The archive files are larger than the limit of %v bytes once extracted", maxExtractedSize)}
+		}
+
 		switch {
 		case strings.TrimPrefix(header.Name, "./") == "manifest.yaml":
 			//Buffer the document for further processing
@@ -173,6 +289,13 @@ func decodeManifests(archive []byte, renderingContext map[string]interface{}, re
 				return nil, err
 			}
 			foundManifest = true
+		case strings.HasSuffix(header.Name, ".yaml"):
+			//Buffer the document for further processing
+			b, err := readBytesFromReader(header.Size, tarReader)
+			if err != nil {
+				return nil, fmt.Errorf("Error reading archive %v: %v", header.Name, err.Error())
+			}
+			files = append(files, archiveFile{name: header.Name, data: b})
 		}
 	}
 
@@ -182,82 +305,59 @@ func decodeManifests(archive []byte, renderingContext map[string]interface{}, re
 		return nil, fmt.Errorf("Error reading archive, unable to read manifest.yaml")
 	}
 
-	// Re-Read the archive and validate against archive manifest.yaml
-	r = bytes.NewReader(archive)
-	gzReader, err = gzip.NewReader(r)
-	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Could not read manifest gzip"))
-	}
-	tarReader = tar.NewReader(gzReader)
-
-	for {
-		header, err := tarReader.Next()
-
-		if err == io.EOF {
-			break
-		}
-
-		if err != nil {
-			return nil, errors.New(fmt.Sprintf("Could not read manifest tar"))
-		}
-
-		// Ignore manifest.yaml on this pass, only read yaml files
-		switch {
-		case strings.TrimPrefix(header.Name, "./") == "manifest.yaml":
-			break
-		case strings.HasSuffix(header.Name, ".yaml"):
-			//Buffer the document for further processing
-			b, err := readBytesFromReader(header.Size, tarReader)
-			if err != nil {
-				return nil, fmt.Errorf("Error reading archive %v: %v", header.Name, err.Error())
-			}
-
-			// Checksum. Lookup the read file in the index and compare sha256
-			match := false
-			b_sum := sha256.Sum256(b)
-			assetSumString := ""
-			for _, content := range stackmanifest.Contents {
-				if content.File == strings.TrimPrefix(header.Name, "./") {
-					// Older releases may not have a sha256 in the manifest.yaml
-					assetSumString = content.Sha256
-					if content.Sha256 != "" {
-						var c_sum [32]byte
-						decoded, err := hex.DecodeString(content.Sha256)
-						if err != nil {
-							return nil, err
-						}
-						copy(c_sum[:], decoded)
-						if b_sum != c_sum {
-							return nil, fmt.Errorf("Archive file: %v  manifest.yaml checksum: %x  did not match file checksum: %x", header.Name, c_sum, b_sum)
-						}
-						match = true
-					} else {
-						// Would be nice if we could make this a warning message, but it seems like the only
-						// options are error and info.  It's possible that some implementation has other methods
-						// but someone needs to investigate.
-						reqLogger.Info(fmt.Sprintf("Archive file %v was listed in the manifest but had no checksum.  Checksum validation for this file is skipped.", header.Name))
-						match = true
+	// Validate the yaml files against the archive manifest.yaml
+	for _, file := range files {
+		// Checksum. Lookup the read file in the index and compare its sha256 or sha512
+		match := false
+		assetSumString := ""
+		for _, content := range stackmanifest.Contents {
+			if content.File == strings.TrimPrefix(file.name, "./") {
+				// Older releases may not have a checksum in the manifest.yaml
+				assetSumString = content.digest()
+				if assetSumString != "" {
+					matched, b_sum, err := MatchDigest(assetSumString, file.data)
+					if err != nil {
+						return nil, err
+					}
+					if !matched {
+						return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveDigestMismatch, err: fmt.Errorf("Archive file: %v  manifest.yaml checksum: %v  did not match file checksum: %v", file.name, assetSumString, b_sum)}
 					}
+					match = true
+				} else {
+					// Would be nice if we could make this a warning message, but it seems like the only
+					// options are error and info.  It's possible that some implementation has other methods
+					// but someone needs to investigate.
+					reqLogger.Info(fmt.Sprintf("Archive file %v was listed in the manifest but had no checksum.  Checksum validation for this file is skipped.", file.name))
+					match = true
 				}
 			}
-			if match != true {
-				return nil, fmt.Errorf("File %v was found in the archive, but not in the manifest.yaml", header.Name)
-			}
+		}
+		if match != true {
+			return nil, fmt.Errorf("File %v was found in the archive, but not in the manifest.yaml", file.name)
+		}
 
-			//Apply the Kabanero yaml directive processor
-			pmanifests, err := processManifest(b, renderingContext, header.Name, assetSumString)
-			if (err != nil) && (err != io.EOF) {
-				return nil, fmt.Errorf("Error decoding %v: %v", header.Name, err.Error())
-			}
-			manifests = append(manifests, pmanifests...)
+		//Apply the Kabanero yaml directive processor
+		pmanifests, err := processManifest(file.data, renderer, renderingContext, file.name, assetSumString)
+		if (err != nil) && (err != io.EOF) {
+			return nil, fmt.Errorf("Error decoding %v: %v", file.name, err.Error())
 		}
+		manifests = append(manifests, pmanifests...)
 	}
 	return manifests, nil
 }
 
-//Apply the Kabanero yaml directive processor
-func processManifest(b []byte, renderingContext map[string]interface{}, filename string, assetSumString string) ([]StackAsset, error) {
+//Apply the Kabanero yaml directive processor, after the Go template renderer if the pipeline uses it
+func processManifest(b []byte, renderer string, renderingContext map[string]interface{}, filename string, assetSumString string) ([]StackAsset, error) {
 	manifests := []StackAsset{}
+	if renderer == kabanerov1alpha2.PipelineRendererGoTemplate {
+		t := &GoTemplateRenderer{}
+		tb, err := t.Render(filename, b, renderingContext)
+		if err != nil {
+			return manifests, err
+		}
+		b = tb
+	}
+
 	s := &DirectiveProcessor{}
 	rb, err := s.Render(b, renderingContext)
 	if err != nil {
@@ -267,6 +367,11 @@ func processManifest(b []byte, renderingContext map[string]interface{}, filename
 	decoder := yaml.NewYAMLToJSONDecoder(bytes.NewReader(rb))
 	out := unstructured.Unstructured{}
 	for err = decoder.Decode(&out); err == nil; {
+		// TriggerTemplates bind their workspaces to the storage of the cluster.
+		if err := injectWorkspaceBindings(&out, renderingContext); err != nil {
+			return manifests, fmt.Errorf("Error binding the workspaces of %v: %v", filename, err.Error())
+		}
+
 		gvk := out.GroupVersionKind()
 		manifests = append(manifests, StackAsset{Name: out.GetName(), Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind, Yaml: out, Sha256: assetSumString})
 		out = unstructured.Unstructured{}
@@ -299,41 +404,89 @@ func getPipelineFileType(pipelineStatus kabanerov1alpha2.PipelineStatus) (fileTy
 	}
 }
 
-func GetManifests(c client.Client, namespace string, pipelineStatus kabanerov1alpha2.PipelineStatus, renderingContext map[string]interface{}, skipCertVerification bool, reqLogger logr.Logger) ([]StackAsset, error) {
-	b, err := DownloadToByte(c, namespace, pipelineStatus.Url, pipelineStatus.GitRelease,skipCertVerification, reqLogger)
+func GetManifests(ctx context.Context, c client.Reader, namespace string, pipelineStatus kabanerov1alpha2.PipelineStatus, renderingContext map[string]interface{}, skipCertVerification bool, proxy *cache.ArtifactProxy, reqLogger logr.Logger) ([]StackAsset, error) {
+	// The decoded manifests of archives whose digest was verified are cached.
+	cacheKey, cacheable := getManifestCacheKey(pipelineStatus, renderingContext)
+	if cacheable {
+		if manifests, found := decodedManifests.get(cacheKey); found {
+			reqLogger.Info(fmt.Sprintf("Using the cached manifests of Pipeline Name %v", pipelineStatus.Name))
+			return manifests, nil
+		}
+	}
+
+	// Tekton bundles are pulled from an OCI registry instead of being downloaded.
+	if isBundlePipeline(pipelineStatus) {
+		manifests, err := getBundleManifests(ctx, c, namespace, pipelineStatus, renderingContext, skipCertVerification, proxy, reqLogger)
+		if err != nil {
+			return nil, err
+		}
+		if cacheable {
+			decodedManifests.put(cacheKey, manifests)
+		}
+		return manifests, nil
+	}
+
+	b, err := DownloadToByte(ctx, c, namespace, pipelineStatus.Url, pipelineStatus.GitRelease, skipCertVerification, proxy, reqLogger)
 	if err != nil {
-		return nil, err
+		if cache.IsDownloadTooLarge(err) {
+			return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveTooLarge, err: err}
+		}
+		return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveUnavailable, err: err}
+	}
+
+	// The signature protects the archive even when the digest in the index was also tampered with.
+	if pipelineStatus.Signature != nil {
+		if err := verifyArchiveSignature(ctx, c, namespace, b, pipelineStatus.Signature, proxy, reqLogger); err != nil {
+			return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveSignatureInvalid, err: fmt.Errorf("Signature verification failed for Pipeline Name %v: %v", pipelineStatus.Name, err)}
+		}
 	}
 
-	b_sum := sha256.Sum256(b)
-	var c_sum [32]byte
-	decoded, err := hex.DecodeString(pipelineStatus.Digest)
+	matched, b_sum, err := MatchDigest(pipelineStatus.Digest, b)
 	if err != nil {
 		return nil, err
 	}
-	copy(c_sum[:], decoded)
 
 	fileType, err := getPipelineFileType(pipelineStatus)
 	if err != nil {
 		return nil, err
 	}
 	if fileType == tarGzType {
-		if b_sum != c_sum {
-			return nil, fmt.Errorf("Index checksum: %x not match download checksum: %x for Pipeline Name %v", c_sum, b_sum, pipelineStatus.Name)
+		if !matched {
+			return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveDigestMismatch, err: fmt.Errorf("Index checksum: %v not match download checksum: %v for Pipeline Name %v", pipelineStatus.Digest, b_sum, pipelineStatus.Name)}
 		}
-		manifests, err := decodeManifests(b, renderingContext, reqLogger)
+		_, span := StartSpan(ctx, "RenderManifests", attribute.String("pipeline.name", pipelineStatus.Name))
+		manifests, err := decodeManifests(b, pipelineStatus.Renderer, renderingContext, reqLogger)
+		EndSpan(span, err)
 		if err != nil {
 			return nil, err
 		}
+		if cacheable {
+			decodedManifests.put(cacheKey, manifests)
+		}
 		return manifests, nil
 	} else if fileType == yamlType {
-		if b_sum != c_sum {
-			reqLogger.Info(fmt.Sprintf("Index checksum: %x not match download checksum: %x for Pipeline Name %v", c_sum, b_sum, pipelineStatus.Name))
+		if !matched {
+			reqLogger.Info(fmt.Sprintf("Index checksum: %v not match download checksum: %v for Pipeline Name %v", pipelineStatus.Digest, b_sum, pipelineStatus.Name))
+		}
+		// The included files are not covered by the digest, and may change, so a pipeline
+		// that includes files is not cached.
+		expanded, included, err := expandIncludes(ctx, c, namespace, pipelineStatus, b, skipCertVerification, proxy, reqLogger)
+		if err != nil {
+			return nil, err
+		}
+		_, span := StartSpan(ctx, "RenderManifests", attribute.String("pipeline.name", pipelineStatus.Name))
+		manifests, err := processManifest(expanded, pipelineStatus.Renderer, renderingContext, pipelineStatus.Name, b_sum)
+		if err == io.EOF {
+			EndSpan(span, nil)
+		} else {
+			EndSpan(span, err)
 		}
-		manifests, err := processManifest(b, renderingContext, pipelineStatus.Name, hex.EncodeToString(b_sum[:]))
 		if (err != nil) && (err != io.EOF) {
 			return nil, err
 		}
+		if cacheable && matched && included == 0 {
+			decodedManifests.put(cacheKey, manifests)
+		}
 		return manifests, nil
 	}
 
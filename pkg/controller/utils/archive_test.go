@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha512"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -77,7 +81,7 @@ func TestGetManifests(t *testing.T) {
 		Digest:     basicPipeline.sha256,
 		GitRelease: kabanerov1alpha2.GitReleaseInfo{}}
 
-	manifests, err := GetManifests(archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, true, reqLogger)
+	manifests, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, true, nil, reqLogger)
 
 	if err != nil {
 		t.Fatal(err)
@@ -99,7 +103,7 @@ func TestGetManifestsQuery(t *testing.T) {
 		Digest:     basicPipeline.sha256,
 		GitRelease: kabanerov1alpha2.GitReleaseInfo{}}
 
-	manifests, err := GetManifests(archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, true, reqLogger)
+	manifests, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, true, nil, reqLogger)
 
 	if err != nil {
 		t.Fatal(err)
@@ -121,7 +125,7 @@ func TestGetManifestsYaml(t *testing.T) {
 		Digest: "3b34de594df82cac3cb67c556a416443f6fafc0bc79101613eaa7ae0d59dd462",
 		GitRelease: kabanerov1alpha2.GitReleaseInfo{}}
 	
-	manifests, err := GetManifests(archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, true, reqLogger)
+	manifests, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, true, nil, reqLogger)
 
 	if err != nil {
 		t.Fatal(err)
@@ -132,6 +136,144 @@ func TestGetManifestsYaml(t *testing.T) {
 	}
 }
 
+// Test that manifest errors report the reason for the failure.
+func TestGetManifestsErrorReason(t *testing.T) {
+	// The server that will host the pipeline zip
+	server := httptest.NewServer(stackHandler{})
+	defer server.Close()
+
+	reqLogger := logf.NullLogger{}
+	pipelineStatus := kabanerov1alpha2.PipelineStatus{
+		Url:        server.URL + basicPipeline.name,
+		Digest:     "0000000000000000000000000000000000000000000000000000000000000000",
+		GitRelease: kabanerov1alpha2.GitReleaseInfo{}}
+
+	_, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{}, true, nil, reqLogger)
+	if err == nil {
+		t.Fatal("Expected the archive digest to be rejected")
+	}
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveDigestMismatch {
+		t.Errorf("Expected reason %v, but found %v", kabanerov1alpha2.StackReasonArchiveDigestMismatch, reason)
+	}
+
+	pipelineStatus.Url = server.URL + "/missing.pipeline.tar.gz"
+	_, err = GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{}, true, nil, reqLogger)
+	if err == nil {
+		t.Fatal("Expected the archive download to fail")
+	}
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveUnavailable {
+		t.Errorf("Expected reason %v, but found %v", kabanerov1alpha2.StackReasonArchiveUnavailable, reason)
+	}
+
+	if reason := ManifestErrorReason(errors.New("bad manifest")); reason != kabanerov1alpha2.StackReasonManifestRejected {
+		t.Errorf("Expected reason %v, but found %v", kabanerov1alpha2.StackReasonManifestRejected, reason)
+	}
+}
+
+// Builds a tar.gz archive holding the input files.
+func buildTestArchive(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+	for name, data := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Test that archives whose files exceed the size limits are rejected.
+func TestDecodeManifestsSizeLimits(t *testing.T) {
+	defer SetArchiveLimits(kabanerov1alpha2.DownloadLimitsSpec{})
+
+	task := []byte("apiVersion: tekton.dev/v1alpha1\nkind: Task\nmetadata:\n  name: build-task\n")
+	archive := buildTestArchive(t, map[string][]byte{
+		"manifest.yaml":   []byte("contents:\n- file: build-task.yaml\n"),
+		"build-task.yaml": task,
+	})
+
+	manifests, err := decodeManifests(archive, "", nil, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 1 {
+		t.Fatalf("Expected 1 manifest, but found %v", len(manifests))
+	}
+
+	// A single file larger than the per-file limit
+	SetArchiveLimits(kabanerov1alpha2.DownloadLimitsSpec{MaxArchiveFileSize: int64(len(task) - 1)})
+	_, err = decodeManifests(archive, "", nil, logf.NullLogger{})
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveTooLarge {
+		t.Errorf("Expected reason %v, but found %v (%v)", kabanerov1alpha2.StackReasonArchiveTooLarge, reason, err)
+	}
+
+	// Files that are each within the per-file limit, but larger in total than the extracted limit
+	SetArchiveLimits(kabanerov1alpha2.DownloadLimitsSpec{MaxArchiveExtractedSize: int64(len(task) + 1)})
+	_, err = decodeManifests(archive, "", nil, logf.NullLogger{})
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveTooLarge {
+		t.Errorf("Expected reason %v, but found %v (%v)", kabanerov1alpha2.StackReasonArchiveTooLarge, reason, err)
+	}
+}
+
+// Test that the files of an archive can be checked against sha512 digests.
+func TestDecodeManifestsSha512(t *testing.T) {
+	task := []byte("apiVersion: tekton.dev/v1alpha1\nkind: Task\nmetadata:\n  name: build-task\n")
+	sum := sha512.Sum512(task)
+	archive := buildTestArchive(t, map[string][]byte{
+		"manifest.yaml":   []byte(fmt.Sprintf("contents:\n- file: build-task.yaml\n  sha512: %x\n", sum)),
+		"build-task.yaml": task,
+	})
+
+	manifests, err := decodeManifests(archive, "", nil, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 1 || manifests[0].Sha256 != fmt.Sprintf("sha512:%x", sum) {
+		t.Fatalf("Expected 1 manifest with the sha512 digest, but found %v", manifests)
+	}
+
+	archive = buildTestArchive(t, map[string][]byte{
+		"manifest.yaml":   []byte(fmt.Sprintf("contents:\n- file: build-task.yaml\n  sha256: sha512:%x\n", sha512.Sum512([]byte("tampered")))),
+		"build-task.yaml": task,
+	})
+	_, err = decodeManifests(archive, "", nil, logf.NullLogger{})
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveDigestMismatch {
+		t.Errorf("Expected reason %v, but found %v (%v)", kabanerov1alpha2.StackReasonArchiveDigestMismatch, reason, err)
+	}
+}
+
+// Test that an archive larger than the download limit is rejected.
+func TestGetManifestsDownloadTooLarge(t *testing.T) {
+	defer SetArchiveLimits(kabanerov1alpha2.DownloadLimitsSpec{})
+	SetArchiveLimits(kabanerov1alpha2.DownloadLimitsSpec{MaxDownloadSize: 16})
+
+	server := httptest.NewServer(stackHandler{})
+	defer server.Close()
+
+	pipelineStatus := kabanerov1alpha2.PipelineStatus{
+		Url:        server.URL + basicPipeline.name,
+		Digest:     basicPipeline.sha256,
+		GitRelease: kabanerov1alpha2.GitReleaseInfo{}}
+
+	_, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{}, true, nil, logf.NullLogger{})
+	if err == nil {
+		t.Fatal("Expected the archive download to be rejected")
+	}
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveTooLarge {
+		t.Errorf("Expected reason %v, but found %v", kabanerov1alpha2.StackReasonArchiveTooLarge, reason)
+	}
+}
+
 func TestCommTraceZero(t *testing.T) {
 	out := commTrace(nil)
 	if out != "" {
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Returns a hash of the content of an asset in the cluster.  The metadata and status are
+// left out, since they change without the content of the asset changing.
+func assetContentHash(u *unstructured.Unstructured) (string, error) {
+	content := make(map[string]interface{})
+	for field, value := range u.Object {
+		if field != "metadata" && field != "status" && field != "apiVersion" && field != "kind" {
+			content[field] = value
+		}
+	}
+
+	// Map keys are marshalled in sorted order, so the hash is stable.
+	bytes, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Returns true if the content of an asset in the cluster no longer matches the content it
+// was applied with.  Assets applied before hashes were recorded are never drifted.
+func isAssetDrifted(u *unstructured.Unstructured, asset kabanerov1alpha2.RepositoryAssetStatus) bool {
+	if len(asset.AppliedHash) == 0 {
+		return false
+	}
+
+	hash, err := assetContentHash(u)
+	if err != nil {
+		return false
+	}
+	return hash != asset.AppliedHash
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Test that changes to the content of an asset are detected, and changes to its
+// metadata and status are not.
+func TestIsAssetDrifted(t *testing.T) {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "tekton.dev/v1alpha1",
+		"kind":       "Task",
+		"spec": map[string]interface{}{
+			"steps": []interface{}{map[string]interface{}{"name": "build", "image": "kabanero/java"}},
+		},
+	}}
+	u.SetName("build-task")
+
+	hash, err := assetContentHash(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset := kabanerov1alpha2.RepositoryAssetStatus{Name: "build-task", AppliedHash: hash}
+
+	if isAssetDrifted(u, asset) {
+		t.Fatal("The asset should not be drifted")
+	}
+
+	u.SetLabels(map[string]string{"app": "build"})
+	u.Object["status"] = map[string]interface{}{"ready": true}
+	if isAssetDrifted(u, asset) {
+		t.Fatal("Metadata and status changes should not make the asset drifted")
+	}
+
+	unstructured.SetNestedField(u.Object, "kabanero/nodejs", "spec", "image")
+	if !isAssetDrifted(u, asset) {
+		t.Fatal("The asset should be drifted")
+	}
+
+	// Assets applied before hashes were recorded are never drifted.
+	asset.AppliedHash = ""
+	if isAssetDrifted(u, asset) {
+		t.Fatal("An asset without a hash should not be drifted")
+	}
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"sort"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// The order in which assets are applied, by kind.  Assets are applied before the assets
+// that reference them, so that, for example, a Pipeline is not created before its Tasks.
+// Kinds that are not listed are applied last.
+var assetKindOrder = map[string]int{
+	"Task":                  0,
+	"ClusterTask":           1,
+	"Condition":             2,
+	"Pipeline":              3,
+	"TriggerBinding":        4,
+	"ClusterTriggerBinding": 5,
+	"TriggerTemplate":       6,
+	"EventListener":         7,
+}
+
+// Returns the apply priority of the input kind.  Lower values are applied first.
+func assetKindPriority(kind string) int {
+	if priority, found := assetKindOrder[kind]; found {
+		return priority
+	}
+	return len(assetKindOrder)
+}
+
+// Sorts the rendered assets of a pipeline archive into apply order.  Assets of the same
+// kind keep their archive order.
+func sortStackAssets(assets []StackAsset) {
+	sort.SliceStable(assets, func(i, j int) bool {
+		return assetKindPriority(assets[i].Kind) < assetKindPriority(assets[j].Kind)
+	})
+}
+
+// Returns the indexes of the input asset statuses in apply order.  Asset lists saved by
+// earlier releases may not be sorted.
+func assetApplyOrder(assets []kabanerov1alpha2.RepositoryAssetStatus) []int {
+	order := make([]int, len(assets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return assetKindPriority(assets[order[i]].Kind) < assetKindPriority(assets[order[j]].Kind)
+	})
+	return order
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Test that assets are sorted by kind, keeping the archive order within a kind.
+func TestSortStackAssets(t *testing.T) {
+	assets := []StackAsset{
+		{Name: "listener", Kind: "EventListener"},
+		{Name: "build-pipeline", Kind: "Pipeline"},
+		{Name: "unknown", Kind: "ConfigMap"},
+		{Name: "build-task", Kind: "Task"},
+		{Name: "template", Kind: "TriggerTemplate"},
+		{Name: "deploy-task", Kind: "Task"},
+		{Name: "binding", Kind: "TriggerBinding"},
+	}
+
+	sortStackAssets(assets)
+
+	expected := []string{"build-task", "deploy-task", "build-pipeline", "binding", "template", "listener", "unknown"}
+	for i, name := range expected {
+		if assets[i].Name != name {
+			t.Fatalf("Expected asset %v at position %v, but found %v", name, i, assets[i].Name)
+		}
+	}
+}
+
+// Test that unsorted asset lists are applied in kind order.
+func TestAssetApplyOrder(t *testing.T) {
+	assets := []kabanerov1alpha2.RepositoryAssetStatus{
+		{Name: "build-pipeline", Kind: "Pipeline"},
+		{Name: "build-task", Kind: "Task"},
+		{Name: "cluster-task", Kind: "ClusterTask"},
+	}
+
+	order := assetApplyOrder(assets)
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 0 {
+		t.Fatalf("Unexpected apply order: %v", order)
+	}
+}
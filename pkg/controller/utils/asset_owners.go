@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	mf "github.com/manifestival/manifestival"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Assets created outside the namespace of their owner, such as the trigger assets created
+// in the trigger namespace, cannot have owner references: the garbage collector would treat
+// the owner as missing.  Instead, their owners are recorded in an annotation, and the first
+// owner is copied to labels, so that the assets can be listed and counted by owner.
+const (
+	// Label set to the UID of the first owner of a cross-namespace asset.
+	AssetOwnerUIDLabel = "kabanero.io/owner-uid"
+
+	// Label set to the name of the first owner of a cross-namespace asset, when it is a Stack.
+	AssetStackLabel = "kabanero.io/stack"
+
+	// Annotation listing all owners of a cross-namespace asset, as Kind/name/UID, separated
+	// by commas.
+	AssetOwnersAnnotation = "kabanero.io/owners"
+)
+
+// The kinds of the assets that are created in the trigger namespace.
+var crossNamespaceAssetKinds = []schema.GroupVersionKind{
+	{Group: "triggers.tekton.dev", Version: "v1alpha1", Kind: "TriggerBinding"},
+	{Group: "triggers.tekton.dev", Version: "v1alpha1", Kind: "TriggerTemplate"},
+	{Group: "triggers.tekton.dev", Version: "v1alpha1", Kind: "EventListener"},
+}
+
+// An owner of a cross-namespace asset.
+type crossNamespaceOwner struct {
+	kind string
+	name string
+	uid  string
+}
+
+func (o crossNamespaceOwner) String() string {
+	return fmt.Sprintf("%v/%v/%v", o.kind, o.name, o.uid)
+}
+
+// Returns the owners recorded on a cross-namespace asset.  Owner references set by earlier
+// releases of the operator are included, so that those assets are migrated.
+func getCrossNamespaceOwners(u metav1.Object) []crossNamespaceOwner {
+	owners := []crossNamespaceOwner{}
+	found := make(map[string]bool)
+	for _, entry := range strings.Split(u.GetAnnotations()[AssetOwnersAnnotation], ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "/", 3)
+		if len(parts) != 3 || found[parts[2]] {
+			continue
+		}
+		found[parts[2]] = true
+		owners = append(owners, crossNamespaceOwner{kind: parts[0], name: parts[1], uid: parts[2]})
+	}
+
+	for _, ownerRef := range u.GetOwnerReferences() {
+		if !found[string(ownerRef.UID)] {
+			found[string(ownerRef.UID)] = true
+			owners = append(owners, crossNamespaceOwner{kind: ownerRef.Kind, name: ownerRef.Name, uid: string(ownerRef.UID)})
+		}
+	}
+
+	return owners
+}
+
+// Records the input owners on a cross-namespace asset, replacing any owner references.
+// When there are no owners left, the owner labels and annotation are removed.
+func setCrossNamespaceOwners(u metav1.Object, owners []crossNamespaceOwner) {
+	u.SetOwnerReferences(nil)
+
+	newLabels := u.GetLabels()
+	if newLabels == nil {
+		newLabels = make(map[string]string)
+	}
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	delete(newLabels, AssetStackLabel)
+	if len(owners) == 0 {
+		delete(newLabels, AssetOwnerUIDLabel)
+		delete(annotations, AssetOwnersAnnotation)
+	} else {
+		entries := []string{}
+		for _, owner := range owners {
+			entries = append(entries, owner.String())
+		}
+		annotations[AssetOwnersAnnotation] = strings.Join(entries, ",")
+		newLabels[AssetOwnerUIDLabel] = owners[0].uid
+		if owners[0].kind == "Stack" {
+			newLabels[AssetStackLabel] = owners[0].name
+		}
+	}
+
+	u.SetLabels(newLabels)
+	u.SetAnnotations(annotations)
+}
+
+// Returns true if the owners of the input object are recorded in the owners annotation.
+func isCrossNamespaceAsset(u metav1.Object) bool {
+	_, ok := u.GetAnnotations()[AssetOwnersAnnotation]
+	return ok
+}
+
+// Returns true if the input owner is one of the owners of a cross-namespace asset.
+func IsCrossNamespaceOwner(u metav1.Object, assetOwner metav1.OwnerReference) bool {
+	for _, owner := range getCrossNamespaceOwners(u) {
+		if owner.uid == string(assetOwner.UID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the input owner to a cross-namespace asset.  Returns true if the asset changed.
+func AddCrossNamespaceOwner(u metav1.Object, assetOwner metav1.OwnerReference) bool {
+	owners := getCrossNamespaceOwners(u)
+	changed := len(u.GetOwnerReferences()) != 0 || !isCrossNamespaceAsset(u)
+	if !IsCrossNamespaceOwner(u, assetOwner) {
+		owners = append(owners, crossNamespaceOwner{kind: assetOwner.Kind, name: assetOwner.Name, uid: string(assetOwner.UID)})
+		changed = true
+	}
+	if changed {
+		setCrossNamespaceOwners(u, owners)
+	}
+	return changed
+}
+
+// Removes the input owner from a cross-namespace asset.  Returns the number of owners left.
+func RemoveCrossNamespaceOwner(u metav1.Object, assetOwner metav1.OwnerReference) int {
+	owners := []crossNamespaceOwner{}
+	for _, owner := range getCrossNamespaceOwners(u) {
+		if owner.uid != string(assetOwner.UID) {
+			owners = append(owners, owner)
+		}
+	}
+	setCrossNamespaceOwners(u, owners)
+	return len(owners)
+}
+
+// Returns a transformer that records the owners of a cross-namespace asset: the owners of
+// the existing asset, if any, and the input owner.
+func injectCrossNamespaceOwner(existing *unstructured.Unstructured, assetOwner metav1.OwnerReference) mf.Transformer {
+	return func(u *unstructured.Unstructured) error {
+		owners := []crossNamespaceOwner{}
+		if existing != nil {
+			owners = getCrossNamespaceOwners(existing)
+		}
+		setCrossNamespaceOwners(u, owners)
+		AddCrossNamespaceOwner(u, assetOwner)
+		return nil
+	}
+}
+
+// Adds the input owner to an existing cross-namespace asset, retrying if the asset was
+// changed by someone else.
+func adoptCrossNamespaceAsset(c AssetClient, u *unstructured.Unstructured, assetOwner metav1.OwnerReference, logger logr.Logger) error {
+	key := client.ObjectKey{Namespace: u.GetNamespace(), Name: u.GetName()}
+	refetch := false
+	return retryAssetApply(logger, u.GetName(), func() error {
+		if refetch {
+			err := c.Get(context.TODO(), key, u)
+			if err != nil {
+				return err
+			}
+		}
+		refetch = true
+
+		// A retained asset that is used again is no longer inactive.
+		reactivated := clearRetainedAssetState(u)
+		if !AddCrossNamespaceOwner(u, assetOwner) && !reactivated {
+			return nil
+		}
+
+		return c.Update(context.TODO(), u)
+	})
+}
+
+// Removes the input owner from the cross-namespace assets it owns in the input namespace.
+// The assets are found by their owner labels rather than the status of the owner, which
+// may no longer list them.  When the last owner is removed, the deletion policy decides
+// what happens to the asset.
+func DeleteCrossNamespaceAssets(c AssetClient, namespace string, assetOwner metav1.OwnerReference, deletionPolicy string, logger logr.Logger) error {
+	requirement, err := labels.NewRequirement(AssetOwnerUIDLabel, selection.Exists, nil)
+	if err != nil {
+		return err
+	}
+	selector := client.MatchingLabelsSelector{Selector: labels.NewSelector().Add(*requirement)}
+
+	var lastErr error
+	for _, gvk := range crossNamespaceAssetKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind + "List"})
+		err := c.List(context.Background(), list, client.InNamespace(namespace), selector)
+		if err != nil {
+			// The Tekton Triggers CRDs may not be installed.
+			if !meta.IsNoMatchError(err) {
+				logger.Error(err, fmt.Sprintf("Unable to list %v assets in namespace %v", gvk.Kind, namespace))
+				lastErr = err
+			}
+			continue
+		}
+
+		for index := range list.Items {
+			u := &list.Items[index]
+			if !IsCrossNamespaceOwner(u, assetOwner) {
+				continue
+			}
+
+			logger.Info(fmt.Sprintf("Removing owner %v/%v from %v %v in namespace %v", assetOwner.Kind, assetOwner.Name, gvk.Kind, u.GetName(), namespace))
+			err = deleteAssetObject(c, u, assetOwner, deletionPolicy, logger)
+			if err != nil {
+				lastErr = err
+			}
+		}
+	}
+
+	return lastErr
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Test that a trigger asset shared by two stacks is labelled with its owners, and deleted
+// with the last one.
+func TestDeleteCrossNamespaceAsset(t *testing.T) {
+	logger := logf.Log.WithName("asset_owners_test")
+	java := metav1.OwnerReference{APIVersion: "kabanero.io/v1alpha2", Kind: "Stack", Name: "java-microprofile", UID: "1"}
+	nodejs := metav1.OwnerReference{APIVersion: "kabanero.io/v1alpha2", Kind: "Stack", Name: "nodejs", UID: "2"}
+	asset := kabanerov1alpha2.RepositoryAssetStatus{Name: "build-binding", Namespace: "tekton-pipelines", Version: "v1alpha1", Kind: "TriggerBinding", Status: AssetStatusActive}
+	key := client.ObjectKey{Name: "build-binding", Namespace: "tekton-pipelines"}
+
+	u := &unstructured.Unstructured{}
+	u.SetName("build-binding")
+	u.SetNamespace("tekton-pipelines")
+	err := injectCrossNamespaceOwner(nil, java)(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.GetLabels()[AssetOwnerUIDLabel] != "1" || u.GetLabels()[AssetStackLabel] != "java-microprofile" {
+		t.Fatalf("The asset should be labelled with its owner: %v", u)
+	}
+	if len(u.GetOwnerReferences()) != 0 {
+		t.Fatalf("The asset should have no owner references: %v", u)
+	}
+	if !AddCrossNamespaceOwner(u, nodejs) || AddCrossNamespaceOwner(u, nodejs) {
+		t.Fatal("The second owner should have been added once")
+	}
+
+	c := deleteAssetTestClient{objs: map[client.ObjectKey]*unstructured.Unstructured{key: u}}
+	err = DeleteAsset(c, asset, java, kabanerov1alpha2.AssetDeletionPolicyDelete, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, ok := c.objs[key]
+	if !ok {
+		t.Fatal("The asset should not have been deleted while another stack owns it")
+	}
+	if u.GetLabels()[AssetOwnerUIDLabel] != "2" || u.GetLabels()[AssetStackLabel] != "nodejs" {
+		t.Fatalf("The asset should be labelled with the remaining owner: %v", u)
+	}
+	if IsCrossNamespaceOwner(u, java) {
+		t.Fatalf("The deleted owner should have been removed: %v", u)
+	}
+
+	err = DeleteAsset(c, asset, nodejs, kabanerov1alpha2.AssetDeletionPolicyDelete, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.objs[key]; ok {
+		t.Fatal("The asset should have been deleted with its last owner")
+	}
+}
+
+// Test that an owner reference set by an earlier release is moved to the owners annotation.
+func TestAddCrossNamespaceOwnerMigratesOwnerReferences(t *testing.T) {
+	java := metav1.OwnerReference{APIVersion: "kabanero.io/v1alpha2", Kind: "Stack", Name: "java-microprofile", UID: "1"}
+
+	u := &unstructured.Unstructured{}
+	u.SetName("listener")
+	u.SetNamespace("tekton-pipelines")
+	u.SetOwnerReferences([]metav1.OwnerReference{java})
+
+	if !AddCrossNamespaceOwner(u, java) {
+		t.Fatal("The owner reference should have been migrated")
+	}
+	if len(u.GetOwnerReferences()) != 0 {
+		t.Fatalf("The owner reference should have been removed: %v", u)
+	}
+	if u.GetAnnotations()[AssetOwnersAnnotation] != "Stack/java-microprofile/1" {
+		t.Fatalf("The owner should be recorded in the annotation: %v", u)
+	}
+
+	// Orphaning the asset removes the owner labels.
+	if RemoveCrossNamespaceOwner(u, java) != 0 {
+		t.Fatal("Expected no owners to remain")
+	}
+	if len(u.GetLabels()) != 0 || isCrossNamespaceAsset(u) {
+		t.Fatalf("The owner labels and annotation should have been removed: %v", u)
+	}
+}
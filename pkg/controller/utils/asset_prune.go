@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	mf "github.com/manifestival/manifestival"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Identifies an asset across pipeline archives.  The version is left out, so that an
+// asset whose API version changed is still considered the same asset.
+type assetKey struct {
+	group     string
+	kind      string
+	namespace string
+	name      string
+}
+
+func newAssetKey(asset kabanerov1alpha2.RepositoryAssetStatus, targetNamespace string) assetKey {
+	namespace := asset.Namespace
+	if len(namespace) == 0 {
+		namespace = targetNamespace
+	}
+	return assetKey{group: asset.Group, kind: asset.Kind, namespace: namespace, name: asset.Name}
+}
+
+// A pipeline, identified by its Id, in a specific version of a stack.
+type pipelineId struct {
+	version string
+	id      string
+}
+
+// Finds the pipelines whose archive changed without changing their Id.  The returned map
+// is keyed by the new archive, and holds the use map entry of the archive it replaces.
+// Only archives that are no longer used by any version are returned.
+func findReplacedPipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alpha2.ComponentStatus, assetUseMap PipelineUseMap) map[PipelineUseMapKey]*PipelineUseMapValue {
+	previous := make(map[pipelineId]PipelineUseMapKey)
+	for _, curStatus := range status.GetVersions() {
+		for _, pipeline := range curStatus.GetPipelines() {
+			if len(pipeline.Name) == 0 {
+				continue
+			}
+			key := PipelineUseMapKey{Digest: pipeline.Digest}
+			if pipeline.GitRelease.IsUsable() {
+				key.GitRelease = pipeline.GitRelease
+			} else {
+				key.Url = pipeline.Url
+			}
+			previous[pipelineId{version: curStatus.GetVersion(), id: pipeline.Name}] = key
+		}
+	}
+
+	replaced := make(map[PipelineUseMapKey]*PipelineUseMapValue)
+	for _, curSpec := range spec.GetVersions() {
+		for _, pipeline := range curSpec.GetPipelines() {
+			key := PipelineUseMapKey{Digest: pipeline.Sha256}
+			if pipeline.GitRelease.IsUsable() {
+				key.GitRelease = gitReleaseSpecToGitReleaseInfo(pipeline.GitRelease)
+			} else {
+				key.Url = pipeline.ArchiveUrl()
+			}
+
+			oldKey, found := previous[pipelineId{version: curSpec.GetVersion(), id: pipeline.Id}]
+			if !found || oldKey == key || replaced[key] != nil {
+				continue
+			}
+
+			oldValue, newValue := assetUseMap[oldKey], assetUseMap[key]
+			if oldValue == nil || newValue == nil || oldValue.useCount > 0 || newValue.useCount <= 0 {
+				continue
+			}
+			replaced[key] = oldValue
+		}
+	}
+
+	return replaced
+}
+
+// Removes the assets of a replaced archive that are not in the asset list of the archive
+// replacing it.  The assets found in both archives are returned: they are re-applied from
+// the new archive instead of being deleted and created again.
+func pruneReplacedAssets(c AssetClient, replaced *PipelineUseMapValue, newAssets []kabanerov1alpha2.RepositoryAssetStatus, targetNamespace string, assetOwner metav1.OwnerReference, deletionPolicy string, logger logr.Logger) map[assetKey]bool {
+	kept := make(map[assetKey]bool)
+	for _, asset := range newAssets {
+		kept[newAssetKey(asset, targetNamespace)] = false
+	}
+
+	for _, asset := range replaced.ActiveAssets {
+		key := newAssetKey(asset, targetNamespace)
+		if _, found := kept[key]; found {
+			kept[key] = true
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("Removing asset %v in namespace %v, which is no longer in the pipeline archive", asset.Name, key.namespace))
+		asset.Namespace = key.namespace
+		DeleteAsset(c, asset, assetOwner, deletionPolicy, logger)
+	}
+
+	carriedOver := make(map[assetKey]bool)
+	for key, found := range kept {
+		if found {
+			carriedOver[key] = true
+		}
+	}
+	return carriedOver
+}
+
+// Returns a transformer that keeps the owners of an object that is re-applied from a new
+// pipeline archive.  Other stacks may share the object.
+func keepOwnerReferences(ownerRefs []metav1.OwnerReference, assetOwner metav1.OwnerReference) mf.Transformer {
+	return func(u *unstructured.Unstructured) error {
+		newOwnerRefs := append([]metav1.OwnerReference{}, ownerRefs...)
+		foundOurselves := false
+		for _, ownerRef := range ownerRefs {
+			if ownerRef.UID == assetOwner.UID {
+				foundOurselves = true
+			}
+		}
+		if !foundOurselves {
+			newOwnerRefs = append(newOwnerRefs, assetOwner)
+		}
+		u.SetOwnerReferences(newOwnerRefs)
+		return nil
+	}
+}
+
+// Returns a transformer that adds the input labels to a resource, keeping its other labels.
+func injectLabels(labels map[string]string) mf.Transformer {
+	return func(u *unstructured.Unstructured) error {
+		newLabels := u.GetLabels()
+		if newLabels == nil {
+			newLabels = make(map[string]string)
+		}
+		for key, value := range labels {
+			newLabels[key] = value
+		}
+		u.SetLabels(newLabels)
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Test that a new archive digest at the same pipeline Id replaces the old archive.
+func TestFindReplacedPipelines(t *testing.T) {
+	spec := kabanerov1alpha2.StackSpec{Versions: []kabanerov1alpha2.StackVersion{{
+		Version:   "0.2.5",
+		Pipelines: []kabanerov1alpha2.PipelineSpec{{Id: "default", Sha256: "2222", Https: kabanerov1alpha2.HttpsProtocolFile{Url: "https://example.com/pipelines.tar.gz"}}},
+	}}}
+	status := kabanerov1alpha2.StackStatus{Versions: []kabanerov1alpha2.StackVersionStatus{{
+		Version:   "0.2.5",
+		Pipelines: []kabanerov1alpha2.PipelineStatus{{Name: "default", Digest: "1111", Url: "https://example.com/pipelines.tar.gz"}},
+	}}}
+
+	oldKey := PipelineUseMapKey{Url: "https://example.com/pipelines.tar.gz", Digest: "1111"}
+	newKey := PipelineUseMapKey{Url: "https://example.com/pipelines.tar.gz", Digest: "2222"}
+	oldValue := &PipelineUseMapValue{useCount: 0}
+	assetUseMap := PipelineUseMap{oldKey: oldValue, newKey: &PipelineUseMapValue{useCount: 1}}
+
+	replaced := findReplacedPipelines(spec, status, assetUseMap)
+	if len(replaced) != 1 || replaced[newKey] != oldValue {
+		t.Fatalf("Expected the old archive to be replaced by the new one: %v", replaced)
+	}
+
+	// The old archive is still used by another version.
+	oldValue.useCount = 1
+	replaced = findReplacedPipelines(spec, status, assetUseMap)
+	if len(replaced) != 0 {
+		t.Fatalf("Expected no replaced archives: %v", replaced)
+	}
+}
+
+// Test that only the assets dropped by the new archive are removed.
+func TestPruneReplacedAssets(t *testing.T) {
+	logger := logf.Log.WithName("asset_prune_test")
+	owner := metav1.OwnerReference{APIVersion: "kabanero.io/v1alpha2", Kind: "Stack", Name: "java-microprofile", UID: "1"}
+
+	c := deleteAssetTestClient{objs: make(map[client.ObjectKey]*unstructured.Unstructured)}
+	for _, name := range []string{"build-task", "deploy-task"} {
+		u := &unstructured.Unstructured{}
+		u.SetName(name)
+		u.SetNamespace("kabanero")
+		u.SetOwnerReferences([]metav1.OwnerReference{owner})
+		c.objs[client.ObjectKey{Name: name, Namespace: "kabanero"}] = u
+	}
+
+	replaced := &PipelineUseMapValue{PipelineStatus: kabanerov1alpha2.PipelineStatus{ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{
+		{Name: "build-task", Group: "tekton.dev", Version: "v1alpha1", Kind: "Task", Status: AssetStatusActive},
+		{Name: "deploy-task", Group: "tekton.dev", Version: "v1alpha1", Kind: "Task", Namespace: "kabanero", Status: AssetStatusActive},
+	}}}
+	newAssets := []kabanerov1alpha2.RepositoryAssetStatus{
+		{Name: "build-task", Group: "tekton.dev", Version: "v1beta1", Kind: "Task", Namespace: "kabanero", Status: AssetStatusUnknown},
+		{Name: "test-task", Group: "tekton.dev", Version: "v1beta1", Kind: "Task", Namespace: "kabanero", Status: AssetStatusUnknown},
+	}
+
+	carriedOver := pruneReplacedAssets(c, replaced, newAssets, "kabanero", owner, kabanerov1alpha2.AssetDeletionPolicyDelete, logger)
+
+	if _, ok := c.objs[client.ObjectKey{Name: "deploy-task", Namespace: "kabanero"}]; ok {
+		t.Fatal("The asset dropped by the new archive should have been deleted")
+	}
+	if _, ok := c.objs[client.ObjectKey{Name: "build-task", Namespace: "kabanero"}]; !ok {
+		t.Fatal("The asset kept by the new archive should not have been deleted")
+	}
+	if len(carriedOver) != 1 || !carriedOver[assetKey{group: "tekton.dev", kind: "Task", namespace: "kabanero", name: "build-task"}] {
+		t.Fatalf("Expected build-task to be carried over: %v", carriedOver)
+	}
+}
+
+// Test that re-applying a carried over asset keeps its other owners.
+func TestKeepOwnerReferences(t *testing.T) {
+	owner := metav1.OwnerReference{Kind: "Stack", Name: "java-microprofile", UID: "1"}
+	other := metav1.OwnerReference{Kind: "Stack", Name: "nodejs", UID: "2"}
+
+	u := &unstructured.Unstructured{}
+	u.SetOwnerReferences([]metav1.OwnerReference{owner})
+	if err := keepOwnerReferences([]metav1.OwnerReference{other}, owner)(u); err != nil {
+		t.Fatal(err)
+	}
+
+	ownerRefs := u.GetOwnerReferences()
+	if len(ownerRefs) != 2 || ownerRefs[0].UID != other.UID || ownerRefs[1].UID != owner.UID {
+		t.Fatalf("Unexpected owner references: %v", ownerRefs)
+	}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// How applying an asset is retried when it fails with a transient error.  The budget is
+// kept small, since the assets of a pipeline are applied one after the other.
+var assetApplyBackoff = timer.Backoff{
+	Attempts: 4,
+	Initial:  250 * time.Millisecond,
+	Factor:   2,
+	Max:      2 * time.Second,
+	Budget:   4 * time.Second,
+}
+
+// Returns true if an error applying an asset is likely to go away on its own: the asset
+// was changed by someone else, or the API server or an admission webhook was unavailable
+// or timed out.
+func isTransientApplyError(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return false
+	}
+
+	statusErr := &apierrors.StatusError{ErrStatus: status.Status()}
+	return apierrors.IsConflict(statusErr) ||
+		apierrors.IsServerTimeout(statusErr) ||
+		apierrors.IsTimeout(statusErr) ||
+		apierrors.IsTooManyRequests(statusErr) ||
+		apierrors.IsInternalError(statusErr) ||
+		apierrors.IsServiceUnavailable(statusErr)
+}
+
+// Runs the apply function, retrying it with exponential backoff while it fails with a
+// transient error.  The last error is returned once the retry budget is exhausted.
+func retryAssetApply(logger logr.Logger, name string, apply func() error) error {
+	return retryAssetApplyWithBackoff(assetApplyBackoff, logger, name, apply)
+}
+
+func retryAssetApplyWithBackoff(backoff timer.Backoff, logger logr.Logger, name string, apply func() error) error {
+	return timer.RetryOnError(backoff, isTransientApplyError, func(attempt int, err error, waitTime time.Duration) {
+		logger.Info(fmt.Sprintf("Transient error applying asset %v. It will be retried. Error: %v", name, err))
+	}, apply)
+}
+
+// Adds the asset owner to an existing asset, and clears the inactive state of a retained
+// asset.  If the asset was changed by someone else in the meantime, it is read again and
+// the update is retried.
+func adoptAsset(c AssetClient, u *unstructured.Unstructured, assetOwner metav1.OwnerReference, logger logr.Logger) error {
+	key := client.ObjectKey{Namespace: u.GetNamespace(), Name: u.GetName()}
+	refetch := false
+	return retryAssetApply(logger, u.GetName(), func() error {
+		if refetch {
+			err := c.Get(context.TODO(), key, u)
+			if err != nil {
+				return err
+			}
+		}
+		refetch = true
+
+		ownerRefs := u.GetOwnerReferences()
+		foundOurselves := false
+		for _, ownerRef := range ownerRefs {
+			if ownerRef.UID == assetOwner.UID {
+				foundOurselves = true
+			}
+		}
+
+		// A retained asset that is used again is no longer inactive.
+		reactivated := clearRetainedAssetState(u)
+
+		if foundOurselves && !reactivated {
+			return nil
+		}
+
+		// There can only be one 'controller' reference, so additional references should not
+		// be controller references.  It's not clear what Kubernetes does with this field.
+		if !foundOurselves {
+			u.SetOwnerReferences(append(ownerRefs, assetOwner))
+		}
+
+		return c.Update(context.TODO(), u)
+	})
+}
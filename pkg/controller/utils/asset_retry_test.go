@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var retrylog = logf.Log.WithName("asset_retry_test")
+
+// Test that conflicts and webhook timeouts are retried, and other errors are not.
+func TestRetryAssetApply(t *testing.T) {
+	backoff := timer.Backoff{Attempts: 3, Initial: time.Millisecond, Factor: 2}
+	gr := schema.GroupResource{Group: "tekton.dev", Resource: "tasks"}
+
+	calls := 0
+	err := retryAssetApplyWithBackoff(backoff, retrylog, "build-task", func() error {
+		calls++
+		if calls == 1 {
+			return apierrors.NewConflict(gr, "build-task", errors.New("the object has been modified"))
+		}
+		if calls == 2 {
+			// Errors may be wrapped by the manifest library.
+			return fmt.Errorf("apply failed: %w", apierrors.NewInternalError(errors.New("failed calling webhook: context deadline exceeded")))
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("Expected the apply to succeed on the third attempt, but found %v after %v attempts", err, calls)
+	}
+
+	calls = 0
+	invalid := apierrors.NewBadRequest("spec.steps is required")
+	err = retryAssetApplyWithBackoff(backoff, retrylog, "build-task", func() error {
+		calls++
+		return invalid
+	})
+	if err != invalid || calls != 1 {
+		t.Fatalf("Expected the error after 1 attempt, but found %v after %v attempts", err, calls)
+	}
+}
+
+// Unit test client whose first update fails with a conflict.
+type conflictTestClient struct {
+	client.Client
+	stored  *unstructured.Unstructured
+	updates *int
+}
+
+func (c conflictTestClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	c.stored.DeepCopyInto(obj.(*unstructured.Unstructured))
+	return nil
+}
+
+func (c conflictTestClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	*c.updates++
+	if *c.updates == 1 {
+		// Someone else added an owner in the meantime.
+		other := metav1.OwnerReference{Kind: "Stack", Name: "nodejs", UID: "2"}
+		c.stored.SetOwnerReferences(append(c.stored.GetOwnerReferences(), other))
+		return apierrors.NewConflict(schema.GroupResource{}, c.stored.GetName(), errors.New("the object has been modified"))
+	}
+	obj.(*unstructured.Unstructured).DeepCopyInto(c.stored)
+	return nil
+}
+
+// Test that adopting an asset reads it again after a conflict, keeping the changes made
+// by others.
+func TestAdoptAssetConflict(t *testing.T) {
+	owner := metav1.OwnerReference{Kind: "Stack", Name: "java-microprofile", UID: "1"}
+	stored := &unstructured.Unstructured{}
+	stored.SetName("build-task")
+	stored.SetNamespace("kabanero")
+
+	updates := 0
+	c := conflictTestClient{stored: stored, updates: &updates}
+	u := stored.DeepCopy()
+	if err := adoptAsset(c, u, owner, retrylog); err != nil {
+		t.Fatal(err)
+	}
+
+	ownerRefs := stored.GetOwnerReferences()
+	if updates != 2 || len(ownerRefs) != 2 || ownerRefs[0].UID != "2" || ownerRefs[1].UID != owner.UID {
+		t.Fatalf("Expected both owners after %v updates, but found %v", updates, ownerRefs)
+	}
+}
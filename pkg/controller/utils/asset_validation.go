@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// The top level fields that a Kubernetes resource may have.
+var assetTopLevelFields = map[string]bool{
+	"apiVersion": true,
+	"kind":       true,
+	"metadata":   true,
+	"spec":       true,
+	"status":     true,
+}
+
+// The list fields required in the spec of the Tekton kinds, and the fields that each
+// list entry requires.
+var assetRequiredSpecLists = map[string]struct {
+	field       string
+	entryFields []string
+}{
+	"Task":            {field: "steps", entryFields: []string{"image"}},
+	"ClusterTask":     {field: "steps", entryFields: []string{"image"}},
+	"Pipeline":        {field: "tasks", entryFields: []string{"name"}},
+	"EventListener":   {field: "triggers"},
+	"TriggerTemplate": {field: "resourcetemplates"},
+}
+
+// Checks the structure of a rendered asset before it is applied, so that a mistake in
+// a pipeline archive is reported with a descriptive message.  The checks are limited
+// to what all versions of the Tekton resources have in common: the full schema is left
+// to the API server.
+func validateAssetManifest(u *unstructured.Unstructured) error {
+	if len(u.GetAPIVersion()) == 0 || len(u.GetKind()) == 0 {
+		return fmt.Errorf("the manifest must specify an apiVersion and a kind")
+	}
+
+	name := u.GetName()
+	if len(name) == 0 {
+		return fmt.Errorf("the %v manifest must specify a metadata.name", u.GetKind())
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		return fmt.Errorf("the name of %v %v is not valid: %v", u.GetKind(), name, strings.Join(errs, ", "))
+	}
+
+	// Only resources with a spec are checked for unknown fields.  Other kinds, such as
+	// ConfigMaps, have their own top level fields.
+	if _, ok := u.Object["spec"]; !ok {
+		if _, required := assetRequiredSpecLists[u.GetKind()]; required {
+			return fmt.Errorf("%v %v does not have a spec", u.GetKind(), name)
+		}
+		return nil
+	}
+
+	unknown := []string{}
+	for field := range u.Object {
+		if !assetTopLevelFields[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) != 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%v %v has unknown top level fields: %v", u.GetKind(), name, strings.Join(unknown, ", "))
+	}
+
+	if _, ok := u.Object["spec"].(map[string]interface{}); !ok {
+		return fmt.Errorf("the spec of %v %v is not an object", u.GetKind(), name)
+	}
+
+	required, found := assetRequiredSpecLists[u.GetKind()]
+	if !found {
+		return nil
+	}
+
+	entries, found, err := unstructured.NestedSlice(u.Object, "spec", required.field)
+	if err != nil {
+		return fmt.Errorf("spec.%v of %v %v is not a list", required.field, u.GetKind(), name)
+	}
+	if !found || len(entries) == 0 {
+		return fmt.Errorf("%v %v must specify at least one entry in spec.%v", u.GetKind(), name, required.field)
+	}
+
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("spec.%v[%v] of %v %v is not an object", required.field, i, u.GetKind(), name)
+		}
+		for _, field := range required.entryFields {
+			if value, ok := fields[field].(string); !ok || len(value) == 0 {
+				return fmt.Errorf("spec.%v[%v] of %v %v is missing the %v field", required.field, i, u.GetKind(), name, field)
+			}
+		}
+	}
+
+	return nil
+}
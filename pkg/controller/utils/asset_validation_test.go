@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Test that structural mistakes in manifests are reported with descriptive messages.
+func TestValidateAssetManifest(t *testing.T) {
+	newTask := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "tekton.dev/v1alpha1",
+			"kind":       "Task",
+			"metadata":   map[string]interface{}{"name": "build-task"},
+			"spec": map[string]interface{}{
+				"steps": []interface{}{map[string]interface{}{"name": "build", "image": "kabanero/java"}},
+			},
+		}}
+	}
+
+	if err := validateAssetManifest(newTask()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		modify   func(u *unstructured.Unstructured)
+		expected string
+	}{
+		{func(u *unstructured.Unstructured) { u.SetName("Build_Task") }, "name of Task Build_Task is not valid"},
+		{func(u *unstructured.Unstructured) { u.Object["sepc"] = u.Object["spec"] }, "unknown top level fields: sepc"},
+		{func(u *unstructured.Unstructured) { delete(u.Object, "spec") }, "does not have a spec"},
+		{func(u *unstructured.Unstructured) { u.Object["spec"] = map[string]interface{}{"step": []interface{}{}} }, "at least one entry in spec.steps"},
+		{func(u *unstructured.Unstructured) {
+			u.Object["spec"] = map[string]interface{}{"steps": []interface{}{map[string]interface{}{"name": "build"}}}
+		}, "spec.steps[0] of Task build-task is missing the image field"},
+	}
+
+	for _, test := range tests {
+		u := newTask()
+		test.modify(u)
+		err := validateAssetManifest(u)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Expected an error containing %q, but found %v", test.expected, err)
+		}
+	}
+
+	// Kinds without a spec are only checked for a name.
+	cm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "settings"},
+		"data":       map[string]interface{}{"key": "value"},
+	}}
+	if err := validateAssetManifest(cm); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
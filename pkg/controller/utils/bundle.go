@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The annotations that Tekton places on each layer of a bundle, naming the resource in it.
+const (
+	bundleAnnotationKind = "dev.tekton.image.kind"
+	bundleAnnotationName = "dev.tekton.image.name"
+)
+
+// Returns the authenticators to try, in order, when pulling from the input registry.
+type RegistryAuthenticatorFunc func(c client.Reader, namespace string, imgRegistry string, logger logr.Logger) ([]authn.Authenticator, error)
+
+// The authenticators used to pull Tekton bundles.  If it is not set, bundles are pulled
+// anonymously.
+var registryAuthenticator RegistryAuthenticatorFunc
+
+// Sets the function that returns the authenticators used to pull Tekton bundles.
+// Controllers set it to the stack controller's registry authentication when they are
+// added to the manager.
+func SetRegistryAuthenticator(f RegistryAuthenticatorFunc) {
+	registryAuthenticator = f
+}
+
+// Returns true if the input pipeline is pulled as a Tekton bundle from an OCI registry.
+func isBundlePipeline(pipelineStatus kabanerov1alpha2.PipelineStatus) bool {
+	return strings.HasPrefix(pipelineStatus.Url, kabanerov1alpha2.OciBundleUrlPrefix)
+}
+
+// Pulls a Tekton bundle, and returns the image holding it, and its raw manifest.
+func pullBundle(ctx context.Context, c client.Reader, namespace string, bundle string, skipCertVerification bool, reqLogger logr.Logger) (v1.Image, []byte, error) {
+	ref, err := name.ParseReference(bundle, name.WeakValidation)
+	if err != nil {
+		return nil, nil, fmt.Errorf("The bundle reference %v is not valid: %v", bundle, err)
+	}
+
+	authenticators := []authn.Authenticator{authn.Anonymous}
+	if registryAuthenticator != nil {
+		authenticators, err = registryAuthenticator(c, namespace, ref.Context().RegistryStr(), reqLogger)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var tlsConfig *tls.Config
+	if skipCertVerification {
+		tlsConfig = &tls.Config{InsecureSkipVerify: skipCertVerification}
+	}
+	transport := cache.WithContext(ctx, cache.NewTransport(tlsConfig))
+
+	// Use the first credentials that work.
+	var img v1.Image
+	for i, authenticator := range authenticators {
+		img, err = remote.Image(ref, remote.WithAuth(authenticator), remote.WithTransport(transport))
+		if err == nil {
+			break
+		}
+		if i < len(authenticators)-1 {
+			reqLogger.Info(fmt.Sprintf("Unable to pull bundle %v using credentials %v of %v. Trying the next ones. Error: %v", bundle, i+1, len(authenticators), err))
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := img.RawManifest()
+	if err != nil {
+		return nil, nil, err
+	}
+	return img, raw, nil
+}
+
+// Reads the resources of a Tekton bundle.  Each layer of the bundle holds one resource,
+// in a tar file, and is annotated with the kind and name of the resource.
+func decodeBundle(img v1.Image, renderer string, renderingContext map[string]interface{}, reqLogger logr.Logger) ([]StackAsset, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	maxFileSize, maxExtractedSize := getArchiveLimits()
+	var extractedSize int64
+	manifests := []StackAsset{}
+	for _, desc := range manifest.Layers {
+		kind := desc.Annotations[bundleAnnotationKind]
+		resourceName := desc.Annotations[bundleAnnotationName]
+		if len(kind) == 0 || len(resourceName) == 0 {
+			reqLogger.Info(fmt.Sprintf("Skipping bundle layer %v, which is not annotated with a Tekton resource", desc.Digest))
+			continue
+		}
+
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		b, err := readBundleLayer(layer, maxFileSize)
+		if _, ok := err.(manifestError); ok {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading bundle layer %v for %v %v: %v", desc.Digest, kind, resourceName, err)
+		}
+		extractedSize += int64(len(b))
+		if extractedSize > maxExtractedSize {
+			return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveTooLarge, err: fmt.Errorf("The bundle resources are larger than the limit of %v bytes once extracted", maxExtractedSize)}
+		}
+
+		//Apply the Kabanero yaml directive processor
+		pmanifests, err := processManifest(b, renderer, renderingContext, strings.ToLower(kind)+"/"+resourceName, desc.Digest.String())
+		if (err != nil) && (err != io.EOF) {
+			return nil, fmt.Errorf("Error decoding %v %v: %v", kind, resourceName, err.Error())
+		}
+		manifests = append(manifests, pmanifests...)
+	}
+	return manifests, nil
+}
+
+// Returns the resource held in the tar file of a bundle layer.
+func readBundleLayer(layer v1.Layer, maxFileSize int64) ([]byte, error) {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	tarReader := tar.NewReader(rc)
+	header, err := tarReader.Next()
+	if err != nil {
+		return nil, err
+	}
+	if header.Size > maxFileSize {
+		return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveTooLarge, err: fmt.Errorf("Bundle file %v is %v bytes, which is larger than the limit of %v bytes", header.Name, header.Size, maxFileSize)}
+	}
+	return readBytesFromReader(header.Size, tarReader)
+}
+
+// Pulls a Tekton bundle, checks it against the digest and signature of the pipeline, and
+// returns its resources.
+func getBundleManifests(ctx context.Context, c client.Reader, namespace string, pipelineStatus kabanerov1alpha2.PipelineStatus, renderingContext map[string]interface{}, skipCertVerification bool, proxy *cache.ArtifactProxy, reqLogger logr.Logger) ([]StackAsset, error) {
+	bundle := strings.TrimPrefix(pipelineStatus.Url, kabanerov1alpha2.OciBundleUrlPrefix)
+	img, raw, err := pullBundle(ctx, c, namespace, bundle, skipCertVerification, reqLogger)
+	if err != nil {
+		return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveUnavailable, err: fmt.Errorf("Unable to pull bundle %v: %v", bundle, err)}
+	}
+
+	// The signature of a bundle is made over its image manifest.
+	if pipelineStatus.Signature != nil {
+		if err := verifyArchiveSignature(ctx, c, namespace, raw, pipelineStatus.Signature, proxy, reqLogger); err != nil {
+			return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveSignatureInvalid, err: fmt.Errorf("Signature verification failed for Pipeline Name %v: %v", pipelineStatus.Name, err)}
+		}
+	}
+
+	matched, sum, err := MatchDigest(pipelineStatus.Digest, raw)
+	if err != nil {
+		return nil, err
+	}
+	if !matched {
+		return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveDigestMismatch, err: fmt.Errorf("Index checksum: %v not match bundle manifest checksum: %v for Pipeline Name %v", pipelineStatus.Digest, sum, pipelineStatus.Name)}
+	}
+
+	return decodeBundle(img, pipelineStatus.Renderer, renderingContext, reqLogger)
+}
+
+// The annotation that tools such as ORAS place on a layer holding a single file.
+const layerAnnotationTitle = "org.opencontainers.image.title"
+
+// Pulls the input image, and returns the content of the file at the input path.  The file
+// is either a layer annotated with the path as its title, as pushed by tools such as ORAS,
+// or a file in the tar layers of the image, where later layers take precedence.
+func GetImageFile(ctx context.Context, c client.Reader, namespace string, image string, path string, skipCertVerification bool, reqLogger logr.Logger) ([]byte, error) {
+	img, _, err := pullBundle(ctx, c, namespace, image, skipCertVerification, reqLogger)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to pull image %v: %v", image, err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	maxFileSize, _ := getArchiveLimits()
+	path = strings.TrimPrefix(path, "/")
+	for i := len(manifest.Layers) - 1; i >= 0; i-- {
+		desc := manifest.Layers[i]
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		if desc.Annotations[layerAnnotationTitle] == path {
+			if desc.Size > maxFileSize {
+				return nil, fmt.Errorf("File %v in image %v is %v bytes, which is larger than the limit of %v bytes", path, image, desc.Size, maxFileSize)
+			}
+			rc, err := layer.Compressed()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return readBytesFromReader(desc.Size, rc)
+		}
+
+		// A layer holding another single file is not a tar file.
+		if _, isFile := desc.Annotations[layerAnnotationTitle]; isFile {
+			continue
+		}
+
+		b, found, err := readLayerFile(layer, path, maxFileSize)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading layer %v of image %v: %v", desc.Digest, image, err)
+		}
+		if found {
+			return b, nil
+		}
+	}
+
+	return nil, fmt.Errorf("File %v was not found in image %v", path, image)
+}
+
+// Returns the content of the file at the input path in the tar file of a layer.
+func readLayerFile(layer v1.Layer, path string, maxFileSize int64) ([]byte, bool, error) {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return nil, false, err
+	}
+	defer rc.Close()
+
+	tarReader := tar.NewReader(rc)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if strings.TrimPrefix(header.Name, "./") != path || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxFileSize {
+			return nil, false, fmt.Errorf("File %v is %v bytes, which is larger than the limit of %v bytes", header.Name, header.Size, maxFileSize)
+		}
+		b, err := readBytesFromReader(header.Size, tarReader)
+		return b, err == nil, err
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/authn"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Test that Tekton bundle pipelines are tracked by their oci:// URL.
+func TestBundlePipelineUrl(t *testing.T) {
+	pipeline := kabanerov1alpha2.PipelineSpec{
+		Id:     "java-pipelines",
+		Sha256: "8080076acd8f54ecbb7de132df148d964e5e93921cce983a0f781418b0871573",
+		Https:  kabanerov1alpha2.HttpsProtocolFile{Url: "https://example.com/pipelines.tar.gz"},
+		Oci:    kabanerov1alpha2.OciBundleSpec{Bundle: "quay.io/kabanero/java-pipelines:0.9.0", SkipCertVerification: true},
+	}
+
+	if url := pipeline.ArchiveUrl(); url != "oci://quay.io/kabanero/java-pipelines:0.9.0" {
+		t.Errorf("Unexpected bundle URL: %v", url)
+	}
+	if !pipeline.SkipCertVerification() {
+		t.Error("Expected the certificate verification of the bundle to be skipped")
+	}
+	if !isBundlePipeline(kabanerov1alpha2.PipelineStatus{Url: pipeline.ArchiveUrl()}) {
+		t.Error("Expected the pipeline to be pulled as a bundle")
+	}
+
+	pipeline.Oci = kabanerov1alpha2.OciBundleSpec{}
+	if url := pipeline.ArchiveUrl(); url != "https://example.com/pipelines.tar.gz" {
+		t.Errorf("Unexpected archive URL: %v", url)
+	}
+	if isBundlePipeline(kabanerov1alpha2.PipelineStatus{Url: pipeline.ArchiveUrl()}) {
+		t.Error("Expected the pipeline not to be pulled as a bundle")
+	}
+}
+
+// Test that a bundle that can not be pulled is reported as unavailable, and that the
+// registry authenticator is asked for credentials.
+func TestGetManifestsBundleUnavailable(t *testing.T) {
+	defer SetRegistryAuthenticator(nil)
+
+	registries := []string{}
+	SetRegistryAuthenticator(func(c client.Reader, namespace string, imgRegistry string, logger logr.Logger) ([]authn.Authenticator, error) {
+		registries = append(registries, imgRegistry)
+		return []authn.Authenticator{authn.Anonymous}, nil
+	})
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	pipelineStatus := kabanerov1alpha2.PipelineStatus{
+		Name:   "java-pipelines",
+		Url:    kabanerov1alpha2.OciBundleUrlPrefix + registry + "/kabanero/java-pipelines:0.9.0",
+		Digest: basicPipeline.sha256,
+	}
+	_, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{}, true, nil, logf.NullLogger{})
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveUnavailable {
+		t.Errorf("Expected reason %v, but found %v (%v)", kabanerov1alpha2.StackReasonArchiveUnavailable, reason, err)
+	}
+	if len(registries) != 1 || registries[0] != registry {
+		t.Errorf("Expected the credentials of registry %v to be requested, but found %v", registry, registries)
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Cache-Control directives that the HTTP cache honors.
+type cacheControl struct {
+	noStore   bool
+	noCache   bool
+	maxAge    time.Duration
+	hasMaxAge bool
+}
+
+// Parses the Cache-Control headers of a response.  Unknown directives are ignored.
+func parseCacheControl(header http.Header) cacheControl {
+	var cc cacheControl
+	for _, value := range header["Cache-Control"] {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			switch {
+			case directive == "no-store":
+				cc.noStore = true
+			case directive == "no-cache":
+				cc.noCache = true
+			case strings.HasPrefix(directive, "max-age="):
+				seconds, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(directive, "max-age="), `"`), 10, 64)
+				if err == nil && seconds >= 0 {
+					cc.maxAge = time.Duration(seconds) * time.Second
+					cc.hasMaxAge = true
+				}
+			}
+		}
+	}
+	return cc
+}
+
+// Returns true if a response with the input headers may be stored in the cache.  The
+// response must not forbid it, and must carry an ETag or Last-Modified header that later
+// requests can be validated with.
+func isCacheable(header http.Header) bool {
+	if parseCacheControl(header).noStore {
+		return false
+	}
+	return len(header.Get("ETag")) > 0 || len(header.Get("Last-Modified")) > 0
+}
+
+// Returns the time until which a response with the input headers, received at the input
+// time, may be used without validating it with the server.  The zero time is returned
+// if the response must always be validated.
+func expiresAt(header http.Header, received time.Time) time.Time {
+	cc := parseCacheControl(header)
+	if cc.noCache || !cc.hasMaxAge || cc.maxAge == 0 {
+		return time.Time{}
+	}
+	return received.Add(cc.maxAge)
+}
+
+// Returns the value to send in the If-Modified-Since header when validating the entry.
+// This is the Last-Modified time sent by the server or, if there was none, the date of
+// the response.
+func (v cacheValue) validationDate() string {
+	if len(v.lastModified) > 0 {
+		return v.lastModified
+	}
+	return v.date
+}
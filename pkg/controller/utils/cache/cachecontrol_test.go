@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const lastModified = "Wed, 21 Oct 2015 07:28:00 GMT"
+
+// HTTP handler that sends the input Cache-Control and Last-Modified headers, but no etag.
+type CacheControlHandler struct {
+	cacheControl string
+	lastModified string
+	requests     *int32
+	cacheHits    *int32
+}
+
+func (ch CacheControlHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	*(ch.requests) += 1
+	if len(ch.lastModified) > 0 && req.Header.Get("If-Modified-Since") == ch.lastModified {
+		// Indicate the resource has not changed.
+		rw.WriteHeader(http.StatusNotModified)
+		*(ch.cacheHits) += 1
+		return
+	}
+	if len(ch.cacheControl) > 0 {
+		rw.Header().Add("Cache-Control", ch.cacheControl)
+	}
+	if len(ch.lastModified) > 0 {
+		rw.Header().Add("Last-Modified", ch.lastModified)
+	}
+	rw.Write([]byte(theResponse))
+}
+
+// Get the page from the input server twice, checking the response each time.
+func getTwice(t *testing.T, url string) {
+	for i := 0; i < 2; i++ {
+		data, err := GetFromCache(context.Background(), httpCacheTestClient{}, url, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare([]byte(theResponse), data) != 0 {
+			t.Fatalf("Response %v not correct", i+1)
+		}
+	}
+}
+
+// Show that a page with a Last-Modified header and no etag is cached, and
+// validated with If-Modified-Since.
+func TestCacheLastModified(t *testing.T) {
+	var requests, cacheHits int32
+	server := httptest.NewServer(CacheControlHandler{lastModified: lastModified, requests: &requests, cacheHits: &cacheHits})
+	defer server.Close()
+
+	getTwice(t, server.URL)
+	if cacheHits != 1 {
+		t.Fatalf("Wrong number of cache hits: %v", cacheHits)
+	}
+}
+
+// Show that a fresh page is used without asking the server again.
+func TestCacheMaxAge(t *testing.T) {
+	var requests, cacheHits int32
+	server := httptest.NewServer(CacheControlHandler{cacheControl: "public, max-age=3600", lastModified: lastModified, requests: &requests, cacheHits: &cacheHits})
+	defer server.Close()
+
+	getTwice(t, server.URL)
+	if requests != 1 {
+		t.Fatalf("Wrong number of requests: %v", requests)
+	}
+}
+
+// Show that a page the server says not to store is not cached.
+func TestCacheNoStore(t *testing.T) {
+	var requests, cacheHits int32
+	server := httptest.NewServer(CacheControlHandler{cacheControl: "no-store", lastModified: lastModified, requests: &requests, cacheHits: &cacheHits})
+	defer server.Close()
+
+	getTwice(t, server.URL)
+	if requests != 2 || cacheHits != 0 {
+		t.Fatalf("Wrong number of requests (%v) or cache hits (%v)", requests, cacheHits)
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		cacheControl string
+		expires      time.Time
+	}{
+		{"", time.Time{}},
+		{"max-age=60", now.Add(time.Minute)},
+		{"public, max-age=\"60\"", now.Add(time.Minute)},
+		{"max-age=60, no-cache", time.Time{}},
+		{"max-age=0", time.Time{}},
+		{"max-age=-1", time.Time{}},
+	}
+	for _, test := range tests {
+		header := http.Header{}
+		header.Set("Cache-Control", test.cacheControl)
+		if expires := expiresAt(header, now); !expires.Equal(test.expires) {
+			t.Errorf("Expected %v for Cache-Control %q, but found %v", test.expires, test.cacheControl, expires)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The environment variable naming the directory that HTTP cache entries are saved to.
+// The directory is usually an emptyDir or persistent volume mount, so that the cache
+// survives operator restarts.  If it is not set, the cache is kept in memory only.
+const DiskCacheDirEnv = "KABANERO_HTTP_CACHE_DIR"
+
+// File name suffixes of the two files that hold a cache entry.
+const (
+	diskCacheMetaSuffix = ".json"
+	diskCacheBodySuffix = ".body"
+)
+
+// The directory cache entries are saved to.  Empty if the cache is kept in memory only.
+// Guarded by the cache lock.
+var diskCacheDir string
+
+// The metadata of a cache entry saved to disk.  The digest and size of the body are
+// checked when the entry is loaded, so that a partially written or corrupted entry is
+// not used.
+type diskCacheMeta struct {
+	Url          string    `json:"url"`
+	Etag         string    `json:"etag"`
+	Date         string    `json:"date"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Expires      time.Time `json:"expires,omitempty"`
+	Sha256       string    `json:"sha256"`
+	Size         int       `json:"size"`
+}
+
+// Saves HTTP cache entries to the input directory, and loads the entries already saved
+// there into the cache.  Entries that fail their integrity check are removed.
+func SetDiskCacheDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("Unable to create the HTTP cache directory %v: %v", dir, err)
+	}
+
+	// Remove the files of writes that were interrupted.
+	tmpFiles, err := filepath.Glob(filepath.Join(dir, "*.tmp*"))
+	if err != nil {
+		return err
+	}
+	for _, tmpFile := range tmpFiles {
+		os.Remove(tmpFile)
+	}
+
+	metaFiles, err := filepath.Glob(filepath.Join(dir, "*"+diskCacheMetaSuffix))
+	if err != nil {
+		return err
+	}
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	diskCacheDir = dir
+	loaded := 0
+	for _, metaFile := range metaFiles {
+		url, value, err := loadDiskCacheEntry(metaFile)
+		if err != nil {
+			cachelog.Info(fmt.Sprintf("Discarding HTTP cache entry %v: %v", metaFile, err))
+			removeDiskCacheFiles(strings.TrimSuffix(metaFile, diskCacheMetaSuffix))
+			continue
+		}
+		httpCache[url] = value
+		loaded++
+	}
+
+	if loaded > 0 {
+		cachelog.Info(fmt.Sprintf("Loaded %v entries into the HTTP cache from %v", loaded, dir))
+	}
+	evictCache()
+	updateCacheSizeMetrics()
+	return nil
+}
+
+// Reads a cache entry, and checks its body against the digest and size in its metadata.
+// The entry is treated as just used, so that it is kept for a full purge period.
+func loadDiskCacheEntry(metaFile string) (string, cacheValue, error) {
+	metaBytes, err := ioutil.ReadFile(metaFile)
+	if err != nil {
+		return "", cacheValue{}, err
+	}
+	var meta diskCacheMeta
+	if err := json.Unmarshal(metaBytes, &meta); err != nil {
+		return "", cacheValue{}, err
+	}
+	if filepath.Base(metaFile) != diskCacheFileName(meta.Url)+diskCacheMetaSuffix {
+		return "", cacheValue{}, fmt.Errorf("The entry is for URL %v, which does not match its file name", meta.Url)
+	}
+
+	body, err := ioutil.ReadFile(strings.TrimSuffix(metaFile, diskCacheMetaSuffix) + diskCacheBodySuffix)
+	if err != nil {
+		return "", cacheValue{}, err
+	}
+	sum := sha256.Sum256(body)
+	if len(body) != meta.Size || hex.EncodeToString(sum[:]) != meta.Sha256 {
+		return "", cacheValue{}, fmt.Errorf("The body does not match its digest or size")
+	}
+
+	return meta.Url, cacheValue{etag: meta.Etag, date: meta.Date, lastModified: meta.LastModified, expires: meta.Expires, body: body, lastUsed: time.Now()}, nil
+}
+
+// A cache entry to be saved to disk.  The entry is captured while the cache lock is held,
+// and written once the lock is released, so that other requests do not wait on the disk.
+type diskCacheEntry struct {
+	url  string
+	base string
+	body []byte
+	meta []byte
+}
+
+// Captures a cache entry to be saved to disk.  Returns nil if the cache is kept in memory
+// only.  The cache lock must be held.
+func snapshotDiskCacheEntry(url string, value cacheValue) *diskCacheEntry {
+	if len(diskCacheDir) == 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256(value.body)
+	meta, err := json.Marshal(diskCacheMeta{Url: url, Etag: value.etag, Date: value.date, LastModified: value.lastModified, Expires: value.expires, Sha256: hex.EncodeToString(sum[:]), Size: len(value.body)})
+	if err != nil {
+		cachelog.Error(err, "Unable to save HTTP cache entry "+url)
+		return nil
+	}
+
+	return &diskCacheEntry{url: url, base: filepath.Join(diskCacheDir, diskCacheFileName(url)), body: value.body, meta: meta}
+}
+
+// Saves a cache entry to disk.  The body is written before the metadata, and each file is
+// renamed into place once written, so that an interrupted write leaves no usable entry.
+// Concurrent writes of the same entry can mix the files of each, which the digest check
+// catches when the entry is loaded.  The cache lock must not be held.
+func saveDiskCacheEntry(entry *diskCacheEntry) {
+	if entry == nil {
+		return
+	}
+
+	if err := writeFileAtomic(entry.base+diskCacheBodySuffix, entry.body); err != nil {
+		cachelog.Error(err, "Unable to save HTTP cache entry "+entry.url)
+		removeDiskCacheFiles(entry.base)
+		return
+	}
+	if err := writeFileAtomic(entry.base+diskCacheMetaSuffix, entry.meta); err != nil {
+		cachelog.Error(err, "Unable to save HTTP cache entry "+entry.url)
+		removeDiskCacheFiles(entry.base)
+		return
+	}
+
+	// The entry may have been evicted or purged while it was written.  Remove it again, so
+	// that it is not loaded after a restart.
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if _, found := httpCache[entry.url]; !found {
+		removeDiskCacheFiles(entry.base)
+	}
+}
+
+// Removes a cache entry from disk.  The cache lock must be held.
+func deleteDiskCacheEntry(url string) {
+	if len(diskCacheDir) == 0 {
+		return
+	}
+	removeDiskCacheFiles(filepath.Join(diskCacheDir, diskCacheFileName(url)))
+}
+
+// Removes the files of the cache entry with the input path, without the suffix.  The
+// metadata goes first, so that the entry is no longer loaded even if the body remains.
+func removeDiskCacheFiles(base string) {
+	for _, suffix := range []string{diskCacheMetaSuffix, diskCacheBodySuffix} {
+		if err := os.Remove(base + suffix); err != nil && !os.IsNotExist(err) {
+			cachelog.Error(err, "Unable to remove HTTP cache file "+base+suffix)
+		}
+	}
+}
+
+// Returns the name of the files of the cache entry for the input URL.  URLs are hashed
+// since they may not be valid file names.
+func diskCacheFileName(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// Writes a file to a temporary name in the same directory, and renames it into place.
+func writeFileAtomic(name string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Forgets the in-memory cache, as an operator restart would.
+func resetCache() {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	httpCache = make(map[string]cacheValue)
+	diskCacheDir = ""
+}
+
+// Show that cached pages are saved to disk, and loaded back after a restart.
+func TestDiskCacheWarmStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer resetCache()
+
+	var cacheHits int32 = 0
+	server := httptest.NewServer(CacheHandler{etag: "DISK1", cacheHits: &cacheHits})
+	defer server.Close()
+
+	resetCache()
+	if err := SetDiskCacheDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	base := filepath.Join(dir, diskCacheFileName(server.URL))
+	if _, err := os.Stat(base + diskCacheMetaSuffix); err != nil {
+		t.Fatalf("Expected the cache entry to be saved: %v", err)
+	}
+
+	// Restart, and make sure the first request after it is answered from the cache.
+	resetCache()
+	if err := SetDiskCacheDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare([]byte(theResponse), data) != 0 {
+		t.Fatal("Response not correct")
+	}
+	if cacheHits != 1 {
+		t.Fatalf("Wrong number of cache hits: %v", cacheHits)
+	}
+}
+
+// Show that an entry whose body does not match its digest is discarded.
+func TestDiskCacheCorruptEntry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer resetCache()
+
+	url := "https://example.com/index.yaml"
+	resetCache()
+	if err := SetDiskCacheDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	value := cacheValue{etag: "ABCDE", date: "GarbageDate", body: []byte(theResponse)}
+	cacheLock.Lock()
+	httpCache[url] = value
+	entry := snapshotDiskCacheEntry(url, value)
+	cacheLock.Unlock()
+	saveDiskCacheEntry(entry)
+
+	base := filepath.Join(dir, diskCacheFileName(url))
+	if err := ioutil.WriteFile(base+diskCacheBodySuffix, []byte(theResponse2), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resetCache()
+	if err := SetDiskCacheDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	cacheLock.Lock()
+	_, found := httpCache[url]
+	cacheLock.Unlock()
+	if found {
+		t.Fatal("Expected the corrupted entry not to be loaded")
+	}
+	if _, err := os.Stat(base + diskCacheMetaSuffix); !os.IsNotExist(err) {
+		t.Fatalf("Expected the corrupted entry to be removed: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The name of the OpenShift cluster Proxy resource.  Its status holds the proxy the
+// cluster's own components use, with the cluster's internal networks added to noProxy.
+const clusterProxyName = "cluster"
+
+// A forward proxy that requests leaving the cluster are sent through.
+type egressProxy struct {
+	httpProxy  *url.URL
+	httpsProxy *url.URL
+	noProxy    []string
+}
+
+// The egress proxy used by every transport made with NewTransport.
+var egress = struct {
+	sync.Mutex
+	proxy egressProxy
+}{}
+
+// Sets the egress proxy from the input spec.  If no field of the spec is set, the proxy
+// of the OpenShift cluster Proxy resource is used.  On clusters without a Proxy
+// resource, requests are sent directly.
+func SetEgressProxy(ctx context.Context, c client.Reader, spec kabanerov1alpha2.EgressProxySpec) error {
+	if len(spec.HttpProxy) == 0 && len(spec.HttpsProxy) == 0 && len(spec.NoProxy) == 0 {
+		clusterSpec, err := getClusterProxy(ctx, c)
+		if err != nil {
+			return err
+		}
+		spec = clusterSpec
+	}
+
+	proxy, err := parseEgressProxy(spec)
+	if err != nil {
+		return err
+	}
+
+	egress.Lock()
+	defer egress.Unlock()
+	egress.proxy = proxy
+	return nil
+}
+
+// Reads the effective proxy settings of the OpenShift cluster Proxy resource.  Returns
+// an empty spec if the cluster has none.
+func getClusterProxy(ctx context.Context, c client.Reader) (kabanerov1alpha2.EgressProxySpec, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{
+		Kind:    "Proxy",
+		Group:   "config.openshift.io",
+		Version: "v1",
+	})
+	err := c.Get(ctx, client.ObjectKey{Name: clusterProxyName}, u)
+	if err != nil {
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return kabanerov1alpha2.EgressProxySpec{}, nil
+		}
+		return kabanerov1alpha2.EgressProxySpec{}, fmt.Errorf("Unable to retrieve the cluster Proxy resource %v: %v", clusterProxyName, err)
+	}
+
+	spec := kabanerov1alpha2.EgressProxySpec{}
+	spec.HttpProxy, _, _ = unstructured.NestedString(u.Object, "status", "httpProxy")
+	spec.HttpsProxy, _, _ = unstructured.NestedString(u.Object, "status", "httpsProxy")
+	spec.NoProxy, _, _ = unstructured.NestedString(u.Object, "status", "noProxy")
+	return spec, nil
+}
+
+// Validates the proxy URLs of the input spec, and splits its noProxy list.
+func parseEgressProxy(spec kabanerov1alpha2.EgressProxySpec) (egressProxy, error) {
+	proxy := egressProxy{}
+	var err error
+	if proxy.httpProxy, err = parseProxyUrl(spec.HttpProxy); err != nil {
+		return egressProxy{}, err
+	}
+	if proxy.httpsProxy, err = parseProxyUrl(spec.HttpsProxy); err != nil {
+		return egressProxy{}, err
+	}
+	for _, entry := range strings.Split(spec.NoProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if len(entry) != 0 {
+			proxy.noProxy = append(proxy.noProxy, entry)
+		}
+	}
+	return proxy, nil
+}
+
+// Parses a proxy URL.  A URL without a scheme is an http proxy, as with HTTP_PROXY.
+func parseProxyUrl(proxyUrl string) (*url.URL, error) {
+	if len(proxyUrl) == 0 {
+		return nil, nil
+	}
+	if !strings.Contains(proxyUrl, "://") {
+		proxyUrl = "http://" + proxyUrl
+	}
+	u, err := url.Parse(proxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("The egress proxy URL %v is not valid: %v", proxyUrl, err)
+	}
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("The egress proxy URL %v is not valid. It must contain a host.", proxyUrl)
+	}
+	return u, nil
+}
+
+// Returns the proxy the input request is sent through, or nil if it is sent directly.
+func egressProxyForRequest(req *http.Request) (*url.URL, error) {
+	egress.Lock()
+	proxy := egress.proxy
+	egress.Unlock()
+
+	var proxyUrl *url.URL
+	switch req.URL.Scheme {
+	case "http":
+		proxyUrl = proxy.httpProxy
+	case "https":
+		proxyUrl = proxy.httpsProxy
+	}
+	if proxyUrl == nil || proxy.bypass(req.URL) {
+		return nil, nil
+	}
+	return proxyUrl, nil
+}
+
+// Returns true if the input URL is connected to directly.  The loopback addresses
+// always are.  Otherwise the host is matched against each noProxy entry: a host or
+// domain, which also matches its subdomains, an IP address or a CIDR, any of which may
+// be limited to a port.
+func (p egressProxy) bypass(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if len(port) == 0 {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+
+	ip := net.ParseIP(host)
+	if host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+
+	for _, entry := range p.noProxy {
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost := entry
+		if h, entryPort, err := net.SplitHostPort(entry); err == nil {
+			if entryPort != port {
+				continue
+			}
+			entryHost = h
+		}
+		if entryIp := net.ParseIP(entryHost); entryIp != nil {
+			if ip != nil && ip.Equal(entryIp) {
+				return true
+			}
+			continue
+		}
+
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
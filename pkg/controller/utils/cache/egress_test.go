@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Forgets the egress proxy, so that requests are sent directly.
+func resetEgressProxy() {
+	egress.Lock()
+	defer egress.Unlock()
+	egress.proxy = egressProxy{}
+}
+
+// HTTP handler that acts as a forward proxy, answering every request itself.
+type ForwardProxyHandler struct {
+	requested *string
+}
+
+func (fh ForwardProxyHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	*(fh.requested) = req.URL.String()
+	rw.Write([]byte(theResponse))
+}
+
+// Show that downloads are sent through the egress proxy.
+func TestEgressProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(ForwardProxyHandler{requested: &requested})
+	defer proxy.Close()
+	defer resetEgressProxy()
+
+	err := SetEgressProxy(context.Background(), httpCacheTestClient{}, kabanerov1alpha2.EgressProxySpec{HttpProxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The host does not exist, so the download only works if it goes thru the proxy.
+	target := "http://index.example.invalid/index.yaml"
+	data, err := GetFromCache(context.Background(), httpCacheTestClient{}, target, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare([]byte(theResponse), data) != 0 {
+		t.Fatal("Response not correct")
+	}
+	if requested != target {
+		t.Fatalf("Expected the proxy to be asked for %v, but it was asked for %v", target, requested)
+	}
+}
+
+func TestEgressProxyBypass(t *testing.T) {
+	proxy, err := parseEgressProxy(kabanerov1alpha2.EgressProxySpec{
+		HttpsProxy: "proxy.example.com:3128",
+		NoProxy:    ".cluster.local, internal.example.com,10.0.0.0/8, 192.168.1.1,registry.example.com:5000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxy.httpsProxy.String() != "http://proxy.example.com:3128" {
+		t.Fatalf("Proxy URL not correct: %v", proxy.httpsProxy)
+	}
+
+	tests := []struct {
+		url    string
+		bypass bool
+	}{
+		{"https://github.com/kabanero-io/stacks", false},
+		{"https://localhost/index.yaml", true},
+		{"https://127.0.0.1:8443/index.yaml", true},
+		{"https://svc.ns.svc.cluster.local/index.yaml", true},
+		{"https://internal.example.com/index.yaml", true},
+		{"https://repo.internal.example.com/index.yaml", true},
+		{"https://notinternal.example.com/index.yaml", false},
+		{"https://10.1.2.3/index.yaml", true},
+		{"https://11.1.2.3/index.yaml", false},
+		{"https://192.168.1.1/index.yaml", true},
+		{"https://registry.example.com:5000/v2/", true},
+		{"https://registry.example.com/v2/", false},
+	}
+	for _, test := range tests {
+		u, _ := url.Parse(test.url)
+		if bypass := proxy.bypass(u); bypass != test.bypass {
+			t.Errorf("Expected bypass %v for %v, but found %v", test.bypass, test.url, bypass)
+		}
+	}
+
+	// Everything bypasses a proxy with a noProxy of *.
+	proxy, _ = parseEgressProxy(kabanerov1alpha2.EgressProxySpec{HttpsProxy: "http://proxy.example.com:3128", NoProxy: "*"})
+	u, _ := url.Parse("https://github.com")
+	if !proxy.bypass(u) {
+		t.Error("Expected every host to bypass the proxy")
+	}
+}
+
+func TestEgressProxyNotValid(t *testing.T) {
+	if _, err := parseEgressProxy(kabanerov1alpha2.EgressProxySpec{HttpProxy: "http://"}); err == nil {
+		t.Fatal("Expected a proxy URL without a host to be rejected")
+	}
+}
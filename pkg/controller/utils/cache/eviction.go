@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The default limits of the HTTP cache, used when the Kabanero instance does not specify them.
+const (
+	DefaultMaxCacheSize    int64 = 256 * 1024 * 1024
+	DefaultMaxCacheEntries       = 500
+)
+
+// The limits of the HTTP cache.  Guarded by the cache lock.
+var (
+	maxCacheSize    = DefaultMaxCacheSize
+	maxCacheEntries = DefaultMaxCacheEntries
+)
+
+func init() {
+	httpCacheMaxBytes.Set(float64(DefaultMaxCacheSize))
+	httpCacheMaxEntries.Set(float64(DefaultMaxCacheEntries))
+}
+
+// Sets the maximum total size, in bytes, and number of entries of the HTTP cache.  A value
+// of zero or less selects the default.  If the cache is over the new limits, the least
+// recently used entries are evicted right away.
+func SetCacheLimits(maxSize int64, maxEntries int) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxCacheSize
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxCacheEntries
+	}
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if maxSize == maxCacheSize && maxEntries == maxCacheEntries {
+		return
+	}
+	maxCacheSize = maxSize
+	maxCacheEntries = maxEntries
+	httpCacheMaxBytes.Set(float64(maxSize))
+	httpCacheMaxEntries.Set(float64(maxEntries))
+
+	evictCache()
+	updateCacheSizeMetrics()
+}
+
+// Returns the limits of the HTTP cache: the total size, in bytes, and the number of entries.
+func GetCacheLimits() (int64, int) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	return maxCacheSize, maxCacheEntries
+}
+
+// Returns the approximate memory used by a cache entry.
+func cacheEntrySize(key string, value cacheValue) int64 {
+	return int64(len(key) + len(value.etag) + len(value.date) + len(value.lastModified) + len(value.body))
+}
+
+// Returns true if an entry of the input size can be stored without evicting every other
+// entry.  The cache lock must be held.
+func fitsInCache(size int64) bool {
+	return size <= maxCacheSize
+}
+
+// Evicts the least recently used entries until the cache is within its limits.  The cache
+// lock must be held.
+func evictCache() {
+	var size int64
+	for key, value := range httpCache {
+		size += cacheEntrySize(key, value)
+	}
+	if len(httpCache) <= maxCacheEntries && size <= maxCacheSize {
+		return
+	}
+
+	keys := make([]string, 0, len(httpCache))
+	for key := range httpCache {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return httpCache[keys[i]].lastUsed.Before(httpCache[keys[j]].lastUsed)
+	})
+
+	for _, key := range keys {
+		if len(httpCache) <= maxCacheEntries && size <= maxCacheSize {
+			break
+		}
+		size -= cacheEntrySize(key, httpCache[key])
+		delete(httpCache, key)
+		deleteDiskCacheEntry(key)
+		httpCacheEvicted.Inc()
+		cachelog.Info(fmt.Sprintf("Evicted from cache: %v", key))
+	}
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// Adds an entry to the cache, last used the input time ago.
+func addCacheEntry(key string, body string, age time.Duration) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	httpCache[key] = cacheValue{body: []byte(body), lastUsed: time.Now().Add(-age)}
+}
+
+func isCached(key string) bool {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	_, found := httpCache[key]
+	return found
+}
+
+// Show that the least recently used entries are evicted when there are too many.
+func TestEvictCacheEntries(t *testing.T) {
+	resetCache()
+	defer resetCache()
+	defer SetCacheLimits(DefaultMaxCacheSize, DefaultMaxCacheEntries)
+
+	addCacheEntry("oldest", "a", 3*time.Minute)
+	addCacheEntry("older", "b", 2*time.Minute)
+	addCacheEntry("newest", "c", time.Minute)
+
+	SetCacheLimits(0, 2)
+	if isCached("oldest") {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if !isCached("older") || !isCached("newest") {
+		t.Error("Expected the most recently used entries to be kept")
+	}
+}
+
+// Show that the least recently used entries are evicted when the cache is too large.
+func TestEvictCacheSize(t *testing.T) {
+	resetCache()
+	defer resetCache()
+	defer SetCacheLimits(DefaultMaxCacheSize, DefaultMaxCacheEntries)
+
+	addCacheEntry("a", "0123456789", 3*time.Minute)
+	addCacheEntry("b", "0123456789", 2*time.Minute)
+	addCacheEntry("c", "0123456789", time.Minute)
+
+	// Each entry is 11 bytes, with its key.
+	SetCacheLimits(25, 0)
+	if isCached("a") {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if !isCached("b") || !isCached("c") {
+		t.Error("Expected the most recently used entries to be kept")
+	}
+
+	// An entry that is larger than the whole cache is not stored.
+	cacheLock.Lock()
+	fits := fitsInCache(26)
+	cacheLock.Unlock()
+	if fits {
+		t.Error("Expected an entry larger than the cache not to fit")
+	}
+}
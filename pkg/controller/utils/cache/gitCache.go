@@ -3,7 +3,6 @@ package cache
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"sync"
 	"time"
@@ -13,7 +12,6 @@ import (
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/secret"
-	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	rlog "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -41,32 +39,52 @@ const gitTickerDuration = 30 * time.Minute
 // Mutex for concurrent map access
 var gitCacheLock sync.Mutex
 
-// Retrieves a stack index file content using GitHub APIs
-func GetStackDataUsingGit(c client.Client, gitRelease kabanerov1alpha2.GitReleaseInfo, skipCertVerification bool, namespace string, reqLogger logr.Logger) ([]byte, error) {
+// Retrieves a stack index file content using GitHub APIs.  If a proxy is specified, the
+// GitHub API requests and the asset download are routed through it.  The requests are
+// abandoned when the input context is done, or when they run longer than the download
+// timeouts allow.
+func GetStackDataUsingGit(ctx context.Context, c client.Reader, gitRelease kabanerov1alpha2.GitReleaseInfo, skipCertVerification bool, namespace string, proxy *ArtifactProxy, reqLogger logr.Logger) ([]byte, error) {
+	// Wait for our turn.  The Github API requests count as part of the download.
+	done := downloads.acquire(gitRelease.Hostname)
+	defer done()
+
+	ctx, cancel := withDownloadTimeout(ctx)
+	defer cancel()
 
 	// Get a Github client.
-	gclient, err := getGitClient(c, gitRelease, skipCertVerification, namespace, reqLogger)
+	gclient, err := getGitClient(c, gitRelease, skipCertVerification, namespace, proxy, reqLogger)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get the release tagged in Github as repoConf.GitRelease.Release.
-	release, response, err := gclient.Repositories.GetReleaseByTag(context.Background(), gitRelease.Organization, gitRelease.Project, gitRelease.Release)
+	release, response, err := gclient.Repositories.GetReleaseByTag(ctx, gitRelease.Organization, gitRelease.Project, gitRelease.Release)
 	if err != nil || response.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("Unable to retrieve object representing Github repository release %v. Configured GitRelease data: %v. Error: %v", gitRelease.Release, gitRelease, err)
 	}
 
-	return getReleaseAsset(gclient, release.Assets, gitRelease)
+	// The asset download is redirected to a different host, which must also go thru the proxy.
+	redirectClient := &http.Client{Transport: NewTransport(nil)}
+	if proxy != nil {
+		redirectClient = &http.Client{Transport: proxy.Transport()}
+	}
+
+	return getReleaseAsset(ctx, gclient, release.Assets, gitRelease, redirectClient)
 }
 
 // Retrieves a Git client.
-func getGitClient(c client.Client, gitRelease kabanerov1alpha2.GitReleaseInfo, skipCertVerification bool, namespace string, reqLogger logr.Logger) (*github.Client, error) {
+func getGitClient(c client.Reader, gitRelease kabanerov1alpha2.GitReleaseInfo, skipCertVerification bool, namespace string, proxy *ArtifactProxy, reqLogger logr.Logger) (*github.Client, error) {
 	var client *github.Client
 
 	// Ignore the error that may come back from GetTLSConfig, and use the
 	// default TLS config.
-	tlsConfig, _ := GetTLSCConfig(c, skipCertVerification, gitCachelog)
-	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	var transport http.RoundTripper
+	if proxy != nil {
+		transport = proxy.Transport()
+	} else {
+		tlsConfig, _ := GetTLSCConfig(c, skipCertVerification, gitCachelog)
+		transport = NewTransport(tlsConfig)
+	}
 
 	// Search all secrets under the given namespace for the one containing the required hostname.
 	annotationKey := "kabanero.io/git-"
@@ -106,7 +124,7 @@ func getGitClient(c client.Client, gitRelease kabanerov1alpha2.GitReleaseInfo, s
 	return client, nil
 }
 
-func getReleaseAsset(gclient *github.Client, assets []github.ReleaseAsset, gitRelease kabanerov1alpha2.GitReleaseInfo) ([]byte, error) {
+func getReleaseAsset(ctx context.Context, gclient *github.Client, assets []github.ReleaseAsset, gitRelease kabanerov1alpha2.GitReleaseInfo, redirectClient *http.Client) ([]byte, error) {
 	var indexBytes []byte
 
 	// Find the asset identified as repoConf.GitRelease.AssetName and download it.
@@ -119,13 +137,13 @@ func getReleaseAsset(gclient *github.Client, assets []github.ReleaseAsset, gitRe
 			cacheData, found := gitCache[path]
 			gitCacheLock.Unlock()
 			if found && isAssetUnchanged(cacheData, asset) {
-				gitCachelog.Info(fmt.Sprintf("Git data retrieved from cache. The data is associated with gitRelease containing: %v", path))
+				contextLogger(ctx, gitCachelog).Info(fmt.Sprintf("Git data retrieved from cache. The data is associated with gitRelease containing: %v", path))
 				cacheData.lastUsed = time.Now()
 				return cacheData.data, nil
 			}
 
 			// The asset is being read for the first time or it was modified and is being read again.
-			indexBytes, err := downloadReleaseAsset(gclient, gitRelease, asset)
+			indexBytes, err := downloadReleaseAsset(ctx, gclient, gitRelease, asset, redirectClient)
 			if err != nil {
 				return nil, err
 			}
@@ -133,11 +151,8 @@ func getReleaseAsset(gclient *github.Client, assets []github.ReleaseAsset, gitRe
 			// Add downloaded data to cache if the data needed for caching is present.
 			gitCacheLock.Lock()
 			if asset.GetID() != 0 && (asset.GetCreatedAt() != github.Timestamp{}) && (asset.GetSize() != 0) {
-				startPurgeTicker.Do(func() {
-					timer.ScheduleWork(gitTickerDuration, gitCachelog, gitPurgeCache, gitPurgeDuration)
-				})
 				gitCache[path] = gitCacheData{assetId: asset.GetID(), creationTime: asset.GetCreatedAt().Time, size: asset.GetSize(), data: indexBytes, lastUsed: time.Now()}
-				gitCachelog.Info(fmt.Sprintf("Git data cached. The data is associated with gitRelease containing: %v", path))
+				contextLogger(ctx, gitCachelog).Info(fmt.Sprintf("Git data cached. The data is associated with gitRelease containing: %v", path))
 			} else {
 				delete(gitCache, path)
 			}
@@ -151,16 +166,24 @@ func getReleaseAsset(gclient *github.Client, assets []github.ReleaseAsset, gitRe
 }
 
 // Downloads a release asset.
-func downloadReleaseAsset(gclient *github.Client, gitRelease kabanerov1alpha2.GitReleaseInfo, asset github.ReleaseAsset) ([]byte, error) {
+func downloadReleaseAsset(ctx context.Context, gclient *github.Client, gitRelease kabanerov1alpha2.GitReleaseInfo, asset github.ReleaseAsset, redirectClient *http.Client) ([]byte, error) {
+	// Don't start downloading an asset that is known to be too large.
+	if limit := GetMaxDownloadSize(); int64(asset.GetSize()) > limit {
+		return nil, DownloadTooLargeError{Name: gitRelease.AssetName, Limit: limit}
+	}
+
 	// The asset is being read for the first time or was modified.
-	reader, _, err := gclient.Repositories.DownloadReleaseAsset(context.Background(), gitRelease.Organization, gitRelease.Project, asset.GetID(), http.DefaultClient)
+	reader, _, err := gclient.Repositories.DownloadReleaseAsset(ctx, gitRelease.Organization, gitRelease.Project, asset.GetID(), redirectClient)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to download release asset %v. Configured GitRelease data: %v. Error: %v", gitRelease.AssetName, gitRelease, err)
 	}
 	defer reader.Close()
 
-	indexBytes, err := ioutil.ReadAll(reader)
+	indexBytes, err := readLimited(reader, gitRelease.AssetName)
 	if err != nil {
+		if IsDownloadTooLarge(err) {
+			return nil, err
+		}
 		return nil, fmt.Errorf(fmt.Sprintf("Unable to read downloaded asset %v from request. Configured GitRelease data: %v. Error: %v", gitRelease.AssetName, gitRelease, err))
 	}
 	return indexBytes, nil
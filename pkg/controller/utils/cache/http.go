@@ -18,9 +18,9 @@ import (
 )
 
 // Retrieves a HTTP client. If the input access token is specified, an oauth2 generated http client is returned.
-// If the access token is not specified a default http client is returned. The default http client will contain
+// If the access token is not specified a default http client is returned. Either client will contain
 // the input transport if specified.
-func GetHTTPClient(accessToken []byte, transport *http.Transport) (*http.Client, error) {
+func GetHTTPClient(accessToken []byte, transport http.RoundTripper) (*http.Client, error) {
 	if accessToken != nil {
 		encodedToken := base64.StdEncoding.EncodeToString([]byte(accessToken))
 		decodedTokenBytes, err := base64.StdEncoding.DecodeString(encodedToken)
@@ -32,6 +32,9 @@ func GetHTTPClient(accessToken []byte, transport *http.Transport) (*http.Client,
 			&oauth2.Token{AccessToken: string(decodedTokenBytes)},
 		)
 		ctx := context.Background()
+		if transport != nil {
+			ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+		}
 		return oauth2.NewClient(ctx, ts), nil
 	}
 
@@ -56,7 +59,7 @@ func logIngressRouterCertError(logger logr.Logger, err error) {
 
 // Populates a TLS config struct based specified input.  Returns nil if the
 // default TLS config should be used.
-func GetTLSCConfig(c client.Client, skipCertVerify bool, logger logr.Logger) (*tls.Config, error) {
+func GetTLSCConfig(c client.Reader, skipCertVerify bool, logger logr.Logger) (*tls.Config, error) {
 	var tlsConfig *tls.Config
 	if skipCertVerify {
 		return &tls.Config{InsecureSkipVerify: skipCertVerify}, nil
@@ -87,7 +90,7 @@ func GetTLSCConfig(c client.Client, skipCertVerify bool, logger logr.Logger) (*t
 }
 
 // Retrieve the ingress operator CA cert.
-func getIngressRouterCACert(c client.Client) ([]byte, error) {
+func getIngressRouterCACert(c client.Reader) ([]byte, error) {
 	secretName := "router-ca"
 	secretNamespace := "openshift-ingress-operator"
 	caRouterSecret, err := secret.GetUnstructuredSecret(c, secretName, secretNamespace)
@@ -1,26 +1,32 @@
 package cache
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+	"golang.org/x/sync/singleflight"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	rlog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var cachelog = rlog.Log.WithName("httpcache")
 
-// Value in the cache map.  This contains the etag returned from the remote
-// server, which is used on subsequent requests to use the cached data.
+// Value in the cache map.  This contains the etag and last modified time
+// returned from the remote server, which are used on subsequent requests to
+// use the cached data.  Until the entry expires, it is used without asking
+// the remote server.
 type cacheValue struct {
-	etag     string
-	date     string
-	body     []byte
-	lastUsed time.Time
+	etag         string
+	date         string
+	lastModified string
+	expires      time.Time
+	body         []byte
+	lastUsed     time.Time
 }
 
 // The cache is stored as a map.  We are storing the value as a struct
@@ -28,9 +34,6 @@ type cacheValue struct {
 // concurrently.
 var httpCache = make(map[string]cacheValue)
 
-// Initialization mutex
-var startPurgeTicker sync.Once
-
 // The Duration at which a cache entry will be purged.
 const purgeDuration = 12 * time.Hour
 
@@ -40,10 +43,15 @@ const tickerDuration = 30 * time.Minute
 // Mutex for concurrent map access
 var cacheLock sync.Mutex
 
+// Concurrent downloads of the same resource.
+var downloadGroup singleflight.Group
+
 // Returns the requested resource, either from the cache, or from the
 // remote server.  The cache is not meant to be a "high performance" or
-// "heavily concurrent" cache.
-func GetFromCache(c client.Client, url string, skipCertVerify bool) ([]byte, error) {
+// "heavily concurrent" cache.  If a proxy is specified, the request is
+// routed through it.  The download is abandoned when the input context is
+// done, or when it runs longer than the download timeouts allow.
+func GetFromCache(ctx context.Context, c client.Reader, url string, skipCertVerify bool, proxy *ArtifactProxy) ([]byte, error) {
 
 	// Build the request.
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -51,22 +59,106 @@ func GetFromCache(c client.Client, url string, skipCertVerify bool) ([]byte, err
 		return nil, err
 	}
 
+	return getFromCache(ctx, c, req, url, skipCertVerify, proxy)
+}
+
+// Drives the input request, using the cache entry stored under the input key.
+// Identical requests that are made at the same time share a single download,
+// which runs with the context of the first request.  Each caller stops waiting
+// for it when its own context is done.
+func getFromCache(ctx context.Context, c client.Reader, req *http.Request, url string, skipCertVerify bool, proxy *ArtifactProxy) ([]byte, error) {
+	// Requests that skip certificate verification do not share downloads with
+	// those that do not.
+	key := fmt.Sprintf("%v skipCertVerify=%v", url, skipCertVerify)
+	results := downloadGroup.DoChan(key, func() (interface{}, error) {
+		return downloadToCache(ctx, c, req, url, skipCertVerify, proxy)
+	})
+
+	select {
+	case result := <-results:
+		if result.Shared {
+			httpCacheSharedDownloads.Inc()
+		}
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.([]byte), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("The download of %v was abandoned: %v", url, ctx.Err())
+	}
+}
+
+// Drives the input request, and updates the cache entry stored under the input key.  A
+// download that fails with a transient error is retried with jittered exponential
+// backoff.  Each attempt has its own download timeout.
+func downloadToCache(ctx context.Context, c client.Reader, req *http.Request, url string, skipCertVerify bool, proxy *ArtifactProxy) ([]byte, error) {
+	backoff, retryOnStatus := getDownloadRetry()
+	attempts := 0
+	var b []byte
+	var retry bool
+	err := timer.RetryOnError(backoff, func(error) bool {
+		return retry && ctx.Err() == nil
+	}, func(attempt int, err error, waitTime time.Duration) {
+		contextLogger(ctx, cachelog).Info(fmt.Sprintf("Transient error downloading %v. It will be retried in %v. Error: %v", url, waitTime, err))
+		httpCacheDownloadRetries.Inc()
+	}, func() error {
+		attempts++
+		var err error
+		b, retry, err = downloadOnce(ctx, c, req, url, skipCertVerify, proxy, retryOnStatus)
+		return err
+	})
+
+	if err != nil && attempts > 1 {
+		return nil, DownloadRetriesError{Attempts: attempts, Err: err}
+	}
+	return b, err
+}
+
+// Makes a single attempt at the input request.  Returns whether a failed attempt may
+// be retried.
+func downloadOnce(ctx context.Context, c client.Reader, req *http.Request, url string, skipCertVerify bool, proxy *ArtifactProxy, retryOnStatus []string) ([]byte, bool, error) {
+	ctx, cancel := withDownloadTimeout(ctx)
+	defer cancel()
+	req = req.Clone(ctx)
+
 	// See if the object is in the cache.  Drop the lock after adding the
 	// header so we're not holding the lock around the HTTP request.
 	cacheLock.Lock()
 	cacheData, ok := httpCache[url]
+	if ok && time.Now().Before(cacheData.expires) {
+		// The server said the data is still fresh, so do not ask again.
+		cacheData.lastUsed = time.Now()
+		httpCache[url] = cacheData
+		cacheLock.Unlock()
+		contextLogger(ctx, cachelog).Info(fmt.Sprintf("Retrieved fresh entry from cache: %v", url))
+		httpCacheHits.Inc()
+		return cacheData.body, false, nil
+	}
 	cacheLock.Unlock()
 	if ok {
-		req.Header.Add("If-None-Match", cacheData.etag)
-		req.Header.Add("If-Modified-Since", cacheData.date)
+		if len(cacheData.etag) > 0 {
+			req.Header.Add("If-None-Match", cacheData.etag)
+		}
+		if ifModifiedSince := cacheData.validationDate(); len(ifModifiedSince) > 0 {
+			req.Header.Add("If-Modified-Since", ifModifiedSince)
+		}
 	}
 
 	// Drive the request. Certificate validation is not disabled by default.
 	// Ignore the error from TLS config - if nil comes back, use the default.
-	transport := &http.Transport{DisableCompression: true}
-	tlsConfig, _ := GetTLSCConfig(c, skipCertVerify, cachelog)
+	var transport http.RoundTripper
+	var tlsConfig *tls.Config
+	if proxy != nil {
+		transport = proxy.Transport()
+		tlsConfig = proxy.tlsConfig
+	} else {
+		tlsConfig, _ = GetTLSCConfig(c, skipCertVerify, contextLogger(ctx, cachelog))
+		transport = NewTransport(tlsConfig)
+	}
 
-	transport.TLSClientConfig = tlsConfig
+	// Wait for our turn.  The slot is held until the response has been read.
+	release := acquireDownload(url)
+	defer release()
 
 	client := &http.Client{Transport: transport}
 	resp, err := client.Do(req)
@@ -74,54 +166,80 @@ func GetFromCache(c client.Client, url string, skipCertVerify bool) ([]byte, err
 	// If something went horribly wrong, tell the user.  If we were using the
 	// default TLS config, make that part of the error message.
 	if err != nil {
+		httpCacheDownloadErrors.WithLabelValues(downloadErrorRequest).Inc()
 		if tlsConfig == nil {
-			return nil, fmt.Errorf("HTTP request error while using the default TLS configuration: %v", err.Error())
+			return nil, true, fmt.Errorf("HTTP request error while using the default TLS configuration: %v", err.Error())
 		}
-		return nil, err
+		return nil, true, err
 	}
 	defer resp.Body.Close()
 
 	// Check to see if we're going to use the cached data.
 	if resp.StatusCode == http.StatusNotModified {
-		cachelog.Info(fmt.Sprintf("Retrieved from cache: %v", url))
+		contextLogger(ctx, cachelog).Info(fmt.Sprintf("Retrieved from cache: %v", url))
+		httpCacheHits.Inc()
 
-		// Update the last used time so the entry does not get purged.
+		// Update the last used time so the entry does not get purged, and
+		// the expiry time the server may have sent along.
 		cacheData.lastUsed = time.Now()
+		cacheData.expires = expiresAt(resp.Header, cacheData.lastUsed)
 		cacheLock.Lock()
 		httpCache[url] = cacheData
 		cacheLock.Unlock()
 
-		return cacheData.body, nil
+		return cacheData.body, false, nil
 	} else if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(fmt.Sprintf("Could not retrieve the resource: %v. Http status code: %v", url, resp.StatusCode))
+		httpCacheDownloadErrors.WithLabelValues(downloadErrorStatus).Inc()
+		return nil, isRetriableStatus(resp.StatusCode, retryOnStatus), fmt.Errorf(fmt.Sprintf("Could not retrieve the resource: %v. Http status code: %v", url, resp.StatusCode))
 	}
 
-	// We got some new data back.  Read it, and then see if we can cache it.
-	r := resp.Body
-	b, err := ioutil.ReadAll(r)
+	// We got some new data back.  Read it, and then see if we can cache it.  A response
+	// that says it is too large is not read at all.
+	if limit := GetMaxDownloadSize(); resp.ContentLength > limit {
+		httpCacheDownloadErrors.WithLabelValues(downloadErrorTooLarge).Inc()
+		return nil, false, DownloadTooLargeError{Name: url, Limit: limit}
+	}
+	b, err := readLimited(resp.Body, url)
 	if err != nil {
-		return nil, err
+		if IsDownloadTooLarge(err) {
+			httpCacheDownloadErrors.WithLabelValues(downloadErrorTooLarge).Inc()
+			return nil, false, err
+		}
+		httpCacheDownloadErrors.WithLabelValues(downloadErrorRead).Inc()
+		return nil, true, err
 	}
+	httpCacheMisses.Inc()
 
-	etag := resp.Header.Get("ETag")
-	date := resp.Header.Get("Date")
+	now := time.Now()
+	value := cacheValue{
+		etag:         resp.Header.Get("ETag"),
+		date:         resp.Header.Get("Date"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		expires:      expiresAt(resp.Header, now),
+		body:         b,
+		lastUsed:     now,
+	}
 
 	// Re-lock the cache before either adding or removing the response from it.
 	cacheLock.Lock()
-	defer cacheLock.Unlock()
-	if (len(etag) > 0) && (len(date) > 0) {
-		// Before adding an entry to the cache, make sure the purge task is running.
-		startPurgeTicker.Do(func() {
-			timer.ScheduleWork(tickerDuration, cachelog, purgeCache, purgeDuration)
-		})
-		httpCache[url] = cacheValue{etag: etag, date: date, body: b, lastUsed: time.Now()}
-		cachelog.Info(fmt.Sprintf("Stored to cache: %v", url))
+	var diskEntry *diskCacheEntry
+	if isCacheable(resp.Header) && fitsInCache(cacheEntrySize(url, value)) {
+		httpCache[url] = value
+		diskEntry = snapshotDiskCacheEntry(url, value)
+		contextLogger(ctx, cachelog).Info(fmt.Sprintf("Stored to cache: %v", url))
+		evictCache()
 	} else {
 		// Take the entry out of the map if it's already there.
-		delete(httpCache, url)
+		if _, found := httpCache[url]; found {
+			delete(httpCache, url)
+			deleteDiskCacheEntry(url)
+		}
 	}
+	updateCacheSizeMetrics()
+	cacheLock.Unlock()
 
-	return b, nil
+	saveDiskCacheEntry(diskEntry)
+	return b, false, nil
 }
 
 // Purges the cache
@@ -132,6 +250,9 @@ func purgeCache(localPurgeDuration time.Duration) {
 		if time.Since(httpCache[key].lastUsed) > localPurgeDuration {
 			cachelog.Info("Purging from cache: " + key)
 			delete(httpCache, key)
+			deleteDiskCacheEntry(key)
+			httpCachePurged.Inc()
 		}
 	}
+	updateCacheSizeMetrics()
 }
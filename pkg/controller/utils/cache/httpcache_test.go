@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -72,7 +74,7 @@ func TestCachePage(t *testing.T) {
 	defer server.Close()
 
 	// Get the page twice... the first time should not cache, the second should cache.
-	data, err := GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -80,7 +82,7 @@ func TestCachePage(t *testing.T) {
 		t.Fatal("Response 1 not correct")
 	}
 
-	data, err = GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err = GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -128,7 +130,7 @@ func TestCacheChangePage(t *testing.T) {
 	defer server.Close()
 
 	// Get the page thrice... the first time and second time should not cache, the third should cache.
-	data, err := GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -136,7 +138,7 @@ func TestCacheChangePage(t *testing.T) {
 		t.Fatal("Response 1 not correct")
 	}
 
-	data, err = GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err = GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -144,7 +146,7 @@ func TestCacheChangePage(t *testing.T) {
 		t.Fatal("Response 2 not correct")
 	}
 
-	data, err = GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err = GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -172,7 +174,7 @@ func TestNoCachePage(t *testing.T) {
 	defer server.Close()
 
 	// Get the page twice... 
-	data, err := GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -180,7 +182,7 @@ func TestNoCachePage(t *testing.T) {
 		t.Fatal("Response 1 not correct")
 	}
 
-	data, err = GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err = GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -197,7 +199,7 @@ func TestCachePurge(t *testing.T) {
 	defer server.Close()
 
 	// Get the page twice... the first time should not cache.
-	data, err := GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -209,7 +211,7 @@ func TestCachePurge(t *testing.T) {
 	purgeCache(0)
 
 	// Get the page the second time... it should not be cached.
-	data, err = GetFromCache(httpCacheTestClient{}, server.URL, true)
+	data, err = GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -222,3 +224,48 @@ func TestCachePurge(t *testing.T) {
 		t.Fatalf("Wrong number of cache hits: %v", cacheHits)
 	}
 }
+
+// Test that the cache metrics reflect hits, misses, purges and download errors.
+func TestCacheMetrics(t *testing.T) {
+	var cacheHits int32 = 0
+	handler := CacheHandler{etag: "ABCDE", cacheHits: &cacheHits}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	hits := testutil.ToFloat64(httpCacheHits)
+	misses := testutil.ToFloat64(httpCacheMisses)
+	purged := testutil.ToFloat64(httpCachePurged)
+	statusErrors := testutil.ToFloat64(httpCacheDownloadErrors.WithLabelValues(downloadErrorStatus))
+
+	// Get the page twice... the second time should hit.
+	for i := 0; i < 2; i++ {
+		_, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if testutil.ToFloat64(httpCacheHits) != hits+1 || testutil.ToFloat64(httpCacheMisses) != misses+1 {
+		t.Fatalf("Expected 1 hit and 1 miss, but found %v hits and %v misses", testutil.ToFloat64(httpCacheHits)-hits, testutil.ToFloat64(httpCacheMisses)-misses)
+	}
+	if testutil.ToFloat64(httpCacheEntries) < 1 || testutil.ToFloat64(httpCacheBytes) < float64(len(theResponse)) {
+		t.Fatalf("Expected the cached page to be counted, but found %v entries and %v bytes", testutil.ToFloat64(httpCacheEntries), testutil.ToFloat64(httpCacheBytes))
+	}
+
+	// Now purge the cache
+	purgeCache(0)
+	if testutil.ToFloat64(httpCachePurged) <= purged || testutil.ToFloat64(httpCacheEntries) != 0 || testutil.ToFloat64(httpCacheBytes) != 0 {
+		t.Fatalf("Expected the cache to be purged, but found %v purged, %v entries and %v bytes", testutil.ToFloat64(httpCachePurged)-purged, testutil.ToFloat64(httpCacheEntries), testutil.ToFloat64(httpCacheBytes))
+	}
+
+	// A missing page is a download error.
+	missingServer := httptest.NewServer(http.NotFoundHandler())
+	defer missingServer.Close()
+	_, err := GetFromCache(context.Background(), httpCacheTestClient{}, missingServer.URL, true, nil)
+	if err == nil {
+		t.Fatal("Expected the download to fail")
+	}
+	if testutil.ToFloat64(httpCacheDownloadErrors.WithLabelValues(downloadErrorStatus)) != statusErrors+1 {
+		t.Fatal("Expected the download error to be counted")
+	}
+}
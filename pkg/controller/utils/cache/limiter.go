@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"net/url"
+	"sync"
+)
+
+// The default download limits, used when the Kabanero instance does not specify them.
+const (
+	DefaultMaxConcurrentDownloads        = 10
+	DefaultMaxConcurrentDownloadsPerHost = 4
+)
+
+// Limits the number of downloads that run at the same time, in total and for each
+// host.  Activating many stacks at once would otherwise start a download for each
+// of their pipelines, which can saturate the network or trip the rate limits of
+// the artifact server.
+type downloadLimiter struct {
+	lock       sync.Mutex
+	cond       *sync.Cond
+	maxTotal   int
+	maxPerHost int
+	total      int
+	perHost    map[string]int
+}
+
+func newDownloadLimiter(maxTotal int, maxPerHost int) *downloadLimiter {
+	l := &downloadLimiter{maxTotal: maxTotal, maxPerHost: maxPerHost, perHost: make(map[string]int)}
+	l.cond = sync.NewCond(&l.lock)
+	return l
+}
+
+// The limiter shared by all downloads.
+var downloads = newDownloadLimiter(DefaultMaxConcurrentDownloads, DefaultMaxConcurrentDownloadsPerHost)
+
+// Sets the download limits.  A value of zero or less selects the default.  Downloads
+// that are already running are not affected.
+func SetDownloadLimits(maxTotal int, maxPerHost int) {
+	if maxTotal <= 0 {
+		maxTotal = DefaultMaxConcurrentDownloads
+	}
+	if maxPerHost <= 0 {
+		maxPerHost = DefaultMaxConcurrentDownloadsPerHost
+	}
+	downloads.setLimits(maxTotal, maxPerHost)
+}
+
+// Returns the download limits in effect: the total, and the limit for each host.
+func GetDownloadLimits() (int, int) {
+	downloads.lock.Lock()
+	defer downloads.lock.Unlock()
+	return downloads.maxTotal, downloads.maxPerHost
+}
+
+func (l *downloadLimiter) setLimits(maxTotal int, maxPerHost int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.maxTotal = maxTotal
+	l.maxPerHost = maxPerHost
+
+	// Raising a limit may let waiting downloads proceed.
+	l.cond.Broadcast()
+}
+
+// Waits until a download from the input host is allowed to start.  The returned
+// function must be called when the download completes.
+func (l *downloadLimiter) acquire(host string) func() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for l.total >= l.maxTotal || l.perHost[host] >= l.maxPerHost {
+		l.cond.Wait()
+	}
+	l.total++
+	l.perHost[host]++
+
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		l.total--
+		l.perHost[host]--
+		if l.perHost[host] == 0 {
+			delete(l.perHost, host)
+		}
+		l.cond.Broadcast()
+	}
+}
+
+// Waits until a download of the input URL is allowed to start.  The returned
+// function must be called when the download completes.
+func acquireDownload(rawUrl string) func() {
+	host := rawUrl
+	u, err := url.Parse(rawUrl)
+	if err == nil && len(u.Host) != 0 {
+		host = u.Host
+	}
+	return downloads.acquire(host)
+}
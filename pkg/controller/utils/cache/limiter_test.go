@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Runs downloads from the input hosts, and returns the most that ran at the same time
+// in total, and for the first host.
+func runDownloads(l *downloadLimiter, hosts []string) (int, int) {
+	lock := sync.Mutex{}
+	running, maxRunning := 0, 0
+	runningFirst, maxRunningFirst := 0, 0
+
+	wg := sync.WaitGroup{}
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			release := l.acquire(host)
+			defer release()
+
+			lock.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			if host == hosts[0] {
+				runningFirst++
+				if runningFirst > maxRunningFirst {
+					maxRunningFirst = runningFirst
+				}
+			}
+			lock.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			lock.Lock()
+			running--
+			if host == hosts[0] {
+				runningFirst--
+			}
+			lock.Unlock()
+		}(host)
+	}
+	wg.Wait()
+	return maxRunning, maxRunningFirst
+}
+
+// Test that the total number of downloads is limited.
+func TestDownloadLimiterTotal(t *testing.T) {
+	l := newDownloadLimiter(3, 100)
+	hosts := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	total, _ := runDownloads(l, hosts)
+	if total > 3 {
+		t.Errorf("Expected at most 3 concurrent downloads, but found %v", total)
+	}
+	if l.total != 0 || len(l.perHost) != 0 {
+		t.Errorf("Expected all downloads to be released, but found %v (%v)", l.total, l.perHost)
+	}
+}
+
+// Test that the number of downloads from a single host is limited.
+func TestDownloadLimiterPerHost(t *testing.T) {
+	l := newDownloadLimiter(100, 2)
+	hosts := []string{"a", "a", "a", "a", "a", "b", "b", "c"}
+
+	_, perHost := runDownloads(l, hosts)
+	if perHost > 2 {
+		t.Errorf("Expected at most 2 concurrent downloads from one host, but found %v", perHost)
+	}
+}
+
+// Test that raising the limit lets waiting downloads start.
+func TestDownloadLimiterSetLimits(t *testing.T) {
+	l := newDownloadLimiter(1, 1)
+	release := l.acquire("a")
+
+	started := make(chan func())
+	go func() {
+		started <- l.acquire("a")
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("Expected the second download to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	l.setLimits(2, 2)
+	select {
+	case release2 := <-started:
+		release2()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the second download to start after the limit was raised")
+	}
+	release()
+}
+
+// Test that the host is taken from the URL.
+func TestAcquireDownload(t *testing.T) {
+	release := acquireDownload("https://github.com/kabanero-io/collections/releases/index.yaml")
+	downloads.lock.Lock()
+	count := downloads.perHost["github.com"]
+	downloads.lock.Unlock()
+	release()
+
+	if count != 1 {
+		t.Errorf("Expected one download from github.com, but found %v", count)
+	}
+}
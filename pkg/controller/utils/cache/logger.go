@@ -0,0 +1,24 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+// The key of the logger stored in a context by WithLogger.
+type loggerKey struct{}
+
+// WithLogger returns a context whose downloads are logged to the input logger, so that
+// they can be found with the other messages of the same reconcile.
+func WithLogger(ctx context.Context, logger logr.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Returns the logger stored in the input context, or the default logger if there is none.
+func contextLogger(ctx context.Context, defaultLogger logr.Logger) logr.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(logr.Logger); ok {
+		return logger
+	}
+	return defaultLogger
+}
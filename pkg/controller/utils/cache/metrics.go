@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Download error reasons.
+const (
+	downloadErrorRequest  = "request"
+	downloadErrorStatus   = "status"
+	downloadErrorRead     = "read"
+	downloadErrorTooLarge = "too-large"
+)
+
+// Metrics describing how effective the HTTP cache is.  They are served by the
+// manager's metrics endpoint.
+var (
+	httpCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kabanero_http_cache_hits_total",
+		Help: "Number of downloads answered from the HTTP cache because the resource was not modified.",
+	})
+
+	httpCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kabanero_http_cache_misses_total",
+		Help: "Number of downloads that retrieved the resource from the remote server.",
+	})
+
+	httpCacheSharedDownloads = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kabanero_http_cache_shared_downloads_total",
+		Help: "Number of requests that shared the result of an identical download already in progress.",
+	})
+
+	httpCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kabanero_http_cache_entries",
+		Help: "Number of entries in the HTTP cache.",
+	})
+
+	httpCacheBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kabanero_http_cache_memory_bytes",
+		Help: "Approximate memory used by the entries in the HTTP cache.",
+	})
+
+	httpCachePurged = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kabanero_http_cache_purged_entries_total",
+		Help: "Number of HTTP cache entries purged because they were not used recently.",
+	})
+
+	httpCacheEvicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kabanero_http_cache_evicted_entries_total",
+		Help: "Number of least recently used HTTP cache entries evicted to keep the cache within its limits.",
+	})
+
+	httpCacheMaxBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kabanero_http_cache_max_bytes",
+		Help: "Maximum memory the entries in the HTTP cache may use.",
+	})
+
+	httpCacheMaxEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kabanero_http_cache_max_entries",
+		Help: "Maximum number of entries in the HTTP cache.",
+	})
+
+	httpCacheDownloadErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kabanero_http_cache_download_errors_total",
+		Help: "Number of failed downloads, by reason: request, status, read or too-large.",
+	}, []string{"reason"})
+
+	httpCacheDownloadRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kabanero_http_cache_download_retries_total",
+		Help: "Number of download attempts that were retried after a transient error.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(httpCacheHits, httpCacheMisses, httpCacheSharedDownloads, httpCacheEntries, httpCacheBytes, httpCachePurged, httpCacheEvicted, httpCacheMaxBytes, httpCacheMaxEntries, httpCacheDownloadErrors, httpCacheDownloadRetries)
+}
+
+// Updates the entry count and memory usage metrics.  The cache lock must be held.
+func updateCacheSizeMetrics() {
+	var size int64
+	for key, value := range httpCache {
+		size += cacheEntrySize(key, value)
+	}
+	httpCacheEntries.Set(float64(len(httpCache)))
+	httpCacheBytes.Set(float64(size))
+}
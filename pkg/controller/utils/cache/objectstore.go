@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/go-logr/logr"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/secret"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The URL schemes of the object storage services that stack indexes and pipeline
+// archives can be read from.
+const (
+	ObjectStorageSchemeS3    = "s3"
+	ObjectStorageSchemeGcs   = "gs"
+	ObjectStorageSchemeAzure = "azblob"
+)
+
+// The prefix of the annotation keys that identify object storage credential secrets.  The
+// annotation value is the bucket URL, or a prefix of the object URLs, that the secret is
+// used for (i.e. kabanero.io/storage-0: s3://my-bucket).
+const storageSecretAnnotationKey = "kabanero.io/storage-"
+
+// The keys of the object storage credential secrets.
+const (
+	// S3, and GCS HMAC keys.
+	storageSecretAccessKeyId     = "accessKeyId"
+	storageSecretSecretAccessKey = "secretAccessKey"
+	storageSecretSessionToken    = "sessionToken"
+	storageSecretRegion          = "region"
+
+	// GCS OAuth2 access token.
+	storageSecretToken = "token"
+
+	// Azure shared access signature.
+	storageSecretSasToken = "sasToken"
+
+	// Replaces the public endpoint of the service, for S3 compatible stores such as
+	// MinIO, or for private clouds.
+	storageSecretEndpoint = "endpoint"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+
+	// The GCS XML API accepts AWS style signatures made with HMAC keys.
+	gcsEndpoint = "https://storage.googleapis.com"
+	gcsRegion   = "auto"
+)
+
+// An object in a bucket.  For Azure, the bucket is the storage account, and the
+// container is the first element of the key.
+type objectLocation struct {
+	scheme string
+	bucket string
+	key    string
+}
+
+// Returns true if the input URL names an object in S3, GCS or Azure Blob storage.
+func IsObjectStorageUrl(objectUrl string) bool {
+	i := strings.Index(objectUrl, "://")
+	if i < 0 {
+		return false
+	}
+	switch strings.ToLower(objectUrl[:i]) {
+	case ObjectStorageSchemeS3, ObjectStorageSchemeGcs, ObjectStorageSchemeAzure:
+		return true
+	}
+	return false
+}
+
+// Splits an object storage URL into its bucket and key.
+func parseObjectUrl(objectUrl string) (objectLocation, error) {
+	u, err := url.Parse(objectUrl)
+	if err != nil {
+		return objectLocation{}, fmt.Errorf("The object storage URL %v is not valid: %v", objectUrl, err)
+	}
+	loc := objectLocation{scheme: strings.ToLower(u.Scheme), bucket: u.Host, key: strings.TrimPrefix(u.Path, "/")}
+	if len(loc.bucket) == 0 || len(loc.key) == 0 {
+		return objectLocation{}, fmt.Errorf("The object storage URL %v is not valid. It must be of the form %v://<bucket>/<object>.", objectUrl, loc.scheme)
+	}
+	if loc.scheme == ObjectStorageSchemeAzure && !strings.Contains(loc.key, "/") {
+		return objectLocation{}, fmt.Errorf("The object storage URL %v is not valid. It must be of the form %v://<account>/<container>/<blob>.", objectUrl, loc.scheme)
+	}
+	return loc, nil
+}
+
+// Retrieves an object from S3, GCS or Azure Blob storage.  The credentials are read from
+// the secret in the input namespace whose kabanero.io/storage- annotation is the longest
+// prefix of the object URL.  If no secret matches, the object is read anonymously.  The
+// object is cached like other HTTP resources, and is routed through the proxy if one is
+// specified.
+func GetFromObjectStorage(ctx context.Context, c client.Reader, namespace string, objectUrl string, skipCertVerify bool, proxy *ArtifactProxy, reqLogger logr.Logger) ([]byte, error) {
+	loc, err := parseObjectUrl(objectUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	storageSecret, err := secret.GetMatchingSecret(c, namespace, storageSecretFilter, objectUrl, storageSecretAnnotationKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find secret matching annotation key %v and URL %v in namespace %v. Error: %v", storageSecretAnnotationKey, objectUrl, namespace, err)
+	}
+
+	creds := map[string][]byte{}
+	if storageSecret != nil {
+		reqLogger.Info(fmt.Sprintf("Secret used for object storage requests: %v. Secret annotations: %v", storageSecret.GetName(), storageSecret.Annotations))
+		creds = storageSecret.Data
+	}
+
+	req, err := newObjectRequest(loc, creds, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := getFromCache(ctx, c, req, objectUrl, skipCertVerify, proxy)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve %v: %v", objectUrl, err)
+	}
+	return b, nil
+}
+
+// Returns the secret whose annotation, with a key of the input prefix, is the longest
+// prefix of the input object URL.  Returns nil if no secret matches.
+func storageSecretFilter(secretList *corev1.SecretList, filterStrings ...string) (*corev1.Secret, error) {
+	objectUrl, annotationKey := filterStrings[0], filterStrings[1]
+	var matchingSecret *corev1.Secret
+	matchingPrefix := ""
+	for _, s := range secretList.Items {
+		for key, value := range s.GetAnnotations() {
+			if !strings.HasPrefix(key, annotationKey) || len(value) <= len(matchingPrefix) {
+				continue
+			}
+			if objectUrl == value || strings.HasPrefix(objectUrl, strings.TrimSuffix(value, "/")+"/") {
+				matchingPrefix = value
+				matchingSecret = s.DeepCopy()
+			}
+		}
+	}
+	return matchingSecret, nil
+}
+
+// Builds the signed request for the input object.
+func newObjectRequest(loc objectLocation, creds map[string][]byte, now time.Time) (*http.Request, error) {
+	endpoint := strings.TrimSuffix(string(creds[storageSecretEndpoint]), "/")
+
+	switch loc.scheme {
+	case ObjectStorageSchemeS3:
+		region := string(creds[storageSecretRegion])
+		if len(region) == 0 {
+			region = defaultS3Region
+		}
+		var objectUrl string
+		if len(endpoint) != 0 {
+			objectUrl = endpoint + "/" + loc.bucket + "/" + escapeObjectKey(loc.key)
+		} else {
+			objectUrl = fmt.Sprintf("https://%v.s3.%v.amazonaws.com/%v", loc.bucket, region, escapeObjectKey(loc.key))
+		}
+		return newSigV4Request(objectUrl, creds, region, now)
+
+	case ObjectStorageSchemeGcs:
+		if len(endpoint) == 0 {
+			endpoint = gcsEndpoint
+		}
+		objectUrl := endpoint + "/" + loc.bucket + "/" + escapeObjectKey(loc.key)
+		if token := creds[storageSecretToken]; len(token) != 0 {
+			req, err := http.NewRequest(http.MethodGet, objectUrl, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+			return req, nil
+		}
+		return newSigV4Request(objectUrl, creds, gcsRegion, now)
+
+	case ObjectStorageSchemeAzure:
+		if len(endpoint) == 0 {
+			endpoint = fmt.Sprintf("https://%v.blob.core.windows.net", loc.bucket)
+		}
+		objectUrl := endpoint + "/" + escapeObjectKey(loc.key)
+		if sas := strings.TrimPrefix(strings.TrimSpace(string(creds[storageSecretSasToken])), "?"); len(sas) != 0 {
+			objectUrl = objectUrl + "?" + sas
+		}
+		req, err := http.NewRequest(http.MethodGet, objectUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-ms-version", "2019-12-12")
+		return req, nil
+	}
+
+	return nil, fmt.Errorf("The object storage scheme %v is not supported", loc.scheme)
+}
+
+// Builds a GET request signed with AWS signature version 4.  The request is not signed if
+// the credentials do not include an access key.
+func newSigV4Request(objectUrl string, creds map[string][]byte, region string, now time.Time) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, objectUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	accessKeyId := strings.TrimSpace(string(creds[storageSecretAccessKeyId]))
+	secretAccessKey := strings.TrimSpace(string(creds[storageSecretSecretAccessKey]))
+	if len(accessKeyId) == 0 || len(secretAccessKey) == 0 {
+		return req, nil
+	}
+
+	sessionToken := strings.TrimSpace(string(creds[storageSecretSessionToken]))
+
+	// The object key is already escaped by escapeObjectKey, so the signer uses the path as is.
+	signer := v4.NewSigner(credentials.NewStaticCredentials(accessKeyId, secretAccessKey, sessionToken), func(s *v4.Signer) {
+		s.DisableURIPathEscaping = true
+	})
+	_, err = signer.Sign(req, nil, "s3", region, now)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to sign the request for %v: %v", objectUrl, err)
+	}
+	return req, nil
+}
+
+// Escapes each element of an object key.  The slashes between elements are kept.
+func escapeObjectKey(key string) string {
+	elements := strings.Split(key, "/")
+	for i, element := range elements {
+		elements[i] = awsEscape(element)
+	}
+	return strings.Join(elements, "/")
+}
+
+// Percent-encodes every byte other than the unreserved characters of RFC 3986, which is
+// the encoding S3 expects in object keys.
+func awsEscape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			sb.WriteByte(b)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+	return sb.String()
+}
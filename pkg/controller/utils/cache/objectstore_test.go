@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Unit test client that lists the object storage secrets.
+type objectStoreTestClient struct {
+	httpCacheTestClient
+	secrets []corev1.Secret
+}
+
+func (c objectStoreTestClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	list.(*corev1.SecretList).Items = c.secrets
+	return nil
+}
+
+func storageSecret(name string, prefix string, data map[string]string) corev1.Secret {
+	s := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{storageSecretAnnotationKey + "0": prefix}}, Data: map[string][]byte{}}
+	for key, value := range data {
+		s.Data[key] = []byte(value)
+	}
+	return s
+}
+
+// HTTP handler that records the request, and serves the object.
+type objectStoreHandler struct {
+	requests chan *http.Request
+}
+
+func (h objectStoreHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	h.requests <- req
+	rw.Write([]byte(theResponse))
+}
+
+func TestIsObjectStorageUrl(t *testing.T) {
+	for _, u := range []string{"s3://bucket/index.yaml", "gs://bucket/index.yaml", "azblob://account/container/index.yaml", "S3://bucket/index.yaml"} {
+		if !IsObjectStorageUrl(u) {
+			t.Errorf("Expected %v to be an object storage URL", u)
+		}
+	}
+	for _, u := range []string{"https://github.com/index.yaml", "oci://quay.io/pipelines:1", "index.yaml"} {
+		if IsObjectStorageUrl(u) {
+			t.Errorf("Expected %v not to be an object storage URL", u)
+		}
+	}
+}
+
+// Test that the secret with the longest matching prefix is used.
+func TestStorageSecretFilter(t *testing.T) {
+	secretList := &corev1.SecretList{Items: []corev1.Secret{
+		storageSecret("bucket", "s3://bucket", nil),
+		storageSecret("stacks", "s3://bucket/stacks/", nil),
+		storageSecret("other", "s3://bucket2", nil),
+	}}
+
+	tests := map[string]string{
+		"s3://bucket/stacks/index.yaml":   "stacks",
+		"s3://bucket/pipelines/p.tar.gz":  "bucket",
+		"s3://bucket2/index.yaml":         "other",
+		"gs://bucket/stacks/index.yaml":   "",
+		"s3://bucket-other/stacks/a.yaml": "",
+	}
+	for objectUrl, expected := range tests {
+		s, err := storageSecretFilter(secretList, objectUrl, storageSecretAnnotationKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := ""
+		if s != nil {
+			name = s.Name
+		}
+		if name != expected {
+			t.Errorf("Expected secret %q for %v, but found %q", expected, objectUrl, name)
+		}
+	}
+}
+
+// Test that the public endpoints of each service are used.
+func TestNewObjectRequest(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]string{
+		"s3://bucket/stacks/index.yaml":         "https://bucket.s3.us-east-1.amazonaws.com/stacks/index.yaml",
+		"gs://bucket/stacks/my index.yaml":      "https://storage.googleapis.com/bucket/stacks/my%20index.yaml",
+		"azblob://account/container/index.yaml": "https://account.blob.core.windows.net/container/index.yaml",
+	}
+	for objectUrl, expected := range tests {
+		loc, err := parseObjectUrl(objectUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := newObjectRequest(loc, map[string][]byte{}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.URL.String() != expected {
+			t.Errorf("Expected %v for %v, but found %v", expected, objectUrl, req.URL.String())
+		}
+		if len(req.Header.Get("Authorization")) != 0 {
+			t.Errorf("Expected an anonymous request for %v", objectUrl)
+		}
+	}
+
+	if _, err := parseObjectUrl("azblob://account/index.yaml"); err == nil {
+		t.Error("Expected an Azure URL without a container to be rejected")
+	}
+}
+
+// Test that S3 requests are signed with the secret's credentials, and sent to its endpoint.
+func TestGetFromObjectStorageS3(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(objectStoreHandler{requests: requests})
+	defer server.Close()
+
+	c := objectStoreTestClient{secrets: []corev1.Secret{storageSecret("minio", "s3://stacks", map[string]string{
+		storageSecretAccessKeyId:     "AKIDEXAMPLE",
+		storageSecretSecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		storageSecretRegion:          "eu-west-1",
+		storageSecretEndpoint:        server.URL,
+	})}}
+
+	b, err := GetFromObjectStorage(context.Background(), c, "kabanero", "s3://stacks/index.yaml", false, nil, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != theResponse {
+		t.Fatalf("Expected %v, but found %v", theResponse, string(b))
+	}
+
+	req := <-requests
+	if req.URL.Path != "/stacks/index.yaml" {
+		t.Errorf("Expected path /stacks/index.yaml, but found %v", req.URL.Path)
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+		t.Errorf("Unexpected Authorization header: %v", auth)
+	}
+	if !strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date,") {
+		t.Errorf("Unexpected signed headers: %v", auth)
+	}
+	if len(req.Header.Get("x-amz-date")) == 0 {
+		t.Error("Expected the x-amz-date header")
+	}
+}
+
+// Test that Azure requests carry the shared access signature of the secret.
+func TestGetFromObjectStorageAzure(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(objectStoreHandler{requests: requests})
+	defer server.Close()
+
+	c := objectStoreTestClient{secrets: []corev1.Secret{storageSecret("azure", "azblob://account/stacks", map[string]string{
+		storageSecretSasToken: "?sv=2019-12-12&sig=abc",
+		storageSecretEndpoint: server.URL,
+	})}}
+
+	if _, err := GetFromObjectStorage(context.Background(), c, "kabanero", "azblob://account/stacks/index.yaml", false, nil, logf.NullLogger{}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-requests
+	if req.URL.Path != "/stacks/index.yaml" {
+		t.Errorf("Expected path /stacks/index.yaml, but found %v", req.URL.Path)
+	}
+	if req.URL.Query().Get("sig") != "abc" {
+		t.Errorf("Expected the shared access signature, but found query %v", req.URL.RawQuery)
+	}
+}
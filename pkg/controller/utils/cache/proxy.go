@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/secret"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// An in-cluster caching proxy that artifact fetches are routed through.  The
+// proxy is trusted according to its own TLS configuration, since it is the
+// proxy's job to connect to the remote server.
+type ArtifactProxy struct {
+	url                *url.URL
+	tlsConfig          *tls.Config
+	forwardCredentials bool
+}
+
+// Builds the artifact proxy described by the input spec.  Returns nil if no
+// proxy is configured.  The CA secret is read from the input namespace.
+func GetArtifactProxy(c client.Reader, namespace string, spec kabanerov1alpha2.ArtifactProxySpec) (*ArtifactProxy, error) {
+	if len(spec.Url) == 0 {
+		return nil, nil
+	}
+
+	proxyUrl, err := url.Parse(spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("The artifact proxy URL %v is not valid: %v", spec.Url, err.Error())
+	}
+	if len(proxyUrl.Scheme) == 0 || len(proxyUrl.Host) == 0 {
+		return nil, fmt.Errorf("The artifact proxy URL %v is not valid. It must contain a scheme and a host.", spec.Url)
+	}
+
+	proxy := &ArtifactProxy{url: proxyUrl, forwardCredentials: spec.ForwardCredentials}
+	switch {
+	case spec.SkipCertVerification:
+		proxy.tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	case len(spec.CaSecretName) != 0:
+		caCert, err := getProxyCACert(c, spec.CaSecretName, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		certPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("Unable to append the artifact proxy CA certificate from secret %v to the system cert pool.", spec.CaSecretName)
+		}
+		proxy.tlsConfig = &tls.Config{RootCAs: certPool}
+	}
+
+	return proxy, nil
+}
+
+// Retrieve the artifact proxy CA cert.
+func getProxyCACert(c client.Reader, secretName string, secretNamespace string) ([]byte, error) {
+	caSecret, err := secret.GetUnstructuredSecret(c, secretName, secretNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the artifact proxy CA secret. Secret name: %v. Namespace: %v. Error: %v", secretName, secretNamespace, err)
+	}
+
+	caCrt, found, err := unstructured.NestedString(caSecret.Object, "data", "ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve data.ca.crt from the secret %v. Namespace: %v. Error: %v", secretName, secretNamespace, err)
+	}
+	if !found {
+		return nil, fmt.Errorf("The data.ca.crt entry was not found in secret %v. Namespace: %v", secretName, secretNamespace)
+	}
+
+	decodedCrt, err := base64.StdEncoding.DecodeString(caCrt)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decode secret %v ca.crt. Namespace: %v. Error: %v", secretName, secretNamespace, err)
+	}
+
+	return decodedCrt, nil
+}
+
+// Rewrites the input URL so that it is fetched through the proxy.  A request
+// for https://host/path?query is sent to <proxy url>/https/host/path?query, so
+// that the proxy knows which scheme to use when it connects to the host.
+func (p *ArtifactProxy) rewrite(u *url.URL) *url.URL {
+	rewritten := *p.url
+	rewritten.Path = strings.TrimSuffix(p.url.Path, "/") + "/" + u.Scheme + "/" + u.Host + u.Path
+	rewritten.RawPath = ""
+	rewritten.RawQuery = u.RawQuery
+	return &rewritten
+}
+
+// Returns a transport that sends every request to the proxy.
+func (p *ArtifactProxy) Transport() http.RoundTripper {
+	return &proxyTransport{proxy: p, base: NewTransport(p.tlsConfig)}
+}
+
+// A transport that rewrites each request URL before sending it.
+type proxyTransport struct {
+	proxy *ArtifactProxy
+	base  http.RoundTripper
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxied := req.Clone(req.Context())
+	proxied.URL = t.proxy.rewrite(req.URL)
+	proxied.Host = ""
+
+	// The credentials are meant for the remote server.  Only hand them to a proxy
+	// that has been trusted with them.
+	if !t.proxy.forwardCredentials {
+		proxied.Header.Del("Authorization")
+	}
+	return t.base.RoundTrip(proxied)
+}
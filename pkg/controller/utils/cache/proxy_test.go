@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// HTTP handler that acts as a pull-through proxy for a single resource.
+type proxyHandler struct {
+	expectedPath  string
+	expectedQuery string
+	expectedAuth  string
+}
+
+func (ph proxyHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != ph.expectedPath || req.URL.RawQuery != ph.expectedQuery {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if req.Header.Get("Authorization") != ph.expectedAuth {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rw.Write([]byte(theResponse))
+}
+
+// Show that the request is rewritten to go thru the proxy.
+func TestGetFromCacheUsingProxy(t *testing.T) {
+	handler := proxyHandler{expectedPath: "/cache/https/github.com/kabanero-io/stacks/index.yaml", expectedQuery: "raw=true"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	proxy, err := GetArtifactProxy(httpCacheTestClient{}, "kabanero", kabanerov1alpha2.ArtifactProxySpec{Url: server.URL + "/cache/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := GetFromCache(context.Background(), httpCacheTestClient{}, "https://github.com/kabanero-io/stacks/index.yaml?raw=true", false, proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare([]byte(theResponse), data) != 0 {
+		t.Fatal("Response not correct")
+	}
+}
+
+// Show that the Authorization header is only sent to a proxy trusted with credentials.
+func TestProxyTransportCredentials(t *testing.T) {
+	for _, forward := range []bool{false, true} {
+		handler := proxyHandler{expectedPath: "/http/example.com/index.yaml"}
+		if forward {
+			handler.expectedAuth = "token abc"
+		}
+		server := httptest.NewServer(handler)
+
+		proxy, err := GetArtifactProxy(httpCacheTestClient{}, "kabanero", kabanerov1alpha2.ArtifactProxySpec{Url: server.URL, ForwardCredentials: forward})
+		if err != nil {
+			server.Close()
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "http://example.com/index.yaml", nil)
+		if err != nil {
+			server.Close()
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "token abc")
+
+		resp, err := proxy.Transport().RoundTrip(req)
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200 with credential forwarding %v, but found %v", forward, resp.StatusCode)
+		}
+	}
+}
+
+// Show that no proxy is returned when one is not configured, and that an invalid URL is rejected.
+func TestGetArtifactProxy(t *testing.T) {
+	proxy, err := GetArtifactProxy(httpCacheTestClient{}, "kabanero", kabanerov1alpha2.ArtifactProxySpec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxy != nil {
+		t.Fatalf("Expected no proxy, but found: %v", proxy)
+	}
+
+	_, err = GetArtifactProxy(httpCacheTestClient{}, "kabanero", kabanerov1alpha2.ArtifactProxySpec{Url: "cache.kabanero.svc"})
+	if err == nil {
+		t.Fatal("Expected an error for a proxy URL without a scheme")
+	}
+}
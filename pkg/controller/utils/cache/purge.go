@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Purges the entries of the HTTP and Git caches that have not been used recently, while
+// the manager runs.  Every controller instance has its own caches, so the purge runs
+// whether or not the instance is the leader.
+type purgeRunnable struct{}
+
+func (purgeRunnable) Start(stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	httpPurge := timer.ScheduleWork(ctx, tickerDuration, false, cachelog, purgeCache, purgeDuration)
+	gitPurge := timer.ScheduleWork(ctx, gitTickerDuration, false, gitCachelog, gitPurgeCache, gitPurgeDuration)
+
+	<-stop
+	httpPurge.Stop()
+	gitPurge.Stop()
+	return nil
+}
+
+func (purgeRunnable) NeedLeaderElection() bool {
+	return false
+}
+
+// Adds the periodic purge of the download caches to the manager.  The purge stops when
+// the manager does.
+func AddToManager(mgr manager.Manager) error {
+	return mgr.Add(purgeRunnable{})
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
+)
+
+// The default download retry policy, used when the Kabanero instance does not specify one.
+const (
+	DefaultDownloadAttempts      = 3
+	DefaultDownloadJitterPercent = 20
+)
+
+// The response status codes that are retried by default: request timeouts, rate
+// limiting, and server errors.
+var DefaultRetryOnStatus = []string{"408", "429", "5xx"}
+
+// How failed downloads are retried.
+var downloadRetry = struct {
+	sync.Mutex
+	backoff       timer.Backoff
+	retryOnStatus []string
+}{backoff: newDownloadBackoff(DefaultDownloadAttempts, DefaultDownloadJitterPercent), retryOnStatus: DefaultRetryOnStatus}
+
+func newDownloadBackoff(attempts int, jitterPercent int) timer.Backoff {
+	return timer.Backoff{
+		Attempts:   attempts,
+		Initial:    time.Second,
+		Factor:     2,
+		Max:        10 * time.Second,
+		Budget:     30 * time.Second,
+		Jitter:     float64(jitterPercent) / 100,
+		MaxElapsed: 5 * time.Minute,
+	}
+}
+
+// Sets how failed downloads are retried: the maximum number of attempts, the percentage
+// by which the wait between attempts is randomized, and the response status codes that
+// are retried.  A status is a code, such as 503, or a class, such as 5xx.  A value of zero
+// or less, or an empty list, selects the default.
+func SetDownloadRetry(attempts int, jitterPercent int, retryOnStatus []string) {
+	if attempts <= 0 {
+		attempts = DefaultDownloadAttempts
+	}
+	if jitterPercent <= 0 {
+		jitterPercent = DefaultDownloadJitterPercent
+	}
+	if len(retryOnStatus) == 0 {
+		retryOnStatus = DefaultRetryOnStatus
+	}
+
+	downloadRetry.Lock()
+	defer downloadRetry.Unlock()
+	downloadRetry.backoff = newDownloadBackoff(attempts, jitterPercent)
+	downloadRetry.retryOnStatus = retryOnStatus
+}
+
+// Returns the backoff between download attempts, and the status codes that are retried.
+func getDownloadRetry() (timer.Backoff, []string) {
+	downloadRetry.Lock()
+	defer downloadRetry.Unlock()
+	return downloadRetry.backoff, downloadRetry.retryOnStatus
+}
+
+// Returns true if a response with the input status code is retried.
+func isRetriableStatus(code int, retryOnStatus []string) bool {
+	status := strconv.Itoa(code)
+	for _, class := range retryOnStatus {
+		class = strings.ToLower(strings.TrimSpace(class))
+		if class == status {
+			return true
+		}
+		if len(class) == 3 && strings.HasSuffix(class, "xx") && len(status) == 3 && class[0] == status[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// A download that failed after it was retried.  The number of attempts is part of the
+// message, to help diagnose flaky servers and mirrors.
+type DownloadRetriesError struct {
+	Attempts int
+	Err      error
+}
+
+func (e DownloadRetriesError) Error() string {
+	return fmt.Sprintf("%v. Download attempts: %v", e.Err, e.Attempts)
+}
+
+func (e DownloadRetriesError) Unwrap() error {
+	return e.Err
+}
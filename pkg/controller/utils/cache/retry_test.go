@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// HTTP handler that fails with the input status code until it has been asked the input
+// number of times.
+type FlakyHandler struct {
+	failures   int32
+	statusCode int
+	requests   *int32
+}
+
+func (fh FlakyHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	*(fh.requests) += 1
+	if *(fh.requests) <= fh.failures {
+		rw.WriteHeader(fh.statusCode)
+		return
+	}
+	rw.Write([]byte(theResponse))
+}
+
+// Sets the input number of download attempts, with short waits between them.
+func setTestDownloadRetry(attempts int) {
+	SetDownloadRetry(attempts, 0, nil)
+	downloadRetry.Lock()
+	defer downloadRetry.Unlock()
+	downloadRetry.backoff.Initial = time.Millisecond
+}
+
+// Show that a download that fails with a server error is retried.
+func TestDownloadRetry(t *testing.T) {
+	setTestDownloadRetry(3)
+	defer SetDownloadRetry(0, 0, nil)
+
+	var requests int32
+	server := httptest.NewServer(FlakyHandler{failures: 2, statusCode: http.StatusServiceUnavailable, requests: &requests})
+	defer server.Close()
+
+	data, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare([]byte(theResponse), data) != 0 {
+		t.Fatal("Response not correct")
+	}
+	if requests != 3 {
+		t.Fatalf("Wrong number of requests: %v", requests)
+	}
+}
+
+// Show that the number of attempts is reported once the retries are exhausted.
+func TestDownloadRetriesExhausted(t *testing.T) {
+	setTestDownloadRetry(2)
+	defer SetDownloadRetry(0, 0, nil)
+
+	var requests int32
+	server := httptest.NewServer(FlakyHandler{failures: 5, statusCode: http.StatusBadGateway, requests: &requests})
+	defer server.Close()
+
+	_, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
+	if err == nil {
+		t.Fatal("Expected the download to fail")
+	}
+	if _, ok := err.(DownloadRetriesError); !ok || !strings.Contains(err.Error(), "Download attempts: 2") {
+		t.Fatalf("Expected the number of attempts to be reported, but found: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("Wrong number of requests: %v", requests)
+	}
+}
+
+// Show that a download that fails with a client error is not retried.
+func TestDownloadNotRetried(t *testing.T) {
+	setTestDownloadRetry(3)
+	defer SetDownloadRetry(0, 0, nil)
+
+	var requests int32
+	server := httptest.NewServer(FlakyHandler{failures: 5, statusCode: http.StatusForbidden, requests: &requests})
+	defer server.Close()
+
+	if _, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil); err == nil {
+		t.Fatal("Expected the download to fail")
+	}
+	if requests != 1 {
+		t.Fatalf("Wrong number of requests: %v", requests)
+	}
+}
+
+func TestIsRetriableStatus(t *testing.T) {
+	tests := []struct {
+		code      int
+		retriable bool
+	}{
+		{http.StatusRequestTimeout, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusGatewayTimeout, true},
+		{http.StatusNotFound, false},
+		{http.StatusUnauthorized, false},
+	}
+	for _, test := range tests {
+		if retriable := isRetriableStatus(test.code, DefaultRetryOnStatus); retriable != test.retriable {
+			t.Errorf("Expected retriable %v for status %v, but found %v", test.retriable, test.code, retriable)
+		}
+	}
+
+	if !isRetriableStatus(http.StatusNotFound, []string{"4XX"}) {
+		t.Error("Expected a status class to match case insensitively")
+	}
+}
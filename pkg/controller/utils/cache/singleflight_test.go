@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// HTTP handler that holds each response until it is released.
+type SlowHandler struct {
+	requests *int32
+	arrived  chan struct{}
+	release  chan struct{}
+}
+
+func (sh SlowHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	atomic.AddInt32(sh.requests, 1)
+	sh.arrived <- struct{}{}
+	<-sh.release
+	rw.Write([]byte(theResponse))
+}
+
+// Show that concurrent requests for the same page share one download.
+func TestSharedDownload(t *testing.T) {
+	var requests int32
+	handler := SlowHandler{requests: &requests, arrived: make(chan struct{}, 10), release: make(chan struct{})}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([][]byte, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil)
+		}(i)
+	}
+
+	// Let the other callers join the download before it completes.
+	<-handler.arrived
+	time.Sleep(100 * time.Millisecond)
+	close(handler.release)
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if bytes.Compare([]byte(theResponse), results[i]) != 0 {
+			t.Fatalf("Response %v not correct", i+1)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Wrong number of requests: %v", requests)
+	}
+}
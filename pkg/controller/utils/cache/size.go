@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// The default maximum size of a download, used when the Kabanero instance does not specify one.
+const DefaultMaxDownloadSize int64 = 50 * 1024 * 1024
+
+// The maximum size of a download.  Larger downloads are abandoned as soon as the limit
+// is exceeded, rather than being read into memory.
+var maxDownloadSize = struct {
+	sync.Mutex
+	value int64
+}{value: DefaultMaxDownloadSize}
+
+// Sets the maximum size of a download.  A value of zero or less selects the default.
+func SetMaxDownloadSize(max int64) {
+	if max <= 0 {
+		max = DefaultMaxDownloadSize
+	}
+	maxDownloadSize.Lock()
+	defer maxDownloadSize.Unlock()
+	maxDownloadSize.value = max
+}
+
+// Returns the maximum size of a download.
+func GetMaxDownloadSize() int64 {
+	maxDownloadSize.Lock()
+	defer maxDownloadSize.Unlock()
+	return maxDownloadSize.value
+}
+
+// Returned when a download is larger than the maximum download size.
+type DownloadTooLargeError struct {
+	Name  string
+	Limit int64
+}
+
+func (e DownloadTooLargeError) Error() string {
+	return fmt.Sprintf("The download of %v was stopped because it is larger than the limit of %v bytes", e.Name, e.Limit)
+}
+
+// Returns true if the input error reports a download that is larger than the maximum download size.
+func IsDownloadTooLarge(err error) bool {
+	_, ok := err.(DownloadTooLargeError)
+	return ok
+}
+
+// Reads the input download, which is named in errors, up to the maximum download size.
+func readLimited(r io.Reader, name string) ([]byte, error) {
+	limit := GetMaxDownloadSize()
+	b, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, DownloadTooLargeError{Name: name, Limit: limit}
+	}
+	return b, nil
+}
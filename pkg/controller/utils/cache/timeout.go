@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The default download timeouts, used when the Kabanero instance does not specify them.
+const (
+	DefaultConnectTimeout = 30 * time.Second
+	DefaultReadTimeout    = 5 * time.Minute
+)
+
+// The download timeouts.  The connect timeout bounds the TCP connection and TLS
+// handshake.  The read timeout bounds the rest of the download, once connected.
+var timeouts = struct {
+	sync.Mutex
+	connect time.Duration
+	read    time.Duration
+}{connect: DefaultConnectTimeout, read: DefaultReadTimeout}
+
+// Sets the download timeouts.  A value of zero or less selects the default.
+func SetTimeouts(connect time.Duration, read time.Duration) {
+	if connect <= 0 {
+		connect = DefaultConnectTimeout
+	}
+	if read <= 0 {
+		read = DefaultReadTimeout
+	}
+	timeouts.Lock()
+	defer timeouts.Unlock()
+	timeouts.connect = connect
+	timeouts.read = read
+}
+
+// Returns the download timeouts in effect: the connect timeout, and the read timeout.
+func GetTimeouts() (time.Duration, time.Duration) {
+	timeouts.Lock()
+	defer timeouts.Unlock()
+	return timeouts.connect, timeouts.read
+}
+
+// Returns a transport that applies the download timeouts, sends requests through the
+// egress proxy, and uses the input TLS configuration.  A nil TLS configuration selects
+// the default.
+func NewTransport(tlsConfig *tls.Config) *http.Transport {
+	connect, read := GetTimeouts()
+	return &http.Transport{
+		Proxy:                 egressProxyForRequest,
+		DialContext:           (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   connect,
+		ResponseHeaderTimeout: read,
+		DisableCompression:    true,
+		TLSClientConfig:       tlsConfig,
+	}
+}
+
+// Returns a context that is done when the input context is, or when a download started
+// now has run longer than the connect and read timeouts allow.
+func withDownloadTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	connect, read := GetTimeouts()
+	return context.WithTimeout(ctx, connect+read)
+}
+
+// Returns a transport that sends each request with the input context, and the download
+// timeouts.  It is used with clients that do not take a context themselves.
+func WithContext(ctx context.Context, transport http.RoundTripper) http.RoundTripper {
+	return &contextTransport{ctx: ctx, base: transport}
+}
+
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := withDownloadTimeout(t.ctx)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The body is read after RoundTrip returns, so the timeout ends when it is closed.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// A response body that releases its context when it is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
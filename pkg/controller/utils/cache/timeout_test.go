@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// HTTP handler that does not respond until it is released.
+type HangingHandler struct {
+	release chan struct{}
+}
+
+func (hh HangingHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	<-hh.release
+	rw.Write([]byte(theResponse))
+}
+
+// Show that a download that takes longer than the read timeout fails.
+func TestReadTimeout(t *testing.T) {
+	handler := HangingHandler{release: make(chan struct{})}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	defer close(handler.release)
+	defer SetTimeouts(DefaultConnectTimeout, DefaultReadTimeout)
+	defer SetDownloadRetry(0, 0, nil)
+
+	SetTimeouts(time.Second, 100*time.Millisecond)
+	SetDownloadRetry(1, 0, nil)
+	start := time.Now()
+	if _, err := GetFromCache(context.Background(), httpCacheTestClient{}, server.URL, true, nil); err == nil {
+		t.Fatal("Expected the download to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("The download was not stopped by the timeout, it took %v", elapsed)
+	}
+}
+
+// Show that a cancelled context stops a download.
+func TestCancelledDownload(t *testing.T) {
+	handler := HangingHandler{release: make(chan struct{})}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	defer close(handler.release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := GetFromCache(ctx, httpCacheTestClient{}, server.URL, true, nil); err == nil {
+		t.Fatal("Expected the download to be cancelled")
+	}
+}
+
+// Show that a transport made with WithContext completes requests, and the timeout lasts
+// until the body has been read.
+func TestWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(theResponse))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: WithContext(context.Background(), NewTransport(nil))}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != theResponse {
+		t.Fatalf("Response not correct: %v", string(data))
+	}
+}
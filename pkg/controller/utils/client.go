@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The parts of a client.Client that the activation of pipelines uses.  Functions take
+// the part they need, so that unit tests can pass a fake that only implements it.  A
+// client.Client implements all of them.
+
+// Getter retrieves objects.
+type Getter interface {
+	Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error
+}
+
+// Creator creates objects.
+type Creator interface {
+	Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error
+}
+
+// Updater updates objects.
+type Updater interface {
+	Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error
+}
+
+// Deleter deletes objects.
+type Deleter interface {
+	Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error
+}
+
+// StatusWriter writes the status subresource of objects.
+type StatusWriter interface {
+	Status() client.StatusWriter
+}
+
+// AssetClient reads, applies, adopts and removes the assets of pipelines.
+type AssetClient interface {
+	client.Reader
+	Creator
+	Updater
+	Deleter
+}
+
+// Returns a client.Client for manifestival, which requires one but only gets, creates,
+// updates and deletes objects.
+func manifestClient(c AssetClient) client.Client {
+	if full, ok := c.(client.Client); ok {
+		return full
+	}
+	return assetOnlyClient{c}
+}
+
+// A client.Client that only supports the operations of an AssetClient.
+type assetOnlyClient struct {
+	AssetClient
+}
+
+func (c assetOnlyClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return fmt.Errorf("Patch is not supported by the asset client")
+}
+
+func (c assetOnlyClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	return fmt.Errorf("DeleteAllOf is not supported by the asset client")
+}
+
+func (c assetOnlyClient) Status() client.StatusWriter {
+	return assetOnlyStatusWriter{}
+}
+
+type assetOnlyStatusWriter struct{}
+
+func (assetOnlyStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return fmt.Errorf("Status updates are not supported by the asset client")
+}
+
+func (assetOnlyStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return fmt.Errorf("Status patches are not supported by the asset client")
+}
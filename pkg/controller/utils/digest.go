@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// The digest algorithms accepted for pipeline archives and the files in them.
+const (
+	digestAlgorithmSha256 = "sha256"
+	digestAlgorithmSha512 = "sha512"
+)
+
+// Splits a digest into its algorithm and hex value.  The algorithm is taken from a
+// sha256: or sha512: prefix, or else from the length of the value.
+func parseDigest(digest string) (string, string, error) {
+	if i := strings.Index(digest, ":"); i >= 0 {
+		algorithm := strings.ToLower(digest[:i])
+		value := digest[i+1:]
+		switch algorithm {
+		case digestAlgorithmSha256, digestAlgorithmSha512:
+			if len(value) != hex.EncodedLen(digestSize(algorithm)) {
+				return "", "", fmt.Errorf("The %v digest %v is not %v hex characters", algorithm, value, hex.EncodedLen(digestSize(algorithm)))
+			}
+			return algorithm, strings.ToLower(value), nil
+		}
+		return "", "", fmt.Errorf("The digest algorithm %v is not supported. Use %v or %v.", algorithm, digestAlgorithmSha256, digestAlgorithmSha512)
+	}
+
+	switch len(digest) {
+	case hex.EncodedLen(sha256.Size):
+		return digestAlgorithmSha256, strings.ToLower(digest), nil
+	case hex.EncodedLen(sha512.Size):
+		return digestAlgorithmSha512, strings.ToLower(digest), nil
+	}
+	return "", "", fmt.Errorf("The digest %v is not a sha256 or sha512 digest", digest)
+}
+
+// Returns the size, in bytes, of a digest made with the input algorithm.
+func digestSize(algorithm string) int {
+	if algorithm == digestAlgorithmSha512 {
+		return sha512.Size
+	}
+	return sha256.Size
+}
+
+// Returns the hex digest of the input data, made with the input algorithm.
+func computeDigest(algorithm string, data []byte) string {
+	if algorithm == digestAlgorithmSha512 {
+		sum := sha512.Sum512(data)
+		return hex.EncodeToString(sum[:])
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Checks the input data against the expected digest, which may be a sha256 or sha512
+// digest, optionally prefixed with its algorithm.  Returns whether the digest matched,
+// and the digest of the data made with the same algorithm.  An empty expected digest
+// never matches, and the sha256 of the data is returned.
+func MatchDigest(expected string, data []byte) (bool, string, error) {
+	if len(expected) == 0 {
+		return false, computeDigest(digestAlgorithmSha256, data), nil
+	}
+	algorithm, value, err := parseDigest(expected)
+	if err != nil {
+		return false, "", err
+	}
+	actual := computeDigest(algorithm, data)
+	return actual == value, actual, nil
+}
+
+// Returns the short form of a digest used in the rendering context: the first eight hex
+// characters, without the algorithm prefix.
+func RenderingDigest(digest string) string {
+	if i := strings.Index(digest, ":"); i >= 0 {
+		digest = digest[i+1:]
+	}
+	if len(digest) < 8 {
+		return "nodigest"
+	}
+	return digest[0:8]
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+)
+
+// Test that sha256 and sha512 digests are matched, with or without an algorithm prefix.
+func TestMatchDigest(t *testing.T) {
+	data := []byte("pipeline archive")
+	sum256 := sha256.Sum256(data)
+	sum512 := sha512.Sum512(data)
+	hex256 := hex.EncodeToString(sum256[:])
+	hex512 := hex.EncodeToString(sum512[:])
+
+	for _, digest := range []string{hex256, "sha256:" + hex256, hex512, "sha512:" + hex512, "SHA512:" + hex512} {
+		matched, actual, err := MatchDigest(digest, data)
+		if err != nil {
+			t.Fatalf("Unexpected error for digest %v: %v", digest, err)
+		}
+		if !matched {
+			t.Errorf("Expected digest %v to match, but the data digest was %v", digest, actual)
+		}
+	}
+
+	matched, actual, err := MatchDigest("sha512:"+hex512, []byte("tampered archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matched || len(actual) != len(hex512) {
+		t.Errorf("Expected a tampered archive not to match, and a sha512 digest to be returned, but found %v", actual)
+	}
+
+	for _, digest := range []string{"md5:" + hex256, "sha256:" + hex512, "1234"} {
+		if _, _, err := MatchDigest(digest, data); err == nil {
+			t.Errorf("Expected digest %v to be rejected", digest)
+		}
+	}
+
+	matched, actual, err = MatchDigest("", data)
+	if err != nil || matched || actual != hex256 {
+		t.Errorf("Expected an empty digest not to match, and the sha256 to be returned, but found %v, %v, %v", matched, actual, err)
+	}
+}
+
+// Test the short digest used in the rendering context.
+func TestRenderingDigest(t *testing.T) {
+	tests := map[string]string{
+		"0123456789abcdef":        "01234567",
+		"sha512:fedcba9876543210": "fedcba98",
+		"":                        "nodigest",
+		"sha256:":                 "nodigest",
+	}
+	for digest, expected := range tests {
+		if actual := RenderingDigest(digest); actual != expected {
+			t.Errorf("Expected %v for digest %v, but found %v", expected, digest, actual)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// The GoTemplateRenderer processes the yaml source as a Go template, with the sprig
+// functions available.  The rendering context is the template data.  Only the hermetic
+// sprig functions are available, so that a template cannot read the operator's environment.
+type GoTemplateRenderer struct {
+}
+
+func (g GoTemplateRenderer) Render(filename string, b []byte, context map[string]interface{}) ([]byte, error) {
+	// A missing key is almost certainly a mistake in the template, so it is reported
+	// instead of rendering as "<no value>".
+	t, err := template.New(filename).Funcs(sprig.HermeticTxtFuncMap()).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing template %v: %v", filename, err.Error())
+	}
+
+	out := bytes.Buffer{}
+	err = t.Execute(&out, context)
+	if err != nil {
+		return nil, fmt.Errorf("Error rendering template %v: %v", filename, err.Error())
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Test that conditionals, loops and sprig functions are rendered, and that directives
+// are still processed afterwards.
+func TestProcessManifestGoTemplate(t *testing.T) {
+	manifest := []byte(`
+#Kabanero! on activate substitute StackId for text 'STACK_ID'
+apiVersion: tekton.dev/v1alpha1
+kind: Task
+metadata:
+  name: STACK_ID-build-task-{{ .Digest | lower }}
+spec:
+  steps:
+{{- range $i, $step := list "build" "test" }}
+  - name: {{ $step }}
+    image: {{ $.StackImage }}
+{{- end }}
+{{- if .storageClass }}
+  volumes:
+  - name: cache
+    storageClass: {{ .storageClass | quote }}
+{{- end }}
+`)
+	context := map[string]interface{}{"StackId": "java-microprofile", "Digest": "ABCD1234", "StackImage": "kabanero/java-microprofile", "storageClass": "fast"}
+
+	manifests, err := processManifest(manifest, kabanerov1alpha2.PipelineRendererGoTemplate, context, "build-task.yaml", "")
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if len(manifests) != 1 {
+		t.Fatalf("Expected 1 manifest, but found %v", len(manifests))
+	}
+
+	u := manifests[0].Yaml
+	if u.GetName() != "java-microprofile-build-task-abcd1234" {
+		t.Errorf("Unexpected name: %v", u.GetName())
+	}
+	steps, _, _ := unstructured.NestedSlice(u.Object, "spec", "steps")
+	if len(steps) != 2 {
+		t.Errorf("Expected 2 steps, but found %v", steps)
+	}
+	volumes, _, _ := unstructured.NestedSlice(u.Object, "spec", "volumes")
+	if len(volumes) != 1 {
+		t.Errorf("Expected 1 volume, but found %v", volumes)
+	}
+}
+
+// Test that a key missing from the rendering context is reported.
+func TestGoTemplateRendererMissingKey(t *testing.T) {
+	r := GoTemplateRenderer{}
+	_, err := r.Render("task.yaml", []byte("name: {{ .Missing }}"), map[string]interface{}{})
+	if err == nil || !strings.Contains(err.Error(), "task.yaml") {
+		t.Fatalf("Expected an error for the missing key, but found %v", err)
+	}
+}
+
+// Test that the sprig functions that read the operator's environment are not available.
+func TestGoTemplateRendererHermetic(t *testing.T) {
+	r := GoTemplateRenderer{}
+	for _, text := range []string{`token: {{ env "HOME" }}`, `token: {{ expandenv "$HOME" }}`} {
+		_, err := r.Render("task.yaml", []byte(text), map[string]interface{}{})
+		if err == nil {
+			t.Fatalf("Expected %v to be rejected", text)
+		}
+	}
+}
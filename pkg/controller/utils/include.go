@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// How deeply included files may themselves include files.
+const maxIncludeDepth = 5
+
+// Matches a line that includes another yaml file, such as "!include tasks/build-task.yaml".
+var includeRegex = regexp.MustCompile(`^!include\s+(\S+)\s*$`)
+
+// The location of a yaml pipeline, or of a file it includes.
+type includeLocation struct {
+	url        string
+	gitRelease kabanerov1alpha2.GitReleaseInfo
+}
+
+// Returns the location of the input reference, relative to the input location.  Relative
+// references are resolved against the URL of the including file, or name another asset of
+// the same Git release.  Absolute URLs are used as is.
+func (l includeLocation) resolve(ref string) (includeLocation, error) {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return includeLocation{}, fmt.Errorf("The include reference %v is not valid: %v", ref, err)
+	}
+	if refURL.IsAbs() {
+		return includeLocation{url: refURL.String()}, nil
+	}
+
+	if l.gitRelease.IsUsable() {
+		resolved := l.gitRelease
+		resolved.AssetName = path.Clean(path.Join(path.Dir(l.gitRelease.AssetName), ref))
+		return includeLocation{gitRelease: resolved}, nil
+	}
+
+	baseURL, err := url.Parse(l.url)
+	if err != nil {
+		return includeLocation{}, err
+	}
+	return includeLocation{url: baseURL.ResolveReference(refURL).String()}, nil
+}
+
+func (l includeLocation) String() string {
+	if l.gitRelease.IsUsable() {
+		return fmt.Sprintf("%v/%v/%v release %v asset %v", l.gitRelease.Hostname, l.gitRelease.Organization, l.gitRelease.Project, l.gitRelease.Release, l.gitRelease.AssetName)
+	}
+	return l.url
+}
+
+// Replaces the include lines of a yaml pipeline with the content of the files they name.
+// Each included file is placed in its own yaml documents.  Returns the expanded yaml, and
+// the number of files included.
+func expandIncludes(ctx context.Context, c client.Reader, namespace string, pipelineStatus kabanerov1alpha2.PipelineStatus, b []byte, skipCertVerification bool, proxy *cache.ArtifactProxy, reqLogger logr.Logger) ([]byte, int, error) {
+	location := includeLocation{url: pipelineStatus.Url, gitRelease: pipelineStatus.GitRelease}
+	included := 0
+	expanded, err := expandIncludesAt(ctx, c, namespace, location, b, skipCertVerification, proxy, reqLogger, map[string]bool{location.String(): true}, 0, &included)
+	return expanded, included, err
+}
+
+func expandIncludesAt(ctx context.Context, c client.Reader, namespace string, location includeLocation, b []byte, skipCertVerification bool, proxy *cache.ArtifactProxy, reqLogger logr.Logger, including map[string]bool, depth int, included *int) ([]byte, error) {
+	if !bytes.Contains(b, []byte("!include")) {
+		return b, nil
+	}
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(b)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := includeRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			out.WriteString(line)
+			out.WriteString("\n")
+			continue
+		}
+
+		if depth >= maxIncludeDepth {
+			return nil, fmt.Errorf("The includes of %v are nested more than %v deep", location, maxIncludeDepth)
+		}
+		target, err := location.resolve(match[1])
+		if err != nil {
+			return nil, err
+		}
+		if including[target.String()] {
+			return nil, fmt.Errorf("The include of %v from %v is circular", target, location)
+		}
+
+		reqLogger.Info(fmt.Sprintf("Including %v in %v", target, location))
+		content, err := DownloadToByte(ctx, c, namespace, target.url, target.gitRelease, skipCertVerification, proxy, reqLogger)
+		if err != nil {
+			return nil, manifestError{reason: kabanerov1alpha2.StackReasonArchiveUnavailable, err: fmt.Errorf("Unable to download %v, included by %v: %v", target, location, err)}
+		}
+		*included++
+
+		including[target.String()] = true
+		content, err = expandIncludesAt(ctx, c, namespace, target, content, skipCertVerification, proxy, reqLogger, including, depth+1, included)
+		delete(including, target.String())
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep the included documents apart from those around them.
+		out.WriteString("---\n")
+		out.Write(content)
+		if !bytes.HasSuffix(content, []byte("\n")) {
+			out.WriteString("\n")
+		}
+		out.WriteString("---\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// HTTP handler that serves yaml files from memory.
+type includeHandler map[string]string
+
+func (h includeHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	content, ok := h[req.URL.Path]
+	if !ok {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	rw.Write([]byte(content))
+}
+
+// Test that a yaml pipeline can hold several documents, and include files relative to its URL.
+func TestGetManifestsYamlInclude(t *testing.T) {
+	server := httptest.NewServer(includeHandler{
+		"/pipelines/pipeline.yaml":         "apiVersion: tekton.dev/v1alpha1\nkind: Pipeline\nmetadata:\n  name: build-pipeline\n---\n!include tasks/build-task.yaml\n---\napiVersion: tekton.dev/v1alpha1\nkind: Condition\nmetadata:\n  name: build-condition\n",
+		"/pipelines/tasks/build-task.yaml": "apiVersion: tekton.dev/v1alpha1\nkind: Task\nmetadata:\n  name: build-task\n---\n!include ../deploy-task.yaml\n",
+		"/pipelines/deploy-task.yaml":      "apiVersion: tekton.dev/v1alpha1\nkind: Task\nmetadata:\n  name: deploy-task",
+		"/pipelines/circular.yaml":         "!include circular.yaml\n",
+	})
+	defer server.Close()
+
+	pipelineStatus := kabanerov1alpha2.PipelineStatus{Name: "build", Url: server.URL + "/pipelines/pipeline.yaml"}
+	manifests, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{}, true, nil, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"build-pipeline", "build-task", "deploy-task", "build-condition"}
+	if len(manifests) != len(expected) {
+		t.Fatalf("Expected %v manifests, but found %v: %v", len(expected), len(manifests), manifests)
+	}
+	for i, name := range expected {
+		if manifests[i].Name != name {
+			t.Errorf("Expected manifest %v to be %v, but found %v", i, name, manifests[i].Name)
+		}
+	}
+
+	pipelineStatus.Url = server.URL + "/pipelines/circular.yaml"
+	if _, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{}, true, nil, logf.NullLogger{}); err == nil {
+		t.Fatal("Expected a circular include to be rejected")
+	}
+}
+
+// Test that an include that can not be downloaded is reported as unavailable.
+func TestGetManifestsYamlIncludeMissing(t *testing.T) {
+	server := httptest.NewServer(includeHandler{
+		"/pipeline.yaml": "!include missing.yaml\n",
+	})
+	defer server.Close()
+
+	pipelineStatus := kabanerov1alpha2.PipelineStatus{Name: "build", Url: server.URL + "/pipeline.yaml"}
+	_, err := GetManifests(context.Background(), archiveTestClient{}, "kabanero", pipelineStatus, map[string]interface{}{}, true, nil, logf.NullLogger{})
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveUnavailable {
+		t.Errorf("Expected reason %v, but found %v (%v)", kabanerov1alpha2.StackReasonArchiveUnavailable, reason, err)
+	}
+}
+
+// Test that includes in a Git release name other assets of the release.
+func TestIncludeLocationResolve(t *testing.T) {
+	release := includeLocation{gitRelease: kabanerov1alpha2.GitReleaseInfo{Hostname: "github.com", Organization: "kabanero-io", Project: "collections", Release: "0.9.0", AssetName: "pipeline.yaml"}}
+	target, err := release.resolve("build-task.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.gitRelease.AssetName != "build-task.yaml" || target.gitRelease.Release != "0.9.0" {
+		t.Errorf("Unexpected Git release include location: %v", target)
+	}
+
+	target, err = release.resolve("https://example.com/tasks/build-task.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.url != "https://example.com/tasks/build-task.yaml" || target.gitRelease.IsUsable() {
+		t.Errorf("Unexpected absolute include location: %v", target)
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Returns the oldest of the input Kabanero instances, or nil if there are none.  Instances
+// created in the same second are ordered by namespace, then by name.
+//
+// The oldest instance of a namespace manages the namespace, and the other instances in it
+// wait until it is deleted.  The oldest instance of all of the watched namespaces manages
+// what the instances share: the settings of the operator process, such as the download
+// limits, the egress proxy and the trace specification, and the cluster level resources,
+// such as the admission webhook configurations and the web console links.
+func OldestKabaneroInstance(instances []kabanerov1alpha2.Kabanero) *kabanerov1alpha2.Kabanero {
+	var oldest *kabanerov1alpha2.Kabanero
+	for i := range instances {
+		if oldest == nil || isOlderKabaneroInstance(&instances[i], oldest) {
+			oldest = &instances[i]
+		}
+	}
+	return oldest
+}
+
+// Returns true if instance a was created before instance b.
+func isOlderKabaneroInstance(a *kabanerov1alpha2.Kabanero, b *kabanerov1alpha2.Kabanero) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
+	}
+	return a.GetName() < b.GetName()
+}
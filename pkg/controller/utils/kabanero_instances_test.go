@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test that the oldest instance is chosen, and that instances created in the same second
+// are ordered by namespace, then by name.
+func TestOldestKabaneroInstance(t *testing.T) {
+	if OldestKabaneroInstance(nil) != nil {
+		t.Fatal("Expected no instance to be chosen from an empty list")
+	}
+
+	created := metav1.NewTime(time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC))
+	later := metav1.NewTime(created.Add(time.Minute))
+	newKabanero := func(namespace string, name string, creationTimestamp metav1.Time) kabanerov1alpha2.Kabanero {
+		return kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, CreationTimestamp: creationTimestamp}}
+	}
+
+	tests := []struct {
+		instances []kabanerov1alpha2.Kabanero
+		oldest    string
+	}{
+		{[]kabanerov1alpha2.Kabanero{newKabanero("team-a", "kabanero", later), newKabanero("team-b", "kabanero", created)}, "team-b/kabanero"},
+		{[]kabanerov1alpha2.Kabanero{newKabanero("team-b", "kabanero", created), newKabanero("team-a", "kabanero", created)}, "team-a/kabanero"},
+		{[]kabanerov1alpha2.Kabanero{newKabanero("kabanero", "second", created), newKabanero("kabanero", "first", created), newKabanero("kabanero", "zero", later)}, "kabanero/first"},
+	}
+
+	for _, test := range tests {
+		oldest := OldestKabaneroInstance(test.instances)
+		if oldest == nil || oldest.GetNamespace()+"/"+oldest.GetName() != test.oldest {
+			t.Errorf("Expected %v to be the oldest instance, but found %v", test.oldest, oldest)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package utils
+
+// The verbosity levels passed to logger.V for messages that are too chatty for the info
+// level.  The zap logger maps a verbosity level to the negative zap level, so debug
+// messages are printed with --zap-level=debug, and trace messages with --zap-level=2.
+const (
+	LogLevelDebug = 1
+	LogLevelTrace = 2
+)
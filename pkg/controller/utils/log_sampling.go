@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// The default number of identical messages a component logs per interval, and the interval.
+const (
+	DefaultLogSampleLimit    = 5
+	DefaultLogSampleInterval = time.Minute
+)
+
+var logMessagesSuppressed = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "kabanero_log_messages_suppressed_total",
+	Help: "Number of repeated log messages that were not written, by component.",
+}, []string{"component"})
+
+func init() {
+	metrics.Registry.MustRegister(logMessagesSuppressed)
+}
+
+// Replaced by tests.
+var sampleClock = time.Now
+
+// How often one message was logged in the current interval.
+type sampleWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// The messages logged by one component, shared by the loggers derived from its logger.
+type logSampler struct {
+	sync.Mutex
+	component string
+	limit     int
+	interval  time.Duration
+	windows   map[string]*sampleWindow
+	lastPrune time.Time
+}
+
+// Returns whether the input message is logged, and how many copies of it were
+// suppressed in the previous interval.
+func (s *logSampler) sample(msg string) (bool, int) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := sampleClock()
+	if now.Sub(s.lastPrune) >= s.interval {
+		for key, window := range s.windows {
+			if now.Sub(window.start) >= s.interval && window.suppressed == 0 {
+				delete(s.windows, key)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	window, found := s.windows[msg]
+	if !found {
+		window = &sampleWindow{start: now}
+		s.windows[msg] = window
+	}
+
+	previouslySuppressed := 0
+	if now.Sub(window.start) >= s.interval {
+		previouslySuppressed = window.suppressed
+		*window = sampleWindow{start: now}
+	}
+
+	window.count++
+	if window.count > s.limit {
+		window.suppressed++
+		logMessagesSuppressed.WithLabelValues(s.component).Inc()
+		return false, 0
+	}
+	return true, previouslySuppressed
+}
+
+// NewSampledLogger returns a logger that writes at most limit copies of an identical info
+// message per interval for the named component, so that messages repeated on every
+// reconcile do not flood the log.  Messages are identical if their text, names, and keys
+// and values are, apart from the reconcile ID.  Errors are always written.  The suppressed
+// copies are counted, and the count is added to the first copy written in the next interval.
+func NewSampledLogger(logger logr.Logger, component string, limit int, interval time.Duration) logr.Logger {
+	return &sampledLogger{
+		Logger: logger,
+		sampler: &logSampler{
+			component: component,
+			limit:     limit,
+			interval:  interval,
+			windows:   make(map[string]*sampleWindow),
+			lastPrune: sampleClock(),
+		},
+	}
+}
+
+type sampledLogger struct {
+	logr.Logger
+	sampler *logSampler
+
+	// The names and values of the logger, which are part of the identity of its messages.
+	context string
+}
+
+func (l *sampledLogger) Info(msg string, keysAndValues ...interface{}) {
+	sampledInfo(l.Logger, l.sampler, l.context, msg, keysAndValues)
+}
+
+func (l *sampledLogger) V(level int) logr.InfoLogger {
+	return &sampledInfoLogger{InfoLogger: l.Logger.V(level), sampler: l.sampler, context: fmt.Sprintf("%v v%v", l.context, level)}
+}
+
+func (l *sampledLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &sampledLogger{Logger: l.Logger.WithValues(keysAndValues...), sampler: l.sampler, context: l.context + sampleKey(keysAndValues)}
+}
+
+func (l *sampledLogger) WithName(name string) logr.Logger {
+	return &sampledLogger{Logger: l.Logger.WithName(name), sampler: l.sampler, context: l.context + " " + name}
+}
+
+type sampledInfoLogger struct {
+	logr.InfoLogger
+	sampler *logSampler
+	context string
+}
+
+func (l *sampledInfoLogger) Info(msg string, keysAndValues ...interface{}) {
+	sampledInfo(l.InfoLogger, l.sampler, l.context, msg, keysAndValues)
+}
+
+// Returns the input key and value pairs as text, without the reconcile ID, which differs
+// on every reconcile.
+func sampleKey(keysAndValues []interface{}) string {
+	key := ""
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if keysAndValues[i] != ReconcileIDKey {
+			key += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+		}
+	}
+	return key
+}
+
+// Writes the input message if the sampler allows it.  Enabled is checked first, so that
+// messages below the log level are not counted.
+func sampledInfo(logger logr.InfoLogger, sampler *logSampler, context string, msg string, keysAndValues []interface{}) {
+	if !logger.Enabled() {
+		return
+	}
+	write, suppressed := sampler.sample(context + sampleKey(keysAndValues) + ": " + msg)
+	if !write {
+		return
+	}
+	if suppressed > 0 {
+		keysAndValues = append(keysAndValues, "suppressed", suppressed)
+	}
+	logger.Info(msg, keysAndValues...)
+}
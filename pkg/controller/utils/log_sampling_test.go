@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Records the messages written, and the key and value pairs of the last one.
+type recordingLogger struct {
+	messages      *[]string
+	keysAndValues *[]interface{}
+}
+
+func (r recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	*r.messages = append(*r.messages, msg)
+	*r.keysAndValues = keysAndValues
+}
+func (r recordingLogger) Enabled() bool { return true }
+func (r recordingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	*r.messages = append(*r.messages, msg)
+}
+func (r recordingLogger) V(level int) logr.InfoLogger                         { return r }
+func (r recordingLogger) WithValues(keysAndValues ...interface{}) logr.Logger { return r }
+func (r recordingLogger) WithName(name string) logr.Logger                    { return r }
+
+// Test that identical messages are suppressed once the limit is reached, and that the
+// count of suppressed messages is written with the next interval.
+func TestSampledLogger(t *testing.T) {
+	clock := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	sampleClock = func() time.Time { return clock }
+	defer func() { sampleClock = time.Now }()
+
+	messages := []string{}
+	keysAndValues := []interface{}{}
+	logger := NewSampledLogger(recordingLogger{messages: &messages, keysAndValues: &keysAndValues}, "test", 2, time.Minute)
+
+	stackLogger := logger.WithValues("Stack.Name", "java-microprofile", ReconcileIDKey, NewReconcileID())
+	for i := 0; i < 5; i++ {
+		stackLogger.Info("Reconciling Stack")
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, but found %v", messages)
+	}
+
+	// A different stack and errors are not affected, but a new reconcile of the same stack is.
+	logger.WithValues("Stack.Name", "nodejs").Info("Reconciling Stack")
+	logger.WithValues("Stack.Name", "java-microprofile", ReconcileIDKey, NewReconcileID()).Info("Reconciling Stack")
+	logger.Error(nil, "Reconciling Stack")
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 messages, but found %v", messages)
+	}
+
+	// The next interval reports the suppressed messages.
+	clock = clock.Add(time.Minute)
+	stackLogger.Info("Reconciling Stack")
+	if len(messages) != 5 || len(keysAndValues) != 2 || keysAndValues[0] != "suppressed" || keysAndValues[1] != 4 {
+		t.Fatalf("Expected the suppressed count, but found %v %v", messages, keysAndValues)
+	}
+}
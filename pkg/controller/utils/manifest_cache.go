@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// The number of decoded pipeline archives kept in memory.
+const maxCachedManifests = 32
+
+// Identifies the decoded manifests of a pipeline archive.  The same archive renders
+// differently for each stack, so the rendering context is part of the key.
+type manifestCacheKey struct {
+	url         string
+	gitRelease  kabanerov1alpha2.GitReleaseInfo
+	digest      string
+	renderer    string
+	contextHash string
+}
+
+type manifestCacheEntry struct {
+	key       manifestCacheKey
+	manifests []StackAsset
+}
+
+// A least recently used cache of decoded pipeline archives, so that the manifests do
+// not need to be downloaded, decompressed and rendered again when an asset has to be
+// created again.
+type manifestCache struct {
+	lock     sync.Mutex
+	capacity int
+	entries  map[manifestCacheKey]*list.Element
+	order    *list.List
+}
+
+func newManifestCache(capacity int) *manifestCache {
+	return &manifestCache{capacity: capacity, entries: make(map[manifestCacheKey]*list.Element), order: list.New()}
+}
+
+var decodedManifests = newManifestCache(maxCachedManifests)
+
+// Returns the cache key of the input pipeline and rendering context.  The second return
+// value is false if the pipeline cannot be cached.
+func getManifestCacheKey(pipelineStatus kabanerov1alpha2.PipelineStatus, renderingContext map[string]interface{}) (manifestCacheKey, bool) {
+	// Signed archives are verified on every read, so that a revoked key takes effect.
+	if len(pipelineStatus.Digest) == 0 || pipelineStatus.Signature != nil {
+		return manifestCacheKey{}, false
+	}
+
+	// Map keys are marshalled in sorted order, so the hash is stable.
+	b, err := json.Marshal(renderingContext)
+	if err != nil {
+		return manifestCacheKey{}, false
+	}
+	sum := sha256.Sum256(b)
+
+	return manifestCacheKey{
+		url:         pipelineStatus.Url,
+		gitRelease:  pipelineStatus.GitRelease,
+		digest:      pipelineStatus.Digest,
+		renderer:    pipelineStatus.Renderer,
+		contextHash: hex.EncodeToString(sum[:]),
+	}, true
+}
+
+// Returns a copy of the cached manifests, or false if they are not cached.
+func (mc *manifestCache) get(key manifestCacheKey) ([]StackAsset, bool) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	element, found := mc.entries[key]
+	if !found {
+		return nil, false
+	}
+	mc.order.MoveToFront(element)
+	return copyStackAssets(element.Value.(*manifestCacheEntry).manifests), true
+}
+
+// Caches a copy of the manifests, evicting the least recently used entry if the cache is full.
+func (mc *manifestCache) put(key manifestCacheKey, manifests []StackAsset) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+
+	if element, found := mc.entries[key]; found {
+		element.Value.(*manifestCacheEntry).manifests = copyStackAssets(manifests)
+		mc.order.MoveToFront(element)
+		return
+	}
+
+	mc.entries[key] = mc.order.PushFront(&manifestCacheEntry{key: key, manifests: copyStackAssets(manifests)})
+	for mc.order.Len() > mc.capacity {
+		oldest := mc.order.Back()
+		mc.order.Remove(oldest)
+		delete(mc.entries, oldest.Value.(*manifestCacheEntry).key)
+	}
+}
+
+// Returns a deep copy of the manifests, so that callers can change them.
+func copyStackAssets(manifests []StackAsset) []StackAsset {
+	copied := make([]StackAsset, len(manifests))
+	for i, manifest := range manifests {
+		copied[i] = manifest
+		copied[i].Yaml = *manifest.Yaml.DeepCopy()
+	}
+	return copied
+}
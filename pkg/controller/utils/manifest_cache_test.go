@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Test that the least recently used entry is evicted, and that callers get copies.
+func TestManifestCache(t *testing.T) {
+	mc := newManifestCache(2)
+	newAssets := func(name string) []StackAsset {
+		u := unstructured.Unstructured{}
+		u.SetName(name)
+		return []StackAsset{{Name: name, Kind: "Task", Yaml: u}}
+	}
+
+	key1 := manifestCacheKey{url: "https://example.com/1.tar.gz", digest: "1111"}
+	key2 := manifestCacheKey{url: "https://example.com/2.tar.gz", digest: "2222"}
+	key3 := manifestCacheKey{url: "https://example.com/3.tar.gz", digest: "3333"}
+
+	mc.put(key1, newAssets("task-1"))
+	mc.put(key2, newAssets("task-2"))
+
+	// Using the first entry makes the second one the least recently used.
+	manifests, found := mc.get(key1)
+	if !found || manifests[0].Name != "task-1" {
+		t.Fatalf("Expected the first entry to be cached: %v", manifests)
+	}
+	manifests[0].Yaml.SetNamespace("changed")
+
+	mc.put(key3, newAssets("task-3"))
+	if _, found := mc.get(key2); found {
+		t.Fatal("Expected the second entry to be evicted")
+	}
+
+	manifests, found = mc.get(key1)
+	if !found || manifests[0].Yaml.GetNamespace() != "" {
+		t.Fatalf("Expected an unchanged copy of the first entry: %v", manifests)
+	}
+}
+
+// Test that the rendering context is part of the key, and that pipelines without a
+// digest are not cached.
+func TestGetManifestCacheKey(t *testing.T) {
+	pipelineStatus := kabanerov1alpha2.PipelineStatus{Url: "https://example.com/pipelines.tar.gz", Digest: "1111"}
+
+	key1, ok1 := getManifestCacheKey(pipelineStatus, map[string]interface{}{"StackId": "java-microprofile", "Digest": "1111"})
+	key2, ok2 := getManifestCacheKey(pipelineStatus, map[string]interface{}{"Digest": "1111", "StackId": "java-microprofile"})
+	key3, ok3 := getManifestCacheKey(pipelineStatus, map[string]interface{}{"StackId": "nodejs", "Digest": "1111"})
+	if !ok1 || !ok2 || !ok3 || key1 != key2 || key1 == key3 {
+		t.Fatalf("Unexpected keys: %v %v %v", key1, key2, key3)
+	}
+
+	pipelineStatus.Digest = ""
+	if _, ok := getManifestCacheKey(pipelineStatus, nil); ok {
+		t.Fatal("A pipeline without a digest should not be cached")
+	}
+}
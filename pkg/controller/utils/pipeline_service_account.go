@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/assets/config"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	mfc "github.com/manifestival/controller-runtime-client"
+	mf "github.com/manifestival/manifestival"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The template, shipped with the operator, for the ServiceAccount, Role and RoleBinding
+// of the runs of a pipeline.
+const pipelineServiceAccountTemplate = "orchestrations/pipeline-service-account/0.1/pipeline-service-account.yaml"
+
+// Label set on the objects created for a pipeline service account.  The value is the UID
+// of the stack, or Kabanero instance, that owns the pipeline.
+const PipelineServiceAccountOwnerLabel = "kabanero.io/pipeline-service-account-owner"
+
+// Returns the service accounts requested by the pipelines of the input spec, keyed by name,
+// with the image pull secrets of each.  Pipelines sharing a name share the service account.
+func getPipelineServiceAccounts(spec kabanerov1alpha2.ComponentSpec, assetOwner metav1.OwnerReference) map[string][]string {
+	accounts := make(map[string][]string)
+	for _, curSpec := range spec.GetVersions() {
+		for _, pipeline := range curSpec.GetPipelines() {
+			if pipeline.ServiceAccount == nil {
+				continue
+			}
+
+			name := pipeline.ServiceAccount.Name
+			if len(name) == 0 {
+				name = strings.ToLower(fmt.Sprintf("%v-%v", assetOwner.Name, pipeline.Id))
+			}
+
+			secrets := accounts[name]
+			for _, secret := range pipeline.ServiceAccount.ImagePullSecrets {
+				found := false
+				for _, cur := range secrets {
+					if cur == secret {
+						found = true
+						break
+					}
+				}
+				if !found {
+					secrets = append(secrets, secret)
+				}
+			}
+			sort.Strings(secrets)
+			accounts[name] = secrets
+		}
+	}
+	return accounts
+}
+
+// Renders the objects of a pipeline service account from the template shipped with the operator.
+func renderPipelineServiceAccount(name string, imagePullSecrets []string, namespace string, assetOwner metav1.OwnerReference) (string, error) {
+	f, err := config.Open(pipelineServiceAccountTemplate)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := template.New(pipelineServiceAccountTemplate).Parse(string(b))
+	if err != nil {
+		return "", err
+	}
+
+	var wr strings.Builder
+	err = t.Execute(&wr, map[string]interface{}{
+		"name":             name,
+		"namespace":        namespace,
+		"imagePullSecrets": imagePullSecrets,
+		"ownerUid":         string(assetOwner.UID),
+	})
+	if err != nil {
+		return "", err
+	}
+	return wr.String(), nil
+}
+
+// Creates the ServiceAccount, Role and RoleBinding of each pipeline that asks for one, in
+// the target namespace.  The objects are owned by the asset owner.  Objects created for
+// pipelines that no longer ask for a service account are deleted.
+func reconcilePipelineServiceAccounts(c AssetClient, spec kabanerov1alpha2.ComponentSpec, targetNamespace string, options ActivationOptions, assetOwner metav1.OwnerReference, logger logr.Logger) error {
+	accounts := getPipelineServiceAccounts(spec, assetOwner)
+
+	for name, imagePullSecrets := range accounts {
+		s, err := renderPipelineServiceAccount(name, imagePullSecrets, targetNamespace, assetOwner)
+		if err != nil {
+			return fmt.Errorf("Unable to render the pipeline service account %v: %v", name, err)
+		}
+
+		mOrig, err := mf.ManifestFrom(mf.Reader(strings.NewReader(s)), mf.UseClient(mfc.NewClient(manifestClient(c))), mf.UseLogger(logger.WithName("manifestival")))
+		if err != nil {
+			return err
+		}
+
+		m, err := mOrig.Transform(transforms.InjectOwnerReference(assetOwner), mf.InjectNamespace(targetNamespace), transforms.InjectCommonMetadata(options.CommonLabels, options.CommonAnnotations))
+		if err != nil {
+			return err
+		}
+
+		err = retryAssetApply(logger, name, func() error { return m.Apply() })
+		if err != nil {
+			return fmt.Errorf("Unable to apply the pipeline service account %v: %v", name, err)
+		}
+	}
+
+	return deleteUnusedPipelineServiceAccounts(c, accounts, targetNamespace, assetOwner, logger)
+}
+
+// Deletes the pipeline service accounts of the asset owner that are not in the input map,
+// along with their Role and RoleBinding.
+func deleteUnusedPipelineServiceAccounts(c AssetClient, accounts map[string][]string, targetNamespace string, assetOwner metav1.OwnerReference, logger logr.Logger) error {
+	saList := &corev1.ServiceAccountList{}
+	err := c.List(context.Background(), saList, client.InNamespace(targetNamespace), client.MatchingLabels{PipelineServiceAccountOwnerLabel: string(assetOwner.UID)})
+	if err != nil {
+		return err
+	}
+
+	for _, sa := range saList.Items {
+		if _, found := accounts[sa.Name]; found {
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("Deleting pipeline service account %v in namespace %v, which is no longer used", sa.Name, targetNamespace))
+		objs := []runtime.Object{
+			&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: sa.Name, Namespace: targetNamespace}},
+			&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: sa.Name, Namespace: targetNamespace}},
+			sa.DeepCopy(),
+		}
+		for _, obj := range objs {
+			err = c.Delete(context.Background(), obj)
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
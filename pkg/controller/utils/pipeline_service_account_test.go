@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Test that service accounts are named after the owner and pipeline by default, and that
+// pipelines sharing a service account share its image pull secrets.
+func TestGetPipelineServiceAccounts(t *testing.T) {
+	owner := metav1.OwnerReference{Kind: "Stack", Name: "java-microprofile", UID: "1"}
+	stack := kabanerov1alpha2.StackSpec{
+		Versions: []kabanerov1alpha2.StackVersion{{
+			Version: "0.2.1",
+			Pipelines: []kabanerov1alpha2.PipelineSpec{
+				{Id: "Default", ServiceAccount: &kabanerov1alpha2.PipelineServiceAccountSpec{}},
+				{Id: "build", ServiceAccount: &kabanerov1alpha2.PipelineServiceAccountSpec{Name: "builder", ImagePullSecrets: []string{"quay"}}},
+				{Id: "none"},
+			},
+		}, {
+			Version: "0.2.2",
+			Pipelines: []kabanerov1alpha2.PipelineSpec{
+				{Id: "build", ServiceAccount: &kabanerov1alpha2.PipelineServiceAccountSpec{Name: "builder", ImagePullSecrets: []string{"docker", "quay"}}},
+			},
+		}},
+	}
+
+	accounts := getPipelineServiceAccounts(stack, owner)
+	expected := map[string][]string{
+		"java-microprofile-default": nil,
+		"builder":                   {"docker", "quay"},
+	}
+	if !reflect.DeepEqual(accounts, expected) {
+		t.Fatalf("Expected service accounts %v, but found %v", expected, accounts)
+	}
+}
+
+// Unit test client that lists service accounts, and records the objects deleted.
+type serviceAccountTestClient struct {
+	client.Client
+	serviceAccounts []corev1.ServiceAccount
+	deleted         *[]string
+}
+
+func (c serviceAccountTestClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	saList := list.(*corev1.ServiceAccountList)
+	for _, sa := range c.serviceAccounts {
+		if sa.Namespace == listOpts.Namespace && listOpts.LabelSelector.Matches(labels.Set(sa.Labels)) {
+			saList.Items = append(saList.Items, sa)
+		}
+	}
+	return nil
+}
+
+func (c serviceAccountTestClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	*c.deleted = append(*c.deleted, fmt.Sprintf("%T/%v", obj, accessor.GetName()))
+	return nil
+}
+
+// Test that only the service accounts of the owner that are no longer used are deleted,
+// along with their Role and RoleBinding.
+func TestDeleteUnusedPipelineServiceAccounts(t *testing.T) {
+	logger := logf.Log.WithName("pipeline_service_account_test")
+	owner := metav1.OwnerReference{Kind: "Stack", Name: "java-microprofile", UID: "1"}
+	ownedBy := func(uid string) map[string]string {
+		return map[string]string{PipelineServiceAccountOwnerLabel: uid}
+	}
+
+	deleted := []string{}
+	c := serviceAccountTestClient{
+		serviceAccounts: []corev1.ServiceAccount{
+			{ObjectMeta: metav1.ObjectMeta{Name: "builder", Namespace: "kabanero", Labels: ownedBy("1")}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "old-builder", Namespace: "kabanero", Labels: ownedBy("1")}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "other-builder", Namespace: "kabanero", Labels: ownedBy("2")}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "kabanero"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "old-builder", Namespace: "other", Labels: ownedBy("1")}},
+		},
+		deleted: &deleted,
+	}
+
+	err := deleteUnusedPipelineServiceAccounts(c, map[string][]string{"builder": nil}, "kabanero", owner, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"*v1.RoleBinding/old-builder", "*v1.Role/old-builder", "*v1.ServiceAccount/old-builder"}
+	if !reflect.DeepEqual(deleted, expected) {
+		t.Fatalf("Expected deletions %v, but found %v", expected, deleted)
+	}
+}
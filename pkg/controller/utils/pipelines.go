@@ -3,13 +3,17 @@ package utils
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
 	mfc "github.com/manifestival/controller-runtime-client"
 	mf "github.com/manifestival/manifestival"
+	"go.opentelemetry.io/otel/attribute"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -22,8 +26,144 @@ const (
 	AssetStatusActive  = "active"
 	AssetStatusFailed  = "failed"
 	AssetStatusUnknown = "unknown"
+	AssetStatusDrifted = "drifted"
 )
 
+// Returns true if the input asset status means that the asset does not match its manifest:
+// it could not be applied, or it was modified after it was applied.
+func IsFailedAssetStatus(status string) bool {
+	return status == AssetStatusFailed || status == AssetStatusDrifted
+}
+
+const (
+	// Label set on an asset that was retained by the Retain deletion policy.
+	AssetStateLabel         = "kabanero.io/asset-state"
+	AssetStateLabelInactive = "inactive"
+
+	// Annotations set on an asset that was retained by the Retain deletion policy.
+	AssetDeactivatedByAnnotation = "kabanero.io/deactivated-by"
+	AssetDeactivatedAtAnnotation = "kabanero.io/deactivated-at"
+
+	// Labels set on a namespace created for assets, and on assets created outside the
+	// namespace of the Kabanero instance, identifying the Kabanero instance.
+	AssetNamespaceInstanceLabel          = "kabanero.io/kabanero-instance"
+	AssetNamespaceInstanceNamespaceLabel = "kabanero.io/kabanero-instance-namespace"
+)
+
+// Returns the labels that identify the input Kabanero instance on the resources it owns in
+// other namespaces, which cannot have an owner reference to it.
+func InstanceLabels(k *kabanerov1alpha2.Kabanero) map[string]string {
+	return map[string]string{
+		AssetNamespaceInstanceLabel:          k.GetName(),
+		AssetNamespaceInstanceNamespaceLabel: k.GetNamespace(),
+	}
+}
+
+// Settings from the Kabanero instance that control how pipelines are activated.
+type ActivationOptions struct {
+	// The namespaces, other than the target namespace, into which assets may be
+	// created.  If empty, assets may be created in any namespace preset in their manifest.
+	AllowedNamespaces []string
+
+	// The proxy that archive downloads are routed through, or nil.
+	ArtifactProxy *cache.ArtifactProxy
+
+	// The kinds of resources, in addition to those in the Tekton groups, that
+	// archives may contain.
+	AllowedKinds []kabanerov1alpha2.AssetGroupKind
+
+	// What happens to an asset that is no longer used.
+	DeletionPolicy string
+
+	// The namespace that trigger assets presetting the default trigger namespace are
+	// created in.
+	TriggerNamespace string
+
+	// Values, provided by the user, that are added to the rendering context of the
+	// pipeline archives.
+	RenderingValues map[string]string
+
+	// The labels of the namespaces created for assets, or nil if missing namespaces
+	// are not created.
+	NamespaceLabels map[string]string
+
+	// The labels set on the assets created outside the target namespace, or nil.
+	InstanceLabels map[string]string
+
+	// The labels and annotations, provided by the user, that are set on every asset.
+	CommonLabels      map[string]string
+	CommonAnnotations map[string]string
+
+	// What happens to an asset that was modified after it was applied.
+	DriftPolicy string
+
+	// Rendering context entries that only apply to the pipelines of one version, keyed
+	// by version.  An archive shared by several versions is rendered with the entries
+	// of the first version that uses it.
+	VersionRenderingContext map[string]map[string]interface{}
+
+	// The storage bound to the workspaces of the PipelineRuns created by TriggerTemplates.
+	Workspaces []kabanerov1alpha2.WorkspaceBindingSpec
+}
+
+// Sets the rendering context entries of the input version, removing those of the
+// other versions.
+func setVersionRenderingContext(renderingContext map[string]interface{}, versionContexts map[string]map[string]interface{}, version string) {
+	for _, values := range versionContexts {
+		for key := range values {
+			delete(renderingContext, key)
+		}
+	}
+	for key, value := range versionContexts[version] {
+		renderingContext[key] = value
+	}
+}
+
+// Builds the activation options from the input Kabanero instance.  A nil instance
+// results in the default options.
+func GetActivationOptions(c client.Reader, k *kabanerov1alpha2.Kabanero) (ActivationOptions, error) {
+	options := ActivationOptions{}
+	if k == nil {
+		return options, nil
+	}
+
+	options.AllowedNamespaces = k.Spec.AllowedAssetNamespaces
+	options.AllowedKinds = k.Spec.AllowedAssetKinds
+	options.DeletionPolicy = k.Spec.AssetDeletionPolicy
+	options.DriftPolicy = k.Spec.AssetDriftPolicy
+	options.TriggerNamespace = GetTriggerNamespace(k)
+	options.Workspaces = k.Spec.Workspaces
+
+	options.InstanceLabels = InstanceLabels(k)
+	options.CommonLabels = k.Spec.CommonLabels
+	options.CommonAnnotations = k.Spec.CommonAnnotations
+	if k.Spec.CreateAssetNamespaces {
+		options.NamespaceLabels = InstanceLabels(k)
+	}
+
+	proxy, err := cache.GetArtifactProxy(c, k.GetNamespace(), k.Spec.ArtifactProxy)
+	if err != nil {
+		return options, err
+	}
+	options.ArtifactProxy = proxy
+
+	renderingValues, err := GetRenderingValues(c, k.GetNamespace(), k.Spec.RenderingContextConfigMap)
+	if err != nil {
+		return options, err
+	}
+	options.RenderingValues = renderingValues
+
+	return options, nil
+}
+
+// Returns the namespace that the Tekton triggers run in for the input Kabanero instance.
+func GetTriggerNamespace(k *kabanerov1alpha2.Kabanero) string {
+	if k == nil || len(k.Spec.TriggerNamespace) == 0 {
+		return kabanerov1alpha2.DefaultTriggerNamespace
+	}
+	return k.Spec.TriggerNamespace
+}
+
 // A key to the pipeline use count map
 type PipelineUseMapKey struct {
 	Url        string
@@ -51,7 +191,18 @@ func gitReleaseSpecToGitReleaseInfo(gitRelease kabanerov1alpha2.GitReleaseSpec)
 	return kabanerov1alpha2.GitReleaseInfo{Hostname: gitRelease.Hostname, Organization: gitRelease.Organization, Project: gitRelease.Project, Release: gitRelease.Release, AssetName: gitRelease.AssetName}
 }
 
-func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alpha2.ComponentStatus, targetNamespace string, renderingContext map[string]interface{}, assetOwner metav1.OwnerReference, c client.Client, logger logr.Logger) (PipelineUseMap, error) {
+// Activates the pipelines referenced by the input spec.  Assets are created in the target
+// namespace, unless the manifest presets a namespace.  A preset namespace must be the
+// target namespace or be in the allowed namespaces list, unless the list is empty.
+// Downloads are abandoned when the input context is done.
+func ActivatePipelines(ctx context.Context, spec kabanerov1alpha2.ComponentSpec, status kabanerov1alpha2.ComponentStatus, targetNamespace string, options ActivationOptions, renderingContext map[string]interface{}, assetOwner metav1.OwnerReference, c AssetClient, logger logr.Logger) (PipelineUseMap, error) {
+
+	// Archives can refer to the trigger namespace instead of presetting tekton-pipelines.
+	if len(options.TriggerNamespace) != 0 {
+		renderingContext["TriggerNamespace"] = options.TriggerNamespace
+	}
+	renderingContext[workspacesRenderingKey] = workspaceRenderingContext(options.Workspaces)
+	addRenderingValues(renderingContext, options.RenderingValues, logger)
 
 	// Multiple versions of the same stack, could be using the same pipeline zip.  Count how many
 	// times each pipeline has been used.
@@ -94,6 +245,9 @@ func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alp
 	// When processing the pipelines currently referenced in the stack spec, save
 	// off whether we should disable certificate verification checking per-resource.
 	certVerification := make(map[PipelineUseMapKey]bool)
+	renderers := make(map[PipelineUseMapKey]string)
+	signatures := make(map[PipelineUseMapKey]*kabanerov1alpha2.PipelineSignatureSpec)
+	pipelineVersions := make(map[PipelineUseMapKey]string)
 	for _, curSpec := range spec.GetVersions() {
 		for _, pipeline := range curSpec.GetPipelines() {
 			key := PipelineUseMapKey{Digest: pipeline.Sha256}
@@ -101,8 +255,13 @@ func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alp
 				key.GitRelease = gitReleaseSpecToGitReleaseInfo(pipeline.GitRelease)
 				certVerification[key] = pipeline.GitRelease.SkipCertVerification
 			} else {
-				key.Url = pipeline.Https.Url
-				certVerification[key] = pipeline.Https.SkipCertVerification
+				key.Url = pipeline.ArchiveUrl()
+				certVerification[key] = pipeline.SkipCertVerification()
+			}
+			renderers[key] = pipeline.Renderer
+			signatures[key] = pipeline.Signature
+			if _, found := pipelineVersions[key]; !found {
+				pipelineVersions[key] = curSpec.GetVersion()
 			}
 			cur := pipelineVersion{PipelineUseMapKey: key, version: curSpec.GetVersion()}
 			if assetsToDecrement[cur] == true {
@@ -134,10 +293,19 @@ func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alp
 		value.useCount++
 	}
 
+	// Find the pipelines whose archive changed without changing their Id.  The assets of
+	// the old archive are removed once the new archive is decoded, and only if the new
+	// archive no longer contains them.
+	replaced := findReplacedPipelines(spec, status, assetUseMap)
+	pendingPrune := make(map[*PipelineUseMapValue]bool)
+	for _, value := range replaced {
+		pendingPrune[value] = true
+	}
+
 	// Now iterate thru the asset use map and delete any assets with a use count of 0,
 	// and create any assets with a positive use count.
 	for _, value := range assetUseMap {
-		if value.useCount <= 0 {
+		if value.useCount <= 0 && !pendingPrune[value] {
 			logger.Info(fmt.Sprintf("Deleting assets with use count %v: %v", value.useCount, value))
 
 			for _, asset := range value.ActiveAssets {
@@ -146,7 +314,7 @@ func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alp
 					asset.Namespace = targetNamespace
 				}
 
-				DeleteAsset(c, asset, assetOwner, logger)
+				DeleteAsset(c, asset, assetOwner, options.DeletionPolicy, logger)
 			}
 		}
 	}
@@ -155,53 +323,96 @@ func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alp
 		if value.useCount > 0 {
 			logger.Info(fmt.Sprintf("Creating assets with use count %v: %v", value.useCount, value))
 
+			// The assets carried over from the archive that this one replaced.
+			carriedOver := make(map[assetKey]bool)
+
 			// Check to see if there is already an asset list.  If not, read the manifests and
 			// create one.
 			if len(value.ActiveAssets) == 0 {
+				// Don't start a new download if the operator is stopping.
+				if IsActivationStopping() {
+					logger.Info(fmt.Sprintf("The operator is stopping. Skipping the manifest download for: %v", value))
+					continue
+				}
+
 				// Add the Digest to the rendering context. No need to validate if the digest was tampered
 				// with here. Later one and before we do anything with this, we will have validated the specified
 				// digest against the generated digest from the archive.
-				if len(value.Digest) >= 8 {
-					renderingContext["Digest"] = value.Digest[0:8]
-				} else {
-					renderingContext["Digest"] = "nodigest"
-				}
+				renderingContext["Digest"] = RenderingDigest(value.Digest)
 
 				// Retrieve manifests as unstructured.  If we could not get them, skip.
-				manifests, err := GetManifests(c, targetNamespace, value.PipelineStatus, renderingContext, certVerification[key], logger)
+				value.Renderer = renderers[key]
+				value.Signature = signatures[key]
+				setVersionRenderingContext(renderingContext, options.VersionRenderingContext, pipelineVersions[key])
+				manifests, err := GetManifests(ctx, c, targetNamespace, value.PipelineStatus, renderingContext, certVerification[key], options.ArtifactProxy, logger)
 				if err != nil {
 					logger.Error(err, fmt.Sprintf("Error retrieving archive manifests: %v", value))
 					value.ManifestError = err
 					continue
 				}
 
-				// Save the manifests for later.
+				// Save the manifests for later, in the order they are applied.
+				sortStackAssets(manifests)
 				value.manifests = manifests
 
 				// Create the asset status slice, but don't apply anything yet.
 				for _, asset := range manifests {
-					// Figure out what namespace we should create the object in.
-					value.ActiveAssets = append(value.ActiveAssets, kabanerov1alpha2.RepositoryAssetStatus{
+					assetStatus := kabanerov1alpha2.RepositoryAssetStatus{
 						Name:          asset.Name,
-						Namespace:     getNamespaceForObject(&asset.Yaml, targetNamespace),
 						Group:         asset.Group,
 						Version:       asset.Version,
 						Kind:          asset.Kind,
 						Digest:        asset.Sha256,
-						Status:        AssetStatusUnknown,
-						StatusMessage: "Asset has not been applied yet.",
-					})
+						ArchiveDigest: value.Digest,
+					}
+					setAssetStatus(&assetStatus, AssetStatusUnknown, "Asset has not been applied yet.", "")
+
+					// Figure out what namespace we should create the object in.
+					namespace, err := getNamespaceForObject(&asset.Yaml, targetNamespace, options.TriggerNamespace, options.AllowedNamespaces)
+					assetStatus.Namespace = namespace
+					if err != nil {
+						logger.Info(fmt.Sprintf("Rejecting asset %v: %v", asset.Name, err.Error()))
+						setAssetStatus(&assetStatus, AssetStatusFailed, "Manifest rejected: "+err.Error(), kabanerov1alpha2.StackReasonManifestRejected)
+					}
+
+					value.ActiveAssets = append(value.ActiveAssets, assetStatus)
+				}
+
+				// Remove the assets that the previous archive of this pipeline had, and this
+				// one does not.
+				if oldValue := replaced[key]; oldValue != nil {
+					carriedOver = pruneReplacedAssets(c, oldValue, value.ActiveAssets, targetNamespace, assetOwner, options.DeletionPolicy, logger)
+					delete(pendingPrune, oldValue)
 				}
 			}
 
 			// Now go thru the asset list and see if the objects are there.  If not, create them.
-			for index, asset := range value.ActiveAssets {
+			// The assets are processed in kind order, so that referenced assets exist first.
+			for _, index := range assetApplyOrder(value.ActiveAssets) {
+				asset := value.ActiveAssets[index]
 				// Old assets may not have a namespace set - correct that now.
 				if len(asset.Namespace) == 0 {
 					asset.Namespace = targetNamespace
 					value.ActiveAssets[index].Namespace = asset.Namespace
 				}
 
+				// Never create an asset in a namespace that is not allowed.  The asset list
+				// may have been saved before the allowed namespaces were changed.
+				if !isAssetNamespaceAllowed(asset.Namespace, targetNamespace, options.AllowedNamespaces) {
+					setAssetStatus(&value.ActiveAssets[index], AssetStatusFailed, fmt.Sprintf("Manifest rejected: namespace %v is not in the list of allowed asset namespaces", asset.Namespace), kabanerov1alpha2.StackReasonManifestRejected)
+					continue
+				}
+
+				// If the operator is stopping, don't apply anything else.  The asset list is
+				// saved in the status, so the next reconcile resumes from here instead of
+				// starting the activation over.
+				if IsActivationStopping() {
+					if asset.Status != AssetStatusActive {
+						setAssetStatus(&value.ActiveAssets[index], AssetStatusUnknown, AssetStatusMessageInterrupted, kabanerov1alpha2.StackReasonActivationInterrupted)
+					}
+					continue
+				}
+
 				u := &unstructured.Unstructured{}
 				u.SetGroupVersionKind(schema.GroupVersionKind{
 					Group:   asset.Group,
@@ -214,27 +425,41 @@ func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alp
 					Name:      asset.Name,
 				}, u)
 
-				if err != nil {
-					if errors.IsNotFound(err) == false {
+				// An asset carried over from the previous archive is applied again, so that
+				// it matches the new archive.
+				reapply := err == nil && carriedOver[newAssetKey(asset, targetNamespace)]
+
+				// An asset that was modified since it was applied is applied again, or reported,
+				// depending on the drift policy.
+				drifted := false
+				if err == nil && !reapply && isAssetDrifted(u, asset) {
+					if options.DriftPolicy == kabanerov1alpha2.AssetDriftPolicyReport {
+						logger.Info(fmt.Sprintf("Asset %v in namespace %v was modified after it was applied", asset.Name, asset.Namespace))
+						drifted = true
+					} else {
+						logger.Info(fmt.Sprintf("Asset %v in namespace %v was modified after it was applied. Applying it again.", asset.Name, asset.Namespace))
+						reapply = true
+					}
+				}
+
+				if err != nil || reapply {
+					if err != nil && errors.IsNotFound(err) == false {
 						logger.Error(err, fmt.Sprintf("Unable to check asset name %v", asset.Name))
-						value.ActiveAssets[index].Status = AssetStatusUnknown
-						value.ActiveAssets[index].StatusMessage = "Unable to check asset: " + err.Error()
+						setAssetStatus(&value.ActiveAssets[index], AssetStatusUnknown, "Unable to check asset: "+err.Error(), kabanerov1alpha2.StackReasonAssetCheckFailed)
 					} else {
 						// Make sure the manifests are loaded.
 						if len(value.manifests) == 0 {
 							// Add the Digest to the rendering context.
-							if len(value.Digest) >= 8 {
-								renderingContext["Digest"] = value.Digest[0:8]
-							} else {
-								renderingContext["Digest"] = "nodigest"
-							}
+							renderingContext["Digest"] = RenderingDigest(value.Digest)
 
 							// Retrieve manifests as unstructured
-							manifests, err := GetManifests(c, targetNamespace, value.PipelineStatus, renderingContext, certVerification[key], logger)
+							value.Renderer = renderers[key]
+							value.Signature = signatures[key]
+							setVersionRenderingContext(renderingContext, options.VersionRenderingContext, pipelineVersions[key])
+							manifests, err := GetManifests(ctx, c, targetNamespace, value.PipelineStatus, renderingContext, certVerification[key], options.ArtifactProxy, logger)
 							if err != nil {
 								logger.Error(err, fmt.Sprintf("Object %v not found and manifests not available: %v", asset.Name, value))
-								value.ActiveAssets[index].Status = AssetStatusFailed
-								value.ActiveAssets[index].StatusMessage = "Manifests are no longer available at specified URL"
+								setAssetStatus(&value.ActiveAssets[index], AssetStatusFailed, "Manifests are no longer available at specified URL", ManifestErrorReason(err))
 							} else {
 								// Save the manifests for later.
 								value.manifests = manifests
@@ -246,42 +471,69 @@ func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alp
 							if asset.Name == manifest.Name {
 								resources := []unstructured.Unstructured{manifest.Yaml}
 
-								// Only allow Group: tekton.dev
+								// Only allow the Tekton groups, and the kinds allowed by the Kabanero instance.
 								allowed := true
 								for _, resource := range resources {
-									if (resource.GroupVersionKind().Group != "tekton.dev") && (resource.GroupVersionKind().Group != "triggers.tekton.dev") {
-										value.ActiveAssets[index].Status = AssetStatusFailed
-										value.ActiveAssets[index].StatusMessage = "Manifest rejected: contains a Group not equal to tekton.dev or triggers.tekton.dev"
+									gvk := resource.GroupVersionKind()
+									if !kabanerov1alpha2.IsAssetGroupKindAllowed(gvk.Group, gvk.Kind, options.AllowedKinds) {
+										setAssetStatus(&value.ActiveAssets[index], AssetStatusFailed, fmt.Sprintf("Manifest rejected: kind %v in group %v is not in the list of allowed asset kinds", gvk.Kind, gvk.Group), kabanerov1alpha2.StackReasonManifestRejected)
+										allowed = false
+									} else if err := validateAssetManifest(&resource); err != nil {
+										logger.Info(fmt.Sprintf("Invalid manifest for asset %v: %v", asset.Name, err.Error()))
+										setAssetStatus(&value.ActiveAssets[index], AssetStatusFailed, "Manifest is not valid: "+err.Error(), kabanerov1alpha2.StackReasonManifestInvalid)
 										allowed = false
 									}
 								}
 
 								if allowed == true {
-									mOrig, err := mf.ManifestFrom(mf.Slice(resources), mf.UseClient(mfc.NewClient(c)), mf.UseLogger(logger.WithName("manifestival")))
+									mOrig, err := mf.ManifestFrom(mf.Slice(resources), mf.UseClient(mfc.NewClient(manifestClient(c))), mf.UseLogger(logger.WithName("manifestival")))
 
-									logger.Info(fmt.Sprintf("Resources: %v", mOrig.Resources()))
+									logger.V(LogLevelTrace).Info(fmt.Sprintf("Resources: %v", mOrig.Resources()))
 
 									transforms := []mf.Transformer{
 										transforms.InjectOwnerReference(assetOwner),
 										mf.InjectNamespace(asset.Namespace),
+										transforms.InjectCommonMetadata(options.CommonLabels, options.CommonAnnotations),
+									}
+									if asset.Namespace != targetNamespace {
+										// Owner references cannot cross namespaces, so the owners are
+										// recorded in labels and an annotation instead.
+										var existing *unstructured.Unstructured
+										if reapply {
+											existing = u
+										}
+										transforms = append(transforms, injectCrossNamespaceOwner(existing, assetOwner))
+									} else if reapply {
+										transforms = append(transforms, keepOwnerReferences(u.GetOwnerReferences(), assetOwner))
+									}
+									if options.InstanceLabels != nil && asset.Namespace != targetNamespace {
+										transforms = append(transforms, injectLabels(options.InstanceLabels))
 									}
 
 									m, err := mOrig.Transform(transforms...)
 									if err != nil {
 										logger.Error(err, fmt.Sprintf("Error transforming manifests for %v", asset.Name))
-										value.ActiveAssets[index].Status = AssetStatusFailed
-										value.ActiveAssets[index].Status = err.Error()
+										setAssetStatus(&value.ActiveAssets[index], AssetStatusFailed, err.Error(), kabanerov1alpha2.StackReasonManifestRejected)
 									} else {
-										logger.Info(fmt.Sprintf("Applying resources: %v", m.Resources()))
-										err = m.Apply()
+										// A namespace preset in the manifest may not exist yet.
+										if options.NamespaceLabels != nil && asset.Namespace != targetNamespace {
+											err = ensureAssetNamespace(c, asset.Namespace, options.NamespaceLabels, logger)
+										}
+										if err == nil {
+											logger.Info(fmt.Sprintf("Applying asset %v in namespace %v", asset.Name, asset.Namespace))
+											logger.V(LogLevelDebug).Info(fmt.Sprintf("Applying resources: %v", m.Resources()))
+											_, span := StartSpan(ctx, "ApplyAsset", attribute.String("asset.name", asset.Name), attribute.String("asset.namespace", asset.Namespace))
+											err = retryAssetApply(logger, asset.Name, func() error { return m.Apply() })
+											EndSpan(span, err)
+										}
 										if err != nil {
 											// Update the asset status with the error message
 											logger.Error(err, "Error installing the resource", "resource", asset.Name)
-											value.ActiveAssets[index].Status = AssetStatusFailed
-											value.ActiveAssets[index].StatusMessage = err.Error()
+											setAssetStatus(&value.ActiveAssets[index], AssetStatusFailed, err.Error(), kabanerov1alpha2.StackReasonManifestApplyFailed)
 										} else {
-											value.ActiveAssets[index].Status = AssetStatusActive
-											value.ActiveAssets[index].StatusMessage = ""
+											setAssetStatus(&value.ActiveAssets[index], AssetStatusActive, "", "")
+											recordAssetApply(&value.ActiveAssets[index], value.Digest)
+											recordAppliedHash(c, &value.ActiveAssets[index], logger)
 										}
 									}
 								}
@@ -290,39 +542,102 @@ func ActivatePipelines(spec kabanerov1alpha2.ComponentSpec, status kabanerov1alp
 					}
 				} else {
 					// Add owner reference
-					ownerRefs := u.GetOwnerReferences()
-					foundOurselves := false
-					for _, ownerRef := range ownerRefs {
-						if ownerRef.UID == assetOwner.UID {
-							foundOurselves = true
-						}
+					if asset.Namespace != targetNamespace {
+						err = adoptCrossNamespaceAsset(c, u, assetOwner, logger)
+					} else {
+						err = adoptAsset(c, u, assetOwner, logger)
+					}
+					if err != nil {
+						logger.Error(err, fmt.Sprintf("Unable to add owner reference to %v", asset.Name))
 					}
 
-					if foundOurselves == false {
-
-						// There can only be one 'controller' reference, so additional references should not
-						// be controller references.  It's not clear what Kubernetes does with this field.
-						ownerRefs = append(ownerRefs, assetOwner)
-						u.SetOwnerReferences(ownerRefs)
+					if drifted {
+						setAssetStatus(&value.ActiveAssets[index], AssetStatusDrifted, "The asset was modified after it was applied.", kabanerov1alpha2.StackReasonAssetDrifted)
+					} else {
+						setAssetStatus(&value.ActiveAssets[index], AssetStatusActive, "", "")
 
-						err = c.Update(context.TODO(), u)
-						if err != nil {
-							logger.Error(err, fmt.Sprintf("Unable to add owner reference to %v", asset.Name))
+						// Assets applied by an earlier release, or by another owner, have no hash yet.
+						// Take the current content as the baseline.
+						if len(asset.AppliedHash) == 0 {
+							if hash, err := assetContentHash(u); err == nil {
+								value.ActiveAssets[index].AppliedHash = hash
+							}
 						}
 					}
-
-					value.ActiveAssets[index].Status = AssetStatusActive
-					value.ActiveAssets[index].StatusMessage = ""
 				}
 			}
 		}
 	}
 
+	// The new archive of a replaced pipeline could not be decoded.  Remove the assets of
+	// the old archive, since the status will no longer refer to them.
+	for value := range pendingPrune {
+		logger.Info(fmt.Sprintf("Deleting assets of replaced pipeline archive: %v", value))
+		for _, asset := range value.ActiveAssets {
+			if len(asset.Namespace) == 0 {
+				asset.Namespace = targetNamespace
+			}
+
+			DeleteAsset(c, asset, assetOwner, options.DeletionPolicy, logger)
+		}
+	}
+
+	// The runs of the pipelines may need an identity managed by the operator.  A failure
+	// here does not affect the status of the assets, and is retried on the next reconcile.
+	err := reconcilePipelineServiceAccounts(c, spec, targetNamespace, options, assetOwner, logger)
+	if err != nil {
+		logger.Error(err, "Unable to reconcile the pipeline service accounts")
+	}
+
 	return assetUseMap, nil
 }
 
+// Sets the status of an asset.  The transition time is updated when the status changes.
+func setAssetStatus(asset *kabanerov1alpha2.RepositoryAssetStatus, status string, message string, reason string) {
+	if asset.Status != status || asset.LastTransitionTime == nil {
+		now := metav1.Now()
+		asset.LastTransitionTime = &now
+	}
+	asset.Status = status
+	asset.StatusMessage = message
+	asset.Reason = reason
+}
+
+// Records that an asset was applied from the pipeline archive with the input digest.
+func recordAssetApply(asset *kabanerov1alpha2.RepositoryAssetStatus, archiveDigest string) {
+	now := metav1.Now()
+	asset.LastApplied = &now
+	asset.ArchiveDigest = archiveDigest
+}
+
+// Records the hash of the content of an asset that was just applied, as stored in the
+// cluster.  The content is read back so that defaults filled in by the cluster are included.
+func recordAppliedHash(c Getter, asset *kabanerov1alpha2.RepositoryAssetStatus, logger logr.Logger) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   asset.Group,
+		Version: asset.Version,
+		Kind:    asset.Kind,
+	})
+
+	err := c.Get(context.Background(), client.ObjectKey{Namespace: asset.Namespace, Name: asset.Name}, u)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to read back asset %v in namespace %v", asset.Name, asset.Namespace))
+		return
+	}
+
+	hash, err := assetContentHash(u)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Unable to hash asset %v in namespace %v", asset.Name, asset.Namespace))
+		return
+	}
+	asset.AppliedHash = hash
+}
+
 // Deletes an asset.  This can mean removing an object owner, or completely deleting it.
-func DeleteAsset(c client.Client, asset kabanerov1alpha2.RepositoryAssetStatus, assetOwner metav1.OwnerReference, logger logr.Logger) error {
+// When the last owner is removed, the deletion policy decides whether the object is
+// deleted, orphaned, or retained and labelled as inactive.
+func DeleteAsset(c AssetClient, asset kabanerov1alpha2.RepositoryAssetStatus, assetOwner metav1.OwnerReference, deletionPolicy string, logger logr.Logger) error {
 	if asset.Status == AssetStatusUnknown || asset.Status == AssetStatusFailed {
 		logger.Info(fmt.Sprintf("Ignoring delete processing for asset with failed or unknown status. Asset name: %v. Namespace %v. Status: %v", asset.Name, asset.Namespace, asset.Status))
 		return nil
@@ -346,47 +661,169 @@ func DeleteAsset(c client.Client, asset kabanerov1alpha2.RepositoryAssetStatus,
 			return err
 		}
 	} else {
-		// Get the owner references.  See if we're the last one.
-		ownerRefs := u.GetOwnerReferences()
-		newOwnerRefs := []metav1.OwnerReference{}
-		for _, ownerRef := range ownerRefs {
-			if ownerRef.UID != assetOwner.UID {
-				newOwnerRefs = append(newOwnerRefs, ownerRef)
-			}
+		return deleteAssetObject(c, u, assetOwner, deletionPolicy, logger)
+	}
+
+	return nil
+}
+
+// Removes an owner from an existing asset.  When it was the last owner, the deletion policy
+// decides whether the object is deleted, orphaned, or retained.
+func deleteAssetObject(c AssetClient, u *unstructured.Unstructured, assetOwner metav1.OwnerReference, deletionPolicy string, logger logr.Logger) error {
+	// Get the owner references.  See if we're the last one.
+	ownerRefs := u.GetOwnerReferences()
+	newOwnerRefs := []metav1.OwnerReference{}
+	for _, ownerRef := range ownerRefs {
+		if ownerRef.UID != assetOwner.UID {
+			newOwnerRefs = append(newOwnerRefs, ownerRef)
 		}
+	}
+	lastOwner := len(newOwnerRefs) == 0
 
-		if len(newOwnerRefs) == 0 {
-			err = c.Delete(context.TODO(), u)
-			if err != nil {
-				logger.Error(err, fmt.Sprintf("Unable to delete asset name %v in namespace %v. Status: %v", asset.Name, asset.Namespace, asset.Status))
-				return err
-			}
-		} else {
-			u.SetOwnerReferences(newOwnerRefs)
-			err = c.Update(context.TODO(), u)
-			if err != nil {
-				logger.Error(err, fmt.Sprintf("Unable to delete owner reference from %v in namespace %v. Status: %v", asset.Name, asset.Namespace, asset.Status))
-				return err
+	// The owners of a cross-namespace asset are recorded in an annotation instead.
+	if isCrossNamespaceAsset(u) {
+		lastOwner = RemoveCrossNamespaceOwner(u, assetOwner) == 0
+		newOwnerRefs = nil
+	}
+
+	if lastOwner && (deletionPolicy == "" || deletionPolicy == kabanerov1alpha2.AssetDeletionPolicyDelete) {
+		err := c.Delete(context.TODO(), u)
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, fmt.Sprintf("Unable to delete asset name %v in namespace %v", u.GetName(), u.GetNamespace()))
+			return err
+		}
+	} else {
+		if lastOwner {
+			logger.Info(fmt.Sprintf("Keeping asset %v in namespace %v because of deletion policy %v", u.GetName(), u.GetNamespace(), deletionPolicy))
+			if deletionPolicy == kabanerov1alpha2.AssetDeletionPolicyRetain {
+				setRetainedAssetState(u, assetOwner)
 			}
 		}
+
+		u.SetOwnerReferences(newOwnerRefs)
+		err := c.Update(context.TODO(), u)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Unable to delete owner reference from %v in namespace %v", u.GetName(), u.GetNamespace()))
+			return err
+		}
 	}
 
 	return nil
 }
 
+// Labels an asset as inactive, and records which owner deactivated it.
+func setRetainedAssetState(u *unstructured.Unstructured, assetOwner metav1.OwnerReference) {
+	labels := u.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[AssetStateLabel] = AssetStateLabelInactive
+	u.SetLabels(labels)
+
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[AssetDeactivatedByAnnotation] = fmt.Sprintf("%v/%v", assetOwner.Kind, assetOwner.Name)
+	annotations[AssetDeactivatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	u.SetAnnotations(annotations)
+}
+
+// Removes the inactive label and annotations from a retained asset.  Returns true
+// if the asset was retained.
+func clearRetainedAssetState(u *unstructured.Unstructured) bool {
+	labels := u.GetLabels()
+	if _, ok := labels[AssetStateLabel]; !ok {
+		return false
+	}
+	delete(labels, AssetStateLabel)
+	u.SetLabels(labels)
+
+	annotations := u.GetAnnotations()
+	delete(annotations, AssetDeactivatedByAnnotation)
+	delete(annotations, AssetDeactivatedAtAnnotation)
+	u.SetAnnotations(annotations)
+
+	return true
+}
+
 // Some objects need to get created in a specific namespace.  Try and figure out what that is.
-func getNamespaceForObject(u *unstructured.Unstructured, defaultNamespace string) string {
+// An error is returned if the object presets a namespace that is not allowed.
+func getNamespaceForObject(u *unstructured.Unstructured, defaultNamespace string, triggerNamespace string, allowedNamespaces []string) (string, error) {
 	kind := u.GetKind()
 
 	// The namespace for TriggerBinding, TriggerTemplate and EventListener is decided as follows:
 	// If the entry spec.metadata.namespace has a preset value, continue to use it. Otherwise, use
-	// the input default namespace.
+	// the input default namespace.  Archives written for the default trigger namespace are
+	// redirected to the configured trigger namespace.
 	if (kind == "TriggerBinding") || (kind == "TriggerTemplate") || (kind == "EventListener") {
 		configuredNamespace := u.GetNamespace()
+		if configuredNamespace == kabanerov1alpha2.DefaultTriggerNamespace && len(triggerNamespace) != 0 {
+			configuredNamespace = triggerNamespace
+		}
 		if len(configuredNamespace) != 0 {
-			return u.GetNamespace()
+			if !isAssetNamespaceAllowed(configuredNamespace, defaultNamespace, allowedNamespaces) {
+				return configuredNamespace, fmt.Errorf("namespace %v is not in the list of allowed asset namespaces", configuredNamespace)
+			}
+			return configuredNamespace, nil
+		}
+	}
+
+	return defaultNamespace, nil
+}
+
+// The reader used to look up asset namespaces.  It should read from the API server, so
+// that the controller's cache does not start watching every namespace in the cluster.
+// If it is not set, the client passed to ActivatePipelines is used.
+var namespaceReader client.Reader
+
+// Sets the reader used to look up asset namespaces.  Controllers set it to the manager's
+// API reader when they are added to the manager.
+func SetNamespaceReader(reader client.Reader) {
+	namespaceReader = reader
+}
+
+// Creates the input namespace, with the input labels, if it does not exist.
+func ensureAssetNamespace(c AssetClient, namespace string, labels map[string]string, logger logr.Logger) error {
+	var reader client.Reader = c
+	if namespaceReader != nil {
+		reader = namespaceReader
+	}
+
+	ns := &corev1.Namespace{}
+	err := reader.Get(context.Background(), client.ObjectKey{Name: namespace}, ns)
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+
+	ns = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: make(map[string]string)}}
+	for key, value := range labels {
+		ns.Labels[key] = value
+	}
+
+	logger.Info(fmt.Sprintf("Creating namespace %v for pipeline assets", namespace))
+	err = c.Create(context.Background(), ns)
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// Returns true if an asset can be created in the input namespace.  The default namespace
+// is always allowed.  An empty allowed namespaces list does not restrict anything.
+func isAssetNamespaceAllowed(namespace string, defaultNamespace string, allowedNamespaces []string) bool {
+	if len(allowedNamespaces) == 0 || namespace == defaultNamespace {
+		return true
+	}
+
+	for _, allowedNamespace := range allowedNamespaces {
+		if namespace == allowedNamespace {
+			return true
 		}
 	}
 
-	return defaultNamespace
+	return false
 }
@@ -0,0 +1,330 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Test that a preset namespace outside of the allowed namespaces is rejected.
+func TestGetNamespaceForObjectAllowedNamespaces(t *testing.T) {
+	u := &unstructured.Unstructured{}
+	u.SetKind("EventListener")
+	u.SetNamespace("other-namespace")
+
+	// No allowed namespaces, so anything goes.
+	namespace, err := getNamespaceForObject(u, "kabanero", "", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if namespace != "other-namespace" {
+		t.Fatalf("Expected namespace other-namespace, but was %v", namespace)
+	}
+
+	// The preset namespace is allowed.
+	namespace, err = getNamespaceForObject(u, "kabanero", "", []string{"tekton-pipelines", "other-namespace"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if namespace != "other-namespace" {
+		t.Fatalf("Expected namespace other-namespace, but was %v", namespace)
+	}
+
+	// The preset namespace is not allowed.
+	namespace, err = getNamespaceForObject(u, "kabanero", "", []string{"tekton-pipelines"})
+	if err == nil {
+		t.Fatalf("Expected an error for namespace %v, but there was none", namespace)
+	}
+
+	// The default namespace is always allowed.
+	u.SetNamespace("kabanero")
+	namespace, err = getNamespaceForObject(u, "kabanero", "", []string{"tekton-pipelines"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Objects without a preset namespace go in the default namespace.
+	u.SetKind("Pipeline")
+	u.SetNamespace("other-namespace")
+	namespace, err = getNamespaceForObject(u, "kabanero", "", []string{"tekton-pipelines"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if namespace != "kabanero" {
+		t.Fatalf("Expected namespace kabanero, but was %v", namespace)
+	}
+}
+
+// Test that trigger assets presetting tekton-pipelines go to the configured trigger namespace.
+func TestGetNamespaceForObjectTriggerNamespace(t *testing.T) {
+	u := &unstructured.Unstructured{}
+	u.SetKind("TriggerTemplate")
+	u.SetNamespace("tekton-pipelines")
+
+	namespace, err := getNamespaceForObject(u, "kabanero", "openshift-pipelines", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if namespace != "openshift-pipelines" {
+		t.Fatalf("Expected namespace openshift-pipelines, but was %v", namespace)
+	}
+
+	// The configured namespace is subject to the allowed namespaces.
+	_, err = getNamespaceForObject(u, "kabanero", "openshift-pipelines", []string{"tekton-pipelines"})
+	if err == nil {
+		t.Fatal("Expected an error for namespace openshift-pipelines, but there was none")
+	}
+
+	// Other preset namespaces are not redirected.
+	u.SetNamespace("other-namespace")
+	namespace, err = getNamespaceForObject(u, "kabanero", "openshift-pipelines", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if namespace != "other-namespace" {
+		t.Fatalf("Expected namespace other-namespace, but was %v", namespace)
+	}
+}
+
+// Unit test client that stores unstructured objects by name.
+type deleteAssetTestClient struct {
+	client.Client
+	objs map[client.ObjectKey]*unstructured.Unstructured
+}
+
+func (c deleteAssetTestClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	stored, ok := c.objs[key]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	stored.DeepCopyInto(obj.(*unstructured.Unstructured))
+	return nil
+}
+
+func (c deleteAssetTestClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	u := obj.(*unstructured.Unstructured)
+	c.objs[client.ObjectKey{Name: u.GetName(), Namespace: u.GetNamespace()}] = u.DeepCopy()
+	return nil
+}
+
+func (c deleteAssetTestClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	u := obj.(*unstructured.Unstructured)
+	delete(c.objs, client.ObjectKey{Name: u.GetName(), Namespace: u.GetNamespace()})
+	return nil
+}
+
+// Test that the deletion policy decides what happens to an asset when its last owner is removed.
+func TestDeleteAssetDeletionPolicy(t *testing.T) {
+	logger := logf.Log.WithName("pipelines_test")
+	owner := metav1.OwnerReference{APIVersion: "kabanero.io/v1alpha2", Kind: "Stack", Name: "java-microprofile", UID: "1"}
+	asset := kabanerov1alpha2.RepositoryAssetStatus{Name: "build-task", Namespace: "kabanero", Version: "v1alpha1", Kind: "Task", Status: AssetStatusActive}
+	key := client.ObjectKey{Name: "build-task", Namespace: "kabanero"}
+
+	newClient := func() deleteAssetTestClient {
+		u := &unstructured.Unstructured{}
+		u.SetName("build-task")
+		u.SetNamespace("kabanero")
+		u.SetOwnerReferences([]metav1.OwnerReference{owner})
+		return deleteAssetTestClient{objs: map[client.ObjectKey]*unstructured.Unstructured{key: u}}
+	}
+
+	// Delete
+	c := newClient()
+	err := DeleteAsset(c, asset, owner, kabanerov1alpha2.AssetDeletionPolicyDelete, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.objs[key]; ok {
+		t.Fatal("The asset should have been deleted")
+	}
+
+	// Orphan
+	c = newClient()
+	err = DeleteAsset(c, asset, owner, kabanerov1alpha2.AssetDeletionPolicyOrphan, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, ok := c.objs[key]
+	if !ok {
+		t.Fatal("The orphaned asset should not have been deleted")
+	}
+	if len(u.GetOwnerReferences()) != 0 || len(u.GetLabels()) != 0 {
+		t.Fatalf("The orphaned asset should have no owners or labels: %v", u)
+	}
+
+	// Retain
+	c = newClient()
+	err = DeleteAsset(c, asset, owner, kabanerov1alpha2.AssetDeletionPolicyRetain, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, ok = c.objs[key]
+	if !ok {
+		t.Fatal("The retained asset should not have been deleted")
+	}
+	if len(u.GetOwnerReferences()) != 0 {
+		t.Fatalf("The retained asset should have no owners: %v", u)
+	}
+	if u.GetLabels()[AssetStateLabel] != AssetStateLabelInactive {
+		t.Fatalf("The retained asset should be labelled as inactive: %v", u)
+	}
+	if u.GetAnnotations()[AssetDeactivatedByAnnotation] != "Stack/java-microprofile" {
+		t.Fatalf("The retained asset should record who deactivated it: %v", u)
+	}
+
+	// Reactivating the asset clears the inactive state.
+	if !clearRetainedAssetState(u) {
+		t.Fatal("The retained asset state should have been cleared")
+	}
+	if _, ok := u.GetLabels()[AssetStateLabel]; ok {
+		t.Fatalf("The inactive label should have been removed: %v", u)
+	}
+}
+
+// Test that the transition time only changes with the status, and applies are recorded.
+func TestSetAssetStatus(t *testing.T) {
+	asset := kabanerov1alpha2.RepositoryAssetStatus{Name: "build-task"}
+	setAssetStatus(&asset, AssetStatusUnknown, "Asset has not been applied yet.", "")
+	if asset.LastTransitionTime == nil {
+		t.Fatal("Expected the transition time to be set")
+	}
+
+	earlier := metav1.NewTime(asset.LastTransitionTime.Add(-time.Hour))
+	asset.LastTransitionTime = &earlier
+	setAssetStatus(&asset, AssetStatusUnknown, "Unable to check asset", kabanerov1alpha2.StackReasonAssetCheckFailed)
+	if !asset.LastTransitionTime.Equal(&earlier) {
+		t.Errorf("Expected the transition time to be kept, but found %v", asset.LastTransitionTime)
+	}
+
+	setAssetStatus(&asset, AssetStatusActive, "", "")
+	recordAssetApply(&asset, "1234")
+	if asset.LastTransitionTime.Equal(&earlier) || asset.LastApplied == nil || asset.ArchiveDigest != "1234" || len(asset.Reason) != 0 {
+		t.Errorf("Expected the transition and the apply to be recorded: %+v", asset)
+	}
+}
+
+// Test that the Tekton groups are always allowed, and other kinds only when listed.
+func TestIsAssetGroupKindAllowed(t *testing.T) {
+	allowedKinds := []kabanerov1alpha2.AssetGroupKind{{Kind: "ConfigMap"}, {Group: "appsody.dev"}}
+
+	tests := []struct {
+		group   string
+		kind    string
+		allowed bool
+	}{
+		{"tekton.dev", "Task", true},
+		{"triggers.tekton.dev", "EventListener", true},
+		{"", "ConfigMap", true},
+		{"", "Secret", false},
+		{"appsody.dev", "AppsodyApplication", true},
+		{"rbac.authorization.k8s.io", "ClusterRoleBinding", false},
+	}
+
+	for _, test := range tests {
+		if allowed := kabanerov1alpha2.IsAssetGroupKindAllowed(test.group, test.kind, allowedKinds); allowed != test.allowed {
+			t.Errorf("Expected kind %v in group %v to be allowed: %v, but was %v", test.kind, test.group, test.allowed, allowed)
+		}
+	}
+
+	if kabanerov1alpha2.IsAssetGroupKindAllowed("", "ConfigMap", nil) {
+		t.Error("Expected ConfigMaps to be rejected by default")
+	}
+}
+
+func TestIsAssetGroupKindManageable(t *testing.T) {
+	tests := []struct {
+		allowed    kabanerov1alpha2.AssetGroupKind
+		manageable bool
+	}{
+		{kabanerov1alpha2.AssetGroupKind{Kind: "ServiceAccount"}, true},
+		{kabanerov1alpha2.AssetGroupKind{Kind: "Namespace"}, false},
+		{kabanerov1alpha2.AssetGroupKind{Group: "apps", Kind: "Deployment"}, true},
+		{kabanerov1alpha2.AssetGroupKind{Group: "apps"}, false},
+		{kabanerov1alpha2.AssetGroupKind{Group: "appsody.dev"}, true},
+		{kabanerov1alpha2.AssetGroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}, false},
+	}
+
+	for _, test := range tests {
+		if manageable := kabanerov1alpha2.IsAssetGroupKindManageable(test.allowed); manageable != test.manageable {
+			t.Errorf("Expected %v to be manageable: %v, but was %v", test.allowed, test.manageable, manageable)
+		}
+	}
+}
+
+// Unit test client that stores namespaces by name.
+type namespaceTestClient struct {
+	client.Client
+	namespaces map[string]*corev1.Namespace
+}
+
+func (c namespaceTestClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	stored, ok := c.namespaces[key.Name]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	stored.DeepCopyInto(obj.(*corev1.Namespace))
+	return nil
+}
+
+func (c namespaceTestClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	ns := obj.(*corev1.Namespace)
+	c.namespaces[ns.Name] = ns.DeepCopy()
+	return nil
+}
+
+// Test that a missing asset namespace is created with the instance labels, and an
+// existing one is left alone.
+func TestEnsureAssetNamespace(t *testing.T) {
+	logger := logf.Log.WithName("pipelines_test")
+	labels := map[string]string{AssetNamespaceInstanceLabel: "kabanero", AssetNamespaceInstanceNamespaceLabel: "kabanero"}
+
+	existing := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tekton-pipelines"}}
+	c := namespaceTestClient{namespaces: map[string]*corev1.Namespace{"tekton-pipelines": existing}}
+
+	if err := ensureAssetNamespace(c, "tekton-pipelines", labels, logger); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.namespaces["tekton-pipelines"].Labels) != 0 {
+		t.Fatalf("The existing namespace should not have been changed: %v", c.namespaces["tekton-pipelines"])
+	}
+
+	if err := ensureAssetNamespace(c, "triggers", labels, logger); err != nil {
+		t.Fatal(err)
+	}
+	ns, ok := c.namespaces["triggers"]
+	if !ok {
+		t.Fatal("The missing namespace should have been created")
+	}
+	if ns.Labels[AssetNamespaceInstanceLabel] != "kabanero" || ns.Labels[AssetNamespaceInstanceNamespaceLabel] != "kabanero" {
+		t.Fatalf("The created namespace should identify the Kabanero instance: %v", ns.Labels)
+	}
+}
+
+// Test that the rendering context entries of one version replace those of the others.
+func TestSetVersionRenderingContext(t *testing.T) {
+	versionContexts := map[string]map[string]interface{}{
+		"0.2.1": {"StackImage": "kabanero/java-microprofile:0.2.1"},
+		"0.2.2": {"StackImage": "kabanero/java-microprofile:0.2.2"},
+	}
+	renderingContext := map[string]interface{}{"StackId": "java-microprofile"}
+
+	setVersionRenderingContext(renderingContext, versionContexts, "0.2.2")
+	if renderingContext["StackImage"] != "kabanero/java-microprofile:0.2.2" || renderingContext["StackId"] != "java-microprofile" {
+		t.Fatalf("Unexpected rendering context for version 0.2.2: %v", renderingContext)
+	}
+
+	setVersionRenderingContext(renderingContext, versionContexts, "0.3.0")
+	if _, found := renderingContext["StackImage"]; found {
+		t.Fatalf("Expected no stack image for a version without one, but found: %v", renderingContext)
+	}
+}
@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// The key under which the correlation ID of a reconcile is added to the log messages.
+const ReconcileIDKey = "ReconcileID"
+
+// Returns a random ID that identifies one reconcile in the log messages.
+func NewReconcileID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
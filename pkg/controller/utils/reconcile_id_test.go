@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"testing"
+)
+
+// Test that each reconcile gets a different ID.
+func TestNewReconcileID(t *testing.T) {
+	first := NewReconcileID()
+	second := NewReconcileID()
+	if len(first) != 16 || first == second {
+		t.Fatalf("Expected two different 16 character IDs, but found %v and %v", first, second)
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The start time of each reconcile in progress, keyed by a sequence number.  Used by the
+// liveness check to detect a reconcile worker that stopped making progress.
+var reconcilesInFlight = make(map[int64]time.Time)
+
+// The sequence number of the next reconcile.
+var nextReconcile int64
+
+// Mutex for the reconciles in progress.
+var reconcileLock sync.Mutex
+
+// Marks the start of a reconcile.  The returned function marks its completion, and must
+// be called when the reconcile returns.
+func BeginReconcile() func() {
+	reconcileLock.Lock()
+	defer reconcileLock.Unlock()
+	id := nextReconcile
+	nextReconcile++
+	reconcilesInFlight[id] = time.Now()
+
+	return func() {
+		reconcileLock.Lock()
+		defer reconcileLock.Unlock()
+		delete(reconcilesInFlight, id)
+	}
+}
+
+// Returns an error if a reconcile started more than the input timeout before the input
+// time, and has not completed.  An idle operator is always making progress.
+func CheckReconcileProgress(timeout time.Duration, now time.Time) error {
+	reconcileLock.Lock()
+	defer reconcileLock.Unlock()
+	for _, started := range reconcilesInFlight {
+		if now.Sub(started) > timeout {
+			return fmt.Errorf("A reconcile started at %v has not completed after %v", started.Format(time.RFC3339), timeout)
+		}
+	}
+	return nil
+}
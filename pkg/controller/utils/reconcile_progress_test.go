@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// Test that a reconcile that runs past the timeout is reported, and that a completed
+// reconcile is not.
+func TestCheckReconcileProgress(t *testing.T) {
+	if err := CheckReconcileProgress(time.Minute, time.Now()); err != nil {
+		t.Fatalf("An idle operator should be making progress: %v", err)
+	}
+
+	endReconcile := BeginReconcile()
+	if err := CheckReconcileProgress(time.Minute, time.Now()); err != nil {
+		t.Fatalf("A new reconcile should be making progress: %v", err)
+	}
+
+	if err := CheckReconcileProgress(time.Minute, time.Now().Add(2*time.Minute)); err == nil {
+		t.Fatal("A reconcile running past the timeout should have been reported")
+	}
+
+	endReconcile()
+	if err := CheckReconcileProgress(time.Minute, time.Now().Add(2*time.Minute)); err != nil {
+		t.Fatalf("A completed reconcile should not have been reported: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The rendering context values that are set by the operator.  User-provided values
+// with these names are ignored.
+var reservedRenderingKeys = map[string]bool{
+	"CollectionId":     true,
+	"Digest":           true,
+	"StackId":          true,
+	"StackImage":       true,
+	"TriggerNamespace": true,
+	"Workspaces":       true,
+}
+
+// Reads the rendering context values from the entries of the input ConfigMap.  An empty
+// name results in no values.
+func GetRenderingValues(c Getter, namespace string, configMapName string) (map[string]string, error) {
+	if len(configMapName) == 0 {
+		return nil, nil
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := c.Get(context.Background(), client.ObjectKey{Name: configMapName, Namespace: namespace}, cm)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the rendering context ConfigMap %v. Namespace: %v. Error: %v", configMapName, namespace, err)
+	}
+
+	return cm.Data, nil
+}
+
+// Returns the input rendering values, with the overrides applied on top.
+func MergeRenderingValues(values map[string]string, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return values
+	}
+
+	merged := make(map[string]string)
+	for key, value := range values {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Adds the user-provided values to the rendering context.  Values set by the operator
+// are never replaced.
+func addRenderingValues(renderingContext map[string]interface{}, values map[string]string, logger logr.Logger) {
+	for key, value := range values {
+		if _, found := renderingContext[key]; found || reservedRenderingKeys[key] {
+			logger.Info(fmt.Sprintf("Ignoring rendering context value %v, which is set by the operator", key))
+			continue
+		}
+		renderingContext[key] = value
+	}
+}
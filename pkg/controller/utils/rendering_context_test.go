@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Test that the stack's values override the Kabanero instance's, and that values set
+// by the operator are never replaced.
+func TestRenderingValues(t *testing.T) {
+	logger := logf.Log.WithName("rendering_context_test")
+
+	kabaneroValues := map[string]string{"registry": "image-registry.openshift-image-registry.svc:5000", "storageClass": "standard"}
+	stackValues := map[string]string{"storageClass": "fast", "Digest": "12345678"}
+
+	values := MergeRenderingValues(kabaneroValues, stackValues)
+	if values["storageClass"] != "fast" || values["registry"] != kabaneroValues["registry"] {
+		t.Fatalf("Unexpected merged values: %v", values)
+	}
+	if kabaneroValues["storageClass"] != "standard" {
+		t.Fatalf("The Kabanero values should not have been changed: %v", kabaneroValues)
+	}
+
+	renderingContext := map[string]interface{}{"StackId": "java-microprofile"}
+	addRenderingValues(renderingContext, map[string]string{"StackId": "other", "storageClass": "fast", "Digest": "12345678"}, logger)
+
+	if renderingContext["StackId"] != "java-microprofile" {
+		t.Fatalf("The stack id should not have been replaced: %v", renderingContext)
+	}
+	if _, found := renderingContext["Digest"]; found {
+		t.Fatalf("The digest should not have been set: %v", renderingContext)
+	}
+	if renderingContext["storageClass"] != "fast" {
+		t.Fatalf("Expected the storage class to be set: %v", renderingContext)
+	}
+}
@@ -13,7 +13,7 @@ import (
 type filter func(secretList *corev1.SecretList, filterStrings ...string) (*corev1.Secret, error)
 
 // Retrieves Secret Objects matching the input annotation key in the specified namespace.
-func GetMatchingSecret(c client.Client, namespace string, f filter, filterStrings ...string) (*corev1.Secret, error) {
+func GetMatchingSecret(c client.Reader, namespace string, f filter, filterStrings ...string) (*corev1.Secret, error) {
 	secretList := &corev1.SecretList{}
 	err := c.List(context.Background(), secretList, client.InNamespace(namespace))
 	if err != nil {
@@ -29,7 +29,7 @@ func GetMatchingSecret(c client.Client, namespace string, f filter, filterString
 }
 
 // Retrieves an unstructured secret object based on the provided inputs.
-func GetUnstructuredSecret(c client.Client, secretName string, namespace string) (*unstructured.Unstructured, error) {
+func GetUnstructuredSecret(c client.Reader, secretName string, namespace string) (*unstructured.Unstructured, error) {
 	uSecret := &unstructured.Unstructured{}
 	uSecret.SetGroupVersionKind(schema.GroupVersionKind{
 		Kind:    "Secret",
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/cache"
+	"golang.org/x/crypto/openpgp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Verifies the input pipeline archive against its detached signature.  The signature is
+// downloaded like the archive, and the public key is read from the secret the signature
+// refers to, in the input namespace.
+func verifyArchiveSignature(ctx context.Context, c client.Reader, namespace string, archive []byte, sig *kabanerov1alpha2.PipelineSignatureSpec, proxy *cache.ArtifactProxy, reqLogger logr.Logger) error {
+	gitRelease := gitReleaseSpecToGitReleaseInfo(sig.GitRelease)
+	skipCertVerification := sig.Https.SkipCertVerification
+	if gitRelease.IsUsable() {
+		skipCertVerification = sig.GitRelease.SkipCertVerification
+	}
+	signature, err := DownloadToByte(ctx, c, namespace, sig.Https.Url, gitRelease, skipCertVerification, proxy, reqLogger)
+	if err != nil {
+		return fmt.Errorf("Unable to download the archive signature: %v", err)
+	}
+
+	publicKey, err := getSignatureKey(c, namespace, sig.KeySecretRef)
+	if err != nil {
+		return err
+	}
+
+	switch sig.Format {
+	case kabanerov1alpha2.PipelineSignatureFormatCosign:
+		return verifyCosignSignature(archive, signature, publicKey)
+	default:
+		return verifyGpgSignature(archive, signature, publicKey)
+	}
+}
+
+// Returns the public key held by the input secret.
+func getSignatureKey(c Getter, namespace string, ref kabanerov1alpha2.SignatureKeySecretRef) ([]byte, error) {
+	key := ref.Key
+	if len(key) == 0 {
+		key = kabanerov1alpha2.DefaultSignatureKeySecretKey
+	}
+
+	secret := &corev1.Secret{}
+	err := c.Get(context.Background(), types.NamespacedName{Name: ref.Name, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the signature key secret %v in namespace %v: %v", ref.Name, namespace, err)
+	}
+
+	publicKey, ok := secret.Data[key]
+	if !ok || len(publicKey) == 0 {
+		return nil, fmt.Errorf("The signature key secret %v in namespace %v does not contain key %v", ref.Name, namespace, key)
+	}
+	return publicKey, nil
+}
+
+// Verifies an armored or binary OpenPGP detached signature, using an armored or binary
+// public key ring.
+func verifyGpgSignature(archive []byte, signature []byte, publicKey []byte) error {
+	var keyring openpgp.EntityList
+	var err error
+	if isArmored(publicKey) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(publicKey))
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to read the OpenPGP public key: %v", err)
+	}
+
+	if isArmored(signature) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(archive), bytes.NewReader(signature))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(archive), bytes.NewReader(signature))
+	}
+	if err != nil {
+		return fmt.Errorf("The OpenPGP signature does not verify the archive: %v", err)
+	}
+	return nil
+}
+
+// Verifies a base64 encoded ECDSA signature over the sha256 of the archive, as written by
+// cosign sign-blob, using a PEM encoded public key.
+func verifyCosignSignature(archive []byte, signature []byte, publicKey []byte) error {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return fmt.Errorf("Unable to read the cosign public key: no PEM data was found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("Unable to read the cosign public key: %v", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("The cosign public key is not an ECDSA key")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("Unable to decode the cosign signature: %v", err)
+	}
+	var esig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(decoded, &esig); err != nil {
+		return fmt.Errorf("Unable to decode the cosign signature: %v", err)
+	}
+
+	digest := sha256.Sum256(archive)
+	if !ecdsa.Verify(ecdsaKey, digest[:], esig.R, esig.S) {
+		return fmt.Errorf("The cosign signature does not verify the archive")
+	}
+	return nil
+}
+
+// Returns true if the input is ASCII armored.
+func isArmored(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN"))
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Unit test client that returns the signature key secret.
+type signatureTestClient struct {
+	archiveTestClient
+	publicKey []byte
+}
+
+func (c signatureTestClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	secret := obj.(*corev1.Secret)
+	secret.Name = key.Name
+	secret.Namespace = key.Namespace
+	secret.Data = map[string][]byte{kabanerov1alpha2.DefaultSignatureKeySecretKey: c.publicKey}
+	return nil
+}
+
+// HTTP handler that serves the pipeline archive, and its signature.
+type signatureHandler struct {
+	archive   []byte
+	signature []byte
+}
+
+func (h signatureHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case basicPipeline.name:
+		rw.Write(h.archive)
+	case basicPipeline.name + ".sig":
+		rw.Write(h.signature)
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Returns a PEM encoded ECDSA public key, and a cosign style signature of the input data.
+func cosignSign(t *testing.T, data []byte) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), []byte(base64.StdEncoding.EncodeToString(sig))
+}
+
+// Test that a cosign signature verifies the archive it was made for, and only that archive.
+func TestVerifyCosignSignature(t *testing.T) {
+	archive := []byte("pipeline archive")
+	publicKey, signature := cosignSign(t, archive)
+
+	if err := verifyCosignSignature(archive, signature, publicKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyCosignSignature([]byte("tampered archive"), signature, publicKey); err == nil {
+		t.Fatal("Expected the signature of a tampered archive to be rejected")
+	}
+
+	otherKey, _ := cosignSign(t, archive)
+	if err := verifyCosignSignature(archive, signature, otherKey); err == nil {
+		t.Fatal("Expected a signature made with another key to be rejected")
+	}
+}
+
+// Test that an armored OpenPGP signature verifies the archive it was made for, and only that archive.
+func TestVerifyGpgSignature(t *testing.T) {
+	archive := []byte("pipeline archive")
+	entity, err := openpgp.NewEntity("pipelines", "", "pipelines@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, entity, bytes.NewReader(archive), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var publicKey bytes.Buffer
+	w, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if err := verifyGpgSignature(archive, signature.Bytes(), publicKey.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyGpgSignature([]byte("tampered archive"), signature.Bytes(), publicKey.Bytes()); err == nil {
+		t.Fatal("Expected the signature of a tampered archive to be rejected")
+	}
+}
+
+// Test that GetManifests verifies the archive signature before decoding the archive.
+func TestGetManifestsSignature(t *testing.T) {
+	archive, err := ioutil.ReadFile("testdata" + basicPipeline.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, signature := cosignSign(t, archive)
+
+	server := httptest.NewServer(signatureHandler{archive: archive, signature: signature})
+	defer server.Close()
+
+	pipelineStatus := kabanerov1alpha2.PipelineStatus{
+		Url:    server.URL + basicPipeline.name,
+		Digest: basicPipeline.sha256,
+		Signature: &kabanerov1alpha2.PipelineSignatureSpec{
+			Format:       kabanerov1alpha2.PipelineSignatureFormatCosign,
+			Https:        kabanerov1alpha2.HttpsProtocolFile{Url: server.URL + basicPipeline.name + ".sig"},
+			KeySecretRef: kabanerov1alpha2.SignatureKeySecretRef{Name: "pipeline-signing-key"},
+		},
+	}
+
+	manifests, err := GetManifests(context.Background(), signatureTestClient{publicKey: publicKey}, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, true, nil, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) == 0 {
+		t.Fatal("Expected the manifests of the signed archive")
+	}
+
+	// A key that did not sign the archive
+	otherKey, _ := cosignSign(t, archive)
+	_, err = GetManifests(context.Background(), signatureTestClient{publicKey: otherKey}, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, true, nil, logf.NullLogger{})
+	if reason := ManifestErrorReason(err); reason != kabanerov1alpha2.StackReasonArchiveSignatureInvalid {
+		t.Errorf("Expected reason %v, but found %v (%v)", kabanerov1alpha2.StackReasonArchiveSignatureInvalid, reason, err)
+	}
+}
@@ -0,0 +1,511 @@
+// Package testing provides fakes that are shared by the unit tests of the controllers.
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kabanero-io/kabanero-operator/pkg/apis"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// The verbs of the actions that the client performs.
+const (
+	VerbGet          = "get"
+	VerbList         = "list"
+	VerbCreate       = "create"
+	VerbUpdate       = "update"
+	VerbPatch        = "patch"
+	VerbDelete       = "delete"
+	VerbDeleteAllOf  = "deleteallof"
+	VerbUpdateStatus = "updatestatus"
+	VerbPatchStatus  = "patchstatus"
+)
+
+// Action is an operation that the client was asked to perform.  The key of a list, or of
+// a deletion of several objects, only has a namespace.
+type Action struct {
+	Verb string
+	Kind schema.GroupVersionKind
+	Key  client.ObjectKey
+}
+
+// Reactor is called before the client performs an action.  When it returns an error, the
+// action fails with that error, and the objects are not changed.
+type Reactor func(action Action, obj runtime.Object) error
+
+// Client is an in-memory client.Client for unit tests.  Objects are stored as unstructured
+// objects, keyed by their kind, namespace and name.  Typed objects are converted with the
+// scheme of the client, so their kinds must be registered in it.  Field selectors are
+// matched against the fields of the stored objects.
+type Client struct {
+	scheme *runtime.Scheme
+
+	mutex           sync.Mutex
+	objects         map[objectKey]*unstructured.Unstructured
+	reactors        []Reactor
+	actions         []Action
+	resourceVersion int
+}
+
+type objectKey struct {
+	kind      schema.GroupVersionKind
+	namespace string
+	name      string
+}
+
+var _ client.Client = &Client{}
+
+// NewScheme returns a scheme with the Kubernetes and Kabanero types.
+func NewScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		panic(err)
+	}
+	if err := apis.AddToScheme(s); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewClient returns a client that holds the input objects, and knows about the Kubernetes
+// and Kabanero types.
+func NewClient(objs ...runtime.Object) *Client {
+	return NewClientWithScheme(NewScheme(), objs...)
+}
+
+// NewClientWithScheme returns a client that holds the input objects, and converts typed
+// objects with the input scheme.
+func NewClientWithScheme(s *runtime.Scheme, objs ...runtime.Object) *Client {
+	c := &Client{scheme: s, objects: make(map[objectKey]*unstructured.Unstructured)}
+	for _, obj := range objs {
+		u, err := c.toUnstructured(obj)
+		if err != nil {
+			panic(err)
+		}
+		if err := c.store(keyOf(u), u, u); err != nil {
+			panic(err)
+		}
+	}
+	return c
+}
+
+// AddReactor adds a reactor that is called before each action.
+func (c *Client) AddReactor(reactor Reactor) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reactors = append(c.reactors, reactor)
+}
+
+// FailOn makes the actions with the input verb on objects of the input kind fail with the
+// input error.  An empty verb or kind matches any.
+func (c *Client) FailOn(verb string, kind string, err error) {
+	c.AddReactor(func(action Action, obj runtime.Object) error {
+		if (len(verb) == 0 || verb == action.Verb) && (len(kind) == 0 || kind == action.Kind.Kind) {
+			return err
+		}
+		return nil
+	})
+}
+
+// Actions returns the actions that the client was asked to perform, in order.
+func (c *Client) Actions() []Action {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]Action{}, c.actions...)
+}
+
+// Objects returns copies of the objects that the client holds, sorted by kind, namespace
+// and name.
+func (c *Client) Objects() []*unstructured.Unstructured {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	objs := []*unstructured.Unstructured{}
+	for _, u := range c.objects {
+		objs = append(objs, u.DeepCopy())
+	}
+	sortObjects(objs)
+	return objs
+}
+
+// Get retrieves an object.
+func (c *Client) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	gvk, err := c.kindOf(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.react(Action{Verb: VerbGet, Kind: gvk, Key: key}, obj); err != nil {
+		return err
+	}
+
+	u := c.objects[objectKey{gvk, key.Namespace, key.Name}]
+	if u == nil {
+		return notFound(gvk, key.Name)
+	}
+	return c.fromUnstructured(u, obj)
+}
+
+// List retrieves the objects of the kind of the input list that match the options.
+func (c *Client) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	gvk, err := c.kindOf(list)
+	if err != nil {
+		return err
+	}
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	options := (&client.ListOptions{}).ApplyOptions(opts)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.react(Action{Verb: VerbList, Kind: gvk, Key: client.ObjectKey{Namespace: options.Namespace}}, list); err != nil {
+		return err
+	}
+
+	objs := c.matching(gvk, options)
+	if ul, ok := list.(*unstructured.UnstructuredList); ok {
+		ul.Items = nil
+		for _, u := range objs {
+			ul.Items = append(ul.Items, *u.DeepCopy())
+		}
+		return nil
+	}
+
+	items := []interface{}{}
+	for _, u := range objs {
+		items = append(items, u.DeepCopy().Object)
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(map[string]interface{}{"items": items}, list)
+}
+
+// Create creates an object.  A name is generated if the object only has a generate name.
+func (c *Client) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	u, err := c.toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(u.GetName()) == 0 && len(u.GetGenerateName()) != 0 {
+		u.SetName(fmt.Sprintf("%v%v", u.GetGenerateName(), len(c.objects)))
+	}
+	key := keyOf(u)
+	if err := c.react(Action{Verb: VerbCreate, Kind: key.kind, Key: client.ObjectKey{Namespace: key.namespace, Name: key.name}}, obj); err != nil {
+		return err
+	}
+
+	if c.objects[key] != nil {
+		return apierrors.NewAlreadyExists(groupResource(key.kind), key.name)
+	}
+	return c.store(key, u, obj)
+}
+
+// Update replaces an object.  The update fails with a conflict if the object has a
+// resource version that is not the current one.
+func (c *Client) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return c.update(VerbUpdate, obj, func(current *unstructured.Unstructured, u *unstructured.Unstructured) *unstructured.Unstructured {
+		return u
+	})
+}
+
+// Patch applies a merge patch to an object.
+func (c *Client) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.patch(VerbPatch, obj, patch, func(current *unstructured.Unstructured, patched *unstructured.Unstructured) *unstructured.Unstructured {
+		return patched
+	})
+}
+
+// Delete deletes an object.
+func (c *Client) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	u, err := c.toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := keyOf(u)
+	if err := c.react(Action{Verb: VerbDelete, Kind: key.kind, Key: client.ObjectKey{Namespace: key.namespace, Name: key.name}}, obj); err != nil {
+		return err
+	}
+
+	if c.objects[key] == nil {
+		return notFound(key.kind, key.name)
+	}
+	delete(c.objects, key)
+	return nil
+}
+
+// DeleteAllOf deletes the objects of the kind of the input object that match the options.
+func (c *Client) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	gvk, err := c.kindOf(obj)
+	if err != nil {
+		return err
+	}
+	options := (&client.DeleteAllOfOptions{}).ApplyOptions(opts)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.react(Action{Verb: VerbDeleteAllOf, Kind: gvk, Key: client.ObjectKey{Namespace: options.Namespace}}, obj); err != nil {
+		return err
+	}
+
+	for _, u := range c.matching(gvk, &options.ListOptions) {
+		delete(c.objects, keyOf(u))
+	}
+	return nil
+}
+
+// Status returns a writer of the status of objects.  Only the status of the stored object
+// is changed.
+func (c *Client) Status() client.StatusWriter {
+	return statusWriter{c}
+}
+
+type statusWriter struct {
+	c *Client
+}
+
+func (w statusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return w.c.update(VerbUpdateStatus, obj, withStatusOf)
+}
+
+func (w statusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return w.c.patch(VerbPatchStatus, obj, patch, withStatusOf)
+}
+
+// Returns the current object, with the status of the updated object.
+func withStatusOf(current *unstructured.Unstructured, updated *unstructured.Unstructured) *unstructured.Unstructured {
+	result := current.DeepCopy()
+	if status, found := updated.Object["status"]; found {
+		result.Object["status"] = status
+	} else {
+		delete(result.Object, "status")
+	}
+	return result
+}
+
+// Replaces a stored object with the result of the input function, which is called with
+// the stored object and the input object.
+func (c *Client) update(verb string, obj runtime.Object, f func(*unstructured.Unstructured, *unstructured.Unstructured) *unstructured.Unstructured) error {
+	u, err := c.toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := keyOf(u)
+	if err := c.react(Action{Verb: verb, Kind: key.kind, Key: client.ObjectKey{Namespace: key.namespace, Name: key.name}}, obj); err != nil {
+		return err
+	}
+
+	current := c.objects[key]
+	if current == nil {
+		return notFound(key.kind, key.name)
+	}
+	if len(u.GetResourceVersion()) != 0 && u.GetResourceVersion() != current.GetResourceVersion() {
+		return apierrors.NewConflict(groupResource(key.kind), key.name, fmt.Errorf("the object has been modified"))
+	}
+	return c.store(key, f(current, u), obj)
+}
+
+// Applies a merge patch to a stored object, and replaces it with the result of the input
+// function, which is called with the stored object and the patched object.
+func (c *Client) patch(verb string, obj runtime.Object, patch client.Patch, f func(*unstructured.Unstructured, *unstructured.Unstructured) *unstructured.Unstructured) error {
+	if patch.Type() != types.MergePatchType {
+		return fmt.Errorf("Patch type %v is not supported", patch.Type())
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	u, err := c.toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := keyOf(u)
+	if err := c.react(Action{Verb: verb, Kind: key.kind, Key: client.ObjectKey{Namespace: key.namespace, Name: key.name}}, obj); err != nil {
+		return err
+	}
+
+	current := c.objects[key]
+	if current == nil {
+		return notFound(key.kind, key.name)
+	}
+	patchMap := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patchMap); err != nil {
+		return err
+	}
+	merged, err := json.Marshal(mergePatch(current.DeepCopy().Object, patchMap))
+	if err != nil {
+		return err
+	}
+	patched := &unstructured.Unstructured{}
+	if err := patched.UnmarshalJSON(merged); err != nil {
+		return err
+	}
+	return c.store(key, f(current, patched), obj)
+}
+
+// Stores an object with a new resource version, and copies the stored object back to the
+// input object.
+func (c *Client) store(key objectKey, u *unstructured.Unstructured, obj runtime.Object) error {
+	c.resourceVersion++
+	u = u.DeepCopy()
+	u.SetResourceVersion(fmt.Sprintf("%v", c.resourceVersion))
+	c.objects[key] = u
+	return c.fromUnstructured(u, obj)
+}
+
+// Calls the reactors, and records the action.
+func (c *Client) react(action Action, obj runtime.Object) error {
+	c.actions = append(c.actions, action)
+	for _, reactor := range c.reactors {
+		if err := reactor(action, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the stored objects of the input kind that match the list options, sorted by
+// namespace and name.
+func (c *Client) matching(gvk schema.GroupVersionKind, options *client.ListOptions) []*unstructured.Unstructured {
+	objs := []*unstructured.Unstructured{}
+	for key, u := range c.objects {
+		if key.kind != gvk || (len(options.Namespace) != 0 && key.namespace != options.Namespace) {
+			continue
+		}
+		if options.LabelSelector != nil && !options.LabelSelector.Matches(labels.Set(u.GetLabels())) {
+			continue
+		}
+		if options.FieldSelector != nil && !options.FieldSelector.Matches(fieldSet(u, options.FieldSelector)) {
+			continue
+		}
+		objs = append(objs, u)
+	}
+	sortObjects(objs)
+	return objs
+}
+
+// Returns the kind of a typed or unstructured object.
+func (c *Client) kindOf(obj runtime.Object) (schema.GroupVersionKind, error) {
+	switch obj.(type) {
+	case *unstructured.Unstructured, *unstructured.UnstructuredList:
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if len(gvk.Kind) == 0 {
+			return gvk, fmt.Errorf("The unstructured object has no kind: %v", obj)
+		}
+		return gvk, nil
+	}
+	return apiutil.GVKForObject(obj, c.scheme)
+}
+
+// Returns a copy of a typed or unstructured object as an unstructured object.
+func (c *Client) toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		if len(u.GetKind()) == 0 {
+			return nil, fmt.Errorf("The unstructured object has no kind: %v", obj)
+		}
+		return u.DeepCopy(), nil
+	}
+
+	gvk, err := c.kindOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	return u, nil
+}
+
+// Copies a stored object to a typed or unstructured object.
+func (c *Client) fromUnstructured(u *unstructured.Unstructured, obj runtime.Object) error {
+	if target, ok := obj.(*unstructured.Unstructured); ok {
+		target.Object = u.DeepCopy().Object
+		return nil
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.DeepCopy().Object, obj)
+}
+
+func keyOf(u *unstructured.Unstructured) objectKey {
+	return objectKey{u.GroupVersionKind(), u.GetNamespace(), u.GetName()}
+}
+
+// The resource of a kind is approximated by its lower case name.
+func groupResource(gvk schema.GroupVersionKind) schema.GroupResource {
+	return schema.GroupResource{Group: gvk.Group, Resource: strings.ToLower(gvk.Kind)}
+}
+
+func notFound(gvk schema.GroupVersionKind, name string) error {
+	return apierrors.NewNotFound(groupResource(gvk), name)
+}
+
+// Returns the values of the fields of an object that the selector refers to.
+func fieldSet(u *unstructured.Unstructured, selector fields.Selector) fields.Set {
+	set := fields.Set{}
+	for _, requirement := range selector.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(u.Object, strings.Split(requirement.Field, ".")...)
+		if found && err == nil {
+			set[requirement.Field] = fmt.Sprintf("%v", value)
+		}
+	}
+	return set
+}
+
+// Applies a JSON merge patch (RFC 7386) to the input object.
+func mergePatch(original map[string]interface{}, patch map[string]interface{}) map[string]interface{} {
+	for key, value := range patch {
+		if value == nil {
+			delete(original, key)
+			continue
+		}
+		patchMap, isMap := value.(map[string]interface{})
+		if !isMap {
+			original[key] = value
+			continue
+		}
+		originalMap, wasMap := original[key].(map[string]interface{})
+		if !wasMap {
+			originalMap = map[string]interface{}{}
+		}
+		original[key] = mergePatch(originalMap, patchMap)
+	}
+	return original
+}
+
+func sortObjects(objs []*unstructured.Unstructured) {
+	sort.Slice(objs, func(i, j int) bool {
+		a, b := objs[i], objs[j]
+		if a.GroupVersionKind().String() != b.GroupVersionKind().String() {
+			return a.GroupVersionKind().String() < b.GroupVersionKind().String()
+		}
+		if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+}
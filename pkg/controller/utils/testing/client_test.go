@@ -0,0 +1,140 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func testStack(name string, namespace string) *kabanerov1alpha2.Stack {
+	return &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": name}},
+		Spec:       kabanerov1alpha2.StackSpec{Name: name},
+	}
+}
+
+// Test that typed objects are stored, retrieved and listed by namespace.
+func TestClientTypedObjects(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient(testStack("nodejs", "kabanero"), testStack("nodejs", "other"))
+
+	err := c.Create(ctx, testStack("java-microprofile", "kabanero"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Create(ctx, testStack("nodejs", "kabanero"))
+	if !apierrors.IsAlreadyExists(err) {
+		t.Fatalf("Expected an already exists error, but found %v", err)
+	}
+
+	stack := &kabanerov1alpha2.Stack{}
+	err = c.Get(ctx, client.ObjectKey{Name: "java-microprofile", Namespace: "kabanero"}, stack)
+	if err != nil || stack.Spec.Name != "java-microprofile" || len(stack.ResourceVersion) == 0 {
+		t.Fatalf("Expected the stack to be found, but found %v: %v", err, stack)
+	}
+	err = c.Get(ctx, client.ObjectKey{Name: "java-microprofile", Namespace: "other"}, stack)
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("Expected a not found error, but found %v", err)
+	}
+
+	stacks := &kabanerov1alpha2.StackList{}
+	err = c.List(ctx, stacks, client.InNamespace("kabanero"))
+	if err != nil || len(stacks.Items) != 2 || stacks.Items[0].Name != "java-microprofile" || stacks.Items[1].Name != "nodejs" {
+		t.Fatalf("Expected the two stacks in namespace kabanero, but found %v: %v", err, stacks.Items)
+	}
+}
+
+// Test that an update of a stale object fails, and that only the status is written
+// through the status writer.
+func TestClientUpdates(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient(testStack("nodejs", "kabanero"))
+	key := client.ObjectKey{Name: "nodejs", Namespace: "kabanero"}
+
+	stale := &kabanerov1alpha2.Stack{}
+	if err := c.Get(ctx, key, stale); err != nil {
+		t.Fatal(err)
+	}
+	current := stale.DeepCopy()
+	current.Spec.Name = "changed"
+	if err := c.Update(ctx, current); err != nil {
+		t.Fatal(err)
+	}
+	stale.Spec.Name = "stale"
+	if err := c.Update(ctx, stale); !apierrors.IsConflict(err) {
+		t.Fatalf("Expected a conflict, but found %v", err)
+	}
+
+	patched := current.DeepCopy()
+	patched.Spec.Name = "ignored"
+	patched.Status.Summary = "[ 0.2.5: active ]"
+	if err := c.Status().Patch(ctx, patched, client.MergeFrom(current)); err != nil {
+		t.Fatal(err)
+	}
+
+	stack := &kabanerov1alpha2.Stack{}
+	if err := c.Get(ctx, key, stack); err != nil {
+		t.Fatal(err)
+	}
+	if stack.Spec.Name != "changed" || stack.Status.Summary != "[ 0.2.5: active ]" {
+		t.Fatalf("Expected only the status to be patched, but found %v", stack)
+	}
+}
+
+// Test that unstructured objects are selected by label, and deleted.
+func TestClientUnstructuredObjects(t *testing.T) {
+	ctx := context.Background()
+	gvk := schema.GroupVersionKind{Group: "tekton.dev", Version: "v1alpha1", Kind: "Task"}
+	c := NewClient()
+	for _, name := range []string{"build-task", "deploy-task"} {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		u.SetName(name)
+		u.SetNamespace("kabanero")
+		u.SetLabels(map[string]string{"task": name})
+		if err := c.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind("TaskList"))
+	err := c.List(ctx, list, client.MatchingLabels{"task": "deploy-task"})
+	if err != nil || len(list.Items) != 1 || list.Items[0].GetName() != "deploy-task" {
+		t.Fatalf("Expected the deploy task, but found %v: %v", err, list.Items)
+	}
+
+	if err := c.Delete(ctx, &list.Items[0]); err != nil {
+		t.Fatal(err)
+	}
+	if objs := c.Objects(); len(objs) != 1 || objs[0].GetName() != "build-task" {
+		t.Fatalf("Expected only the build task to be left, but found %v", objs)
+	}
+}
+
+// Test that failures are injected, and that the actions are recorded.
+func TestClientFailOn(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient()
+	c.FailOn(VerbCreate, "Stack", errors.New("injected"))
+
+	err := c.Create(ctx, testStack("nodejs", "kabanero"))
+	if err == nil || err.Error() != "injected" {
+		t.Fatalf("Expected the injected error, but found %v", err)
+	}
+	if len(c.Objects()) != 0 {
+		t.Fatalf("Expected no objects, but found %v", c.Objects())
+	}
+
+	actions := c.Actions()
+	if len(actions) != 1 || actions[0].Verb != VerbCreate || actions[0].Kind.Kind != "Stack" || actions[0].Key.Name != "nodejs" {
+		t.Fatalf("Expected the create to be recorded, but found %v", actions)
+	}
+}
@@ -0,0 +1,190 @@
+package timer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression.  The expression has five fields: minute,
+// hour, day of month, month and day of week, for example "0 2 * * *" for 02:00 every
+// day.  Each field is *, a value, a range such as 1-5, or a comma separated list of
+// them, and a value or range may be followed by a step such as */15.  Months and days
+// of the week may also be named, such as jan or mon.  The expressions @yearly,
+// @monthly, @weekly, @daily and @hourly are also accepted.  Times are in UTC.
+type CronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// Whether the day of month or day of week field was *.  When neither was, a day
+	// matches if either field matches, as with cron.
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// The bounds and names of the values of a field.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute     = cronField{name: "minute", min: 0, max: 59}
+	cronHour       = cronField{name: "hour", min: 0, max: 23}
+	cronDayOfMonth = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth      = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDayOfWeek = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCronSchedule parses the input cron expression.
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, found := cronMacros[strings.ToLower(spec)]; found {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("The cron expression %q must have 5 fields: minute, hour, day of month, month and day of week.", expr)
+	}
+
+	s := &CronSchedule{}
+	var err error
+	if s.minute, _, err = cronMinute.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("The cron expression %q is not valid: %v", expr, err)
+	}
+	if s.hour, _, err = cronHour.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("The cron expression %q is not valid: %v", expr, err)
+	}
+	if s.dayOfMonth, s.anyDayOfMonth, err = cronDayOfMonth.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("The cron expression %q is not valid: %v", expr, err)
+	}
+	if s.month, _, err = cronMonth.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("The cron expression %q is not valid: %v", expr, err)
+	}
+	if s.dayOfWeek, s.anyDayOfWeek, err = cronDayOfWeek.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("The cron expression %q is not valid: %v", expr, err)
+	}
+
+	// Sunday is both 0 and 7.
+	if s.dayOfWeek&(1<<7) != 0 {
+		s.dayOfWeek |= 1
+	}
+	return s, nil
+}
+
+// Returns the set of values of the input field as a bit set, and whether the field was *.
+func (f cronField) parse(field string) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(strings.ToLower(field), ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("the %v step %q is not a positive number", f.name, part[i+1:])
+			}
+			part = part[:i]
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = f.value(bounds[0]); err != nil {
+				return 0, false, err
+			}
+			if end, err = f.value(bounds[1]); err != nil {
+				return 0, false, err
+			}
+			if start > end {
+				return 0, false, fmt.Errorf("the %v range %q is backwards", f.name, part)
+			}
+		default:
+			var err error
+			if start, err = f.value(part); err != nil {
+				return 0, false, err
+			}
+			// A single value with a step runs to the end of the range, as with cron.
+			if step == 1 {
+				end = start
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, field == "*", nil
+}
+
+// Returns the input value of a field, which may be a number or a name.
+func (f cronField) value(s string) (int, error) {
+	if v, found := f.names[s]; found {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("the %v %q is not a number", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("the %v %v is not between %v and %v", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time the schedule fires after the input time.  Returns the zero
+// time if it never fires, such as on February 30th.
+func (s *CronSchedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	// A schedule that fires at all fires within the next few years.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Returns true if the day of the input time matches the day of month and day of week fields.
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,55 @@
+package timer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2020, time.July, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"0 2 * * *", time.Date(2020, time.July, 16, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2020, time.July, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2020, time.July, 15, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, time.July, 15, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2020, time.July, 16, 10, 30, 0, 0, time.UTC)},
+		{"0 22 * * sat,sun", time.Date(2020, time.July, 18, 22, 0, 0, 0, time.UTC)},
+		{"0 3 * * 7", time.Date(2020, time.July, 19, 3, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 1-3 * * mon-fri", time.Date(2020, time.July, 16, 1, 0, 0, 0, time.UTC)},
+		{"0 0 1 * mon", time.Date(2020, time.July, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		s, err := ParseCronSchedule(test.expr)
+		if err != nil {
+			t.Errorf("Unable to parse %q: %v", test.expr, err)
+			continue
+		}
+		if next := s.Next(from); !next.Equal(test.next) {
+			t.Errorf("Expected %q to fire next at %v, but found %v", test.expr, test.next, next)
+		}
+	}
+}
+
+func TestCronScheduleNever(t *testing.T) {
+	s, err := ParseCronSchedule("0 0 30 feb *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Fatalf("Expected the schedule never to fire, but found %v", next)
+	}
+}
+
+func TestCronScheduleNotValid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@never"} {
+		if _, err := ParseCronSchedule(expr); err == nil {
+			t.Errorf("Expected %q to be rejected", expr)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package timer
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -28,31 +30,198 @@ func Retry(attempts int, waitTime time.Duration, gf GenRetryFunc) error {
 	return fmt.Errorf("Retriable function did not reach the expected outcome. Retry attempts: %v. Wait time: %v", attempts, waitTime)
 }
 
+// Backoff describes how RetryWithBackoff spaces out its attempts.
+type Backoff struct {
+	// The maximum number of attempts.
+	Attempts int
+
+	// The wait time after the first failed attempt.
+	Initial time.Duration
+
+	// The factor by which the wait time grows after each failed attempt.
+	Factor float64
+
+	// The maximum wait time between two attempts.  Zero means no maximum.
+	Max time.Duration
+
+	// The maximum total wait time.  No attempt is made once the budget would be exceeded.
+	// Zero means no budget.
+	Budget time.Duration
+
+	// The fraction, between 0 and 1, by which each wait time is randomly lengthened or
+	// shortened, so that callers that failed together do not all retry together.  Zero
+	// means no jitter.
+	Jitter float64
+
+	// The maximum time from the start of the first attempt, including the time the
+	// attempts take.  No attempt is started after it.  Zero means no maximum.
+	MaxElapsed time.Duration
+}
+
+// Returns true if no further attempt is made after waiting the input time.
+func (b Backoff) exhausted(attempt int, waited time.Duration, waitTime time.Duration, started time.Time) bool {
+	if attempt >= b.Attempts {
+		return true
+	}
+	if b.Budget > 0 && waited+waitTime > b.Budget {
+		return true
+	}
+	return b.MaxElapsed > 0 && now().Add(waitTime).Sub(started) > b.MaxElapsed
+}
+
+// Returns the wait time after the input one.
+func (b Backoff) next(waitTime time.Duration) time.Duration {
+	waitTime = time.Duration(float64(waitTime) * b.Factor)
+	if b.Max > 0 && waitTime > b.Max {
+		waitTime = b.Max
+	}
+	return waitTime
+}
+
+// Replaced by tests.
+var sleep = time.Sleep
+var random = rand.Float64
+var now = time.Now
+
+// Returns the input wait time, randomly lengthened or shortened by up to the input fraction.
+func applyJitter(waitTime time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return waitTime
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	return time.Duration(float64(waitTime) * (1 + jitter*(2*random()-1)))
+}
+
+// RetryWithBackoff executes the given function until it reaches the expected outcome, waiting
+// exponentially longer between attempts, until the attempts or the wait budget are exhausted.
+func RetryWithBackoff(backoff Backoff, gf GenRetryFunc) error {
+	started := now()
+	waitTime := backoff.Initial
+	waited := time.Duration(0)
+	for i := 1; i <= backoff.Attempts; i++ {
+		ok, err := gf()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		jitteredWaitTime := applyJitter(waitTime, backoff.Jitter)
+		if backoff.exhausted(i, waited, jitteredWaitTime, started) {
+			break
+		}
+
+		sleep(jitteredWaitTime)
+		waited += jitteredWaitTime
+		waitTime = backoff.next(waitTime)
+	}
+
+	return fmt.Errorf("Retriable function did not reach the expected outcome. Retry attempts: %v. Total wait time: %v", backoff.Attempts, waited)
+}
+
+// ErrorClassifier returns true if the input error may go away on its own, so the failed
+// attempt is worth retrying.
+type ErrorClassifier func(err error) bool
+
+// RetryCallback is called after a failed attempt, before waiting for the next one.  The
+// first attempt is number 1.
+type RetryCallback func(attempt int, err error, waitTime time.Duration)
+
+// RetryOnError executes the given function until it succeeds, waiting between attempts
+// as RetryWithBackoff does.  An error that the classifier does not consider retriable is
+// returned right away.  A nil classifier retries every error.  The callback, if any, is
+// called before each wait.  Once the attempts or budgets are exhausted, the last error is
+// returned.  The function is always executed at least once.
+func RetryOnError(backoff Backoff, retriable ErrorClassifier, onRetry RetryCallback, f func() error) error {
+	started := now()
+	waitTime := backoff.Initial
+	waited := time.Duration(0)
+	for i := 1; ; i++ {
+		err := f()
+		if err == nil || (retriable != nil && !retriable(err)) {
+			return err
+		}
+
+		jitteredWaitTime := applyJitter(waitTime, backoff.Jitter)
+		if backoff.exhausted(i, waited, jitteredWaitTime, started) {
+			return err
+		}
+
+		if onRetry != nil {
+			onRetry(i, err, jitteredWaitTime)
+		}
+		sleep(jitteredWaitTime)
+		waited += jitteredWaitTime
+		waitTime = backoff.next(waitTime)
+	}
+}
+
 // GenSchedFunc is a generic scheduleable function
 type GenSchedFunc func(timeparm time.Duration)
 
-// Starts ticker task to run custom work.
-func ScheduleWork(tickerDuration time.Duration, l logr.Logger, gsf GenSchedFunc, timeparm time.Duration) {
+// ScheduledWork is a handle on work started by ScheduleWork.
+type ScheduledWork struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop stops the scheduled work, and waits for a run that is in progress to finish.
+func (w *ScheduledWork) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+// Done returns a channel that is closed once the scheduled work has stopped.
+func (w *ScheduledWork) Done() <-chan struct{} {
+	return w.done
+}
+
+// Starts ticker task to run custom work, until the input context is done or the returned
+// handle is stopped.  The work runs at fixed intervals measured from when it was
+// scheduled, so the schedule does not drift by the time each run takes.  A tick that
+// comes while the work is still running is skipped.  If runImmediately is true, the work
+// also runs once right away.
+func ScheduleWork(ctx context.Context, tickerDuration time.Duration, runImmediately bool, l logr.Logger, gsf GenSchedFunc, timeparm time.Duration) *ScheduledWork {
+	ctx, cancel := context.WithCancel(ctx)
+	work := &ScheduledWork{cancel: cancel, done: make(chan struct{})}
+
 	// Start a ticker that will receive periodic requests to run the input function.
 	purgeTicker := time.NewTicker(tickerDuration)
 
-	// This is the function that will run custom work.  Note that this function
-	// never ends since we expect this to be running in a Kubernetes pod which will
-	// never end on its own.
+	run := func() {
+		if l != nil {
+			l.Info("Started execution of scheduled custom work.")
+		}
+
+		gsf(timeparm)
+
+		if l != nil {
+			l.Info("Finished execution of scheduled custom work.")
+		}
+	}
+
+	// This is the function that will run custom work, until it is stopped.
 	go func() {
+		defer close(work.done)
+		defer purgeTicker.Stop()
+
+		if runImmediately && ctx.Err() == nil {
+			run()
+		}
+
 		for {
 			select {
 			case <-purgeTicker.C:
-				if l != nil {
-					l.Info("Started execution of scheduled custom work.")
-				}
-
-				gsf(timeparm)
-
-				if l != nil {
-					l.Info("Finished execution of scheduled custom work.")
-				}
+				run()
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
+
+	return work
 }
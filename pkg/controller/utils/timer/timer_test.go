@@ -0,0 +1,225 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Records the wait times instead of sleeping.  The returned function restores sleeping.
+func recordSleeps() (*[]time.Duration, func()) {
+	waits := []time.Duration{}
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+	return &waits, func() { sleep = time.Sleep }
+}
+
+// Test that the wait time grows exponentially, up to the maximum.
+func TestRetryWithBackoff(t *testing.T) {
+	waits, restore := recordSleeps()
+	defer restore()
+
+	calls := 0
+	err := RetryWithBackoff(Backoff{Attempts: 5, Initial: time.Second, Factor: 2, Max: 5 * time.Second}, func() (bool, error) {
+		calls++
+		return calls == 5, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if len(*waits) != len(expected) {
+		t.Fatalf("Expected waits %v, but found %v", expected, *waits)
+	}
+	for i := range expected {
+		if (*waits)[i] != expected[i] {
+			t.Fatalf("Expected waits %v, but found %v", expected, *waits)
+		}
+	}
+}
+
+// Test that no attempt is made once the wait budget would be exceeded.
+func TestRetryWithBackoffBudget(t *testing.T) {
+	waits, restore := recordSleeps()
+	defer restore()
+
+	calls := 0
+	err := RetryWithBackoff(Backoff{Attempts: 10, Initial: time.Second, Factor: 2, Budget: 4 * time.Second}, func() (bool, error) {
+		calls++
+		return false, nil
+	})
+	if err == nil {
+		t.Fatal("Expected the retry budget to be exhausted")
+	}
+	if calls != 3 || len(*waits) != 2 {
+		t.Fatalf("Expected 3 attempts and 2 waits, but found %v attempts and waits %v", calls, *waits)
+	}
+}
+
+// Test that an error stops the retries.
+func TestRetryWithBackoffError(t *testing.T) {
+	_, restore := recordSleeps()
+	defer restore()
+
+	calls := 0
+	failure := errors.New("failure")
+	err := RetryWithBackoff(Backoff{Attempts: 10, Initial: time.Second, Factor: 2}, func() (bool, error) {
+		calls++
+		return false, failure
+	})
+	if err != failure || calls != 1 {
+		t.Fatalf("Expected the error to be returned after 1 attempt, but found %v after %v attempts", err, calls)
+	}
+}
+
+// Test that retriable errors are retried, with a callback before each wait, and that other
+// errors are returned right away.
+func TestRetryOnError(t *testing.T) {
+	_, restore := recordSleeps()
+	defer restore()
+
+	transient := errors.New("transient")
+	permanent := errors.New("permanent")
+	retriable := func(err error) bool { return err == transient }
+
+	retried := []int{}
+	onRetry := func(attempt int, err error, waitTime time.Duration) {
+		if err != transient || waitTime != time.Second<<uint(attempt-1) {
+			t.Fatalf("Unexpected retry of attempt %v after %v: %v", attempt, waitTime, err)
+		}
+		retried = append(retried, attempt)
+	}
+
+	calls := 0
+	err := RetryOnError(Backoff{Attempts: 5, Initial: time.Second, Factor: 2}, retriable, onRetry, func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || calls != 3 || len(retried) != 2 || retried[1] != 2 {
+		t.Fatalf("Expected success on the third attempt, but found %v after %v attempts, retries %v", err, calls, retried)
+	}
+
+	calls = 0
+	err = RetryOnError(Backoff{Attempts: 5, Initial: time.Second, Factor: 2}, retriable, nil, func() error {
+		calls++
+		return permanent
+	})
+	if err != permanent || calls != 1 {
+		t.Fatalf("Expected the permanent error after 1 attempt, but found %v after %v attempts", err, calls)
+	}
+
+	// The last error is returned once the attempts are exhausted.
+	calls = 0
+	err = RetryOnError(Backoff{Attempts: 2, Initial: time.Second, Factor: 2}, nil, nil, func() error {
+		calls++
+		return transient
+	})
+	if err != transient || calls != 2 {
+		t.Fatalf("Expected the transient error after 2 attempts, but found %v after %v attempts", err, calls)
+	}
+}
+
+// Test that no attempt is started after the maximum elapsed time, counting the time the
+// attempts take.
+func TestRetryOnErrorMaxElapsed(t *testing.T) {
+	clock := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	sleep = func(d time.Duration) { clock = clock.Add(d) }
+	defer func() {
+		now = time.Now
+		sleep = time.Sleep
+	}()
+
+	calls := 0
+	err := RetryOnError(Backoff{Attempts: 10, Initial: time.Second, Factor: 1, MaxElapsed: 25 * time.Second}, nil, nil, func() error {
+		calls++
+		clock = clock.Add(9 * time.Second)
+		return errors.New("slow")
+	})
+	if err == nil || calls != 3 {
+		t.Fatalf("Expected 3 attempts, but found %v attempts: %v", calls, err)
+	}
+}
+
+// Test that the wait times are randomized by up to the jitter fraction.
+func TestRetryWithBackoffJitter(t *testing.T) {
+	waits, restore := recordSleeps()
+	defer restore()
+	randoms := []float64{0, 1, 0.5}
+	random = func() float64 {
+		r := randoms[0]
+		randoms = randoms[1:]
+		return r
+	}
+	defer func() { random = rand.Float64 }()
+
+	calls := 0
+	err := RetryWithBackoff(Backoff{Attempts: 4, Initial: time.Second, Factor: 2, Jitter: 0.5}, func() (bool, error) {
+		calls++
+		return calls == 4, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []time.Duration{500 * time.Millisecond, 3 * time.Second, 4 * time.Second}
+	if len(*waits) != len(expected) {
+		t.Fatalf("Expected waits %v, but found %v", expected, *waits)
+	}
+	for i := range expected {
+		if (*waits)[i] != expected[i] {
+			t.Fatalf("Expected waits %v, but found %v", expected, *waits)
+		}
+	}
+}
+
+// Test that scheduled work runs right away when asked to, and stops with its context.
+func TestScheduleWork(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := make(chan time.Duration, 10)
+	work := ScheduleWork(ctx, time.Hour, true, nil, func(timeparm time.Duration) {
+		runs <- timeparm
+	}, time.Minute)
+
+	select {
+	case timeparm := <-runs:
+		if timeparm != time.Minute {
+			t.Fatalf("Expected the work to be passed %v, but found %v", time.Minute, timeparm)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the work to run immediately")
+	}
+
+	cancel()
+	select {
+	case <-work.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the work to stop when its context is done")
+	}
+}
+
+// Test that stopping scheduled work stops its ticks.
+func TestScheduleWorkStop(t *testing.T) {
+	runs := make(chan time.Duration, 100)
+	work := ScheduleWork(context.Background(), 10*time.Millisecond, false, nil, func(timeparm time.Duration) {
+		runs <- timeparm
+	}, 0)
+
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the work to run on the ticker")
+	}
+
+	work.Stop()
+	count := len(runs)
+	time.Sleep(50 * time.Millisecond)
+	if len(runs) != count {
+		t.Fatal("Expected the work not to run once it was stopped")
+	}
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The name of the ConfigMap, in the Kabanero namespace, whose traceSpec entry sets the log
+// level of the operator components.  Each of its other entries sets the level of the
+// component it is named after, for example controller_stack: debug.  Its entries take
+// precedence over the trace specification of the Kabanero instance.
+const (
+	TraceConfigMapName = "kabanero-operator-trace"
+	TraceConfigMapKey  = "traceSpec"
+)
+
+// The component of a trace specification that sets the level of every logger.
+const traceDefaultComponent = "*"
+
+// The levels of a trace specification.  A level can also be given as a verbosity number,
+// as with --zap-level.
+var traceLevels = map[string]zapcore.Level{
+	"error": zapcore.ErrorLevel,
+	"info":  zapcore.InfoLevel,
+	"debug": zapcore.Level(-LogLevelDebug),
+	"trace": zapcore.Level(-LogLevelTrace),
+}
+
+// The level that the operator's zap logger writes messages at.  It is kept at the most
+// verbose level of the trace components, and each component filters its own messages.
+var TraceLevel = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// The level of each component, by logger name.
+type traceComponents struct {
+	defaultLevel zapcore.Level
+	levels       map[string]zapcore.Level
+}
+
+var traceLock sync.RWMutex
+var currentTrace = traceComponents{defaultLevel: zapcore.InfoLevel, levels: map[string]zapcore.Level{}}
+
+// The level of the components that a trace specification does not set.
+var traceDefaultLevel = zapcore.InfoLevel
+
+// Parses a trace specification, a colon separated list of component=level entries, for
+// example *=info:stack=debug.  A later entry for a component replaces an earlier one.
+func ParseTraceSpec(spec string) (map[string]zapcore.Level, error) {
+	levels := make(map[string]zapcore.Level)
+	for _, entry := range strings.Split(spec, ":") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+			return nil, fmt.Errorf("The trace specification entry %v is not of the form component=level", entry)
+		}
+		level, err := parseTraceLevel(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("The trace specification entry %v is not valid: %v", entry, err)
+		}
+		levels[strings.TrimSpace(parts[0])] = level
+	}
+	return levels, nil
+}
+
+// Returns the trace specification held by the data of the trace ConfigMap: its traceSpec
+// entry, followed by an entry for each of the component keys.  The component keys are
+// sorted, so that the specification does not change when the ConfigMap is re-read.
+func TraceSpecFromConfigMap(data map[string]string) (string, error) {
+	spec := data[TraceConfigMapKey]
+	if _, err := ParseTraceSpec(spec); err != nil {
+		return "", fmt.Errorf("The %v entry of ConfigMap %v is not valid: %v", TraceConfigMapKey, TraceConfigMapName, err)
+	}
+
+	components := []string{}
+	for component := range data {
+		if component != TraceConfigMapKey {
+			components = append(components, component)
+		}
+	}
+	sort.Strings(components)
+
+	for _, component := range components {
+		level := strings.TrimSpace(data[component])
+		if _, err := parseTraceLevel(level); err != nil {
+			return "", fmt.Errorf("The %v entry of ConfigMap %v is not valid: %v", component, TraceConfigMapName, err)
+		}
+		spec = spec + ":" + component + "=" + level
+	}
+	return spec, nil
+}
+
+// Parses a level name, or a verbosity number.
+func parseTraceLevel(value string) (zapcore.Level, error) {
+	if level, found := traceLevels[strings.ToLower(value)]; found {
+		return level, nil
+	}
+	verbosity, err := strconv.Atoi(value)
+	if err != nil || verbosity < 0 {
+		return zapcore.InfoLevel, fmt.Errorf("The level %v is not one of error, info, debug, trace or a verbosity number", value)
+	}
+	return zapcore.Level(-verbosity), nil
+}
+
+// Sets the level of the components that a trace specification does not set, for example
+// to the level of the --zap-level flag.  The trace specification is reset.
+func SetTraceDefaultLevel(value string) error {
+	level, err := parseTraceLevel(value)
+	if err != nil {
+		return err
+	}
+
+	traceLock.Lock()
+	traceDefaultLevel = level
+	traceLock.Unlock()
+	SetTraceComponents(nil)
+	return nil
+}
+
+// Sets the level of each component.  The components that are not listed log at the level
+// of the * component, or at the default level.
+func SetTraceComponents(levels map[string]zapcore.Level) {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+
+	trace := traceComponents{defaultLevel: traceDefaultLevel, levels: make(map[string]zapcore.Level)}
+	for component, level := range levels {
+		if component == traceDefaultComponent {
+			trace.defaultLevel = level
+		} else {
+			trace.levels[component] = level
+		}
+	}
+
+	mostVerbose := trace.defaultLevel
+	for _, level := range trace.levels {
+		if level < mostVerbose {
+			mostVerbose = level
+		}
+	}
+
+	currentTrace = trace
+	TraceLevel.SetLevel(mostVerbose)
+}
+
+// Returns whether a message of the input verbosity is written by the logger with the input
+// name.  The level of the longest component that matches the name, or that the name
+// starts with followed by a period, applies.
+func isTraceEnabled(name string, verbosity int) bool {
+	traceLock.RLock()
+	defer traceLock.RUnlock()
+
+	level := currentTrace.defaultLevel
+	matched := -1
+	for component, componentLevel := range currentTrace.levels {
+		if len(component) > matched && (name == component || strings.HasPrefix(name, component+".")) {
+			level = componentLevel
+			matched = len(component)
+		}
+	}
+	return zapcore.Level(-verbosity) >= level
+}
+
+// A logger that writes the messages allowed by the trace level of its component.  Errors
+// are always written.
+type traceLogger struct {
+	logr.Logger
+	name string
+}
+
+// An info logger that writes nothing.
+type disabledInfoLogger struct{}
+
+func (disabledInfoLogger) Enabled() bool                                 { return false }
+func (disabledInfoLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+// Returns a logger that filters the messages of the input logger by the trace levels set
+// with SetTraceComponents.
+func NewTraceLogger(logger logr.Logger) logr.Logger {
+	return traceLogger{Logger: logger}
+}
+
+func (l traceLogger) Enabled() bool {
+	return isTraceEnabled(l.name, 0) && l.Logger.Enabled()
+}
+
+func (l traceLogger) Info(msg string, keysAndValues ...interface{}) {
+	if isTraceEnabled(l.name, 0) {
+		l.Logger.Info(msg, keysAndValues...)
+	}
+}
+
+func (l traceLogger) V(level int) logr.InfoLogger {
+	if !isTraceEnabled(l.name, level) {
+		return disabledInfoLogger{}
+	}
+	return l.Logger.V(level)
+}
+
+func (l traceLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return traceLogger{Logger: l.Logger.WithValues(keysAndValues...), name: l.name}
+}
+
+func (l traceLogger) WithName(name string) logr.Logger {
+	fullName := name
+	if len(l.name) != 0 {
+		fullName = l.name + "." + name
+	}
+	return traceLogger{Logger: l.Logger.WithName(name), name: fullName}
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Test that trace specifications are parsed, and badly formed ones are rejected.
+func TestParseTraceSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		levels  map[string]zapcore.Level
+		invalid bool
+	}{
+		{spec: "", levels: map[string]zapcore.Level{}},
+		{spec: "*=info", levels: map[string]zapcore.Level{"*": zapcore.InfoLevel}},
+		{spec: "*=error: stack=Debug :controller_kabaneroplatform=trace", levels: map[string]zapcore.Level{"*": zapcore.ErrorLevel, "stack": zapcore.DebugLevel, "controller_kabaneroplatform": zapcore.Level(-2)}},
+		{spec: "stack=debug:stack=3", levels: map[string]zapcore.Level{"stack": zapcore.Level(-3)}},
+		{spec: "stack", invalid: true},
+		{spec: "=debug", invalid: true},
+		{spec: "stack=verbose", invalid: true},
+		{spec: "stack=-1", invalid: true},
+	}
+
+	for _, test := range tests {
+		levels, err := ParseTraceSpec(test.spec)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("Expected trace specification %v to be rejected", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Trace specification %v was rejected: %v", test.spec, err)
+			continue
+		}
+		if len(levels) != len(test.levels) {
+			t.Errorf("Trace specification %v: expected %v, but found %v", test.spec, test.levels, levels)
+			continue
+		}
+		for component, level := range test.levels {
+			if levels[component] != level {
+				t.Errorf("Trace specification %v: expected %v, but found %v", test.spec, test.levels, levels)
+			}
+		}
+	}
+}
+
+// Test that each logger writes the messages allowed by the level of its component.
+func TestSetTraceComponents(t *testing.T) {
+	defer SetTraceComponents(nil)
+
+	levels, err := ParseTraceSpec("*=error:stack=debug:stack.index=trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTraceComponents(levels)
+
+	if TraceLevel.Level() != zapcore.Level(-LogLevelTrace) {
+		t.Fatalf("Expected the zap level to be the most verbose level, but found %v", TraceLevel.Level())
+	}
+
+	tests := []struct {
+		name      string
+		verbosity int
+		enabled   bool
+	}{
+		{"cmd", 0, false},
+		{"stack", 0, true},
+		{"stack", LogLevelDebug, true},
+		{"stack", LogLevelTrace, false},
+		{"stack.index", LogLevelTrace, true},
+		{"stack.archive", LogLevelTrace, false},
+		{"stackhub", 0, false},
+	}
+	for _, test := range tests {
+		if enabled := isTraceEnabled(test.name, test.verbosity); enabled != test.enabled {
+			t.Errorf("Expected logger %v at verbosity %v to be enabled: %v, but found %v", test.name, test.verbosity, test.enabled, enabled)
+		}
+	}
+
+	// The logger names are joined as they are derived, and errors are always written.
+	messages := []string{}
+	keysAndValues := []interface{}{}
+	logger := NewTraceLogger(recordingLogger{messages: &messages, keysAndValues: &keysAndValues})
+	logger.WithName("stack").WithValues("key", "value").WithName("index").V(LogLevelTrace).Info("index trace")
+	logger.WithName("stack").V(LogLevelTrace).Info("stack trace")
+	logger.WithName("cmd").Info("cmd info")
+	logger.WithName("cmd").Error(nil, "cmd error")
+	if len(messages) != 2 || messages[0] != "index trace" || messages[1] != "cmd error" {
+		t.Fatalf("Expected the index trace and cmd error messages, but found %v", messages)
+	}
+
+	// Without a trace specification, every component logs at the default level.
+	SetTraceComponents(nil)
+	if TraceLevel.Level() != zapcore.InfoLevel || !isTraceEnabled("cmd", 0) || isTraceEnabled("stack", LogLevelDebug) {
+		t.Fatal("Expected every component to log at the info level")
+	}
+}
+
+// Test that the component keys of the trace ConfigMap follow its traceSpec entry.
+func TestTraceSpecFromConfigMap(t *testing.T) {
+	tests := []struct {
+		data    map[string]string
+		spec    string
+		invalid bool
+	}{
+		{data: nil, spec: ""},
+		{data: map[string]string{TraceConfigMapKey: "*=info:stack=debug"}, spec: "*=info:stack=debug"},
+		{data: map[string]string{"stack": " trace ", "controller_kabaneroplatform": "debug", TraceConfigMapKey: "stack=info"}, spec: "stack=info:controller_kabaneroplatform=debug:stack=trace"},
+		{data: map[string]string{TraceConfigMapKey: "stack"}, invalid: true},
+		{data: map[string]string{"stack": "verbose"}, invalid: true},
+	}
+
+	for _, test := range tests {
+		spec, err := TraceSpecFromConfigMap(test.data)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("Expected ConfigMap data %v to be rejected", test.data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ConfigMap data %v was rejected: %v", test.data, err)
+			continue
+		}
+		if spec != test.spec {
+			t.Errorf("ConfigMap data %v: expected trace specification %v, but found %v", test.data, test.spec, spec)
+		}
+	}
+
+	// The component keys take precedence over the traceSpec entry.
+	spec, _ := TraceSpecFromConfigMap(map[string]string{"stack": "trace", TraceConfigMapKey: "stack=info"})
+	levels, err := ParseTraceSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levels["stack"] != zapcore.Level(-LogLevelTrace) {
+		t.Fatalf("Expected the stack component to log at the trace level, but found %v", levels["stack"])
+	}
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/credentials"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var tracinglog = logf.Log.WithName("tracing")
+
+// The name of the tracer that creates the operator's spans, and the service name they
+// are reported with.
+const (
+	tracerName         = "github.com/kabanero-io/kabanero-operator"
+	tracingServiceName = "kabanero-operator"
+)
+
+// How long the spans of a replaced exporter are given to be sent.
+const tracingShutdownTimeout = 5 * time.Second
+
+// The tracer provider that exports to the current collector, or nil if there is none.
+var tracingLock sync.Mutex
+var tracingProvider *sdktrace.TracerProvider
+var tracingSpec kabanerov1alpha2.TracingSpec
+
+// Exports the operator's spans over OTLP gRPC to the collector of the input
+// specification.  Spans are not recorded while no endpoint is set.  When the collector
+// changes, the spans of the previous exporter are flushed before it is shut down.
+func SetTracingEndpoint(ctx context.Context, spec kabanerov1alpha2.TracingSpec) error {
+	tracingLock.Lock()
+	defer tracingLock.Unlock()
+
+	if spec == tracingSpec {
+		return nil
+	}
+
+	var provider *sdktrace.TracerProvider
+	if len(spec.Endpoint) != 0 {
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(spec.Endpoint)}
+		if spec.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		} else {
+			options = append(options, otlptracegrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, "")))
+		}
+		exporter, err := otlptracegrpc.New(ctx, options...)
+		if err != nil {
+			return fmt.Errorf("Unable to export spans to %v: %v", spec.Endpoint, err)
+		}
+		provider = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", tracingServiceName))))
+	}
+
+	if tracingProvider != nil {
+		shutdownCtx, cancel := context.WithTimeout(ctx, tracingShutdownTimeout)
+		defer cancel()
+		if err := tracingProvider.Shutdown(shutdownCtx); err != nil {
+			tracinglog.Error(err, fmt.Sprintf("Unable to send the remaining spans to %v", tracingSpec.Endpoint))
+		}
+	}
+
+	if provider != nil {
+		otel.SetTracerProvider(provider)
+		tracinglog.Info(fmt.Sprintf("Exporting spans to %v", spec.Endpoint))
+	} else {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		tracinglog.Info("Spans are not exported")
+	}
+	tracingProvider = provider
+	tracingSpec = spec
+	return nil
+}
+
+// Starts a span of the operator, as a child of the span of the input context, if any.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// Ends a span, and records the input error, if any, as its status.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Test that spans are nested by their context, and that errors are recorded as their status.
+func TestStartSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	ctx, parent := StartSpan(context.Background(), "ReconcileStack", attribute.String("stack.name", "nodejs"))
+	_, child := StartSpan(ctx, "Download")
+	EndSpan(child, errors.New("connection refused"))
+	EndSpan(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, but found %v", len(spans))
+	}
+	if spans[0].Name() != "Download" || spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("Expected the Download span to be a child of the ReconcileStack span")
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "connection refused" {
+		t.Errorf("Expected the Download span to record its error, but found %v", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("Expected the ReconcileStack span not to have a status, but found %v", spans[1].Status())
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The rendering context entry holding the workspace bindings, keyed by workspace name.
+const workspacesRenderingKey = "Workspaces"
+
+// The volume sources that bind a workspace to storage chosen by the pipeline archive.
+var workspaceStorageSources = []string{"persistentVolumeClaim", "volumeClaimTemplate", "configMap", "secret"}
+
+// Returns the rendering context entry for the input workspace bindings.  Each binding is
+// a map holding its StorageClassName, Size and AccessMode, so that Go templates can refer
+// to them, as in {{ .Workspaces.source.Size }}.
+func workspaceRenderingContext(bindings []kabanerov1alpha2.WorkspaceBindingSpec) map[string]interface{} {
+	workspaces := make(map[string]interface{})
+	for _, binding := range bindings {
+		workspaces[binding.Name] = map[string]interface{}{
+			"StorageClassName": binding.StorageClassName,
+			"Size":             binding.GetSize(),
+			"AccessMode":       binding.GetAccessMode(),
+		}
+	}
+	return workspaces
+}
+
+// Binds the workspaces of the PipelineRuns created by the input TriggerTemplate to claims
+// made from the workspace bindings in the rendering context.  Only the workspaces that the
+// PipelineRun binds to an emptyDir, or to no volume, are bound, so that an archive can run
+// without the bindings and still choose its own storage.
+func injectWorkspaceBindings(u *unstructured.Unstructured, renderingContext map[string]interface{}) error {
+	if u.GetKind() != "TriggerTemplate" {
+		return nil
+	}
+	workspaces, _ := renderingContext[workspacesRenderingKey].(map[string]interface{})
+	if len(workspaces) == 0 {
+		return nil
+	}
+
+	templates, found, err := unstructured.NestedSlice(u.Object, "spec", "resourcetemplates")
+	if !found || err != nil {
+		return err
+	}
+
+	changed := false
+	for _, template := range templates {
+		run, ok := template.(map[string]interface{})
+		if !ok || run["kind"] != "PipelineRun" {
+			continue
+		}
+
+		bindings, _, err := unstructured.NestedSlice(run, "spec", "workspaces")
+		if err != nil {
+			return err
+		}
+		runChanged := false
+		for i, binding := range bindings {
+			workspace, ok := binding.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := workspace["name"].(string)
+			values, ok := workspaces[name].(map[string]interface{})
+			if !ok || hasWorkspaceStorage(workspace) {
+				continue
+			}
+
+			bound := map[string]interface{}{"name": name, "volumeClaimTemplate": newWorkspaceClaimTemplate(values)}
+			if subPath, ok := workspace["subPath"]; ok {
+				bound["subPath"] = subPath
+			}
+			bindings[i] = bound
+			runChanged = true
+		}
+		if runChanged {
+			if err := unstructured.SetNestedSlice(run, bindings, "spec", "workspaces"); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return unstructured.SetNestedSlice(u.Object, templates, "spec", "resourcetemplates")
+}
+
+// Returns true if the input workspace binding is bound to storage chosen by the archive.
+func hasWorkspaceStorage(workspace map[string]interface{}) bool {
+	for _, source := range workspaceStorageSources {
+		if _, found := workspace[source]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the PersistentVolumeClaim template of a workspace binding in the rendering context.
+func newWorkspaceClaimTemplate(values map[string]interface{}) map[string]interface{} {
+	spec := map[string]interface{}{
+		"accessModes": []interface{}{values["AccessMode"]},
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{"storage": values["Size"]},
+		},
+	}
+	if storageClassName, _ := values["StorageClassName"].(string); len(storageClassName) != 0 {
+		spec["storageClassName"] = storageClassName
+	}
+	return map[string]interface{}{"spec": spec}
+}
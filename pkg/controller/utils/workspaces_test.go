@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var workspaceTriggerTemplate = []byte(`apiVersion: triggers.tekton.dev/v1alpha1
+kind: TriggerTemplate
+metadata:
+  name: build-template
+spec:
+  resourcetemplates:
+  - apiVersion: tekton.dev/v1alpha1
+    kind: PipelineRun
+    metadata:
+      generateName: build-
+    spec:
+      pipelineRef:
+        name: build-pipeline
+      workspaces:
+      - name: source
+        emptyDir: {}
+        subPath: src
+      - name: cache
+        persistentVolumeClaim:
+          claimName: maven-cache
+      - name: output
+`)
+
+// Test that the workspaces bound to an emptyDir, or to no volume, are bound to a claim.
+func TestInjectWorkspaceBindings(t *testing.T) {
+	renderingContext := map[string]interface{}{
+		workspacesRenderingKey: workspaceRenderingContext([]kabanerov1alpha2.WorkspaceBindingSpec{
+			{Name: "source", StorageClassName: "fast", Size: "5Gi"},
+			{Name: "cache", StorageClassName: "fast"},
+			{Name: "output"},
+		}),
+	}
+
+	manifests, err := processManifest(workspaceTriggerTemplate, "", renderingContext, "trigger-template.yaml", "")
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if len(manifests) != 1 {
+		t.Fatalf("Expected one manifest, but found %v", len(manifests))
+	}
+
+	templates, _, _ := unstructured.NestedSlice(manifests[0].Yaml.Object, "spec", "resourcetemplates")
+	workspaces, _, _ := unstructured.NestedSlice(templates[0].(map[string]interface{}), "spec", "workspaces")
+	if len(workspaces) != 3 {
+		t.Fatalf("Expected three workspaces, but found %v", workspaces)
+	}
+
+	source := workspaces[0].(map[string]interface{})
+	expected := map[string]interface{}{
+		"name":    "source",
+		"subPath": "src",
+		"volumeClaimTemplate": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"accessModes":      []interface{}{"ReadWriteOnce"},
+				"storageClassName": "fast",
+				"resources":        map[string]interface{}{"requests": map[string]interface{}{"storage": "5Gi"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(source, expected) {
+		t.Fatalf("Expected workspace %v, but found %v", expected, source)
+	}
+
+	// The claim chosen by the archive is kept.
+	cache := workspaces[1].(map[string]interface{})
+	if _, found := cache["volumeClaimTemplate"]; found {
+		t.Fatalf("The claim of the cache workspace should have been kept: %v", cache)
+	}
+
+	// The default size is used, and the default storage class of the cluster.
+	size, _, _ := unstructured.NestedString(workspaces[2].(map[string]interface{}), "volumeClaimTemplate", "spec", "resources", "requests", "storage")
+	_, found, _ := unstructured.NestedString(workspaces[2].(map[string]interface{}), "volumeClaimTemplate", "spec", "storageClassName")
+	if size != kabanerov1alpha2.DefaultWorkspaceSize || found {
+		t.Fatalf("Expected the default claim for the output workspace, but found %v", workspaces[2])
+	}
+}
+
+// Test that the workspace bindings of a stack override those of the Kabanero instance.
+func TestMergeWorkspaceBindings(t *testing.T) {
+	bindings := []kabanerov1alpha2.WorkspaceBindingSpec{{Name: "source", Size: "1Gi"}, {Name: "cache", Size: "10Gi"}}
+	merged := kabanerov1alpha2.MergeWorkspaceBindings(bindings, []kabanerov1alpha2.WorkspaceBindingSpec{{Name: "source", Size: "5Gi"}})
+	expected := []kabanerov1alpha2.WorkspaceBindingSpec{{Name: "cache", Size: "10Gi"}, {Name: "source", Size: "5Gi"}}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("Expected bindings %v, but found %v", expected, merged)
+	}
+}
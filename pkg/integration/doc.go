@@ -0,0 +1,15 @@
+// Package integration runs the stack controller and the admission webhooks against a real
+// API server, started by envtest with the kabanero CRDs installed.  It covers what the
+// fake clients of the unit tests cannot: the schemas of the CRDs, the webhooks and the
+// conversion of v1beta1 objects, the status subresource, and owner references.
+//
+// The tests need the envtest build tag, and the kube-apiserver and etcd binaries in the
+// directory named by KUBEBUILDER_ASSETS:
+//
+//	KUBEBUILDER_ASSETS=/usr/local/kubebuilder/bin go test -tags envtest ./pkg/integration/...
+//
+// With USE_EXISTING_CLUSTER=true the tests run against the cluster of the current
+// kubeconfig instead.  That cluster runs the garbage collector, so the tests also check
+// that the assets of a deleted stack are collected, but the webhooks served by the tests
+// cannot be reached from it, so they are not installed or tested.
+package integration
@@ -0,0 +1,306 @@
+// +build envtest
+
+package integration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabanerov1beta1 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1beta1"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/testutils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The kinds of the assets in the basic pipeline archive.
+var assetKinds = []schema.GroupVersionKind{
+	{Group: "tekton.dev", Version: "v1alpha1", Kind: "Pipeline"},
+	{Group: "tekton.dev", Version: "v1alpha1", Kind: "Task"},
+}
+
+// Returns a stack with one active version, whose pipelines are the basic pipeline archive
+// and whose image is served by the test server.  Returns the digest of the image.
+func newStack(name string) (*kabanerov1alpha2.Stack, string) {
+	digest := server.AddImage("kabanero/"+name, "0.2.5", name+" 0.2.5")
+	return &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec: kabanerov1alpha2.StackSpec{
+			Name: name,
+			Versions: []kabanerov1alpha2.StackVersion{{
+				Version:      "0.2.5",
+				DesiredState: kabanerov1alpha2.StackDesiredStateActive,
+				Pipelines: []kabanerov1alpha2.PipelineSpec{{
+					Id:     "default",
+					Sha256: testutils.BasicPipeline.Sha256,
+					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: server.URL + testutils.BasicPipeline.Name},
+				}},
+				Images: []kabanerov1alpha2.Image{{Id: name, Image: server.Host() + "/kabanero/" + name}},
+			}},
+		},
+	}, digest
+}
+
+// Waits until the condition is met, or fails the test.
+func waitFor(t *testing.T, description string, condition func() (bool, error)) {
+	t.Helper()
+	if err := wait.PollImmediate(pollInterval, pollTimeout, condition); err != nil {
+		t.Fatalf("Timed out waiting for %v: %v", description, err)
+	}
+}
+
+// Waits until the version of the stack is active, and returns the stack.
+func waitForActive(t *testing.T, name string) *kabanerov1alpha2.Stack {
+	t.Helper()
+	stack := &kabanerov1alpha2.Stack{}
+	waitFor(t, "stack "+name+" to be active", func() (bool, error) {
+		if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: testNamespace}, stack); err != nil {
+			return false, err
+		}
+		return len(stack.Status.Versions) == 1 && stack.Status.Versions[0].Status == kabanerov1alpha2.StackDesiredStateActive, nil
+	})
+	return stack
+}
+
+// Returns the assets in the test namespace that have an owner reference to the UID.
+func ownedAssets(t *testing.T, uid types.UID) []unstructured.Unstructured {
+	t.Helper()
+	assets := []unstructured.Unstructured{}
+	for _, gvk := range assetKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind + "List"})
+		if err := k8sClient.List(context.Background(), list, client.InNamespace(testNamespace)); err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range list.Items {
+			for _, ownerRef := range item.GetOwnerReferences() {
+				if ownerRef.UID == uid {
+					assets = append(assets, item)
+				}
+			}
+		}
+	}
+	return assets
+}
+
+// Deletes the stack, if it still exists.
+func deleteStack(t *testing.T, name string) {
+	stack := &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace}}
+	if err := k8sClient.Delete(context.Background(), stack); err != nil && !apierrors.IsNotFound(err) {
+		t.Errorf("Unable to delete stack %v: %v", name, err)
+	}
+}
+
+// Test that a stack is activated: its assets are applied with an owner reference to the
+// stack, and its status reports them, and the digest of its image.
+func TestStackActivation(t *testing.T) {
+	stack, digest := newStack("activation")
+	if !existingCluster {
+		// The mutating webhook removes the tag.
+		stack.Spec.Versions[0].Images[0].Image += ":0.2.5"
+	}
+	if err := k8sClient.Create(context.Background(), stack); err != nil {
+		t.Fatal(err)
+	}
+	defer deleteStack(t, stack.Name)
+
+	stack = waitForActive(t, stack.Name)
+
+	if image := stack.Spec.Versions[0].Images[0].Image; image != server.Host()+"/kabanero/activation" {
+		t.Errorf("Expected the image without its tag, but found %v", image)
+	}
+
+	pipelines := stack.Status.Versions[0].Pipelines
+	if len(pipelines) != 1 || len(pipelines[0].ActiveAssets) != 2 {
+		t.Fatalf("Expected 2 active assets, but found %v", pipelines)
+	}
+	for _, asset := range pipelines[0].ActiveAssets {
+		if asset.Status != cutils.AssetStatusActive {
+			t.Errorf("Expected asset %v to be active, but found %v: %v", asset.Name, asset.Status, asset.StatusMessage)
+		}
+	}
+
+	images := stack.Status.Versions[0].Images
+	if len(images) != 1 || "sha256:"+images[0].Digest.Activation != digest {
+		t.Errorf("Expected the activation digest %v, but found %v", digest, images)
+	}
+
+	assets := ownedAssets(t, stack.UID)
+	if len(assets) != 2 {
+		t.Fatalf("Expected 2 assets owned by the stack, but found %v", len(assets))
+	}
+	for _, asset := range assets {
+		for _, ownerRef := range asset.GetOwnerReferences() {
+			if ownerRef.UID == stack.UID && (ownerRef.APIVersion != "kabanero.io/v1alpha2" || ownerRef.Kind != "Stack" || ownerRef.Name != stack.Name) {
+				t.Errorf("Expected asset %v to refer to stack %v, but found %v", asset.GetName(), stack.Name, ownerRef)
+			}
+		}
+	}
+}
+
+// Test that the status is only written through the status subresource: status writes do
+// not change the generation, and updates of the stack do not change the status.
+func TestStackStatusSubresource(t *testing.T) {
+	stack, _ := newStack("status")
+	if err := k8sClient.Create(context.Background(), stack); err != nil {
+		t.Fatal(err)
+	}
+	defer deleteStack(t, stack.Name)
+
+	stack = waitForActive(t, stack.Name)
+	if stack.Generation != 1 {
+		t.Errorf("Expected generation 1 after the status was written, but found %v", stack.Generation)
+	}
+	ready := stack.Status.GetCondition(kabanerov1alpha2.StackConditionReady)
+	if ready == nil || ready.Status != string(corev1.ConditionTrue) {
+		t.Errorf("Expected the stack to be ready, but found %v", ready)
+	}
+
+	updated := &kabanerov1alpha2.Stack{}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: stack.Name, Namespace: testNamespace}, updated); err != nil {
+			return err
+		}
+		updated.Spec.DeletionPolicy = kabanerov1alpha2.AssetDeletionPolicyDelete
+		updated.Status = kabanerov1alpha2.StackStatus{}
+		return k8sClient.Update(context.Background(), updated)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Generation != 2 {
+		t.Errorf("Expected generation 2 after the spec changed, but found %v", updated.Generation)
+	}
+	if len(updated.Status.Versions) != 1 {
+		t.Errorf("Expected the update to keep the status, but found %v", updated.Status)
+	}
+}
+
+// Test that the validating webhook rejects an invalid stack.
+func TestStackValidation(t *testing.T) {
+	if existingCluster {
+		t.Skip("The webhooks are not installed in an existing cluster")
+	}
+
+	stack, _ := newStack("invalid")
+	stack.Spec.Versions[0].Pipelines[0].Https.Url = server.URL + "/pipelines.zip"
+	err := k8sClient.Create(context.Background(), stack)
+	if err == nil {
+		deleteStack(t, stack.Name)
+		t.Fatal("Expected the stack to be rejected")
+	}
+	if !strings.Contains(err.Error(), "must be a .tar.gz or .yaml") {
+		t.Errorf("Expected the stack to be rejected by the validating webhook, but found %v", err)
+	}
+}
+
+// Test that a v1beta1 stack is defaulted by its webhook, and converted to the storage
+// version, and back.
+func TestStackV1beta1(t *testing.T) {
+	if existingCluster {
+		t.Skip("The webhooks are not installed in an existing cluster")
+	}
+
+	stack := &kabanerov1beta1.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: testNamespace},
+		Spec: kabanerov1beta1.StackSpec{
+			Versions: []kabanerov1beta1.StackVersion{{
+				Version:      "0.3.1",
+				DesiredState: kabanerov1alpha2.StackDesiredStateInactive,
+				Images:       []kabanerov1alpha2.Image{{Id: "nodejs", Image: server.Host() + "/kabanero/nodejs"}},
+			}},
+		},
+	}
+	if err := k8sClient.Create(context.Background(), stack); err != nil {
+		t.Fatal(err)
+	}
+	defer deleteStack(t, stack.Name)
+
+	stored := &kabanerov1alpha2.Stack{}
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "nodejs", Namespace: testNamespace}, stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Spec.Name != "nodejs" || stored.Spec.Versions[0].DesiredState != kabanerov1alpha2.StackDesiredStateInactive {
+		t.Errorf("Expected the id to default to the name, but found %v", stored.Spec)
+	}
+
+	converted := &kabanerov1beta1.Stack{}
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Name: "nodejs", Namespace: testNamespace}, converted); err != nil {
+		t.Fatal(err)
+	}
+	if converted.Spec.Id != "nodejs" || converted.Spec.Versions[0].Version != "0.3.1" {
+		t.Errorf("Expected the stack to convert back to v1beta1, but found %v", converted.Spec)
+	}
+}
+
+// Test that the finalizer of the stack controller deletes the assets of a deleted stack.
+func TestStackDeletion(t *testing.T) {
+	stack, _ := newStack("deletion")
+	if err := k8sClient.Create(context.Background(), stack); err != nil {
+		t.Fatal(err)
+	}
+	defer deleteStack(t, stack.Name)
+
+	stack = waitForActive(t, stack.Name)
+	if len(ownedAssets(t, stack.UID)) != 2 {
+		t.Fatal("Expected 2 assets owned by the stack")
+	}
+
+	deleteStack(t, stack.Name)
+	waitFor(t, "the stack and its assets to be deleted", func() (bool, error) {
+		err := k8sClient.Get(context.Background(), types.NamespacedName{Name: stack.Name, Namespace: testNamespace}, &kabanerov1alpha2.Stack{})
+		if !apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return len(ownedAssets(t, stack.UID)) == 0, nil
+	})
+}
+
+// Test that the assets of a stack that is deleted without the finalizer of the stack
+// controller are left to the garbage collector.  envtest does not run the garbage
+// collector, so the assets are only checked to be collected in an existing cluster.
+func TestStackGarbageCollection(t *testing.T) {
+	stack, _ := newStack("collection")
+	if err := k8sClient.Create(context.Background(), stack); err != nil {
+		t.Fatal(err)
+	}
+	defer deleteStack(t, stack.Name)
+
+	stack = waitForActive(t, stack.Name)
+
+	// Removing the finalizer does not change the generation, so the controller does not
+	// put it back.
+	withoutFinalizer := stack.DeepCopy()
+	withoutFinalizer.Finalizers = nil
+	if err := k8sClient.Patch(context.Background(), withoutFinalizer, client.MergeFrom(stack)); err != nil {
+		t.Fatal(err)
+	}
+	if err := k8sClient.Delete(context.Background(), stack, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the stack to be deleted", func() (bool, error) {
+		err := k8sClient.Get(context.Background(), types.NamespacedName{Name: stack.Name, Namespace: testNamespace}, &kabanerov1alpha2.Stack{})
+		return apierrors.IsNotFound(err), nil
+	})
+
+	if !existingCluster {
+		if len(ownedAssets(t, stack.UID)) != 2 {
+			t.Fatal("Expected the 2 assets to keep their owner reference to the deleted stack")
+		}
+		t.Log("The garbage collector does not run in envtest. The collection of the assets was not checked.")
+		return
+	}
+
+	waitFor(t, "the assets to be collected", func() (bool, error) {
+		return len(ownedAssets(t, stack.UID)) == 0, nil
+	})
+}
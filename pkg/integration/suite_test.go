@@ -0,0 +1,452 @@
+// +build envtest
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/kabanero-io/kabanero-operator/pkg/apis"
+	kabanerov1beta1 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1beta1"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	"github.com/kabanero-io/kabanero-operator/pkg/testutils"
+	kabanerowebhookv1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/webhook/kabanero/v1alpha2"
+	stackwebhook "github.com/kabanero-io/kabanero-operator/pkg/webhook/stack"
+	imagev1 "github.com/openshift/api/image/v1"
+	pipelinev1alpha1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+)
+
+// The namespace of the stacks.
+const testNamespace = "kabanero"
+
+// The address on which the webhooks are served.
+const webhookHost = "127.0.0.1"
+
+// How long to wait for the API server, and for the controller to act.
+const (
+	pollInterval = 100 * time.Millisecond
+	pollTimeout  = 30 * time.Second
+)
+
+// The CRDs installed in the API server, relative to this package.
+var crdFiles = []string{
+	"../../deploy/crds/kabanero.io_kabaneros_crd.yaml",
+	"../../deploy/crds/kabanero.io_stacks_crd.yaml",
+	"../../deploy/crds/kabanero.io_stackhubs_crd.yaml",
+	"testdata/dependencies_crds.yaml",
+}
+
+// The webhook configurations installed in the API server, relative to this package.
+const webhookConfigFile = "../../config/orchestrations/admission-webhook/0.2/kabanero-operator-admission-webhook-config.yaml"
+
+var crdGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+
+var (
+	// A client that reads from the API server, rather than from the cache of the manager.
+	k8sClient client.Client
+
+	// Serves the pipeline archives and the image manifests of the stacks.
+	server *testutils.Server
+
+	// True when the tests run against an existing cluster.
+	existingCluster bool
+)
+
+func TestMain(m *testing.M) {
+	logf.SetLogger(zap.Logger(true))
+	os.Exit(run(m))
+}
+
+// Starts the API server and the manager, runs the tests, and stops them.  Returns the
+// exit code of the tests.
+func run(m *testing.M) int {
+	existingCluster = os.Getenv("USE_EXISTING_CLUSTER") == "true"
+
+	env := &envtest.Environment{}
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to start the API server: %v\n", err)
+		return 1
+	}
+	defer env.Stop()
+
+	certDir, err := ioutil.TempDir("", "kabanero-envtest")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create the certificate directory: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(certDir)
+
+	caBundle, err := writeServingCertificate(certDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create the webhook serving certificate: %v\n", err)
+		return 1
+	}
+
+	port, err := freePort()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to find a port for the webhooks: %v\n", err)
+		return 1
+	}
+	webhookURL := fmt.Sprintf("https://%v:%v", webhookHost, port)
+	if existingCluster {
+		webhookURL = ""
+	}
+
+	s, err := newScheme()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create the scheme: %v\n", err)
+		return 1
+	}
+
+	if err := installCRDs(cfg, s, webhookURL, caBundle); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to install the CRDs: %v\n", err)
+		return 1
+	}
+
+	// The client is created once the CRDs are installed, so that it can map their kinds.
+	k8sClient, err = client.New(cfg, client.Options{Scheme: s})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create the client: %v\n", err)
+		return 1
+	}
+
+	err = k8sClient.Create(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNamespace}})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		fmt.Fprintf(os.Stderr, "Unable to create namespace %v: %v\n", testNamespace, err)
+		return 1
+	}
+
+	if len(webhookURL) != 0 {
+		if err := installWebhookConfigs(webhookURL, caBundle); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to install the webhook configurations: %v\n", err)
+			return 1
+		}
+	}
+
+	mgr, err := manager.New(cfg, manager.Options{
+		Scheme:             s,
+		MetricsBindAddress: "0",
+		Host:               webhookHost,
+		Port:               port,
+		CertDir:            certDir,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create the manager: %v\n", err)
+		return 1
+	}
+
+	// The same webhooks as the admission webhook deployment.
+	hookServer := mgr.GetWebhookServer()
+	hookServer.Register("/validate-kabaneros/v1alpha2", kabanerowebhookv1alpha2.BuildValidatingWebhook(&mgr))
+	hookServer.Register("/validate-stacks", stackwebhook.BuildValidatingWebhook(&mgr))
+	hookServer.Register("/mutate-stacks", stackwebhook.BuildMutatingWebhook(&mgr))
+	hookServer.Register("/mutate-stacks/v1beta1", admission.DefaultingWebhookFor(&kabanerov1beta1.Stack{}))
+	hookServer.Register("/mutate-kabaneros/v1beta1", admission.DefaultingWebhookFor(&kabanerov1beta1.Kabanero{}))
+	hookServer.Register("/convert", &conversion.Webhook{})
+
+	if err := stack.AddToManager(mgr); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to add the stack controller: %v\n", err)
+		return 1
+	}
+
+	server = testutils.NewServer("../controller/stack/testdata")
+	defer server.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		if err := mgr.Start(stop); err != nil {
+			fmt.Fprintf(os.Stderr, "The manager stopped: %v\n", err)
+		}
+	}()
+
+	if len(webhookURL) != 0 {
+		if err := waitForWebhooks(port, caBundle); err != nil {
+			fmt.Fprintf(os.Stderr, "The webhooks are not being served: %v\n", err)
+			return 1
+		}
+	}
+
+	return m.Run()
+}
+
+// Returns a scheme with the types that the stack controller reads.
+func newScheme() (*runtime.Scheme, error) {
+	s := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, apis.AddToScheme, pipelinev1alpha1.AddToScheme} {
+		if err := addToScheme(s); err != nil {
+			return nil, err
+		}
+	}
+
+	// The ImageStream types are added directly, as the stack controller command does, to
+	// work around the scheme registration of the openshift/api version that is used.
+	imageGV := schema.GroupVersion{Group: "image.openshift.io", Version: "v1"}
+	s.AddKnownTypes(imageGV, &imagev1.ImageStream{}, &imagev1.ImageStreamList{})
+	metav1.AddToGroupVersion(s, imageGV)
+
+	return s, nil
+}
+
+// Creates the CRDs in the files, and waits until they are established.  When the webhook
+// URL is set, the conversion webhooks of the CRDs are served from it.
+func installCRDs(cfg *rest.Config, s *runtime.Scheme, webhookURL string, caBundle []byte) error {
+	c, err := client.New(cfg, client.Options{Scheme: s})
+	if err != nil {
+		return err
+	}
+
+	crds := []*unstructured.Unstructured{}
+	for _, file := range crdFiles {
+		docs, err := readDocuments(file)
+		if err != nil {
+			return err
+		}
+		crds = append(crds, docs...)
+	}
+
+	for _, crd := range crds {
+		strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+		if strategy == "Webhook" {
+			if len(webhookURL) == 0 {
+				unstructured.RemoveNestedField(crd.Object, "spec", "conversion")
+			} else {
+				clientConfig := map[string]interface{}{
+					"url":      webhookURL + "/convert",
+					"caBundle": base64.StdEncoding.EncodeToString(caBundle),
+				}
+				if err := unstructured.SetNestedMap(crd.Object, clientConfig, "spec", "conversion", "webhook", "clientConfig"); err != nil {
+					return err
+				}
+			}
+		}
+
+		err := c.Create(context.Background(), crd)
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("Unable to create CRD %v: %v", crd.GetName(), err)
+		}
+	}
+
+	for _, crd := range crds {
+		err := wait.PollImmediate(pollInterval, pollTimeout, func() (bool, error) {
+			current := &unstructured.Unstructured{}
+			current.SetGroupVersionKind(crdGVK)
+			if err := c.Get(context.Background(), client.ObjectKey{Name: crd.GetName()}, current); err != nil {
+				return false, nil
+			}
+			conditions, _, _ := unstructured.NestedSlice(current.Object, "status", "conditions")
+			for _, condition := range conditions {
+				if m, ok := condition.(map[string]interface{}); ok && m["type"] == "Established" && m["status"] == "True" {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+		if err != nil {
+			return fmt.Errorf("CRD %v was not established: %v", crd.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// Creates the webhook configurations of the admission webhook deployment, calling the
+// webhooks served by the tests instead of the webhook service.
+func installWebhookConfigs(webhookURL string, caBundle []byte) error {
+	b, err := ioutil.ReadFile(webhookConfigFile)
+	if err != nil {
+		return err
+	}
+
+	t, err := template.New("webhooks").Parse(string(b))
+	if err != nil {
+		return err
+	}
+	rendered := strings.Builder{}
+	err = t.Execute(&rendered, map[string]interface{}{"version": "0.2", "caBundle": base64.StdEncoding.EncodeToString(caBundle)})
+	if err != nil {
+		return err
+	}
+
+	configs, err := decodeDocuments(strings.NewReader(rendered.String()))
+	if err != nil {
+		return err
+	}
+
+	for _, config := range configs {
+		webhooks, _, err := unstructured.NestedSlice(config.Object, "webhooks")
+		if err != nil {
+			return err
+		}
+		for i, webhook := range webhooks {
+			w := webhook.(map[string]interface{})
+			path, _, _ := unstructured.NestedString(w, "clientConfig", "service", "path")
+			unstructured.RemoveNestedField(w, "clientConfig", "service")
+			if err := unstructured.SetNestedField(w, webhookURL+path, "clientConfig", "url"); err != nil {
+				return err
+			}
+			webhooks[i] = w
+		}
+		if err := unstructured.SetNestedSlice(config.Object, webhooks, "webhooks"); err != nil {
+			return err
+		}
+
+		if err := k8sClient.Create(context.Background(), config); err != nil {
+			return fmt.Errorf("Unable to create %v %v: %v", config.GetKind(), config.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// Reads the YAML documents of a file.
+func readDocuments(file string) ([]*unstructured.Unstructured, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDocuments(bytes.NewReader(b))
+}
+
+// Decodes a stream of YAML documents.  Empty documents are skipped.
+func decodeDocuments(r io.Reader) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	docs := []*unstructured.Unstructured{}
+	for {
+		obj := map[string]interface{}{}
+		err := decoder.Decode(&obj)
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(obj) != 0 {
+			docs = append(docs, &unstructured.Unstructured{Object: obj})
+		}
+	}
+}
+
+// Waits until the webhook server accepts TLS connections with its certificate.
+func waitForWebhooks(port int, caBundle []byte) error {
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caBundle)
+	address := fmt.Sprintf("%v:%v", webhookHost, port)
+	return wait.PollImmediate(pollInterval, pollTimeout, func() (bool, error) {
+		conn, err := tls.Dial("tcp", address, &tls.Config{RootCAs: roots})
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	})
+}
+
+// Returns a port that is not in use.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", webhookHost+":0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Writes a serving certificate for the webhook host, and its key, to tls.crt and tls.key
+// in the directory.  Returns the PEM encoded certificate of the CA that signed it.
+func writeServingCertificate(dir string) ([]byte, error) {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(24 * time.Hour)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kabanero-envtest-ca"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	certTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: webhookHost},
+		IPAddresses:  []net.IP{net.ParseIP(webhookHost)},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, certTemplate, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "tls.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "tls.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), nil
+}
@@ -0,0 +1,86 @@
+package testutils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// An archive served by the test server, and the sha256 of its content.
+type Archive struct {
+	Name   string
+	Sha256 string
+}
+
+// The pipeline archives in the testdata directory of the stack controller.
+var (
+	BasicPipeline = Archive{
+		Name:   "/basic.pipeline.tar.gz",
+		Sha256: "8080076acd8f54ecbb7de132df148d964e5e93921cce983a0f781418b0871573"}
+
+	BadPipeline = Archive{
+		Name:   "/bad.pipeline.tar.gz",
+		Sha256: "eca24c909ee2b463abcae7c3b8d1be406297e0e1958e43dff1185dc765af985b"}
+
+	Digest1Pipeline = Archive{
+		Name:   "/digest1.pipeline.tar.gz",
+		Sha256: "0238ff31f191396ca4bf5e0ebeea323d012d5dbc7e3f0997e1bf66b017228aaf"}
+
+	Digest2Pipeline = Archive{
+		Name:   "/digest2.pipeline.tar.gz",
+		Sha256: "c3f28ffca707942a8b351000722f1aebda080e3706aa006650a29d10f4aa226b"}
+
+	TriggerPipeline = Archive{
+		Name:   "/trigger.pipeline.tar.gz",
+		Sha256: "901435c796815bbfdf7dd2f8fd44824c8d76535144af80b84ba0ae2fb65113f1"}
+)
+
+// NewArchive builds a tar.gz archive containing the files, keyed by their path in the
+// archive.  The files are written in the order of their path, with a fixed modification
+// time, so the same files always produce the same archive and sha256.
+func NewArchive(files map[string]string) ([]byte, string, error) {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	gz.ModTime = time.Unix(0, 0)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		content := []byte(files[name])
+		header := &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			ModTime:  time.Unix(0, 0),
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, "", err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), sha256Hex(buf.Bytes()), nil
+}
+
+// Returns the hex encoded sha256 of the data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,275 @@
+// Package testutils provides an HTTP server for the unit tests of the controllers.  It
+// serves stack indexes and pipeline archives, from a directory or generated by the test,
+// and the image manifests of a container registry.
+package testutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The media types of the image manifests served by the registry.
+const (
+	manifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+	configMediaType   = "application/vnd.docker.container.image.v1+json"
+)
+
+// Server is a test HTTP server.  Requests for files added to the server are answered with
+// their content, other requests are answered with the file of the same path in the
+// directory of the server.  Responses carry an ETag, and requests that send the current
+// ETag in If-None-Match are answered with 304 Not Modified.
+//
+// Requests under /v2/ are answered as a container registry: the manifest of an image
+// added with AddImage is returned for its tag, or its digest.
+type Server struct {
+	*httptest.Server
+
+	// The directory of the files that were not added to the server.  Empty if there is none.
+	dir string
+
+	lock sync.Mutex
+
+	// The content of the files, keyed by path.
+	files map[string][]byte
+
+	// The image manifests, keyed by repository and then by tag or digest.
+	manifests map[string]map[string][]byte
+
+	// The failures to inject, keyed by path.
+	failures map[string]*failure
+
+	// The number of requests, and of 304 responses, by path.
+	requests    map[string]int
+	notModified map[string]int
+
+	etags bool
+}
+
+// A failure injected for the requests of a path.
+type failure struct {
+	status int
+
+	// The number of requests left to fail.  Negative if all of them fail.
+	remaining int
+}
+
+// NewServer starts a server for the files in the directory.
+func NewServer(dir string) *Server {
+	s := newServer(dir)
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// NewTLSServer starts a server for the files in the directory, using TLS.  The client of
+// the server trusts its certificate.
+func NewTLSServer(dir string) *Server {
+	s := newServer(dir)
+	s.Server = httptest.NewTLSServer(s)
+	return s
+}
+
+func newServer(dir string) *Server {
+	return &Server{
+		dir:         dir,
+		files:       make(map[string][]byte),
+		manifests:   make(map[string]map[string][]byte),
+		failures:    make(map[string]*failure),
+		requests:    make(map[string]int),
+		notModified: make(map[string]int),
+		etags:       true,
+	}
+}
+
+// Host returns the host and port of the server, to use as the registry of an image.
+func (s *Server) Host() string {
+	return strings.TrimPrefix(strings.TrimPrefix(s.URL, "https://"), "http://")
+}
+
+// AddFile serves the content for the path.  The content of a path can be replaced.
+func (s *Server) AddFile(path string, content []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files[path] = content
+}
+
+// AddArchive serves a tar.gz archive of the files for the path, and returns the sha256 of
+// the archive.
+func (s *Server) AddArchive(path string, files map[string]string) (string, error) {
+	archive, sha256, err := NewArchive(files)
+	if err != nil {
+		return "", err
+	}
+	s.AddFile(path, archive)
+	return sha256, nil
+}
+
+// AddImage serves an image manifest for the repository and tag, and returns its digest,
+// in the form sha256:<hex>.  The manifest refers to an image configuration with the
+// content, so adding the same tag with different content changes the digest of the tag.
+func (s *Server) AddImage(repository string, tag string, content string) string {
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     manifestMediaType,
+		"config": map[string]interface{}{
+			"mediaType": configMediaType,
+			"size":      len(content),
+			"digest":    "sha256:" + sha256Hex([]byte(content)),
+		},
+		"layers": []interface{}{},
+	})
+	digest := "sha256:" + sha256Hex(manifest)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.manifests[repository] == nil {
+		s.manifests[repository] = make(map[string][]byte)
+	}
+	s.manifests[repository][tag] = manifest
+	s.manifests[repository][digest] = manifest
+	return digest
+}
+
+// FailOn answers the next requests for the path with the status code.  When count is
+// negative, all of the requests fail until ClearFailures is called.
+func (s *Server) FailOn(path string, status int, count int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures[path] = &failure{status: status, remaining: count}
+}
+
+// ClearFailures stops the injected failures.
+func (s *Server) ClearFailures() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = make(map[string]*failure)
+}
+
+// SetETags sets whether responses carry an ETag.  They do by default.
+func (s *Server) SetETags(enabled bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.etags = enabled
+}
+
+// Requests returns the number of requests received for the path.
+func (s *Server) Requests(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests[path]
+}
+
+// NotModified returns the number of requests for the path that were answered with
+// 304 Not Modified.
+func (s *Server) NotModified(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.notModified[path]
+}
+
+func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+
+	s.lock.Lock()
+	s.requests[path]++
+	if f, ok := s.failures[path]; ok && f.remaining != 0 {
+		if f.remaining > 0 {
+			f.remaining--
+		}
+		s.lock.Unlock()
+		rw.WriteHeader(f.status)
+		return
+	}
+	s.lock.Unlock()
+
+	if path == "/v2/" || strings.HasPrefix(path, "/v2/") {
+		s.serveRegistry(rw, req)
+		return
+	}
+
+	content, err := s.content(path)
+	if err != nil {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	s.serveContent(rw, req, path, content, "")
+}
+
+// Returns the content of a file added to the server, or of the file in the directory.
+func (s *Server) content(path string) ([]byte, error) {
+	s.lock.Lock()
+	content, ok := s.files[path]
+	s.lock.Unlock()
+	if ok {
+		return content, nil
+	}
+
+	if len(s.dir) == 0 {
+		return nil, fmt.Errorf("File %v was not found", path)
+	}
+	return ioutil.ReadFile(filepath.Join(s.dir, filepath.FromSlash(path)))
+}
+
+// Answers a request for content, with 304 Not Modified if the client has the current content.
+func (s *Server) serveContent(rw http.ResponseWriter, req *http.Request, path string, content []byte, contentType string) {
+	s.lock.Lock()
+	etags := s.etags
+	s.lock.Unlock()
+
+	if len(contentType) != 0 {
+		rw.Header().Set("Content-Type", contentType)
+	}
+	if etags {
+		etag := fmt.Sprintf("\"%v\"", sha256Hex(content))
+		rw.Header().Set("ETag", etag)
+		if req.Header.Get("If-None-Match") == etag {
+			s.lock.Lock()
+			s.notModified[path]++
+			s.lock.Unlock()
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	rw.Header().Set("Content-Length", fmt.Sprintf("%v", len(content)))
+	rw.WriteHeader(http.StatusOK)
+	if req.Method != http.MethodHead {
+		rw.Write(content)
+	}
+}
+
+// Answers the requests of the registry API for image manifests.  Anonymous access is
+// allowed, so the ping of the API succeeds.
+func (s *Server) serveRegistry(rw http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	if path == "/v2/" {
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	i := strings.LastIndex(path, "/manifests/")
+	if i < 0 {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	repository := strings.TrimPrefix(path[:i], "/v2/")
+	reference := path[i+len("/manifests/"):]
+
+	s.lock.Lock()
+	manifest, ok := s.manifests[repository][reference]
+	s.lock.Unlock()
+	if !ok {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusNotFound)
+		rw.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+		return
+	}
+
+	rw.Header().Set("Docker-Content-Digest", "sha256:"+sha256Hex(manifest))
+	s.serveContent(rw, req, path, manifest, manifestMediaType)
+}
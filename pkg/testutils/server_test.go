@@ -0,0 +1,125 @@
+package testutils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+// Test that a generated archive contains the files, and is the same each time.
+func TestNewArchive(t *testing.T) {
+	files := map[string]string{"manifest.yaml": "contents: []", "pipeline.yaml": "kind: Pipeline"}
+	archive, sha256, err := NewArchive(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sha256Again, err := NewArchive(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha256 != sha256Again || sha256 != sha256Hex(archive) {
+		t.Fatalf("Expected the same archive each time, but found %v and %v", sha256, sha256Again)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	found := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[header.Name] = string(content)
+	}
+	if len(found) != 2 || found["pipeline.yaml"] != "kind: Pipeline" {
+		t.Fatalf("Expected the files %v, but found %v", files, found)
+	}
+}
+
+// Test that files are revalidated with their ETag, and that failures are injected.
+func TestServerFiles(t *testing.T) {
+	server := NewServer("")
+	defer server.Close()
+	sha256, err := server.AddArchive("/test.pipeline.tar.gz", map[string]string{"pipeline.yaml": "kind: Pipeline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(server.URL + "/test.pipeline.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || sha256Hex(body) != sha256 || len(etag) == 0 {
+		t.Fatalf("Expected the archive with an ETag, but found status %v and ETag %v", resp.StatusCode, etag)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/test.pipeline.tar.gz", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified || server.NotModified("/test.pipeline.tar.gz") != 1 {
+		t.Fatalf("Expected the archive not to be modified, but found status %v", resp.StatusCode)
+	}
+
+	server.FailOn("/test.pipeline.tar.gz", http.StatusServiceUnavailable, 1)
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		resp, err = http.Get(server.URL + "/test.pipeline.tar.gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Expected status %v, but found %v", status, resp.StatusCode)
+		}
+	}
+	if server.Requests("/test.pipeline.tar.gz") != 4 {
+		t.Fatalf("Expected 4 requests, but found %v", server.Requests("/test.pipeline.tar.gz"))
+	}
+
+	resp, err = http.Get(server.URL + "/missing.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a missing file to be not found, but found status %v", resp.StatusCode)
+	}
+}
+
+// Test that the manifest of an image is served for its tag and its digest.
+func TestServerImages(t *testing.T) {
+	server := NewServer("")
+	defer server.Close()
+	digest := server.AddImage("kabanero/nodejs", "0.3", "nodejs 0.3.1")
+
+	for _, reference := range []string{"0.3", digest} {
+		resp, err := http.Get(server.URL + "/v2/kabanero/nodejs/manifests/" + reference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Docker-Content-Digest") != digest || resp.Header.Get("Content-Type") != manifestMediaType {
+			t.Fatalf("Expected the manifest of %v for %v, but found status %v and digest %v", digest, reference, resp.StatusCode, resp.Header.Get("Docker-Content-Digest"))
+		}
+	}
+
+	if moved := server.AddImage("kabanero/nodejs", "0.3", "nodejs 0.3.2"); moved == digest {
+		t.Fatalf("Expected a new digest for the new content, but found %v", moved)
+	}
+}
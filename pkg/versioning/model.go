@@ -1,11 +1,15 @@
 package versioning
 
 import (
+	"github.com/blang/semver"
 	"github.com/kabanero-io/kabanero-operator/pkg/assets/config"
 	"gopkg.in/yaml.v2"
 	"net/http"
 )
 
+// The channel that the related versions of a Kabanero version are in.
+const DefaultChannel = "stable"
+
 var Data = func() VersionDocument {
 	f, err := config.Open("versions.yaml")
 	if err != nil {
@@ -58,6 +62,11 @@ type KabaneroRevision struct {
 	// The versions associated with this Kabanero Version
 	RelatedVersions map[string]string `yaml:"related-versions,omitempty"`
 
+	// Other channels of related software versions, keyed by channel name.  Each channel
+	// lists the versions of each software component that are in the channel.  A component
+	// that is not listed in a channel uses its related version.
+	Channels map[string]map[string][]string `yaml:"channels,omitempty"`
+
 	Document *VersionDocument `yaml:"-"`
 }
 
@@ -75,6 +84,50 @@ func (KabaneroRevision KabaneroRevision) SoftwareComponent(softwareComponent str
 	return nil
 }
 
+// Returns true if the channel is known to this Kabanero version.  The default channel always is.
+func (KabaneroRevision KabaneroRevision) HasChannel(channel string) bool {
+	if channel == DefaultChannel {
+		return true
+	}
+
+	_, ok := KabaneroRevision.Channels[channel]
+	return ok
+}
+
+// Returns the revision of a software component in a channel: the latest of the versions
+// listed for the component in the channel, or else the related version of the component.
+func (KabaneroRevision KabaneroRevision) SoftwareComponentInChannel(softwareComponent string, channel string) *SoftwareRevision {
+	channelVersions := KabaneroRevision.Channels[channel][softwareComponent]
+	if channel == DefaultChannel || len(channelVersions) == 0 {
+		return KabaneroRevision.SoftwareComponent(softwareComponent)
+	}
+
+	var latest *SoftwareRevision
+	var latestVersion semver.Version
+	for _, revision := range KabaneroRevision.Document.RelatedSoftwareRevisions[softwareComponent] {
+		inChannel := false
+		for _, channelVersion := range channelVersions {
+			if revision.Version == channelVersion {
+				inChannel = true
+				break
+			}
+		}
+		if !inChannel {
+			continue
+		}
+
+		// A version that is not semantic, such as a prototype, is older than any other.
+		version, _ := semver.ParseTolerant(revision.Version)
+		if latest == nil || version.GT(latestVersion) {
+			r := revision
+			latest = &r
+			latestVersion = version
+		}
+	}
+
+	return latest
+}
+
 // Contains version specific data for software which is orchestrated as part of Kabanero
 type SoftwareRevision struct {
 	// The version of this piece of software that the orchestrations and identifiers apply to
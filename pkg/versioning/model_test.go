@@ -35,6 +35,24 @@ func TestStaticVersionData(t *testing.T) {
 				t.Fatalf("The Kabanero version `%v` points to the software %v version `%v`, but that reference cannot be resolved", k.Version, sw, v)
 			}
 		}
+
+		//Verify the versions listed in the channels of the kabanero revision
+		for channel, related := range k.Channels {
+			for sw, versions := range related {
+				for _, v := range versions {
+					var found bool
+					for _, rev := range k.Document.RelatedSoftwareRevisions[sw] {
+						if rev.Version == v {
+							found = true
+							break
+						}
+					}
+					if !found {
+						t.Fatalf("The Kabanero version `%v` channel %v lists the software %v version `%v`, but that reference cannot be resolved", k.Version, channel, sw, v)
+					}
+				}
+			}
+		}
 	}
 
 	if len(v.RelatedSoftwareRevisions) < 1 {
@@ -54,3 +72,48 @@ func TestKabaneroRevisions(t *testing.T) {
 		t.Fatal("Revision was nil")
 	}
 }
+
+// Verifies that the latest version of a component in a channel is chosen
+func TestSoftwareComponentInChannel(t *testing.T) {
+	doc := &VersionDocument{
+		RelatedSoftwareRevisions: map[string][]SoftwareRevision{
+			"cli-services": []SoftwareRevision{
+				{Version: "go-prototype"},
+				{Version: "0.10.1"},
+				{Version: "0.10.0"},
+				{Version: "0.9.1"},
+			},
+			"landing": []SoftwareRevision{
+				{Version: "0.10.0"},
+			},
+		},
+	}
+	k := KabaneroRevision{
+		Version:         "0.10.0",
+		RelatedVersions: map[string]string{"cli-services": "0.10.0", "landing": "0.10.0"},
+		Channels: map[string]map[string][]string{
+			"candidate": map[string][]string{"cli-services": []string{"0.9.1", "0.10.1", "go-prototype"}},
+		},
+		Document: doc,
+	}
+
+	if !k.HasChannel(DefaultChannel) || !k.HasChannel("candidate") || k.HasChannel("fast") {
+		t.Fatal("Expected the stable and candidate channels only")
+	}
+
+	rev := k.SoftwareComponentInChannel("cli-services", "candidate")
+	if rev == nil || rev.Version != "0.10.1" {
+		t.Fatalf("Expected cli-services 0.10.1 in the candidate channel, but found %v", rev)
+	}
+
+	rev = k.SoftwareComponentInChannel("cli-services", DefaultChannel)
+	if rev == nil || rev.Version != "0.10.0" {
+		t.Fatalf("Expected cli-services 0.10.0 in the stable channel, but found %v", rev)
+	}
+
+	// A component that is not listed in the channel uses its related version
+	rev = k.SoftwareComponentInChannel("landing", "candidate")
+	if rev == nil || rev.Version != "0.10.0" {
+		t.Fatalf("Expected landing 0.10.0 in the candidate channel, but found %v", rev)
+	}
+}
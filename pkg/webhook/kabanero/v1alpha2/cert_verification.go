@@ -0,0 +1,66 @@
+package kabanero
+
+import (
+	"fmt"
+	"strings"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Returns the fields of the input Kabanero instance that skip certificate verification for
+// a host that its certificate verification policy does not allow.  The registries that
+// Spec.Stacks.SkipRegistryCertVerification applies to are not known, so it is never allowed
+// by an enforced policy.
+func getDisallowedSkipCertVerification(kab *kabanerov1alpha2.Kabanero) []string {
+	policy := kab.Spec.AdmissionControllerWebhook.CertVerificationPolicy
+	if !policy.Enforce {
+		return nil
+	}
+
+	fields := []string{}
+	check := func(skip bool, host string, field string) {
+		if skip && !policy.IsSkipAllowed(host) {
+			fields = append(fields, field)
+		}
+	}
+
+	check(kab.Spec.Stacks.SkipRegistryCertVerification, "", "Spec.Stacks.SkipRegistryCertVerification")
+	check(kab.Spec.ArtifactProxy.SkipCertVerification, kabanerov1alpha2.GetUrlHost(kab.Spec.ArtifactProxy.Url), "Spec.ArtifactProxy.SkipCertVerification")
+	check(kab.Spec.VulnerabilityScan.SkipCertVerification, kabanerov1alpha2.GetUrlHost(kab.Spec.VulnerabilityScan.Url), "Spec.VulnerabilityScan.SkipCertVerification")
+
+	for _, repository := range kab.Spec.Stacks.Repositories {
+		check(repository.Https.SkipCertVerification, kabanerov1alpha2.GetUrlHost(repository.Https.Url), fmt.Sprintf("Spec.Stacks.Repositories[%v].Https.SkipCertVerification", repository.Name))
+		check(repository.GitRelease.SkipCertVerification, repository.GitRelease.Hostname, fmt.Sprintf("Spec.Stacks.Repositories[%v].GitRelease.SkipCertVerification", repository.Name))
+		check(repository.Oci.SkipCertVerification, kabanerov1alpha2.GetImageHost(repository.Oci.Image), fmt.Sprintf("Spec.Stacks.Repositories[%v].Oci.SkipCertVerification", repository.Name))
+		for _, pipeline := range repository.Pipelines {
+			check(pipeline.SkipCertVerification(), pipeline.ArchiveHost(), fmt.Sprintf("Spec.Stacks.Repositories[%v].Pipelines[%v]", repository.Name, pipeline.Id))
+		}
+	}
+
+	for _, pipeline := range kab.Spec.Stacks.Pipelines {
+		check(pipeline.SkipCertVerification(), pipeline.ArchiveHost(), fmt.Sprintf("Spec.Stacks.Pipelines[%v]", pipeline.Id))
+	}
+
+	for _, pipeline := range kab.Spec.Gitops.Pipelines {
+		check(pipeline.SkipCertVerification(), pipeline.ArchiveHost(), fmt.Sprintf("Spec.Gitops.Pipelines[%v]", pipeline.Id))
+	}
+
+	for _, trigger := range kab.Spec.Triggers {
+		if trigger.GitRelease.IsUsable() {
+			check(trigger.GitRelease.SkipCertVerification, trigger.GitRelease.Hostname, fmt.Sprintf("Spec.Triggers[%v].GitRelease.SkipCertVerification", trigger.Id))
+		} else {
+			check(trigger.Https.SkipCertVerification, kabanerov1alpha2.GetUrlHost(trigger.Https.Url), fmt.Sprintf("Spec.Triggers[%v].Https.SkipCertVerification", trigger.Id))
+		}
+	}
+
+	return fields
+}
+
+// Validates the input Kabanero instance against its certificate verification policy.
+func validateCertVerificationPolicy(kab *kabanerov1alpha2.Kabanero) (bool, string) {
+	fields := getDisallowedSkipCertVerification(kab)
+	if len(fields) == 0 {
+		return true, ""
+	}
+	return false, fmt.Sprintf("Kabanero %v skips certificate verification in %v, which Spec.AdmissionControllerWebhook.CertVerificationPolicy does not allow. Verify the certificates, or add the hosts to Spec.AdmissionControllerWebhook.CertVerificationPolicy.AllowedHosts.", kab.Name, strings.Join(fields, ", "))
+}
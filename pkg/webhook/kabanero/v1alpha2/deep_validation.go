@@ -0,0 +1,69 @@
+package kabanero
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Setting this annotation to true on a Kabanero instance makes the webhook check that the
+// gitops pipeline archives can be reached.  Admission waits for the checks, so they are
+// opt-in.
+const DeepValidationAnnotation = "kabanero.io/deep-validation"
+
+// How long the webhook waits for each pipeline archive.  The API server gives up on the
+// webhook after 30 seconds.
+const deepValidationTimeout = 5 * time.Second
+
+// Sends a HEAD request to the input URL, and returns the response status code.  Replaced
+// by tests.
+var headRequest = func(ctx context.Context, url string, skipCertVerification bool) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, deepValidationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: skipCertVerification},
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// Returns true if deep validation was requested for the input Kabanero instance.
+func isDeepValidationRequested(kab *kabanerov1alpha2.Kabanero) bool {
+	return strings.ToLower(kab.GetAnnotations()[DeepValidationAnnotation]) == "true"
+}
+
+// Checks that the https archive of each gitops pipeline can be reached.  A server that
+// does not support HEAD requests is assumed to serve the archive.  Git releases and
+// Tekton bundles are not checked.
+func validateGitopsPipelinesReachable(ctx context.Context, kab *kabanerov1alpha2.Kabanero) (bool, string, error) {
+	for _, pipeline := range kab.Spec.Gitops.Pipelines {
+		if pipeline.GitRelease.IsUsable() || len(pipeline.Oci.Bundle) != 0 || len(pipeline.Https.Url) == 0 {
+			continue
+		}
+
+		code, err := headRequest(ctx, pipeline.Https.Url, pipeline.Https.SkipCertVerification)
+		if err == nil && code >= http.StatusBadRequest && code != http.StatusMethodNotAllowed {
+			err = fmt.Errorf("Http status code: %v", code)
+		}
+		if err != nil {
+			reason := fmt.Sprintf("Kabanero %v Spec.Gitops.Pipelines[] %v archive %v could not be reached: %v. Remove the %v annotation to skip this check.", kab.Name, pipeline.Id, pipeline.Https.Url, err, DeepValidationAnnotation)
+			return false, reason, fmt.Errorf(reason)
+		}
+	}
+	return true, "", nil
+}
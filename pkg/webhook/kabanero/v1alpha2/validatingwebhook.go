@@ -7,10 +7,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 
 	kutils "github.com/kabanero-io/kabanero-operator/pkg/controller/kabaneroplatform/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/utils/timer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -44,24 +47,205 @@ func (v *kabaneroValidator) Handle(ctx context.Context, req admission.Request) a
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	return admission.ValidationResponse(allowed, reason)
+	response := admission.ValidationResponse(allowed, reason)
+	if warnings := getKabaneroWarnings(kabanero); allowed && len(warnings) != 0 {
+		// The Warnings field of the admission response needs a newer Kubernetes API, so the
+		// warnings are recorded in the audit log, and in the message of the response.
+		response.Result.Message = strings.Join(warnings, " ")
+		response.AuditAnnotations = map[string]string{"warnings": response.Result.Message}
+	}
+	return response
+}
+
+// Returns guidance about settings that are allowed, but are probably not what the
+// administrator wants.
+func getKabaneroWarnings(kab *kabanerov1alpha2.Kabanero) []string {
+	warnings := []string{}
+
+	if kab.Spec.Stacks.SkipRegistryCertVerification {
+		warnings = append(warnings, fmt.Sprintf("Kabanero %v Spec.Stacks.SkipRegistryCertVerification is true. The certificates of image registries are not verified.", kab.Name))
+	}
+
+	if kab.Spec.ArtifactProxy.SkipCertVerification {
+		warnings = append(warnings, fmt.Sprintf("Kabanero %v Spec.ArtifactProxy.SkipCertVerification is true. The certificate of the artifact proxy is not verified.", kab.Name))
+	}
+
+	if kab.Spec.VulnerabilityScan.SkipCertVerification {
+		warnings = append(warnings, fmt.Sprintf("Kabanero %v Spec.VulnerabilityScan.SkipCertVerification is true. The certificate of the vulnerability scanner is not verified.", kab.Name))
+	}
+
+	for _, repository := range kab.Spec.Stacks.Repositories {
+		if repository.Https.SkipCertVerification || repository.GitRelease.SkipCertVerification || repository.Oci.SkipCertVerification {
+			warnings = append(warnings, fmt.Sprintf("Kabanero %v Spec.Stacks.Repositories[] %v skips certificate verification. The repository index is downloaded without verifying the server certificate.", kab.Name, repository.Name))
+		}
+		warnings = append(warnings, getPipelineWarnings(kab.Name, fmt.Sprintf("Spec.Stacks.Repositories[%v].Pipelines[]", repository.Name), repository.Pipelines)...)
+	}
+
+	warnings = append(warnings, getPipelineWarnings(kab.Name, "Spec.Stacks.Pipelines[]", kab.Spec.Stacks.Pipelines)...)
+	return warnings
+}
+
+// Returns the warnings about the input pipelines.  The gitops pipelines are not checked,
+// since a missing sha256 is an error for them.
+func getPipelineWarnings(name string, field string, pipelines []kabanerov1alpha2.PipelineSpec) []string {
+	warnings := []string{}
+	for _, pipeline := range pipelines {
+		if len(pipeline.Sha256) == 0 {
+			warnings = append(warnings, fmt.Sprintf("Kabanero %v %v %v does not set a sha256. The pipeline archive is not verified against a digest.", name, field, pipeline.Id))
+		}
+		if pipeline.SkipCertVerification() {
+			warnings = append(warnings, fmt.Sprintf("Kabanero %v %v %v skips certificate verification. The pipeline archive is downloaded without verifying the server certificate.", name, field, pipeline.Id))
+		}
+	}
+	return warnings
 }
 
 func (v *kabaneroValidator) validatekabaneroFn(ctx context.Context, kab *kabanerov1alpha2.Kabanero) (bool, string, error) {
-	allowed, reason, err := isKabaneroInstanceAllowed(v.client, ctx, kab)
+	allowed, reason, err := kutils.ValidateGovernanceStackPolicy(kab)
 	if !allowed {
 		return allowed, reason, err
 	}
 
-	allowed, reason, err = kutils.ValidateGovernanceStackPolicy(kab)
+	allowed, reason, err = kutils.ValidateStackConflictPolicy(kab)
 	if !allowed {
 		return allowed, reason, err
 	}
 
+	if !kabanerov1alpha2.IsValidAssetDeletionPolicy(kab.Spec.AssetDeletionPolicy) {
+		reason = fmt.Sprintf("Kabanero %v Spec.AssetDeletionPolicy may only be set to %v, %v or %v.", kab.Name, kabanerov1alpha2.AssetDeletionPolicyDelete, kabanerov1alpha2.AssetDeletionPolicyOrphan, kabanerov1alpha2.AssetDeletionPolicyRetain)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	// An entry must not allow every kind in the core group.
+	for _, allowed := range kab.Spec.AllowedAssetKinds {
+		if len(allowed.Group) == 0 && len(allowed.Kind) == 0 {
+			reason = fmt.Sprintf("Kabanero %v Spec.AllowedAssetKinds contains an entry with neither a Group nor a Kind. Entries for the core group must specify a Kind.", kab.Name)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+
+		if !kabanerov1alpha2.IsAssetGroupKindManageable(allowed) {
+			reason = fmt.Sprintf("Kabanero %v Spec.AllowedAssetKinds contains kind %v in group %v, which the stack controller is not granted access to.", kab.Name, allowed.Kind, allowed.Group)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+	}
+
+	if !kabanerov1alpha2.IsValidAssetDriftPolicy(kab.Spec.AssetDriftPolicy) {
+		reason = fmt.Sprintf("Kabanero %v Spec.AssetDriftPolicy may only be set to %v or %v.", kab.Name, kabanerov1alpha2.AssetDriftPolicyRepair, kabanerov1alpha2.AssetDriftPolicyReport)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if kab.Spec.Downloads.MaxConcurrent < 0 || kab.Spec.Downloads.MaxConcurrentPerHost < 0 {
+		reason = fmt.Sprintf("Kabanero %v Spec.Downloads.MaxConcurrent and Spec.Downloads.MaxConcurrentPerHost must not be negative.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if kab.Spec.Downloads.MaxDownloadSize < 0 || kab.Spec.Downloads.MaxArchiveFileSize < 0 || kab.Spec.Downloads.MaxArchiveExtractedSize < 0 {
+		reason = fmt.Sprintf("Kabanero %v Spec.Downloads.MaxDownloadSize, Spec.Downloads.MaxArchiveFileSize and Spec.Downloads.MaxArchiveExtractedSize must not be negative.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidDownloadRetry(kab.Spec.Downloads.Retry) {
+		reason = fmt.Sprintf("Kabanero %v Spec.Downloads.Retry.RetryOnStatus entries must be a status code, such as 503, or a status class, such as 5xx.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if _, parseErr := cutils.ParseTraceSpec(kab.Spec.Logging.TraceSpec); parseErr != nil {
+		reason = fmt.Sprintf("Kabanero %v Spec.Logging.TraceSpec is not valid. %v", kab.Name, parseErr)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if len(kab.Spec.Stacks.RefreshSchedule) != 0 {
+		if _, parseErr := timer.ParseCronSchedule(kab.Spec.Stacks.RefreshSchedule); parseErr != nil {
+			reason = fmt.Sprintf("Kabanero %v Spec.Stacks.RefreshSchedule is not valid. %v", kab.Name, parseErr)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+	}
+
+	if !kabanerov1alpha2.IsValidCommonLabels(kab.Spec.CommonLabels) {
+		reason = fmt.Sprintf("Kabanero %v Spec.CommonLabels must contain valid label keys and values.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidCommonAnnotations(kab.Spec.CommonAnnotations) {
+		reason = fmt.Sprintf("Kabanero %v Spec.CommonAnnotations must contain valid annotation keys.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if kab.Spec.CliServices.Replicas != nil && *kab.Spec.CliServices.Replicas < 1 {
+		reason = fmt.Sprintf("Kabanero %v Spec.CliServices.Replicas must be at least 1. Set Spec.CliServices.Enable to false to remove the CLI services.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidCliOidc(kab.Spec.CliServices.Oidc) {
+		reason = fmt.Sprintf("Kabanero %v Spec.CliServices.Oidc must have an https IssuerUrl, a ClientId and a ClientSecretRef.Name.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidRouteCustomization(kab.Spec.CliServices.Route) {
+		reason = fmt.Sprintf("Kabanero %v Spec.CliServices.Route must have a DNS subdomain Hostname, a Termination of %v or %v, and an InsecureEdgeTerminationPolicy of Redirect, None, or Allow when the Termination is not %v.", kab.Name, kabanerov1alpha2.RouteTerminationReencrypt, kabanerov1alpha2.RouteTerminationPassthrough, kabanerov1alpha2.RouteTerminationPassthrough)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if len(kab.Spec.CliServices.TlsSecretName) != 0 && kab.Spec.CliServices.Route.Termination == kabanerov1alpha2.RouteTerminationPassthrough {
+		reason = fmt.Sprintf("Kabanero %v Spec.CliServices.TlsSecretName can not be set when Spec.CliServices.Route.Termination is %v.", kab.Name, kabanerov1alpha2.RouteTerminationPassthrough)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidRouteCustomization(kab.Spec.Landing.Route) {
+		reason = fmt.Sprintf("Kabanero %v Spec.Landing.Route must have a DNS subdomain Hostname, a Termination of %v or %v, and an InsecureEdgeTerminationPolicy of Redirect, None, or Allow when the Termination is not %v.", kab.Name, kabanerov1alpha2.RouteTerminationReencrypt, kabanerov1alpha2.RouteTerminationPassthrough, kabanerov1alpha2.RouteTerminationPassthrough)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if allowed, reason := validateCertVerificationPolicy(kab); !allowed {
+		return false, reason, fmt.Errorf(reason)
+	}
+
+	if !kabanerov1alpha2.IsValidVulnerabilityScan(kab.Spec.VulnerabilityScan) {
+		reason = fmt.Sprintf("Kabanero %v Spec.VulnerabilityScan must have an http or https Url, a Scanner of %v or %v, an Action of %v or %v, a FailurePolicy of %v or %v, and a MaxCriticalVulnerabilities that is not negative.", kab.Name, kabanerov1alpha2.VulnerabilityScannerQuay, kabanerov1alpha2.VulnerabilityScannerTrivy, kabanerov1alpha2.VulnerabilityScanActionBlock, kabanerov1alpha2.VulnerabilityScanActionWarn, kabanerov1alpha2.VulnerabilityScanFailurePolicyFail, kabanerov1alpha2.VulnerabilityScanFailurePolicyIgnore)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidPipelineRunRetention(kab.Spec.PipelineRunRetention) {
+		reason = fmt.Sprintf("Kabanero %v Spec.PipelineRunRetention must have a MaxCount that is not negative, and a MaxAge that is a positive duration, such as 72h.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	for _, repository := range kab.Spec.Stacks.Repositories {
+		if !repository.HasResolverSettings() {
+			reason = fmt.Sprintf("Kabanero %v Spec.Stacks.Repositories[%v] uses the %v resolver, but does not have its settings.", kab.Name, repository.Name, repository.Resolver)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+	}
+
+	if !kabanerov1alpha2.IsValidWorkspaceBindings(kab.Spec.Workspaces) {
+		reason = fmt.Sprintf("Kabanero %v Spec.Workspaces must have unique names, a positive Size, such as 5Gi, and an AccessMode of ReadWriteOnce, ReadOnlyMany or ReadWriteMany.", kab.Name)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
 	// Make sure any pipelines have a location, and a sha256 set.
 	for _, pipeline := range kab.Spec.Gitops.Pipelines {
-		if len(pipeline.Https.Url) == 0 && pipeline.GitRelease == (kabanerov1alpha2.GitReleaseSpec{}) {
-			reason = fmt.Sprintf("Kabanero %v does not contain a Spec.Gitops.Pipelines[].Https.Url or a populated Spec.Gitops.Pipelines[].GitRelease{}. One of them must be specified. If both are specified, Spec.Gitops.Pipelines[].GitRelease{} takes precedence.", kab.Name)
+		if len(pipeline.Https.Url) == 0 && pipeline.GitRelease == (kabanerov1alpha2.GitReleaseSpec{}) && len(pipeline.Oci.Bundle) == 0 {
+			reason = fmt.Sprintf("Kabanero %v does not contain a Spec.Gitops.Pipelines[].Https.Url, a populated Spec.Gitops.Pipelines[].GitRelease{} or a Spec.Gitops.Pipelines[].Oci.Bundle. One of them must be specified. If several are specified, Spec.Gitops.Pipelines[].GitRelease{} takes precedence, followed by Spec.Gitops.Pipelines[].Oci.Bundle.", kab.Name)
 			err = fmt.Errorf(reason)
 			return false, reason, err
 		}
@@ -71,6 +255,35 @@ func (v *kabaneroValidator) validatekabaneroFn(ctx context.Context, kab *kabaner
 			err = fmt.Errorf(reason)
 			return false, reason, err
 		}
+
+		if !kabanerov1alpha2.IsValidPipelineSha256(pipeline.Sha256) {
+			reason = fmt.Sprintf("Kabanero %v Spec.Gitops.Pipelines[].Sha256 %v must be a sha256 or sha512 hex digest, optionally prefixed with sha256: or sha512:.", kab.Name, pipeline.Sha256)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+
+		if !kabanerov1alpha2.IsValidPipelineRenderer(pipeline.Renderer) {
+			reason = fmt.Sprintf("Kabanero %v Spec.Gitops.Pipelines[].Renderer may only be set to %v or %v.", kab.Name, kabanerov1alpha2.PipelineRendererDirective, kabanerov1alpha2.PipelineRendererGoTemplate)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+
+		if !kabanerov1alpha2.IsValidPipelineServiceAccount(pipeline.ServiceAccount) {
+			reason = fmt.Sprintf("Kabanero %v Spec.Gitops.Pipelines[].ServiceAccount.Name %v is not a valid object name.", kab.Name, pipeline.ServiceAccount.Name)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+
+		if !kabanerov1alpha2.IsValidPipelineSignature(pipeline.Signature) {
+			reason = fmt.Sprintf("Kabanero %v Spec.Gitops.Pipelines[].Signature must have a Format of %v or %v, a Https.Url or a populated GitRelease{}, and a KeySecretRef.Name.", kab.Name, kabanerov1alpha2.PipelineSignatureFormatGpg, kabanerov1alpha2.PipelineSignatureFormatCosign)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+	}
+
+	// Make sure the gitops pipelines can be retrieved, if requested.
+	if isDeepValidationRequested(kab) {
+		return validateGitopsPipelinesReachable(ctx, kab)
 	}
 
 	return true, "", nil
@@ -87,27 +300,3 @@ func (v *kabaneroValidator) InjectDecoder(d *admission.Decoder) error {
 	v.decoder = d
 	return nil
 }
-
-// Validates that no more than one kabanero instance in a given namespace is allowed.
-func isKabaneroInstanceAllowed(cl client.Client, ctx context.Context, kab *kabanerov1alpha2.Kabanero) (bool, string, error) {
-	name := kab.ObjectMeta.Name
-	namespace := kab.ObjectMeta.Namespace
-	kabaneroList := &kabanerov1alpha2.KabaneroList{}
-	options := []client.ListOption{client.InNamespace(namespace)}
-	err := cl.List(ctx, kabaneroList, options...)
-	if err != nil {
-		return false, fmt.Sprintf("Failed to list Kabaneros in namespace: %s", namespace), err
-	}
-
-	for _, kabanero := range kabaneroList.Items {
-		if name == kabanero.Name {
-			// Matching name, allow Update
-			break
-		} else {
-			// This is an additional instance. Reject it.
-			return false, fmt.Sprintf("Rejecting additional Kabanero instance: %s in namespace: %s. Multiple Kabanero instances are not allowed.", name, namespace), nil
-		}
-	}
-
-	return true, "", nil
-}
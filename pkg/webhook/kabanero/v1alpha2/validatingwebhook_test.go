@@ -0,0 +1,170 @@
+package kabanero
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kutils "github.com/kabanero-io/kabanero-operator/pkg/controller/kabaneroplatform/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Test the warnings about settings that are allowed.
+func TestGetKabaneroWarnings(t *testing.T) {
+	kab := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Stacks: kabanerov1alpha2.InstanceStackConfig{
+				Repositories: []kabanerov1alpha2.RepositoryConfig{{
+					Name:  "central",
+					Https: kabanerov1alpha2.HttpsProtocolFile{Url: "https://github.com/kabanero-io/stacks/releases/download/0.6.0/kabanero-stack-hub-index.yaml"},
+					Pipelines: []kabanerov1alpha2.PipelineSpec{{
+						Id:     "default",
+						Sha256: "deb5162495e1fe60ab52632f0879f9c9b95e943066590574865138791cbe948f",
+						Https:  kabanerov1alpha2.HttpsProtocolFile{Url: "https://github.com/kabanero-io/stacks/releases/download/0.6.0/default-kabanero-pipelines.tar.gz"},
+					}},
+				}},
+			},
+		},
+	}
+
+	if warnings := getKabaneroWarnings(kab); len(warnings) != 0 {
+		t.Fatalf("Expected no warnings, but found %v", warnings)
+	}
+
+	kab.Spec.Stacks.SkipRegistryCertVerification = true
+	kab.Spec.Stacks.Repositories[0].Pipelines[0].Sha256 = ""
+	kab.Spec.Stacks.Repositories[0].Pipelines[0].Https.SkipCertVerification = true
+	kab.Spec.VulnerabilityScan.SkipCertVerification = true
+	if warnings := getKabaneroWarnings(kab); len(warnings) != 4 {
+		t.Fatalf("Expected 4 warnings, but found %v", warnings)
+	}
+
+	// Gitops pipelines without a sha256 are rejected instead.
+	kab = &kabanerov1alpha2.Kabanero{}
+	kab.Spec.Gitops.Pipelines = []kabanerov1alpha2.PipelineSpec{{Id: "gitops"}}
+	if warnings := getKabaneroWarnings(kab); len(warnings) != 0 {
+		t.Fatalf("Expected no warnings, but found %v", warnings)
+	}
+}
+
+// Test that the gitops pipeline archives are only checked if deep validation is requested.
+func TestValidateGitopsPipelinesReachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pipelines.tar.gz":
+			w.WriteHeader(http.StatusOK)
+		case "/head-not-allowed.tar.gz":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kab := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+	kab.Spec.Gitops.Pipelines = []kabanerov1alpha2.PipelineSpec{
+		{Id: "found", Https: kabanerov1alpha2.HttpsProtocolFile{Url: server.URL + "/pipelines.tar.gz"}},
+		{Id: "head-not-allowed", Https: kabanerov1alpha2.HttpsProtocolFile{Url: server.URL + "/head-not-allowed.tar.gz"}},
+		{Id: "release", GitRelease: kabanerov1alpha2.GitReleaseSpec{Hostname: "github.com", Organization: "kabanero-io", Project: "stacks", Release: "0.6.0", AssetName: "missing.tar.gz"}},
+	}
+	if isDeepValidationRequested(kab) {
+		t.Fatal("Expected deep validation to be off by default")
+	}
+
+	kab.SetAnnotations(map[string]string{DeepValidationAnnotation: "True"})
+	if !isDeepValidationRequested(kab) {
+		t.Fatal("Expected deep validation to be requested")
+	}
+	allowed, reason, err := validateGitopsPipelinesReachable(context.Background(), kab)
+	if !allowed || err != nil {
+		t.Fatalf("Expected the pipelines to be reachable, but found %v", reason)
+	}
+
+	kab.Spec.Gitops.Pipelines = append(kab.Spec.Gitops.Pipelines, kabanerov1alpha2.PipelineSpec{Id: "missing", Https: kabanerov1alpha2.HttpsProtocolFile{Url: server.URL + "/missing.tar.gz"}})
+	allowed, reason, err = validateGitopsPipelinesReachable(context.Background(), kab)
+	if allowed || err == nil || !strings.Contains(reason, "missing") || !strings.Contains(reason, "404") {
+		t.Fatalf("Expected the missing pipeline to be rejected, but found %v", reason)
+	}
+}
+
+// Test that the rules of the governance policy are validated.
+func TestValidateGovernancePolicyRules(t *testing.T) {
+	tests := []struct {
+		policy  kabanerov1alpha2.GovernancePolicyConfig
+		allowed bool
+	}{
+		{kabanerov1alpha2.GovernancePolicyConfig{}, true},
+		{kabanerov1alpha2.GovernancePolicyConfig{StackPolicy: "loose"}, false},
+		{kabanerov1alpha2.GovernancePolicyConfig{AllowedRegistries: []string{"quay.io", "registry.example.com:5000"}, RequireDigests: true, BannedStackIds: []string{"nodejs"}, MaxActiveVersions: 2}, true},
+		{kabanerov1alpha2.GovernancePolicyConfig{AllowedRegistries: []string{"quay.io/kabanero"}}, false},
+		{kabanerov1alpha2.GovernancePolicyConfig{AllowedRegistries: []string{""}}, false},
+		{kabanerov1alpha2.GovernancePolicyConfig{BannedStackIds: []string{"NodeJS"}}, false},
+		{kabanerov1alpha2.GovernancePolicyConfig{MaxActiveVersions: -1}, false},
+		{kabanerov1alpha2.GovernancePolicyConfig{MaxActiveVersions: 2, MaxActiveVersionsPolicy: kabanerov1alpha2.MaxActiveVersionsPolicyDeactivateOldest}, true},
+		{kabanerov1alpha2.GovernancePolicyConfig{MaxActiveVersions: 2, MaxActiveVersionsPolicy: "DeleteOldest"}, false},
+	}
+
+	for _, test := range tests {
+		kab := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+		kab.Spec.GovernancePolicy = test.policy
+		allowed, reason, _ := kutils.ValidateGovernanceStackPolicy(kab)
+		if allowed != test.allowed {
+			t.Errorf("Expected policy %+v to be allowed: %v, but found %v (%v)", test.policy, test.allowed, allowed, reason)
+		}
+	}
+}
+
+// Test that skipping certificate verification is only allowed for the hosts the policy allows.
+func TestValidateCertVerificationPolicy(t *testing.T) {
+	kab := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+	kab.Spec.Stacks.Repositories = []kabanerov1alpha2.RepositoryConfig{{
+		Name:  "central",
+		Https: kabanerov1alpha2.HttpsProtocolFile{Url: "https://stacks.example.com:8443/index.yaml", SkipCertVerification: true},
+	}}
+	kab.Spec.Gitops.Pipelines = []kabanerov1alpha2.PipelineSpec{{Id: "gitops", Oci: kabanerov1alpha2.OciBundleSpec{Bundle: "registry.internal:5000/gitops:1.0", SkipCertVerification: true}}}
+
+	if allowed, reason := validateCertVerificationPolicy(kab); !allowed {
+		t.Fatalf("Expected the instance to be allowed without a policy, but found %v", reason)
+	}
+
+	kab.Spec.AdmissionControllerWebhook.CertVerificationPolicy.Enforce = true
+	allowed, reason := validateCertVerificationPolicy(kab)
+	if allowed || !strings.Contains(reason, "Repositories[central]") || !strings.Contains(reason, "Gitops.Pipelines[gitops]") {
+		t.Fatalf("Expected the repository and the pipeline to be rejected, but found %v", reason)
+	}
+
+	kab.Spec.AdmissionControllerWebhook.CertVerificationPolicy.AllowedHosts = []string{"*.example.com", "registry.internal:5000"}
+	if allowed, reason := validateCertVerificationPolicy(kab); !allowed {
+		t.Fatalf("Expected the allowed hosts to be accepted, but found %v", reason)
+	}
+
+	// The registries are not known, so skipping their verification is never allowed.
+	kab.Spec.Stacks.SkipRegistryCertVerification = true
+	if allowed, _ := validateCertVerificationPolicy(kab); allowed {
+		t.Fatal("Expected Spec.Stacks.SkipRegistryCertVerification to be rejected")
+	}
+}
+
+// Test that the retention of completed runs must have non-negative limits.
+func TestValidatePipelineRunRetention(t *testing.T) {
+	tests := []struct {
+		retention kabanerov1alpha2.PipelineRunRetentionSpec
+		allowed   bool
+	}{
+		{kabanerov1alpha2.PipelineRunRetentionSpec{}, true},
+		{kabanerov1alpha2.PipelineRunRetentionSpec{MaxCount: 20, MaxAge: "168h"}, true},
+		{kabanerov1alpha2.PipelineRunRetentionSpec{MaxCount: -1}, false},
+		{kabanerov1alpha2.PipelineRunRetentionSpec{MaxAge: "7d"}, false},
+		{kabanerov1alpha2.PipelineRunRetentionSpec{MaxAge: "-1h"}, false},
+	}
+
+	for _, test := range tests {
+		if allowed := kabanerov1alpha2.IsValidPipelineRunRetention(test.retention); allowed != test.allowed {
+			t.Errorf("Expected retention %+v to be allowed: %v, but found %v", test.retention, test.allowed, allowed)
+		}
+	}
+}
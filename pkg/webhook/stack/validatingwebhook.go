@@ -13,6 +13,7 @@ import (
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -62,12 +63,37 @@ func (v *stackValidator) validateStackFn(ctx context.Context, stack *kabanerov1a
 		return false, reason, err
 	}
 
+	if !kabanerov1alpha2.IsValidStackId(stack.Spec.Name) {
+		reason = fmt.Sprintf("Stack Spec.Name %v is not a valid stack id. It must be %v characters or less, start with a lowercase letter, contain only lowercase letters, numbers, or dashes, and cannot end in a dash. stack: %v", stack.Spec.Name, kabanerov1alpha2.MaxStackIdLength, stack)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidAssetDeletionPolicy(stack.Spec.DeletionPolicy) {
+		reason = fmt.Sprintf("Stack %v Spec.DeletionPolicy may only be set to %v, %v or %v. stack: %v", stack.Spec.Name, kabanerov1alpha2.AssetDeletionPolicyDelete, kabanerov1alpha2.AssetDeletionPolicyOrphan, kabanerov1alpha2.AssetDeletionPolicyRetain, stack)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidEventListenerRoute(stack.Spec.EventListenerRoute) {
+		reason = fmt.Sprintf("Stack %v Spec.EventListenerRoute.Kind may only be set to %v or %v. An %v requires Spec.EventListenerRoute.Host, and only an %v can set Spec.EventListenerRoute.TlsSecretName. stack: %v", stack.Spec.Name, kabanerov1alpha2.EventListenerRouteKindRoute, kabanerov1alpha2.EventListenerRouteKindIngress, kabanerov1alpha2.EventListenerRouteKindIngress, kabanerov1alpha2.EventListenerRouteKindIngress, stack)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
+	if !kabanerov1alpha2.IsValidWorkspaceBindings(stack.Spec.Workspaces) {
+		reason = fmt.Sprintf("Stack %v Spec.Workspaces must have unique names, a positive Size, such as 5Gi, and an AccessMode of ReadWriteOnce, ReadOnlyMany or ReadWriteMany. stack: %v", stack.Spec.Name, stack)
+		err = fmt.Errorf(reason)
+		return false, reason, err
+	}
+
 	if len(stack.Spec.Versions) == 0 {
 		reason = fmt.Sprintf("Stack %v Spec.Versions[] list is empty. stack: %v", stack.Spec.Name, stack)
 		err = fmt.Errorf(reason)
 		return false, reason, err
 	}
 
+	versions := make(map[string]bool)
 	for _, version := range stack.Spec.Versions {
 
 		if len(version.Version) == 0 {
@@ -76,19 +102,34 @@ func (v *stackValidator) validateStackFn(ctx context.Context, stack *kabanerov1a
 			return false, reason, err
 		}
 
-		_, err := semver.Parse(version.Version)
+		semVersion, err := semver.Parse(version.Version)
 		if err != nil {
 			reason = fmt.Sprintf("Stack %v %v spec.Versions[].Version must be semver. %v. stack: %v", stack.Spec.Name, version.Version, err, stack)
 			err = fmt.Errorf(reason)
 			return false, reason, err
 		}
 
+		// Only one entry of a version is activated, so a second one is a mistake.
+		if versions[semVersion.String()] {
+			reason = fmt.Sprintf("Stack %v %v is listed more than once in spec.Versions[]. stack: %v", stack.Spec.Name, version.Version, stack)
+			err = fmt.Errorf(reason)
+			return false, reason, err
+		}
+		versions[semVersion.String()] = true
+
 		if (len(version.DesiredState) != 0) && !((strings.ToLower(version.DesiredState) == "active") || (strings.ToLower(version.DesiredState) == "inactive")) {
 			reason = fmt.Sprintf("Stack %v %v Spec.Versions[].DesiredState may only be set to active or inactive. stack: %v", stack.Spec.Name, version.Version, stack)
 			err = fmt.Errorf(reason)
 			return false, reason, err
 		}
 
+		if len(version.EndOfSupport) != 0 {
+			if _, err := kabanerov1alpha2.ParseEndOfSupport(version.EndOfSupport); err != nil {
+				reason = fmt.Sprintf("Stack %v %v Spec.Versions[].EndOfSupport %v must be a date, as in 2021-06-30, or an RFC 3339 time. stack: %v", stack.Spec.Name, version.Version, version.EndOfSupport, stack)
+				return false, reason, err
+			}
+		}
+
 		if len(version.Images) == 0 {
 			reason = fmt.Sprintf("Stack %v %v must contain at least one entry for spec.Versions[].Images. stack: %v", stack.Spec.Name, version.Version, stack)
 			err = fmt.Errorf(reason)
@@ -109,12 +150,42 @@ func (v *stackValidator) validateStackFn(ctx context.Context, stack *kabanerov1a
 		}
 
 		for _, pipeline := range version.Pipelines {
-			if len(pipeline.Https.Url) == 0 && pipeline.GitRelease == (kabanerov1alpha2.GitReleaseSpec{}) {
-				reason = fmt.Sprintf("Stack %v %v does not contain a Spec.Versions[].Pipelines[].Https.Url or a populated Spec.Versions[].Pipelines[].GitRelease{}. One of them must be specified. If both are specified, Spec.Versions[].Pipelines[].GitRelease{} takes precedence. Stack: %v", stack.Spec.Name, version.Version, stack)
+			if len(pipeline.Https.Url) == 0 && pipeline.GitRelease == (kabanerov1alpha2.GitReleaseSpec{}) && len(pipeline.Oci.Bundle) == 0 {
+				reason = fmt.Sprintf("Stack %v %v does not contain a Spec.Versions[].Pipelines[].Https.Url, a populated Spec.Versions[].Pipelines[].GitRelease{} or a Spec.Versions[].Pipelines[].Oci.Bundle. One of them must be specified. If several are specified, Spec.Versions[].Pipelines[].GitRelease{} takes precedence, followed by Spec.Versions[].Pipelines[].Oci.Bundle. Stack: %v", stack.Spec.Name, version.Version, stack)
+				err = fmt.Errorf(reason)
+				return false, reason, err
+			}
+
+			if !kabanerov1alpha2.IsValidPipelineRenderer(pipeline.Renderer) {
+				reason = fmt.Sprintf("Stack %v %v Spec.Versions[].Pipelines[].Renderer may only be set to %v or %v. stack: %v", stack.Spec.Name, version.Version, kabanerov1alpha2.PipelineRendererDirective, kabanerov1alpha2.PipelineRendererGoTemplate, stack)
+				err = fmt.Errorf(reason)
+				return false, reason, err
+			}
+
+			if !kabanerov1alpha2.IsValidPipelineServiceAccount(pipeline.ServiceAccount) {
+				reason = fmt.Sprintf("Stack %v %v Spec.Versions[].Pipelines[].ServiceAccount.Name %v is not a valid object name. stack: %v", stack.Spec.Name, version.Version, pipeline.ServiceAccount.Name, stack)
+				err = fmt.Errorf(reason)
+				return false, reason, err
+			}
+
+			if !kabanerov1alpha2.IsValidPipelineSignature(pipeline.Signature) {
+				reason = fmt.Sprintf("Stack %v %v Spec.Versions[].Pipelines[].Signature must have a Format of %v or %v, a Https.Url or a populated GitRelease{}, and a KeySecretRef.Name. stack: %v", stack.Spec.Name, version.Version, kabanerov1alpha2.PipelineSignatureFormatGpg, kabanerov1alpha2.PipelineSignatureFormatCosign, stack)
 				err = fmt.Errorf(reason)
 				return false, reason, err
 			}
 			
+			if !kabanerov1alpha2.IsValidPipelineSha256(pipeline.Sha256) {
+				reason = fmt.Sprintf("Stack %v %v Spec.Versions[].Pipelines[].Sha256 %v must be a sha256 or sha512 hex digest, optionally prefixed with its algorithm, as in sha512:<digest>. stack: %v", stack.Spec.Name, version.Version, pipeline.Sha256, stack)
+				err = fmt.Errorf(reason)
+				return false, reason, err
+			}
+
+			if len(pipeline.Oci.Bundle) != 0 && len(pipeline.Sha256) == 0 {
+				reason = fmt.Sprintf("Stack %v %v Spec.Versions[].Pipelines[].Sha256 must be set for a Tekton bundle. stack: %v", stack.Spec.Name, version.Version, stack)
+				err = fmt.Errorf(reason)
+				return false, reason, err
+			}
+
 			if len(pipeline.Https.Url) != 0 {
 				fileNameURL, err := url.Parse(pipeline.Https.Url)
 				if err != nil {
@@ -157,9 +228,96 @@ func (v *stackValidator) validateStackFn(ctx context.Context, stack *kabanerov1a
 		}
 	}
 
+	// Stacks created by a StackHub, or by the Kabanero instance, come from stack repositories.
+	// The governance policy is applied to them when they are reconciled, so that one stack in
+	// a repository does not prevent the others from being updated.
+	if v.client != nil && !isFromStackRepository(stack) {
+		kabaneros := &kabanerov1alpha2.KabaneroList{}
+		err = v.client.List(ctx, kabaneros, client.InNamespace(stack.GetNamespace()))
+		if err != nil {
+			reason = fmt.Sprintf("Unable to list the Kabanero instances in namespace %v: %v", stack.GetNamespace(), err.Error())
+			return false, reason, err
+		}
+
+		for _, kabanero := range kabaneros.Items {
+			allowed, reason := validateGovernancePolicy(&kabanero, stack)
+			if !allowed {
+				return false, reason, fmt.Errorf(reason)
+			}
+
+			allowed, reason = validateCertVerificationPolicy(&kabanero, stack)
+			if !allowed {
+				return false, reason, fmt.Errorf(reason)
+			}
+		}
+	}
+
 	return true, reason, nil
 }
 
+// Returns true if the input stack is controlled by a StackHub or a Kabanero instance.
+func isFromStackRepository(stack *kabanerov1alpha2.Stack) bool {
+	owner := metav1.GetControllerOf(stack)
+	return owner != nil && (owner.Kind == "StackHub" || owner.Kind == "Kabanero")
+}
+
+// Validates the active versions of the input stack against the governance policy of the
+// Kabanero instance.  Too many active versions are allowed when the stack controller is
+// asked to deactivate the oldest ones.
+func validateGovernancePolicy(kabanero *kabanerov1alpha2.Kabanero, stack *kabanerov1alpha2.Stack) (bool, string) {
+	policy := kabanero.Spec.GovernancePolicy
+	violations := utils.CheckGovernancePolicy(policy, stack.Spec)
+	for _, version := range stack.Spec.Versions {
+		if violation, violated := violations[version.Version]; violated {
+			// The stack controller deactivates the oldest versions itself.
+			if violation.Reason == kabanerov1alpha2.StackReasonTooManyActiveVersions && policy.MaxActiveVersionsPolicy == kabanerov1alpha2.MaxActiveVersionsPolicyDeactivateOldest {
+				continue
+			}
+
+			return false, fmt.Sprintf("%v (%v) Set Spec.Versions[].DesiredState to inactive, or change Kabanero %v Spec.GovernancePolicy.", violation.Message, violation.Reason, kabanero.Name)
+		}
+	}
+	return true, ""
+}
+
+// Validates the input stack against the certificate verification policy of the Kabanero
+// instance.  Skipping the verification of the image registries is allowed if each image of
+// the version is from an allowed registry.
+func validateCertVerificationPolicy(kabanero *kabanerov1alpha2.Kabanero, stack *kabanerov1alpha2.Stack) (bool, string) {
+	policy := kabanero.Spec.AdmissionControllerWebhook.CertVerificationPolicy
+	if !policy.Enforce {
+		return true, ""
+	}
+
+	fields := []string{}
+	for _, version := range stack.Spec.Versions {
+		if version.SkipRegistryCertVerification {
+			for _, image := range version.Images {
+				registry, err := utils.GetImageRegistry(image.Image)
+				if err != nil || !policy.IsSkipAllowed(registry) {
+					fields = append(fields, fmt.Sprintf("Spec.Versions[%v].SkipRegistryCertVerification", version.Version))
+					break
+				}
+			}
+		}
+
+		if version.SkipCertVerification && !policy.IsSkipAllowed(kabanerov1alpha2.GetUrlHost(version.RepositoryUrl)) {
+			fields = append(fields, fmt.Sprintf("Spec.Versions[%v].SkipCertVerification", version.Version))
+		}
+
+		for _, pipeline := range version.Pipelines {
+			if pipeline.SkipCertVerification() && !policy.IsSkipAllowed(pipeline.ArchiveHost()) {
+				fields = append(fields, fmt.Sprintf("Spec.Versions[%v].Pipelines[%v]", version.Version, pipeline.Id))
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return true, ""
+	}
+	return false, fmt.Sprintf("Stack %v skips certificate verification in %v, which Kabanero %v Spec.AdmissionControllerWebhook.CertVerificationPolicy does not allow. Verify the certificates, or add the hosts to Spec.AdmissionControllerWebhook.CertVerificationPolicy.AllowedHosts.", stack.Spec.Name, strings.Join(fields, ", "), kabanero.Name)
+}
+
 // InjectClient injects the client.
 func (v *stackValidator) InjectClient(c client.Client) error {
 	v.client = c
@@ -1,6 +1,7 @@
 package stack
 
 import (
+	"strings"
 	"testing"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
@@ -28,7 +29,7 @@ var validatingStack kabanerov1alpha2.Stack = kabanerov1alpha2.Stack{
 			DesiredState: "active",
 			Version:      "1.2.3",
 			Pipelines: []kabanerov1alpha2.PipelineSpec{{
-				Sha256: "abc121cba",
+				Sha256: "deb5162495e1fe60ab52632f0879f9c9b95e943066590574865138791cbe948f",
 				Https: kabanerov1alpha2.HttpsProtocolFile{
 					Url: "http://pipelinelink/pipeline.tar.gz",
 				},
@@ -517,3 +518,242 @@ func TestValidatingWebhook21(t *testing.T) {
 		t.Fatal("Validation failed. An error was expected: ", err)
 	}
 }
+
+// Spec.DeletionPolicy is not valid
+func TestValidatingWebhook22(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.DeletionPolicy = "Keep"
+
+	cv := stackValidator{}
+	allowed, msg, err := cv.validateStackFn(nil, newStack)
+
+	if allowed {
+		t.Fatal("Validation should have failed because the deletion policy is not valid.")
+	}
+
+	if len(msg) == 0 {
+		t.Fatal("Validation failed. A message was expected: ", msg)
+	}
+
+	if err == nil {
+		t.Fatal("Validation failed. An error was expected: ", err)
+	}
+
+	newStack.Spec.DeletionPolicy = kabanerov1alpha2.AssetDeletionPolicyRetain
+	allowed, msg, err = cv.validateStackFn(nil, newStack)
+
+	if !allowed {
+		t.Fatal("Validation should have passed for the Retain deletion policy. Error: ", err)
+	}
+}
+
+// Spec.Versions[].Pipelines[].Signature is not valid
+func TestValidatingWebhook23(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.Versions[0].Pipelines[0].Signature = &kabanerov1alpha2.PipelineSignatureSpec{
+		Format: "x509",
+		Https:  kabanerov1alpha2.HttpsProtocolFile{Url: "https://github.com/kabanero-io/collections/releases/download/0.1.0/incubator.common.pipeline.default.tar.gz.sig"},
+		KeySecretRef: kabanerov1alpha2.SignatureKeySecretRef{Name: "pipeline-signing-key"},
+	}
+
+	cv := stackValidator{}
+	allowed, msg, err := cv.validateStackFn(nil, newStack)
+
+	if allowed {
+		t.Fatal("Validation should have failed because the signature format is not valid.")
+	}
+
+	if len(msg) == 0 {
+		t.Fatal("Validation failed. A message was expected: ", msg)
+	}
+
+	if err == nil {
+		t.Fatal("Validation failed. An error was expected: ", err)
+	}
+
+	newStack.Spec.Versions[0].Pipelines[0].Signature.Format = kabanerov1alpha2.PipelineSignatureFormatCosign
+	allowed, msg, err = cv.validateStackFn(nil, newStack)
+
+	if !allowed {
+		t.Fatal("Validation should have passed for the cosign signature format. Error: ", err)
+	}
+}
+
+// Spec.Name is not a valid stack id
+func TestValidatingWebhook24(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.Name = "Java-Microprofile"
+
+	cv := stackValidator{}
+	allowed, msg, err := cv.validateStackFn(nil, newStack)
+
+	if allowed {
+		t.Fatal("Validation should have failed because the stack id contains uppercase letters.")
+	}
+
+	if len(msg) == 0 {
+		t.Fatal("Validation failed. A message was expected: ", msg)
+	}
+
+	if err == nil {
+		t.Fatal("Validation failed. An error was expected: ", err)
+	}
+
+	newStack.Spec.Name = strings.Repeat("a", kabanerov1alpha2.MaxStackIdLength+1)
+	allowed, msg, err = cv.validateStackFn(nil, newStack)
+
+	if allowed {
+		t.Fatal("Validation should have failed because the stack id is too long.")
+	}
+}
+
+// Spec.Versions[].Pipelines[].Sha256 is not a sha256 or sha512 digest
+func TestValidatingWebhook25(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.Versions[0].Pipelines[0].Sha256 = "abc121cba"
+
+	cv := stackValidator{}
+	allowed, msg, err := cv.validateStackFn(nil, newStack)
+
+	if allowed {
+		t.Fatal("Validation should have failed because the pipeline digest is not a sha256 or sha512 digest.")
+	}
+
+	if len(msg) == 0 {
+		t.Fatal("Validation failed. A message was expected: ", msg)
+	}
+
+	if err == nil {
+		t.Fatal("Validation failed. An error was expected: ", err)
+	}
+
+	newStack.Spec.Versions[0].Pipelines[0].Sha256 = "sha512:" + strings.Repeat("0123456789abcdef", 8)
+	allowed, msg, err = cv.validateStackFn(nil, newStack)
+
+	if !allowed {
+		t.Fatal("Validation should have passed for a sha512 digest. Error: ", err)
+	}
+}
+
+// Spec.Versions[] contains the same version twice
+func TestValidatingWebhook26(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.Versions = append(newStack.Spec.Versions, *newStack.Spec.Versions[0].DeepCopy())
+	newStack.Spec.Versions[1].DesiredState = "inactive"
+
+	cv := stackValidator{}
+	allowed, msg, err := cv.validateStackFn(nil, newStack)
+
+	if allowed {
+		t.Fatal("Validation should have failed because version 1.2.3 is listed twice.")
+	}
+
+	if len(msg) == 0 {
+		t.Fatal("Validation failed. A message was expected: ", msg)
+	}
+
+	if err == nil {
+		t.Fatal("Validation failed. An error was expected: ", err)
+	}
+
+	newStack.Spec.Versions[1].Version = "1.2.4"
+	allowed, msg, err = cv.validateStackFn(nil, newStack)
+
+	if !allowed {
+		t.Fatal("Validation should have passed for two different versions. Error: ", err)
+	}
+}
+
+// Spec.EventListenerRoute is not valid
+func TestValidatingWebhook27(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.EventListenerRoute = &kabanerov1alpha2.EventListenerRouteSpec{Kind: kabanerov1alpha2.EventListenerRouteKindIngress}
+
+	cv := stackValidator{}
+	allowed, msg, err := cv.validateStackFn(nil, newStack)
+
+	if allowed {
+		t.Fatal("Validation should have failed because the Ingress has no host.")
+	}
+
+	if len(msg) == 0 {
+		t.Fatal("Validation failed. A message was expected: ", msg)
+	}
+
+	if err == nil {
+		t.Fatal("Validation failed. An error was expected: ", err)
+	}
+
+	newStack.Spec.EventListenerRoute.Host = "webhooks.example.com"
+	allowed, msg, err = cv.validateStackFn(nil, newStack)
+
+	if !allowed {
+		t.Fatal("Validation should have passed for an Ingress with a host. Error: ", err)
+	}
+}
+
+// Test that stacks created outside of the Kabanero instance are checked against its governance policy.
+func TestValidateGovernancePolicy(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	if isFromStackRepository(newStack) {
+		t.Fatal("Expected the stack not to be controlled by the Kabanero instance")
+	}
+
+	controller := true
+	newStack.OwnerReferences[0].Controller = &controller
+	if !isFromStackRepository(newStack) {
+		t.Fatal("Expected the stack to be controlled by the Kabanero instance")
+	}
+
+	newStack.OwnerReferences[0].Kind = "StackHub"
+	if !isFromStackRepository(newStack) {
+		t.Fatal("Expected the stack to be controlled by a StackHub")
+	}
+	newStack.OwnerReferences[0].Kind = "Kabanero"
+
+	kabanero := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+	allowed, msg := validateGovernancePolicy(kabanero, newStack)
+	if !allowed {
+		t.Fatal("Validation should have passed without a governance policy. Message: ", msg)
+	}
+
+	kabanero.Spec.GovernancePolicy.AllowedRegistries = []string{"quay.io"}
+	allowed, msg = validateGovernancePolicy(kabanero, newStack)
+	if allowed {
+		t.Fatal("Validation should have failed because docker.io is not an allowed registry")
+	}
+	if !strings.Contains(msg, kabanerov1alpha2.StackReasonRegistryNotAllowed) {
+		t.Fatal("Expected the message to contain the reason. Message: ", msg)
+	}
+
+	newStack.Spec.Versions[0].DesiredState = kabanerov1alpha2.StackDesiredStateInactive
+	allowed, msg = validateGovernancePolicy(kabanero, newStack)
+	if !allowed {
+		t.Fatal("Validation should have passed for an inactive version. Message: ", msg)
+	}
+}
+
+// Test that skipping certificate verification is only allowed for the hosts the policy allows.
+func TestValidateCertVerificationPolicy(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.Versions[0].SkipRegistryCertVerification = true
+	newStack.Spec.Versions[0].Pipelines[0].Https.SkipCertVerification = true
+
+	kabanero := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+	allowed, msg := validateCertVerificationPolicy(kabanero, newStack)
+	if !allowed {
+		t.Fatal("Validation should have passed without a policy. Message: ", msg)
+	}
+
+	kabanero.Spec.AdmissionControllerWebhook.CertVerificationPolicy.Enforce = true
+	allowed, msg = validateCertVerificationPolicy(kabanero, newStack)
+	if allowed || !strings.Contains(msg, "SkipRegistryCertVerification") || !strings.Contains(msg, "Pipelines") {
+		t.Fatal("Validation should have failed for the registry and the pipeline. Message: ", msg)
+	}
+
+	kabanero.Spec.AdmissionControllerWebhook.CertVerificationPolicy.AllowedHosts = []string{"docker.io", "pipelinelink"}
+	allowed, msg = validateCertVerificationPolicy(kabanero, newStack)
+	if !allowed {
+		t.Fatal("Validation should have passed for allowed hosts. Message: ", msg)
+	}
+}